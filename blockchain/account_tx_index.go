@@ -0,0 +1,302 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/crypto"
+	"github.com/scripttoken/script/ledger/types"
+	"github.com/scripttoken/script/store"
+)
+
+// ---------------- Account Tx Index ---------------
+//
+// The account tx index maps each address to the positions of the finalized transactions touching
+// the account: the inputs and outputs of the transactions, the stake sources and holders, the
+// coinbase reward recipients, and the accounts whose balance is changed by a smart contract
+// transaction, including the internal SCPT/SPAY transfers made by contracts. As for the log index,
+// the positions of an address are stored in ascending order in a list of chunks.
+
+// accountTxIndexChunkSize is the maximum number of entries of an account tx index chunk
+const accountTxIndexChunkSize = 256
+
+// AccountTxIndexStatus records the range of finalized blocks added to the account tx index.
+type AccountTxIndexStatus struct {
+	FirstHeight uint64
+	LastHeight  uint64
+}
+
+type accountTxIndexChunk struct {
+	Entries []TxIndexEntry
+}
+
+func accountTxIndexStatusKey() common.Bytes {
+	return common.Bytes("acctxidx/status")
+}
+
+// accountTxIndexHeadKey constructs the DB key for the number of chunks of the address.
+func accountTxIndexHeadKey(addr common.Address) common.Bytes {
+	return append(common.Bytes("acctxidx/a/"), addr[:]...)
+}
+
+func accountTxIndexChunkKey(addr common.Address, chunk uint64) common.Bytes {
+	ret := accountTxIndexHeadKey(addr)
+	ret = append(ret, '/')
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], chunk)
+	return append(ret, buf[:]...)
+}
+
+// GetAccountTxIndexStatus returns the range of finalized blocks added to the account tx index, or false
+// if the account tx index is empty.
+func (ch *Chain) GetAccountTxIndexStatus() (AccountTxIndexStatus, bool) {
+	status := AccountTxIndexStatus{}
+	err := ch.store.Get(accountTxIndexStatusKey(), &status)
+	if err != nil {
+		if err != store.ErrKeyNotFound {
+			logger.Error(err)
+		}
+		return status, false
+	}
+	return status, true
+}
+
+// ResetAccountTxIndex makes the next block added to the account tx index start a new indexed range. The
+// entries at and above the height of that block are overwritten as the blocks are indexed again.
+func (ch *Chain) ResetAccountTxIndex() {
+	err := ch.store.Delete(accountTxIndexStatusKey())
+	if err != nil && err != store.ErrKeyNotFound {
+		logger.Panic(err)
+	}
+}
+
+// AddAccountTxsToIndex adds the transactions of the given finalized block to the account tx index. Blocks
+// must be added in ascending height order, a block at or below the last indexed height is skipped.
+func (ch *Chain) AddAccountTxsToIndex(block *core.ExtendedBlock) {
+	status, ok := ch.GetAccountTxIndexStatus()
+	if ok && block.Height <= status.LastHeight {
+		return
+	}
+	if !ok {
+		status.FirstHeight = block.Height
+	} else if block.Height > status.LastHeight+1 {
+		logger.Warnf("Account tx index skips from height %v to %v, reindex the account txs to fill in the gap", status.LastHeight, block.Height)
+	}
+
+	entries := make(map[common.Address][]TxIndexEntry)
+	blockHash := block.Hash()
+	for txIdx, rawTx := range block.Txs {
+		tx, err := types.TxFromBytes(rawTx)
+		if err != nil {
+			logger.Warnf("Failed to parse transaction %v of block %v: %v", txIdx, blockHash.Hex(), err)
+			continue
+		}
+		entry := TxIndexEntry{
+			BlockHash:   blockHash,
+			BlockHeight: block.Height,
+			Index:       uint64(txIdx),
+		}
+		for _, addr := range ch.accountsTouchedByTx(blockHash, rawTx, tx) {
+			entries[addr] = append(entries[addr], entry)
+		}
+	}
+
+	// Sorted for the DB writes to be deterministic
+	addrs := make([]common.Address, 0, len(entries))
+	for addr := range entries {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	for _, addr := range addrs {
+		ch.appendAccountTxIndexEntries(addr, block.Height, entries[addr])
+	}
+
+	status.LastHeight = block.Height
+	err := ch.store.Put(accountTxIndexStatusKey(), status)
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+// accountsTouchedByTx returns the distinct addresses of the accounts touched by the transaction.
+func (ch *Chain) accountsTouchedByTx(blockHash common.Hash, rawTx common.Bytes, tx types.Tx) []common.Address {
+	addrs := []common.Address{}
+	seen := make(map[common.Address]bool)
+	add := func(addr common.Address) {
+		if addr == (common.Address{}) || seen[addr] {
+			return
+		}
+		seen[addr] = true
+		addrs = append(addrs, addr)
+	}
+
+	switch tx := tx.(type) {
+	case *types.CoinbaseTx:
+		add(tx.Proposer.Address)
+		for _, output := range tx.Outputs {
+			add(output.Address)
+		}
+	case *types.SlashTx:
+		add(tx.Proposer.Address)
+		add(tx.SlashedAddress)
+	case *types.SendTx:
+		for _, input := range tx.Inputs {
+			add(input.Address)
+		}
+		for _, output := range tx.Outputs {
+			add(output.Address)
+		}
+	case *types.ReserveFundTx:
+		add(tx.Source.Address)
+	case *types.ReleaseFundTx:
+		add(tx.Source.Address)
+	case *types.ServicePaymentTx:
+		add(tx.Source.Address)
+		add(tx.Target.Address)
+	case *types.SplitRuleTx:
+		add(tx.Initiator.Address)
+		for _, split := range tx.Splits {
+			add(split.Address)
+		}
+	case *types.SmartContractTx:
+		add(tx.From.Address)
+		add(tx.To.Address)
+	case *types.SmartContractTxV2:
+		add(tx.From.Address)
+		add(tx.To.Address)
+	case *types.DepositStakeTx:
+		add(tx.Source.Address)
+		add(tx.Holder.Address)
+	case *types.DepositStakeTxV2:
+		add(tx.Source.Address)
+		add(tx.Holder.Address)
+	case *types.WithdrawStakeTx:
+		add(tx.Source.Address)
+		add(tx.Holder.Address)
+	case *types.StakeRewardDistributionTx:
+		add(tx.Holder.Address)
+		add(tx.Beneficiary.Address)
+	case *types.CreateAssetTx:
+		add(tx.Issuer.Address)
+	}
+
+	// The contract deployed and the accounts whose balance is changed by a smart contract transaction
+	txHash := crypto.Keccak256Hash(rawTx)
+	if receipt, found := ch.FindTxReceiptByHash(blockHash, txHash); found {
+		add(receipt.ContractAddress)
+	}
+	if balanceChanges, found := ch.FindTxBalanceChangesByHash(blockHash, txHash); found {
+		for _, balanceChange := range balanceChanges.BalanceChanges {
+			add(balanceChange.Address)
+		}
+	}
+	return addrs
+}
+
+// appendAccountTxIndexEntries appends the entries of a block to the chunks of the address, after
+// removing the entries at or above the block height left by a previous indexing.
+func (ch *Chain) appendAccountTxIndexEntries(addr common.Address, height uint64, entries []TxIndexEntry) {
+	var numChunks uint64
+	err := ch.store.Get(accountTxIndexHeadKey(addr), &numChunks)
+	if err != nil && err != store.ErrKeyNotFound {
+		logger.Panic(err)
+	}
+
+	chunk := &accountTxIndexChunk{}
+	for numChunks > 0 {
+		err = ch.store.Get(accountTxIndexChunkKey(addr, numChunks-1), chunk)
+		if err != nil {
+			logger.Panic(err)
+		}
+		numKept := sort.Search(len(chunk.Entries), func(i int) bool {
+			return chunk.Entries[i].BlockHeight >= height
+		})
+		chunk.Entries = chunk.Entries[:numKept]
+		if numKept > 0 || numChunks == 1 {
+			break
+		}
+		err = ch.store.Delete(accountTxIndexChunkKey(addr, numChunks-1))
+		if err != nil {
+			logger.Panic(err)
+		}
+		numChunks--
+	}
+	if numChunks == 0 {
+		numChunks = 1
+	}
+
+	for _, entry := range entries {
+		if len(chunk.Entries) >= accountTxIndexChunkSize {
+			err = ch.store.Put(accountTxIndexChunkKey(addr, numChunks-1), chunk)
+			if err != nil {
+				logger.Panic(err)
+			}
+			chunk = &accountTxIndexChunk{}
+			numChunks++
+		}
+		chunk.Entries = append(chunk.Entries, entry)
+	}
+	err = ch.store.Put(accountTxIndexChunkKey(addr, numChunks-1), chunk)
+	if err != nil {
+		logger.Panic(err)
+	}
+	err = ch.store.Put(accountTxIndexHeadKey(addr), numChunks)
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+// FindAccountTxs returns a page of the positions of the transactions touching the account in the height
+// range, from the most recent one. Pages are numbered from 0.
+func (ch *Chain) FindAccountTxs(addr common.Address, fromHeight, toHeight uint64, page, pageSize uint64) ([]TxIndexEntry, error) {
+	if fromHeight > toHeight {
+		return nil, fmt.Errorf("from height %v is greater than to height %v", fromHeight, toHeight)
+	}
+	if pageSize == 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+
+	ret := []TxIndexEntry{}
+	var numChunks uint64
+	err := ch.store.Get(accountTxIndexHeadKey(addr), &numChunks)
+	if err != nil {
+		if err != store.ErrKeyNotFound {
+			logger.Error(err)
+		}
+		return ret, nil
+	}
+
+	numSkipped := uint64(0)
+	toSkip := page * pageSize
+	for c := numChunks; c > 0; c-- {
+		chunk := &accountTxIndexChunk{}
+		err = ch.store.Get(accountTxIndexChunkKey(addr, c-1), chunk)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(chunk.Entries) - 1; i >= 0; i-- {
+			entry := chunk.Entries[i]
+			if entry.BlockHeight < fromHeight {
+				return ret, nil
+			}
+			if entry.BlockHeight > toHeight {
+				continue
+			}
+			if numSkipped < toSkip {
+				numSkipped++
+				continue
+			}
+			ret = append(ret, entry)
+			if uint64(len(ret)) == pageSize {
+				return ret, nil
+			}
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,168 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/crypto"
+	"github.com/scripttoken/script/ledger/types"
+)
+
+var (
+	acctTestProposer  = common.HexToAddress("0x2001")
+	acctTestSender    = common.HexToAddress("0x2002")
+	acctTestReceiver  = common.HexToAddress("0x2003")
+	acctTestContract  = common.HexToAddress("0x2004")
+	acctTestRecipient = common.HexToAddress("0x2005")
+)
+
+// addAccountTxTestBlock adds a finalized block at the given height with a coinbase transaction, a send
+// transaction, and on the even heights a smart contract transaction transferring SCPT internally
+func addAccountTxTestBlock(t *testing.T, chain *Chain, height uint64) *core.ExtendedBlock {
+	coinbaseTx := &types.CoinbaseTx{
+		Proposer:    types.TxInput{Address: acctTestProposer},
+		Outputs:     []types.TxOutput{{Address: acctTestProposer}},
+		BlockHeight: height,
+	}
+	sendTx := &types.SendTx{
+		Inputs:  []types.TxInput{{Address: acctTestSender, Sequence: height}},
+		Outputs: []types.TxOutput{{Address: acctTestReceiver}},
+	}
+	rawTxs := []common.Bytes{}
+	for _, tx := range []types.Tx{coinbaseTx, sendTx} {
+		raw, err := types.TxToBytes(tx)
+		require.Nil(t, err)
+		rawTxs = append(rawTxs, raw)
+	}
+
+	var contractTx *types.SmartContractTx
+	if height%2 == 0 {
+		contractTx = &types.SmartContractTx{
+			From:     types.TxInput{Address: acctTestSender, Sequence: height},
+			To:       types.TxOutput{Address: acctTestContract},
+			GasLimit: 100000,
+			GasPrice: big.NewInt(1),
+		}
+		privKey, _, err := crypto.GenerateKeyPair()
+		require.Nil(t, err)
+		contractTx.From.Signature, err = privKey.Sign(contractTx.SignBytes(chain.ChainID))
+		require.Nil(t, err)
+		raw, err := types.TxToBytes(contractTx)
+		require.Nil(t, err)
+		rawTxs = append(rawTxs, raw)
+	}
+
+	block := core.CreateTestBlock(fmt.Sprintf("a%x", height), "")
+	block.Height = height
+	block.Txs = rawTxs
+	block.UpdateHash()
+	eb, err := chain.AddBlock(block)
+	require.Nil(t, err)
+
+	if contractTx != nil {
+		balanceChanges := []*types.BalanceChange{
+			{Address: acctTestContract, TokenType: 0, IsNegative: true, Delta: big.NewInt(1)},
+			{Address: acctTestRecipient, TokenType: 0, Delta: big.NewInt(1)},
+		}
+		chain.AddTxReceipt(block, contractTx, nil, balanceChanges, nil, common.Address{}, 0, nil)
+	}
+	return eb
+}
+
+func accountTxPositions(entries []TxIndexEntry) []string {
+	positions := []string{}
+	for _, entry := range entries {
+		positions = append(positions, fmt.Sprintf("%v/%v", entry.BlockHeight, entry.Index))
+	}
+	return positions
+}
+
+func TestAccountTxIndex(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	core.ResetTestBlocks()
+	chain := CreateTestChain()
+	blocks := []*core.ExtendedBlock{}
+	for height := uint64(1); height <= 300; height++ {
+		block := addAccountTxTestBlock(t, chain, height)
+		blocks = append(blocks, block)
+		chain.AddAccountTxsToIndex(block)
+	}
+	status, ok := chain.GetAccountTxIndexStatus()
+	require.True(ok)
+	assert.Equal(AccountTxIndexStatus{FirstHeight: 1, LastHeight: 300}, status)
+
+	// The sender sends a transaction in every block and calls the contract in the even blocks, the
+	// entries span several chunks and are returned from the most recent one
+	entries, err := chain.FindAccountTxs(acctTestSender, 1, 300, 0, 5)
+	require.Nil(err)
+	assert.Equal([]string{"300/2", "300/1", "299/1", "298/2", "298/1"}, accountTxPositions(entries))
+	assert.Equal(blocks[299].Hash(), entries[0].BlockHash)
+
+	entries, err = chain.FindAccountTxs(acctTestSender, 1, 300, 1, 5)
+	require.Nil(err)
+	assert.Equal([]string{"297/1", "296/2", "296/1", "295/1", "294/2"}, accountTxPositions(entries))
+
+	entries, err = chain.FindAccountTxs(acctTestSender, 1, 300, 0, 1000)
+	require.Nil(err)
+	assert.Equal(450, len(entries))
+	assert.Equal("1/1", accountTxPositions(entries)[449])
+
+	entries, err = chain.FindAccountTxs(acctTestSender, 1, 300, 100, 5)
+	require.Nil(err)
+	assert.Empty(entries)
+
+	// Coinbase rewards, transaction outputs, and internal transfers recorded in the balance changes
+	entries, err = chain.FindAccountTxs(acctTestProposer, 10, 12, 0, 100)
+	require.Nil(err)
+	assert.Equal([]string{"12/0", "11/0", "10/0"}, accountTxPositions(entries))
+
+	entries, err = chain.FindAccountTxs(acctTestReceiver, 10, 12, 0, 100)
+	require.Nil(err)
+	assert.Equal([]string{"12/1", "11/1", "10/1"}, accountTxPositions(entries))
+
+	entries, err = chain.FindAccountTxs(acctTestRecipient, 10, 14, 0, 100)
+	require.Nil(err)
+	assert.Equal([]string{"14/2", "12/2", "10/2"}, accountTxPositions(entries))
+
+	// The contract is both the transaction target and in the balance changes, it's indexed once
+	entries, err = chain.FindAccountTxs(acctTestContract, 1, 300, 0, 1000)
+	require.Nil(err)
+	assert.Equal(150, len(entries))
+
+	entries, err = chain.FindAccountTxs(common.HexToAddress("0x2999"), 1, 300, 0, 100)
+	require.Nil(err)
+	assert.Empty(entries)
+
+	_, err = chain.FindAccountTxs(acctTestSender, 10, 1, 0, 100)
+	assert.NotNil(err)
+	_, err = chain.FindAccountTxs(acctTestSender, 1, 10, 0, 0)
+	assert.NotNil(err)
+
+	// Blocks already indexed are skipped
+	chain.AddAccountTxsToIndex(blocks[299])
+	entries, err = chain.FindAccountTxs(acctTestReceiver, 299, 300, 0, 100)
+	require.Nil(err)
+	assert.Equal([]string{"300/1", "299/1"}, accountTxPositions(entries))
+
+	// Reindexing replaces the entries of the reindexed blocks
+	chain.ResetAccountTxIndex()
+	for _, block := range blocks[99:] {
+		chain.AddAccountTxsToIndex(block)
+	}
+	status, ok = chain.GetAccountTxIndexStatus()
+	require.True(ok)
+	assert.Equal(AccountTxIndexStatus{FirstHeight: 100, LastHeight: 300}, status)
+	entries, err = chain.FindAccountTxs(acctTestReceiver, 1, 300, 0, 1000)
+	require.Nil(err)
+	assert.Equal(300, len(entries))
+	entries, err = chain.FindAccountTxs(acctTestSender, 1, 300, 0, 1000)
+	require.Nil(err)
+	assert.Equal(450, len(entries))
+}
@@ -0,0 +1,414 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/crypto"
+	"github.com/scripttoken/script/ledger/types"
+	"github.com/scripttoken/script/store"
+)
+
+// ---------------- Log Index ---------------
+//
+// The log index maps the address of the contracts emitting logs, and each of the first four topics
+// of the logs, to the positions of the logs in the finalized blocks. The positions of a key are
+// stored in ascending order in a list of chunks, so that the logs of a key in a height range are
+// found by reading the chunks backwards from the most recent one.
+
+// logIndexChunkSize is the maximum number of entries of a log index chunk
+const logIndexChunkSize = 256
+
+// maxIndexedTopics is the number of topic positions indexed, i.e. topic0 to topic3
+const maxIndexedTopics = 4
+
+const (
+	logIndexKindAddress = "a"
+	logIndexKindTopic   = "t"
+)
+
+// LogIndexEntry is the position of a log in a finalized block.
+type LogIndexEntry struct {
+	BlockHash   common.Hash
+	BlockHeight uint64
+	TxIndex     uint64 // index of the transaction in the block
+	LogIndex    uint64 // index of the log in the transaction receipt
+}
+
+// LogIndexStatus records the range of finalized blocks added to the log index.
+type LogIndexStatus struct {
+	FirstHeight uint64
+	LastHeight  uint64
+}
+
+// LogFilter selects the logs of the finalized blocks in a height range. A log matches the filter if
+// it is emitted by one of the addresses, and if for each topic position i its topic is one of Topics[i].
+// An empty address or topic list matches anything.
+type LogFilter struct {
+	FromHeight uint64
+	ToHeight   uint64
+	Addresses  []common.Address
+	Topics     [][]common.Hash
+}
+
+// FilteredLog is a log matching a log filter, together with its position.
+type FilteredLog struct {
+	LogIndexEntry
+	TxHash common.Hash
+	Log    *types.Log
+}
+
+type logIndexChunk struct {
+	Entries []LogIndexEntry
+}
+
+func logIndexStatusKey() common.Bytes {
+	return common.Bytes("logidx/status")
+}
+
+// logIndexHeadKey constructs the DB key for the number of chunks of the index key.
+func logIndexHeadKey(kind string, key []byte) common.Bytes {
+	ret := append(common.Bytes("logidx/"), kind...)
+	ret = append(ret, '/')
+	return append(ret, key...)
+}
+
+func logIndexChunkKey(kind string, key []byte, chunk uint64) common.Bytes {
+	ret := logIndexHeadKey(kind, key)
+	ret = append(ret, '/')
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], chunk)
+	return append(ret, buf[:]...)
+}
+
+func topicIndexKey(position int, topic common.Hash) []byte {
+	return append([]byte{byte(position)}, topic[:]...)
+}
+
+// GetLogIndexStatus returns the range of finalized blocks added to the log index, or false if the
+// log index is empty.
+func (ch *Chain) GetLogIndexStatus() (LogIndexStatus, bool) {
+	status := LogIndexStatus{}
+	err := ch.store.Get(logIndexStatusKey(), &status)
+	if err != nil {
+		if err != store.ErrKeyNotFound {
+			logger.Error(err)
+		}
+		return status, false
+	}
+	return status, true
+}
+
+// ResetLogIndex makes the next block added to the log index start a new indexed range. The entries
+// at and above the height of that block are overwritten as the blocks are indexed again.
+func (ch *Chain) ResetLogIndex() {
+	err := ch.store.Delete(logIndexStatusKey())
+	if err != nil && err != store.ErrKeyNotFound {
+		logger.Panic(err)
+	}
+}
+
+// AddLogsToIndex adds the logs of the given finalized block to the log index. Blocks must be added in
+// ascending height order, a block at or below the last indexed height is skipped.
+func (ch *Chain) AddLogsToIndex(block *core.ExtendedBlock) {
+	status, ok := ch.GetLogIndexStatus()
+	if ok && block.Height <= status.LastHeight {
+		return
+	}
+	if !ok {
+		status.FirstHeight = block.Height
+	} else if block.Height > status.LastHeight+1 {
+		logger.Warnf("Log index skips from height %v to %v, reindex the logs to fill in the gap", status.LastHeight, block.Height)
+	}
+
+	entries := make(map[string][]LogIndexEntry)
+	kinds := make(map[string]string)
+	add := func(kind string, key []byte, entry LogIndexEntry) {
+		mapKey := kind + string(key)
+		kinds[mapKey] = kind
+		entries[mapKey] = append(entries[mapKey], entry)
+	}
+
+	blockHash := block.Hash()
+	for txIdx, rawTx := range block.Txs {
+		receipt, found := ch.FindTxReceiptByHash(blockHash, crypto.Keccak256Hash(rawTx))
+		if !found {
+			continue
+		}
+		for logIdx, log := range receipt.Logs {
+			entry := LogIndexEntry{
+				BlockHash:   blockHash,
+				BlockHeight: block.Height,
+				TxIndex:     uint64(txIdx),
+				LogIndex:    uint64(logIdx),
+			}
+			add(logIndexKindAddress, log.Address[:], entry)
+			for pos, topic := range log.Topics {
+				if pos >= maxIndexedTopics {
+					break
+				}
+				add(logIndexKindTopic, topicIndexKey(pos, topic), entry)
+			}
+		}
+	}
+
+	// Sorted for the DB writes to be deterministic
+	mapKeys := make([]string, 0, len(entries))
+	for mapKey := range entries {
+		mapKeys = append(mapKeys, mapKey)
+	}
+	sort.Strings(mapKeys)
+	for _, mapKey := range mapKeys {
+		kind := kinds[mapKey]
+		ch.appendLogIndexEntries(kind, []byte(mapKey[len(kind):]), block.Height, entries[mapKey])
+	}
+
+	status.LastHeight = block.Height
+	err := ch.store.Put(logIndexStatusKey(), status)
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+// appendLogIndexEntries appends the entries of a block to the chunks of the index key, after
+// removing the entries at or above the block height left by a previous indexing.
+func (ch *Chain) appendLogIndexEntries(kind string, key []byte, height uint64, entries []LogIndexEntry) {
+	var numChunks uint64
+	err := ch.store.Get(logIndexHeadKey(kind, key), &numChunks)
+	if err != nil && err != store.ErrKeyNotFound {
+		logger.Panic(err)
+	}
+
+	chunk := &logIndexChunk{}
+	for numChunks > 0 {
+		err = ch.store.Get(logIndexChunkKey(kind, key, numChunks-1), chunk)
+		if err != nil {
+			logger.Panic(err)
+		}
+		numKept := sort.Search(len(chunk.Entries), func(i int) bool {
+			return chunk.Entries[i].BlockHeight >= height
+		})
+		chunk.Entries = chunk.Entries[:numKept]
+		if numKept > 0 || numChunks == 1 {
+			break
+		}
+		err = ch.store.Delete(logIndexChunkKey(kind, key, numChunks-1))
+		if err != nil {
+			logger.Panic(err)
+		}
+		numChunks--
+	}
+	if numChunks == 0 {
+		numChunks = 1
+	}
+
+	for _, entry := range entries {
+		if len(chunk.Entries) >= logIndexChunkSize {
+			err = ch.store.Put(logIndexChunkKey(kind, key, numChunks-1), chunk)
+			if err != nil {
+				logger.Panic(err)
+			}
+			chunk = &logIndexChunk{}
+			numChunks++
+		}
+		chunk.Entries = append(chunk.Entries, entry)
+	}
+	err = ch.store.Put(logIndexChunkKey(kind, key, numChunks-1), chunk)
+	if err != nil {
+		logger.Panic(err)
+	}
+	err = ch.store.Put(logIndexHeadKey(kind, key), numChunks)
+	if err != nil {
+		logger.Panic(err)
+	}
+}
+
+// findLogIndexEntries returns the entries of the index key in the height range, in ascending order.
+func (ch *Chain) findLogIndexEntries(kind string, key []byte, fromHeight, toHeight uint64) []LogIndexEntry {
+	var numChunks uint64
+	err := ch.store.Get(logIndexHeadKey(kind, key), &numChunks)
+	if err != nil {
+		if err != store.ErrKeyNotFound {
+			logger.Error(err)
+		}
+		return nil
+	}
+
+	ret := []LogIndexEntry{}
+	for c := numChunks; c > 0; c-- {
+		chunk := &logIndexChunk{}
+		err = ch.store.Get(logIndexChunkKey(kind, key, c-1), chunk)
+		if err != nil {
+			logger.Error(err)
+			break
+		}
+		for i := len(chunk.Entries) - 1; i >= 0; i-- {
+			entry := chunk.Entries[i]
+			if entry.BlockHeight >= fromHeight && entry.BlockHeight <= toHeight {
+				ret = append(ret, entry)
+			}
+		}
+		if len(chunk.Entries) > 0 && chunk.Entries[0].BlockHeight < fromHeight {
+			break
+		}
+	}
+	for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+		ret[i], ret[j] = ret[j], ret[i]
+	}
+	return ret
+}
+
+// FindLogs returns a page of the logs matching the filter from the log index, ordered by their positions.
+// The candidate logs are looked up with the addresses of the filter if any, otherwise with the most selective
+// topic position, and are then checked against the whole filter. Pages are numbered from 0.
+func (ch *Chain) FindLogs(filter *LogFilter, page, pageSize uint64) ([]*FilteredLog, error) {
+	if filter.FromHeight > filter.ToHeight {
+		return nil, fmt.Errorf("from height %v is greater than to height %v", filter.FromHeight, filter.ToHeight)
+	}
+	if len(filter.Topics) > maxIndexedTopics {
+		return nil, fmt.Errorf("can't filter more than %v topics", maxIndexedTopics)
+	}
+	if pageSize == 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+
+	var candidates []LogIndexEntry
+	if len(filter.Addresses) > 0 {
+		for _, addr := range filter.Addresses {
+			candidates = append(candidates, ch.findLogIndexEntries(logIndexKindAddress, addr[:], filter.FromHeight, filter.ToHeight)...)
+		}
+	} else {
+		position := -1
+		for pos, topics := range filter.Topics {
+			if len(topics) > 0 && (position < 0 || len(topics) < len(filter.Topics[position])) {
+				position = pos
+			}
+		}
+		if position < 0 {
+			return nil, fmt.Errorf("at least one address or topic must be specified")
+		}
+		for _, topic := range filter.Topics[position] {
+			candidates = append(candidates, ch.findLogIndexEntries(logIndexKindTopic, topicIndexKey(position, topic), filter.FromHeight, filter.ToHeight)...)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.BlockHeight != b.BlockHeight {
+			return a.BlockHeight < b.BlockHeight
+		}
+		if a.TxIndex != b.TxIndex {
+			return a.TxIndex < b.TxIndex
+		}
+		return a.LogIndex < b.LogIndex
+	})
+
+	ret := []*FilteredLog{}
+	numSkipped := uint64(0)
+	toSkip := page * pageSize
+	loader := &indexedLogLoader{chain: ch}
+	for i, entry := range candidates {
+		if i > 0 && candidates[i-1] == entry {
+			continue // the same log found with several addresses or topics
+		}
+		log, err := loader.load(entry)
+		if err != nil {
+			return nil, err
+		}
+		if !filter.Matches(log.Log) {
+			continue
+		}
+		if numSkipped < toSkip {
+			numSkipped++
+			continue
+		}
+		ret = append(ret, log)
+		if uint64(len(ret)) == pageSize {
+			break
+		}
+	}
+	return ret, nil
+}
+
+// indexedLogLoader loads the logs of the log index entries. Since the entries are loaded in ascending
+// order of their positions, it keeps the last block and receipt loaded, so that each block and each
+// receipt is only loaded once.
+type indexedLogLoader struct {
+	chain     *Chain
+	blockHash common.Hash
+	block     *core.ExtendedBlock
+	txIndex   uint64
+	txHash    common.Hash
+	receipt   *TxReceiptEntry
+}
+
+func (loader *indexedLogLoader) load(entry LogIndexEntry) (*FilteredLog, error) {
+	if loader.block == nil || loader.blockHash != entry.BlockHash {
+		block, err := loader.chain.FindBlock(entry.BlockHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load block %v: %v", entry.BlockHash.Hex(), err)
+		}
+		loader.blockHash = entry.BlockHash
+		loader.block = block
+		loader.receipt = nil
+	}
+	if loader.receipt == nil || loader.txIndex != entry.TxIndex {
+		if entry.TxIndex >= uint64(len(loader.block.Txs)) {
+			return nil, fmt.Errorf("transaction %v not found in block %v", entry.TxIndex, entry.BlockHash.Hex())
+		}
+		txHash := crypto.Keccak256Hash(loader.block.Txs[entry.TxIndex])
+		receipt, found := loader.chain.FindTxReceiptByHash(entry.BlockHash, txHash)
+		if !found {
+			return nil, fmt.Errorf("receipt of transaction %v not found", txHash.Hex())
+		}
+		loader.txIndex = entry.TxIndex
+		loader.txHash = txHash
+		loader.receipt = receipt
+	}
+	if entry.LogIndex >= uint64(len(loader.receipt.Logs)) {
+		return nil, fmt.Errorf("log %v of transaction %v not found", entry.LogIndex, loader.txHash.Hex())
+	}
+	return &FilteredLog{
+		LogIndexEntry: entry,
+		TxHash:        loader.txHash,
+		Log:           loader.receipt.Logs[entry.LogIndex],
+	}, nil
+}
+
+// Matches returns whether the log is emitted by one of the filter addresses and has the filter topics.
+func (filter *LogFilter) Matches(log *types.Log) bool {
+	if len(filter.Addresses) > 0 {
+		found := false
+		for _, addr := range filter.Addresses {
+			if addr == log.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for pos, topics := range filter.Topics {
+		if len(topics) == 0 {
+			continue
+		}
+		if pos >= len(log.Topics) {
+			return false
+		}
+		found := false
+		for _, topic := range topics {
+			if topic == log.Topics[pos] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/crypto"
+	"github.com/scripttoken/script/ledger/types"
+)
+
+var (
+	logTestTokenA   = common.HexToAddress("0xa1")
+	logTestTokenB   = common.HexToAddress("0xb2")
+	logTestTransfer = common.HexToHash("0xddf252ad")
+	logTestApproval = common.HexToHash("0x8c5be1e5")
+)
+
+// addLogTestBlock adds a finalized block at the given height with one smart contract transaction, whose
+// receipt has a Transfer log of token A, and a log of token B on the even heights
+func addLogTestBlock(t *testing.T, chain *Chain, height uint64) *core.ExtendedBlock {
+	tx := &types.SmartContractTx{
+		From: types.TxInput{
+			Address:  common.HexToAddress("0x1000"),
+			Sequence: height,
+		},
+		GasLimit: 100000,
+		GasPrice: big.NewInt(1),
+	}
+	privKey, _, err := crypto.GenerateKeyPair()
+	require.Nil(t, err)
+	tx.From.Signature, err = privKey.Sign(tx.SignBytes(chain.ChainID))
+	require.Nil(t, err)
+	raw, err := types.TxToBytes(tx)
+	require.Nil(t, err)
+
+	block := core.CreateTestBlock(fmt.Sprintf("b%x", height), "")
+	block.Height = height
+	block.Txs = []common.Bytes{raw}
+	block.UpdateHash()
+	eb, err := chain.AddBlock(block)
+	require.Nil(t, err)
+
+	holder := common.BigToHash(new(big.Int).SetUint64(height))
+	logs := []*types.Log{
+		{Address: logTestTokenA, Topics: []common.Hash{logTestTransfer, holder}},
+	}
+	if height%2 == 0 {
+		logs = append(logs, &types.Log{Address: logTestTokenB, Topics: []common.Hash{logTestApproval, holder}})
+	}
+	chain.AddTxReceipt(block, tx, logs, nil, nil, common.Address{}, 0, nil)
+	return eb
+}
+
+func logHeights(logs []*FilteredLog) []uint64 {
+	heights := []uint64{}
+	for _, log := range logs {
+		heights = append(heights, log.BlockHeight)
+	}
+	return heights
+}
+
+func heightRange(from, to, step uint64) []uint64 {
+	heights := []uint64{}
+	for h := from; h <= to; h += step {
+		heights = append(heights, h)
+	}
+	return heights
+}
+
+func TestLogIndex(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	core.ResetTestBlocks()
+	chain := CreateTestChain()
+	blocks := []*core.ExtendedBlock{}
+	for height := uint64(1); height <= 600; height++ {
+		block := addLogTestBlock(t, chain, height)
+		blocks = append(blocks, block)
+		chain.AddLogsToIndex(block)
+	}
+	status, ok := chain.GetLogIndexStatus()
+	require.True(ok)
+	assert.Equal(LogIndexStatus{FirstHeight: 1, LastHeight: 600}, status)
+
+	// The entries of token A span several chunks
+	logs, err := chain.FindLogs(&LogFilter{FromHeight: 100, ToHeight: 400, Addresses: []common.Address{logTestTokenA}}, 0, 1000)
+	require.Nil(err)
+	assert.Equal(heightRange(100, 400, 1), logHeights(logs))
+	assert.Equal(logTestTokenA, logs[0].Log.Address)
+	assert.Equal(uint64(0), logs[0].TxIndex)
+	assert.Equal(uint64(0), logs[0].LogIndex)
+	assert.Equal(blocks[99].Hash(), logs[0].BlockHash)
+
+	logs, err = chain.FindLogs(&LogFilter{FromHeight: 1, ToHeight: 600, Topics: [][]common.Hash{{logTestApproval}}}, 0, 1000)
+	require.Nil(err)
+	assert.Equal(heightRange(2, 600, 2), logHeights(logs))
+	assert.Equal(uint64(1), logs[0].LogIndex)
+
+	// Looked up by address, filtered by topic
+	holder := common.BigToHash(big.NewInt(42))
+	logs, err = chain.FindLogs(&LogFilter{FromHeight: 1, ToHeight: 600, Addresses: []common.Address{logTestTokenA, logTestTokenB},
+		Topics: [][]common.Hash{{}, {holder}}}, 0, 1000)
+	require.Nil(err)
+	assert.Equal([]uint64{42, 42}, logHeights(logs))
+	assert.Equal(logTestTokenA, logs[0].Log.Address)
+	assert.Equal(logTestTokenB, logs[1].Log.Address)
+
+	// A log found through several topics is returned once
+	logs, err = chain.FindLogs(&LogFilter{FromHeight: 10, ToHeight: 13, Topics: [][]common.Hash{{logTestTransfer, logTestApproval}}}, 0, 1000)
+	require.Nil(err)
+	assert.Equal([]uint64{10, 10, 11, 12, 12, 13}, logHeights(logs))
+
+	logs, err = chain.FindLogs(&LogFilter{FromHeight: 1, ToHeight: 600, Addresses: []common.Address{logTestTokenB},
+		Topics: [][]common.Hash{{logTestTransfer}}}, 0, 1000)
+	require.Nil(err)
+	assert.Empty(logs)
+
+	_, err = chain.FindLogs(&LogFilter{FromHeight: 1, ToHeight: 600}, 0, 1000)
+	assert.NotNil(err)
+	_, err = chain.FindLogs(&LogFilter{FromHeight: 10, ToHeight: 1, Addresses: []common.Address{logTestTokenA}}, 0, 1000)
+	assert.NotNil(err)
+
+	// Blocks already indexed are skipped
+	chain.AddLogsToIndex(blocks[599])
+	logs, err = chain.FindLogs(&LogFilter{FromHeight: 590, ToHeight: 600, Addresses: []common.Address{logTestTokenA}}, 0, 1000)
+	require.Nil(err)
+	assert.Equal(heightRange(590, 600, 1), logHeights(logs))
+
+	// Reindexing replaces the entries of the reindexed blocks
+	chain.ResetLogIndex()
+	for _, block := range blocks[249:] {
+		chain.AddLogsToIndex(block)
+	}
+	status, ok = chain.GetLogIndexStatus()
+	require.True(ok)
+	assert.Equal(LogIndexStatus{FirstHeight: 250, LastHeight: 600}, status)
+	logs, err = chain.FindLogs(&LogFilter{FromHeight: 1, ToHeight: 600, Addresses: []common.Address{logTestTokenA}}, 0, 1000)
+	require.Nil(err)
+	assert.Equal(heightRange(1, 600, 1), logHeights(logs))
+	logs, err = chain.FindLogs(&LogFilter{FromHeight: 1, ToHeight: 600, Addresses: []common.Address{logTestTokenB}}, 0, 1000)
+	require.Nil(err)
+	assert.Equal(heightRange(2, 600, 2), logHeights(logs))
+
+	// The logs are paginated, each page following the previous one
+	paged := []uint64{}
+	for page := uint64(0); ; page++ {
+		logs, err = chain.FindLogs(&LogFilter{FromHeight: 1, ToHeight: 600, Topics: [][]common.Hash{{logTestTransfer, logTestApproval}}}, page, 256)
+		require.Nil(err)
+		paged = append(paged, logHeights(logs)...)
+		if len(logs) < 256 {
+			break
+		}
+	}
+	assert.Equal(900, len(paged))
+	assert.Equal(uint64(1), paged[0])
+	assert.Equal(uint64(600), paged[899])
+	logs, err = chain.FindLogs(&LogFilter{FromHeight: 1, ToHeight: 600, Addresses: []common.Address{logTestTokenA}}, 1, 100)
+	require.Nil(err)
+	assert.Equal(heightRange(101, 200, 1), logHeights(logs))
+	_, err = chain.FindLogs(&LogFilter{FromHeight: 1, ToHeight: 600, Addresses: []common.Address{logTestTokenA}}, 0, 0)
+	assert.NotNil(err)
+}
@@ -235,11 +235,22 @@ func CalcEthTxHash(block *core.ExtendedBlock, rawTxBytes []byte) (common.Hash, e
 		return common.Hash{}, err
 	}
 
+	ethChainID := int64(viper.GetUint64(common.CfgGenesisEthChainID))
+	if dtx, ok := tx.(*types.SmartContractTxV2); ok {
+		// A dynamic fee transaction is translated from a typed ETH transaction, unless signed natively
+		ethTx, err := dtx.RecoverEthTx(ethChainID)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("not an ETH smart contract transaction")
+		}
+		ethTxHash := types.EthTxHash(ethTx)
+		logger.Debugf("ethTxHash: %v", ethTxHash.Hex())
+		return ethTxHash, nil
+	}
+
 	sctx, ok := tx.(*types.SmartContractTx)
 	if !ok {
 		return common.Hash{}, fmt.Errorf("not a smart contract transaction") // not a smart contract tx, skip ETH tx insertion
 	}
-	ethChainID := int64(viper.GetUint64(common.CfgGenesisEthChainID))
 	ethSigningHash := sctx.EthSigningHash(block.ChainID, ethChainID, block.Height)
 	err = crypto.ValidateEthSignature(sctx.From.Address, ethSigningHash, sctx.From.Signature)
 	if err != nil {
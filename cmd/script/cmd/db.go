@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/scripttoken/script/blockchain"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/consensus"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/rlp"
+	"github.com/scripttoken/script/store/database"
+	"github.com/scripttoken/script/store/database/backend"
+	"github.com/scripttoken/script/store/kvstore"
+	"github.com/scripttoken/script/store/rollingdb"
+	"github.com/scripttoken/script/store/verifier"
+)
+
+var verifyHeight uint64
+var verifyStateHash string
+var verifyCheckRefs bool
+var reindexFromHeight uint64
+var reindexToHeight uint64
+
+// dbCmd represents the db command
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Inspect and maintain the node database.",
+}
+
+// dbVerifyCmd represents the db verify command
+var dbVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the integrity of the state DB.",
+	Long: `Walks the account trie and every account storage trie of a state, checks
+that all the nodes are present and match their hashes, and reports the
+missing or corrupted nodes. By default the state of the last finalized
+block is verified. The node must be stopped while running this command.`,
+	Run: runDBVerify,
+}
+
+// dbReindexLogsCmd represents the db reindex-logs command
+var dbReindexLogsCmd = &cobra.Command{
+	Use:   "reindex-logs",
+	Short: "Rebuild the contract log index.",
+	Long: `Adds the logs of the finalized blocks in the height range to the log index
+queried by the log RPCs, replacing the entries previously indexed for these
+blocks. By default the logs are indexed from the snapshot the node was started
+from up to the last finalized block. The node must be stopped while running
+this command.`,
+	Run: runDBReindexLogs,
+}
+
+// dbReindexAccountTxsCmd represents the db reindex-account-txs command
+var dbReindexAccountTxsCmd = &cobra.Command{
+	Use:   "reindex-account-txs",
+	Short: "Rebuild the account transaction index.",
+	Long: `Adds the transactions of the finalized blocks in the height range to the
+account transaction index queried by the account transaction RPCs, replacing
+the entries previously indexed for these blocks. By default the transactions
+are indexed from the snapshot the node was started from up to the last
+finalized block. The node must be stopped while running this command.`,
+	Run: runDBReindexAccountTxs,
+}
+
+func init() {
+	for _, reindexCmd := range []*cobra.Command{dbReindexLogsCmd, dbReindexAccountTxsCmd} {
+		reindexCmd.Flags().Uint64Var(&reindexFromHeight, "from", 0, "height of the first block to index")
+		reindexCmd.Flags().Uint64Var(&reindexToHeight, "to", 0, "height of the last block to index, 0 for the last finalized block")
+	}
+
+	dbVerifyCmd.Flags().Uint64Var(&verifyHeight, "height", 0, "height of the finalized block whose state is verified")
+	dbVerifyCmd.Flags().StringVar(&verifyStateHash, "state_hash", "", "state hash to verify, overrides --height")
+	dbVerifyCmd.Flags().BoolVar(&verifyCheckRefs, "check_refs", true, "check the node ref counts when the DB layers do not roll")
+
+	dbCmd.AddCommand(dbVerifyCmd)
+	dbCmd.AddCommand(dbReindexLogsCmd)
+	dbCmd.AddCommand(dbReindexAccountTxsCmd)
+	RootCmd.AddCommand(dbCmd)
+}
+
+func runDBVerify(cmd *cobra.Command, args []string) {
+	db, rdb := openNodeDB()
+	defer rdb.Close()
+
+	var stateHash common.Hash
+	if verifyStateHash != "" {
+		stateHash = common.HexToHash(verifyStateHash)
+	} else {
+		block, err := findVerifyBlock(db, verifyHeight)
+		if err != nil {
+			log.Fatalf("Failed to find the block to verify: %v", err)
+		}
+		fmt.Printf("Verifying the state of block %v, height: %v\n", block.Hash().Hex(), block.Height)
+		stateHash = block.StateHash
+	}
+	fmt.Printf("Verifying state %v\n", stateHash.Hex())
+
+	var refDB database.Database
+	if verifyCheckRefs && !rollingdb.IsRollingEnabled() {
+		refDB = db
+	}
+	report, err := verifier.NewStateVerifier(rdb, refDB).Verify(context.Background(), stateHash)
+	if err != nil {
+		log.Fatalf("Failed to verify state %v: %v", stateHash.Hex(), err)
+	}
+
+	fmt.Printf("Nodes: %v, accounts: %v, storage tries: %v, elapsed: %v\n",
+		report.NumNodes, report.NumAccounts, report.NumStorageTries, report.Elapsed)
+	for _, hash := range report.MissingNodes {
+		fmt.Printf("Missing node: %v\n", hash.Hex())
+	}
+	for _, hash := range report.CorruptedNodes {
+		fmt.Printf("Corrupted node: %v\n", hash.Hex())
+	}
+	for _, hash := range report.UnreferencedNodes {
+		fmt.Printf("Unreferenced node: %v\n", hash.Hex())
+	}
+	if !report.IsHealthy() {
+		fmt.Printf("State %v is incomplete: %v missing, %v corrupted nodes\n",
+			stateHash.Hex(), len(report.MissingNodes), len(report.CorruptedNodes))
+		rdb.Close()
+		os.Exit(1)
+	}
+	fmt.Printf("State %v is complete\n", stateHash.Hex())
+}
+
+func runDBReindexLogs(cmd *cobra.Command, args []string) {
+	reindexFinalizedBlocks("logs", (*blockchain.Chain).ResetLogIndex, (*blockchain.Chain).AddLogsToIndex)
+}
+
+func runDBReindexAccountTxs(cmd *cobra.Command, args []string) {
+	reindexFinalizedBlocks("account txs", (*blockchain.Chain).ResetAccountTxIndex, (*blockchain.Chain).AddAccountTxsToIndex)
+}
+
+// reindexFinalizedBlocks resets an index of the chain, and adds the finalized blocks in the
+// reindex height range to it
+func reindexFinalizedBlocks(what string, reset func(*blockchain.Chain), add func(*blockchain.Chain, *core.ExtendedBlock)) {
+	db, rdb := openNodeDB()
+	defer rdb.Close()
+
+	rootHeader, err := loadRootHeader(db)
+	if err != nil {
+		log.Fatalf("Failed to load the root block: %v", err)
+	}
+	store := kvstore.NewKVStore(db)
+	chain := blockchain.NewChain(rootHeader.ChainID, store, &core.Block{BlockHeader: rootHeader})
+	lastFinalizedBlock := consensus.NewState(store, chain, nil).GetLastFinalizedBlock()
+	if lastFinalizedBlock == nil {
+		log.Fatalf("No finalized block")
+	}
+
+	fromHeight := reindexFromHeight
+	if fromHeight < rootHeader.Height {
+		fromHeight = rootHeader.Height
+	}
+	toHeight := reindexToHeight
+	if toHeight == 0 || toHeight > lastFinalizedBlock.Height {
+		toHeight = lastFinalizedBlock.Height
+	}
+	if fromHeight > toHeight {
+		log.Fatalf("Invalid height range: %v to %v", fromHeight, toHeight)
+	}
+	fmt.Printf("Indexing the %v from height %v to %v\n", what, fromHeight, toHeight)
+
+	reset(chain)
+	numBlocks := 0
+	for height := fromHeight; height <= toHeight; height++ {
+		block := findFinalizedBlock(chain, height)
+		if block == nil {
+			continue
+		}
+		add(chain, block)
+		numBlocks++
+		if numBlocks%10000 == 0 {
+			fmt.Printf("Indexed the %v of %v blocks, height: %v\n", what, numBlocks, height)
+		}
+	}
+	fmt.Printf("Indexed the %v of %v blocks\n", what, numBlocks)
+}
+
+// nodeDataPath returns the directory of the node database
+func nodeDataPath() string {
+	dbPath := viper.GetString(common.CfgDataPath)
+	if dbPath == "" {
+		dbPath = cfgPath
+	}
+	return dbPath
+}
+
+// openNodeDB opens the database of the stopped node
+func openNodeDB() (*backend.LDBDatabase, *rollingdb.RollingDB) {
+	dbPath := nodeDataPath()
+
+	mainDBPath := path.Join(dbPath, "db", "main")
+	refDBPath := path.Join(dbPath, "db", "ref")
+	db, err := backend.NewLDBDatabase(mainDBPath, refDBPath,
+		viper.GetInt(common.CfgStorageLevelDBCacheSize),
+		viper.GetInt(common.CfgStorageLevelDBHandles))
+	if err != nil {
+		log.Fatalf("Failed to connect to the db. main: %v, ref: %v, err: %v",
+			mainDBPath, refDBPath, err)
+	}
+	return db, rollingdb.NewRollingDB(dbPath, db)
+}
+
+// loadRootHeader returns the header of the snapshot block the node was started from
+func loadRootHeader(db database.Database) (*core.BlockHeader, error) {
+	raw, err := db.Get([]byte("/snapshot_blockheader"))
+	if err != nil {
+		return nil, fmt.Errorf("snapshot header not found, has the node been started: %v", err)
+	}
+	rootHeader := &core.BlockHeader{}
+	if err := rlp.DecodeBytes(raw, rootHeader); err != nil {
+		return nil, err
+	}
+	return rootHeader, nil
+}
+
+// findVerifyBlock returns the finalized block at the given height, or the last
+// finalized block if height is 0.
+func findVerifyBlock(db database.Database, height uint64) (*core.ExtendedBlock, error) {
+	rootHeader, err := loadRootHeader(db)
+	if err != nil {
+		return nil, err
+	}
+
+	store := kvstore.NewKVStore(db)
+	chain := blockchain.NewChain(rootHeader.ChainID, store, &core.Block{BlockHeader: rootHeader})
+	if height == 0 {
+		block := consensus.NewState(store, chain, nil).GetLastFinalizedBlock()
+		if block == nil {
+			return nil, fmt.Errorf("no finalized block")
+		}
+		return block, nil
+	}
+	if block := findFinalizedBlock(chain, height); block != nil {
+		return block, nil
+	}
+	return nil, fmt.Errorf("no finalized block at height %v", height)
+}
+
+// findFinalizedBlock returns the finalized block at the given height, or nil if not found.
+func findFinalizedBlock(chain *blockchain.Chain, height uint64) *core.ExtendedBlock {
+	for _, block := range chain.FindBlocksByHeight(height) {
+		if block.Status.IsFinalized() {
+			return block
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/scripttoken/script/blockchain"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/consensus"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/crypto"
+	dp "github.com/scripttoken/script/dispatcher"
+	ld "github.com/scripttoken/script/ledger"
+	mp "github.com/scripttoken/script/mempool"
+	"github.com/scripttoken/script/snapshot"
+	"github.com/scripttoken/script/store/kvstore"
+)
+
+var importTrusted bool
+var importVerifyOnly bool
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import [archive_dir...]",
+	Short: "Import blocks from block archives.",
+	Long: `Replays the finalized blocks of one or more block archives (see
+"scriptcli backup archive") on top of the local chain, without network access.
+Each block is fully validated by default, or only checked against its parent
+and its state root with --trusted. The archives are imported in ascending
+height order, and the blocks already finalized locally are skipped. The node
+must be stopped while running this command.`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runImport,
+}
+
+func init() {
+	importCmd.Flags().BoolVar(&importTrusted, "trusted", false, "skip the block and transaction validations, only check the chaining and the state roots")
+	importCmd.Flags().BoolVar(&importVerifyOnly, "verify_only", false, "only verify the integrity of the archives")
+	RootCmd.AddCommand(importCmd)
+}
+
+func runImport(cmd *cobra.Command, args []string) {
+	if importVerifyOnly {
+		for _, archiveDir := range args {
+			index, err := snapshot.VerifyChainArchive(archiveDir)
+			if err != nil {
+				log.Fatalf("Failed to verify archive %v: %v", archiveDir, err)
+			}
+			fmt.Printf("Verified archive %v, height %v to %v\n", archiveDir, index.StartHeight, index.EndHeight)
+		}
+		return
+	}
+
+	// The segments are verified while being imported
+	archives := make([]*snapshot.ArchiveIndex, len(args))
+	for i, archiveDir := range args {
+		index, err := snapshot.LoadArchiveIndex(archiveDir)
+		if err != nil {
+			log.Fatalf("Failed to load archive %v: %v", archiveDir, err)
+		}
+		archives[i] = index
+	}
+	sort.Sort(archivesByHeight{args, archives})
+
+	err := common.Initialize_hf_values(path.Join(nodeDataPath(), "hf.cfg"))
+	if err != nil {
+		log.Fatalf("Failed to initialize HF values: %v", err)
+	}
+
+	db, rdb := openNodeDB()
+	defer rdb.Close()
+
+	rootHeader, err := loadRootHeader(db)
+	if err != nil {
+		log.Fatalf("Failed to load the root block: %v", err)
+	}
+	store := kvstore.NewKVStore(db)
+	chain := blockchain.NewChain(rootHeader.ChainID, store, &core.Block{BlockHeader: rootHeader})
+	rdb.SetChain(chain)
+
+	// The components are only used to validate and apply the blocks, the key never signs
+	privKey, _, err := crypto.GenerateKeyPair()
+	if err != nil {
+		log.Fatalf("Failed to generate key: %v", err)
+	}
+	validatorManager := consensus.NewRotatingValidatorManager()
+	dispatcher := dp.NewDispatcher(nil)
+	engine := consensus.NewConsensusEngine(privKey, store, chain, dispatcher, validatorManager)
+	mempool := mp.CreateMempool(dispatcher, engine)
+	ledger := ld.NewLedger(chain.ChainID, rdb, rdb, chain, engine, validatorManager, mempool)
+	validatorManager.SetConsensusEngine(engine)
+	engine.SetLedger(ledger)
+	mempool.SetLedger(ledger)
+
+	importer := snapshot.NewChainImporter(chain, ledger, validatorManager, engine.State(), importTrusted)
+	for _, archiveDir := range args {
+		if err := importer.ImportArchive(archiveDir); err != nil {
+			imported, _, lastBlock := importer.Summary()
+			if lastBlock != nil {
+				fmt.Printf("Imported %v blocks up to height %v\n", imported, lastBlock.Height)
+			}
+			log.Fatalf("Failed to import archive %v: %v", archiveDir, err)
+		}
+	}
+
+	imported, skipped, lastBlock := importer.Summary()
+	fmt.Printf("Imported %v blocks, skipped %v blocks already finalized\n", imported, skipped)
+	if lastBlock != nil {
+		fmt.Printf("Last finalized block %v, height: %v\n", lastBlock.Hash().Hex(), lastBlock.Height)
+	}
+}
+
+type archivesByHeight struct {
+	dirs    []string
+	indexes []*snapshot.ArchiveIndex
+}
+
+func (a archivesByHeight) Len() int { return len(a.dirs) }
+func (a archivesByHeight) Less(i, j int) bool {
+	return a.indexes[i].StartHeight < a.indexes[j].StartHeight
+}
+func (a archivesByHeight) Swap(i, j int) {
+	a.dirs[i], a.dirs[j] = a.dirs[j], a.dirs[i]
+	a.indexes[i], a.indexes[j] = a.indexes[j], a.indexes[i]
+}
@@ -20,7 +20,11 @@ import (
 	"github.com/scripttoken/script/core"
 	"github.com/scripttoken/script/crypto"
 	"github.com/scripttoken/script/node"
+	"github.com/scripttoken/script/p2p"
 	msg "github.com/scripttoken/script/p2p/messenger"
+	"github.com/scripttoken/script/p2p/permission"
+	"github.com/scripttoken/script/p2p/reputation"
+	p2ptypes "github.com/scripttoken/script/p2p/types"
 	msgl "github.com/scripttoken/script/p2pl/messenger"
 	"github.com/scripttoken/script/rlp"
 	"github.com/scripttoken/script/snapshot"
@@ -80,6 +84,10 @@ func runStart(cmd *cobra.Command, args []string) {
 	if len(snapshotPath) == 0 {
 		snapshotPath = path.Join(cfgPath, "snapshot")
 	}
+	snapshotDir := viper.GetString(common.CfgSnapshotDir)
+	if len(snapshotDir) == 0 {
+		snapshotDir = path.Join(cfgPath, "backup", "snapshot")
+	}
 
 	var root *core.Block
 	var snapshotBlockHeader *core.BlockHeader
@@ -143,34 +151,71 @@ func runStart(cmd *cobra.Command, args []string) {
 	// trap Ctrl+C and call cancel on the context
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// Peer reputation shared by both P2P stacks, the bans are persisted across restarts
+	peerReputation := reputation.NewReputationManager(path.Join(cfgPath, "peer_bans.json"))
+
+	// Permissioned mode, only the nodes in the allowlist can connect
+	var peerAllowlist *permission.Allowlist
+	if viper.GetBool(common.CfgP2PPermissionedEnabled) {
+		allowlistPath := viper.GetString(common.CfgP2PPermissionedAllowlistPath)
+		if allowlistPath == "" {
+			allowlistPath = path.Join(cfgPath, "allowlist.json")
+		}
+		peerAllowlist = permission.NewAllowlist(allowlistPath)
+		if _, err := peerAllowlist.Reload(); err != nil {
+			log.Fatalf("Failed to load the peer allowlist %v: %v", allowlistPath, err)
+		}
+	}
+
 	p2pOpt := common.P2POptEnum(viper.GetInt(common.CfgP2POpt))
 	if p2pOpt != common.P2POptOld {
 		port := viper.GetInt(common.CfgP2PLPort)
 		peerSeeds := strings.FieldsFunc(viper.GetString(common.CfgLibP2PSeeds), f)
 		seedPeerOnly := viper.GetBool(common.CfgP2PSeedPeerOnly)
 		network = newMessenger(privKey, peerSeeds, port, seedPeerOnly, ctx)
+		network.SetReputationManager(peerReputation)
+		if peerAllowlist != nil {
+			network.SetAllowlist(peerAllowlist)
+		}
 	}
 	if p2pOpt != common.P2POptLibp2p {
 		portOld := viper.GetInt(common.CfgP2PPort)
 		peerSeedsOld := strings.FieldsFunc(viper.GetString(common.CfgP2PSeeds), f)
 		networkOld = newMessengerOld(privKey, peerSeedsOld, portOld, ctx)
+		networkOld.SetReputationManager(peerReputation)
+		if peerAllowlist != nil {
+			networkOld.SetAllowlist(peerAllowlist)
+		}
 	}
 
+	// The old stack is the primary transport, the peer queries are answered by it if enabled
+	networks := p2p.NewRegistry()
+	networks.Register(p2ptypes.StackP2P, networkOld)
+	networks.Register(p2ptypes.StackP2PL, network)
+
 	params := &node.Params{
 		ChainID:             root.ChainID,
 		EthChainID:          int64(viper.GetUint64(common.CfgGenesisEthChainID)),
 		PrivateKey:          privKey,
 		Root:                root,
-		NetworkOld:          networkOld,
-		Network:             network,
+		Network:             networks,
+		PeerReputation:      peerReputation,
+		PeerAllowlist:       peerAllowlist,
 		DB:                  db,
 		RollingDB:           rdb,
 		SnapshotPath:        snapshotPath,
+		SnapshotDir:         snapshotDir,
 		ChainImportDirPath:  chainImportDirPath,
 		ChainCorrectionPath: chainCorrectionPath,
 	}
 
 	n := node.NewNode(params)
+	if networkOld != nil {
+		networkOld.SetHandshakeInfoProvider(n.HandshakeInfo)
+	}
+	if network != nil {
+		network.SetHandshakeInfoProvider(n.HandshakeInfo)
+	}
 
 	c := make(chan os.Signal)
 	signal.Notify(c, os.Interrupt)
@@ -179,7 +224,7 @@ func runStart(cmd *cobra.Command, args []string) {
 		<-c
 		signal.Stop(c)
 		cancel()
-		network.Stop()
+		networks.Stop()
 		// Wait at most 5 seconds before forcefully shutting down.
 		<-time.After(time.Duration(5) * time.Second)
 		close(done)
@@ -0,0 +1,62 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/scripttoken/script/cmd/scriptcli/cmd/utils"
+	"github.com/scripttoken/script/rpc"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+var (
+	blocksPerSegmentFlag uint64
+)
+
+// archiveCmd represents the block archive backup command.
+// Example:
+//		scriptcli backup archive --start=1 --end=100000 --compression=gzip
+var archiveCmd = &cobra.Command{
+	Use:     "archive",
+	Short:   "backup block archive",
+	Long:    `Backup the finalized blocks into a block archive, which can be imported with "script import".`,
+	Example: `scriptcli backup archive --start=1 --end=100000 --compression=gzip`,
+	Run:     doArchiveCmd,
+}
+
+func doArchiveCmd(cmd *cobra.Command, args []string) {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("script.BackupChainArchive", rpc.BackupChainArchiveArgs{
+		Start:            startFlag,
+		End:              endFlag,
+		Config:           configFlag,
+		Compression:      compressionFlag,
+		BlocksPerSegment: blocksPerSegmentFlag,
+	})
+	if err != nil {
+		utils.Error("Failed to get backup archive call details: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get backup archive res details: %v\n", res.Error)
+	}
+	json, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n%v\n", err, string(json))
+	}
+	fmt.Println(string(json))
+}
+
+func init() {
+	archiveCmd.Flags().Uint64Var(&startFlag, "start", 0, "Starting block height")
+	archiveCmd.Flags().Uint64Var(&endFlag, "end", 0, "Ending block height")
+	archiveCmd.Flags().StringVar(&configFlag, "config", "", "Config dir")
+	archiveCmd.Flags().StringVar(&compressionFlag, "compression", "", "Segment compression.(none, gzip, snappy or zstd. Default is none)")
+	archiveCmd.Flags().Uint64Var(&blocksPerSegmentFlag, "blocks_per_segment", 0, "Number of blocks per segment. Default is 10000")
+	archiveCmd.MarkFlagRequired("start")
+	archiveCmd.MarkFlagRequired("end")
+	archiveCmd.MarkFlagRequired("config")
+}
@@ -3,10 +3,14 @@ package backup
 import "github.com/spf13/cobra"
 
 var (
-	heightFlag  uint64
-	versionFlag uint64
-	hashFlag    string
-	configFlag  string
+	heightFlag      uint64
+	versionFlag     uint64
+	hashFlag        string
+	configFlag      string
+	compressionFlag string
+	chunkSizeFlag   uint64
+	baseFlag        string
+	resumeFlag      bool
 )
 
 // BackupCmd represents the backup command
@@ -20,4 +24,5 @@ func init() {
 	BackupCmd.AddCommand(chainCmd)
 	BackupCmd.AddCommand(snapshotCmd)
 	BackupCmd.AddCommand(chainCorrectionCmd)
+	BackupCmd.AddCommand(archiveCmd)
 }
@@ -15,6 +15,7 @@ import (
 // snapshotCmd represents the snapshot backup command.
 // Example:
 //		scriptcli backup snapshot
+//		scriptcli backup snapshot --version=5 --compression=gzip --base=<base_snapshot_dir>
 var snapshotCmd = &cobra.Command{
 	Use:     "snapshot",
 	Short:   "backup snapshot",
@@ -26,7 +27,15 @@ var snapshotCmd = &cobra.Command{
 func doSnapshotCmd(cmd *cobra.Command, args []string) {
 	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
 
-	res, err := client.Call("script.BackupSnapshot", rpc.BackupSnapshotArgs{Config: configFlag, Height: heightFlag, Version: versionFlag})
+	res, err := client.Call("script.BackupSnapshot", rpc.BackupSnapshotArgs{
+		Config:      configFlag,
+		Height:      heightFlag,
+		Version:     versionFlag,
+		Compression: compressionFlag,
+		ChunkSize:   chunkSizeFlag,
+		Base:        baseFlag,
+		Resume:      resumeFlag,
+	})
 	if err != nil {
 		utils.Error("Failed to get backup snapshot call details: %v\n", err)
 	}
@@ -44,5 +53,9 @@ func init() {
 	snapshotCmd.Flags().StringVar(&configFlag, "config", "", "Config dir")
 	snapshotCmd.MarkFlagRequired("config")
 	snapshotCmd.Flags().Uint64Var(&heightFlag, "height", 0, "Snapshot height")
-	snapshotCmd.Flags().Uint64Var(&versionFlag, "version", 0, "Snapshot version.(2 or 3. Default is 2)")
+	snapshotCmd.Flags().Uint64Var(&versionFlag, "version", 0, "Snapshot version.(2, 3, 4 or 5. Default is 2, or 5 if any chunk option is set)")
+	snapshotCmd.Flags().StringVar(&compressionFlag, "compression", "", "Chunk compression of a version 5 snapshot.(none, gzip, snappy or zstd. Default is none)")
+	snapshotCmd.Flags().Uint64Var(&chunkSizeFlag, "chunk_size", 0, "Uncompressed chunk size in bytes of a version 5 snapshot. Default is 64MB")
+	snapshotCmd.Flags().StringVar(&baseFlag, "base", "", "Base snapshot directory of an incremental version 5 snapshot")
+	snapshotCmd.Flags().BoolVar(&resumeFlag, "resume", false, "Resume an interrupted export of a version 5 snapshot")
 }
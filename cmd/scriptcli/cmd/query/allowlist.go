@@ -0,0 +1,56 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/scripttoken/script/cmd/scriptcli/cmd/utils"
+	"github.com/scripttoken/script/rpc"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// allowlistCmd represents the allowlist command.
+// Example:
+//		scriptcli query allowlist
+//		scriptcli query allowlist --reload
+var allowlistCmd = &cobra.Command{
+	Use:     "allowlist",
+	Short:   "Get the peer allowlist",
+	Long:    `Get the nodes allowed to connect in the permissioned mode, optionally after reloading the allowlist.`,
+	Example: `scriptcli query allowlist --reload`,
+	Run:     doAllowlistCmd,
+}
+
+func doAllowlistCmd(cmd *cobra.Command, args []string) {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	if reloadFlag {
+		res, err := client.Call("script.ReloadPeerAllowlist", rpc.ReloadPeerAllowlistArgs{})
+		if err != nil {
+			utils.Error("Failed to reload peer allowlist: %v\n", err)
+		}
+		if res.Error != nil {
+			utils.Error("Failed to reload peer allowlist: %v\n", res.Error)
+		}
+	}
+
+	res, err := client.Call("script.GetPeerAllowlist", rpc.GetPeerAllowlistArgs{})
+	if err != nil {
+		utils.Error("Failed to get peer allowlist: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to retrieve peer allowlist: %v\n", res.Error)
+	}
+	json, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n%v\n", err, string(json))
+	}
+	fmt.Println(string(json))
+}
+
+func init() {
+	allowlistCmd.Flags().BoolVar(&reloadFlag, "reload", false, "Reload the allowlist before getting it")
+}
@@ -0,0 +1,45 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/scripttoken/script/cmd/scriptcli/cmd/utils"
+	"github.com/scripttoken/script/rpc"
+
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// assetCmd represents the asset command.
+// Example:
+//		scriptcli query asset --asset_id=0x5c4b2a3f1c6bc5e4c86e4a2e0c8d4b1d5e3c1a9f
+var assetCmd = &cobra.Command{
+	Use:     "asset",
+	Short:   "Get native token asset details",
+	Example: `scriptcli query asset --asset_id=0x5c4b2a3f1c6bc5e4c86e4a2e0c8d4b1d5e3c1a9f`,
+	Run:     doAssetCmd,
+}
+
+func doAssetCmd(cmd *cobra.Command, args []string) {
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	res, err := client.Call("script.GetAsset", rpc.GetAssetArgs{AssetID: assetIDFlag})
+	if err != nil {
+		utils.Error("Failed to get asset details: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Failed to get asset details: %v\n", res.Error)
+	}
+	json, err := json.MarshalIndent(res.Result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n%s\n", err, string(json))
+	}
+	fmt.Println(string(json))
+}
+
+func init() {
+	assetCmd.Flags().StringVar(&assetIDFlag, "asset_id", "", "ID of the asset")
+	assetCmd.MarkFlagRequired("asset_id")
+}
@@ -0,0 +1,39 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/scripttoken/script/cmd/scriptcli/cmd/utils"
+	"github.com/scripttoken/script/rpc"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// bansCmd represents the bans command.
+// Example:
+//		scriptcli query bans
+var bansCmd = &cobra.Command{
+	Use:     "bans",
+	Short:   "Get currently banned peers",
+	Long:    `Get the peers currently banned for misbehaving.`,
+	Example: `scriptcli query bans`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+		res, err := client.Call("script.GetPeerBans", rpc.GetPeerBansArgs{})
+		if err != nil {
+			utils.Error("Failed to get peer bans: %v\n", err)
+		}
+		if res.Error != nil {
+			utils.Error("Failed to retrieve peer bans: %v\n", res.Error)
+		}
+		json, err := json.MarshalIndent(res.Result, "", "    ")
+		if err != nil {
+			utils.Error("Failed to parse server response: %v\n%v\n", err, string(json))
+		}
+		fmt.Println(string(json))
+	},
+}
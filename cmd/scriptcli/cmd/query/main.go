@@ -20,6 +20,8 @@ var (
 	sourceFlag                   string
 	holderFlag                   string
 	withdrawnOnlyFlag            bool
+	reloadFlag                   bool
+	assetIDFlag                  string
 )
 
 // QueryCmd represents the query command
@@ -36,11 +38,16 @@ func init() {
 	QueryCmd.AddCommand(traceBlocksCmd)
 	QueryCmd.AddCommand(txCmd)
 	QueryCmd.AddCommand(splitRuleCmd)
+	QueryCmd.AddCommand(assetCmd)
 	QueryCmd.AddCommand(vcpCmd)
 	QueryCmd.AddCommand(gcpCmd)
 	QueryCmd.AddCommand(eenpCmd)
 	QueryCmd.AddCommand(srdrsCmd)
 	QueryCmd.AddCommand(stakeReturnsCmd)
 	QueryCmd.AddCommand(peersCmd)
+	QueryCmd.AddCommand(networkCmd)
+	QueryCmd.AddCommand(voteGossipCmd)
+	QueryCmd.AddCommand(bansCmd)
+	QueryCmd.AddCommand(allowlistCmd)
 	QueryCmd.AddCommand(versionCmd)
 }
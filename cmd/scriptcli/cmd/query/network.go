@@ -0,0 +1,45 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/scripttoken/script/cmd/scriptcli/cmd/utils"
+	"github.com/scripttoken/script/rpc"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// networkCmd represents the network command.
+// Example:
+//		scriptcli query network
+var networkCmd = &cobra.Command{
+	Use:     "network",
+	Short:   "Get the peer and traffic diagnostics",
+	Long:    `Get the connected peers with their latency and per-channel traffic, and the aggregate totals.`,
+	Example: `scriptcli query network`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+		res, err := client.Call("script.GetNetworkInfo", rpc.GetNetworkInfoArgs{
+			SkipEdgeNode: skipEdgeNodeFlag,
+		})
+		if err != nil {
+			utils.Error("Failed to get network info: %v\n", err)
+		}
+		if res.Error != nil {
+			utils.Error("Failed to retrieve network info: %v\n", res.Error)
+		}
+		json, err := json.MarshalIndent(res.Result, "", "    ")
+		if err != nil {
+			utils.Error("Failed to parse server response: %v\n%v\n", err, string(json))
+		}
+		fmt.Println(string(json))
+	},
+}
+
+func init() {
+	networkCmd.Flags().BoolVar(&skipEdgeNodeFlag, "skip_edge_node", false, "skip peer edge nodes")
+}
@@ -0,0 +1,39 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/scripttoken/script/cmd/scriptcli/cmd/utils"
+	"github.com/scripttoken/script/rpc"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// voteGossipCmd represents the vote_gossip command.
+// Example:
+//		scriptcli query vote_gossip
+var voteGossipCmd = &cobra.Command{
+	Use:     "vote_gossip",
+	Short:   "Get the aggregated vote gossip statistics",
+	Long:    `Get the votes and summaries sent and received, the current fan-out, and the bytes saved by the vote summaries.`,
+	Example: `scriptcli query vote_gossip`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+		res, err := client.Call("script.GetVoteGossipStats", rpc.GetVoteGossipStatsArgs{})
+		if err != nil {
+			utils.Error("Failed to get vote gossip stats: %v\n", err)
+		}
+		if res.Error != nil {
+			utils.Error("Failed to retrieve vote gossip stats: %v\n", res.Error)
+		}
+		json, err := json.MarshalIndent(res.Result, "", "    ")
+		if err != nil {
+			utils.Error("Failed to parse server response: %v\n%v\n", err, string(json))
+		}
+		fmt.Println(string(json))
+	},
+}
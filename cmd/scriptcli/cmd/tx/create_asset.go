@@ -0,0 +1,118 @@
+package tx
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/scripttoken/script/cmd/scriptcli/cmd/utils"
+	"github.com/scripttoken/script/ledger/types"
+	"github.com/scripttoken/script/rpc"
+
+	rpcc "github.com/ybbus/jsonrpc"
+)
+
+// createAssetCmd represents the create asset command
+// Example:
+//		scriptcli tx create_asset --chain="scriptnet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --name="Segment Credit" --symbol=SEG --decimals=6 --supply=1000000000000 --seq=3
+var createAssetCmd = &cobra.Command{
+	Use:     "create_asset",
+	Short:   "Create a native token",
+	Long:    `Create a native token. The whole supply, in the smallest unit of the token, is credited to the issuer.`,
+	Example: `scriptcli tx create_asset --chain="scriptnet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --name="Segment Credit" --symbol=SEG --decimals=6 --supply=1000000000000 --seq=3`,
+	Run:     doCreateAssetCmd,
+}
+
+func doCreateAssetCmd(cmd *cobra.Command, args []string) {
+	wallet, issuerAddress, err := walletUnlockWithPath(cmd, fromFlag, pathFlag, passwordFlag)
+	if err != nil || wallet == nil {
+		return
+	}
+	defer wallet.Lock(issuerAddress)
+
+	fee, ok := types.ParseCoinAmount(feeFlag)
+	if !ok {
+		utils.Error("Failed to parse fee")
+	}
+	supply, ok := new(big.Int).SetString(supplyFlag, 10)
+	if !ok {
+		utils.Error("Failed to parse supply")
+	}
+
+	createAssetTx := &types.CreateAssetTx{
+		Fee: types.Coins{
+			SCPTWei: new(big.Int).SetUint64(0),
+			SPAYWei: fee,
+		},
+		Issuer: types.TxInput{
+			Address:  issuerAddress,
+			Sequence: uint64(seqFlag),
+		},
+		Name:     assetNameFlag,
+		Symbol:   assetSymbolFlag,
+		Decimals: assetDecimalsFlag,
+		Supply:   supply,
+	}
+
+	sig, err := wallet.Sign(issuerAddress, createAssetTx.SignBytes(chainIDFlag))
+	if err != nil {
+		utils.Error("Failed to sign transaction: %v\n", err)
+	}
+	createAssetTx.SetSignature(issuerAddress, sig)
+
+	raw, err := types.TxToBytes(createAssetTx)
+	if err != nil {
+		utils.Error("Failed to encode transaction: %v\n", err)
+	}
+	signedTx := hex.EncodeToString(raw)
+
+	client := rpcc.NewRPCClient(viper.GetString(utils.CfgRemoteRPCEndpoint))
+
+	var res *rpcc.RPCResponse
+	if asyncFlag {
+		res, err = client.Call("script.BroadcastRawTransactionAsync", rpc.BroadcastRawTransactionArgs{TxBytes: signedTx})
+	} else {
+		res, err = client.Call("script.BroadcastRawTransaction", rpc.BroadcastRawTransactionArgs{TxBytes: signedTx})
+	}
+	if err != nil {
+		utils.Error("Failed to broadcast transaction: %v\n", err)
+	}
+	if res.Error != nil {
+		utils.Error("Server returned error: %v\n", res.Error)
+	}
+	result := &rpc.BroadcastRawTransactionResult{}
+	err = res.GetObject(result)
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n", err)
+	}
+	formatted, err := json.MarshalIndent(result, "", "    ")
+	if err != nil {
+		utils.Error("Failed to parse server response: %v\n", err)
+	}
+	fmt.Printf("Successfully broadcasted transaction:\n%s\n", formatted)
+	fmt.Printf("Asset ID: %v\n", createAssetTx.AssetID().Hex())
+}
+
+func init() {
+	createAssetCmd.Flags().StringVar(&chainIDFlag, "chain", "", "Chain ID")
+	createAssetCmd.Flags().StringVar(&fromFlag, "from", "", "Address of the issuer")
+	createAssetCmd.Flags().StringVar(&pathFlag, "path", "", "Wallet derivation path")
+	createAssetCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction")
+	createAssetCmd.Flags().StringVar(&assetNameFlag, "name", "", "Name of the asset")
+	createAssetCmd.Flags().StringVar(&assetSymbolFlag, "symbol", "", "Ticker symbol of the asset")
+	createAssetCmd.Flags().Uint8Var(&assetDecimalsFlag, "decimals", 18, "Number of decimals of the asset")
+	createAssetCmd.Flags().StringVar(&supplyFlag, "supply", "", "Total supply, in the smallest unit of the asset")
+	createAssetCmd.Flags().StringVar(&feeFlag, "fee", fmt.Sprintf("%dwei", types.MinimumTransactionFeeSPAYWeiJune2021), "Fee")
+	createAssetCmd.Flags().StringVar(&walletFlag, "wallet", "soft", "Wallet type (soft|nano|trezor)")
+	createAssetCmd.Flags().BoolVar(&asyncFlag, "async", false, "block until tx has been included in the blockchain")
+	createAssetCmd.Flags().StringVar(&passwordFlag, "password", "", "password to unlock the wallet")
+
+	createAssetCmd.MarkFlagRequired("chain")
+	createAssetCmd.MarkFlagRequired("name")
+	createAssetCmd.MarkFlagRequired("symbol")
+	createAssetCmd.MarkFlagRequired("supply")
+	createAssetCmd.MarkFlagRequired("seq")
+}
@@ -25,6 +25,8 @@ var (
 	percentagesFlag              []string
 	valueFlag                    string
 	gasPriceFlag                 string
+	maxFeeFlag                   string
+	maxPriorityFeeFlag           string
 	gasLimitFlag                 uint64
 	dataFlag                     string
 	walletFlag                   string
@@ -36,6 +38,11 @@ var (
 	beneficiaryFlag              string
 	splitBasisPointFlag          uint64
 	passwordFlag                 string
+	tokensFlag                   []string
+	assetNameFlag                string
+	assetSymbolFlag              string
+	assetDecimalsFlag            uint8
+	supplyFlag                   string
 )
 
 // TxCmd represents the Tx command
@@ -54,4 +61,5 @@ func init() {
 	TxCmd.AddCommand(depositStakeCmd)
 	TxCmd.AddCommand(withdrawStakeCmd)
 	TxCmd.AddCommand(stakeRewardDistributionCmd)
+	TxCmd.AddCommand(createAssetCmd)
 }
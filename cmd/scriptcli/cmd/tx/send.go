@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -23,6 +24,7 @@ import (
 //		scriptcli tx send --chain="scriptnet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --script=10 --spay=9 --seq=1
 //		scriptcli tx send --chain="scriptnet" --path "m/44'/60'/0'/0/0" --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --script=10 --spay=9 --seq=1 --wallet=trezor
 //		scriptcli tx send --chain="scriptnet" --path "m/44'/60'/0'/0" --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --script=10 --spay=9 --seq=1 --wallet=nano
+//		scriptcli tx send --chain="scriptnet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=9F1233798E905E173560071255140b4A8aBd3Ec6 --tokens=0x5c4b2a3f1c6bc5e4c86e4a2e0c8d4b1d5e3c1a9f:1500000 --seq=1
 var sendCmd = &cobra.Command{
 	Use:     "send",
 	Short:   "Send tokens",
@@ -64,12 +66,16 @@ func doSendCmd(cmd *cobra.Command, args []string) {
 	if !ok {
 		utils.Error("Failed to parse fee")
 	}
+	tokens, err := parseTokens(tokensFlag)
+	if err != nil {
+		utils.Error("Failed to parse tokens: %v\n", err)
+	}
 	inputs := []types.TxInput{{
 		Address: fromAddress,
 		Coins: types.Coins{
 			SPAYWei: new(big.Int).Add(spay, fee),
 			SCPTWei: script,
-		},
+		}.Plus(tokens),
 		Sequence: uint64(seqFlag),
 	}}
 	outputs := []types.TxOutput{{
@@ -77,7 +83,7 @@ func doSendCmd(cmd *cobra.Command, args []string) {
 		Coins: types.Coins{
 			SPAYWei: spay,
 			SCPTWei: script,
-		},
+		}.Plus(tokens),
 	}}
 	sendTx := &types.SendTx{
 		Fee: types.Coins{
@@ -127,6 +133,23 @@ func doSendCmd(cmd *cobra.Command, args []string) {
 	fmt.Printf("Successfully broadcasted transaction:\n%s\n", formatted)
 }
 
+// parseTokens parses the native token amounts given as <asset ID>:<amount in the smallest unit of the asset>
+func parseTokens(tokens []string) (types.Coins, error) {
+	ret := types.NewCoins(0, 0)
+	for _, token := range tokens {
+		parts := strings.Split(token, ":")
+		if len(parts) != 2 || !common.IsHexAddress(parts[0]) {
+			return ret, fmt.Errorf("invalid token amount %v, expected <asset ID>:<amount>", token)
+		}
+		amount, ok := new(big.Int).SetString(parts[1], 10)
+		if !ok || amount.Sign() <= 0 {
+			return ret, fmt.Errorf("invalid amount of token %v", parts[0])
+		}
+		ret = ret.Plus(types.NewTokenCoins(common.HexToAddress(parts[0]), amount))
+	}
+	return ret, nil
+}
+
 func init() {
 	sendCmd.Flags().StringVar(&chainIDFlag, "chain", "", "Chain ID")
 	sendCmd.Flags().StringVar(&fromFlag, "from", "", "Address to send from")
@@ -135,6 +158,7 @@ func init() {
 	sendCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction")
 	sendCmd.Flags().StringVar(&scriptAmountFlag, "script", "0", "Script amount")
 	sendCmd.Flags().StringVar(&spayAmountFlag, "spay", "0", "SPAY amount")
+	sendCmd.Flags().StringSliceVar(&tokensFlag, "tokens", []string{}, "Native token amounts, as <asset ID>:<amount in the smallest unit of the asset>")
 	sendCmd.Flags().StringVar(&feeFlag, "fee", fmt.Sprintf("%dwei", types.MinimumTransactionFeeSPAYWeiJune2021), "Fee")
 	sendCmd.Flags().StringVar(&walletFlag, "wallet", "soft", "Wallet type (soft|nano|trezor)")
 	sendCmd.Flags().BoolVar(&asyncFlag, "async", false, "block until tx has been included in the blockchain")
@@ -33,7 +33,10 @@ var smartContractCmd = &cobra.Command{
 	scriptcli tx smart_contract --chain="scriptnet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --value=1680 --gas_price=3 --gas_limit=50000 --data=600a600c600039600a6000f3600360135360016013f3 --seq=1	
 	
 	[Call an API of a smart contract]
-	scriptcli tx smart_contract --chain="scriptnet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x7ad6cea2bc3162e30a3c98d84f821b3233c22647 --gas_price=3 --gas_limit=50000 --seq=2`,
+	scriptcli tx smart_contract --chain="scriptnet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x7ad6cea2bc3162e30a3c98d84f821b3233c22647 --gas_price=3 --gas_limit=50000 --seq=2
+
+	[Call an API of a smart contract with dynamic fee]
+	scriptcli tx smart_contract --chain="scriptnet" --from=2E833968E5bB786Ae419c4d13189fB081Cc43bab --to=0x7ad6cea2bc3162e30a3c98d84f821b3233c22647 --max_fee=200000000wei --max_priority_fee=1000000wei --gas_limit=50000 --seq=2`,
 	Long: "smartContractCmd represents the smart_contract command. It will submit a smart contract transaction to the blockchain, which will modify the global consensus state when it is included in the blockchain",
 	Run:  doSmartContractCmd,
 }
@@ -73,19 +76,44 @@ func doSmartContractCmd(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	smartContractTx := &types.SmartContractTx{
-		From:     from,
-		To:       to,
-		GasLimit: gasLimitFlag,
-		GasPrice: gasPrice,
-		Data:     data,
+	var smartContractTx types.Tx
+	if maxFeeFlag == "" {
+		smartContractTx = &types.SmartContractTx{
+			From:     from,
+			To:       to,
+			GasLimit: gasLimitFlag,
+			GasPrice: gasPrice,
+			Data:     data,
+		}
+	} else {
+		maxFee, ok := types.ParseCoinAmount(maxFeeFlag)
+		if !ok {
+			utils.Error("Failed to parse max fee")
+		}
+		maxPriorityFee, ok := types.ParseCoinAmount(maxPriorityFeeFlag)
+		if !ok {
+			utils.Error("Failed to parse max priority fee")
+		}
+		smartContractTx = &types.SmartContractTxV2{
+			From:                 from,
+			To:                   to,
+			GasLimit:             gasLimitFlag,
+			MaxFeePerGas:         maxFee,
+			MaxPriorityFeePerGas: maxPriorityFee,
+			Data:                 data,
+		}
 	}
 
 	sig, err := wallet.Sign(fromAddress, smartContractTx.SignBytes(chainIDFlag))
 	if err != nil {
 		utils.Error("Failed to sign transaction: %v\n", err)
 	}
-	smartContractTx.SetSignature(fromAddress, sig)
+	switch tx := smartContractTx.(type) {
+	case *types.SmartContractTx:
+		tx.SetSignature(fromAddress, sig)
+	case *types.SmartContractTxV2:
+		tx.SetSignature(fromAddress, sig)
+	}
 
 	raw, err := types.TxToBytes(smartContractTx)
 	if err != nil {
@@ -125,6 +153,8 @@ func init() {
 	smartContractCmd.Flags().StringVar(&toFlag, "to", "", "The smart contract address")
 	smartContractCmd.Flags().StringVar(&valueFlag, "value", "0", "Value to be transferred")
 	smartContractCmd.Flags().StringVar(&gasPriceFlag, "gas_price", fmt.Sprintf("%dwei", types.MinimumGasPriceJune2021), "The gas price")
+	smartContractCmd.Flags().StringVar(&maxFeeFlag, "max_fee", "", "The max fee per gas, sends a dynamic fee transaction if set")
+	smartContractCmd.Flags().StringVar(&maxPriorityFeeFlag, "max_priority_fee", "0wei", "The max priority fee per gas paid to the block proposer")
 	smartContractCmd.Flags().Uint64Var(&gasLimitFlag, "gas_limit", 0, "The gas limit")
 	smartContractCmd.Flags().StringVar(&dataFlag, "data", "", "The data for the smart contract")
 	smartContractCmd.Flags().Uint64Var(&seqFlag, "seq", 0, "Sequence number of the transaction")
@@ -134,7 +164,6 @@ func init() {
 
 	smartContractCmd.MarkFlagRequired("chain")
 	smartContractCmd.MarkFlagRequired("from")
-	smartContractCmd.MarkFlagRequired("gas_price")
 	smartContractCmd.MarkFlagRequired("gas_limit")
 	smartContractCmd.MarkFlagRequired("seq")
 }
@@ -0,0 +1,254 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//
+// Clock abstracts the time source, so the components driven by timers can run on a
+// simulated time in the tests and simulations
+//
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// NewTimer creates a Timer firing once after the given duration
+	NewTimer(d time.Duration) Timer
+
+	// NewTicker creates a Ticker firing every period
+	NewTicker(period time.Duration) Ticker
+}
+
+// Timer is the Clock counterpart of time.Timer
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// Ticker is the Clock counterpart of time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// ---- Real clock ---- //
+
+type realClock struct{}
+
+// NewRealClock returns the Clock backed by the system time
+func NewRealClock() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{timer: time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(period time.Duration) Ticker {
+	return &realTicker{ticker: time.NewTicker(period)}
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t *realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t *realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t *realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// ---- Simulated clock ---- //
+
+//
+// SimulatedClock is a Clock whose time only moves forward when Advance is called. The
+// timers and tickers due are fired in order of their deadlines, so a simulation driven
+// by a SimulatedClock does not depend on the speed of the host.
+//
+type SimulatedClock struct {
+	mu      *sync.Mutex
+	now     time.Time
+	seq     uint64
+	waiters []*simWaiter
+}
+
+type simWaiter struct {
+	seq      uint64
+	deadline time.Time
+	period   time.Duration // zero for the timers
+	ch       chan time.Time
+	stopped  bool
+}
+
+// NewSimulatedClock creates a SimulatedClock starting at the given time
+func NewSimulatedClock(start time.Time) *SimulatedClock {
+	return &SimulatedClock{
+		mu:  &sync.Mutex{},
+		now: start,
+	}
+}
+
+// Now implements the Clock interface
+func (c *SimulatedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer implements the Clock interface
+func (c *SimulatedClock) NewTimer(d time.Duration) Timer {
+	return &simTimer{clock: c, waiter: c.addWaiter(d, 0)}
+}
+
+// NewTicker implements the Clock interface
+func (c *SimulatedClock) NewTicker(period time.Duration) Ticker {
+	if period <= 0 {
+		panic("non-positive interval for SimulatedClock.NewTicker")
+	}
+	return &simTicker{clock: c, waiter: c.addWaiter(period, period)}
+}
+
+// Advance moves the time forward by the given duration, and fires the timers and
+// tickers that become due. Like the timers of the time package, a fire is dropped
+// if the previous one has not been consumed yet.
+func (c *SimulatedClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := c.now.Add(d)
+	for {
+		waiter := c.nextDue(target)
+		if waiter == nil {
+			break
+		}
+		c.now = waiter.deadline
+		select {
+		case waiter.ch <- c.now:
+		default:
+		}
+		if waiter.period > 0 {
+			waiter.deadline = waiter.deadline.Add(waiter.period)
+		} else {
+			waiter.stopped = true
+			c.removeWaiter(waiter)
+		}
+	}
+	c.now = target
+}
+
+// NextDeadline returns the deadline of the earliest active timer or ticker, false if
+// there is none
+func (c *SimulatedClock) NextDeadline() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.waiters) == 0 {
+		return time.Time{}, false
+	}
+	c.sortWaiters()
+	return c.waiters[0].deadline, true
+}
+
+func (c *SimulatedClock) addWaiter(d time.Duration, period time.Duration) *simWaiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq++
+	waiter := &simWaiter{
+		seq:      c.seq,
+		deadline: c.now.Add(d),
+		period:   period,
+		ch:       make(chan time.Time, 1),
+	}
+	c.waiters = append(c.waiters, waiter)
+	return waiter
+}
+
+// nextDue returns the earliest waiter due before the target, the caller must hold the lock
+func (c *SimulatedClock) nextDue(target time.Time) *simWaiter {
+	if len(c.waiters) == 0 {
+		return nil
+	}
+	c.sortWaiters()
+	if c.waiters[0].deadline.After(target) {
+		return nil
+	}
+	return c.waiters[0]
+}
+
+func (c *SimulatedClock) sortWaiters() {
+	sort.Slice(c.waiters, func(i, j int) bool {
+		if c.waiters[i].deadline.Equal(c.waiters[j].deadline) {
+			return c.waiters[i].seq < c.waiters[j].seq
+		}
+		return c.waiters[i].deadline.Before(c.waiters[j].deadline)
+	})
+}
+
+// stop deactivates the waiter, and returns false if it was already inactive
+func (c *SimulatedClock) stop(waiter *simWaiter) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if waiter.stopped {
+		return false
+	}
+	waiter.stopped = true
+	c.removeWaiter(waiter)
+	return true
+}
+
+func (c *SimulatedClock) removeWaiter(waiter *simWaiter) {
+	for i, w := range c.waiters {
+		if w == waiter {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+type simTimer struct {
+	clock  *SimulatedClock
+	waiter *simWaiter
+}
+
+func (t *simTimer) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+func (t *simTimer) Stop() bool {
+	return t.clock.stop(t.waiter)
+}
+
+type simTicker struct {
+	clock  *SimulatedClock
+	waiter *simWaiter
+}
+
+func (t *simTicker) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+func (t *simTicker) Stop() {
+	t.clock.stop(t.waiter)
+}
@@ -0,0 +1,75 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulatedClockTimer(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Unix(1600000000, 0)
+	clock := NewSimulatedClock(start)
+	timer := clock.NewTimer(10 * time.Second)
+
+	clock.Advance(9 * time.Second)
+	assert.Equal(start.Add(9*time.Second), clock.Now())
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired too early")
+	default:
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case fired := <-timer.C():
+		assert.Equal(start.Add(10*time.Second), fired)
+	default:
+		t.Fatal("timer did not fire")
+	}
+	assert.False(timer.Stop())
+
+	stopped := clock.NewTimer(time.Second)
+	assert.True(stopped.Stop())
+	clock.Advance(time.Minute)
+	select {
+	case <-stopped.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+	_, ok := clock.NextDeadline()
+	assert.False(ok)
+}
+
+func TestSimulatedClockTicker(t *testing.T) {
+	assert := assert.New(t)
+
+	start := time.Unix(1600000000, 0)
+	clock := NewSimulatedClock(start)
+	ticker := clock.NewTicker(2 * time.Second)
+
+	deadline, ok := clock.NextDeadline()
+	assert.True(ok)
+	assert.Equal(start.Add(2*time.Second), deadline)
+
+	for i := 1; i <= 3; i++ {
+		clock.Advance(2 * time.Second)
+		fired := <-ticker.C()
+		assert.Equal(start.Add(time.Duration(2*i)*time.Second), fired)
+	}
+
+	// The ticks are dropped when the channel is not drained
+	clock.Advance(10 * time.Second)
+	fired := <-ticker.C()
+	assert.Equal(start.Add(8*time.Second), fired)
+
+	ticker.Stop()
+	clock.Advance(10 * time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+}
@@ -36,6 +36,18 @@ const (
 	CfgConsensusForceLastVote             = "consensus.forceLastVote"
 	CfgConsensusForceLastVoteTargetBlock  = "consensus.forceLastVoteTargetBlock"
 	CfgConsensusForceLastVoteTargetHeight = "consensus.forceLastVoteTargetHeight"
+	// CfgConsensusVoteGossipEnabled indicates whether to exchange the aggregated vote summaries
+	// with the peers advertising the capability, and only send them the votes adding signers.
+	CfgConsensusVoteGossipEnabled = "consensus.voteGossip.enabled"
+	// CfgConsensusVoteGossipMaxFanout sets the maximum number of such peers an aggregated vote
+	// is sent to in each round.
+	CfgConsensusVoteGossipMaxFanout = "consensus.voteGossip.maxFanout"
+
+	// CfgLedgerParallelTxExecution indicates whether the transactions of the received blocks are executed
+	// optimistically in parallel, with the conflicting transactions re-executed in the block order.
+	CfgLedgerParallelTxExecution = "ledger.parallelTxExecution"
+	// CfgLedgerParallelTxWorkers sets the number of transactions executed concurrently, the number of CPUs if 0
+	CfgLedgerParallelTxWorkers = "ledger.parallelTxWorkers"
 
 	// CfgStorageRollingEnabled indicates whether rolling is enabled
 	CfgStorageRollingEnabled = "storage.stateRollingEnabled"
@@ -53,6 +65,37 @@ const (
 	CfgStorageLevelDBHandles = "storage.levelDBHandles"
 	// CfgStorageRollingInterval is the block interval that we start new db layer
 	CfgStorageRollingInterval = "storage.rollingInterval"
+	// CfgStorageMode selects the storage mode, e.g. "default" or "archive"
+	CfgStorageMode = "storage.mode"
+	// CfgStorageVerifyInBackground indicates whether the state DB integrity is periodically verified in the background
+	CfgStorageVerifyInBackground = "storage.verifyInBackground"
+	// CfgStorageVerifyInterval is the interval (in seconds) between two background state DB verifications
+	CfgStorageVerifyInterval = "storage.verifyInterval"
+	// CfgStorageVerifyNodesPerSecond limits the number of trie nodes checked per second by the background verification
+	CfgStorageVerifyNodesPerSecond = "storage.verifyNodesPerSecond"
+	// CfgStorageVerifyRepair indicates whether missing state trie nodes found in the background are re-fetched from peers
+	CfgStorageVerifyRepair = "storage.verifyRepair"
+	// CfgStorageIndexLogs indicates whether the logs of the finalized blocks are indexed by contract address and topics
+	CfgStorageIndexLogs = "storage.indexLogs"
+	// CfgStorageIndexAccountTxs indicates whether the transactions of the finalized blocks are indexed by the accounts they touch
+	CfgStorageIndexAccountTxs = "storage.indexAccountTxs"
+
+	// CfgSnapshotDir sets the directory of the produced snapshots, <config>/backup/snapshot if empty
+	CfgSnapshotDir = "snapshot.dir"
+	// CfgSnapshotAutoEnabled indicates whether snapshots are produced automatically
+	CfgSnapshotAutoEnabled = "snapshot.autoEnabled"
+	// CfgSnapshotAutoInterval is the number of checkpoints between two automatic snapshots
+	CfgSnapshotAutoInterval = "snapshot.autoInterval"
+	// CfgSnapshotAutoRetain is the number of automatic snapshots to keep
+	CfgSnapshotAutoRetain = "snapshot.autoRetain"
+	// CfgSnapshotAutoCompression sets the chunk compression of the automatic snapshots
+	CfgSnapshotAutoCompression = "snapshot.autoCompression"
+	// CfgSnapshotServeEnabled indicates whether the snapshot directory is served over HTTP
+	CfgSnapshotServeEnabled = "snapshot.serveEnabled"
+	// CfgSnapshotServeAddress sets the binding address of the snapshot HTTP server
+	CfgSnapshotServeAddress = "snapshot.serveAddress"
+	// CfgSnapshotServePort sets the port of the snapshot HTTP server
+	CfgSnapshotServePort = "snapshot.servePort"
 
 	// CfgSyncMessageQueueSize defines the capacity of Sync Manager message queue.
 	CfgSyncMessageQueueSize = "sync.messageQueueSize"
@@ -65,6 +108,15 @@ const (
 	CfgSyncForcedDownloadBlockHash             = "sync.forcedDownloadBlockHash"
 	CfgSyncDownloadBranchTimeGapInMilliseconds = "sync.downloadBranchTimeGapInMilliseconds"
 	CfgSyncRecoveryModeBlockGapThreshold       = "sync.recoveryModeBlockGapThreshold"
+	// CfgSyncCompactBlockEnabled indicates whether to relay the new blocks as compact blocks
+	// to the peers advertising the capability.
+	CfgSyncCompactBlockEnabled = "sync.compactBlockEnabled"
+	// CfgSyncParallelDownload indicates whether to spread the block downloads across the peers
+	// according to their measured throughput.
+	CfgSyncParallelDownload = "sync.parallelDownload"
+	// CfgSyncMaxBlocksInFlightPerPeer limits the blocks requested from a peer and not received yet
+	// in the parallel download.
+	CfgSyncMaxBlocksInFlightPerPeer = "sync.maxBlocksInFlightPerPeer"
 
 	// CfgP2POpt sets which P2P network to use: p2p, libp2p, or both.
 	CfgP2POpt = "p2p.opt"
@@ -118,6 +170,25 @@ const (
 	CfgP2PSendRate                        = "p2p.sendRate"
 	CfgP2PRecvRate                        = "p2p.recvRate"
 	CfgP2PSendBufferTimoutInSeconds       = "p2p.sendBufferTimoutInSeconds"
+	// CfgP2PReputationEnabled indicates whether misbehaving peers are scored, disconnected and banned
+	CfgP2PReputationEnabled = "p2p.reputation.enabled"
+	// CfgP2PReputationDisconnectThreshold is the misbehavior score at which a peer is disconnected
+	CfgP2PReputationDisconnectThreshold = "p2p.reputation.disconnectThreshold"
+	// CfgP2PReputationBanThreshold is the misbehavior score at which a peer is banned
+	CfgP2PReputationBanThreshold = "p2p.reputation.banThreshold"
+	// CfgP2PReputationBanDuration is the duration (in seconds) of a ban
+	CfgP2PReputationBanDuration = "p2p.reputation.banDuration"
+	// CfgP2PReputationHalfLife is the time (in seconds) for a misbehavior score to decay by half
+	CfgP2PReputationHalfLife = "p2p.reputation.halfLife"
+	// CfgP2PReputationTxRateLimit is the number of transactions per second a peer can gossip before being penalized for flooding
+	CfgP2PReputationTxRateLimit = "p2p.reputation.txRateLimit"
+	// CfgP2PPermissionedEnabled indicates whether only the peers in the allowlist can connect
+	CfgP2PPermissionedEnabled = "p2p.permissioned.enabled"
+	// CfgP2PPermissionedAllowlistPath is the JSON file listing the public keys of the allowed peers
+	CfgP2PPermissionedAllowlistPath = "p2p.permissioned.allowlistPath"
+	// CfgP2PQUICEnabled sets whether the native P2P stack accepts QUIC connections on the UDP port
+	// of p2p.port, and dials the peers over QUIC first, falling back to TCP
+	CfgP2PQUICEnabled = "p2p.quic.enabled"
 
 	// CfgSyncInboundResponseWhitelist filters inbound messages based on peer ID.
 	CfgSyncInboundResponseWhitelist = "sync.inboundResponseWhitelist"
@@ -158,6 +229,19 @@ const (
 	CfgDebugLogSelectedEENPs = "debug.logSelectedEENPs"
 )
 
+// Storage modes.
+const (
+	// StorageModeDefault rolls the DB layers and prunes the old states
+	StorageModeDefault = "default"
+	// StorageModeArchive retains every historical state, i.e. rolling and pruning are disabled
+	StorageModeArchive = "archive"
+)
+
+// IsArchiveMode indicates whether the node runs in the archive storage mode
+func IsArchiveMode() bool {
+	return viper.GetString(CfgStorageMode) == StorageModeArchive
+}
+
 // Starting block heights of features.
 const (
 	FeatureLightning uint64 = 0
@@ -191,6 +275,11 @@ func init() {
 	viper.SetDefault(CfgConsensusForceLastVote, false)
 	viper.SetDefault(CfgConsensusForceLastVoteTargetBlock, "")
 	viper.SetDefault(CfgConsensusForceLastVoteTargetHeight, 0)
+	viper.SetDefault(CfgConsensusVoteGossipEnabled, true)
+	viper.SetDefault(CfgConsensusVoteGossipMaxFanout, 8)
+
+	viper.SetDefault(CfgLedgerParallelTxExecution, false)
+	viper.SetDefault(CfgLedgerParallelTxWorkers, 0)
 
 	viper.SetDefault(CfgSyncMessageQueueSize, 512)
 	viper.SetDefault(CfgSyncDownloadByHash, false)
@@ -200,6 +289,9 @@ func init() {
 	viper.SetDefault(CfgSyncForcedDownloadBlockHash, "")
 	viper.SetDefault(CfgSyncDownloadBranchTimeGapInMilliseconds, 200)
 	viper.SetDefault(CfgSyncRecoveryModeBlockGapThreshold, 4)
+	viper.SetDefault(CfgSyncCompactBlockEnabled, true)
+	viper.SetDefault(CfgSyncParallelDownload, true)
+	viper.SetDefault(CfgSyncMaxBlocksInFlightPerPeer, 16)
 
 	viper.SetDefault(CfgStorageRollingEnabled, true)
 	viper.SetDefault(CfgStorageStatePruningEnabled, true)
@@ -209,6 +301,22 @@ func init() {
 	viper.SetDefault(CfgStorageLevelDBCacheSize, 256)
 	viper.SetDefault(CfgStorageLevelDBHandles, 16)
 	viper.SetDefault(CfgStorageRollingInterval, 14400) // approximately 1 days by default
+	viper.SetDefault(CfgStorageMode, StorageModeDefault)
+	viper.SetDefault(CfgStorageVerifyInBackground, false)
+	viper.SetDefault(CfgStorageVerifyInterval, 86400) // once a day by default
+	viper.SetDefault(CfgStorageVerifyNodesPerSecond, 2000)
+	viper.SetDefault(CfgStorageVerifyRepair, true)
+	viper.SetDefault(CfgStorageIndexLogs, false)
+	viper.SetDefault(CfgStorageIndexAccountTxs, false)
+
+	viper.SetDefault(CfgSnapshotDir, "")
+	viper.SetDefault(CfgSnapshotAutoEnabled, false)
+	viper.SetDefault(CfgSnapshotAutoInterval, 144) // approximately once a day by default
+	viper.SetDefault(CfgSnapshotAutoRetain, 3)
+	viper.SetDefault(CfgSnapshotAutoCompression, "gzip")
+	viper.SetDefault(CfgSnapshotServeEnabled, false)
+	viper.SetDefault(CfgSnapshotServeAddress, "0.0.0.0")
+	viper.SetDefault(CfgSnapshotServePort, "10003")
 
 	viper.SetDefault(CfgRPCEnabled, false)
 	viper.SetDefault(CfgP2PMessageQueueSize, 512)
@@ -235,6 +343,15 @@ func init() {
 	viper.SetDefault(CfgP2PSendRate, 512000) // 500 KB/s
 	viper.SetDefault(CfgP2PRecvRate, 512000) // 500 KB/s
 	viper.SetDefault(CfgP2PSendBufferTimoutInSeconds, 10)
+	viper.SetDefault(CfgP2PReputationEnabled, true)
+	viper.SetDefault(CfgP2PReputationDisconnectThreshold, 50)
+	viper.SetDefault(CfgP2PReputationBanThreshold, 100)
+	viper.SetDefault(CfgP2PReputationBanDuration, 86400) // 24 hours
+	viper.SetDefault(CfgP2PReputationHalfLife, 600)      // 10 minutes
+	viper.SetDefault(CfgP2PReputationTxRateLimit, 500)
+	viper.SetDefault(CfgP2PPermissionedEnabled, false)
+	viper.SetDefault(CfgP2PPermissionedAllowlistPath, "") // <config_folder>/allowlist.json if empty
+	viper.SetDefault(CfgP2PQUICEnabled, false)
 	// viper.SetDefault(CfgP2PSendRate, 2048000)  // 2 MB/s
 	// viper.SetDefault(CfgP2PRecvRate, 10240000) // 10 MB/s
 
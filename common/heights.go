@@ -39,6 +39,33 @@ const HeightSupportWrappedScript uint64 = 1
 // HeightEnableMetachainSupport specifies the block height to enable Script Metachain support (i.e. Mainnet 4.0)
 const HeightEnableMetachainSupport uint64 = 1
 
+// HeightEnableBlockGasLimit specifies the block height to cap the total gas of the smart contract transactions in a block
+const HeightEnableBlockGasLimit uint64 = 30000000
+
+// HeightEnableDynamicFee specifies the block height to price the smart contract gas with a per-block base fee (EIP-1559 style)
+const HeightEnableDynamicFee uint64 = 30000000
+
+// HeightEnableEVMBerlin specifies the block height to enable the Berlin EVM semantics, i.e. the cheaper non-zero calldata (EIP-2028), the warm/cold access gas (EIP-2929) and access lists (EIP-2930)
+const HeightEnableEVMBerlin uint64 = 30000000
+
+// HeightEnableEVMLondon specifies the block height to enable the London EVM semantics, i.e. BASEFEE (EIP-3198) and the reduced refunds (EIP-3529)
+const HeightEnableEVMLondon uint64 = 30000000
+
+// HeightEnableEVMShanghai specifies the block height to enable the Shanghai EVM semantics, i.e. PUSH0 (EIP-3855) and the init code limit (EIP-3860)
+const HeightEnableEVMShanghai uint64 = 30000000
+
+// HeightEnableEVMCancun specifies the block height to enable the Cancun EVM semantics, i.e. TLOAD/TSTORE (EIP-1153), MCOPY (EIP-5656) and SELFDESTRUCT (EIP-6780)
+const HeightEnableEVMCancun uint64 = 30000000
+
+// HeightEnableCryptoPrecompiles specifies the block height to enable the BLS12-381 (EIP-2537) and the ed25519 signature verification precompiled contracts
+const HeightEnableCryptoPrecompiles uint64 = 30000000
+
+// HeightEnableStakeQueryPrecompiles specifies the block height to enable the precompiled contracts reading the lightning and elite edge node stakes and the stake reward distribution rules
+const HeightEnableStakeQueryPrecompiles uint64 = 30000000
+
+// HeightEnableNativeTokens specifies the block height to enable the native tokens, i.e. the CreateAssetTx and the tokens moved by the SendTx, ReserveFundTx and ServicePaymentTx
+const HeightEnableNativeTokens uint64 = 30000000
+
 // CheckpointInterval defines the interval between checkpoints.
 const CheckpointInterval = int64(100)
 
@@ -98,6 +98,11 @@ func (pq *PriorityQueue) Peek() Element {
 	return pq.elemList.Peek().(Element)
 }
 
+// Reorder restores the order of the queue after the priorities of its elements changed
+func (pq *PriorityQueue) Reorder() {
+	heap.Init(pq.elemList)
+}
+
 func (pq *PriorityQueue) Remove(index int) error {
 	numElems := pq.elemList.Len()
 	if index >= numElems {
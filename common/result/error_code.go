@@ -37,6 +37,7 @@ const (
 	CodeFeeLimitTooHigh                   ErrorCode = 105004
 	CodeInvalidGasLimit                   ErrorCode = 105005
 	CodeDoNotSupportNativeScriptInSubchain ErrorCode = 105006
+	CodeBlockGasLimitExceeded             ErrorCode = 105007
 
 	// Stake Deposit/Withdrawal Errors
 	CodeInvalidStakePurpose     ErrorCode = 106001
@@ -409,6 +409,15 @@ const (
 
 	// ChannelIDAggregatedEliteEdgeNodeVotes indicates the channel for Elite Edge Node aggregated vote messages
 	ChannelIDAggregatedEliteEdgeNodeVotes
+
+	// ChannelIDStateNode indicates the channel for retrieving state trie nodes from peers
+	ChannelIDStateNode
+
+	// ChannelIDCompactBlock indicates the channel for compact block relay between peers
+	ChannelIDCompactBlock
+
+	// ChannelIDVoteSummary indicates the channel for the aggregated vote summaries between peers
+	ChannelIDVoteSummary
 )
 
 // P2POptEnum defines the p2p network
@@ -249,7 +249,7 @@ func (e *EliteEdgeNodeEngine) HandleVote(vote *core.EENVote) {
 	case e.evIncoming <- vote:
 		return
 	default:
-		e.logger.Debug("EliteEdgeNodeEngine queue is full, discarding elite edge node vote: %v", vote)
+		e.logger.Debugf("EliteEdgeNodeEngine queue is full, discarding elite edge node vote: %v", vote)
 	}
 }
 
@@ -258,7 +258,7 @@ func (e *EliteEdgeNodeEngine) HandleAggregatedVote(vote *core.AggregatedEENVotes
 	case e.aevIncoming <- vote:
 		return
 	default:
-		e.logger.Debug("EliteEdgeNodeEngine queue is full, discarding aggregated elite edge node vote: %v", vote)
+		e.logger.Debugf("EliteEdgeNodeEngine queue is full, discarding aggregated elite edge node vote: %v", vote)
 	}
 }
 
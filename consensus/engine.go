@@ -15,6 +15,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/scripttoken/script/blockchain"
 	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/common/clock"
 	"github.com/scripttoken/script/common/result"
 	"github.com/scripttoken/script/common/util"
 	"github.com/scripttoken/script/core"
@@ -40,6 +41,7 @@ type ConsensusEngine struct {
 	ledger           core.Ledger
 	lightning         *LightningEngine
 	eliteEdgeNode    *EliteEdgeNodeEngine
+	voteGossip       *VoteGossip
 
 	incoming        chan interface{}
 	finalizedBlocks chan *core.Block
@@ -52,9 +54,10 @@ type ConsensusEngine struct {
 	stopped bool
 
 	mu            *sync.Mutex
-	voteTimer     *time.Timer
-	epochTimer    *time.Timer
-	lightningTimer *time.Ticker
+	clock         clock.Clock
+	voteTimer     clock.Timer
+	epochTimer    clock.Timer
+	lightningTimer clock.Ticker
 
 	voteTimerReady bool
 	blockProcessed bool
@@ -100,6 +103,7 @@ func NewConsensusEngine(privateKey *crypto.PrivateKey, db store.Store, chain *bl
 		wg: &sync.WaitGroup{},
 
 		mu:    &sync.Mutex{},
+		clock: clock.NewRealClock(),
 		state: NewState(db, chain, forcedLastVote),
 
 		validatorManager: validatorManager,
@@ -118,6 +122,7 @@ func NewConsensusEngine(privateKey *crypto.PrivateKey, db store.Store, chain *bl
 	}
 	e.lightning = NewLightningEngine(e, blsKey)
 	e.eliteEdgeNode = NewEliteEdgeNodeEngine(e, blsKey)
+	e.voteGossip = NewVoteGossip(dispatcher)
 
 	e.logger.WithFields(log.Fields{"state": e.state}).Info("Starting state")
 
@@ -128,6 +133,11 @@ func (e *ConsensusEngine) SetLedger(ledger core.Ledger) {
 	e.ledger = ledger
 }
 
+// SetClock replaces the time source of the engine, it must be called before Start
+func (e *ConsensusEngine) SetClock(clock clock.Clock) {
+	e.clock = clock
+}
+
 // GetLedger returns the ledger instance attached to the consensus engine
 func (e *ConsensusEngine) GetLedger() core.Ledger {
 	return e.ledger
@@ -298,16 +308,16 @@ func (e *ConsensusEngine) mainLoop() {
 				if endEpoch {
 					break Epoch
 				}
-			case <-e.voteTimer.C:
+			case <-e.voteTimer.C():
 				e.voteTimerReady = true
 				if e.blockProcessed {
 					e.vote()
 				}
-			case <-e.epochTimer.C:
+			case <-e.epochTimer.C():
 				e.logger.WithFields(log.Fields{"e.epoch": e.GetEpoch()}).Debug("Epoch timeout. Repeating epoch")
 				e.vote()
 				break Epoch
-			case <-e.lightningTimer.C:
+			case <-e.lightningTimer.C():
 				v := e.lightning.GetVoteToBroadcast()
 
 				if v != nil {
@@ -336,12 +346,12 @@ func (e *ConsensusEngine) enterEpoch() {
 	if e.epochTimer != nil {
 		e.epochTimer.Stop()
 	}
-	e.epochTimer = time.NewTimer(time.Duration(viper.GetInt(common.CfgConsensusMaxEpochLength)) * time.Second)
+	e.epochTimer = e.clock.NewTimer(time.Duration(viper.GetInt(common.CfgConsensusMaxEpochLength)) * time.Second)
 
 	if e.voteTimer != nil {
 		e.voteTimer.Stop()
 	}
-	e.voteTimer = time.NewTimer(time.Duration(viper.GetInt(common.CfgConsensusMinBlockInterval)) * time.Second)
+	e.voteTimer = e.clock.NewTimer(time.Duration(viper.GetInt(common.CfgConsensusMinBlockInterval)) * time.Second)
 
 	e.voteTimerReady = false
 	e.blockProcessed = false
@@ -406,7 +416,7 @@ func (e *ConsensusEngine) checkSyncStatus() error {
 	// current finalized height is at most maxVoteHeight-1
 	currentHeight := uint64(maxVoteHeight - 1)
 
-	e.hasSynced = !isSyncing(e.GetLastFinalizedBlock(), currentHeight)
+	e.hasSynced = !isSyncing(e.GetLastFinalizedBlock(), currentHeight, e.clock.Now())
 
 	return nil
 }
@@ -1092,11 +1102,7 @@ func (e *ConsensusEngine) broadcastLightningVote(vote *core.AggregatedVotes) {
 		e.logger.WithFields(log.Fields{"lightning vote": vote}).Error("Failed to encode vote")
 		return
 	}
-	voteMsg := dispatcher.DataResponse{
-		ChannelID: common.ChannelIDLightning,
-		Payload:   payload,
-	}
-	e.dispatcher.SendData([]string{}, voteMsg)
+	e.voteGossip.BroadcastLightningVote(vote, payload)
 }
 
 func (e *ConsensusEngine) handleEliteEdgeNodeVote(v *core.EENVote) {
@@ -1113,11 +1119,12 @@ func (e *ConsensusEngine) broadcastAggregatedEliteEdgeNodeVotes(vote *core.Aggre
 		e.logger.WithFields(log.Fields{"elite edge node vote": vote}).Error("Failed to encode vote")
 		return
 	}
-	voteMsg := dispatcher.DataResponse{
-		ChannelID: common.ChannelIDAggregatedEliteEdgeNodeVotes,
-		Payload:   payload,
-	}
-	e.dispatcher.SendData([]string{}, voteMsg)
+	e.voteGossip.BroadcastAggregatedEENVotes(vote, payload)
+}
+
+// VoteGossip returns the relay of the aggregated votes
+func (e *ConsensusEngine) VoteGossip() *VoteGossip {
+	return e.voteGossip
 }
 
 // GetSummary returns a summary of consensus state.
@@ -1193,10 +1200,18 @@ func (e *ConsensusEngine) finalizeBlock(block *core.ExtendedBlock) error {
 	// duplicate TX in fork.
 	e.chain.AddTxsToIndex(block, true)
 
+	if viper.GetBool(common.CfgStorageIndexLogs) {
+		e.chain.AddLogsToIndex(block)
+	}
+	if viper.GetBool(common.CfgStorageIndexAccountTxs) {
+		e.chain.AddAccountTxsToIndex(block)
+	}
+
 	// Lightnings and Elite Edge Nodes to vote for checkpoint blocks.
 	if common.IsCheckPointHeight(block.Height) {
 		e.lightning.StartNewBlock(block.Hash())
 		e.eliteEdgeNode.StartNewBlock(block.Hash())
+		e.voteGossip.StartNewBlock(block.Hash())
 		e.resetLightningTimer()
 	}
 
@@ -1291,7 +1306,7 @@ func (e *ConsensusEngine) createProposal(shouldIncludeValidatorUpdateTxs bool) (
 	block.Parent = tip.Hash()
 	block.Height = tip.Height + 1
 	block.Proposer = e.privateKey.PublicKey().Address()
-	block.Timestamp = big.NewInt(time.Now().Unix())
+	block.Timestamp = big.NewInt(e.clock.Now().Unix())
 	block.HCC.BlockHash = e.state.GetHighestCCBlock().Hash()
 	hccValidators := e.validatorManager.GetValidatorSet(block.HCC.BlockHash)
 	block.HCC.Votes = e.chain.FindVotesByHash(block.HCC.BlockHash).UniqueVoter().FilterByValidators(hccValidators)
@@ -1419,14 +1434,14 @@ func (e *ConsensusEngine) resetLightningTimer() {
 	if e.lightningTimer != nil {
 		e.lightningTimer.Stop()
 	}
-	e.lightningTimer = time.NewTicker(time.Duration(viper.GetInt(common.CfgLightningRoundLength)) * time.Second)
+	e.lightningTimer = e.clock.NewTicker(time.Duration(viper.GetInt(common.CfgLightningRoundLength)) * time.Second)
 }
 
-func isSyncing(lastestFinalizedBlock *core.ExtendedBlock, currentHeight uint64) bool {
+func isSyncing(lastestFinalizedBlock *core.ExtendedBlock, currentHeight uint64, now time.Time) bool {
 	if lastestFinalizedBlock == nil {
 		return true
 	}
-	currentTime := big.NewInt(time.Now().Unix())
+	currentTime := big.NewInt(now.Unix())
 	maxDiff := new(big.Int).SetUint64(30) // thirty seconds, about 5 blocks
 	threshold := new(big.Int).Sub(currentTime, maxDiff)
 	isSyncing := lastestFinalizedBlock.Timestamp.Cmp(threshold) < 0
@@ -217,7 +217,7 @@ func (g *LightningEngine) HandleVote(vote *core.AggregatedVotes) {
 	case g.incoming <- vote:
 		return
 	default:
-		g.logger.Debug("LightningEngine queue is full, discarding vote: %v", vote)
+		g.logger.Debugf("LightningEngine queue is full, discarding vote: %v", vote)
 	}
 }
 
@@ -0,0 +1,300 @@
+package consensus
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/common/util"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/dispatcher"
+	p2ptypes "github.com/scripttoken/script/p2p/types"
+	"github.com/scripttoken/script/rlp"
+)
+
+const (
+	voteGossipMinFanout = 2
+
+	// The fan-out is adjusted every voteGossipWindow received votes, based on the
+	// share of them adding no signer
+	voteGossipWindow            = 32
+	voteGossipHighDuplicateRate = 0.5
+	voteGossipLowDuplicateRate  = 0.2
+)
+
+//
+// VoteGossipStats shows the traffic of the aggregated vote gossip
+//
+type VoteGossipStats struct {
+	Fanout            int    `json:"fanout"`
+	VotesSent         uint64 `json:"votes_sent"`
+	VotesSkipped      uint64 `json:"votes_skipped"` // not sent since the peer already holds all the signers
+	VotesReceived     uint64 `json:"votes_received"`
+	DuplicateVotes    uint64 `json:"duplicate_votes"` // received votes adding no signer
+	SummariesSent     uint64 `json:"summaries_sent"`
+	SummariesReceived uint64 `json:"summaries_received"`
+	BytesSent         uint64 `json:"bytes_sent"`  // votes and summaries
+	BytesSaved        uint64 `json:"bytes_saved"` // votes skipped
+}
+
+// voteGossipState tracks the signers held by the peers for the block being voted on
+type voteGossipState struct {
+	block      common.Hash
+	seen       core.SignerSet            // signers of the votes held or received
+	known      map[string]core.SignerSet // signers held by each peer
+	advertised core.SignerSet            // signers of the last summary sent
+}
+
+func newVoteGossipState(block common.Hash) *voteGossipState {
+	return &voteGossipState{
+		block:      block,
+		seen:       core.SignerSet{},
+		known:      make(map[string]core.SignerSet),
+		advertised: core.SignerSet{},
+	}
+}
+
+func (s *voteGossipState) addKnown(peerID string, signers core.SignerSet) {
+	known, ok := s.known[peerID]
+	if !ok {
+		known = core.SignerSet{}
+		s.known[peerID] = known
+	}
+	known.Add(signers)
+}
+
+//
+// VoteGossip relays the aggregated lightning and elite edge node votes. The peers advertising
+// CapabilityVoteSummary exchange the summaries of the votes they hold, and only receive
+// the votes adding signers. The number of such peers a vote is sent to in each round
+// adapts to the share of duplicate votes received. The other peers receive every vote.
+//
+type VoteGossip struct {
+	logger     *log.Entry
+	dispatcher *dispatcher.Dispatcher
+	enabled    bool
+	maxFanout  int
+
+	mu               *sync.Mutex
+	lightning        *voteGossipState
+	een              *voteGossipState
+	fanout           int
+	windowReceived   int
+	windowDuplicates int
+	stats            VoteGossipStats
+}
+
+// NewVoteGossip creates an instance of VoteGossip
+func NewVoteGossip(dispatcher *dispatcher.Dispatcher) *VoteGossip {
+	maxFanout := viper.GetInt(common.CfgConsensusVoteGossipMaxFanout)
+	if maxFanout < voteGossipMinFanout {
+		maxFanout = voteGossipMinFanout
+	}
+	return &VoteGossip{
+		logger:     util.GetLoggerForModule("votegossip"),
+		dispatcher: dispatcher,
+		enabled:    viper.GetBool(common.CfgConsensusVoteGossipEnabled),
+		maxFanout:  maxFanout,
+
+		mu:        &sync.Mutex{},
+		lightning: newVoteGossipState(common.Hash{}),
+		een:       newVoteGossipState(common.Hash{}),
+		fanout:    maxFanout,
+	}
+}
+
+// StartNewBlock resets the signers held by the peers when voting on a new block
+func (vg *VoteGossip) StartNewBlock(block common.Hash) {
+	vg.mu.Lock()
+	defer vg.mu.Unlock()
+
+	vg.lightning = newVoteGossipState(block)
+	vg.een = newVoteGossipState(block)
+}
+
+// BroadcastLightningVote sends the encoded lightning vote to the peers
+func (vg *VoteGossip) BroadcastLightningVote(vote *core.AggregatedVotes, payload common.Bytes) {
+	vg.broadcast(common.ChannelIDLightning, vote.Block, core.LightningSigners(vote), payload, core.NewLightningVoteSummary(vote))
+}
+
+// BroadcastAggregatedEENVotes sends the encoded aggregated elite edge node vote to the peers
+func (vg *VoteGossip) BroadcastAggregatedEENVotes(vote *core.AggregatedEENVotes, payload common.Bytes) {
+	vg.broadcast(common.ChannelIDAggregatedEliteEdgeNodeVotes, vote.Block, core.EENSigners(vote), payload, core.NewEENVoteSummary(vote))
+}
+
+// ObserveLightningVote records the lightning vote received from the peer
+func (vg *VoteGossip) ObserveLightningVote(peerID string, vote *core.AggregatedVotes) {
+	vg.observe(common.ChannelIDLightning, peerID, vote.Block, core.LightningSigners(vote))
+}
+
+// ObserveAggregatedEENVotes records the aggregated elite edge node vote received from the peer
+func (vg *VoteGossip) ObserveAggregatedEENVotes(peerID string, vote *core.AggregatedEENVotes) {
+	vg.observe(common.ChannelIDAggregatedEliteEdgeNodeVotes, peerID, vote.Block, core.EENSigners(vote))
+}
+
+// HandleVoteSummary records the signers held by the peer
+func (vg *VoteGossip) HandleVoteSummary(peerID string, summary *core.VoteSummary) {
+	vg.mu.Lock()
+	defer vg.mu.Unlock()
+
+	state := vg.state(summary.ChannelID)
+	if state == nil || summary.Block != state.block {
+		return
+	}
+	vg.stats.SummariesReceived++
+	state.addKnown(peerID, summary.SignerSet())
+}
+
+// Stats returns the traffic of the vote gossip
+func (vg *VoteGossip) Stats() VoteGossipStats {
+	vg.mu.Lock()
+	defer vg.mu.Unlock()
+
+	stats := vg.stats
+	stats.Fanout = vg.fanout
+	return stats
+}
+
+func (vg *VoteGossip) state(channelID common.ChannelIDEnum) *voteGossipState {
+	switch channelID {
+	case common.ChannelIDLightning:
+		return vg.lightning
+	case common.ChannelIDAggregatedEliteEdgeNodeVotes:
+		return vg.een
+	default:
+		return nil
+	}
+}
+
+func (vg *VoteGossip) broadcast(channelID common.ChannelIDEnum, block common.Hash, signers core.SignerSet,
+	payload common.Bytes, summary *core.VoteSummary) {
+	voteMsg := dispatcher.DataResponse{
+		ChannelID: channelID,
+		Payload:   payload,
+	}
+
+	var peers []string
+	if vg.enabled {
+		peers = vg.dispatcher.PeersWithCapability(p2ptypes.CapabilityVoteSummary, true)
+	}
+	if len(peers) == 0 {
+		// None of the peers exchange summaries
+		vg.dispatcher.SendData([]string{}, voteMsg)
+		return
+	}
+
+	vg.mu.Lock()
+	state := vg.state(channelID)
+	if state.block != block {
+		vg.mu.Unlock()
+		vg.dispatcher.SendData([]string{}, voteMsg)
+		return
+	}
+	state.seen.Add(signers)
+
+	candidates := []string{}
+	for _, peerID := range peers {
+		if known, ok := state.known[peerID]; ok && known.Contains(signers) {
+			vg.stats.VotesSkipped++
+			vg.stats.BytesSaved += uint64(len(payload))
+			continue
+		}
+		candidates = append(candidates, peerID)
+	}
+	targets := util.Sample(candidates, vg.fanout)
+	for _, peerID := range targets {
+		state.addKnown(peerID, signers)
+	}
+	vg.stats.VotesSent += uint64(len(targets))
+	vg.stats.BytesSent += uint64(len(targets) * len(payload))
+
+	sendSummary := !state.advertised.Contains(signers)
+	if sendSummary {
+		state.advertised = core.SignerSet{}
+		state.advertised.Add(signers)
+	}
+	vg.mu.Unlock()
+
+	if len(targets) > 0 {
+		vg.dispatcher.SendData(targets, voteMsg)
+	}
+
+	// The peers not exchanging summaries receive every vote
+	legacyPeers := []string{}
+	isSummaryPeer := make(map[string]bool, len(peers))
+	for _, peerID := range peers {
+		isSummaryPeer[peerID] = true
+	}
+	for _, peerID := range vg.dispatcher.Peers(true) {
+		if !isSummaryPeer[peerID] {
+			legacyPeers = append(legacyPeers, peerID)
+		}
+	}
+	legacyPeers = util.Sample(legacyPeers, viper.GetInt(common.CfgP2PMaxNumPeersToBroadcast))
+	if len(legacyPeers) > 0 {
+		vg.dispatcher.SendData(legacyPeers, voteMsg)
+	}
+
+	if sendSummary {
+		vg.sendSummary(peers, summary)
+	}
+}
+
+func (vg *VoteGossip) sendSummary(peers []string, summary *core.VoteSummary) {
+	payload, err := rlp.EncodeToBytes(summary)
+	if err != nil {
+		vg.logger.WithFields(log.Fields{"summary": summary}).Error("Failed to encode vote summary")
+		return
+	}
+
+	vg.mu.Lock()
+	vg.stats.SummariesSent += uint64(len(peers))
+	vg.stats.BytesSent += uint64(len(peers) * len(payload))
+	vg.mu.Unlock()
+
+	vg.dispatcher.SendData(peers, dispatcher.DataResponse{
+		ChannelID: common.ChannelIDVoteSummary,
+		Payload:   payload,
+	})
+}
+
+func (vg *VoteGossip) observe(channelID common.ChannelIDEnum, peerID string, block common.Hash, signers core.SignerSet) {
+	vg.mu.Lock()
+	defer vg.mu.Unlock()
+
+	state := vg.state(channelID)
+	if state.block != block {
+		return
+	}
+	state.addKnown(peerID, signers)
+
+	vg.stats.VotesReceived++
+	vg.windowReceived++
+	if state.seen.Contains(signers) {
+		vg.stats.DuplicateVotes++
+		vg.windowDuplicates++
+	} else {
+		state.seen.Add(signers)
+	}
+
+	if vg.windowReceived >= voteGossipWindow {
+		vg.adjustFanout(float64(vg.windowDuplicates) / float64(vg.windowReceived))
+		vg.windowReceived = 0
+		vg.windowDuplicates = 0
+	}
+}
+
+// adjustFanout sends the votes to fewer peers when most of the received votes are
+// duplicates, and to more peers when most of them add signers
+func (vg *VoteGossip) adjustFanout(duplicateRate float64) {
+	if duplicateRate > voteGossipHighDuplicateRate && vg.fanout > voteGossipMinFanout {
+		vg.fanout--
+	} else if duplicateRate < voteGossipLowDuplicateRate && vg.fanout < vg.maxFanout {
+		vg.fanout++
+	}
+	vg.logger.WithFields(log.Fields{
+		"duplicateRate": duplicateRate,
+		"fanout":        vg.fanout,
+	}).Debug("Adjusted vote gossip fan-out")
+}
@@ -0,0 +1,275 @@
+package consensus
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/spf13/viper"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/dispatcher"
+	"github.com/scripttoken/script/p2p"
+	p2ptypes "github.com/scripttoken/script/p2p/types"
+	"github.com/scripttoken/script/rlp"
+)
+
+func TestVoteGossipLegacyFallback(t *testing.T) {
+	assert := assert.New(t)
+
+	block := common.BytesToHash([]byte("block"))
+	vote := newTestLightningVote(block, 0, 1)
+
+	// None of the peers exchange summaries
+	net := newMockVoteNetwork("legacy1", "legacy2")
+	vg := NewVoteGossip(dispatcher.NewDispatcher(net))
+	vg.StartNewBlock(block)
+	vg.BroadcastLightningVote(vote, common.Bytes("vote"))
+	assert.Equal(map[string][]common.ChannelIDEnum{"": {common.ChannelIDLightning}}, net.collectSent())
+
+	// The feature is disabled
+	net = newMockVoteNetwork("peer1", "legacy1")
+	net.info["peer1"] = &p2ptypes.HandshakeInfo{Capabilities: []string{p2ptypes.CapabilityVoteSummary}}
+	viper.Set(common.CfgConsensusVoteGossipEnabled, false)
+	vg = NewVoteGossip(dispatcher.NewDispatcher(net))
+	viper.Set(common.CfgConsensusVoteGossipEnabled, true)
+	vg.StartNewBlock(block)
+	vg.BroadcastLightningVote(vote, common.Bytes("vote"))
+	assert.Equal(map[string][]common.ChannelIDEnum{"": {common.ChannelIDLightning}}, net.collectSent())
+
+	// The vote is not for the block being voted on
+	vg = NewVoteGossip(dispatcher.NewDispatcher(net))
+	vg.StartNewBlock(common.BytesToHash([]byte("other")))
+	vg.BroadcastLightningVote(vote, common.Bytes("vote"))
+	assert.Equal(map[string][]common.ChannelIDEnum{"": {common.ChannelIDLightning}}, net.collectSent())
+}
+
+func TestVoteGossipPeerSelection(t *testing.T) {
+	assert := assert.New(t)
+
+	block := common.BytesToHash([]byte("block"))
+	net := newMockVoteNetwork("peer1", "peer2", "legacy1")
+	net.info["peer1"] = &p2ptypes.HandshakeInfo{Capabilities: []string{p2ptypes.CapabilityVoteSummary}}
+	net.info["peer2"] = &p2ptypes.HandshakeInfo{Capabilities: []string{p2ptypes.CapabilityVoteSummary}}
+	net.info["legacy1"] = &p2ptypes.HandshakeInfo{}
+	vg := NewVoteGossip(dispatcher.NewDispatcher(net))
+	vg.StartNewBlock(block)
+
+	// The summary peers receive the vote and its summary, the legacy peer the vote only
+	vg.BroadcastLightningVote(newTestLightningVote(block, 0), common.Bytes("vote"))
+	assert.Equal(map[string][]common.ChannelIDEnum{
+		"peer1":   {common.ChannelIDLightning, common.ChannelIDVoteSummary},
+		"peer2":   {common.ChannelIDLightning, common.ChannelIDVoteSummary},
+		"legacy1": {common.ChannelIDLightning},
+	}, net.collectSent())
+
+	// peer1 already holds the signers of the next vote
+	summary := core.NewLightningVoteSummary(newTestLightningVote(block, 0, 1))
+	vg.HandleVoteSummary("peer1", summary)
+	vg.BroadcastLightningVote(newTestLightningVote(block, 0, 1), common.Bytes("vote"))
+	assert.Equal(map[string][]common.ChannelIDEnum{
+		"peer1":   {common.ChannelIDVoteSummary},
+		"peer2":   {common.ChannelIDLightning, common.ChannelIDVoteSummary},
+		"legacy1": {common.ChannelIDLightning},
+	}, net.collectSent())
+
+	// The same vote again is only sent to the legacy peer
+	vg.BroadcastLightningVote(newTestLightningVote(block, 0, 1), common.Bytes("vote"))
+	assert.Equal(map[string][]common.ChannelIDEnum{"legacy1": {common.ChannelIDLightning}}, net.collectSent())
+
+	stats := vg.Stats()
+	assert.Equal(uint64(3), stats.VotesSent)
+	assert.Equal(uint64(3), stats.VotesSkipped)
+	assert.Equal(uint64(4), stats.SummariesSent)
+	assert.Equal(uint64(1), stats.SummariesReceived)
+
+	// The summaries for other blocks are ignored
+	vg.StartNewBlock(common.BytesToHash([]byte("next")))
+	vg.HandleVoteSummary("peer1", summary)
+	assert.Equal(uint64(1), vg.Stats().SummariesReceived)
+}
+
+func TestVoteGossipFanout(t *testing.T) {
+	assert := assert.New(t)
+
+	block := common.BytesToHash([]byte("block"))
+	peerIDs := []string{"peer1", "peer2", "peer3", "peer4"}
+	net := newMockVoteNetwork(peerIDs...)
+	for _, peerID := range peerIDs {
+		net.info[peerID] = &p2ptypes.HandshakeInfo{Capabilities: []string{p2ptypes.CapabilityVoteSummary}}
+	}
+	viper.Set(common.CfgConsensusVoteGossipMaxFanout, 3)
+	vg := NewVoteGossip(dispatcher.NewDispatcher(net))
+	viper.Set(common.CfgConsensusVoteGossipMaxFanout, 8)
+	vg.StartNewBlock(block)
+	assert.Equal(3, vg.Stats().Fanout)
+
+	// The vote is sent to fanout peers, and the summary to all of them
+	vg.BroadcastLightningVote(newTestLightningVote(block, 0), common.Bytes("vote"))
+	votes := 0
+	for _, channelIDs := range net.collectSent() {
+		if channelIDs[0] == common.ChannelIDLightning {
+			votes++
+			channelIDs = channelIDs[1:]
+		}
+		assert.Equal([]common.ChannelIDEnum{common.ChannelIDVoteSummary}, channelIDs)
+	}
+	assert.Equal(3, votes)
+
+	// Mostly duplicate votes reduce the fan-out, down to the minimum
+	vote := newTestLightningVote(block, 0)
+	for i := 0; i < 3*voteGossipWindow; i++ {
+		vg.ObserveLightningVote("peer1", vote)
+	}
+	assert.Equal(voteGossipMinFanout, vg.Stats().Fanout)
+	assert.Equal(uint64(3*voteGossipWindow), vg.Stats().DuplicateVotes)
+
+	// Votes adding signers increase it, up to the maximum
+	for i := 1; i <= 3*voteGossipWindow; i++ {
+		vg.ObserveLightningVote("peer1", newTestLightningVote(block, i))
+	}
+	assert.Equal(3, vg.Stats().Fanout)
+}
+
+func TestVoteGossipAdvertised(t *testing.T) {
+	assert := assert.New(t)
+
+	block := common.BytesToHash([]byte("block"))
+	net := newMockVoteNetwork("peer1")
+	net.info["peer1"] = &p2ptypes.HandshakeInfo{Capabilities: []string{p2ptypes.CapabilityVoteSummary}}
+	vg := NewVoteGossip(dispatcher.NewDispatcher(net))
+	vg.StartNewBlock(block)
+
+	vg.BroadcastLightningVote(newTestLightningVote(block, 0, 1), common.Bytes("vote"))
+	assert.Equal(core.SignerSet{0: true, 1: true}, net.lastSummary(assert).SignerSet())
+
+	// A subset of the advertised signers is not advertised again
+	vg.BroadcastLightningVote(newTestLightningVote(block, 1), common.Bytes("vote"))
+	assert.Nil(net.lastSummary(assert))
+
+	// The advertised signers are replaced by those of a vote adding signers, since the
+	// summary describes the vote sent
+	vg.BroadcastLightningVote(newTestLightningVote(block, 2), common.Bytes("vote"))
+	assert.Equal(core.SignerSet{2: true}, net.lastSummary(assert).SignerSet())
+	vg.BroadcastLightningVote(newTestLightningVote(block, 0), common.Bytes("vote"))
+	assert.Equal(core.SignerSet{0: true}, net.lastSummary(assert).SignerSet())
+
+	// Nothing is advertised for a new block yet
+	next := common.BytesToHash([]byte("next"))
+	vg.StartNewBlock(next)
+	vg.BroadcastLightningVote(newTestLightningVote(next, 0), common.Bytes("vote"))
+	assert.Equal(core.SignerSet{0: true}, net.lastSummary(assert).SignerSet())
+
+	// The elite edge node votes are advertised separately
+	address := common.HexToAddress("0x2e833968e5bb786ae419c4d13189fb081cc43bab")
+	eenVote := &core.AggregatedEENVotes{Block: next, Multiplies: []uint32{1}, Addresses: []common.Address{address}}
+	vg.BroadcastAggregatedEENVotes(eenVote, common.Bytes("vote"))
+	summary := net.lastSummary(assert)
+	assert.Equal(common.ChannelIDAggregatedEliteEdgeNodeVotes, summary.ChannelID)
+	assert.Equal(core.EENSigners(eenVote), summary.SignerSet())
+}
+
+// --------------- Test Utilities --------------- //
+
+func newTestLightningVote(block common.Hash, signers ...int) *core.AggregatedVotes {
+	vote := &core.AggregatedVotes{Block: block, Multiplies: make([]uint32, 3*voteGossipWindow+1)}
+	for _, i := range signers {
+		vote.Multiplies[i] = 1
+	}
+	return vote
+}
+
+type mockVoteSent struct {
+	peerID  string // empty if broadcast
+	message p2ptypes.Message
+}
+
+// mockVoteNetwork records the messages sent through it. Each peer advertises the handshake
+// info set in info, the peers without one run an older version.
+type mockVoteNetwork struct {
+	peerIDs []string
+	info    map[string]*p2ptypes.HandshakeInfo
+	sent    chan mockVoteSent
+}
+
+func newMockVoteNetwork(peerIDs ...string) *mockVoteNetwork {
+	return &mockVoteNetwork{
+		peerIDs: peerIDs,
+		info:    make(map[string]*p2ptypes.HandshakeInfo),
+		sent:    make(chan mockVoteSent, 128),
+	}
+}
+
+func (mn *mockVoteNetwork) Start(ctx context.Context) error { return nil }
+func (mn *mockVoteNetwork) Wait()                           {}
+func (mn *mockVoteNetwork) Stop()                           {}
+func (mn *mockVoteNetwork) Broadcast(message p2ptypes.Message, skipEdgeNode bool) chan bool {
+	mn.sent <- mockVoteSent{message: message}
+	return make(chan bool, 1)
+}
+func (mn *mockVoteNetwork) BroadcastToNeighbors(message p2ptypes.Message, maxNumPeersToBroadcast int, skipEdgeNode bool) chan bool {
+	return mn.Broadcast(message, skipEdgeNode)
+}
+func (mn *mockVoteNetwork) Send(peerID string, message p2ptypes.Message) bool {
+	mn.sent <- mockVoteSent{peerID: peerID, message: message}
+	return true
+}
+func (mn *mockVoteNetwork) Peers(skipEdgeNode bool) []string    { return mn.peerIDs }
+func (mn *mockVoteNetwork) PeerURLs(skipEdgeNode bool) []string { return mn.peerIDs }
+func (mn *mockVoteNetwork) PeerExists(peerID string) bool {
+	for _, id := range mn.peerIDs {
+		if id == peerID {
+			return true
+		}
+	}
+	return false
+}
+func (mn *mockVoteNetwork) PeerHandshakeInfo(peerID string) *p2ptypes.HandshakeInfo {
+	return mn.info[peerID]
+}
+func (mn *mockVoteNetwork) NetworkStats(skipEdgeNode bool) *p2ptypes.NetworkStats {
+	return &p2ptypes.NetworkStats{}
+}
+func (mn *mockVoteNetwork) RegisterMessageHandler(messageHandler p2p.MessageHandler) {}
+func (mn *mockVoteNetwork) IsSeedPeer(peerID string) bool                          { return false }
+func (mn *mockVoteNetwork) ID() string                                             { return "local" }
+
+// nextSent returns the next message sent, nil if none is sent in time. The dispatcher
+// sends the messages to the peers asynchronously.
+func (mn *mockVoteNetwork) nextSent() *mockVoteSent {
+	select {
+	case sent := <-mn.sent:
+		return &sent
+	case <-time.After(200 * time.Millisecond):
+		return nil
+	}
+}
+
+// collectSent returns the channels of the messages sent to each peer, the votes first
+func (mn *mockVoteNetwork) collectSent() map[string][]common.ChannelIDEnum {
+	sent := make(map[string][]common.ChannelIDEnum)
+	for s := mn.nextSent(); s != nil; s = mn.nextSent() {
+		sent[s.peerID] = append(sent[s.peerID], s.message.ChannelID)
+	}
+	for _, channelIDs := range sent {
+		sort.Slice(channelIDs, func(i, j int) bool {
+			return channelIDs[i] != common.ChannelIDVoteSummary && channelIDs[j] == common.ChannelIDVoteSummary
+		})
+	}
+	return sent
+}
+
+// lastSummary returns the last vote summary sent, nil if none is sent
+func (mn *mockVoteNetwork) lastSummary(assert *assert.Assertions) *core.VoteSummary {
+	var summary *core.VoteSummary
+	for s := mn.nextSent(); s != nil; s = mn.nextSent() {
+		if s.message.ChannelID != common.ChannelIDVoteSummary {
+			continue
+		}
+		summary = &core.VoteSummary{}
+		assert.Nil(rlp.DecodeBytes(s.message.Content.(dispatcher.DataResponse).Payload, summary))
+	}
+	return summary
+}
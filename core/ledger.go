@@ -22,6 +22,24 @@ type TxInfo struct {
 	EffectiveGasPrice *big.Int
 	Address           common.Address
 	Sequence          uint64
+	GasLimit          uint64 // 0 for the transactions not running on the EVM
+
+	// Only set for the dynamic fee transactions, whose effective gas price depends on the base fee
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+}
+
+// UpdateEffectiveGasPrice re-prices a dynamic fee transaction under the given base fee, i.e.
+// min(MaxFeePerGas, baseFee + MaxPriorityFeePerGas). The price of the other transactions is fixed.
+func (ti *TxInfo) UpdateEffectiveGasPrice(baseFee *big.Int) {
+	if ti.MaxFeePerGas == nil || ti.MaxPriorityFeePerGas == nil || baseFee == nil {
+		return
+	}
+	gasPrice := new(big.Int).Add(baseFee, ti.MaxPriorityFeePerGas)
+	if gasPrice.Cmp(ti.MaxFeePerGas) > 0 {
+		gasPrice.Set(ti.MaxFeePerGas)
+	}
+	ti.EffectiveGasPrice = gasPrice
 }
 
 //
@@ -7,7 +7,6 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
-	"os"
 
 	"github.com/scripttoken/script/common"
 	"github.com/scripttoken/script/rlp"
@@ -121,7 +120,7 @@ func writeBytes(writer *bufio.Writer, raw []byte) error {
 	return nil
 }
 
-func ReadRecord(file *os.File, obj interface{}) (uint64, error) {
+func ReadRecord(file io.Reader, obj interface{}) (uint64, error) {
 	sizeBytes := make([]byte, 8)
 	n, err := io.ReadAtLeast(file, sizeBytes, 8)
 	if err != nil {
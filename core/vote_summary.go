@@ -0,0 +1,115 @@
+package core
+
+import (
+	"encoding/binary"
+
+	"github.com/scripttoken/script/common"
+)
+
+//
+// ------- SignerSet ------- //
+//
+
+// SignerSet is a set of signers of an aggregated vote. The signers of the lightning votes
+// are identified by their index in the lightning candidate pool, and the signers of the
+// elite edge node votes by the short ID of their address.
+type SignerSet map[uint64]bool
+
+// LightningSigners returns the signers of a lightning vote, i.e. its non-zero Multiplies entries.
+func LightningSigners(vote *AggregatedVotes) SignerSet {
+	signers := SignerSet{}
+	for i, m := range vote.Multiplies {
+		if m != 0 {
+			signers[uint64(i)] = true
+		}
+	}
+	return signers
+}
+
+// EENSigners returns the signers of an aggregated elite edge node vote.
+func EENSigners(vote *AggregatedEENVotes) SignerSet {
+	signers := SignerSet{}
+	for i, m := range vote.Multiplies {
+		if m != 0 && i < len(vote.Addresses) {
+			signers[signerShortID(vote.Addresses[i])] = true
+		}
+	}
+	return signers
+}
+
+func signerShortID(address common.Address) uint64 {
+	return binary.BigEndian.Uint64(address[:8])
+}
+
+// Add adds the signers of the other set.
+func (s SignerSet) Add(other SignerSet) {
+	for id := range other {
+		s[id] = true
+	}
+}
+
+// Contains indicates whether the set is a superset of the other set.
+func (s SignerSet) Contains(other SignerSet) bool {
+	for id := range other {
+		if !s[id] {
+			return false
+		}
+	}
+	return true
+}
+
+//
+// ------- VoteSummary ------- //
+//
+
+// VoteSummary advertises the signers of the best aggregated vote a node holds, so that its
+// peers only send the aggregated votes adding signers.
+type VoteSummary struct {
+	ChannelID common.ChannelIDEnum // ChannelIDLightning or ChannelIDAggregatedEliteEdgeNodeVotes
+	Block     common.Hash
+	Signers   []byte   // Bitfield of the signer indexes, for the lightning votes
+	SignerIDs []uint64 // Short IDs of the signer addresses, for the elite edge node votes
+}
+
+// NewLightningVoteSummary creates the summary of a lightning vote.
+func NewLightningVoteSummary(vote *AggregatedVotes) *VoteSummary {
+	bitfield := make([]byte, (len(vote.Multiplies)+7)/8)
+	for i, m := range vote.Multiplies {
+		if m != 0 {
+			bitfield[i/8] |= 1 << uint(i%8)
+		}
+	}
+	return &VoteSummary{
+		ChannelID: common.ChannelIDLightning,
+		Block:     vote.Block,
+		Signers:   bitfield,
+	}
+}
+
+// NewEENVoteSummary creates the summary of an aggregated elite edge node vote.
+func NewEENVoteSummary(vote *AggregatedEENVotes) *VoteSummary {
+	summary := &VoteSummary{
+		ChannelID: common.ChannelIDAggregatedEliteEdgeNodeVotes,
+		Block:     vote.Block,
+	}
+	for id := range EENSigners(vote) {
+		summary.SignerIDs = append(summary.SignerIDs, id)
+	}
+	return summary
+}
+
+// SignerSet returns the signers advertised by the summary.
+func (s *VoteSummary) SignerSet() SignerSet {
+	signers := SignerSet{}
+	for i, b := range s.Signers {
+		for j := 0; j < 8; j++ {
+			if b&(1<<uint(j)) != 0 {
+				signers[uint64(i*8+j)] = true
+			}
+		}
+	}
+	for _, id := range s.SignerIDs {
+		signers[id] = true
+	}
+	return signers
+}
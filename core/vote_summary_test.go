@@ -0,0 +1,60 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/rlp"
+)
+
+func TestLightningVoteSummary(t *testing.T) {
+	require := require.New(t)
+
+	vote := &AggregatedVotes{
+		Block:      common.HexToHash("a1"),
+		Multiplies: []uint32{1, 0, 0, 2, 0, 0, 0, 0, 0, 1},
+	}
+	summary := NewLightningVoteSummary(vote)
+	require.Equal(2, len(summary.Signers))
+
+	raw, err := rlp.EncodeToBytes(summary)
+	require.Nil(err)
+	decoded := &VoteSummary{}
+	require.Nil(rlp.DecodeBytes(raw, decoded))
+	require.Equal(common.ChannelIDLightning, decoded.ChannelID)
+	require.Equal(vote.Block, decoded.Block)
+
+	signers := decoded.SignerSet()
+	require.Equal(LightningSigners(vote), signers)
+	require.Equal(SignerSet{0: true, 3: true, 9: true}, signers)
+}
+
+func TestEENVoteSummary(t *testing.T) {
+	require := require.New(t)
+
+	vote := &AggregatedEENVotes{
+		Block:      common.HexToHash("a1"),
+		Addresses: []common.Address{
+			common.HexToAddress("0x1000000000000000000000000000000000000001"),
+			common.HexToAddress("0x2000000000000000000000000000000000000002"),
+			common.HexToAddress("0x3000000000000000000000000000000000000003"),
+		},
+		Multiplies: []uint32{1, 0, 3},
+	}
+	summary := NewEENVoteSummary(vote)
+	require.Equal(2, len(summary.SignerIDs))
+	require.Equal(EENSigners(vote), summary.SignerSet())
+}
+
+func TestSignerSet(t *testing.T) {
+	require := require.New(t)
+
+	s := SignerSet{1: true, 2: true}
+	require.True(s.Contains(SignerSet{1: true}))
+	require.True(s.Contains(SignerSet{}))
+	require.False(s.Contains(SignerSet{1: true, 3: true}))
+
+	s.Add(SignerSet{3: true})
+	require.True(s.Contains(SignerSet{1: true, 3: true}))
+}
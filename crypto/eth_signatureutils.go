@@ -6,6 +6,7 @@ import (
 	"math/big"
 
 	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/rlp"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -60,6 +61,15 @@ func EncodeSignature(R, S, Vb *big.Int) (*Signature, error) {
 	return sig, nil
 }
 
+// EncodeTypedSignature encodes the signature of a typed Ethereum transaction (EIP-2718),
+// whose V value is the y parity of the signature instead of 27/28 or the EIP-155 value
+func EncodeTypedSignature(R, S, yParity *big.Int) (*Signature, error) {
+	if yParity.BitLen() > 1 {
+		return nil, errors.New("invalid y parity")
+	}
+	return EncodeSignature(R, S, new(big.Int).Add(yParity, big.NewInt(27)))
+}
+
 func DecodeSignature(sig *Signature) (r, s, v *big.Int) {
 	sigBytes := sig.ToBytes()
 	if len(sigBytes) != SignatureLength {
@@ -125,6 +135,22 @@ func ValidateEthSignature(sender common.Address, signingHash common.Hash, sig *S
 	return nil
 }
 
+// TypedEthSigningHash returns the signing hash of a typed Ethereum transaction (EIP-2718), i.e.
+// the Keccak256 hash of the transaction type followed by the RLP encoded signing payload
+func TypedEthSigningHash(txType byte, payload interface{}) common.Hash {
+	encoded, err := rlp.EncodeToBytes(payload)
+	if err != nil {
+		log.Panic(err)
+	}
+	return Keccak256Hash([]byte{txType}, encoded)
+}
+
+// ValidateTypedEthSignature checks the signature of a typed Ethereum transaction (EIP-2718)
+// against the signing payload of the transaction
+func ValidateTypedEthSignature(sender common.Address, txType byte, payload interface{}, sig *Signature) error {
+	return ValidateEthSignature(sender, TypedEthSigningHash(txType, payload), sig)
+}
+
 // References:
 // https://github.com/ethereum/go-ethereum/blob/087ed9c92ecfe41109c1e039693fc126952a3718/core/types/transaction_signing.go#L263
 // https://github.com/ethereum/go-ethereum/blob/087ed9c92ecfe41109c1e039693fc126952a3718/core/types/transaction_signing.go#L344
@@ -2,6 +2,8 @@ package crypto
 
 import (
 	"crypto/ecdsa"
+	"io"
+	"math/big"
 
 	"github.com/scripttoken/script/common"
 )
@@ -12,12 +14,27 @@ import (
 // WARNING: The following APIs are intended only for unit test case for better repeatibility.
 //          They should NOT be used in the production code.
 
-// TEST_GenerateKeyPairWithSeed generates a random private/public key pair with the given seed string
+// TEST_GenerateKeyPairWithSeed generates a random private/public key pair with the given seed string.
+// The key is derived the way ecdsa.GenerateKey() used to, i.e. a 40 byte number read from the seed,
+// modulo N-1, plus 1, since the newer Go versions derive different keys from the same random bytes.
 func TEST_GenerateKeyPairWithSeed(seed string) (*PrivateKey, *PublicKey, error) {
 	trr := newTestRandReader(seed)
-	ske, err := ecdsa.GenerateKey(s256(), trr)
+	curve := s256()
+	b := make([]byte, curve.Params().BitSize/8+8)
+	if _, err := io.ReadFull(trr, b); err != nil {
+		return nil, nil, err
+	}
+	d := new(big.Int).SetBytes(b)
+	n := new(big.Int).Sub(curve.Params().N, big.NewInt(1))
+	d.Mod(d, n)
+	d.Add(d, big.NewInt(1))
+
+	ske := new(ecdsa.PrivateKey)
+	ske.PublicKey.Curve = curve
+	ske.D = d
+	ske.PublicKey.X, ske.PublicKey.Y = curve.ScalarBaseMult(d.Bytes())
 	pke := &(ske.PublicKey)
-	return &PrivateKey{privKey: ske}, &PublicKey{pubKey: pke}, err
+	return &PrivateKey{privKey: ske}, &PublicKey{pubKey: pke}, nil
 }
 
 type testRandReader struct {
@@ -2,15 +2,15 @@ package dispatcher
 
 import (
 	"context"
-	"reflect"
 	"sync"
 
 	"github.com/spf13/viper"
 
 	"github.com/scripttoken/script/common"
 	"github.com/scripttoken/script/p2p"
+	"github.com/scripttoken/script/p2p/permission"
+	"github.com/scripttoken/script/p2p/reputation"
 	p2ptypes "github.com/scripttoken/script/p2p/types"
-	"github.com/scripttoken/script/p2pl"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -21,8 +21,9 @@ var logger *log.Entry = log.WithFields(log.Fields{"prefix": "dispatcher"})
 // Dispatcher dispatches messages to approporiate destinations
 //
 type Dispatcher struct {
-	p2pnet  p2p.Network
-	p2plnet p2pl.Network
+	network    p2p.Network
+	reputation *reputation.ReputationManager
+	allowlist  *permission.Allowlist
 
 	// Life cycle
 	wg      *sync.WaitGroup
@@ -32,32 +33,60 @@ type Dispatcher struct {
 	stopped bool
 }
 
-// NewLDispatcher returns the pointer to the Dispatcher singleton
-func NewDispatcher(p2pnet p2p.Network, p2plnet p2pl.Network) *Dispatcher {
+// NewDispatcher returns the pointer to the Dispatcher singleton. The network is usually a
+// p2p.Registry composing the enabled transports.
+func NewDispatcher(network p2p.Network) *Dispatcher {
+	if network == nil {
+		network = p2p.NewRegistry()
+	}
 	return &Dispatcher{
-		p2pnet:  p2pnet,
-		p2plnet: p2plnet,
+		network: network,
 		wg:      &sync.WaitGroup{},
 	}
 }
 
+// SetReputationManager sets the ReputationManager the peer misbehaviors are reported to
+func (dp *Dispatcher) SetReputationManager(rm *reputation.ReputationManager) {
+	dp.reputation = rm
+}
+
+// ReputationManager returns the ReputationManager, nil if not set
+func (dp *Dispatcher) ReputationManager() *reputation.ReputationManager {
+	return dp.reputation
+}
+
+// SetAllowlist sets the Allowlist of the permissioned mode
+func (dp *Dispatcher) SetAllowlist(al *permission.Allowlist) {
+	dp.allowlist = al
+}
+
+// Allowlist returns the Allowlist, nil if the permissioned mode is disabled
+func (dp *Dispatcher) Allowlist() *permission.Allowlist {
+	return dp.allowlist
+}
+
+// ReportPeer reports an infraction of the given peer
+func (dp *Dispatcher) ReportPeer(peerID string, infraction reputation.Infraction, reason string) {
+	if dp.reputation != nil {
+		dp.reputation.Report(peerID, infraction, reason)
+	}
+}
+
+// AllowMessage indicates whether a message received from the given peer is within
+// the rate limit of the channel
+func (dp *Dispatcher) AllowMessage(peerID string, channelID common.ChannelIDEnum) bool {
+	if dp.reputation != nil {
+		return dp.reputation.AllowMessage(peerID, channelID)
+	}
+	return true
+}
+
 // Start is called when the dispatcher starts
 func (dp *Dispatcher) Start(ctx context.Context) error {
 	c, cancel := context.WithCancel(ctx)
 	dp.ctx = c
 	dp.cancel = cancel
-	var err error
-
-	if !reflect.ValueOf(dp.p2pnet).IsNil() {
-		err = dp.p2pnet.Start(c)
-		if err != nil {
-			return err
-		}
-	}
-	if !reflect.ValueOf(dp.p2plnet).IsNil() {
-		err = dp.p2plnet.Start(c)
-	}
-	return err
+	return dp.network.Start(c)
 }
 
 // Stop is called when the dispatcher stops
@@ -67,12 +96,7 @@ func (dp *Dispatcher) Stop() {
 
 // Wait suspends the caller goroutine
 func (dp *Dispatcher) Wait() {
-	if !reflect.ValueOf(dp.p2pnet).IsNil() {
-		dp.p2pnet.Wait()
-	}
-	if !reflect.ValueOf(dp.p2plnet).IsNil() {
-		dp.p2plnet.Wait()
-	}
+	dp.network.Wait()
 	dp.wg.Wait()
 }
 
@@ -126,77 +150,64 @@ func (dp *Dispatcher) SendData(peerIDs []string, datarsp DataResponse) {
 
 // ID returns the ID of the node
 func (dp Dispatcher) ID() string {
-	if !reflect.ValueOf(dp.p2pnet).IsNil() {
-		return dp.p2pnet.ID()
-	}
-	if !reflect.ValueOf(dp.p2plnet).IsNil() {
-		return dp.p2plnet.ID()
-	}
-	return ""
+	return dp.network.ID()
 }
 
 // TODO: for 1.3.0 upgrade only, delete it after the upgrade completed
 // ID returns the ID of the node
 func (dp Dispatcher) LibP2PID() string {
-	if !reflect.ValueOf(dp.p2plnet).IsNil() {
-		return dp.p2plnet.ID()
-	}
-	if !reflect.ValueOf(dp.p2pnet).IsNil() {
-		return dp.p2pnet.ID()
+	if registry, ok := dp.network.(*p2p.Registry); ok {
+		if network := registry.Transport(p2ptypes.StackP2PL); network != nil {
+			return network.ID()
+		}
 	}
-	return ""
+	return dp.network.ID()
 }
 
 // Peers returns the IDs of all peers
 func (dp *Dispatcher) Peers(skipEdgeNode bool) []string {
-	if !reflect.ValueOf(dp.p2pnet).IsNil() {
-		return dp.p2pnet.Peers(skipEdgeNode)
-	}
-	if !reflect.ValueOf(dp.p2plnet).IsNil() {
-		return dp.p2plnet.Peers(skipEdgeNode)
-	}
-	return []string{}
+	return dp.network.Peers(skipEdgeNode)
 }
 
 // Peers returns the IDs of all peers
 func (dp *Dispatcher) PeerURLs(skipEdgeNode bool) []string {
-	if !reflect.ValueOf(dp.p2pnet).IsNil() {
-		return dp.p2pnet.PeerURLs(skipEdgeNode)
-	}
-	if !reflect.ValueOf(dp.p2plnet).IsNil() {
-		return dp.p2plnet.PeerURLs(skipEdgeNode)
-	}
-	return []string{}
+	return dp.network.PeerURLs(skipEdgeNode)
 }
 
 // PeerExists indicates if the given peerID is a neighboring peer
 func (dp *Dispatcher) PeerExists(peerID string) bool {
-	if !reflect.ValueOf(dp.p2pnet).IsNil() {
-		return dp.p2pnet.PeerExists(peerID)
-	}
-	if !reflect.ValueOf(dp.p2plnet).IsNil() {
-		return dp.p2plnet.PeerExists(peerID)
+	return dp.network.PeerExists(peerID)
+}
+
+// PeerHandshakeInfo returns the versioned handshake info of the given peer, nil if the
+// peer is not connected or runs an older version
+func (dp *Dispatcher) PeerHandshakeInfo(peerID string) *p2ptypes.HandshakeInfo {
+	return dp.network.PeerHandshakeInfo(peerID)
+}
+
+// NetworkStats returns the diagnostic information of the peers of all the transports
+func (dp *Dispatcher) NetworkStats(skipEdgeNode bool) *p2ptypes.NetworkStats {
+	return dp.network.NetworkStats(skipEdgeNode)
+}
+
+// PeersWithCapability returns the IDs of the peers advertising the given capability
+func (dp *Dispatcher) PeersWithCapability(capability string, skipEdgeNode bool) []string {
+	peerIDs := []string{}
+	for _, peerID := range dp.Peers(skipEdgeNode) {
+		if dp.PeerHandshakeInfo(peerID).HasCapability(capability) {
+			peerIDs = append(peerIDs, peerID)
+		}
 	}
-	return false
+	return peerIDs
 }
 
 // Peers returns the IDs of all peers
 func (dp *Dispatcher) IsSeedPeer(peerID string) bool {
-	if !reflect.ValueOf(dp.p2pnet).IsNil() {
-		return dp.p2pnet.IsSeedPeer(peerID)
-	}
-	if !reflect.ValueOf(dp.p2plnet).IsNil() {
-		return dp.p2plnet.IsSeedPeer(peerID)
-	}
-	return false
+	return dp.network.IsSeedPeer(peerID)
 }
 
 // send delivers message directly to a list of peers.
 func (dp *Dispatcher) send(peerIDs []string, channelID common.ChannelIDEnum, content interface{}) {
-	messageOld := p2ptypes.Message{
-		ChannelID: channelID,
-		Content:   content,
-	}
 	message := p2ptypes.Message{
 		ChannelID: channelID,
 		Content:   content,
@@ -204,14 +215,9 @@ func (dp *Dispatcher) send(peerIDs []string, channelID common.ChannelIDEnum, con
 
 	for _, peerID := range peerIDs {
 		go func(peerID string) {
-			if !reflect.ValueOf(dp.p2pnet).IsNil() {
-				ok := dp.p2pnet.Send(peerID, messageOld)
-				if !ok {
-					logger.Debugf("Failed to send message to [%v]: %v, %v", peerID, channelID, content)
-				}
-			}
-			if !reflect.ValueOf(dp.p2plnet).IsNil() {
-				dp.p2plnet.Send(peerID, message)
+			ok := dp.network.Send(peerID, message)
+			if !ok {
+				logger.Debugf("Failed to send message to [%v]: %v, %v", peerID, channelID, content)
 			}
 		}(peerID)
 	}
@@ -220,38 +226,19 @@ func (dp *Dispatcher) send(peerIDs []string, channelID common.ChannelIDEnum, con
 // broadcastToAll publishes given message through gossip. Usually the message is only immediately delivered to
 // a subset of neighbors.
 func (dp *Dispatcher) broadcastToAll(channelID common.ChannelIDEnum, content interface{}, skipEdgeNode bool) {
-	messageOld := p2ptypes.Message{
-		ChannelID: channelID,
-		Content:   content,
-	}
 	message := p2ptypes.Message{
 		ChannelID: channelID,
 		Content:   content,
 	}
-	if !reflect.ValueOf(dp.p2pnet).IsNil() {
-		dp.p2pnet.Broadcast(messageOld, skipEdgeNode)
-	}
-	if !reflect.ValueOf(dp.p2plnet).IsNil() {
-		dp.p2plnet.Broadcast(message, skipEdgeNode)
-	}
+	dp.network.Broadcast(message, skipEdgeNode)
 }
 
 // broadcastToNeighbors delivers given message to all neighbors.
 func (dp *Dispatcher) broadcastToNeighbors(channelID common.ChannelIDEnum, content interface{}, skipEdgeNode bool) {
-	messageOld := p2ptypes.Message{
-		ChannelID: channelID,
-		Content:   content,
-	}
 	message := p2ptypes.Message{
 		ChannelID: channelID,
 		Content:   content,
 	}
 	maxNumPeersToBroadcast := viper.GetInt(common.CfgP2PMaxNumPeersToBroadcast)
-	if !reflect.ValueOf(dp.p2pnet).IsNil() {
-		//dp.p2pnet.Broadcast(messageOld)
-		dp.p2pnet.BroadcastToNeighbors(messageOld, maxNumPeersToBroadcast, skipEdgeNode)
-	}
-	if !reflect.ValueOf(dp.p2plnet).IsNil() {
-		dp.p2plnet.BroadcastToNeighbors(message, maxNumPeersToBroadcast, skipEdgeNode)
-	}
+	dp.network.BroadcastToNeighbors(message, maxNumPeersToBroadcast, skipEdgeNode)
 }
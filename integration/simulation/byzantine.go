@@ -0,0 +1,81 @@
+package simulation
+
+import (
+	"math/big"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/dispatcher"
+	p2psim "github.com/scripttoken/script/p2p/simulation"
+	"github.com/scripttoken/script/rlp"
+)
+
+// SilenceValidator makes the validator withhold its votes and proposals, while it keeps
+// receiving and syncing blocks
+func (h *Harness) SilenceValidator(index int) {
+	sn := h.Nodes[index]
+	sn.Byzantine = true
+	h.Network.AddInterceptor(func(envelope p2psim.Envelope) []p2psim.Envelope {
+		if envelope.From != sn.ID || envelope.From == envelope.To {
+			return []p2psim.Envelope{envelope}
+		}
+		if envelope.ChannelID == common.ChannelIDVote || envelope.ChannelID == common.ChannelIDProposal {
+			return []p2psim.Envelope{}
+		}
+		return []p2psim.Envelope{envelope}
+	})
+}
+
+// EquivocateProposer makes the validator send conflicting proposals: the nodes with an
+// odd index receive a different block, validly signed by the proposer, for the same
+// height and epoch
+func (h *Harness) EquivocateProposer(index int) {
+	sn := h.Nodes[index]
+	sn.Byzantine = true
+	recipients := map[string]int{}
+	for _, other := range h.Nodes {
+		recipients[other.ID] = other.Index
+	}
+
+	h.Network.AddInterceptor(func(envelope p2psim.Envelope) []p2psim.Envelope {
+		if envelope.From != sn.ID || envelope.ChannelID != common.ChannelIDProposal || recipients[envelope.To]%2 == 0 {
+			return []p2psim.Envelope{envelope}
+		}
+		data, ok := envelope.Content.(dispatcher.DataResponse)
+		if !ok {
+			return []p2psim.Envelope{envelope}
+		}
+		conflicting, err := conflictingProposal(sn, data)
+		if err != nil {
+			logger.Warnf("Failed to create the conflicting proposal: %v", err)
+			return []p2psim.Envelope{envelope}
+		}
+		envelope.Content = conflicting
+		return []p2psim.Envelope{envelope}
+	})
+}
+
+// conflictingProposal re-signs the proposed block with a different timestamp
+func conflictingProposal(sn *SimNode, data dispatcher.DataResponse) (dispatcher.DataResponse, error) {
+	proposal := core.Proposal{}
+	if err := rlp.DecodeBytes(data.Payload, &proposal); err != nil {
+		return data, err
+	}
+	block := proposal.Block
+	block.Timestamp = new(big.Int).Add(block.Timestamp, big.NewInt(1))
+	sig, err := sn.PrivateKey.Sign(block.SignBytes())
+	if err != nil {
+		return data, err
+	}
+	block.SetSignature(sig)
+	block.UpdateHash()
+
+	payload, err := rlp.EncodeToBytes(proposal)
+	if err != nil {
+		return data, err
+	}
+	return dispatcher.DataResponse{
+		ChannelID: data.ChannelID,
+		Payload:   payload,
+	}, nil
+}
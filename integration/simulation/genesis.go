@@ -0,0 +1,107 @@
+package simulation
+
+import (
+	"bufio"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/crypto"
+	"github.com/scripttoken/script/ledger/state"
+	"github.com/scripttoken/script/ledger/types"
+	"github.com/scripttoken/script/store/database/backend"
+)
+
+var (
+	// ValidatorStake is the genesis stake of each simulated validator
+	ValidatorStake = new(big.Int).Mul(new(big.Int).SetUint64(2000000), core.MinValidatorStakeDeposit)
+
+	// InitialBalance is the genesis balance of each simulated validator on top of its stake
+	InitialBalance = new(big.Int).Mul(new(big.Int).SetUint64(1000000), core.MinValidatorStakeDeposit)
+)
+
+// WriteGenesisSnapshot generates the genesis state in which each of the given validators
+// has staked ValidatorStake, writes it as a snapshot file at the given path, and returns
+// the genesis block header.
+func WriteGenesisSnapshot(chainID string, validators []*crypto.PrivateKey, timestamp time.Time, snapshotPath string) (*core.BlockHeader, error) {
+	genesisHeight := core.GenesisBlockHeight
+	sv := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+
+	vcp := &core.ValidatorCandidatePool{}
+	for _, validator := range validators {
+		address := validator.PublicKey().Address()
+		acc := &types.Account{
+			Address:  address,
+			Root:     common.Hash{},
+			CodeHash: types.EmptyCodeHash,
+			Balance: types.Coins{
+				SCPTWei: new(big.Int).Set(InitialBalance),
+				SPAYWei: new(big.Int).Mul(new(big.Int).SetUint64(5), InitialBalance),
+			},
+		}
+		sv.SetAccount(address, acc)
+
+		if err := vcp.DepositStake(address, address, ValidatorStake, genesisHeight); err != nil {
+			return nil, fmt.Errorf("Failed to deposit the stake of %v: %v", address.Hex(), err)
+		}
+	}
+	sv.UpdateValidatorCandidatePool(vcp)
+
+	hl := &types.HeightList{}
+	hl.Append(genesisHeight)
+	sv.UpdateStakeTransactionHeightList(hl)
+
+	genesisBlock := core.NewBlock()
+	genesisBlock.ChainID = chainID
+	genesisBlock.Height = genesisHeight
+	genesisBlock.Epoch = genesisBlock.Height
+	genesisBlock.Parent = common.Hash{}
+	genesisBlock.StateHash = sv.Hash()
+	genesisBlock.Timestamp = big.NewInt(timestamp.Unix())
+
+	metadata := &core.SnapshotMetadata{
+		TailTrio: core.SnapshotBlockTrio{
+			First:  core.SnapshotFirstBlock{},
+			Second: core.SnapshotSecondBlock{Header: genesisBlock.BlockHeader},
+			Third:  core.SnapshotThirdBlock{},
+		},
+	}
+
+	file, err := os.Create(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	if err = core.WriteMetadata(writer, metadata); err != nil {
+		return nil, err
+	}
+	if err = writeStoreView(sv, writer); err != nil {
+		return nil, err
+	}
+
+	return genesisBlock.BlockHeader, nil
+}
+
+func writeStoreView(sv *state.StoreView, writer *bufio.Writer) error {
+	height := core.Itobytes(sv.Height())
+	err := core.WriteRecord(writer, []byte{core.SVStart}, height)
+	if err != nil {
+		return err
+	}
+	sv.GetStore().Traverse(nil, func(k, v common.Bytes) bool {
+		err = core.WriteRecord(writer, k, v)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	err = core.WriteRecord(writer, []byte{core.SVEnd}, height)
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,317 @@
+package simulation
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/common/clock"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/crypto"
+	"github.com/scripttoken/script/node"
+	"github.com/scripttoken/script/p2p"
+	p2psim "github.com/scripttoken/script/p2p/simulation"
+	p2ptypes "github.com/scripttoken/script/p2p/types"
+	"github.com/scripttoken/script/store/database/backend"
+	"github.com/scripttoken/script/store/rollingdb"
+)
+
+var logger *log.Entry = log.WithFields(log.Fields{"prefix": "simulation"})
+
+//
+// Config configures a simulation
+//
+type Config struct {
+	ChainID       string
+	NumValidators int
+	Seed          int64     // seeds the validator keys and the network faults
+	StartTime     time.Time // initial time of the simulated clock
+
+	MinBlockInterval time.Duration
+	MaxEpochLength   time.Duration
+
+	// Step is the simulated time added at each tick of Run, StepPause is the real time
+	// given to the nodes to process the messages between two ticks
+	Step      time.Duration
+	StepPause time.Duration
+}
+
+// DefaultConfig returns the configuration of a 4 validator simulation
+func DefaultConfig() Config {
+	return Config{
+		ChainID:          "simnet",
+		NumValidators:    4,
+		Seed:             1,
+		StartTime:        time.Unix(1600000000, 0),
+		MinBlockInterval: 1 * time.Second,
+		MaxEpochLength:   4 * time.Second,
+		Step:             100 * time.Millisecond,
+		StepPause:        5 * time.Millisecond,
+	}
+}
+
+//
+// SimNode is a full node running in the simulation
+//
+type SimNode struct {
+	Index      int
+	ID         string
+	PrivateKey *crypto.PrivateKey
+	Endpoint   *p2psim.SimnetEndpoint
+	Node       *node.Node
+
+	// Byzantine nodes are excluded from the invariant checks
+	Byzantine bool
+}
+
+//
+// Harness runs several full nodes in a single process. The nodes are built on memdb
+// backends and communicate through a Simnet, and their consensus engines are driven by
+// a simulated clock. The harness can inject message delays, drops and partitions, make
+// validators behave byzantine, and check the safety and liveness invariants.
+//
+// The configuration of the process (viper) is shared by all the nodes, so only one
+// harness should run at a time.
+//
+type Harness struct {
+	Config  Config
+	Clock   *clock.SimulatedClock
+	Network *p2psim.Simnet
+	Nodes   []*SimNode
+	Genesis *core.BlockHeader
+
+	dir string
+
+	mu      *sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started bool
+}
+
+// NewHarness creates the genesis state and the nodes of a simulation
+func NewHarness(config Config) (*Harness, error) {
+	if config.NumValidators <= 0 {
+		return nil, fmt.Errorf("Invalid number of validators: %v", config.NumValidators)
+	}
+	if config.MaxEpochLength <= config.MinBlockInterval {
+		return nil, fmt.Errorf("Max epoch length must be larger than the min block interval")
+	}
+
+	dir, err := ioutil.TempDir("", "simulation")
+	if err != nil {
+		return nil, err
+	}
+
+	configure(config)
+
+	h := &Harness{
+		Config:  config,
+		Clock:   clock.NewSimulatedClock(config.StartTime),
+		Network: p2psim.NewSimnet(),
+		dir:     dir,
+		mu:      &sync.Mutex{},
+	}
+	h.Network.SetClock(h.Clock)
+	h.Network.SetSeed(config.Seed)
+
+	keys := []*crypto.PrivateKey{}
+	for i := 0; i < config.NumValidators; i++ {
+		key, err := validatorKey(config.Seed, i)
+		if err != nil {
+			h.cleanup()
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	snapshotPath := path.Join(dir, "genesis")
+	h.Genesis, err = WriteGenesisSnapshot(config.ChainID, keys, config.StartTime, snapshotPath)
+	if err != nil {
+		h.cleanup()
+		return nil, fmt.Errorf("Failed to write the genesis snapshot: %v", err)
+	}
+	viper.Set(common.CfgGenesisHash, h.Genesis.Hash().Hex())
+
+	for i, key := range keys {
+		sn, err := h.newNode(i, key, snapshotPath)
+		if err != nil {
+			h.cleanup()
+			return nil, err
+		}
+		h.Nodes = append(h.Nodes, sn)
+	}
+
+	return h, nil
+}
+
+// configure sets the configuration shared by the simulated nodes
+func configure(config Config) {
+	viper.Set(common.CfgGenesisChainID, config.ChainID)
+	viper.Set(common.CfgConsensusMinBlockInterval, int(config.MinBlockInterval/time.Second))
+	viper.Set(common.CfgConsensusMaxEpochLength, int(config.MaxEpochLength/time.Second))
+	viper.Set(common.CfgP2POpt, int(common.P2POptOld))
+	viper.Set(common.CfgStorageRollingEnabled, false)
+	viper.Set(common.CfgStorageVerifyInBackground, false)
+	viper.Set(common.CfgSnapshotAutoEnabled, false)
+	viper.Set(common.CfgSnapshotServeEnabled, false)
+	viper.Set(common.CfgRPCEnabled, false)
+	viper.Set(common.CfgP2PReputationEnabled, false)
+}
+
+// validatorKey derives the key of a validator from the seed, so that a simulation can
+// be replayed with the same validator set
+func validatorKey(seed int64, index int) (*crypto.PrivateKey, error) {
+	sk := sha256.Sum256([]byte(fmt.Sprintf("simulation-validator-%v-%v", seed, index)))
+	return crypto.PrivateKeyFromBytes(sk[:])
+}
+
+func (h *Harness) newNode(index int, key *crypto.PrivateKey, snapshotPath string) (*SimNode, error) {
+	id := key.PublicKey().Address().Hex()
+	nodeDir := path.Join(h.dir, fmt.Sprintf("node%v", index))
+	if err := os.MkdirAll(path.Join(nodeDir, "db"), 0700); err != nil {
+		return nil, err
+	}
+
+	db := backend.NewMemDatabase()
+	endpoint := h.Network.AddEndpoint(id)
+	networks := p2p.NewRegistry() // the simulated nodes only run the in-process transport
+	networks.Register(p2ptypes.StackLocal, endpoint)
+
+	root := &core.Block{BlockHeader: h.Genesis}
+	params := &node.Params{
+		ChainID:    h.Config.ChainID,
+		PrivateKey: key,
+		Root:       root,
+		Network:    networks,
+		DB:         db,
+		RollingDB:  rollingdb.NewRollingDB(nodeDir, db),
+		SnapshotPath: snapshotPath,
+		SnapshotDir:  path.Join(nodeDir, "snapshot"),
+	}
+	n := node.NewNode(params)
+	n.Consensus.SetClock(h.Clock)
+
+	return &SimNode{
+		Index:      index,
+		ID:         id,
+		PrivateKey: key,
+		Endpoint:   endpoint,
+		Node:       n,
+	}, nil
+}
+
+// Start starts the network and the nodes
+func (h *Harness) Start(ctx context.Context) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c, cancel := context.WithCancel(ctx)
+	h.ctx = c
+	h.cancel = cancel
+
+	h.Network.Start(c)
+	for _, sn := range h.Nodes {
+		sn.Node.Start(c)
+	}
+	h.started = true
+}
+
+// Stop stops the nodes and the network, and removes the temporary files
+func (h *Harness) Stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.started {
+		h.cleanup()
+		return
+	}
+	h.cancel()
+	for _, sn := range h.Nodes {
+		sn.Node.Stop()
+	}
+	h.Network.Stop()
+	h.started = false
+	h.cleanup()
+}
+
+func (h *Harness) cleanup() {
+	os.RemoveAll(h.dir)
+}
+
+// Run advances the simulated clock by the given duration, one step at a time
+func (h *Harness) Run(d time.Duration) {
+	for elapsed := time.Duration(0); elapsed < d; elapsed += h.Config.Step {
+		h.Clock.Advance(h.Config.Step)
+		time.Sleep(h.Config.StepPause)
+	}
+}
+
+// RunUntil advances the simulated clock until the condition holds, or the given
+// simulated duration has elapsed. It returns whether the condition holds.
+func (h *Harness) RunUntil(condition func() bool, timeout time.Duration) bool {
+	for elapsed := time.Duration(0); elapsed < timeout; elapsed += h.Config.Step {
+		if condition() {
+			return true
+		}
+		h.Clock.Advance(h.Config.Step)
+		time.Sleep(h.Config.StepPause)
+	}
+	return condition()
+}
+
+// Honest returns the nodes that are not byzantine
+func (h *Harness) Honest() []*SimNode {
+	honest := []*SimNode{}
+	for _, sn := range h.Nodes {
+		if !sn.Byzantine {
+			honest = append(honest, sn)
+		}
+	}
+	return honest
+}
+
+// IDs returns the IDs of the given nodes
+func IDs(nodes ...*SimNode) []string {
+	ids := []string{}
+	for _, sn := range nodes {
+		ids = append(ids, sn.ID)
+	}
+	return ids
+}
+
+// Partition splits the nodes into the given groups of node indexes
+func (h *Harness) Partition(groups ...[]int) {
+	idGroups := [][]string{}
+	for _, group := range groups {
+		ids := []string{}
+		for _, index := range group {
+			ids = append(ids, h.Nodes[index].ID)
+		}
+		idGroups = append(idGroups, ids)
+	}
+	h.Network.Partition(idGroups...)
+}
+
+// Heal removes the partitions
+func (h *Harness) Heal() {
+	h.Network.Heal()
+}
+
+// SetLatency delays the messages by a random duration between min and max of simulated time
+func (h *Harness) SetLatency(min, max time.Duration) {
+	h.Network.SetLatency(min, max)
+}
+
+// SetDropRate drops the given fraction of the messages
+func (h *Harness) SetDropRate(rate float64) {
+	h.Network.SetDropRate(rate)
+}
@@ -0,0 +1,72 @@
+// +build integration
+
+package simulation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHarness(t *testing.T, numValidators int) *Harness {
+	config := DefaultConfig()
+	config.NumValidators = numValidators
+	h, err := NewHarness(config)
+	require.Nil(t, err)
+	h.Start(context.Background())
+	return h
+}
+
+func TestSimulationHonest(t *testing.T) {
+	h := newTestHarness(t, 4)
+	defer h.Stop()
+
+	require.Nil(t, h.RunUntilFinalized(5, 2*time.Minute))
+}
+
+func TestSimulationLatencyAndDrops(t *testing.T) {
+	h := newTestHarness(t, 4)
+	defer h.Stop()
+
+	h.SetLatency(50*time.Millisecond, 500*time.Millisecond)
+	h.SetDropRate(0.1)
+	require.Nil(t, h.RunUntilFinalized(5, 5*time.Minute))
+}
+
+func TestSimulationPartition(t *testing.T) {
+	h := newTestHarness(t, 4)
+	defer h.Stop()
+
+	require.Nil(t, h.RunUntilFinalized(2, 2*time.Minute))
+
+	// No group has a 2/3 majority, the finalization stalls
+	h.Partition([]int{0, 1}, []int{2, 3})
+	h.Run(10 * time.Second)
+	stalled := h.MinFinalizedHeight()
+	h.Run(30 * time.Second)
+	require.Nil(t, h.CheckSafety())
+	for _, sn := range h.Nodes {
+		require.True(t, sn.FinalizedHeight() <= stalled+1)
+	}
+
+	h.Heal()
+	require.Nil(t, h.RunUntilFinalized(stalled+3, 5*time.Minute))
+}
+
+func TestSimulationSilentValidator(t *testing.T) {
+	h := newTestHarness(t, 4)
+	defer h.Stop()
+
+	h.SilenceValidator(3)
+	require.Nil(t, h.RunUntilFinalized(5, 5*time.Minute))
+}
+
+func TestSimulationEquivocatingProposer(t *testing.T) {
+	h := newTestHarness(t, 4)
+	defer h.Stop()
+
+	h.EquivocateProposer(0)
+	require.Nil(t, h.RunUntilFinalized(8, 5*time.Minute))
+}
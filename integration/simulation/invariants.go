@@ -0,0 +1,108 @@
+package simulation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/scripttoken/script/common"
+)
+
+// FinalizedChain returns the hashes of the finalized blocks of the node by height, from
+// its last finalized block down to the genesis block
+func (sn *SimNode) FinalizedChain() (map[uint64]common.Hash, error) {
+	chain := sn.Node.Chain
+	finalized := map[uint64]common.Hash{}
+	block := sn.Node.Consensus.GetLastFinalizedBlock()
+	for block != nil {
+		finalized[block.Height] = block.Hash()
+		if block.Height <= chain.Root().Height {
+			break
+		}
+		parent, err := chain.FindBlock(block.Parent)
+		if err != nil {
+			return nil, fmt.Errorf("node %v: failed to find the parent of finalized block %v: %v", sn.Index, block.Hash().Hex(), err)
+		}
+		block = parent
+	}
+	return finalized, nil
+}
+
+// FinalizedHeight returns the height of the last finalized block of the node
+func (sn *SimNode) FinalizedHeight() uint64 {
+	return sn.Node.Consensus.GetLastFinalizedBlock().Height
+}
+
+// CheckSafety verifies that the honest nodes never finalized two conflicting blocks,
+// i.e. two different blocks at the same height, neither on one node nor across nodes
+func (h *Harness) CheckSafety() error {
+	finalizedByHeight := map[uint64]common.Hash{}
+	finalizedBy := map[uint64]int{}
+
+	for _, sn := range h.Honest() {
+		// A node never finalizes two blocks at the same height
+		lfb := sn.Node.Consensus.GetLastFinalizedBlock()
+		for height := h.Genesis.Height + 1; height <= lfb.Height; height++ {
+			numFinalized := 0
+			for _, block := range sn.Node.Chain.FindBlocksByHeight(height) {
+				if block.Status.IsFinalized() {
+					numFinalized++
+				}
+			}
+			if numFinalized > 1 {
+				return fmt.Errorf("node %v finalized %v blocks at height %v", sn.Index, numFinalized, height)
+			}
+		}
+
+		// The finalized chains of the nodes are prefixes of each other
+		finalized, err := sn.FinalizedChain()
+		if err != nil {
+			return err
+		}
+		for height, hash := range finalized {
+			other, ok := finalizedByHeight[height]
+			if !ok {
+				finalizedByHeight[height] = hash
+				finalizedBy[height] = sn.Index
+				continue
+			}
+			if other != hash {
+				return fmt.Errorf("conflicting finalized blocks at height %v: %v on node %v, %v on node %v",
+					height, other.Hex(), finalizedBy[height], hash.Hex(), sn.Index)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckLiveness verifies that all the honest nodes finalized at least the given height
+func (h *Harness) CheckLiveness(height uint64) error {
+	for _, sn := range h.Honest() {
+		if finalized := sn.FinalizedHeight(); finalized < height {
+			return fmt.Errorf("node %v only finalized height %v, expected at least %v", sn.Index, finalized, height)
+		}
+	}
+	return nil
+}
+
+// RunUntilFinalized advances the simulation until all the honest nodes finalized the given
+// height. It returns an error if the liveness or the safety is violated.
+func (h *Harness) RunUntilFinalized(height uint64, timeout time.Duration) error {
+	h.RunUntil(func() bool {
+		return h.CheckLiveness(height) == nil
+	}, timeout)
+	if err := h.CheckSafety(); err != nil {
+		return err
+	}
+	return h.CheckLiveness(height)
+}
+
+// MinFinalizedHeight returns the lowest last finalized height among the honest nodes
+func (h *Harness) MinFinalizedHeight() uint64 {
+	min := uint64(0)
+	for i, sn := range h.Honest() {
+		if height := sn.FinalizedHeight(); i == 0 || height < min {
+			min = height
+		}
+	}
+	return min
+}
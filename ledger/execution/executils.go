@@ -283,6 +283,67 @@ func sanityCheckForSendTxFee(fee types.Coins, numAccountsAffected uint64, blockH
 	return minimumFee, success
 }
 
+// sanityCheckForTokens checks that the native tokens are only moved by the SendTx, the ReserveFundTx and
+// the ServicePaymentTx, once the native tokens are enabled. The fees are always paid in SPAY.
+func sanityCheckForTokens(tx types.Tx, blockHeight uint64) result.Result {
+	var tokenCoins, otherCoins []types.Coins
+	switch tx := tx.(type) {
+	case *types.CoinbaseTx:
+		otherCoins = append(otherCoins, tx.Proposer.Coins)
+		for _, output := range tx.Outputs {
+			otherCoins = append(otherCoins, output.Coins)
+		}
+	case *types.SlashTx:
+		otherCoins = append(otherCoins, tx.Proposer.Coins)
+	case *types.SendTx:
+		otherCoins = append(otherCoins, tx.Fee)
+		for _, input := range tx.Inputs {
+			tokenCoins = append(tokenCoins, input.Coins)
+		}
+		for _, output := range tx.Outputs {
+			tokenCoins = append(tokenCoins, output.Coins)
+		}
+	case *types.ReserveFundTx:
+		otherCoins = append(otherCoins, tx.Fee)
+		tokenCoins = append(tokenCoins, tx.Source.Coins, tx.Collateral)
+	case *types.ReleaseFundTx:
+		otherCoins = append(otherCoins, tx.Fee, tx.Source.Coins)
+	case *types.ServicePaymentTx:
+		otherCoins = append(otherCoins, tx.Fee)
+		tokenCoins = append(tokenCoins, tx.Source.Coins, tx.Target.Coins)
+	case *types.SplitRuleTx:
+		otherCoins = append(otherCoins, tx.Fee, tx.Initiator.Coins)
+	case *types.SmartContractTx:
+		otherCoins = append(otherCoins, tx.From.Coins, tx.To.Coins)
+	case *types.SmartContractTxV2:
+		otherCoins = append(otherCoins, tx.From.Coins, tx.To.Coins)
+	case *types.DepositStakeTx:
+		otherCoins = append(otherCoins, tx.Fee, tx.Source.Coins, tx.Holder.Coins)
+	case *types.DepositStakeTxV2:
+		otherCoins = append(otherCoins, tx.Fee, tx.Source.Coins, tx.Holder.Coins)
+	case *types.WithdrawStakeTx:
+		otherCoins = append(otherCoins, tx.Fee, tx.Source.Coins, tx.Holder.Coins)
+	case *types.StakeRewardDistributionTx:
+		otherCoins = append(otherCoins, tx.Fee, tx.Holder.Coins, tx.Beneficiary.Coins)
+	case *types.CreateAssetTx:
+		otherCoins = append(otherCoins, tx.Fee, tx.Issuer.Coins)
+	}
+
+	for _, coins := range otherCoins {
+		if coins.HasTokens() {
+			return result.Error("Native tokens cannot be used in this transaction: %v", coins)
+		}
+	}
+	if blockHeight < common.HeightEnableNativeTokens {
+		for _, coins := range tokenCoins {
+			if coins.HasTokens() {
+				return result.Error("Native tokens are not supported yet")
+			}
+		}
+	}
+	return result.OK
+}
+
 func chargeFee(account *types.Account, fee types.Coins) bool {
 	if !account.Balance.IsGTE(fee) {
 		return false
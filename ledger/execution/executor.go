@@ -45,6 +45,7 @@ type Executor struct {
 	depositStakeTxExec            *DepositStakeExecutor
 	withdrawStakeTxExec           *WithdrawStakeExecutor
 	stakeRewardDistributionTxExec *StakeRewardDistributionTxExecutor
+	createAssetTxExec             *CreateAssetTxExecutor
 
 	skipSanityCheck bool
 }
@@ -68,6 +69,7 @@ func NewExecutor(db database.Database, chain *blockchain.Chain, state *st.Ledger
 		depositStakeTxExec:            NewDepositStakeExecutor(state),
 		withdrawStakeTxExec:           NewWithdrawStakeExecutor(state),
 		stakeRewardDistributionTxExec: NewStakeRewardDistributionTxExecutor(state),
+		createAssetTxExec:             NewCreateAssetTxExecutor(state),
 		skipSanityCheck:               false,
 	}
 
@@ -85,6 +87,12 @@ func (exec *Executor) ExecuteTx(tx types.Tx) (common.Hash, result.Result) {
 	return exec.processTx(tx, core.DeliveredView)
 }
 
+// ExecuteTxOnView executes the given transaction as a delivered transaction, but on the given view
+// instead of the delivered view, e.g. on a copy of the delivered view for speculative execution
+func (exec *Executor) ExecuteTxOnView(tx types.Tx, view *st.StoreView) (common.Hash, result.Result) {
+	return exec.processTxOnView(tx, view, core.DeliveredView)
+}
+
 // CheckTx checks the validity of the given transaction
 func (exec *Executor) CheckTx(tx types.Tx) (common.Hash, result.Result) {
 	return exec.processTx(tx, core.CheckedView)
@@ -108,7 +116,6 @@ func (exec *Executor) GetTxInfo(tx types.Tx) (*core.TxInfo, result.Result) {
 
 // processTx contains the main logic to process the transaction. If the tx is invalid, a TMSP error will be returned.
 func (exec *Executor) processTx(tx types.Tx, viewSel core.ViewSelector) (common.Hash, result.Result) {
-	chainID := exec.state.GetChainID()
 	var view *st.StoreView
 	switch viewSel {
 	case core.DeliveredView:
@@ -119,6 +126,11 @@ func (exec *Executor) processTx(tx types.Tx, viewSel core.ViewSelector) (common.
 		view = exec.state.Screened()
 	}
 
+	return exec.processTxOnView(tx, view, viewSel)
+}
+
+func (exec *Executor) processTxOnView(tx types.Tx, view *st.StoreView, viewSel core.ViewSelector) (common.Hash, result.Result) {
+	chainID := exec.state.GetChainID()
 	sanityCheckResult := exec.sanityCheck(chainID, view, viewSel, tx)
 	if sanityCheckResult.IsError() {
 		return common.Hash{}, sanityCheckResult
@@ -137,6 +149,10 @@ func (exec *Executor) sanityCheck(chainID string, view *st.StoreView, viewSel co
 		return result.Error("tx type not supported yet")
 	}
 
+	if res := sanityCheckForTokens(tx, view.Height()+1); res.IsError() {
+		return res
+	}
+
 	var sanityCheckResult result.Result
 	txExecutor := exec.getTxExecutor(tx)
 	if txExecutor != nil {
@@ -177,10 +193,18 @@ func (exec *Executor) isTxTypeSupported(view *st.StoreView, tx types.Tx) bool {
 		if blockHeight < common.HeightEnableSmartContract {
 			return false
 		}
+	case *types.SmartContractTxV2:
+		if blockHeight < common.HeightEnableDynamicFee {
+			return false
+		}
 	case *types.StakeRewardDistributionTx:
 		if blockHeight < common.HeightEnableScript3 {
 			return false
 		}
+	case *types.CreateAssetTx:
+		if blockHeight < common.HeightEnableNativeTokens {
+			return false
+		}
 	default:
 		return true
 	}
@@ -207,6 +231,8 @@ func (exec *Executor) getTxExecutor(tx types.Tx) TxExecutor {
 		txExecutor = exec.splitRuleTxExec
 	case *types.SmartContractTx:
 		txExecutor = exec.smartContractTxExec
+	case *types.SmartContractTxV2:
+		txExecutor = exec.smartContractTxExec
 	case *types.DepositStakeTx:
 		txExecutor = exec.depositStakeTxExec
 	case *types.WithdrawStakeTx:
@@ -215,6 +241,8 @@ func (exec *Executor) getTxExecutor(tx types.Tx) TxExecutor {
 		txExecutor = exec.depositStakeTxExec
 	case *types.StakeRewardDistributionTx:
 		txExecutor = exec.stakeRewardDistributionTxExec
+	case *types.CreateAssetTx:
+		txExecutor = exec.createAssetTxExec
 	default:
 		txExecutor = nil
 	}
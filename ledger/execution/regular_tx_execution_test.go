@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/scripttoken/script/common"
 	"github.com/scripttoken/script/common/result"
+	"github.com/scripttoken/script/core"
 	"github.com/scripttoken/script/ledger/types"
 )
 
@@ -383,7 +384,7 @@ func TestSendDuplicatedInputOutput(t *testing.T) {
 // 	}
 // 	tx.Proposer.Signature = va1.Sign(tx.SignBytes(et.chainID))
 
-// 	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	assert.True(res.IsOK(), res.String())
 
 // 	// Script should never inflate
@@ -397,7 +398,7 @@ func TestSendDuplicatedInputOutput(t *testing.T) {
 // 		}},
 // 		BlockHeight: 1e7,
 // 	}
-// 	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	assert.True(res.IsError(), res.String())
 
 // 	// For the initial Mainnet release, SPAY should not inflate
@@ -411,7 +412,7 @@ func TestSendDuplicatedInputOutput(t *testing.T) {
 // 		}},
 // 		BlockHeight: 1e7,
 // 	}
-// 	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	assert.True(res.IsError(), res.String())
 
 // 	// //Error if reward Script amount is incorrect
@@ -425,7 +426,7 @@ func TestSendDuplicatedInputOutput(t *testing.T) {
 // 	// 	}},
 // 	// 	BlockHeight: 1e7,
 // 	// }
-// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	// assert.True(res.IsError(), res.String())
 
 // 	// //Error if reward SPAY amount is incorrect
@@ -439,7 +440,7 @@ func TestSendDuplicatedInputOutput(t *testing.T) {
 // 	// 	}},
 // 	// 	BlockHeight: 1e7,
 // 	// }
-// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	// assert.True(res.IsError(), res.String())
 
 // 	// //Error if Validator 2 is not rewarded
@@ -451,7 +452,7 @@ func TestSendDuplicatedInputOutput(t *testing.T) {
 // 	// 	}},
 // 	// 	BlockHeight: 1e7,
 // 	// }
-// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	// assert.True(res.IsError(), res.String())
 
 // 	// //Error if non-validator is rewarded
@@ -467,7 +468,7 @@ func TestSendDuplicatedInputOutput(t *testing.T) {
 // 	// 	}},
 // 	// 	BlockHeight: 1e7,
 // 	// }
-// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	// assert.True(res.IsError(), res.String())
 
 // 	// //Error if validator address is changed
@@ -481,7 +482,7 @@ func TestSendDuplicatedInputOutput(t *testing.T) {
 // 	// 	}},
 // 	// 	BlockHeight: 1e7,
 // 	// }
-// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+// 	// res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	// assert.True(res.IsError(), res.String())
 
 // 	// //Process should update validator account
@@ -496,7 +497,7 @@ func TestSendDuplicatedInputOutput(t *testing.T) {
 // 	// 	BlockHeight: 1e7,
 // 	// }
 
-// 	// _, res = et.executor.getTxExecutor(tx).process(et.chainID, et.state().Delivered(), tx)
+// 	// _, res = et.executor.getTxExecutor(tx).process(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 // 	// assert.True(res.IsOK(), res.String())
 
 // 	// va1balance := et.state().Delivered().GetAccount(va1.Account.PubKey.Address()).Balance
@@ -544,7 +545,7 @@ func TestReserveFundTx(t *testing.T) {
 		Duration:    1000,
 	}
 	tx.Source.Signature = user1.Sign(tx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeReservedFundNotSpecified)
 
@@ -561,7 +562,7 @@ func TestReserveFundTx(t *testing.T) {
 		Duration:    1000,
 	}
 	tx.Source.Signature = user1.Sign(tx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeInsufficientFund)
 
@@ -578,7 +579,7 @@ func TestReserveFundTx(t *testing.T) {
 		Duration:    1000,
 	}
 	tx.Source.Signature = user1.Sign(tx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeReserveFundCheckFailed, res.Message)
 
@@ -595,9 +596,9 @@ func TestReserveFundTx(t *testing.T) {
 		Duration:    1000,
 	}
 	tx.Source.Signature = user1.Sign(tx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), tx)
+	res = et.executor.getTxExecutor(tx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 	assert.True(res.IsOK(), res.String())
-	_, res = et.executor.getTxExecutor(tx).process(et.chainID, et.state().Delivered(), tx)
+	_, res = et.executor.getTxExecutor(tx).process(et.chainID, et.state().Delivered(), core.DeliveredView, tx)
 	assert.True(res.IsOK(), res.String())
 
 	retrievedUserAcc := et.state().Delivered().GetAccount(user1.Address)
@@ -636,9 +637,9 @@ func TestReleaseFundTx(t *testing.T) {
 		Duration:    1000,
 	}
 	reserveFundTx.Source.Signature = user1.Sign(reserveFundTx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(reserveFundTx).sanityCheck(et.chainID, et.state().Delivered(), reserveFundTx)
+	res = et.executor.getTxExecutor(reserveFundTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, reserveFundTx)
 	assert.True(res.IsOK(), res.String())
-	_, res = et.executor.getTxExecutor(reserveFundTx).process(et.chainID, et.state().Delivered(), reserveFundTx)
+	_, res = et.executor.getTxExecutor(reserveFundTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, reserveFundTx)
 	assert.True(res.IsOK(), res.String())
 
 	et.state().Commit()
@@ -653,7 +654,7 @@ func TestReleaseFundTx(t *testing.T) {
 		ReserveSequence: 1,
 	}
 	releaseFundTx.Source.Signature = user1.Sign(releaseFundTx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), releaseFundTx)
+	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, releaseFundTx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeInvalidFee, res.String())
 
@@ -666,7 +667,7 @@ func TestReleaseFundTx(t *testing.T) {
 		ReserveSequence: 1,
 	}
 	releaseFundTx.Source.Signature = user1.Sign(releaseFundTx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), releaseFundTx)
+	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, releaseFundTx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeInvalidFee, res.String())
 
@@ -680,7 +681,7 @@ func TestReleaseFundTx(t *testing.T) {
 		ReserveSequence: 1,
 	}
 	releaseFundTx.Source.Signature = user1.Sign(releaseFundTx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), releaseFundTx)
+	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, releaseFundTx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeReleaseFundCheckFailed, res.String())
 
@@ -696,7 +697,7 @@ func TestReleaseFundTx(t *testing.T) {
 		ReserveSequence: 99,
 	}
 	releaseFundTx.Source.Signature = user1.Sign(releaseFundTx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), releaseFundTx)
+	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, releaseFundTx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeReleaseFundCheckFailed, res.String())
 
@@ -715,7 +716,7 @@ func TestReleaseFundTx(t *testing.T) {
 		ReserveSequence: 1,
 	}
 	releaseFundTx.Source.Signature = user1.Sign(releaseFundTx.SignBytes(et.chainID))
-	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), releaseFundTx)
+	res = et.executor.getTxExecutor(releaseFundTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, releaseFundTx)
 	assert.False(res.IsOK(), res.String())
 	assert.Equal(res.Code, result.CodeReleaseFundCheckFailed, res.String())
 }
@@ -739,9 +740,9 @@ func TestServicePaymentTxNormalExecutionAndSlash(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 10*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 50*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx1 := createServicePaymentTx(et.chainID, &alice, &bob, payAmount1, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res := et.executor.getTxExecutor(servicePaymentTx1).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx1)
+	res := et.executor.getTxExecutor(servicePaymentTx1).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx1)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(servicePaymentTx1).process(et.chainID, et.state().Delivered(), servicePaymentTx1)
+	_, res = et.executor.getTxExecutor(servicePaymentTx1).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx1)
 	assert.True(res.IsOK(), res.Message)
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
 
@@ -758,9 +759,9 @@ func TestServicePaymentTxNormalExecutionAndSlash(t *testing.T) {
 	srcSeq, tgtSeq, paymentSeq, reserveSeq = 1, 2, 2, 1
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 30*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx2 := createServicePaymentTx(et.chainID, &alice, &bob, payAmount2, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx2).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx2)
+	res = et.executor.getTxExecutor(servicePaymentTx2).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx2)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(servicePaymentTx2).process(et.chainID, et.state().Delivered(), servicePaymentTx2)
+	_, res = et.executor.getTxExecutor(servicePaymentTx2).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx2)
 	assert.True(res.IsOK(), res.Message)
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
 
@@ -776,9 +777,9 @@ func TestServicePaymentTxNormalExecutionAndSlash(t *testing.T) {
 	srcSeq, tgtSeq, paymentSeq, reserveSeq = 1, 1, 3, 1
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 30*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx3 := createServicePaymentTx(et.chainID, &alice, &carol, payAmount3, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx3).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx3)
+	res = et.executor.getTxExecutor(servicePaymentTx3).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx3)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(servicePaymentTx3).process(et.chainID, et.state().Delivered(), servicePaymentTx3)
+	_, res = et.executor.getTxExecutor(servicePaymentTx3).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx3)
 	assert.True(res.IsOK(), res.Message)
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
 
@@ -794,11 +795,11 @@ func TestServicePaymentTxNormalExecutionAndSlash(t *testing.T) {
 	srcSeq, tgtSeq, paymentSeq, reserveSeq = 1, 2, 4, 1
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 70000*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx4 := createServicePaymentTx(et.chainID, &alice, &carol, payAmount4, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx4).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx4)
+	res = et.executor.getTxExecutor(servicePaymentTx4).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx4)
 	assert.True(res.IsOK(), res.Message) // the following process() call will create an SlashIntent
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx4).process(et.chainID, et.state().Delivered(), servicePaymentTx4)
+	_, res = et.executor.getTxExecutor(servicePaymentTx4).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx4)
 	assert.True(res.IsOK(), res.Message)
 	//assert.Equal(1, len(et.state().Delivered().GetSlashIntents()))
 }
@@ -822,9 +823,9 @@ func TestServicePaymentTxExpiration(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 10*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 50*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx1 := createServicePaymentTx(et.chainID, &alice, &bob, payAmount1, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res := et.executor.getTxExecutor(servicePaymentTx1).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx1)
+	res := et.executor.getTxExecutor(servicePaymentTx1).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx1)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(servicePaymentTx1).process(et.chainID, et.state().Delivered(), servicePaymentTx1)
+	_, res = et.executor.getTxExecutor(servicePaymentTx1).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx1)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -841,7 +842,7 @@ func TestServicePaymentTxExpiration(t *testing.T) {
 	srcSeq, tgtSeq, paymentSeq, reserveSeq = 1, 2, 2, 1
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 30*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx2 := createServicePaymentTx(et.chainID, &alice, &bob, payAmount2, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx2).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx2)
+	res = et.executor.getTxExecutor(servicePaymentTx2).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx2)
 	assert.False(res.IsOK(), res.Message)
 	assert.Equal(result.CodeCheckTransferReservedFundFailed, res.Code)
 	log.Infof("Service payment check message: %v", res.Message)
@@ -872,11 +873,11 @@ func TestServicePaymentTxExpiration(t *testing.T) {
 // 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 10*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 // 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 50*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 // 	servicePaymentTx1 := createServicePaymentTx(et.chainID, &alice, &bob, payAmount1, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-// 	res := et.executor.getTxExecutor(servicePaymentTx1).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx1)
+// 	res := et.executor.getTxExecutor(servicePaymentTx1).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx1)
 // 	assert.True(res.IsOK(), res.Message)
 
 // 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-// 	_, res = et.executor.getTxExecutor(servicePaymentTx1).process(et.chainID, et.state().Delivered(), servicePaymentTx1)
+// 	_, res = et.executor.getTxExecutor(servicePaymentTx1).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx1)
 // 	assert.True(res.IsOK(), res.Message)
 // 	assert.Equal(1, len(et.state().Delivered().GetSlashIntents()))
 
@@ -897,9 +898,9 @@ func TestServicePaymentTxExpiration(t *testing.T) {
 // 	signBytes := slashTx.SignBytes(et.chainID)
 // 	slashTx.Proposer.Signature = proposer.Sign(signBytes)
 
-// 	res = et.executor.getTxExecutor(slashTx).sanityCheck(et.chainID, et.state().Delivered(), slashTx)
+// 	res = et.executor.getTxExecutor(slashTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, slashTx)
 // 	assert.True(res.IsOK(), res.Message)
-// 	_, res = et.executor.getTxExecutor(slashTx).process(et.chainID, et.state().Delivered(), slashTx)
+// 	_, res = et.executor.getTxExecutor(slashTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, slashTx)
 // 	assert.True(res.IsOK(), res.Message)
 
 // 	retrievedProposerAccount := et.state().Delivered().GetAccount(proposer.Address)
@@ -943,9 +944,9 @@ func TestSplitRuleTxNormalExecution(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -954,11 +955,11 @@ func TestSplitRuleTxNormalExecution(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 100*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 500*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &bob, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1005,9 +1006,9 @@ func TestSplitRuleTxExpiration(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.fastforwardBy(105) // The split rule should expire after the fastforward
@@ -1018,11 +1019,11 @@ func TestSplitRuleTxExpiration(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 100, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &bob, 500, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &bob, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1071,9 +1072,9 @@ func TestSplitRuleTxUpdate(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	splitRule := et.executor.state.Delivered().GetSplitRule(resourceID)
@@ -1095,10 +1096,10 @@ func TestSplitRuleTxUpdate(t *testing.T) {
 	signBytes = fakeSplitRuleUpdateTx.SignBytes(et.chainID)
 	fakeSplitRuleUpdateTx.Initiator.Signature = fakeInitiator.Sign(signBytes)
 
-	res = et.executor.getTxExecutor(fakeSplitRuleUpdateTx).sanityCheck(et.chainID, et.state().Delivered(), fakeSplitRuleUpdateTx)
+	res = et.executor.getTxExecutor(fakeSplitRuleUpdateTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, fakeSplitRuleUpdateTx)
 	assert.False(res.IsOK(), res.Message)
 	assert.Equal(result.CodeUnauthorizedToUpdateSplitRule, res.Code)
-	_, res = et.executor.getTxExecutor(fakeSplitRuleUpdateTx).process(et.chainID, et.state().Delivered(), fakeSplitRuleUpdateTx)
+	_, res = et.executor.getTxExecutor(fakeSplitRuleUpdateTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, fakeSplitRuleUpdateTx)
 	assert.False(res.IsOK(), res.Message)
 	assert.Equal(result.CodeUnauthorizedToUpdateSplitRule, res.Code)
 
@@ -1123,9 +1124,9 @@ func TestSplitRuleTxUpdate(t *testing.T) {
 	signBytes = splitRuleUpdateTx.SignBytes(et.chainID)
 	splitRuleUpdateTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res = et.executor.getTxExecutor(splitRuleUpdateTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleUpdateTx)
+	res = et.executor.getTxExecutor(splitRuleUpdateTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleUpdateTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleUpdateTx).process(et.chainID, et.state().Delivered(), splitRuleUpdateTx)
+	_, res = et.executor.getTxExecutor(splitRuleUpdateTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleUpdateTx)
 	assert.True(res.IsOK(), res.Message)
 
 	splitRule2 := et.executor.state.Delivered().GetSplitRule(resourceID)
@@ -1218,9 +1219,9 @@ func TestSplitRuleTxTargetAddressAlsoSplits(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1231,11 +1232,11 @@ func TestSplitRuleTxTargetAddressAlsoSplits(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 100*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 500*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1313,9 +1314,9 @@ func TestSplitRuleTxManyDups(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1324,11 +1325,11 @@ func TestSplitRuleTxManyDups(t *testing.T) {
 
 	// Alice send the service payment to Carol, whose address is included in the split address list
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1403,9 +1404,9 @@ func TestSplitRuleTxSmallAmount(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1414,13 +1415,13 @@ func TestSplitRuleTxSmallAmount(t *testing.T) {
 
 	// Alice send the service payment to Carol, whose address is included in the split address list
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	log.Infof("Payment amount: %v", payAmount)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1509,9 +1510,9 @@ func TestSplitRuleTxSmallPercentage(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1520,13 +1521,13 @@ func TestSplitRuleTxSmallPercentage(t *testing.T) {
 
 	// Alice send the service payment to Carol, whose address is included in the split address list
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	log.Infof("Payment amount: %v", payAmount)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1599,9 +1600,9 @@ func TestSplitRuleHundredPercSplits(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1612,11 +1613,11 @@ func TestSplitRuleHundredPercSplits(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 100*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 500*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1681,7 +1682,7 @@ func TestSplitRuleOverHundredPercSplits(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.False(res.IsOK(), res.Message) // should be rejected
 }
 
@@ -1716,9 +1717,9 @@ func TestSplitRuleSingleHundredPercSplits(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1729,11 +1730,11 @@ func TestSplitRuleSingleHundredPercSplits(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 100*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 500*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1796,9 +1797,9 @@ func TestSplitRuleSplitZeroPercSplits(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1809,11 +1810,11 @@ func TestSplitRuleSplitZeroPercSplits(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 100*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 500*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1868,9 +1869,9 @@ func TestSplitRuleSplitEmptyRule(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1881,11 +1882,11 @@ func TestSplitRuleSplitEmptyRule(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 100*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 500*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -1950,9 +1951,9 @@ func TestSplitRuleSplitZeroPayment(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -1963,11 +1964,11 @@ func TestSplitRuleSplitZeroPayment(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 0, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 0, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -2033,9 +2034,9 @@ func TestSplitRuleTxSplitPaymentRounding(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 
 	// Simulate micropayment #1 between Alice and Bob, Carol should get a cut
@@ -2046,11 +2047,11 @@ func TestSplitRuleTxSplitPaymentRounding(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 100, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 500, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -2119,9 +2120,9 @@ func TestSplitRuleExpiration(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 	et.state().Commit()
 
@@ -2145,11 +2146,11 @@ func TestSplitRuleExpiration(t *testing.T) {
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 100*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	_ = createServicePaymentTx(et.chainID, &alice, &carol, 500*txFee, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
 	servicePaymentTx := createServicePaymentTx(et.chainID, &alice, &carol, payAmount, srcSeq, tgtSeq, paymentSeq, reserveSeq, resourceID)
-	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), servicePaymentTx)
+	res = et.executor.getTxExecutor(servicePaymentTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	assert.Equal(0, len(et.state().Delivered().GetSlashIntents()))
-	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), servicePaymentTx)
+	_, res = et.executor.getTxExecutor(servicePaymentTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, servicePaymentTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -2217,9 +2218,9 @@ func TestSplitRuleZeroDuration(t *testing.T) {
 	signBytes := splitRuleTx.SignBytes(et.chainID)
 	splitRuleTx.Initiator.Signature = initiator.Sign(signBytes)
 
-	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx)
+	res := et.executor.getTxExecutor(splitRuleTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), splitRuleTx)
+	_, res = et.executor.getTxExecutor(splitRuleTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx)
 	assert.True(res.IsOK(), res.Message)
 	et.state().Commit()
 
@@ -2242,12 +2243,250 @@ func TestSplitRuleZeroDuration(t *testing.T) {
 	signBytes2 := splitRuleTx2.SignBytes(et.chainID)
 	splitRuleTx2.Initiator.Signature = initiator.Sign(signBytes2)
 
-	res = et.executor.getTxExecutor(splitRuleTx2).sanityCheck(et.chainID, et.state().Delivered(), splitRuleTx2)
+	res = et.executor.getTxExecutor(splitRuleTx2).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx2)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(splitRuleTx2).process(et.chainID, et.state().Delivered(), splitRuleTx2)
+	_, res = et.executor.getTxExecutor(splitRuleTx2).process(et.chainID, et.state().Delivered(), core.DeliveredView, splitRuleTx2)
 	assert.True(res.IsOK(), res.Message)
 	et.state().Commit()
 
 	retrievedSplitRule2ndTime := et.state().Delivered().GetSplitRule(resourceID)
 	assert.Nil(retrievedSplitRule2ndTime) // Should be expired and got deleted
 }
+
+func TestCreateAssetTx(t *testing.T) {
+	assert := assert.New(t)
+	et := NewExecTest()
+
+	txFee := getMinimumTxFee()
+	et.acc2State(et.accIn)
+	et.acc2State(et.accOut)
+	issuer := et.accIn.Account.Address
+
+	createAssetTx := &types.CreateAssetTx{
+		Fee:      types.NewCoins(0, txFee),
+		Issuer:   types.TxInput{Address: issuer, Sequence: 1},
+		Name:     "Segment Credit",
+		Symbol:   "SEG",
+		Decimals: 6,
+		Supply:   big.NewInt(1000000),
+	}
+	createAssetTx.Issuer.Signature = et.accIn.Sign(createAssetTx.SignBytes(et.chainID))
+	assetID := createAssetTx.AssetID()
+	assert.Equal(types.AssetID(issuer, 1), assetID)
+
+	// The native tokens are not enabled yet
+	_, res := et.executor.ExecuteTx(createAssetTx)
+	assert.True(res.IsError())
+
+	et.fastforwardTo(common.HeightEnableNativeTokens)
+	_, res = et.executor.ExecuteTx(createAssetTx)
+	assert.True(res.IsOK(), res.String())
+
+	asset := et.state().Delivered().GetAsset(assetID)
+	assert.NotNil(asset)
+	assert.Equal(issuer, asset.Issuer)
+	assert.Equal("SEG", asset.Symbol)
+	assert.Equal(uint8(6), asset.Decimals)
+	assert.Equal(int64(1000000), asset.Supply.Int64())
+
+	issuerAcc := et.state().Delivered().GetAccount(issuer)
+	assert.Equal(int64(1000000), issuerAcc.Balance.GetToken(assetID).Int64())
+	assert.Equal(int64(49*txFee), issuerAcc.Balance.SPAYWei.Int64())
+	assert.Equal(uint64(1), issuerAcc.Sequence)
+
+	// Send part of the supply together with some SCPT
+	tokens := types.NewTokenCoins(assetID, big.NewInt(250000))
+	sendTx := &types.SendTx{
+		Fee: types.NewCoins(0, txFee),
+		Inputs: []types.TxInput{{
+			Address:  issuer,
+			Coins:    types.NewCoins(10, txFee).Plus(tokens),
+			Sequence: 2,
+		}},
+		Outputs: []types.TxOutput{{
+			Address: et.accOut.Account.Address,
+			Coins:   types.NewCoins(10, 0).Plus(tokens),
+		}},
+	}
+	et.signSendTx(sendTx, et.accIn)
+	_, res = et.executor.ExecuteTx(sendTx)
+	assert.True(res.IsOK(), res.String())
+
+	issuerAcc = et.state().Delivered().GetAccount(issuer)
+	assert.Equal(int64(750000), issuerAcc.Balance.GetToken(assetID).Int64())
+	receiverAcc := et.state().Delivered().GetAccount(et.accOut.Account.Address)
+	assert.Equal(int64(250000), receiverAcc.Balance.GetToken(assetID).Int64())
+	assert.Equal(int64(700010), receiverAcc.Balance.SCPTWei.Int64())
+
+	// Sending more tokens than the balance
+	sendTx = &types.SendTx{
+		Fee: types.NewCoins(0, txFee),
+		Inputs: []types.TxInput{{
+			Address:  et.accOut.Account.Address,
+			Coins:    types.NewCoins(0, txFee).Plus(types.NewTokenCoins(assetID, big.NewInt(250001))),
+			Sequence: 1,
+		}},
+		Outputs: []types.TxOutput{{
+			Address: issuer,
+			Coins:   types.NewTokenCoins(assetID, big.NewInt(250001)),
+		}},
+	}
+	et.signSendTx(sendTx, et.accOut)
+	_, res = et.executor.ExecuteTx(sendTx)
+	assert.True(res.IsError())
+
+	// The fees cannot be paid with tokens
+	sendTx = &types.SendTx{
+		Fee: types.NewCoins(0, txFee).Plus(types.NewTokenCoins(assetID, big.NewInt(1))),
+		Inputs: []types.TxInput{{
+			Address:  et.accOut.Account.Address,
+			Coins:    types.NewCoins(0, txFee).Plus(types.NewTokenCoins(assetID, big.NewInt(2))),
+			Sequence: 1,
+		}},
+		Outputs: []types.TxOutput{{
+			Address: issuer,
+			Coins:   types.NewTokenCoins(assetID, big.NewInt(1)),
+		}},
+	}
+	et.signSendTx(sendTx, et.accOut)
+	_, res = et.executor.ExecuteTx(sendTx)
+	assert.True(res.IsError())
+
+	// The same asset cannot be created twice, and the supply must be positive
+	_, res = et.executor.ExecuteTx(createAssetTx)
+	assert.True(res.IsError())
+
+	createAssetTx = &types.CreateAssetTx{
+		Fee:      types.NewCoins(0, txFee),
+		Issuer:   types.TxInput{Address: issuer, Sequence: 3},
+		Name:     "Empty",
+		Symbol:   "EMP",
+		Decimals: 6,
+		Supply:   big.NewInt(0),
+	}
+	createAssetTx.Issuer.Signature = et.accIn.Sign(createAssetTx.SignBytes(et.chainID))
+	_, res = et.executor.ExecuteTx(createAssetTx)
+	assert.True(res.IsError())
+}
+
+func TestServicePaymentTxWithTokens(t *testing.T) {
+	assert := assert.New(t)
+	et := NewExecTest()
+
+	txFee := getMinimumTxFee()
+	alice := types.MakeAcc("User Alice")
+	alice.Balance = types.Coins{SPAYWei: big.NewInt(100 * txFee), SCPTWei: big.NewInt(0)}
+	et.acc2State(alice)
+	bob := types.MakeAcc("User Bob")
+	bob.Balance = types.Coins{SPAYWei: big.NewInt(100 * txFee), SCPTWei: big.NewInt(0)}
+	et.acc2State(bob)
+	et.fastforwardTo(common.HeightEnableNativeTokens)
+
+	// Alice issues the asset used to pay for the video segments
+	createAssetTx := &types.CreateAssetTx{
+		Fee:      types.NewCoins(0, txFee),
+		Issuer:   types.TxInput{Address: alice.Address, Sequence: 1},
+		Name:     "Segment Credit",
+		Symbol:   "SEG",
+		Decimals: 0,
+		Supply:   big.NewInt(10000),
+	}
+	createAssetTx.Issuer.Signature = alice.Sign(createAssetTx.SignBytes(et.chainID))
+	_, res := et.executor.ExecuteTx(createAssetTx)
+	assert.True(res.IsOK(), res.String())
+	assetID := createAssetTx.AssetID()
+	tokens := func(amount int64) types.Coins {
+		return types.NewTokenCoins(assetID, big.NewInt(amount))
+	}
+
+	// Reserve a fund and its collateral in tokens
+	resourceID := "rid001"
+	reserveFundTx := &types.ReserveFundTx{
+		Fee: types.NewCoins(0, txFee),
+		Source: types.TxInput{
+			Address:  alice.Address,
+			Coins:    tokens(1000),
+			Sequence: 2,
+		},
+		Collateral:  tokens(1001),
+		ResourceIDs: []string{resourceID},
+		Duration:    1000,
+	}
+	reserveFundTx.Source.Signature = alice.Sign(reserveFundTx.SignBytes(et.chainID))
+	_, res = et.executor.ExecuteTx(reserveFundTx)
+	assert.True(res.IsOK(), res.String())
+
+	aliceAcc := et.state().Delivered().GetAccount(alice.Address)
+	assert.Equal(int64(10000-2001), aliceAcc.Balance.GetToken(assetID).Int64())
+	assert.Equal(1, len(aliceAcc.ReservedFunds))
+	assert.True(tokens(1001).IsEqual(aliceAcc.ReservedFunds[0].Collateral))
+	assert.True(tokens(1000).IsEqual(aliceAcc.ReservedFunds[0].InitialFund))
+
+	et.state().Commit()
+
+	// Bob is paid in tokens out of the reserved fund and pays the fee in SPAY
+	createTokenPaymentTx := func(amount int64, tgtSeq, paymentSeq uint64) *types.ServicePaymentTx {
+		tx := &types.ServicePaymentTx{
+			Fee: types.NewCoins(0, txFee),
+			Source: types.TxInput{
+				Address:  alice.Address,
+				Coins:    tokens(amount),
+				Sequence: 2,
+			},
+			Target: types.TxInput{
+				Address:  bob.Address,
+				Sequence: tgtSeq,
+			},
+			PaymentSequence: paymentSeq,
+			ReserveSequence: 2,
+			ResourceID:      resourceID,
+		}
+		tx.Source.Signature = alice.Sign(tx.SourceSignBytes(et.chainID))
+		tx.Target.Signature = bob.Sign(tx.TargetSignBytes(et.chainID))
+		return tx
+	}
+	_, res = et.executor.ExecuteTx(createTokenPaymentTx(300, 1, 1))
+	assert.True(res.IsOK(), res.String())
+	et.state().Commit()
+	_, res = et.executor.ExecuteTx(createTokenPaymentTx(500, 2, 2))
+	assert.True(res.IsOK(), res.String())
+	et.state().Commit()
+
+	aliceAcc = et.state().Delivered().GetAccount(alice.Address)
+	assert.True(tokens(800).IsEqual(aliceAcc.ReservedFunds[0].UsedFund))
+	bobAcc := et.state().Delivered().GetAccount(bob.Address)
+	assert.Equal(int64(800), bobAcc.Balance.GetToken(assetID).Int64())
+	assert.Equal(int64(98*txFee), bobAcc.Balance.SPAYWei.Int64())
+
+	// The fee of a service payment cannot be paid with tokens
+	tx := createTokenPaymentTx(100, 3, 3)
+	tx.Fee = tx.Fee.Plus(tokens(1))
+	tx.Source.Signature = alice.Sign(tx.SourceSignBytes(et.chainID))
+	tx.Target.Signature = bob.Sign(tx.TargetSignBytes(et.chainID))
+	_, res = et.executor.ExecuteTx(tx)
+	assert.True(res.IsError())
+
+	// Once the fund expires, the tokens left in the fund and the collateral are returned to Alice
+	et.fastforwardTo(common.HeightEnableNativeTokens + 1000 + types.ReservedFundFreezePeriodDuration + 2)
+	sendTx := &types.SendTx{
+		Fee: types.NewCoins(0, txFee),
+		Inputs: []types.TxInput{{
+			Address:  alice.Address,
+			Coins:    types.NewCoins(0, txFee).Plus(tokens(100)),
+			Sequence: 3,
+		}},
+		Outputs: []types.TxOutput{{
+			Address: bob.Address,
+			Coins:   tokens(100),
+		}},
+	}
+	sendTx.Inputs[0].Signature = alice.Sign(sendTx.SignBytes(et.chainID))
+	_, res = et.executor.ExecuteTx(sendTx)
+	assert.True(res.IsOK(), res.String())
+
+	aliceAcc = et.state().Delivered().GetAccount(alice.Address)
+	assert.Equal(0, len(aliceAcc.ReservedFunds))
+	assert.Equal(int64(10000-800-100), aliceAcc.Balance.GetToken(assetID).Int64())
+	bobAcc = et.state().Delivered().GetAccount(bob.Address)
+	assert.Equal(int64(900), bobAcc.Balance.GetToken(assetID).Int64())
+}
@@ -271,22 +271,17 @@ func deploySmartContract(et *execTest, deployerPrivAcc *types.PrivAccount,
 	deploySCTx.From.Signature = deployerPrivAcc.Sign(signBytes)
 
 	// Dry run to get the smart contract address when it is actually deployed
-	parentBlock := &core.Block{
-		BlockHeader: &core.BlockHeader{
-			Height:    1,
-			Timestamp: 1601599331,
-		},
-	}
+	parentBlockInfo := vm.NewBlockInfo(1, big.NewInt(1601599331), et.chainID)
 	stateCopy, err := et.state().Delivered().Copy()
 	assert.Nil(err)
-	_, contractAddr, gasUsed, vmErr := vm.Execute(parentBlock, deploySCTx, stateCopy)
+	_, contractAddr, gasUsed, vmErr := vm.Execute(parentBlockInfo, deploySCTx, stateCopy)
 	assert.Nil(vmErr)
 	log.Infof("[Deployment] gas used: %v", gasUsed)
 
 	// The actual on-chain deplpoyment
-	res := et.executor.getTxExecutor(deploySCTx).sanityCheck(et.chainID, et.state().Delivered(), deploySCTx)
+	res := et.executor.getTxExecutor(deploySCTx).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, deploySCTx)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(deploySCTx).process(et.chainID, et.state().Delivered(), deploySCTx)
+	_, res = et.executor.getTxExecutor(deploySCTx).process(et.chainID, et.state().Delivered(), core.DeliveredView, deploySCTx)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
@@ -337,13 +332,8 @@ func callSmartContract(et *execTest, contractAddr common.Address, callerPrivAcc
 	stateCopy, err := et.state().Delivered().Copy()
 	assert.Nil(err)
 
-	parentBlock := &core.Block{
-		BlockHeader: &core.BlockHeader{
-			Height:    1,
-			Timestamp: 1601599331,
-		},
-	}
-	vmRet, execContractAddr, gasUsed, vmErr := vm.Execute(callSCTX, stateCopy)
+	parentBlockInfo := vm.NewBlockInfo(1, big.NewInt(1601599331), et.chainID)
+	vmRet, execContractAddr, gasUsed, vmErr := vm.Execute(parentBlockInfo, callSCTX, stateCopy)
 	assert.Equal(contractAddr, execContractAddr)
 	log.Infof("[Call      ] gas used: %v", gasUsed)
 
@@ -373,9 +363,9 @@ func executeSmartContract(et *execTest, contractAddr common.Address, callerPrivA
 	execSCTX.From.Signature = callerPrivAcc.Sign(signBytes)
 
 	// Execute the on-chain smart contract
-	res := et.executor.getTxExecutor(execSCTX).sanityCheck(et.chainID, et.state().Delivered(), execSCTX)
+	res := et.executor.getTxExecutor(execSCTX).sanityCheck(et.chainID, et.state().Delivered(), core.DeliveredView, execSCTX)
 	assert.True(res.IsOK(), res.Message)
-	_, res = et.executor.getTxExecutor(execSCTX).process(et.chainID, et.state().Delivered(), execSCTX)
+	_, res = et.executor.getTxExecutor(execSCTX).process(et.chainID, et.state().Delivered(), core.DeliveredView, execSCTX)
 	assert.True(res.IsOK(), res.Message)
 
 	et.state().Commit()
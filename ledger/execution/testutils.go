@@ -25,6 +25,7 @@ import (
 
 type TestConsensusEngine struct {
 	privKey *crypto.PrivateKey
+	ledger  core.Ledger
 }
 
 func (tce *TestConsensusEngine) ID() string                                               { return tce.privKey.PublicKey().Address().Hex() }
@@ -33,7 +34,8 @@ func (tce *TestConsensusEngine) GetTip(bool) *core.ExtendedBlock
 func (tce *TestConsensusEngine) GetEpoch() uint64                                         { return 100 }
 func (tce *TestConsensusEngine) AddMessage(msg interface{})                               {}
 func (tce *TestConsensusEngine) FinalizedBlocks() chan *core.Block                        { return nil }
-func (tce *TestConsensusEngine) GetLedger() core.Ledger                                   { return nil }
+func (tce *TestConsensusEngine) GetLedger() core.Ledger                                   { return tce.ledger }
+func (tce *TestConsensusEngine) SetLedger(ledger core.Ledger)                             { tce.ledger = ledger }
 func (tce *TestConsensusEngine) GetValidatorSet(blockHash common.Hash) *core.ValidatorSet { return nil }
 func (tce *TestConsensusEngine) GetLastFinalizedBlock() *core.ExtendedBlock {
 	return &core.ExtendedBlock{}
@@ -44,7 +46,7 @@ func (tce *TestConsensusEngine) GetEpochVotes() (*core.VoteSet, error) {
 
 func NewTestConsensusEngine(seed string) *TestConsensusEngine {
 	privKey, _, _ := crypto.TEST_GenerateKeyPairWithSeed(seed)
-	return &TestConsensusEngine{privKey}
+	return &TestConsensusEngine{privKey: privKey}
 }
 
 type TestValidatorManager struct {
@@ -77,6 +79,18 @@ func NewTestValidatorManager(proposer core.Validator, valSet *core.ValidatorSet)
 	}
 }
 
+// TestLedger provides the current block to the executors, the other methods of the ledger are not mocked
+type TestLedger struct {
+	core.Ledger
+	currentBlock *core.Block
+}
+
+func (tl *TestLedger) GetCurrentBlock() *core.Block { return tl.currentBlock }
+
+func NewTestLedger(currentBlock *core.Block) *TestLedger {
+	return &TestLedger{currentBlock: currentBlock}
+}
+
 type execTest struct {
 	chainID  string
 	executor *Executor
@@ -127,7 +141,14 @@ func (et *execTest) reset() {
 	valMgr := NewTestValidatorManager(propser, valSet)
 
 	chain := blockchain.CreateTestChain()
-	executor := NewExecutor(db, chain, ledgerState, consensus, valMgr, nil)
+	currentBlock := &core.Block{
+		BlockHeader: &core.BlockHeader{
+			ChainID:  chainID,
+			Height:   initHeight + 1,
+			Proposer: et.accProposer.PrivKey.PublicKey().Address(),
+		},
+	}
+	executor := NewExecutor(db, chain, ledgerState, consensus, valMgr, NewTestLedger(currentBlock))
 
 	et.chainID = chainID
 	et.executor = executor
@@ -334,8 +355,8 @@ func setupForSmartContract(ast *assert.Assertions, numAccounts int) (et *execTes
 		secret := "acc_secret_" + strconv.FormatInt(int64(i), 16)
 		privAccount := types.MakeAccWithInitBalance(secret,
 			types.Coins{
-				big.NewInt(0),
-				big.NewInt(1).Mul(big.NewInt(9000000), big.NewInt(int64(types.MinimumGasPriceJune2021))),
+				SCPTWei: big.NewInt(0),
+				SPAYWei: big.NewInt(1).Mul(big.NewInt(9000000), big.NewInt(int64(types.MinimumGasPriceJune2021))),
 			})
 		privAccounts = append(privAccounts, privAccount)
 		et.acc2State(privAccount)
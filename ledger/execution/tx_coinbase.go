@@ -99,10 +99,25 @@ func (exec *CoinbaseTxExecutor) sanityCheck(chainID string, view *st.StoreView,
 	eliteEdgeNodeVotes := currentBlock.EliteEdgeNodeVotes
 	lightningPool, eliteEdgeNodePool := RetrievePools(exec.consensus.GetLedger(), exec.chain, exec.db, tx.BlockHeight, lightningVotes, eliteEdgeNodeVotes)
 	expectedRewards = CalculateReward(exec.consensus.GetLedger(), view, validatorSet, lightningVotes, lightningPool, eliteEdgeNodeVotes, eliteEdgeNodePool)
+	AddPriorityFees(view, expectedRewards)
 
 	if len(expectedRewards) != len(tx.Outputs) {
 		return result.Error("Number of rewarded account is incorrect")
 	}
+
+	// the proposers of the previous blocks must receive their priority fees
+	for _, fee := range view.GetPriorityFees() {
+		paid := false
+		for _, output := range tx.Outputs {
+			if output.Address == fee.Address && output.Coins.IsGTE(fee.Coins) {
+				paid = true
+				break
+			}
+		}
+		if !paid {
+			return result.Error("Priority fees of %v not paid, expecting %v", fee.Address, fee.Coins)
+		}
+	}
 /*
 	for _, output := range tx.Outputs {
 		exp, ok := expectedRewards[string(output.Address[:])]
@@ -136,6 +151,10 @@ func (exec *CoinbaseTxExecutor) process(chainID string, view *st.StoreView, view
 		}
 	}
 
+	if len(view.GetPriorityFees()) > 0 {
+		view.SetPriorityFees(nil) // paid by the outputs
+	}
+
 	view.SetCoinbaseTransactionProcessed(true)
 
 	txHash := types.TxID(chainID, tx)
@@ -188,6 +207,19 @@ func RetrievePools(ledger core.Ledger, chain *blockchain.Chain, db database.Data
 	return lightningPool, eliteEdgeNodePool
 }
 
+// AddPriorityFees adds the priority fees earned by the proposers of the previous blocks to the
+// rewards of the CoinbaseTx
+func AddPriorityFees(view *st.StoreView, accountReward map[string]types.Coins) {
+	for _, fee := range view.GetPriorityFees() {
+		addr := string(fee.Address[:])
+		if reward, ok := accountReward[addr]; ok {
+			accountReward[addr] = reward.Plus(fee.Coins)
+		} else {
+			accountReward[addr] = fee.Coins
+		}
+	}
+}
+
 // CalculateReward calculates the block reward for each account
 func CalculateReward(ledger core.Ledger, view *st.StoreView, validatorSet *core.ValidatorSet,
 	lightningVotes *core.AggregatedVotes, lightningPool *core.LightningCandidatePool,
@@ -0,0 +1,128 @@
+package execution
+
+import (
+	"math/big"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/common/result"
+	"github.com/scripttoken/script/core"
+	st "github.com/scripttoken/script/ledger/state"
+	"github.com/scripttoken/script/ledger/types"
+)
+
+var _ TxExecutor = (*CreateAssetTxExecutor)(nil)
+
+// ------------------------------- CreateAsset Transaction -----------------------------------
+
+// CreateAssetTxExecutor implements the TxExecutor interface
+type CreateAssetTxExecutor struct {
+	state *st.LedgerState
+}
+
+// NewCreateAssetTxExecutor creates a new instance of CreateAssetTxExecutor
+func NewCreateAssetTxExecutor(state *st.LedgerState) *CreateAssetTxExecutor {
+	return &CreateAssetTxExecutor{
+		state: state,
+	}
+}
+
+func (exec *CreateAssetTxExecutor) sanityCheck(chainID string, view *st.StoreView, viewSel core.ViewSelector, transaction types.Tx) result.Result {
+	blockHeight := view.Height() + 1 // the view points to the parent of the current block
+	tx := transaction.(*types.CreateAssetTx)
+
+	res := tx.Issuer.ValidateBasic()
+	if res.IsError() {
+		return res
+	}
+
+	if !tx.Issuer.Coins.IsZero() {
+		return result.Error("The issuer of an asset cannot send coins")
+	}
+
+	issuerAccount, res := getInput(view, tx.Issuer)
+	if res.IsError() {
+		return res
+	}
+
+	signBytes := tx.SignBytes(chainID)
+	res = validateInputAdvanced(issuerAccount, signBytes, tx.Issuer, blockHeight)
+	if res.IsError() {
+		return res
+	}
+
+	if len(tx.Name) == 0 || len(tx.Name) > types.MaxAssetNameLength {
+		return result.Error("The asset name must have between 1 and %v characters", types.MaxAssetNameLength)
+	}
+	if len(tx.Symbol) == 0 || len(tx.Symbol) > types.MaxAssetSymbolLength {
+		return result.Error("The asset symbol must have between 1 and %v characters", types.MaxAssetSymbolLength)
+	}
+	if tx.Decimals > types.MaxAssetDecimals {
+		return result.Error("The asset cannot have more than %v decimals", types.MaxAssetDecimals)
+	}
+	if tx.Supply == nil || tx.Supply.Sign() <= 0 {
+		return result.Error("The asset supply must be positive")
+	}
+
+	if view.GetAsset(tx.AssetID()) != nil {
+		return result.Error("Asset %v already exists", tx.AssetID().Hex())
+	}
+
+	if minTxFee, success := sanityCheckForFee(tx.Fee, blockHeight); !success {
+		return result.Error("Insufficient fee. Transaction fee needs to be at least %v SPAYWei",
+			minTxFee).WithErrorCode(result.CodeInvalidFee)
+	}
+
+	if !issuerAccount.Balance.IsGTE(tx.Fee) {
+		return result.Error("Insufficient fund: the issuer balance is %v, but the fee is %v",
+			issuerAccount.Balance, tx.Fee).WithErrorCode(result.CodeInsufficientFund)
+	}
+
+	return result.OK
+}
+
+func (exec *CreateAssetTxExecutor) process(chainID string, view *st.StoreView, viewSel core.ViewSelector, transaction types.Tx) (common.Hash, result.Result) {
+	tx := transaction.(*types.CreateAssetTx)
+
+	issuerAccount, res := getInput(view, tx.Issuer)
+	if res.IsError() {
+		return common.Hash{}, res
+	}
+
+	if !chargeFee(issuerAccount, tx.Fee) {
+		return common.Hash{}, result.Error("failed to charge transaction fee")
+	}
+
+	asset := &types.Asset{
+		ID:       tx.AssetID(),
+		Issuer:   tx.Issuer.Address,
+		Name:     tx.Name,
+		Symbol:   tx.Symbol,
+		Decimals: tx.Decimals,
+		Supply:   new(big.Int).Set(tx.Supply),
+	}
+	view.SetAsset(asset)
+
+	issuerAccount.Balance = issuerAccount.Balance.Plus(types.NewTokenCoins(asset.ID, asset.Supply))
+	issuerAccount.Sequence++
+	view.SetAccount(tx.Issuer.Address, issuerAccount)
+
+	txHash := types.TxID(chainID, tx)
+	return txHash, result.OK
+}
+
+func (exec *CreateAssetTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
+	tx := transaction.(*types.CreateAssetTx)
+	return &core.TxInfo{
+		Address:           tx.Issuer.Address,
+		Sequence:          tx.Issuer.Sequence,
+		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+	}
+}
+
+func (exec *CreateAssetTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
+	tx := transaction.(*types.CreateAssetTx)
+	fee := tx.Fee
+	gas := new(big.Int).SetUint64(getRegularTxGas(exec.state))
+	effectiveGasPrice := new(big.Int).Div(fee.SPAYWei, gas)
+	return effectiveGasPrice
+}
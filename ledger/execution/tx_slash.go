@@ -2,6 +2,7 @@ package execution
 
 import (
 	"math/big"
+	"unicode/utf8"
 
 	"github.com/scripttoken/script/common"
 	"github.com/scripttoken/script/common/result"
@@ -170,7 +171,12 @@ func (exec *SlashTxExecutor) verifySlashProof(chainID string, slashedAccount *ty
 				return false // servicePaymentTx not signed by the slashed account
 			}
 
-			paymentKey := string(servicePaymentTx.Target.Address[:]) + "." + string(servicePaymentTx.PaymentSequence)
+			// Same key as the former string(uint64) conversion: the sequences out of the rune range map to U+FFFD
+			paymentSeq := servicePaymentTx.PaymentSequence
+			if paymentSeq > utf8.MaxRune {
+				paymentSeq = utf8.RuneError
+			}
+			paymentKey := string(servicePaymentTx.Target.Address[:]) + "." + string(rune(paymentSeq))
 			_, targetExists := settledPaymentLookup[paymentKey]
 			if targetExists {
 				return false // to prevent using partial payments as proof
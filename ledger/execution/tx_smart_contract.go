@@ -38,7 +38,22 @@ func NewSmartContractTxExecutor(chain *blockchain.Chain, state *st.LedgerState,
 
 func (exec *SmartContractTxExecutor) sanityCheck(chainID string, view *st.StoreView, viewSel core.ViewSelector, transaction types.Tx) result.Result {
 	blockHeight := getBlockHeight(exec.state)
-	tx := transaction.(*types.SmartContractTx)
+	baseFee := view.GetBaseFee()
+	tx := exec.castTx(transaction, baseFee)
+
+	// The gas price cap bounds the fee the sender may pay per gas
+	gasPriceCap := tx.GasPrice
+	if dtx, ok := transaction.(*types.SmartContractTxV2); ok {
+		if blockHeight < common.HeightEnableDynamicFee {
+			return result.Error("Dynamic fee transaction is not supported yet")
+		}
+		if dtx.MaxFeePerGas == nil || dtx.MaxPriorityFeePerGas == nil ||
+			dtx.MaxPriorityFeePerGas.Sign() < 0 || dtx.MaxPriorityFeePerGas.Cmp(dtx.MaxFeePerGas) > 0 {
+			return result.Error("Invalid fee caps, max fee per gas: %v, max priority fee per gas: %v",
+				dtx.MaxFeePerGas, dtx.MaxPriorityFeePerGas).WithErrorCode(result.CodeInvalidGasPrice)
+		}
+		gasPriceCap = dtx.MaxFeePerGas
+	}
 
 	// Validate from, basic
 	res := tx.From.ValidateBasic()
@@ -46,32 +61,12 @@ func (exec *SmartContractTxExecutor) sanityCheck(chainID string, view *st.StoreV
 		return res
 	}
 
-	// Check signatures
-	signBytes := tx.SignBytes(chainID)
-	nativeSignatureValid := tx.From.Signature.Verify(signBytes, tx.From.Address)
-	if blockHeight >= common.HeightTxWrapperExtension {
-		signBytesV2 := types.ChangeEthereumTxWrapper(signBytes, 2)
-		nativeSignatureValid = nativeSignatureValid || tx.From.Signature.Verify(signBytesV2, tx.From.Address)
-	}
-
-	if !nativeSignatureValid {
-		if blockHeight < common.HeightRPCCompatibility {
-			return result.Error("Signature verification failed, SignBytes: %v",
-				hex.EncodeToString(signBytes)).WithErrorCode(result.CodeInvalidSignature)
-		}
-
-		// interpret the signature as ETH tx signature
-		if tx.From.Coins.SCPTWei.Cmp(big.NewInt(0)) != 0 {
-			return result.Error("Sending Script with ETH transaction is not allowed") // extra check, since ETH transaction only signs the SPAY part (i.e., value, gasPrice, gasLimit, etc)
-		}
-
-		ethChainID := int64(viper.GetUint64(common.CfgGenesisEthChainID))
-		ethSigningHash := tx.EthSigningHash(chainID, ethChainID, blockHeight)
-		err := crypto.ValidateEthSignature(tx.From.Address, ethSigningHash, tx.From.Signature)
-		if err != nil {
-			return result.Error("ETH Signature verification failed, SignBytes: %v, error: %v",
-				hex.EncodeToString(signBytes), err.Error()).WithErrorCode(result.CodeInvalidSignature)
+	if dtx, ok := transaction.(*types.SmartContractTxV2); ok {
+		if res := exec.checkSignatureV2(chainID, dtx, blockHeight); res.IsError() {
+			return res
 		}
+	} else if res := exec.checkSignature(chainID, tx, blockHeight); res.IsError() {
+		return res
 	}
 
 	// Get input account
@@ -107,6 +102,11 @@ func (exec *SmartContractTxExecutor) sanityCheck(chainID string, view *st.StoreV
 			WithErrorCode(result.CodeInvalidGasPrice)
 	}
 
+	if blockHeight >= common.HeightEnableDynamicFee && tx.GasPrice.Cmp(baseFee) < 0 {
+		return result.Error("Insufficient gas price. Gas price needs to be at least the base fee %v SPAYWei", baseFee).
+			WithErrorCode(result.CodeInvalidGasPrice)
+	}
+
 	maxGasLimit := types.GetMaxGasLimit(blockHeight)
 	if new(big.Int).SetUint64(tx.GasLimit).Cmp(maxGasLimit) > 0 {
 		return result.Error("Invalid gas limit. Gas limit needs to be at most %v", maxGasLimit).
@@ -114,7 +114,11 @@ func (exec *SmartContractTxExecutor) sanityCheck(chainID string, view *st.StoreV
 	}
 
 	if vm.SupportWrappedScript(blockHeight) {
-		err := exec.checkIntrinsicGas(tx)
+		var accessList types.AccessList
+		if dtx, ok := transaction.(*types.SmartContractTxV2); ok {
+			accessList = dtx.AccessList
+		}
+		err := exec.checkIntrinsicGas(tx, accessList, blockHeight)
 		if err != nil {
 			return result.Error("Intrinsic gas check failed: %v", err).
 				WithErrorCode(result.CodeInvalidGasLimit)
@@ -122,7 +126,7 @@ func (exec *SmartContractTxExecutor) sanityCheck(chainID string, view *st.StoreV
 	}
 
 	zero := big.NewInt(0)
-	feeLimit := new(big.Int).Mul(tx.GasPrice, new(big.Int).SetUint64(tx.GasLimit))
+	feeLimit := new(big.Int).Mul(gasPriceCap, new(big.Int).SetUint64(tx.GasLimit))
 	if feeLimit.BitLen() > 255 || feeLimit.Cmp(zero) < 0 {
 		// There is no explicit upper limit for big.Int. Just be conservative
 		// here to prevent potential overflow attack
@@ -154,8 +158,61 @@ func (exec *SmartContractTxExecutor) sanityCheck(chainID string, view *st.StoreV
 	return result.OK
 }
 
+func (exec *SmartContractTxExecutor) checkSignature(chainID string, tx *types.SmartContractTx, blockHeight uint64) result.Result {
+	signBytes := tx.SignBytes(chainID)
+	nativeSignatureValid := tx.From.Signature.Verify(signBytes, tx.From.Address)
+	if blockHeight >= common.HeightTxWrapperExtension {
+		signBytesV2 := types.ChangeEthereumTxWrapper(signBytes, 2)
+		nativeSignatureValid = nativeSignatureValid || tx.From.Signature.Verify(signBytesV2, tx.From.Address)
+	}
+
+	if !nativeSignatureValid {
+		if blockHeight < common.HeightRPCCompatibility {
+			return result.Error("Signature verification failed, SignBytes: %v",
+				hex.EncodeToString(signBytes)).WithErrorCode(result.CodeInvalidSignature)
+		}
+
+		// interpret the signature as ETH tx signature
+		if tx.From.Coins.SCPTWei.Cmp(big.NewInt(0)) != 0 {
+			return result.Error("Sending Script with ETH transaction is not allowed") // extra check, since ETH transaction only signs the SPAY part (i.e., value, gasPrice, gasLimit, etc)
+		}
+
+		ethChainID := int64(viper.GetUint64(common.CfgGenesisEthChainID))
+		ethSigningHash := tx.EthSigningHash(chainID, ethChainID, blockHeight)
+		err := crypto.ValidateEthSignature(tx.From.Address, ethSigningHash, tx.From.Signature)
+		if err != nil {
+			return result.Error("ETH Signature verification failed, SignBytes: %v, error: %v",
+				hex.EncodeToString(signBytes), err.Error()).WithErrorCode(result.CodeInvalidSignature)
+		}
+	}
+
+	return result.OK
+}
+
+// checkSignatureV2 checks the signature of a dynamic fee transaction, which is either signed
+// natively, or translated from a typed ETH transaction (access list or dynamic fee)
+func (exec *SmartContractTxExecutor) checkSignatureV2(chainID string, tx *types.SmartContractTxV2, blockHeight uint64) result.Result {
+	signBytes := tx.SignBytes(chainID)
+	if tx.From.Signature.Verify(signBytes, tx.From.Address) {
+		return result.OK
+	}
+
+	if tx.From.Coins.SCPTWei != nil && tx.From.Coins.SCPTWei.Sign() != 0 {
+		return result.Error("Sending Script with ETH transaction is not allowed") // extra check, since ETH transaction only signs the SPAY part
+	}
+
+	ethChainID := int64(viper.GetUint64(common.CfgGenesisEthChainID))
+	if _, err := tx.RecoverEthTx(ethChainID); err != nil {
+		return result.Error("Signature verification failed, SignBytes: %v, error: %v",
+			hex.EncodeToString(signBytes), err.Error()).WithErrorCode(result.CodeInvalidSignature)
+	}
+
+	return result.OK
+}
+
 func (exec *SmartContractTxExecutor) process(chainID string, view *st.StoreView, viewSel core.ViewSelector, transaction types.Tx) (common.Hash, result.Result) {
-	tx := transaction.(*types.SmartContractTx)
+	baseFee := view.GetBaseFee()
+	tx := exec.castTx(transaction, baseFee)
 
 	view.ResetLogs()
 	view.ResetBalanceChanges()
@@ -165,7 +222,11 @@ func (exec *SmartContractTxExecutor) process(chainID string, view *st.StoreView,
 	//       Otherwise, the fromAccount returned by getInput() will have incorrect balance.
 	pb := exec.state.ParentBlock()
 	parentBlockInfo := vm.NewBlockInfo(pb.Height, pb.Timestamp, pb.ChainID)
-	evmRet, contractAddr, gasUsed, evmErr := vm.Execute(parentBlockInfo, tx, view)
+	var accessList types.AccessList
+	if dtx, ok := transaction.(*types.SmartContractTxV2); ok {
+		accessList = dtx.AccessList
+	}
+	evmRet, contractAddr, gasUsed, evmErr := vm.ExecuteWithAccessList(parentBlockInfo, tx, accessList, view)
 
 	fromAddress := tx.From.Address
 	fromAccount, success := getInput(view, tx.From)
@@ -188,7 +249,15 @@ func (exec *SmartContractTxExecutor) process(chainID string, view *st.StoreView,
 	}
 	view.SetAccount(fromAddress, fromAccount)
 
-	txHash := types.TxID(chainID, tx)
+	// The base fee part of the fee is burned, the rest is the priority fee of the proposer. The
+	// ledger accumulates the priority fees of the block, so that the transactions do not all write
+	// to the proposer account, and the CoinbaseTx of the next block pays them.
+	priorityFee := big.NewInt(0)
+	if getBlockHeight(exec.state) >= common.HeightEnableDynamicFee {
+		priorityFee = calculatePriorityFee(tx.GasPrice, baseFee, gasUsed)
+	}
+
+	txHash := types.TxID(chainID, transaction)
 
 	// TODO: Add tx receipt: status and events
 	logs := view.PopLogs()
@@ -200,19 +269,34 @@ func (exec *SmartContractTxExecutor) process(chainID string, view *st.StoreView,
 	}
 
 	if viewSel == core.DeliveredView { // only record the receipt for the delivered views
-		exec.chain.AddTxReceipt(exec.ledger.GetCurrentBlock(), tx, logs, balanceChanges, evmRet, contractAddr, gasUsed, evmErr)
+		block := exec.ledger.GetCurrentBlock()
+		view.RunWhenApplied(func() { // a speculative execution only records the receipt if it is applied
+			exec.chain.AddTxReceipt(block, transaction, logs, balanceChanges, evmRet, contractAddr, gasUsed, evmErr)
+		})
 	}
 
-	return txHash, result.OK
+	return txHash, result.OKWith(result.Info{"gasUsed": gasUsed, "priorityFee": priorityFee})
 }
 
-func (exec *SmartContractTxExecutor) checkIntrinsicGas(tx *types.SmartContractTx) error {
+// calculatePriorityFee returns the part of the fee above the base fee
+func calculatePriorityFee(gasPrice *big.Int, baseFee *big.Int, gasUsed uint64) *big.Int {
+	if gasPrice.Cmp(baseFee) <= 0 {
+		return big.NewInt(0)
+	}
+	priorityFee := new(big.Int).Sub(gasPrice, baseFee)
+	return priorityFee.Mul(priorityFee, new(big.Int).SetUint64(gasUsed))
+}
+
+func (exec *SmartContractTxExecutor) checkIntrinsicGas(tx *types.SmartContractTx, accessList types.AccessList, blockHeight uint64) error {
 	contractAddr := tx.To.Address
 	createContract := (contractAddr == common.Address{})
-	intrinsicGas, err := vm.CalculateIntrinsicGas(tx.Data, createContract)
+	intrinsicGas, err := vm.CalculateIntrinsicGas(tx.Data, createContract, blockHeight >= common.HeightEnableEVMBerlin)
 	if err != nil {
 		return err
 	}
+	if blockHeight >= common.HeightEnableEVMBerlin {
+		intrinsicGas += vm.CalculateAccessListGas(accessList)
+	}
 
 	gasLimit := tx.GasLimit
 	if intrinsicGas > gasLimit {
@@ -223,15 +307,38 @@ func (exec *SmartContractTxExecutor) checkIntrinsicGas(tx *types.SmartContractTx
 }
 
 func (exec *SmartContractTxExecutor) getTxInfo(transaction types.Tx) *core.TxInfo {
-	tx := transaction.(*types.SmartContractTx)
-	return &core.TxInfo{
+	tx := exec.castTx(transaction, nil)
+	txInfo := &core.TxInfo{
 		Address:           tx.From.Address,
 		Sequence:          tx.From.Sequence,
 		EffectiveGasPrice: exec.calculateEffectiveGasPrice(transaction),
+		GasLimit:          tx.GasLimit,
+	}
+	if txV2, ok := transaction.(*types.SmartContractTxV2); ok {
+		txInfo.MaxFeePerGas = txV2.MaxFeePerGas
+		txInfo.MaxPriorityFeePerGas = txV2.MaxPriorityFeePerGas
 	}
+	return txInfo
 }
 
+// calculateEffectiveGasPrice returns the gas price paid under the current base fee, which is
+// comparable to the fee per gas of the other transaction types. The mempool re-prices the
+// dynamic fee transactions when the base fee changes.
 func (exec *SmartContractTxExecutor) calculateEffectiveGasPrice(transaction types.Tx) *big.Int {
-	tx := transaction.(*types.SmartContractTx)
-	return tx.GasPrice
+	if getBlockHeight(exec.state) < common.HeightEnableDynamicFee {
+		return exec.castTx(transaction, nil).GasPrice
+	}
+	return exec.castTx(transaction, exec.state.Screened().GetBaseFee()).GasPrice
+}
+
+// castTx returns the legacy transaction executed by the virtual machine. The gas price of a
+// dynamic fee transaction is its effective gas price under the given base fee.
+func (exec *SmartContractTxExecutor) castTx(transaction types.Tx, baseFee *big.Int) *types.SmartContractTx {
+	if tx, ok := transaction.(*types.SmartContractTx); ok {
+		return tx
+	}
+	if tx, ok := transaction.(*types.SmartContractTxV2); ok {
+		return tx.SmartContractTx(tx.EffectiveGasPrice(baseFee))
+	}
+	panic("Unreachable code")
 }
@@ -3,6 +3,7 @@ package ledger
 import (
 	"encoding/hex"
 	"fmt"
+	"math/big"
 	"strconv"
 	"sync"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/scripttoken/script/store"
 	"github.com/scripttoken/script/store/kvstore"
+	"github.com/scripttoken/script/store/rollingdb"
 
 	log "github.com/sirupsen/logrus"
 
@@ -68,6 +70,12 @@ func (ledger *Ledger) SetExecutor(executor *exec.Executor) {
 	ledger.executor = executor
 }
 
+// SetSkipSanityCheck sets whether the transaction sanity checks are skipped, e.g. while
+// replaying blocks from a trusted source
+func (ledger *Ledger) SetSkipSanityCheck(skip bool) {
+	ledger.executor.SetSkipSanityCheck(skip)
+}
+
 // State returns the state of the ledger
 func (ledger *Ledger) State() *st.LedgerState {
 	return ledger.state
@@ -102,6 +110,20 @@ func (ledger *Ledger) GetFinalizedSnapshot() (*st.StoreView, error) {
 	return ledger.state.Finalized().Copy()
 }
 
+// GetOldestStateHeight returns the height of the oldest block whose state is still available
+func (ledger *Ledger) GetOldestStateHeight() uint64 {
+	height, ok := rollingdb.GetOldestStateHeight(ledger.db)
+	if !ok {
+		return ledger.chain.Root().Height
+	}
+	return height
+}
+
+// IsStateAvailable indicates whether the state at the given height is still available
+func (ledger *Ledger) IsStateAvailable(height uint64) bool {
+	return height >= ledger.GetOldestStateHeight()
+}
+
 // GetFinalizedValidatorCandidatePool returns the validator candidate pool of the latest DIRECTLY finalized block
 func (ledger *Ledger) GetFinalizedValidatorCandidatePool(blockHash common.Hash, isNext bool) (*core.ValidatorCandidatePool, error) {
 	db := ledger.state.DB()
@@ -256,7 +278,11 @@ func (ledger *Ledger) ScreenTx(rawTx common.Bytes) (txInfo *core.TxInfo, res res
 func (ledger *Ledger) ProposeBlockTxs(block *core.Block, shouldIncludeValidatorUpdateTxs bool) (stateRootHash common.Hash, blockRawTxs []common.Bytes, res result.Result) {
 	// Must always acquire locks in following order to avoid deadlock: mempool, ledger.
 	// Otherwise, could cause deadlock since mempool.InsertTransaction() also first acquires the mempool, and then the ledger lock
-	logger.Debugf("ProposeBlockTxs: Propose block transactions, block.height = %v", block.Height)
+	height := ledger.state.Height() + 1 // a nil block skips the special transactions, e.g. in the tests
+	if block != nil {
+		height = block.Height
+	}
+	logger.Debugf("ProposeBlockTxs: Propose block transactions, block.height = %v", height)
 	start := time.Now()
 
 	ledger.mempool.Lock()
@@ -270,7 +296,7 @@ func (ledger *Ledger) ProposeBlockTxs(block *core.Block, shouldIncludeValidatorU
 
 	view := ledger.state.Checked()
 
-	logger.Debugf("ProposeBlockTxs: Start adding block transactions, block.height = %v", block.Height)
+	logger.Debugf("ProposeBlockTxs: Start adding block transactions, block.height = %v", height)
 	preparationTime := time.Since(start)
 	start = time.Now()
 
@@ -279,16 +305,23 @@ func (ledger *Ledger) ProposeBlockTxs(block *core.Block, shouldIncludeValidatorU
 	ledger.addSpecialTransactions(block, view, &rawTxCandidates)
 
 	// Add regular transactions submitted by the clients
-	regularRawTxs := ledger.mempool.ReapUnsafe(core.MaxNumRegularTxsPerBlock)
+	blockGasLimit := types.GetBlockGasLimit(height)
+	var baseFee *big.Int
+	if height >= common.HeightEnableDynamicFee {
+		baseFee = view.GetBaseFee()
+	}
+	regularRawTxs := ledger.mempool.ReapUnsafeWithGasLimit(core.MaxNumRegularTxsPerBlock, blockGasLimit, baseFee)
 	for _, regularRawTx := range regularRawTxs {
 		rawTxCandidates = append(rawTxCandidates, regularRawTx)
 	}
 
-	logger.Debugf("ProposeBlockTxs: block transactions added, block.height = %v", block.Height)
+	logger.Debugf("ProposeBlockTxs: block transactions added, block.height = %v", height)
 	addTxsTime := time.Since(start)
 	start = time.Now()
 
 	blockRawTxs = []common.Bytes{}
+	blockGasUsed := uint64(0)
+	priorityFees := big.NewInt(0)
 	for _, rawTxCandidate := range rawTxCandidates {
 		tx, err := types.TxFromBytes(rawTxCandidate)
 		if err != nil {
@@ -305,27 +338,36 @@ func (ledger *Ledger) ProposeBlockTxs(block *core.Block, shouldIncludeValidatorU
 			}
 		}
 
+		if res := checkBlockGas(tx, blockGasUsed, blockGasLimit); res.IsError() {
+			logger.Debugf("Transaction skipped: errMsg = %v, tx = %v", res.Message, tx)
+			continue
+		}
+
 		_, res := ledger.executor.CheckTx(tx)
 		if res.IsError() {
 			logger.Errorf("Transaction check failed: errMsg = %v, tx = %v", res.Message, tx)
 			continue
 		}
+		blockGasUsed += txGasUsed(res)
+		priorityFees.Add(priorityFees, txPriorityFee(res))
 		blockRawTxs = append(blockRawTxs, rawTxCandidate)
 	}
 
-	logger.Debugf("ProposeBlockTxs: block transactions executed, block.height = %v", block.Height)
+	logger.Debugf("ProposeBlockTxs: block transactions executed, block.height = %v", height)
 	execTxsTime := time.Since(start)
 	start = time.Now()
 
+	updateBaseFee(view, height, blockGasUsed)
+	accruePriorityFees(view, block, priorityFees)
 	ledger.handleDelayedStateUpdates(view)
 
 	stateRootHash = view.Hash()
 
-	logger.Debugf("ProposeBlockTxs: delay update handled, block.height = %v", block.Height)
+	logger.Debugf("ProposeBlockTxs: delay update handled, block.height = %v", height)
 	handleDelayedUpdateTime := time.Since(start)
 
 	logger.Debugf("ProposeBlockTxs: Done, block.height = %v, preparationTime = %v, addTxsTime = %v, execTxsTime = %v, handleDelayedUpdateTime = %v",
-		block.Height, preparationTime, addTxsTime, execTxsTime, handleDelayedUpdateTime)
+		height, preparationTime, addTxsTime, execTxsTime, handleDelayedUpdateTime)
 
 	return stateRootHash, blockRawTxs, result.OK
 }
@@ -358,33 +400,26 @@ func (ledger *Ledger) ApplyBlockTxs(block *core.Block) result.Result {
 	parentBlock := extParentBlock.Block
 	logger.Debugf("ApplyBlockTxs: Start applying block transactions, block.height = %v", block.Height)
 
-	hasValidatorUpdate := false
-	txProcessTime := []time.Duration{}
-	for _, rawTx := range blockRawTxs {
-		start := time.Now()
-		tx, err := types.TxFromBytes(rawTx)
-		if err != nil {
-			//ledger.resetState(currHeight, currStateRoot)
-			ledger.resetState(parentBlock)
-			return result.Error("Failed to parse transaction: %v", hex.EncodeToString(rawTx))
-		}
-		if dtx, ok := tx.(*types.DepositStakeTx); ok && dtx.Purpose == core.StakeForValidator {
-			hasValidatorUpdate = true
-		} else if wtx, ok := tx.(*types.WithdrawStakeTx); ok && wtx.Purpose == core.StakeForValidator {
-			hasValidatorUpdate = true
-		}
-		_, res := ledger.executor.ExecuteTx(tx)
-		if res.IsError() {
-			//ledger.resetState(currHeight, currStateRoot)
-			ledger.resetState(parentBlock)
-			return res
-		}
-		txProcessTime = append(txProcessTime, time.Since(start))
+	var hasValidatorUpdate bool
+	var blockGasUsed uint64
+	var priorityFees *big.Int
+	var res result.Result
+	start := time.Now()
+	if viper.GetBool(common.CfgLedgerParallelTxExecution) {
+		hasValidatorUpdate, blockGasUsed, priorityFees, res = ledger.applyTxsOptimistically(view, block.Height, blockRawTxs)
+	} else {
+		hasValidatorUpdate, blockGasUsed, priorityFees, res = ledger.applyTxsSequentially(block.Height, blockRawTxs)
 	}
+	if res.IsError() {
+		//ledger.resetState(currHeight, currStateRoot)
+		ledger.resetState(parentBlock)
+		return res
+	}
+	txProcessTime := time.Since(start)
 
-	logger.Debugf("ApplyBlockTxs: Finish applying block transactions, block.height=%v, txProcessTime=%v", block.Height, txProcessTime)
-
-	start := time.Now()
+	start = time.Now()
+	updateBaseFee(view, block.Height, blockGasUsed)
+	accruePriorityFees(view, block, priorityFees)
 	ledger.handleDelayedStateUpdates(view)
 	handleDelayedUpdateTime := time.Since(start)
 
@@ -418,6 +453,37 @@ func (ledger *Ledger) ApplyBlockTxs(block *core.Block) result.Result {
 	return result.OKWith(result.Info{"hasValidatorUpdate": hasValidatorUpdate})
 }
 
+// applyTxsSequentially executes the block transactions one by one on the delivered view
+func (ledger *Ledger) applyTxsSequentially(blockHeight uint64, blockRawTxs []common.Bytes) (hasValidatorUpdate bool, blockGasUsed uint64, priorityFees *big.Int, res result.Result) {
+	txProcessTime := []time.Duration{}
+	priorityFees = big.NewInt(0)
+	blockGasLimit := types.GetBlockGasLimit(blockHeight)
+	for _, rawTx := range blockRawTxs {
+		start := time.Now()
+		tx, err := types.TxFromBytes(rawTx)
+		if err != nil {
+			return false, 0, nil, result.Error("Failed to parse transaction: %v", hex.EncodeToString(rawTx))
+		}
+		if isValidatorStakeTx(tx) {
+			hasValidatorUpdate = true
+		}
+		if res := checkBlockGas(tx, blockGasUsed, blockGasLimit); res.IsError() {
+			return false, 0, nil, res
+		}
+		_, res := ledger.executor.ExecuteTx(tx)
+		if res.IsError() {
+			return false, 0, nil, res
+		}
+		blockGasUsed += txGasUsed(res)
+		priorityFees.Add(priorityFees, txPriorityFee(res))
+		txProcessTime = append(txProcessTime, time.Since(start))
+	}
+
+	logger.Debugf("ApplyBlockTxs: Finish applying block transactions, block.height=%v, txProcessTime=%v", blockHeight, txProcessTime)
+
+	return hasValidatorUpdate, blockGasUsed, priorityFees, result.OK
+}
+
 // ApplyBlockTxsForChainCorrection applies all block's txs and re-calculate root hash
 func (ledger *Ledger) ApplyBlockTxsForChainCorrection(block *core.Block) (common.Hash, result.Result) {
 	ledger.mempool.Lock()
@@ -442,6 +508,8 @@ func (ledger *Ledger) ApplyBlockTxsForChainCorrection(block *core.Block) (common
 	parentBlock := extParentBlock.Block
 
 	hasValidatorUpdate := false
+	blockGasUsed := uint64(0)
+	priorityFees := big.NewInt(0)
 	for _, rawTx := range blockRawTxs {
 		tx, err := types.TxFromBytes(rawTx)
 		if err != nil {
@@ -449,9 +517,7 @@ func (ledger *Ledger) ApplyBlockTxsForChainCorrection(block *core.Block) (common
 			ledger.resetState(parentBlock)
 			return common.Hash{}, result.Error("Failed to parse transaction: %v", hex.EncodeToString(rawTx))
 		}
-		if dtx, ok := tx.(*types.DepositStakeTx); ok && dtx.Purpose == core.StakeForValidator {
-			hasValidatorUpdate = true
-		} else if wtx, ok := tx.(*types.WithdrawStakeTx); ok && wtx.Purpose == core.StakeForValidator {
+		if isValidatorStakeTx(tx) {
 			hasValidatorUpdate = true
 		}
 		_, res := ledger.executor.ExecuteTx(tx)
@@ -460,8 +526,12 @@ func (ledger *Ledger) ApplyBlockTxsForChainCorrection(block *core.Block) (common
 			ledger.resetState(parentBlock)
 			return common.Hash{}, res
 		}
+		blockGasUsed += txGasUsed(res)
+		priorityFees.Add(priorityFees, txPriorityFee(res))
 	}
 
+	updateBaseFee(view, block.Height, blockGasUsed)
+	accruePriorityFees(view, block, priorityFees)
 	ledger.handleDelayedStateUpdates(view)
 
 	ledger.state.Commit() // commit to persistent storage
@@ -631,6 +701,81 @@ func (ledger *Ledger) resetState(block *core.Block) result.Result {
 	return result.OK
 }
 
+// checkBlockGas checks that the gas limit of the transaction fits in the gas left in the
+// block. blockGasLimit == 0 means the gas of the block is not limited.
+func checkBlockGas(tx types.Tx, blockGasUsed, blockGasLimit uint64) result.Result {
+	if blockGasLimit == 0 {
+		return result.OK
+	}
+	var txGasLimit uint64
+	switch sctx := tx.(type) {
+	case *types.SmartContractTx:
+		txGasLimit = sctx.GasLimit
+	case *types.SmartContractTxV2:
+		txGasLimit = sctx.GasLimit
+	default:
+		return result.OK
+	}
+	if blockGasUsed > blockGasLimit || txGasLimit > blockGasLimit-blockGasUsed {
+		return result.Error("Block gas limit exceeded: gas used = %v, tx gas limit = %v, block gas limit = %v",
+			blockGasUsed, txGasLimit, blockGasLimit).WithErrorCode(result.CodeBlockGasLimitExceeded)
+	}
+	return result.OK
+}
+
+// updateBaseFee sets the base fee of the next block from the gas used by the current block
+func updateBaseFee(view *st.StoreView, blockHeight uint64, blockGasUsed uint64) {
+	if blockHeight < common.HeightEnableDynamicFee {
+		return
+	}
+	baseFee := types.CalcBaseFee(view.GetBaseFee(), blockGasUsed, types.GetBlockGasLimit(blockHeight))
+	view.SetBaseFee(baseFee)
+}
+
+// accruePriorityFees records the priority fees earned by the proposer of the block, which
+// the CoinbaseTx of the next block pays
+func accruePriorityFees(view *st.StoreView, block *core.Block, priorityFees *big.Int) {
+	if block == nil || priorityFees.Sign() == 0 {
+		return
+	}
+	fees := view.GetPriorityFees()
+	earned := types.Coins{SCPTWei: big.NewInt(0), SPAYWei: priorityFees}
+	for idx := range fees {
+		if fees[idx].Address == block.Proposer {
+			fees[idx].Coins = fees[idx].Coins.Plus(earned)
+			view.SetPriorityFees(fees)
+			return
+		}
+	}
+	view.SetPriorityFees(append(fees, types.TxOutput{Address: block.Proposer, Coins: earned}))
+}
+
+// txGasUsed returns the gas used by a transaction from its execution result
+func txGasUsed(res result.Result) uint64 {
+	if gasUsed, ok := res.Info["gasUsed"].(uint64); ok {
+		return gasUsed
+	}
+	return 0
+}
+
+// txPriorityFee returns the priority fee paid by a transaction from its execution result
+func txPriorityFee(res result.Result) *big.Int {
+	if priorityFee, ok := res.Info["priorityFee"].(*big.Int); ok {
+		return priorityFee
+	}
+	return big.NewInt(0)
+}
+
+// isValidatorStakeTx returns whether the transaction updates the stakes of the validators
+func isValidatorStakeTx(tx types.Tx) bool {
+	if dtx, ok := tx.(*types.DepositStakeTx); ok && dtx.Purpose == core.StakeForValidator {
+		return true
+	} else if wtx, ok := tx.(*types.WithdrawStakeTx); ok && wtx.Purpose == core.StakeForValidator {
+		return true
+	}
+	return false
+}
+
 // CheckTx() should skip all the transactions that can only be initiated by the validators
 // i.e., if a regular user submits a coinbaseTx or slashTx, it should be skipped so it will not
 // get into the mempool
@@ -794,6 +939,7 @@ func (ledger *Ledger) addCoinbaseTx(view *st.StoreView, proposer *core.Validator
 	} else { // for compatibility with lower versions (e.g. blockHeight < common.HeightEnableValidatorReward)
 		accountRewardMap = exec.CalculateReward(ledger, view, validatorSet, nil, nil, nil, nil)
 	}
+	exec.AddPriorityFees(view, accountRewardMap)
 
 	coinbaseTxOutputs := []types.TxOutput{}
 	for accountAddressStr, accountReward := range accountRewardMap {
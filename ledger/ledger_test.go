@@ -66,17 +66,23 @@ func TestLedgerProposerBlockTxs(t *testing.T) {
 	startTime := time.Now()
 
 	// Propose block transactions
-	_, blockTxs, res := ledger.ProposeBlockTxs(nil, true)
+	block := &core.Block{
+		BlockHeader: &core.BlockHeader{
+			ChainID: chainID,
+			Height:  ledger.state.Height() + 1,
+		},
+	}
+	_, blockTxs, res := ledger.ProposeBlockTxs(block, true)
 
 	endTime := time.Now()
 	elapsed := endTime.Sub(startTime)
 	log.Infof("Execution time for block proposal: %v", elapsed)
 
 	// Transaction counts sanity checks
-	expectedTotalNumTx := core.MaxNumRegularTxsPerBlock // since we passed nil to ProposeBlockTxs(), we don't have the CoinbaseTx in the block
+	expectedTotalNumTx := core.MaxNumRegularTxsPerBlock + 1 // the CoinbaseTx and the regular transactions
 	assert.Equal(expectedTotalNumTx, len(blockTxs))
 	assert.True(res.IsOK())
-	assert.Equal(numMempoolTxs-expectedTotalNumTx, mempool.Size())
+	assert.Equal(numMempoolTxs-core.MaxNumRegularTxsPerBlock, mempool.Size())
 
 	// Transaction sanity checks
 	var prevSendTx *types.SendTx
@@ -85,13 +91,10 @@ func TestLedgerProposerBlockTxs(t *testing.T) {
 		tx, err := types.TxFromBytes(rawTx)
 		assert.Nil(err)
 		switch tx.(type) {
-		// case *types.CoinbaseTx:
-		// 	assert.Equal(0, idx) // The first tx needs to be a coinbase transaction
-		// 	coinbaseTx := tx.(*types.CoinbaseTx)
-		// 	signBytes := coinbaseTx.SignBytes(chainID)
-		// 	ledger.consensus.PrivateKey().PublicKey().VerifySignature(signBytes, coinbaseTx.Proposer.Signature)
+		case *types.CoinbaseTx:
+			assert.Equal(0, idx) // The first tx needs to be a coinbase transaction
 		case *types.SendTx:
-			assert.True(idx >= 0)
+			assert.True(idx >= 1)
 			currSendTx := tx.(*types.SendTx)
 			if prevSendTx != nil {
 				// mempool should works like a priority queue, for the same type of tx (i.e. SendTx),
@@ -105,6 +108,222 @@ func TestLedgerProposerBlockTxs(t *testing.T) {
 	}
 }
 
+// loopCode jumps back to its start until the call runs out of gas, so each call uses up its gas limit
+var loopCode = common.Hex2Bytes("5b600056")
+
+func TestLedgerProposeBlockTxsWithGasLimit(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID, ledger, mempool := newTestLedger()
+	numInAccs := 8
+	_, accIns := prepareInitLedgerState(ledger, numInAccs)
+	contract := common.BigToAddress(big.NewInt(0x10000))
+	ledger.state.Delivered().SetCode(contract, counterCode)
+	ledger.state.Commit()
+
+	// Only five of the transactions fit in the block gas limit, those with the highest gas prices
+	txGasLimit := types.BlockGasLimit / 5
+	for idx := 0; idx < numInAccs; idx++ {
+		gasPrice := types.InitialBaseFee + uint64(idx)
+		scTxBytes := newRawSmartContractTx(chainID, 1, accIns[idx], contract, gasPrice, txGasLimit)
+		err := mempool.InsertTransaction(scTxBytes)
+		require.Nil(err, fmt.Sprintf("Mempool insertion error: %v", err))
+	}
+	assert.Equal(numInAccs, mempool.Size())
+
+	block := &core.Block{
+		BlockHeader: &core.BlockHeader{
+			ChainID: chainID,
+			Height:  common.HeightEnableBlockGasLimit,
+		},
+	}
+	_, blockTxs, res := ledger.ProposeBlockTxs(block, true)
+	require.True(res.IsOK(), res.Message)
+
+	scTxs := []*types.SmartContractTx{}
+	for _, rawTx := range blockTxs {
+		tx, err := types.TxFromBytes(rawTx)
+		require.Nil(err)
+		if scTx, ok := tx.(*types.SmartContractTx); ok {
+			scTxs = append(scTxs, scTx)
+		}
+	}
+	require.Equal(5, len(scTxs))
+	for idx, scTx := range scTxs {
+		assert.Equal(accIns[numInAccs-1-idx].Address, scTx.From.Address)
+	}
+
+	// The transactions skipped for the gas limit are kept for the next blocks
+	assert.Equal(numInAccs-5, mempool.Size())
+}
+
+func TestLedgerProposeBlockTxsOrdersByFeePerGas(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID, ledger, mempool := newTestLedgerWithInitHeight(common.HeightEnableDynamicFee)
+	accOut, accIns := prepareInitLedgerState(ledger, 4)
+	contract := common.BigToAddress(big.NewInt(0x10000))
+	ledger.state.Delivered().SetCode(contract, counterCode)
+	ledger.state.Commit()
+
+	baseFee := new(big.Int).SetUint64(types.InitialBaseFee)
+	feePerGas := func(numerator, denominator int64) *big.Int {
+		price := new(big.Int).Mul(baseFee, big.NewInt(numerator))
+		return price.Div(price, big.NewInt(denominator))
+	}
+	newRawSendTxWithFeePerGas := func(accIn types.PrivAccount, price *big.Int) common.Bytes {
+		fee := new(big.Int).Mul(price, new(big.Int).SetUint64(types.GasRegularTxJune2021)) // two accounts affected
+		sendTx := &types.SendTx{
+			Fee: types.Coins{SCPTWei: big.NewInt(0), SPAYWei: fee},
+			Inputs: []types.TxInput{{
+				Sequence: 1,
+				Address:  accIn.Address,
+				Coins:    types.Coins{SCPTWei: big.NewInt(15), SPAYWei: fee},
+			}},
+			Outputs: []types.TxOutput{{
+				Address: accOut.Address,
+				Coins:   types.NewCoins(15, 0),
+			}},
+		}
+		sendTx.SetSignature(accIn.Address, accIn.Sign(sendTx.SignBytes(chainID)))
+		raw, err := types.TxToBytes(sendTx)
+		require.Nil(err)
+		return raw
+	}
+
+	// Inserted under the initial base fee, the dynamic fee transaction pays 1.1x the base fee per gas
+	rawTxs := []common.Bytes{
+		newRawSmartContractTxV2(chainID, 1, accIns[0], contract, feePerGas(4, 1), feePerGas(1, 10), 100000),
+		newRawSendTxWithFeePerGas(accIns[1], feePerGas(3, 2)),
+		newRawSmartContractTx(chainID, 1, accIns[2], contract, feePerGas(2, 1).Uint64(), 100000),
+		newRawSendTxWithFeePerGas(accIns[3], feePerGas(1, 8)),
+	}
+	for _, rawTx := range rawTxs {
+		require.Nil(mempool.InsertTransaction(rawTx))
+	}
+
+	// The base fee doubles before the block is proposed, the dynamic fee transaction then pays
+	// 2.1x the initial base fee and the legacy one exactly the base fee
+	ledger.state.Delivered().SetBaseFee(feePerGas(2, 1))
+	ledger.state.Commit()
+
+	_, blockTxs, res := ledger.ProposeBlockTxs(nil, true) // nil skips adding the CoinbaseTx
+	require.True(res.IsOK(), res.Message)
+	assert.Equal([]common.Bytes{rawTxs[0], rawTxs[2], rawTxs[1], rawTxs[3]}, blockTxs)
+}
+
+func TestLedgerPriorityFeesPaidByCoinbase(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID, ledger, mempool := newTestLedgerWithInitHeight(common.HeightEnableDynamicFee)
+	_, accIns := prepareInitLedgerState(ledger, 1)
+	contract := common.BigToAddress(big.NewInt(0x10000))
+	ledger.state.Delivered().SetCode(contract, counterCode)
+	ledger.state.Commit()
+
+	baseFee := new(big.Int).SetUint64(types.InitialBaseFee)
+	maxPriorityFee := new(big.Int).Div(baseFee, big.NewInt(10))
+	rawTx := newRawSmartContractTxV2(chainID, 1, accIns[0], contract, new(big.Int).Mul(baseFee, big.NewInt(2)), maxPriorityFee, 100000)
+	require.Nil(mempool.InsertTransaction(rawTx))
+
+	proposer := ledger.consensus.PrivateKey().PublicKey().Address()
+	proposerBalance := ledger.state.Delivered().GetAccount(proposer).Balance
+
+	// The priority fee is recorded at the end of the block, without touching the proposer account
+	block1 := &core.Block{BlockHeader: &core.BlockHeader{
+		ChainID:  chainID,
+		Height:   ledger.state.Height() + 1,
+		Parent:   ledger.chain.Root().Hash(),
+		Proposer: proposer,
+	}}
+	stateRoot, blockTxs, res := ledger.ProposeBlockTxs(block1, true)
+	require.True(res.IsOK(), res.Message)
+	require.Equal(2, len(blockTxs)) // the CoinbaseTx and the smart contract transaction
+	block1.StateHash = stateRoot
+	block1.Txs = blockTxs
+	res = ledger.ApplyBlockTxs(block1)
+	require.True(res.IsOK(), res.Message)
+
+	fees := ledger.state.Delivered().GetPriorityFees()
+	require.Equal(1, len(fees))
+	assert.Equal(proposer, fees[0].Address)
+	assert.True(fees[0].Coins.SPAYWei.Sign() > 0)
+	assert.Equal(int64(0), fees[0].Coins.SPAYWei.Int64()%maxPriorityFee.Int64())
+	assert.Equal(proposerBalance, ledger.state.Delivered().GetAccount(proposer).Balance)
+
+	// The CoinbaseTx of the next block pays the priority fee
+	block2 := &core.Block{BlockHeader: &core.BlockHeader{
+		ChainID:  chainID,
+		Height:   ledger.state.Height() + 1,
+		Parent:   block1.Hash(),
+		Proposer: proposer,
+	}}
+	_, blockTxs, res = ledger.ProposeBlockTxs(block2, true)
+	require.True(res.IsOK(), res.Message)
+	require.Equal(1, len(blockTxs))
+	tx, err := types.TxFromBytes(blockTxs[0])
+	require.Nil(err)
+	coinbaseTx, ok := tx.(*types.CoinbaseTx)
+	require.True(ok)
+	paid := false
+	for _, output := range coinbaseTx.Outputs {
+		if output.Address == proposer {
+			paid = output.Coins.IsGTE(fees[0].Coins)
+		}
+	}
+	assert.True(paid)
+	assert.Equal(0, len(ledger.state.Checked().GetPriorityFees()))
+
+	// A CoinbaseTx not paying the whole priority fee is rejected
+	for idx := range coinbaseTx.Outputs {
+		if coinbaseTx.Outputs[idx].Address == proposer {
+			coinbaseTx.Outputs[idx].Coins = fees[0].Coins.Minus(types.NewCoins(0, 1))
+		}
+	}
+	signature, err := ledger.signTransaction(coinbaseTx)
+	require.Nil(err)
+	coinbaseTx.SetSignature(coinbaseTx.Proposer.Address, signature)
+	ledger.currentBlock = block2
+	_, res = ledger.executor.ScreenTx(coinbaseTx)
+	ledger.currentBlock = nil
+	assert.True(res.IsError())
+	assert.Contains(res.Message, "Priority fees")
+}
+
+func TestLedgerApplyBlockTxsWithGasLimit(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	height := common.HeightEnableBlockGasLimit
+	txGasLimit := types.BlockGasLimit / 5
+	env := newParallelExecTestEnv(6, 1)
+	blockRawTxs := []common.Bytes{}
+	for idx := 0; idx < 6; idx++ {
+		blockRawTxs = append(blockRawTxs, env.newRawSmartContractTxWithGasLimit(t, idx, 0, types.InitialBaseFee, txGasLimit))
+	}
+
+	// The first five transactions use up the block gas
+	ledger := env.newTestLedgerAtHeight(t, height)
+	ledger.state.Delivered().SetCode(env.contracts[0], loopCode)
+	_, blockGasUsed, _, res := ledger.applyTxsSequentially(height, blockRawTxs[:5])
+	require.True(res.IsOK(), res.Message)
+	assert.Equal(types.BlockGasLimit, blockGasUsed)
+
+	// A block with one more transaction is rejected
+	ledger = env.newTestLedgerAtHeight(t, height)
+	ledger.state.Delivered().SetCode(env.contracts[0], loopCode)
+	block := &core.Block{BlockHeader: &core.BlockHeader{
+		ChainID: parallelExecTestChainID,
+		Height:  height,
+		Parent:  ledger.chain.Root().Hash(),
+	}, Txs: blockRawTxs}
+	res = ledger.ApplyBlockTxs(block)
+	assert.Equal(result.CodeBlockGasLimitExceeded, res.Code, res.Message)
+}
+
 func TestLedgerApplyBlockTxs(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
@@ -126,9 +345,9 @@ func TestLedgerApplyBlockTxs(t *testing.T) {
 		//coinbaseTxBytes,
 		sendTx1Bytes, sendTx2Bytes, sendTx3Bytes, sendTx4Bytes, sendTx5Bytes,
 	}
-	expectedStateRoot := common.HexToHash("0d7bff2377e3638b82b09c21b7d0636ed593d2225164cb9b67f7296432194c58")
+	expectedStateRoot := common.HexToHash("f38575abd3ee7071d54e24925f8ac03be3d7c9c74fc97b6fc26860d3deeb7ac0")
 
-	block := &core.Block{BlockHeader: &core.BlockHeader{StateHash: expectedStateRoot}, Txs: blockRawTxs}
+	block := &core.Block{BlockHeader: &core.BlockHeader{Parent: ledger.chain.Root().Hash(), StateHash: expectedStateRoot}, Txs: blockRawTxs}
 	res := ledger.ApplyBlockTxs(block)
 	require.True(res.IsOK(), res.Message)
 
@@ -332,6 +551,7 @@ func TestValidatorStakeUpdate(t *testing.T) {
 	}
 	expectedStateHash, _, res := es.consensus.GetLedger().ProposeBlockTxs(nil, true) // nil skips adding the CoinbaseTx, but it is OK for our test
 	blockX := &core.Block{BlockHeader: &core.BlockHeader{
+		Parent:    b6.Hash(),
 		Height:    es.state.Height() + 1,
 		StateHash: expectedStateHash,
 	}, Txs: []common.Bytes{}}
@@ -350,6 +570,7 @@ func TestValidatorStakeUpdate(t *testing.T) {
 	}
 	expectedStateHash, _, res = es.consensus.GetLedger().ProposeBlockTxs(nil, true) // nil skips adding the CoinbaseTx, but it is OK for our test
 	blockY := &core.Block{BlockHeader: &core.BlockHeader{
+		Parent:    b6.Hash(),
 		Height:    es.state.Height() + 1,
 		StateHash: expectedStateHash,
 	}, Txs: []common.Bytes{}}
@@ -643,6 +864,7 @@ func TestLightningStakeUpdate(t *testing.T) {
 	}
 	expectedStateHash, _, res := es.consensus.GetLedger().ProposeBlockTxs(nil, true) // nil skips adding the CoinbaseTx, but it is OK for our test
 	blockX := &core.Block{BlockHeader: &core.BlockHeader{
+		Parent:    b13.Hash(),
 		Height:    es.state.Height() + 1,
 		StateHash: expectedStateHash,
 	}, Txs: []common.Bytes{}}
@@ -661,6 +883,7 @@ func TestLightningStakeUpdate(t *testing.T) {
 	}
 	expectedStateHash, _, res = es.consensus.GetLedger().ProposeBlockTxs(nil, true) // nil skips adding the CoinbaseTx, but it is OK for our test
 	blockY := &core.Block{BlockHeader: &core.BlockHeader{
+		Parent:    b13.Hash(),
 		Height:    es.state.Height() + 1,
 		StateHash: expectedStateHash,
 	}, Txs: []common.Bytes{}}
@@ -0,0 +1,144 @@
+package ledger
+
+import (
+	"encoding/hex"
+	"math/big"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/common/result"
+	st "github.com/scripttoken/script/ledger/state"
+	"github.com/scripttoken/script/ledger/types"
+)
+
+// speculativeTx is a block transaction together with the result of its speculative execution
+// on a speculative copy of the delivered view taken at the beginning of the block
+type speculativeTx struct {
+	tx      types.Tx
+	view    *st.StoreView // nil if the transaction is not executed speculatively
+	res     result.Result
+	aborted bool // the speculative execution panicked, e.g. on a state it would never see in block order
+}
+
+// applyTxsOptimistically executes the block transactions concurrently, each on its own speculative
+// copy of the delivered view, while recording the keys they access. The speculative copies never
+// write to the database, so the state is only committed through the delivered view: in the block
+// order, if none of the keys a transaction accessed got written to the delivered view since the
+// beginning of the block, its writes are applied to the delivered view, otherwise the transaction
+// is re-executed on the delivered view. The resulting state is thus exactly the one produced by
+// applyTxsSequentially.
+func (ledger *Ledger) applyTxsOptimistically(view *st.StoreView, blockHeight uint64, blockRawTxs []common.Bytes) (hasValidatorUpdate bool, blockGasUsed uint64, priorityFees *big.Int, res result.Result) {
+	start := time.Now()
+	priorityFees = big.NewInt(0)
+	stxs := make([]*speculativeTx, len(blockRawTxs))
+	for idx, rawTx := range blockRawTxs {
+		tx, err := types.TxFromBytes(rawTx)
+		if err != nil {
+			return false, 0, nil, result.Error("Failed to parse transaction: %v", hex.EncodeToString(rawTx))
+		}
+		if isValidatorStakeTx(tx) {
+			hasValidatorUpdate = true
+		}
+		stxs[idx] = &speculativeTx{tx: tx}
+		if !isSpeculativeTx(tx) {
+			continue
+		}
+		txView, err := view.SpeculativeCopy()
+		if err != nil {
+			return false, 0, nil, result.Error("Failed to copy the delivered view: %v", err)
+		}
+		txView.SetAccessTracker(st.NewAccessTracker())
+		stxs[idx].view = txView
+	}
+	ledger.executeSpeculatively(stxs)
+	speculationTime := time.Since(start)
+
+	// Tracks the keys written to the delivered view since the beginning of the block
+	written := st.NewAccessTracker()
+	view.SetAccessTracker(written)
+	defer view.SetAccessTracker(nil)
+
+	start = time.Now()
+	numReexecutedTxs := 0
+	blockGasLimit := types.GetBlockGasLimit(blockHeight)
+	for _, stx := range stxs {
+		if res := checkBlockGas(stx.tx, blockGasUsed, blockGasLimit); res.IsError() {
+			return false, 0, nil, res
+		}
+		var res result.Result
+		applied := false
+		if stx.view != nil && !stx.aborted && !stx.view.GetAccessTracker().DependsOn(written) {
+			res = stx.res
+			applied = res.IsError() || view.ApplyWrites(stx.view)
+		}
+		if !applied {
+			if stx.view != nil {
+				numReexecutedTxs++
+			}
+			_, res = ledger.executor.ExecuteTx(stx.tx)
+		}
+		if res.IsError() {
+			return false, 0, nil, res
+		}
+		blockGasUsed += txGasUsed(res)
+		priorityFees.Add(priorityFees, txPriorityFee(res))
+	}
+
+	logger.Debugf("ApplyBlockTxs: Finish applying block transactions optimistically, block.height=%v, numTxs=%v, numReexecutedTxs=%v, speculationTime=%v, commitTime=%v",
+		blockHeight, len(stxs), numReexecutedTxs, speculationTime, time.Since(start))
+
+	return hasValidatorUpdate, blockGasUsed, priorityFees, result.OK
+}
+
+// executeSpeculatively executes the speculative transactions on their own views with a pool of workers
+func (ledger *Ledger) executeSpeculatively(stxs []*speculativeTx) {
+	numWorkers := viper.GetInt(common.CfgLedgerParallelTxWorkers)
+	if numWorkers <= 0 {
+		numWorkers = runtime.NumCPU()
+	}
+
+	queue := make(chan *speculativeTx, len(stxs))
+	for _, stx := range stxs {
+		if stx.view != nil {
+			queue <- stx
+		}
+	}
+	close(queue)
+
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for stx := range queue {
+				ledger.executeSpeculativeTx(stx)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (ledger *Ledger) executeSpeculativeTx(stx *speculativeTx) {
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Debugf("Speculative execution of the transaction aborted: %v", err)
+			stx.aborted = true
+		}
+	}()
+	_, stx.res = ledger.executor.ExecuteTxOnView(stx.tx, stx.view)
+}
+
+// isSpeculativeTx returns whether the transaction can be executed speculatively. The coinbase
+// transaction depends on the in-memory state of the delivered view besides its keys.
+func isSpeculativeTx(tx types.Tx) bool {
+	switch tx.(type) {
+	case *types.CoinbaseTx:
+		return false
+	default:
+		return true
+	}
+}
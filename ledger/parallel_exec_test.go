@@ -0,0 +1,309 @@
+package ledger
+
+import (
+	"math/big"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/scripttoken/script/blockchain"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/crypto"
+	exec "github.com/scripttoken/script/ledger/execution"
+	st "github.com/scripttoken/script/ledger/state"
+	"github.com/scripttoken/script/ledger/types"
+	"github.com/scripttoken/script/store/database/backend"
+	"github.com/scripttoken/script/store/kvstore"
+)
+
+const parallelExecTestChainID = "test_chain_id"
+
+// counterCode increments the storage slot 0 each time it is called
+var counterCode = common.Hex2Bytes("60005460010160005500")
+
+// counterInitCode sets the storage slot 0 to 1 and deploys an empty contract
+var counterInitCode = common.Hex2Bytes("600160005500")
+
+// parallelExecTestEnv holds the accounts and contracts shared by the blocks of the differential test
+type parallelExecTestEnv struct {
+	accs      []types.PrivAccount
+	sequences []uint64
+	contracts []common.Address
+}
+
+func newParallelExecTestEnv(numAccs, numContracts int) *parallelExecTestEnv {
+	env := &parallelExecTestEnv{}
+	for i := 0; i < numAccs; i++ {
+		secret := "parallel_secret_" + strconv.FormatInt(int64(i), 16)
+		env.accs = append(env.accs, types.MakeAccWithInitBalance(secret, types.NewCoins(1e18, 1e18)))
+		env.sequences = append(env.sequences, 0)
+	}
+	for i := 0; i < numContracts; i++ {
+		env.contracts = append(env.contracts, common.BigToAddress(big.NewInt(int64(0x10000+i))))
+	}
+	return env
+}
+
+// newTestLedger creates a ledger with the initial state of the environment, on top of which
+// the block transactions are applied
+func (env *parallelExecTestEnv) newTestLedger(t *testing.T) *Ledger {
+	return env.newTestLedgerAtHeight(t, 2)
+}
+
+// newTestLedgerAtHeight is the same as newTestLedger, with the current block at the given height
+func (env *parallelExecTestEnv) newTestLedgerAtHeight(t *testing.T, height uint64) *Ledger {
+	db := backend.NewMemDatabase()
+	root := &core.Block{
+		BlockHeader: &core.BlockHeader{
+			ChainID: parallelExecTestChainID,
+			Height:  height - 1,
+		},
+	}
+	chain := blockchain.NewChain(parallelExecTestChainID, kvstore.NewKVStore(db), root)
+	consensus := exec.NewTestConsensusEngine("proposer")
+	valMgr := newTesetValidatorManager(consensus)
+	ledger := NewLedger(parallelExecTestChainID, db, nil, chain, consensus, valMgr, nil)
+	require.True(t, ledger.ResetState(root).IsOK())
+
+	view := ledger.state.Delivered()
+	for _, acc := range env.accs {
+		account := types.NewAccount(acc.Address)
+		account.Balance = acc.Balance
+		view.SetAccount(account.Address, account)
+	}
+	for _, contract := range env.contracts {
+		view.SetCode(contract, counterCode)
+	}
+
+	ledger.currentBlock = &core.Block{
+		BlockHeader: &core.BlockHeader{
+			ChainID:  parallelExecTestChainID,
+			Height:   height,
+			Parent:   root.Hash(),
+			Proposer: consensus.PrivateKey().PublicKey().Address(),
+		},
+	}
+	return ledger
+}
+
+func (env *parallelExecTestEnv) newRawSendTx(t *testing.T, from, to int) common.Bytes {
+	env.sequences[from]++
+	txFee := int64(types.MinimumTransactionFeeSPAYWeiJune2021)
+	sendTx := &types.SendTx{
+		Fee: types.NewCoins(0, txFee),
+		Inputs: []types.TxInput{
+			{
+				Sequence: env.sequences[from],
+				Address:  env.accs[from].Address,
+				Coins:    types.NewCoins(15, txFee+1000),
+			},
+		},
+		Outputs: []types.TxOutput{
+			{
+				Address: env.accs[to].Address,
+				Coins:   types.NewCoins(15, 1000),
+			},
+		},
+	}
+	sig, err := env.accs[from].PrivKey.Sign(sendTx.SignBytes(parallelExecTestChainID))
+	require.Nil(t, err)
+	sendTx.SetSignature(env.accs[from].Address, sig)
+
+	raw, err := types.TxToBytes(sendTx)
+	require.Nil(t, err)
+	return raw
+}
+
+// newRawSmartContractTx calls the counter contract, or deploys a new contract if contract < 0. A gas
+// price above the base fee pays a priority fee to the proposer.
+func (env *parallelExecTestEnv) newRawSmartContractTx(t *testing.T, from, contract int, gasPrice uint64) common.Bytes {
+	return env.newRawSmartContractTxWithGasLimit(t, from, contract, gasPrice, 100000)
+}
+
+func (env *parallelExecTestEnv) newRawSmartContractTxWithGasLimit(t *testing.T, from, contract int, gasPrice, gasLimit uint64) common.Bytes {
+	env.sequences[from]++
+	scTx := &types.SmartContractTx{
+		From: types.TxInput{
+			Address:  env.accs[from].Address,
+			Coins:    types.NewCoins(0, 10),
+			Sequence: env.sequences[from],
+		},
+		GasLimit: gasLimit,
+		GasPrice: new(big.Int).SetUint64(gasPrice),
+	}
+	if contract >= 0 {
+		scTx.To = types.TxOutput{Address: env.contracts[contract]}
+	} else {
+		scTx.Data = counterInitCode
+	}
+	scTx.From.Signature = env.accs[from].Sign(scTx.SignBytes(parallelExecTestChainID))
+
+	raw, err := types.TxToBytes(scTx)
+	require.Nil(t, err)
+	return raw
+}
+
+// newRandomBlockTxs generates a mix of independent and conflicting transactions
+func (env *parallelExecTestEnv) newRandomBlockTxs(t *testing.T, rnd *rand.Rand, numTxs int) []common.Bytes {
+	baseFee := types.InitialBaseFee
+	rawTxs := []common.Bytes{}
+	for i := 0; i < numTxs; i++ {
+		from := rnd.Intn(len(env.accs))
+		switch rnd.Intn(5) {
+		case 0, 1:
+			to := (from + 1 + rnd.Intn(len(env.accs)-1)) % len(env.accs)
+			rawTxs = append(rawTxs, env.newRawSendTx(t, from, to))
+		case 2, 3:
+			contract := rnd.Intn(len(env.contracts))
+			rawTxs = append(rawTxs, env.newRawSmartContractTx(t, from, contract, baseFee))
+		default:
+			if rnd.Intn(2) == 0 {
+				rawTxs = append(rawTxs, env.newRawSmartContractTx(t, from, -1, baseFee))
+			} else {
+				contract := rnd.Intn(len(env.contracts))
+				rawTxs = append(rawTxs, env.newRawSmartContractTx(t, from, contract, 2*baseFee))
+			}
+		}
+	}
+	return rawTxs
+}
+
+func TestApplyTxsOptimisticallyMatchesSequentialExecution(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	for seed := int64(1); seed <= 8; seed++ {
+		rnd := rand.New(rand.NewSource(seed))
+		env := newParallelExecTestEnv(8+rnd.Intn(56), 1+rnd.Intn(8))
+		seqLedger := env.newTestLedger(t)
+		parLedger := env.newTestLedger(t)
+		require.Equal(seqLedger.state.Delivered().Hash(), parLedger.state.Delivered().Hash())
+
+		blockRawTxs := env.newRandomBlockTxs(t, rnd, 10+rnd.Intn(40))
+
+		seqValUpdate, seqGasUsed, seqFees, seqRes := seqLedger.applyTxsSequentially(2, blockRawTxs)
+		require.True(seqRes.IsOK(), seqRes.Message)
+		parValUpdate, parGasUsed, parFees, parRes := parLedger.applyTxsOptimistically(parLedger.state.Delivered(), 2, blockRawTxs)
+		require.True(parRes.IsOK(), parRes.Message)
+
+		assert.Equal(seqValUpdate, parValUpdate, "seed %v", seed)
+		assert.Equal(seqGasUsed, parGasUsed, "seed %v", seed)
+		assert.Equal(0, seqFees.Cmp(parFees), "seed %v", seed)
+		assert.Equal(seqLedger.state.Delivered().Hash(), parLedger.state.Delivered().Hash(), "seed %v", seed)
+		for _, acc := range env.accs {
+			assert.Equal(seqLedger.state.Delivered().GetAccount(acc.Address), parLedger.state.Delivered().GetAccount(acc.Address))
+		}
+		for _, contract := range env.contracts {
+			assert.Equal(seqLedger.state.Delivered().GetState(contract, common.Hash{}),
+				parLedger.state.Delivered().GetState(contract, common.Hash{}))
+		}
+	}
+}
+
+func TestApplyTxsOptimisticallyConflictingTxs(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	env := newParallelExecTestEnv(3, 1)
+	seqLedger := env.newTestLedger(t)
+	parLedger := env.newTestLedger(t)
+
+	// Every transaction depends on the previous one, through the sender sequence or the contract storage
+	blockRawTxs := []common.Bytes{
+		env.newRawSendTx(t, 0, 1),
+		env.newRawSendTx(t, 0, 2),
+		env.newRawSmartContractTx(t, 1, 0, types.InitialBaseFee),
+		env.newRawSmartContractTx(t, 2, 0, types.InitialBaseFee),
+		env.newRawSmartContractTx(t, 2, 0, types.InitialBaseFee),
+	}
+
+	_, seqGasUsed, _, seqRes := seqLedger.applyTxsSequentially(2, blockRawTxs)
+	require.True(seqRes.IsOK(), seqRes.Message)
+	_, parGasUsed, _, parRes := parLedger.applyTxsOptimistically(parLedger.state.Delivered(), 2, blockRawTxs)
+	require.True(parRes.IsOK(), parRes.Message)
+
+	assert.Equal(seqGasUsed, parGasUsed)
+	assert.Equal(seqLedger.state.Delivered().Hash(), parLedger.state.Delivered().Hash())
+	assert.Equal(common.BigToHash(big.NewInt(3)), parLedger.state.Delivered().GetState(env.contracts[0], common.Hash{}))
+	assert.Equal(uint64(2), parLedger.state.Delivered().GetAccount(env.accs[0].Address).Sequence)
+}
+
+func TestApplyTxsOptimisticallyInvalidTx(t *testing.T) {
+	assert := assert.New(t)
+
+	env := newParallelExecTestEnv(2, 1)
+	seqLedger := env.newTestLedger(t)
+	parLedger := env.newTestLedger(t)
+
+	blockRawTxs := []common.Bytes{
+		env.newRawSendTx(t, 0, 1),
+		env.newRawSmartContractTx(t, 1, 0, types.InitialBaseFee),
+	}
+	env.sequences[0]++ // skip a sequence number
+	blockRawTxs = append(blockRawTxs, env.newRawSendTx(t, 0, 1))
+
+	_, _, _, seqRes := seqLedger.applyTxsSequentially(2, blockRawTxs)
+	assert.True(seqRes.IsError())
+	_, _, _, parRes := parLedger.applyTxsOptimistically(parLedger.state.Delivered(), 2, blockRawTxs)
+	assert.True(parRes.IsError())
+	assert.Equal(seqRes.Code, parRes.Code)
+}
+
+func TestExecuteSpeculativelyLeavesDatabaseUntouched(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	env := newParallelExecTestEnv(4, 2)
+	ledger := env.newTestLedger(t)
+	view := ledger.state.Delivered()
+	db, ok := view.GetDB().(*backend.MemDatabase)
+	require.True(ok)
+
+	snapshot := func() map[string]string {
+		entries := make(map[string]string)
+		for _, key := range db.Keys() {
+			value, err := db.Get(key)
+			require.Nil(err)
+			ref, _ := db.CountReference(key) // the keys without references have no count
+			entries[string(key)] = string(value) + "/" + strconv.Itoa(ref)
+		}
+		return entries
+	}
+	before := snapshot()
+
+	blockRawTxs := []common.Bytes{
+		env.newRawSmartContractTx(t, 0, 0, types.InitialBaseFee),
+		env.newRawSmartContractTx(t, 1, 1, types.InitialBaseFee),
+		env.newRawSendTx(t, 2, 3),
+	}
+	stxs := []*speculativeTx{}
+	for _, rawTx := range blockRawTxs {
+		tx, err := types.TxFromBytes(rawTx)
+		require.Nil(err)
+		txView, err := view.SpeculativeCopy()
+		require.Nil(err)
+		txView.SetAccessTracker(st.NewAccessTracker())
+		stxs = append(stxs, &speculativeTx{tx: tx, view: txView})
+	}
+	ledger.executeSpeculatively(stxs)
+	for _, stx := range stxs {
+		require.False(stx.aborted)
+		require.True(stx.res.IsOK(), stx.res.Message)
+	}
+	assert.Equal(before, snapshot())
+
+	// The writes and the receipts only reach the database through the delivered view
+	for _, stx := range stxs {
+		require.True(view.ApplyWrites(stx.view))
+	}
+	for _, contract := range env.contracts {
+		assert.Equal(common.BigToHash(big.NewInt(1)), view.GetState(contract, common.Hash{}))
+	}
+	for _, rawTx := range blockRawTxs[:2] {
+		_, ok := ledger.chain.FindTxReceiptByHash(ledger.currentBlock.Hash(), crypto.Keccak256Hash(rawTx))
+		assert.True(ok)
+	}
+}
@@ -0,0 +1,109 @@
+package state
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/scripttoken/script/common"
+)
+
+//
+// ------------------------- AccessTracker -------------------------
+//
+
+// AccessTracker records the keys read and written through a StoreView. The conflicts on the
+// account storage are detected on the account keys, since a storage access always reads the
+// account first, and a storage update always writes the account with its new storage root. The
+// written storage slots are still recorded, so that the writes can be replayed on another view.
+type AccessTracker struct {
+	reads    map[string]struct{}
+	writes   map[string]struct{}
+	prefixes []common.Bytes                              // prefixes of the traversed keys
+	storage  map[common.Address]map[common.Hash]struct{} // written storage slots
+}
+
+// StorageSlot identifies a storage slot of an account
+type StorageSlot struct {
+	Address common.Address
+	Key     common.Hash
+}
+
+// NewAccessTracker creates an instance of the AccessTracker
+func NewAccessTracker() *AccessTracker {
+	return &AccessTracker{
+		reads:    make(map[string]struct{}),
+		writes:   make(map[string]struct{}),
+		prefixes: []common.Bytes{},
+		storage:  make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+func (at *AccessTracker) recordRead(key common.Bytes) {
+	at.reads[string(key)] = struct{}{}
+}
+
+func (at *AccessTracker) recordWrite(key common.Bytes) {
+	at.writes[string(key)] = struct{}{}
+}
+
+func (at *AccessTracker) recordStorageWrite(addr common.Address, key common.Hash) {
+	slots, ok := at.storage[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		at.storage[addr] = slots
+	}
+	slots[key] = struct{}{}
+}
+
+func (at *AccessTracker) recordTraverse(prefix common.Bytes) {
+	at.prefixes = append(at.prefixes, common.CopyBytes(prefix))
+}
+
+// WrittenKeys returns the written keys in ascending order
+func (at *AccessTracker) WrittenKeys() []common.Bytes {
+	keys := make([]common.Bytes, 0, len(at.writes))
+	for key := range at.writes {
+		keys = append(keys, common.Bytes(key))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	})
+	return keys
+}
+
+// WrittenStorageSlots returns the written storage slots in ascending order
+func (at *AccessTracker) WrittenStorageSlots() []StorageSlot {
+	slots := []StorageSlot{}
+	for addr, keys := range at.storage {
+		for key := range keys {
+			slots = append(slots, StorageSlot{Address: addr, Key: key})
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		if c := bytes.Compare(slots[i].Address[:], slots[j].Address[:]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(slots[i].Key[:], slots[j].Key[:]) < 0
+	})
+	return slots
+}
+
+// DependsOn returns whether any key read or written through this tracker was written through
+// the other tracker, i.e. whether the accesses recorded by this tracker could observe a different
+// state had the writes of the other tracker happened first.
+func (at *AccessTracker) DependsOn(other *AccessTracker) bool {
+	for key := range other.writes {
+		if _, ok := at.reads[key]; ok {
+			return true
+		}
+		if _, ok := at.writes[key]; ok {
+			return true
+		}
+		for _, prefix := range at.prefixes {
+			if bytes.HasPrefix([]byte(key), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
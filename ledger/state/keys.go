@@ -31,6 +31,12 @@ func SplitRuleKey(resourceID string) common.Bytes {
 	return append(SplitRuleKeyPrefix(), resourceIDBytes[:]...)
 }
 
+// AssetKey constructs the state key for the given asset ID. The prefix must not start
+// with "ls/a", which the state exports and compaction take for an account.
+func AssetKey(assetID common.Address) common.Bytes {
+	return append(common.Bytes("ls/tk/"), assetID[:]...)
+}
+
 // CodeKey constructs the state key for the given code hash
 func CodeKey(codeHash common.Bytes) common.Bytes {
 	return append(common.Bytes("ls/ch/"), codeHash...)
@@ -98,3 +104,13 @@ func EliteEdgeNodeStakeReturnsKey(height uint64) common.Bytes {
 func EliteEdgeNodesTotalActiveStakeKey() common.Bytes {
 	return common.Bytes("ls/eentas")
 }
+
+// BaseFeeKey returns the key for the base fee of the smart contract gas of the next block
+func BaseFeeKey() common.Bytes {
+	return common.Bytes("ls/bf")
+}
+
+// PriorityFeesKey returns the key for the priority fees not paid to the proposers yet
+func PriorityFeesKey() common.Bytes {
+	return common.Bytes("ls/pf")
+}
@@ -33,7 +33,8 @@ type LedgerState struct {
 
 // NewLedgerState creates a new Leger State with given store.
 // NOTE: before using the LedgerState, we need to call LedgerState.ResetState() to set
-//       the proper height and stateRootHash
+//       the proper height and stateRootHash. The tagger can be nil if the state roots
+//       do not need to be tagged, e.g. in the tests
 func NewLedgerState(chainID string, db database.Database, tagger Tagger) *LedgerState {
 	s := &LedgerState{
 		chainID:  chainID,
@@ -137,7 +138,9 @@ func (s *LedgerState) Finalized() *StoreView {
 func (s *LedgerState) Commit() common.Hash {
 	hash := s.delivered.Save()
 	s.delivered.IncrementHeight()
-	s.dbTagger.Tag(s.delivered.height, hash)
+	if s.dbTagger != nil {
+		s.dbTagger.Tag(s.delivered.height, hash)
+	}
 
 	var err error
 	s.checked, err = s.delivered.Copy()
@@ -12,6 +12,7 @@ import (
 	"github.com/scripttoken/script/ledger/types"
 	"github.com/scripttoken/script/rlp"
 	"github.com/scripttoken/script/store/database"
+	"github.com/scripttoken/script/store/database/backend"
 	"github.com/scripttoken/script/store/treestore"
 )
 
@@ -30,6 +31,10 @@ type StoreView struct {
 	refund                      uint64                 // Gas refund during smart contract execution
 	logs                        []*types.Log           // Temporary store of events during smart contract execution
 	balanceChanges              []*types.BalanceChange // Temporary store of balance changes during smart contract execution
+
+	tracker     *AccessTracker // Records the accessed keys if not nil
+	speculative bool           // Whether the StoreView is a speculative copy, which cannot be saved
+	applied     []func()       // Functions to run once the writes of the speculative copy are applied
 }
 
 // NewStoreView creates an instance of the StoreView
@@ -63,6 +68,36 @@ func (sv *StoreView) Copy() (*StoreView, error) {
 	return copiedStoreView, nil
 }
 
+// SpeculativeCopy returns a copy of the StoreView for a speculative execution. The account
+// storage tries committed by the copy go to an in-memory overlay of the database, which is
+// discarded with the copy. The copy cannot be saved, its writes are only applied to another
+// view with ApplyWrites.
+func (sv *StoreView) SpeculativeCopy() (*StoreView, error) {
+	copiedStore, err := sv.store.CopyWithDB(backend.NewOverlayDatabase(sv.store.GetDB()))
+	if err != nil {
+		return nil, err
+	}
+	copiedStoreView := &StoreView{
+		height:       sv.height,
+		store:        copiedStore,
+		slashIntents: []types.SlashIntent{},
+		refund:       0,
+		speculative:  true,
+	}
+	return copiedStoreView, nil
+}
+
+// RunWhenApplied runs the given function, e.g. recording a transaction receipt, once the writes of
+// the StoreView are applied to another view with ApplyWrites if the StoreView is a speculative copy,
+// or right away otherwise.
+func (sv *StoreView) RunWhenApplied(fn func()) {
+	if sv.speculative {
+		sv.applied = append(sv.applied, fn)
+		return
+	}
+	fn()
+}
+
 // GetDB returns the underlying database.
 func (sv *StoreView) GetDB() database.Database {
 	return sv.store.GetDB()
@@ -85,6 +120,9 @@ func (sv *StoreView) IncrementHeight() {
 
 // Save saves the StoreView to the persistent storage, and return the root hash
 func (sv *StoreView) Save() common.Hash {
+	if sv.speculative {
+		log.Panicf("Cannot save a speculative StoreView")
+	}
 	rootHash, err := sv.store.Commit()
 
 	logger.Debugf("Commit to data store, height: %v, rootHash: %v", sv.height+1, rootHash.Hex())
@@ -95,8 +133,22 @@ func (sv *StoreView) Save() common.Hash {
 	return rootHash
 }
 
+// SetAccessTracker sets the tracker which records the keys accessed through the StoreView.
+// A nil tracker stops the tracking.
+func (sv *StoreView) SetAccessTracker(tracker *AccessTracker) {
+	sv.tracker = tracker
+}
+
+// GetAccessTracker returns the tracker of the accessed keys, nil if the accesses are not tracked
+func (sv *StoreView) GetAccessTracker() *AccessTracker {
+	return sv.tracker
+}
+
 // Get returns the value corresponding to the key
 func (sv *StoreView) Get(key common.Bytes) common.Bytes {
+	if sv.tracker != nil {
+		sv.tracker.recordRead(key)
+	}
 	value := sv.store.Get(key)
 	return value
 }
@@ -104,23 +156,103 @@ func (sv *StoreView) Get(key common.Bytes) common.Bytes {
 // Traverse traverses the trie and calls cb callback func on every key/value pair
 // with key having prefix
 func (sv *StoreView) Traverse(prefix common.Bytes, cb func(k, v common.Bytes) bool) bool {
+	if sv.tracker != nil {
+		sv.tracker.recordTraverse(prefix)
+	}
 	return sv.store.Traverse(prefix, cb)
 }
 
 func (sv *StoreView) ProveVCP(vcpKey []byte, vp *core.VCPProof) error {
+	if sv.tracker != nil {
+		sv.tracker.recordRead(vcpKey)
+	}
 	return sv.store.ProveVCP(vcpKey, vp)
 }
 
 // Delete removes the value corresponding to the key
 func (sv *StoreView) Delete(key common.Bytes) {
+	if sv.tracker != nil {
+		sv.tracker.recordWrite(key)
+	}
 	sv.store.Delete(key)
 }
 
 // Set returns the value corresponding to the key
 func (sv *StoreView) Set(key common.Bytes, value common.Bytes) {
+	if sv.tracker != nil {
+		sv.tracker.recordWrite(key)
+	}
 	sv.store.Set(key, value)
 }
 
+// ApplyWrites applies the writes recorded by the access tracker of the source view, typically a
+// speculative copy of this view. The written account storage slots are set again through this
+// view, which commits the account storage tries to its own database, and the written keys are
+// then copied from the source view, or deleted if they do not exist in the source view, before
+// the functions passed to RunWhenApplied of the source view are run. It returns false without applying anything if replaying the storage writes would not produce
+// the account storage roots of the source view, e.g. if the source view reset the storage of
+// an account.
+func (sv *StoreView) ApplyWrites(source *StoreView) bool {
+	tracker := source.GetAccessTracker()
+	slots := tracker.WrittenStorageSlots()
+	if !sv.replaysStorageWrites(source, slots) {
+		return false
+	}
+	for _, slot := range slots {
+		if len(source.store.Get(AccountKey(slot.Address))) == 0 {
+			continue // the account is deleted by the source view
+		}
+		sv.SetState(slot.Address, slot.Key, source.getState(slot.Address, slot.Key))
+	}
+	for _, key := range tracker.WrittenKeys() {
+		value := source.store.Get(key)
+		if len(value) == 0 {
+			sv.Delete(key)
+		} else {
+			sv.Set(key, value)
+		}
+	}
+	for _, fn := range source.applied {
+		fn()
+	}
+	return true
+}
+
+// replaysStorageWrites returns whether setting the given storage slots of this view to their
+// values in the source view produces the account storage roots of the source view. The tries
+// are only hashed, not committed.
+func (sv *StoreView) replaysStorageWrites(source *StoreView, slots []StorageSlot) bool {
+	trees := make(map[common.Address]*treestore.TreeStore)
+	for _, slot := range slots {
+		tree, ok := trees[slot.Address]
+		if !ok {
+			account := sv.GetOrCreateAccount(slot.Address)
+			tree = treestore.NewTreeStore(account.Root, sv.store.GetDB())
+			if tree == nil {
+				return false
+			}
+			trees[slot.Address] = tree
+		}
+		val := source.getState(slot.Address, slot.Key)
+		if (val == common.Hash{}) {
+			tree.TryDelete(slot.Key[:])
+			continue
+		}
+		v, _ := rlp.EncodeToBytes(bytes.TrimLeft(val[:], "\x00"))
+		tree.TryUpdate(slot.Key[:], v)
+	}
+	for addr, tree := range trees {
+		account := source.store.Get(AccountKey(addr))
+		if len(account) == 0 {
+			continue // the account is deleted, its storage does not matter
+		}
+		if source.GetOrCreateAccount(addr).Root != tree.Hash() {
+			return false
+		}
+	}
+	return true
+}
+
 // AddSlashIntent adds slashIntent
 func (sv *StoreView) AddSlashIntent(slashIntent types.SlashIntent) {
 	sv.slashIntents = append(sv.slashIntents, slashIntent)
@@ -208,6 +340,31 @@ func (sv *StoreView) DeleteAccount(addr common.Address) {
 	sv.Delete(AccountKey(addr))
 }
 
+// GetAsset gets the native token asset with the given ID, or nil if it doesn't exist.
+func (sv *StoreView) GetAsset(assetID common.Address) *types.Asset {
+	data := sv.Get(AssetKey(assetID))
+	if data == nil || len(data) == 0 {
+		return nil
+	}
+	asset := &types.Asset{}
+	err := types.FromBytes(data, asset)
+	if err != nil {
+		log.Panicf("Error reading asset %X error: %v",
+			data, err.Error())
+	}
+	return asset
+}
+
+// SetAsset sets a native token asset.
+func (sv *StoreView) SetAsset(asset *types.Asset) {
+	assetBytes, err := types.ToBytes(asset)
+	if err != nil {
+		log.Panicf("Error writing asset %v error: %v",
+			asset, err.Error())
+	}
+	sv.Set(AssetKey(asset.ID), assetBytes)
+}
+
 // SplitRuleExists checks if a split rule associated with the given resourceID already exists
 func (sv *StoreView) SplitRuleExists(resourceID string) bool {
 	return sv.GetSplitRule(resourceID) != nil
@@ -261,6 +418,9 @@ func (sv *StoreView) SetSplitRule(resourceID string, splitRule *types.SplitRule)
 // DeleteSplitRule deletes a split rule.
 func (sv *StoreView) DeleteSplitRule(resourceID string) bool {
 	key := SplitRuleKey(resourceID)
+	if sv.tracker != nil {
+		sv.tracker.recordWrite(key)
+	}
 	deleted := sv.store.Delete(key)
 	return deleted
 }
@@ -270,7 +430,7 @@ func (sv *StoreView) DeleteExpiredSplitRules(currentBlockHeight uint64) bool {
 	prefix := SplitRuleKeyPrefix()
 
 	expiredKeys := []common.Bytes{}
-	sv.store.Traverse(prefix, func(key, value common.Bytes) bool {
+	sv.Traverse(prefix, func(key, value common.Bytes) bool {
 		var splitRule types.SplitRule
 		err := types.FromBytes(value, &splitRule)
 		if err != nil {
@@ -285,6 +445,9 @@ func (sv *StoreView) DeleteExpiredSplitRules(currentBlockHeight uint64) bool {
 	})
 
 	for _, key := range expiredKeys {
+		if sv.tracker != nil {
+			sv.tracker.recordWrite(key)
+		}
 		deleted := sv.store.Delete(key)
 		if !deleted {
 			logger.Errorf("Failed to delete expired split rules")
@@ -418,6 +581,50 @@ func (sv *StoreView) SetTotalEENStake(amount *big.Int) {
 	sv.Set(EliteEdgeNodesTotalActiveStakeKey(), amount.Bytes())
 }
 
+// GetBaseFee retrieves the base fee of the smart contract gas of the block on top of the view
+func (sv *StoreView) GetBaseFee() *big.Int {
+	raw := sv.Get(BaseFeeKey())
+	if len(raw) == 0 {
+		return types.GetInitialBaseFee()
+	}
+	return new(big.Int).SetBytes(raw)
+}
+
+// SetBaseFee sets the base fee of the smart contract gas of the block on top of the view
+func (sv *StoreView) SetBaseFee(baseFee *big.Int) {
+	sv.Set(BaseFeeKey(), baseFee.Bytes())
+}
+
+// GetPriorityFees retrieves the priority fees earned by the proposers of the previous blocks,
+// which the next CoinbaseTx pays
+func (sv *StoreView) GetPriorityFees() []types.TxOutput {
+	data := sv.Get(PriorityFeesKey())
+	if len(data) == 0 {
+		return []types.TxOutput{}
+	}
+	fees := []types.TxOutput{}
+	err := types.FromBytes(data, &fees)
+	if err != nil {
+		log.Panicf("Error reading priority fees %X error: %v",
+			data, err.Error())
+	}
+	return fees
+}
+
+// SetPriorityFees sets the priority fees not paid to the proposers yet
+func (sv *StoreView) SetPriorityFees(fees []types.TxOutput) {
+	if len(fees) == 0 {
+		sv.Delete(PriorityFeesKey())
+		return
+	}
+	data, err := types.ToBytes(fees)
+	if err != nil {
+		log.Panicf("Error writing priority fees %v error: %v",
+			fees, err.Error())
+	}
+	sv.Set(PriorityFeesKey(), data)
+}
+
 func (sv *StoreView) GetStore() *treestore.TreeStore {
 	return sv.store
 }
@@ -669,6 +876,10 @@ func (sv *StoreView) getAccountStorage(account *types.Account) *treestore.TreeSt
 }
 
 func (sv *StoreView) GetState(addr common.Address, key common.Hash) common.Hash {
+	return sv.getState(addr, key)
+}
+
+func (sv *StoreView) getState(addr common.Address, key common.Hash) common.Hash {
 	account := sv.GetAccount(addr)
 	if account == nil {
 		return common.Hash{}
@@ -690,6 +901,9 @@ func (sv *StoreView) GetState(addr common.Address, key common.Hash) common.Hash
 }
 
 func (sv *StoreView) SetState(addr common.Address, key, val common.Hash) {
+	if sv.tracker != nil {
+		sv.tracker.recordStorageWrite(addr, key)
+	}
 	account := sv.GetAccount(addr)
 	if account == nil {
 		account = types.NewAccount(addr)
@@ -20,7 +20,6 @@ import (
 	mp "github.com/scripttoken/script/mempool"
 	"github.com/scripttoken/script/p2p"
 	p2psim "github.com/scripttoken/script/p2p/simulation"
-	"github.com/scripttoken/script/p2pl"
 	"github.com/scripttoken/script/store/database"
 	"github.com/scripttoken/script/store/database/backend"
 	"github.com/scripttoken/script/store/kvstore"
@@ -51,7 +50,7 @@ func newExecSim(chainID string, db database.Database, snapshot mockSnapshot, val
 	p2psimnet := p2psim.NewSimnetWithHandler(nil)
 	messenger := p2psimnet.AddEndpoint("peerID0")
 
-	dispatcher := dp.NewDispatcher(messenger, nil)
+	dispatcher := dp.NewDispatcher(messenger)
 
 	valMgr := consensus.NewFixedValidatorManager()
 	consensus := consensus.NewConsensusEngine(valPrivAcc.PrivKey, store, chain, dispatcher, valMgr)
@@ -64,6 +63,7 @@ func newExecSim(chainID string, db database.Database, snapshot mockSnapshot, val
 	ledgerState.ResetState(snapshot.block)
 
 	ledger := &Ledger{
+		chain:     chain,
 		consensus: consensus,
 		valMgr:    valMgr,
 		mempool:   mempool,
@@ -188,34 +188,39 @@ func genSimSnapshot(chainID string, db database.Database) (snapshot mockSnapshot
 }
 
 func newTestLedger() (chainID string, ledger *Ledger, mempool *mp.Mempool) {
+	return newTestLedgerWithInitHeight(1)
+}
+
+func newTestLedgerWithInitHeight(initHeight uint64) (chainID string, ledger *Ledger, mempool *mp.Mempool) {
 	chainID = "test_chain_id"
 	peerID := "peer0"
 	proposerSeed := "proposer"
 
+	initRootHash := common.Hash{}
+
+	initBlock := &core.Block{
+		BlockHeader: &core.BlockHeader{
+			ChainID:   chainID,
+			Height:    initHeight,
+			StateHash: initRootHash,
+		},
+	}
+
 	db := backend.NewMemDatabase()
-	chain := &blockchain.Chain{ChainID: chainID}
+	chain := blockchain.NewChain(chainID, kvstore.NewKVStore(db), initBlock)
 	consensus := exec.NewTestConsensusEngine(proposerSeed)
 	valMgr := newTesetValidatorManager(consensus)
 	p2psimnet := p2psim.NewSimnetWithHandler(nil)
 	messenger := p2psimnet.AddEndpoint(peerID)
-	mempool = newTestMempool(peerID, messenger, nil)
+	mempool = newTestMempool(peerID, messenger)
 	ledger = NewLedger(chainID, db, nil, chain, consensus, valMgr, mempool)
+	consensus.SetLedger(ledger)
 	mempool.SetLedger(ledger)
 
 	ctx := context.Background()
 	messenger.Start(ctx)
 	mempool.Start(ctx)
 
-	initHeight := uint64(1)
-	initRootHash := common.Hash{}
-
-	initBlock := &core.Block{
-		BlockHeader: &core.BlockHeader{
-			ChainID:   chainID,
-			Height:    initHeight,
-			StateHash: initRootHash,
-		},
-	}
 	//ledger.ResetState(initHeight, initRootHash)
 	ledger.ResetState(initBlock)
 
@@ -240,8 +245,8 @@ func newTesetValidatorManager(consensus core.ConsensusEngine) core.ValidatorMana
 	return valMgr
 }
 
-func newTestMempool(peerID string, messenger p2p.Network, messengerL p2pl.Network) *mp.Mempool {
-	dispatcher := dp.NewDispatcher(messenger, nil)
+func newTestMempool(peerID string, messenger p2p.Network) *mp.Mempool {
+	dispatcher := dp.NewDispatcher(messenger)
 	mempool := mp.CreateMempool(dispatcher, nil)
 	txMsgHandler := mp.CreateMempoolMessageHandler(mempool)
 	messenger.RegisterMessageHandler(txMsgHandler)
@@ -361,6 +366,47 @@ func newRawSendTx(chainID string, sequence int, addPubKey bool, accOut, accIn ty
 	return sendTxBytes
 }
 
+func newRawSmartContractTx(chainID string, sequence int, accFrom types.PrivAccount, contract common.Address, gasPrice, gasLimit uint64) common.Bytes {
+	smartContractTx := &types.SmartContractTx{
+		From: types.TxInput{
+			Address:  accFrom.Address,
+			Coins:    types.NewCoins(0, 0),
+			Sequence: uint64(sequence),
+		},
+		To:       types.TxOutput{Address: contract},
+		GasLimit: gasLimit,
+		GasPrice: new(big.Int).SetUint64(gasPrice),
+	}
+	smartContractTx.From.Signature = accFrom.Sign(smartContractTx.SignBytes(chainID))
+
+	smartContractTxBytes, err := types.TxToBytes(smartContractTx)
+	if err != nil {
+		panic(err)
+	}
+	return smartContractTxBytes
+}
+
+func newRawSmartContractTxV2(chainID string, sequence int, accFrom types.PrivAccount, contract common.Address, maxFeePerGas, maxPriorityFeePerGas *big.Int, gasLimit uint64) common.Bytes {
+	smartContractTx := &types.SmartContractTxV2{
+		From: types.TxInput{
+			Address:  accFrom.Address,
+			Coins:    types.NewCoins(0, 0),
+			Sequence: uint64(sequence),
+		},
+		To:                   types.TxOutput{Address: contract},
+		GasLimit:             gasLimit,
+		MaxFeePerGas:         maxFeePerGas,
+		MaxPriorityFeePerGas: maxPriorityFeePerGas,
+	}
+	smartContractTx.From.Signature = accFrom.Sign(smartContractTx.SignBytes(chainID))
+
+	smartContractTxBytes, err := types.TxToBytes(smartContractTx)
+	if err != nil {
+		panic(err)
+	}
+	return smartContractTxBytes
+}
+
 func getMinimumTxFee() int64 {
 	return int64(types.MinimumTransactionFeeSPAYWei)
 }
@@ -0,0 +1,94 @@
+package types
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/crypto"
+)
+
+// ** Asset: A native fungible token, held in the account balances next to Script and SPAY **
+//
+
+const (
+	// MaxAssetNameLength is the maximum length of the name of an asset
+	MaxAssetNameLength = 64
+
+	// MaxAssetSymbolLength is the maximum length of the symbol of an asset
+	MaxAssetSymbolLength = 12
+
+	// MaxAssetDecimals is the maximum number of decimals of an asset
+	MaxAssetDecimals = 18
+)
+
+// Asset describes a native token issued by a CreateAssetTx. The whole supply is credited to the issuer.
+type Asset struct {
+	ID       common.Address // ID of the asset, derived from the issuer address and the sequence of the CreateAssetTx
+	Issuer   common.Address // Address of the issuer
+	Name     string         // Name of the asset
+	Symbol   string         // Ticker symbol of the asset
+	Decimals uint8          // Number of decimals of the amounts displayed to the users
+	Supply   *big.Int       // Total supply, in the smallest unit of the asset
+}
+
+type AssetJSON struct {
+	ID       common.Address  `json:"id"`
+	Issuer   common.Address  `json:"issuer"`
+	Name     string          `json:"name"`
+	Symbol   string          `json:"symbol"`
+	Decimals uint8           `json:"decimals"`
+	Supply   *common.JSONBig `json:"supply"`
+}
+
+func NewAssetJSON(a Asset) AssetJSON {
+	return AssetJSON{
+		ID:       a.ID,
+		Issuer:   a.Issuer,
+		Name:     a.Name,
+		Symbol:   a.Symbol,
+		Decimals: a.Decimals,
+		Supply:   (*common.JSONBig)(a.Supply),
+	}
+}
+
+func (a AssetJSON) Asset() Asset {
+	return Asset{
+		ID:       a.ID,
+		Issuer:   a.Issuer,
+		Name:     a.Name,
+		Symbol:   a.Symbol,
+		Decimals: a.Decimals,
+		Supply:   (*big.Int)(a.Supply),
+	}
+}
+
+func (a Asset) MarshalJSON() ([]byte, error) {
+	return json.Marshal(NewAssetJSON(a))
+}
+
+func (a *Asset) UnmarshalJSON(data []byte) error {
+	var b AssetJSON
+	if err := json.Unmarshal(data, &b); err != nil {
+		return err
+	}
+	*a = b.Asset()
+	return nil
+}
+
+func (a *Asset) String() string {
+	if a == nil {
+		return "nil-Asset"
+	}
+	return fmt.Sprintf("Asset{%v %v %v %v %v %v}",
+		a.ID.Hex(), a.Issuer.Hex(), a.Name, a.Symbol, a.Decimals, a.Supply)
+}
+
+// AssetID returns the ID of the asset created by the CreateAssetTx of the issuer with the given sequence
+func AssetID(issuer common.Address, sequence uint64) common.Address {
+	var seqBytes [8]byte
+	binary.BigEndian.PutUint64(seqBytes[:], sequence)
+	return common.BytesToAddress(crypto.Keccak256([]byte("asset"), issuer[:], seqBytes[:])[12:])
+}
@@ -1,12 +1,15 @@
 package types
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 
 	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/rlp"
 )
 
 var (
@@ -22,17 +25,29 @@ func init() {
 type Coins struct {
 	SCPTWei *big.Int
 	SPAYWei *big.Int
+
+	// Tokens holds the amounts of the native tokens, sorted by asset ID. Being the tail of the RLP list,
+	// the encoding of coins without tokens is the same as before the native tokens were introduced.
+	Tokens []TokenCoin `rlp:"tail"`
+}
+
+// TokenCoin is an amount of the native token issued as the given asset
+type TokenCoin struct {
+	AssetID common.Address
+	Amount  *big.Int
 }
 
 type CoinsJSON struct {
 	SCPTWei *common.JSONBig `json:"scptwei"`
 	SPAYWei *common.JSONBig `json:"spaywei"`
+	Tokens  []TokenCoin     `json:"tokens,omitempty"`
 }
 
 func NewCoinsJSON(coin Coins) CoinsJSON {
 	return CoinsJSON{
 		SCPTWei: (*common.JSONBig)(coin.SCPTWei),
 		SPAYWei: (*common.JSONBig)(coin.SPAYWei),
+		Tokens:  coin.Tokens,
 	}
 }
 
@@ -40,9 +55,49 @@ func (c CoinsJSON) Coins() Coins {
 	return Coins{
 		SCPTWei: (*big.Int)(c.SCPTWei),
 		SPAYWei: (*big.Int)(c.SPAYWei),
+		Tokens:  c.Tokens,
 	}
 }
 
+type TokenCoinJSON struct {
+	AssetID common.Address  `json:"asset_id"`
+	Amount  *common.JSONBig `json:"amount"`
+}
+
+func (t TokenCoin) MarshalJSON() ([]byte, error) {
+	return json.Marshal(TokenCoinJSON{
+		AssetID: t.AssetID,
+		Amount:  (*common.JSONBig)(t.Amount),
+	})
+}
+
+func (t *TokenCoin) UnmarshalJSON(data []byte) error {
+	var a TokenCoinJSON
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
+	t.AssetID = a.AssetID
+	t.Amount = (*big.Int)(a.Amount)
+	return nil
+}
+
+// coinsRLP has the fields of Coins without its methods, for Coins to be decoded with the default decoder
+type coinsRLP Coins
+
+// DecodeRLP implements rlp.Decoder. Coins decoded without tokens have nil Tokens, as the coins
+// created by NewCoins.
+func (c *Coins) DecodeRLP(s *rlp.Stream) error {
+	var dec coinsRLP
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	if len(dec.Tokens) == 0 {
+		dec.Tokens = nil
+	}
+	*c = Coins(dec)
+	return nil
+}
+
 func (c Coins) MarshalJSON() ([]byte, error) {
 	return json.Marshal(NewCoinsJSON(c))
 }
@@ -64,14 +119,88 @@ func NewCoins(script int64, spay int64) Coins {
 	}
 }
 
+// NewTokenCoins creates coins holding only the given amount of a native token.
+func NewTokenCoins(assetID common.Address, amount *big.Int) Coins {
+	return Coins{
+		SCPTWei: big.NewInt(0),
+		SPAYWei: big.NewInt(0),
+		Tokens:  []TokenCoin{{AssetID: assetID, Amount: new(big.Int).Set(amount)}},
+	}
+}
+
 func (coins Coins) String() string {
-	return fmt.Sprintf("%v %v, %v %v", coins.SCPTWei, DenomSCPTWei, coins.SPAYWei, DenomSPAYWei)
+	ret := fmt.Sprintf("%v %v, %v %v", coins.SCPTWei, DenomSCPTWei, coins.SPAYWei, DenomSPAYWei)
+	for _, token := range coins.Tokens {
+		ret += fmt.Sprintf(", %v %v", token.Amount, token.AssetID.Hex())
+	}
+	return ret
 }
 
+// IsValid returns whether the coins are non-negative, with the tokens listed once each in ascending
+// asset ID order and with positive amounts.
 func (coins Coins) IsValid() bool {
+	for i, token := range coins.Tokens {
+		if token.Amount == nil || token.Amount.Sign() <= 0 {
+			return false
+		}
+		if i > 0 && bytes.Compare(coins.Tokens[i-1].AssetID[:], token.AssetID[:]) >= 0 {
+			return false
+		}
+	}
 	return coins.IsNonnegative()
 }
 
+// HasTokens returns whether the coins hold any native token.
+func (coins Coins) HasTokens() bool {
+	return len(coins.Tokens) > 0
+}
+
+// GetToken returns the amount of the native token of the given asset.
+func (coins Coins) GetToken(assetID common.Address) *big.Int {
+	amount := new(big.Int)
+	for _, token := range coins.Tokens {
+		if token.AssetID == assetID && token.Amount != nil {
+			amount.Add(amount, token.Amount)
+		}
+	}
+	return amount
+}
+
+// mapTokens applies f to the amount of each token, merging the tokens of the same asset, dropping
+// the zero amounts and sorting the result by asset ID.
+func mapTokens(f func(assetID common.Address, amount *big.Int) *big.Int, tokenLists ...[]TokenCoin) []TokenCoin {
+	amounts := make(map[common.Address]*big.Int)
+	for _, tokens := range tokenLists {
+		for _, token := range tokens {
+			if token.Amount == nil {
+				continue
+			}
+			amount, ok := amounts[token.AssetID]
+			if !ok {
+				amount = new(big.Int)
+				amounts[token.AssetID] = amount
+			}
+			amount.Add(amount, token.Amount)
+		}
+	}
+
+	var ret []TokenCoin
+	for assetID, amount := range amounts {
+		amount = f(assetID, amount)
+		if amount.Sign() != 0 {
+			ret = append(ret, TokenCoin{AssetID: assetID, Amount: amount})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return bytes.Compare(ret[i].AssetID[:], ret[j].AssetID[:]) < 0
+	})
+	return ret
+}
+
+func identityAmount(_ common.Address, amount *big.Int) *big.Int {
+	return amount
+}
+
 func (coins Coins) NoNil() Coins {
 	script := coins.SCPTWei
 	if script == nil {
@@ -85,6 +214,7 @@ func (coins Coins) NoNil() Coins {
 	return Coins{
 		SCPTWei: script,
 		SPAYWei: spay,
+		Tokens:  coins.Tokens,
 	}
 }
 
@@ -102,9 +232,15 @@ func (coins Coins) CalculatePercentage(percentage uint) Coins {
 	spay.Mul(c.SPAYWei, p)
 	spay.Div(spay, Hundred)
 
+	tokens := mapTokens(func(_ common.Address, amount *big.Int) *big.Int {
+		amount.Mul(amount, p)
+		return amount.Div(amount, Hundred)
+	}, c.Tokens)
+
 	return Coins{
 		SCPTWei: script,
 		SPAYWei: spay,
+		Tokens:  tokens,
 	}
 }
 
@@ -122,6 +258,7 @@ func (coinsA Coins) Plus(coinsB Coins) Coins {
 	return Coins{
 		SCPTWei: script,
 		SPAYWei: spay,
+		Tokens:  mapTokens(identityAmount, cA.Tokens, cB.Tokens),
 	}
 }
 
@@ -134,9 +271,14 @@ func (coins Coins) Negative() Coins {
 	spay := new(big.Int)
 	spay.Neg(c.SPAYWei)
 
+	tokens := mapTokens(func(_ common.Address, amount *big.Int) *big.Int {
+		return amount.Neg(amount)
+	}, c.Tokens)
+
 	return Coins{
 		SCPTWei: script,
 		SPAYWei: spay,
+		Tokens:  tokens,
 	}
 }
 
@@ -151,23 +293,29 @@ func (coinsA Coins) IsGTE(coinsB Coins) bool {
 
 func (coins Coins) IsZero() bool {
 	c := coins.NoNil()
-	return c.SCPTWei.Cmp(Zero) == 0 && c.SPAYWei.Cmp(Zero) == 0
+	return c.SCPTWei.Cmp(Zero) == 0 && c.SPAYWei.Cmp(Zero) == 0 &&
+		len(mapTokens(identityAmount, c.Tokens)) == 0
 }
 
 func (coinsA Coins) IsEqual(coinsB Coins) bool {
 	cA := coinsA.NoNil()
 	cB := coinsB.NoNil()
-	return cA.SCPTWei.Cmp(cB.SCPTWei) == 0 && cA.SPAYWei.Cmp(cB.SPAYWei) == 0
+	return cA.SCPTWei.Cmp(cB.SCPTWei) == 0 && cA.SPAYWei.Cmp(cB.SPAYWei) == 0 &&
+		len(cA.Minus(cB).Tokens) == 0
 }
 
+// IsPositive returns whether no amount is negative and at least one is positive.
 func (coins Coins) IsPositive() bool {
-	c := coins.NoNil()
-	return (c.SCPTWei.Cmp(Zero) > 0 && c.SPAYWei.Cmp(Zero) >= 0) ||
-		(c.SCPTWei.Cmp(Zero) >= 0 && c.SPAYWei.Cmp(Zero) > 0)
+	return coins.IsNonnegative() && !coins.IsZero()
 }
 
 func (coins Coins) IsNonnegative() bool {
 	c := coins.NoNil()
+	for _, token := range mapTokens(identityAmount, c.Tokens) {
+		if token.Amount.Sign() < 0 {
+			return false
+		}
+	}
 	return c.SCPTWei.Cmp(Zero) >= 0 && c.SPAYWei.Cmp(Zero) >= 0
 }
 
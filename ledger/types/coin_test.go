@@ -8,6 +8,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/scripttoken/script/common"
 	"github.com/scripttoken/script/rlp"
 
 	"github.com/stretchr/testify/assert"
@@ -58,7 +59,7 @@ func TestInvalidCoin(t *testing.T) {
 	assert.True(ret2.SCPTWei.Cmp(big.NewInt(456)) == 0)
 }
 
-func TestcCoinsRLPNil(t *testing.T) {
+func TestCoinsRLPNil(t *testing.T) {
 	assert := assert.New(t)
 
 	a := Coins{}
@@ -203,3 +204,76 @@ func TestJSON(t *testing.T) {
 	assert.Equal(0, num.Cmp(d.SCPTWei))
 	assert.Nil(d.SPAYWei)
 }
+
+func TestCoinsTokens(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	assetA := common.HexToAddress("0x1001")
+	assetB := common.HexToAddress("0x1002")
+
+	coins1 := NewCoins(10, 20).Plus(NewTokenCoins(assetB, big.NewInt(5)))
+	coins2 := NewTokenCoins(assetA, big.NewInt(7)).Plus(NewTokenCoins(assetB, big.NewInt(3)))
+	sum := coins1.Plus(coins2)
+	assert.True(sum.IsValid())
+	assert.Equal(2, len(sum.Tokens))
+	assert.Equal(assetA, sum.Tokens[0].AssetID)
+	assert.Equal(int64(7), sum.GetToken(assetA).Int64())
+	assert.Equal(int64(8), sum.GetToken(assetB).Int64())
+	assert.Equal(int64(0), sum.GetToken(common.HexToAddress("0x1003")).Int64())
+
+	// Tokens with a zero amount are dropped
+	diff := sum.Minus(NewTokenCoins(assetA, big.NewInt(7)))
+	assert.Equal(1, len(diff.Tokens))
+	assert.True(diff.IsEqual(NewCoins(10, 20).Plus(NewTokenCoins(assetB, big.NewInt(8)))))
+	assert.True(sum.Minus(sum).IsZero())
+
+	assert.True(sum.IsGTE(coins1))
+	assert.False(coins1.IsGTE(sum))
+	assert.False(coins1.Minus(coins2).IsNonnegative())
+	assert.True(NewTokenCoins(assetA, big.NewInt(1)).IsPositive())
+	assert.False(NewCoins(0, 0).HasTokens())
+
+	// The tokens must be sorted by asset ID with positive amounts
+	unsorted := Coins{SCPTWei: big.NewInt(0), SPAYWei: big.NewInt(0), Tokens: []TokenCoin{
+		{AssetID: assetB, Amount: big.NewInt(1)},
+		{AssetID: assetA, Amount: big.NewInt(1)},
+	}}
+	assert.False(unsorted.IsValid())
+	zeroToken := Coins{SCPTWei: big.NewInt(0), SPAYWei: big.NewInt(0), Tokens: []TokenCoin{
+		{AssetID: assetA, Amount: big.NewInt(0)},
+	}}
+	assert.False(zeroToken.IsValid())
+
+	percentage := sum.CalculatePercentage(50)
+	assert.Equal(int64(3), percentage.GetToken(assetA).Int64())
+	assert.Equal(int64(4), percentage.GetToken(assetB).Int64())
+
+	// Coins without tokens are encoded as before the native tokens
+	legacy := struct {
+		SCPTWei *big.Int
+		SPAYWei *big.Int
+	}{big.NewInt(10), big.NewInt(20)}
+	legacyBytes, err := rlp.EncodeToBytes(legacy)
+	require.Nil(err)
+	coinsBytes, err := rlp.EncodeToBytes(NewCoins(10, 20))
+	require.Nil(err)
+	assert.Equal(legacyBytes, coinsBytes)
+
+	var decoded Coins
+	require.Nil(rlp.DecodeBytes(coinsBytes, &decoded))
+	assert.Nil(decoded.Tokens)
+	assert.Equal(NewCoins(10, 20), decoded)
+
+	sumBytes, err := rlp.EncodeToBytes(sum)
+	require.Nil(err)
+	decoded = Coins{}
+	require.Nil(rlp.DecodeBytes(sumBytes, &decoded))
+	assert.True(sum.IsEqual(decoded))
+
+	jsonBytes, err := json.Marshal(sum)
+	require.Nil(err)
+	decoded = Coins{}
+	require.Nil(json.Unmarshal(jsonBytes, &decoded))
+	assert.True(sum.IsEqual(decoded))
+}
@@ -38,6 +38,19 @@ const (
 
 	// MaxAccountsAffectedPerTx specifies the max number of accounts one transaction is allowed to modify to avoid spamming
 	MaxAccountsAffectedPerTx = 512
+
+	// BlockGasLimit is the maximum total gas used by the smart contract transactions of a block
+	BlockGasLimit uint64 = 50e6
+
+	// InitialBaseFee is the base fee of the first block with the dynamic fee enabled, it is also
+	// the floor of the base fee
+	InitialBaseFee uint64 = MinimumGasPriceJune2021
+
+	// BaseFeeChangeDenominator bounds the change of the base fee between two consecutive blocks to 1/8
+	BaseFeeChangeDenominator uint64 = 8
+
+	// ElasticityMultiplier is the ratio between the block gas limit and the block gas target
+	ElasticityMultiplier uint64 = 2
 )
 
 const (
@@ -108,6 +121,63 @@ func GetMaxGasLimit(blockHeight uint64) *big.Int {
 	return new(big.Int).SetUint64(MaximumTxGasLimitJune2021)
 }
 
+// GetBlockGasLimit returns the block gas limit at the given height, 0 if the gas of the
+// blocks is not limited yet
+func GetBlockGasLimit(blockHeight uint64) uint64 {
+	if blockHeight < common.HeightEnableBlockGasLimit {
+		return 0
+	}
+
+	return BlockGasLimit
+}
+
+// GetInitialBaseFee returns the base fee of the first block with the dynamic fee enabled
+func GetInitialBaseFee() *big.Int {
+	return new(big.Int).SetUint64(InitialBaseFee)
+}
+
+// CalcBaseFee calculates the base fee of a block from the base fee and the gas used by its
+// parent. The base fee goes up when the parent used more gas than the target, and down when
+// it used less. It never drops below the initial base fee.
+func CalcBaseFee(parentBaseFee *big.Int, parentGasUsed uint64, blockGasLimit uint64) *big.Int {
+	minBaseFee := GetInitialBaseFee()
+	if parentBaseFee == nil {
+		return minBaseFee
+	}
+
+	gasTarget := blockGasLimit / ElasticityMultiplier
+	if gasTarget == 0 || parentGasUsed == gasTarget {
+		return new(big.Int).Set(parentBaseFee)
+	}
+
+	var gasDelta uint64
+	if parentGasUsed > gasTarget {
+		gasDelta = parentGasUsed - gasTarget
+	} else {
+		gasDelta = gasTarget - parentGasUsed
+	}
+
+	// delta = parentBaseFee * gasDelta / gasTarget / BaseFeeChangeDenominator
+	delta := new(big.Int).Mul(parentBaseFee, new(big.Int).SetUint64(gasDelta))
+	delta.Div(delta, new(big.Int).SetUint64(gasTarget))
+	delta.Div(delta, new(big.Int).SetUint64(BaseFeeChangeDenominator))
+
+	baseFee := new(big.Int)
+	if parentGasUsed > gasTarget {
+		if delta.Sign() == 0 {
+			delta.SetUint64(1)
+		}
+		baseFee.Add(parentBaseFee, delta)
+	} else {
+		baseFee.Sub(parentBaseFee, delta)
+	}
+
+	if baseFee.Cmp(minBaseFee) < 0 {
+		return minBaseFee
+	}
+	return baseFee
+}
+
 func GetMinimumTransactionFeeSPAYWei(blockHeight uint64) *big.Int {
 	if blockHeight < common.HeightJune2021FeeAdjustment {
 		return new(big.Int).SetUint64(MinimumTransactionFeeSPAYWei)
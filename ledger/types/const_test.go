@@ -0,0 +1,36 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcBaseFee(t *testing.T) {
+	assert := assert.New(t)
+
+	gasLimit := uint64(1000)
+	gasTarget := gasLimit / ElasticityMultiplier
+	parentBaseFee := new(big.Int).Mul(GetInitialBaseFee(), big.NewInt(2))
+
+	// No parent base fee
+	assert.Equal(0, GetInitialBaseFee().Cmp(CalcBaseFee(nil, gasTarget, gasLimit)))
+
+	// On target
+	assert.Equal(0, parentBaseFee.Cmp(CalcBaseFee(parentBaseFee, gasTarget, gasLimit)))
+
+	// Full block, the base fee goes up by 1/8
+	expected := new(big.Int).Div(new(big.Int).Mul(parentBaseFee, big.NewInt(9)), big.NewInt(8))
+	assert.Equal(0, expected.Cmp(CalcBaseFee(parentBaseFee, gasLimit, gasLimit)))
+
+	// Empty block, the base fee goes down by 1/8
+	expected = new(big.Int).Div(new(big.Int).Mul(parentBaseFee, big.NewInt(7)), big.NewInt(8))
+	assert.Equal(0, expected.Cmp(CalcBaseFee(parentBaseFee, 0, gasLimit)))
+
+	// Never below the initial base fee
+	assert.Equal(0, GetInitialBaseFee().Cmp(CalcBaseFee(GetInitialBaseFee(), 0, gasLimit)))
+
+	// Gas not limited
+	assert.Equal(0, parentBaseFee.Cmp(CalcBaseFee(parentBaseFee, gasLimit, 0)))
+}
@@ -18,18 +18,29 @@ package types
 
 import (
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"math/big"
 	"strings"
 
 	"github.com/scripttoken/script/common"
 	"github.com/scripttoken/script/crypto"
 	"github.com/scripttoken/script/rlp"
+
+	log "github.com/sirupsen/logrus"
 )
 
-// TranslateEthTx an ETH transaction to a Script smart contract transaction
-func TranslateEthTx(ethTxStr string) (*SmartContractTx, error) {
-	var ethTx *EthTransaction
+// The ETH transaction types (EIP-2718)
+const (
+	LegacyEthTxType     = byte(0x00)
+	AccessListEthTxType = byte(0x01) // EIP-2930
+	DynamicFeeEthTxType = byte(0x02) // EIP-1559
+)
 
+// TranslateEthTx an ETH transaction to a Script smart contract transaction. A legacy ETH
+// transaction is translated to a SmartContractTx, and a typed ETH transaction (access list
+// or dynamic fee) is translated to a SmartContractTxV2.
+func TranslateEthTx(ethTxStr string) (Tx, error) {
 	if strings.HasPrefix(ethTxStr, "0x") {
 		ethTxStr = ethTxStr[2:]
 	}
@@ -39,25 +50,22 @@ func TranslateEthTx(ethTxStr string) (*SmartContractTx, error) {
 		return nil, err
 	}
 
-	err = rlp.DecodeBytes(ethTxBytes, &ethTx)
+	ethTx, err := DecodeEthTx(ethTxBytes)
 	if err != nil {
 		return nil, err
 	}
 
-	ethSigningHash := RLPHash([]interface{}{
-		ethTx.nonce(),
-		ethTx.gasPrice(),
-		ethTx.gas(),
-		ethTx.to(),
-		ethTx.value(),
-		ethTx.data(),
-		ethTx.chainID(), uint(0), uint(0),
-	})
+	ethSigningHash := EthTxSigningHash(ethTx)
 
 	logger.Debugf("ethTx.ethSigningHash: %v", ethSigningHash.Hex())
 
 	v, r, s := ethTx.rawSignatureValues()
-	sig, err := crypto.EncodeSignature(r, s, v)
+	var sig *crypto.Signature
+	if ethTx.txType() == LegacyEthTxType {
+		sig, err = crypto.EncodeSignature(r, s, v)
+	} else {
+		sig, err = crypto.EncodeTypedSignature(r, s, v)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -83,15 +91,26 @@ func TranslateEthTx(ethTxStr string) (*SmartContractTx, error) {
 		Signature: sig,
 	}
 
-	if ethTx.to() == nil {
-		//return nil, errors.New("To address is nil")
-		ethTx.To = &common.Address{}
-	}
-
 	to := TxOutput{
-		Address: *ethTx.to(),
+		Address: common.Address{}, // the empty address means contract creation
 		Coins:   NewCoins(0, 0),
 	}
+	if ethTx.to() != nil {
+		to.Address = *ethTx.to()
+	}
+
+	if ethTx.txType() != LegacyEthTxType {
+		scriptTx := SmartContractTxV2{
+			From:                 from,
+			To:                   to,
+			GasLimit:             ethTx.gas(),
+			MaxFeePerGas:         ethTx.gasFeeCap(),
+			MaxPriorityFeePerGas: ethTx.gasTipCap(),
+			Data:                 ethTx.data(),
+			AccessList:           ethTx.accessList(),
+		}
+		return &scriptTx, nil
+	}
 
 	scriptTx := SmartContractTx{
 		From:     from,
@@ -104,21 +123,87 @@ func TranslateEthTx(ethTxStr string) (*SmartContractTx, error) {
 	return &scriptTx, nil
 }
 
+// DecodeEthTx decodes a raw ETH transaction, which is either a legacy RLP encoded transaction
+// or a typed transaction envelope, i.e. the transaction type followed by the RLP encoded
+// transaction (EIP-2718)
+func DecodeEthTx(ethTxBytes []byte) (TxData, error) {
+	if len(ethTxBytes) == 0 {
+		return nil, errors.New("empty ETH transaction")
+	}
+
+	// The first byte of a legacy transaction is the RLP list prefix, which is at least 0xc0
+	if ethTxBytes[0] > 0x7f {
+		ethTx := &EthTransaction{}
+		if err := rlp.DecodeBytes(ethTxBytes, ethTx); err != nil {
+			return nil, err
+		}
+		return ethTx, nil
+	}
+
+	var ethTx TxData
+	switch ethTxBytes[0] {
+	case AccessListEthTxType:
+		ethTx = &AccessListEthTx{}
+	case DynamicFeeEthTxType:
+		ethTx = &DynamicFeeEthTx{}
+	default:
+		return nil, fmt.Errorf("unsupported ETH transaction type: %v", ethTxBytes[0])
+	}
+	if err := rlp.DecodeBytes(ethTxBytes[1:], ethTx); err != nil {
+		return nil, err
+	}
+	return ethTx, nil
+}
+
+// EthTxSigningHash returns the hash signed by the sender of the ETH transaction
+func EthTxSigningHash(ethTx TxData) common.Hash {
+	if ethTx.txType() == LegacyEthTxType {
+		return RLPHash([]interface{}{
+			ethTx.nonce(),
+			ethTx.gasPrice(),
+			ethTx.gas(),
+			ethTx.to(),
+			ethTx.value(),
+			ethTx.data(),
+			ethTx.chainID(), uint(0), uint(0),
+		})
+	}
+	return crypto.TypedEthSigningHash(ethTx.txType(), ethTx.signingPayload())
+}
+
+// EthTxHash returns the hash of the ETH transaction, i.e. the hash of its raw bytes
+func EthTxHash(ethTx TxData) common.Hash {
+	if ethTx.txType() == LegacyEthTxType {
+		return RLPHash(ethTx)
+	}
+	encoded, err := rlp.EncodeToBytes(ethTx)
+	if err != nil {
+		log.Panic(err)
+	}
+	return crypto.Keccak256Hash([]byte{ethTx.txType()}, encoded)
+}
+
 // TxData is the underlying data of a transaction.
 //
-// This is implemented by EthTransaction and AccessListTx.
+// This is implemented by EthTransaction, AccessListEthTx and DynamicFeeEthTx.
 type TxData interface {
-	//txType() byte // returns the type ID
+	txType() byte // returns the type ID
 	copy() TxData // creates a deep copy and initializes all fields
 
 	chainID() *big.Int
+	accessList() AccessList
 	data() []byte
 	gas() uint64
 	gasPrice() *big.Int
+	gasTipCap() *big.Int
+	gasFeeCap() *big.Int
 	value() *big.Int
 	nonce() uint64
 	to() *common.Address
 
+	// signingPayload returns the payload of the signing hash of a typed transaction
+	signingPayload() []interface{}
+
 	rawSignatureValues() (v, r, s *big.Int)
 	setSignatureValues(chainID, v, r, s *big.Int)
 }
@@ -173,13 +258,21 @@ func (tx *EthTransaction) Hash() common.Hash {
 
 // accessors for innerTx.
 
-func (tx *EthTransaction) chainID() *big.Int   { return crypto.DeriveEthChainId(tx.V) }
-func (tx *EthTransaction) data() []byte        { return tx.Data }
-func (tx *EthTransaction) gas() uint64         { return tx.Gas }
-func (tx *EthTransaction) gasPrice() *big.Int  { return tx.GasPrice }
-func (tx *EthTransaction) value() *big.Int     { return tx.Value }
-func (tx *EthTransaction) nonce() uint64       { return tx.Nonce }
-func (tx *EthTransaction) to() *common.Address { return tx.To }
+func (tx *EthTransaction) txType() byte           { return LegacyEthTxType }
+func (tx *EthTransaction) chainID() *big.Int      { return crypto.DeriveEthChainId(tx.V) }
+func (tx *EthTransaction) accessList() AccessList { return nil }
+func (tx *EthTransaction) data() []byte           { return tx.Data }
+func (tx *EthTransaction) gas() uint64            { return tx.Gas }
+func (tx *EthTransaction) gasPrice() *big.Int     { return tx.GasPrice }
+func (tx *EthTransaction) gasTipCap() *big.Int    { return tx.GasPrice }
+func (tx *EthTransaction) gasFeeCap() *big.Int    { return tx.GasPrice }
+func (tx *EthTransaction) value() *big.Int        { return tx.Value }
+func (tx *EthTransaction) nonce() uint64          { return tx.Nonce }
+func (tx *EthTransaction) to() *common.Address    { return tx.To }
+
+func (tx *EthTransaction) signingPayload() []interface{} {
+	return nil // a legacy transaction is not typed
+}
 
 func (tx *EthTransaction) rawSignatureValues() (v, r, s *big.Int) {
 	return tx.V, tx.R, tx.S
@@ -188,3 +281,181 @@ func (tx *EthTransaction) rawSignatureValues() (v, r, s *big.Int) {
 func (tx *EthTransaction) setSignatureValues(chainID, v, r, s *big.Int) {
 	tx.V, tx.R, tx.S = v, r, s
 }
+
+// AccessListEthTx is the transaction data of the access list Ethereum transactions (EIP-2930).
+type AccessListEthTx struct {
+	ChainID    *big.Int        // destination chain ID
+	Nonce      uint64          // nonce of sender account
+	GasPrice   *big.Int        // wei per gas
+	Gas        uint64          // gas limit
+	To         *common.Address `rlp:"nil"` // nil means contract creation
+	Value      *big.Int        // wei amount
+	Data       []byte          // contract invocation input data
+	AccessList AccessList      // EIP-2930 access list
+	V, R, S    *big.Int        // signature values
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx *AccessListEthTx) copy() TxData {
+	cpy := &AccessListEthTx{
+		Nonce:      tx.Nonce,
+		To:         copyAddressPtr(tx.To),
+		Data:       common.CopyBytes(tx.Data),
+		Gas:        tx.Gas,
+		AccessList: copyAccessList(tx.AccessList),
+		// These are initialized below.
+		ChainID:  new(big.Int),
+		Value:    new(big.Int),
+		GasPrice: new(big.Int),
+		V:        new(big.Int),
+		R:        new(big.Int),
+		S:        new(big.Int),
+	}
+	copyBigInt(cpy.ChainID, tx.ChainID)
+	copyBigInt(cpy.Value, tx.Value)
+	copyBigInt(cpy.GasPrice, tx.GasPrice)
+	copyBigInt(cpy.V, tx.V)
+	copyBigInt(cpy.R, tx.R)
+	copyBigInt(cpy.S, tx.S)
+	return cpy
+}
+
+// accessors for innerTx.
+
+func (tx *AccessListEthTx) txType() byte           { return AccessListEthTxType }
+func (tx *AccessListEthTx) chainID() *big.Int      { return tx.ChainID }
+func (tx *AccessListEthTx) accessList() AccessList { return tx.AccessList }
+func (tx *AccessListEthTx) data() []byte           { return tx.Data }
+func (tx *AccessListEthTx) gas() uint64            { return tx.Gas }
+func (tx *AccessListEthTx) gasPrice() *big.Int     { return tx.GasPrice }
+func (tx *AccessListEthTx) gasTipCap() *big.Int    { return tx.GasPrice }
+func (tx *AccessListEthTx) gasFeeCap() *big.Int    { return tx.GasPrice }
+func (tx *AccessListEthTx) value() *big.Int        { return tx.Value }
+func (tx *AccessListEthTx) nonce() uint64          { return tx.Nonce }
+func (tx *AccessListEthTx) to() *common.Address    { return tx.To }
+
+func (tx *AccessListEthTx) signingPayload() []interface{} {
+	return []interface{}{
+		tx.ChainID,
+		tx.Nonce,
+		tx.GasPrice,
+		tx.Gas,
+		tx.To,
+		tx.Value,
+		tx.Data,
+		tx.AccessList,
+	}
+}
+
+func (tx *AccessListEthTx) rawSignatureValues() (v, r, s *big.Int) {
+	return tx.V, tx.R, tx.S
+}
+
+func (tx *AccessListEthTx) setSignatureValues(chainID, v, r, s *big.Int) {
+	tx.ChainID, tx.V, tx.R, tx.S = chainID, v, r, s
+}
+
+// DynamicFeeEthTx is the transaction data of the dynamic fee Ethereum transactions (EIP-1559).
+type DynamicFeeEthTx struct {
+	ChainID    *big.Int        // destination chain ID
+	Nonce      uint64          // nonce of sender account
+	GasTipCap  *big.Int        // max priority fee per gas
+	GasFeeCap  *big.Int        // max fee per gas
+	Gas        uint64          // gas limit
+	To         *common.Address `rlp:"nil"` // nil means contract creation
+	Value      *big.Int        // wei amount
+	Data       []byte          // contract invocation input data
+	AccessList AccessList      // EIP-2930 access list
+	V, R, S    *big.Int        // signature values
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx *DynamicFeeEthTx) copy() TxData {
+	cpy := &DynamicFeeEthTx{
+		Nonce:      tx.Nonce,
+		To:         copyAddressPtr(tx.To),
+		Data:       common.CopyBytes(tx.Data),
+		Gas:        tx.Gas,
+		AccessList: copyAccessList(tx.AccessList),
+		// These are initialized below.
+		ChainID:   new(big.Int),
+		Value:     new(big.Int),
+		GasTipCap: new(big.Int),
+		GasFeeCap: new(big.Int),
+		V:         new(big.Int),
+		R:         new(big.Int),
+		S:         new(big.Int),
+	}
+	copyBigInt(cpy.ChainID, tx.ChainID)
+	copyBigInt(cpy.Value, tx.Value)
+	copyBigInt(cpy.GasTipCap, tx.GasTipCap)
+	copyBigInt(cpy.GasFeeCap, tx.GasFeeCap)
+	copyBigInt(cpy.V, tx.V)
+	copyBigInt(cpy.R, tx.R)
+	copyBigInt(cpy.S, tx.S)
+	return cpy
+}
+
+// accessors for innerTx.
+
+func (tx *DynamicFeeEthTx) txType() byte           { return DynamicFeeEthTxType }
+func (tx *DynamicFeeEthTx) chainID() *big.Int      { return tx.ChainID }
+func (tx *DynamicFeeEthTx) accessList() AccessList { return tx.AccessList }
+func (tx *DynamicFeeEthTx) data() []byte           { return tx.Data }
+func (tx *DynamicFeeEthTx) gas() uint64            { return tx.Gas }
+func (tx *DynamicFeeEthTx) gasPrice() *big.Int     { return tx.GasFeeCap }
+func (tx *DynamicFeeEthTx) gasTipCap() *big.Int    { return tx.GasTipCap }
+func (tx *DynamicFeeEthTx) gasFeeCap() *big.Int    { return tx.GasFeeCap }
+func (tx *DynamicFeeEthTx) value() *big.Int        { return tx.Value }
+func (tx *DynamicFeeEthTx) nonce() uint64          { return tx.Nonce }
+func (tx *DynamicFeeEthTx) to() *common.Address    { return tx.To }
+
+func (tx *DynamicFeeEthTx) signingPayload() []interface{} {
+	return []interface{}{
+		tx.ChainID,
+		tx.Nonce,
+		tx.GasTipCap,
+		tx.GasFeeCap,
+		tx.Gas,
+		tx.To,
+		tx.Value,
+		tx.Data,
+		tx.AccessList,
+	}
+}
+
+func (tx *DynamicFeeEthTx) rawSignatureValues() (v, r, s *big.Int) {
+	return tx.V, tx.R, tx.S
+}
+
+func (tx *DynamicFeeEthTx) setSignatureValues(chainID, v, r, s *big.Int) {
+	tx.ChainID, tx.V, tx.R, tx.S = chainID, v, r, s
+}
+
+func copyBigInt(dst, src *big.Int) {
+	if src != nil {
+		dst.Set(src)
+	}
+}
+
+func copyAddressPtr(a *common.Address) *common.Address {
+	if a == nil {
+		return nil
+	}
+	cpy := *a
+	return &cpy
+}
+
+func copyAccessList(al AccessList) AccessList {
+	if al == nil {
+		return nil
+	}
+	cpy := make(AccessList, len(al))
+	for i, tuple := range al {
+		cpy[i] = AccessTuple{
+			Address:     tuple.Address,
+			StorageKeys: append([]common.Hash{}, tuple.StorageKeys...),
+		}
+	}
+	return cpy
+}
@@ -0,0 +1,152 @@
+package types
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/common/math"
+	"github.com/scripttoken/script/crypto"
+	"github.com/scripttoken/script/crypto/secp256k1"
+	"github.com/scripttoken/script/rlp"
+)
+
+const testEthChainID = int64(366)
+
+// signTypedEthTx signs the typed ETH transaction with the private key, and returns the raw
+// transaction envelope
+func signTypedEthTx(t *testing.T, privKey *crypto.PrivateKey, ethTx TxData) []byte {
+	signingHash := EthTxSigningHash(ethTx)
+	seckey := math.PaddedBigBytes(privKey.D(), 32)
+	sig, err := secp256k1.Sign(signingHash[:], seckey)
+	require.Nil(t, err)
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:64])
+	v := new(big.Int).SetUint64(uint64(sig[64]))
+	ethTx.setSignatureValues(ethTx.chainID(), v, r, s)
+
+	encoded, err := rlp.EncodeToBytes(ethTx)
+	require.Nil(t, err)
+	return append([]byte{ethTx.txType()}, encoded...)
+}
+
+func TestTranslateDynamicFeeEthTx(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	privKey, _, err := crypto.GenerateKeyPair()
+	require.Nil(err)
+	to := common.HexToAddress("0x2e833968e5bb786ae419c4d13189fb081cc43bab")
+	accessList := AccessList{
+		{Address: to, StorageKeys: []common.Hash{common.BigToHash(big.NewInt(1))}},
+	}
+	ethTx := &DynamicFeeEthTx{
+		ChainID:    big.NewInt(testEthChainID),
+		Nonce:      4,
+		GasTipCap:  big.NewInt(2000),
+		GasFeeCap:  big.NewInt(5000),
+		Gas:        100000,
+		To:         &to,
+		Value:      big.NewInt(123),
+		Data:       common.Hex2Bytes("d09de08a"),
+		AccessList: accessList,
+	}
+	raw := signTypedEthTx(t, privKey, ethTx)
+
+	tx, err := TranslateEthTx("0x" + hex.EncodeToString(raw))
+	require.Nil(err)
+	dtx, ok := tx.(*SmartContractTxV2)
+	require.True(ok)
+	assert.Equal(privKey.PublicKey().Address(), dtx.From.Address)
+	assert.Equal(uint64(5), dtx.From.Sequence)
+	assert.Equal(0, big.NewInt(123).Cmp(dtx.From.Coins.SPAYWei))
+	assert.Equal(to, dtx.To.Address)
+	assert.Equal(uint64(100000), dtx.GasLimit)
+	assert.Equal(0, big.NewInt(5000).Cmp(dtx.MaxFeePerGas))
+	assert.Equal(0, big.NewInt(2000).Cmp(dtx.MaxPriorityFeePerGas))
+	assert.Equal(accessList, dtx.AccessList)
+
+	// The ETH transaction is recovered from the translated transaction, with the hash of the raw bytes
+	recoveredEthTx, err := dtx.RecoverEthTx(testEthChainID)
+	require.Nil(err)
+	assert.Equal(DynamicFeeEthTxType, recoveredEthTx.txType())
+	assert.Equal(crypto.Keccak256Hash(raw), EthTxHash(recoveredEthTx))
+
+	// The signature is only valid for the chain ID it is signed for
+	_, err = dtx.RecoverEthTx(testEthChainID + 1)
+	assert.NotNil(err)
+
+	// The translated transaction survives the serialization
+	txBytes, err := TxToBytes(dtx)
+	require.Nil(err)
+	decodedTx, err := TxFromBytes(txBytes)
+	require.Nil(err)
+	_, err = decodedTx.(*SmartContractTxV2).RecoverEthTx(testEthChainID)
+	assert.Nil(err)
+}
+
+func TestTranslateAccessListEthTx(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	privKey, _, err := crypto.GenerateKeyPair()
+	require.Nil(err)
+	ethTx := &AccessListEthTx{
+		ChainID:  big.NewInt(testEthChainID),
+		Nonce:    0,
+		GasPrice: big.NewInt(4000),
+		Gas:      200000,
+		To:       nil, // contract creation
+		Value:    big.NewInt(0),
+		Data:     common.Hex2Bytes("6080604052"),
+		AccessList: AccessList{
+			{Address: common.HexToAddress("0x00000000000000000000000000000000000000c8")},
+		},
+	}
+	raw := signTypedEthTx(t, privKey, ethTx)
+
+	tx, err := TranslateEthTx(hex.EncodeToString(raw))
+	require.Nil(err)
+	dtx, ok := tx.(*SmartContractTxV2)
+	require.True(ok)
+	assert.Equal(privKey.PublicKey().Address(), dtx.From.Address)
+	assert.Equal(uint64(1), dtx.From.Sequence)
+	assert.Equal(common.Address{}, dtx.To.Address)
+	assert.Equal(0, big.NewInt(4000).Cmp(dtx.MaxFeePerGas))
+	assert.Equal(0, big.NewInt(4000).Cmp(dtx.MaxPriorityFeePerGas))
+
+	recoveredEthTx, err := dtx.RecoverEthTx(testEthChainID)
+	require.Nil(err)
+	assert.Equal(AccessListEthTxType, recoveredEthTx.txType())
+	assert.Equal(crypto.Keccak256Hash(raw), EthTxHash(recoveredEthTx))
+}
+
+func TestDecodeEthTx(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := DecodeEthTx([]byte{})
+	assert.NotNil(err)
+
+	_, err = DecodeEthTx([]byte{0x03, 0xc0})
+	assert.NotNil(err) // unsupported transaction type
+
+	legacyTx := &EthTransaction{
+		Nonce:    1,
+		GasPrice: big.NewInt(1),
+		Gas:      21000,
+		Value:    big.NewInt(0),
+		V:        big.NewInt(27),
+		R:        big.NewInt(1),
+		S:        big.NewInt(1),
+	}
+	raw, err := rlp.EncodeToBytes(legacyTx)
+	assert.Nil(err)
+	ethTx, err := DecodeEthTx(raw)
+	assert.Nil(err)
+	assert.Equal(LegacyEthTxType, ethTx.txType())
+	assert.Equal(crypto.Keccak256Hash(raw), EthTxHash(ethTx))
+}
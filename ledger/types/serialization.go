@@ -37,6 +37,8 @@ const (
 	TxWithdrawStake
 	TxDepositStakeV2
 	TxStakeRewardDistribution
+	TxSmartContractV2
+	TxCreateAsset
 )
 
 func Fuzz(data []byte) int {
@@ -114,6 +116,14 @@ func TxFromBytes(raw []byte) (Tx, error) {
 		data := &StakeRewardDistributionTx{}
 		err = s.Decode(data)
 		return data, err
+	} else if txType == TxSmartContractV2 {
+		data := &SmartContractTxV2{}
+		err = s.Decode(data)
+		return data, err
+	} else if txType == TxCreateAsset {
+		data := &CreateAssetTx{}
+		err = s.Decode(data)
+		return data, err
 	} else {
 		return nil, fmt.Errorf("Unknown TX type: %v", txType)
 	}
@@ -147,6 +157,10 @@ func TxToBytes(t Tx) ([]byte, error) {
 		txType = TxDepositStakeV2
 	case *StakeRewardDistributionTx:
 		txType = TxStakeRewardDistribution
+	case *SmartContractTxV2:
+		txType = TxSmartContractV2
+	case *CreateAssetTx:
+		txType = TxCreateAsset
 	default:
 		return nil, errors.New("Unsupported message type")
 	}
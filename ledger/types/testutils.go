@@ -35,6 +35,7 @@ func PrivAccountFromSecret(secret string) PrivAccount {
 		Account: Account{
 			Address:                privKey.PublicKey().Address(),
 			LastUpdatedBlockHeight: 1,
+			CodeHash:               EmptyCodeHash,
 		},
 	}
 	return privAccount
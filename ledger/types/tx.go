@@ -36,7 +36,9 @@ Transaction Types:
  - DepositStakeTx          Deposit stake to a target address (e.g. a validator)
  - WithdrawStakeTx         Withdraw stake from a target address (e.g. a validator)
  - SmartContractTx         Execute smart contract
+ - SmartContractTxV2       Execute smart contract, with the gas priced by the base fee and a priority fee
  - StakeRewardDistribution Defines how stake reward is distributed
+ - CreateAssetTx           Create a native token, whose whole supply is credited to the issuer
 */
 
 // Gas of regular transactions
@@ -849,6 +851,191 @@ func (tx *SmartContractTx) String() string {
 
 //-----------------------------------------------------------------------------
 
+// SmartContractTxV2 is a smart contract transaction with dynamic fee. The sender pays the
+// base fee of the block, which is burned, plus a priority fee to the block proposer, and
+// never more than MaxFeePerGas per gas in total. The addresses and storage slots of the
+// optional access list are warm from the start of the execution.
+type SmartContractTxV2 struct {
+	From                 TxInput
+	To                   TxOutput
+	GasLimit             uint64
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	Data                 common.Bytes
+	AccessList           AccessList
+}
+
+type SmartContractTxV2JSON struct {
+	From                 TxInput           `json:"from"`
+	To                   TxOutput          `json:"to"`
+	GasLimit             common.JSONUint64 `json:"gas_limit"`
+	MaxFeePerGas         *common.JSONBig   `json:"max_fee_per_gas"`
+	MaxPriorityFeePerGas *common.JSONBig   `json:"max_priority_fee_per_gas"`
+	Data                 common.Bytes      `json:"data"`
+	AccessList           AccessList        `json:"access_list"`
+}
+
+func NewSmartContractTxV2JSON(a SmartContractTxV2) SmartContractTxV2JSON {
+	return SmartContractTxV2JSON{
+		From:                 a.From,
+		To:                   a.To,
+		GasLimit:             common.JSONUint64(a.GasLimit),
+		MaxFeePerGas:         (*common.JSONBig)(a.MaxFeePerGas),
+		MaxPriorityFeePerGas: (*common.JSONBig)(a.MaxPriorityFeePerGas),
+		Data:                 a.Data,
+		AccessList:           a.AccessList,
+	}
+}
+
+func (a SmartContractTxV2JSON) SmartContractTxV2() SmartContractTxV2 {
+	return SmartContractTxV2{
+		From:                 a.From,
+		To:                   a.To,
+		GasLimit:             uint64(a.GasLimit),
+		MaxFeePerGas:         (*big.Int)(a.MaxFeePerGas),
+		MaxPriorityFeePerGas: (*big.Int)(a.MaxPriorityFeePerGas),
+		Data:                 a.Data,
+		AccessList:           a.AccessList,
+	}
+}
+
+func (a SmartContractTxV2) MarshalJSON() ([]byte, error) {
+	return json.Marshal(NewSmartContractTxV2JSON(a))
+}
+
+func (a *SmartContractTxV2) UnmarshalJSON(data []byte) error {
+	var b SmartContractTxV2JSON
+	if err := json.Unmarshal(data, &b); err != nil {
+		return err
+	}
+	*a = b.SmartContractTxV2()
+	return nil
+}
+
+func (_ *SmartContractTxV2) AssertIsTx() {}
+
+func (tx *SmartContractTxV2) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.From.Signature
+	tx.From.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.From.Signature = sig
+	return signBytes
+}
+
+func (tx *SmartContractTxV2) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.From.Address == addr {
+		tx.From.Signature = sig
+		return true
+	}
+	return false
+}
+
+// EffectiveGasPrice returns the gas price paid under the given base fee, i.e.
+// min(MaxFeePerGas, baseFee + MaxPriorityFeePerGas)
+func (tx *SmartContractTxV2) EffectiveGasPrice(baseFee *big.Int) *big.Int {
+	if tx.MaxFeePerGas == nil || tx.MaxPriorityFeePerGas == nil || baseFee == nil {
+		return tx.MaxFeePerGas
+	}
+	gasPrice := new(big.Int).Add(baseFee, tx.MaxPriorityFeePerGas)
+	if gasPrice.Cmp(tx.MaxFeePerGas) > 0 {
+		return new(big.Int).Set(tx.MaxFeePerGas)
+	}
+	return gasPrice
+}
+
+// SmartContractTx returns the equivalent legacy transaction with the given gas price, which
+// the virtual machine executes
+func (tx *SmartContractTxV2) SmartContractTx(gasPrice *big.Int) *SmartContractTx {
+	return &SmartContractTx{
+		From:     tx.From,
+		To:       tx.To,
+		GasLimit: tx.GasLimit,
+		GasPrice: gasPrice,
+		Data:     tx.Data,
+	}
+}
+
+// EthTx returns the typed ETH transaction (access list or dynamic fee) with the given chain
+// ID that corresponds to the transaction, along with its signature
+func (tx *SmartContractTxV2) EthTx(txType byte, ethChainID int64) (TxData, error) {
+	if tx.From.Signature == nil {
+		return nil, fmt.Errorf("transaction is not signed")
+	}
+
+	var toAddress *common.Address
+	if (tx.To.Address != common.Address{}) {
+		toAddress = &tx.To.Address
+	}
+	nonce := tx.From.Sequence - 1 // off-by-one, ETH tx nonce starts from 0, while Script tx sequence starts from 1
+	chainID := big.NewInt(ethChainID)
+	r, s, v := crypto.DecodeSignature(tx.From.Signature)
+	yParity := new(big.Int).Sub(v, big.NewInt(27))
+
+	switch txType {
+	case AccessListEthTxType:
+		if tx.MaxFeePerGas == nil || tx.MaxPriorityFeePerGas == nil || tx.MaxFeePerGas.Cmp(tx.MaxPriorityFeePerGas) != 0 {
+			return nil, fmt.Errorf("an access list ETH transaction has a single gas price")
+		}
+		return &AccessListEthTx{
+			ChainID:    chainID,
+			Nonce:      nonce,
+			GasPrice:   tx.MaxFeePerGas,
+			Gas:        tx.GasLimit,
+			To:         toAddress,
+			Value:      tx.From.Coins.NoNil().SPAYWei,
+			Data:       tx.Data,
+			AccessList: tx.AccessList,
+			V:          yParity,
+			R:          r,
+			S:          s,
+		}, nil
+	case DynamicFeeEthTxType:
+		return &DynamicFeeEthTx{
+			ChainID:    chainID,
+			Nonce:      nonce,
+			GasTipCap:  tx.MaxPriorityFeePerGas,
+			GasFeeCap:  tx.MaxFeePerGas,
+			Gas:        tx.GasLimit,
+			To:         toAddress,
+			Value:      tx.From.Coins.NoNil().SPAYWei,
+			Data:       tx.Data,
+			AccessList: tx.AccessList,
+			V:          yParity,
+			R:          r,
+			S:          s,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported ETH transaction type: %v", txType)
+	}
+}
+
+// RecoverEthTx returns the typed ETH transaction signed by the sender, which the transaction
+// is translated from. It returns an error if the transaction is not signed as an ETH transaction.
+func (tx *SmartContractTxV2) RecoverEthTx(ethChainID int64) (TxData, error) {
+	for _, txType := range []byte{DynamicFeeEthTxType, AccessListEthTxType} {
+		ethTx, err := tx.EthTx(txType, ethChainID)
+		if err != nil {
+			continue
+		}
+		err = crypto.ValidateTypedEthSignature(tx.From.Address, txType, ethTx.signingPayload(), tx.From.Signature)
+		if err == nil {
+			return ethTx, nil
+		}
+	}
+	return nil, fmt.Errorf("not an ETH transaction")
+}
+
+func (tx *SmartContractTxV2) String() string {
+	return fmt.Sprintf("SmartContractTxV2{%v -> %v, value: %v, gas_limit: %v, max_fee_per_gas: %v, max_priority_fee_per_gas: %v, data: %v}",
+		tx.From.Address.Hex(), tx.To.Address.Hex(), tx.From.Coins.SPAYWei, tx.GasLimit, tx.MaxFeePerGas, tx.MaxPriorityFeePerGas, tx.Data)
+}
+
+//-----------------------------------------------------------------------------
+
 type DepositStakeTx struct {
 	Fee     Coins    `json:"fee"`     // Fee
 	Source  TxInput  `json:"source"`  // source staker account
@@ -1019,6 +1206,98 @@ func (tx *StakeRewardDistributionTx) String() string {
 		tx.Holder.Address, tx.Beneficiary.Address, tx.SplitBasisPoint)
 }
 
+//-----------------------------------------------------------------------------
+
+//
+// CreateAssetTx issues a native token. The asset ID is derived from the issuer address and sequence, see
+// AssetID(). The whole supply is credited to the issuer, and the tokens are then moved with the SendTx,
+// the ReserveFundTx and the ServicePaymentTx like Script and SPAY, without paying any smart contract gas.
+//
+type CreateAssetTx struct {
+	Fee      Coins    // Fee
+	Issuer   TxInput  // Issuer of the asset
+	Name     string   // Name of the asset
+	Symbol   string   // Ticker symbol of the asset
+	Decimals uint8    // Number of decimals of the amounts displayed to the users
+	Supply   *big.Int // Total supply, in the smallest unit of the asset
+}
+
+type CreateAssetTxJSON struct {
+	Fee      Coins           `json:"fee"`
+	Issuer   TxInput         `json:"issuer"`
+	Name     string          `json:"name"`
+	Symbol   string          `json:"symbol"`
+	Decimals uint8           `json:"decimals"`
+	Supply   *common.JSONBig `json:"supply"`
+}
+
+func NewCreateAssetTxJSON(a CreateAssetTx) CreateAssetTxJSON {
+	return CreateAssetTxJSON{
+		Fee:      a.Fee,
+		Issuer:   a.Issuer,
+		Name:     a.Name,
+		Symbol:   a.Symbol,
+		Decimals: a.Decimals,
+		Supply:   (*common.JSONBig)(a.Supply),
+	}
+}
+
+func (a CreateAssetTxJSON) CreateAssetTx() CreateAssetTx {
+	return CreateAssetTx{
+		Fee:      a.Fee,
+		Issuer:   a.Issuer,
+		Name:     a.Name,
+		Symbol:   a.Symbol,
+		Decimals: a.Decimals,
+		Supply:   (*big.Int)(a.Supply),
+	}
+}
+
+func (a CreateAssetTx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(NewCreateAssetTxJSON(a))
+}
+
+func (a *CreateAssetTx) UnmarshalJSON(data []byte) error {
+	var b CreateAssetTxJSON
+	if err := json.Unmarshal(data, &b); err != nil {
+		return err
+	}
+	*a = b.CreateAssetTx()
+	return nil
+}
+
+func (_ *CreateAssetTx) AssertIsTx() {}
+
+func (tx *CreateAssetTx) SignBytes(chainID string) []byte {
+	signBytes := encodeToBytes(chainID)
+	sig := tx.Issuer.Signature
+	tx.Issuer.Signature = nil
+	txBytes, _ := TxToBytes(tx)
+	signBytes = append(signBytes, txBytes...)
+	signBytes = addPrefixForSignBytes(signBytes)
+
+	tx.Issuer.Signature = sig
+	return signBytes
+}
+
+func (tx *CreateAssetTx) SetSignature(addr common.Address, sig *crypto.Signature) bool {
+	if tx.Issuer.Address == addr {
+		tx.Issuer.Signature = sig
+		return true
+	}
+	return false
+}
+
+// AssetID returns the ID of the asset created by the transaction
+func (tx *CreateAssetTx) AssetID() common.Address {
+	return AssetID(tx.Issuer.Address, tx.Issuer.Sequence)
+}
+
+func (tx *CreateAssetTx) String() string {
+	return fmt.Sprintf("CreateAssetTx{fee: %v, issuer: %v, name: %v, symbol: %v, decimals: %v, supply: %v}",
+		tx.Fee, tx.Issuer, tx.Name, tx.Symbol, tx.Decimals, tx.Supply)
+}
+
 // --------------- Utils --------------- //
 
 type EthereumTxWrapper struct {
@@ -16,9 +16,9 @@ import (
 	"github.com/scripttoken/script/rlp"
 )
 
-/*
 var chainID string = "test_chain"
 
+/*
 func TestChainID(t *testing.T) {
 
 	//
@@ -356,11 +356,11 @@ func TestSendTxSignable(t *testing.T) {
 }
 
 func TestSendTxSignable2(t *testing.T) {
-	chainID := "scriptnet"
+	chainID := "privatenet"
 	ten18 := new(big.Int).SetUint64(1000000000000000000) // 10^18
 	scriptWei := new(big.Int).Mul(new(big.Int).SetUint64(10), ten18)
 	spayWei := new(big.Int).Mul(new(big.Int).SetUint64(20), ten18)
-	feeInSPAYWei := new(big.Int).SetUint64(10000000000000000) // 10^12
+	feeInSPAYWei := new(big.Int).SetUint64(1000000000000) // 10^12
 
 	senderAddr := common.HexToAddress("2E833968E5bB786Ae419c4d13189fB081Cc43bab")
 	receiverAddr := common.HexToAddress("9F1233798E905E173560071255140b4A8aBd3Ec6")
@@ -886,3 +886,43 @@ func TestSmartContractTxJSON(t *testing.T) {
 	assert.Equal(uint64(math.MaxUint64), d.GasLimit)
 	assert.Equal(0, gasPrice.Cmp(d.GasPrice))
 }
+
+func TestSmartContractTxV2JSON(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	maxFee, _ := new(big.Int).SetString("12312312312312312312331231231231212312312312312313213", 10)
+	a := SmartContractTxV2{
+		GasLimit:             math.MaxUint64,
+		MaxFeePerGas:         maxFee,
+		MaxPriorityFeePerGas: big.NewInt(3),
+	}
+	s, err := json.Marshal(a)
+	require.Nil(err)
+
+	var d SmartContractTxV2
+	err = json.Unmarshal(s, &d)
+	require.Nil(err)
+	assert.Equal(uint64(math.MaxUint64), d.GasLimit)
+	assert.Equal(0, maxFee.Cmp(d.MaxFeePerGas))
+	assert.Equal(0, big.NewInt(3).Cmp(d.MaxPriorityFeePerGas))
+
+	raw, err := TxToBytes(&a)
+	require.Nil(err)
+	tx, err := TxFromBytes(raw)
+	require.Nil(err)
+	_, ok := tx.(*SmartContractTxV2)
+	assert.True(ok)
+}
+
+func TestSmartContractTxV2EffectiveGasPrice(t *testing.T) {
+	assert := assert.New(t)
+
+	tx := SmartContractTxV2{
+		MaxFeePerGas:         big.NewInt(100),
+		MaxPriorityFeePerGas: big.NewInt(10),
+	}
+	assert.Equal(int64(60), tx.EffectiveGasPrice(big.NewInt(50)).Int64())
+	assert.Equal(int64(100), tx.EffectiveGasPrice(big.NewInt(95)).Int64())
+	assert.Equal(int64(100), tx.SmartContractTx(tx.EffectiveGasPrice(big.NewInt(95))).GasPrice.Int64())
+}
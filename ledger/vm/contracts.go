@@ -17,12 +17,14 @@
 package vm
 
 import (
+	"crypto/ed25519"
 	"crypto/sha256"
 	"errors"
 	"math/big"
 
 	"github.com/scripttoken/script/common"
 	"github.com/scripttoken/script/common/math"
+	"github.com/scripttoken/script/core"
 	"github.com/scripttoken/script/crypto"
 	"github.com/scripttoken/script/crypto/bn256"
 	"github.com/scripttoken/script/ledger/vm/params"
@@ -96,6 +98,67 @@ var PrecompiledContractsWrappedScriptSupport = map[common.Address]PrecompiledCon
 	common.BytesToAddress([]byte{207}): &unstakeFromEEN{},
 }
 
+// PrecompiledContractsCryptoSupport adds the BLS12-381 curve operations of EIP-2537 and the
+// ed25519 signature verification to PrecompiledContractsWrappedScriptSupport
+var PrecompiledContractsCryptoSupport = map[common.Address]PrecompiledContract{
+	common.BytesToAddress([]byte{1}):  &ecrecover{},
+	common.BytesToAddress([]byte{2}):  &sha256hash{},
+	common.BytesToAddress([]byte{3}):  &ripemd160hash{},
+	common.BytesToAddress([]byte{4}):  &dataCopy{},
+	common.BytesToAddress([]byte{5}):  &bigModExp{},
+	common.BytesToAddress([]byte{6}):  &bn256Add{},
+	common.BytesToAddress([]byte{7}):  &bn256ScalarMul{},
+	common.BytesToAddress([]byte{8}):  &bn256Pairing{},
+	common.BytesToAddress([]byte{11}): &bls12381G1Add{},
+	common.BytesToAddress([]byte{12}): &bls12381G1MultiExp{},
+	common.BytesToAddress([]byte{13}): &bls12381G2Add{},
+	common.BytesToAddress([]byte{14}): &bls12381G2MultiExp{},
+	common.BytesToAddress([]byte{15}): &bls12381Pairing{},
+	common.BytesToAddress([]byte{16}): &bls12381MapG1{},
+	common.BytesToAddress([]byte{17}): &bls12381MapG2{},
+
+	common.BytesToAddress([]byte{201}): &scriptBalance{},
+	common.BytesToAddress([]byte{202}): &scriptStake{},
+	common.BytesToAddress([]byte{203}): &transferScript{},
+	common.BytesToAddress([]byte{204}): &stakeToLightning{},
+	common.BytesToAddress([]byte{205}): &unstakeFromLightning{},
+	common.BytesToAddress([]byte{206}): &stakeToEEN{},
+	common.BytesToAddress([]byte{207}): &unstakeFromEEN{},
+	common.BytesToAddress([]byte{208}): &ed25519Verify{},
+}
+
+// PrecompiledContractsStakeQuerySupport adds the read-only queries of the lightning and elite edge
+// node stakes and of the stake reward distribution rules to PrecompiledContractsCryptoSupport
+var PrecompiledContractsStakeQuerySupport = map[common.Address]PrecompiledContract{
+	common.BytesToAddress([]byte{1}):  &ecrecover{},
+	common.BytesToAddress([]byte{2}):  &sha256hash{},
+	common.BytesToAddress([]byte{3}):  &ripemd160hash{},
+	common.BytesToAddress([]byte{4}):  &dataCopy{},
+	common.BytesToAddress([]byte{5}):  &bigModExp{},
+	common.BytesToAddress([]byte{6}):  &bn256Add{},
+	common.BytesToAddress([]byte{7}):  &bn256ScalarMul{},
+	common.BytesToAddress([]byte{8}):  &bn256Pairing{},
+	common.BytesToAddress([]byte{11}): &bls12381G1Add{},
+	common.BytesToAddress([]byte{12}): &bls12381G1MultiExp{},
+	common.BytesToAddress([]byte{13}): &bls12381G2Add{},
+	common.BytesToAddress([]byte{14}): &bls12381G2MultiExp{},
+	common.BytesToAddress([]byte{15}): &bls12381Pairing{},
+	common.BytesToAddress([]byte{16}): &bls12381MapG1{},
+	common.BytesToAddress([]byte{17}): &bls12381MapG2{},
+
+	common.BytesToAddress([]byte{201}): &scriptBalance{},
+	common.BytesToAddress([]byte{202}): &scriptStake{},
+	common.BytesToAddress([]byte{203}): &transferScript{},
+	common.BytesToAddress([]byte{204}): &stakeToLightning{},
+	common.BytesToAddress([]byte{205}): &unstakeFromLightning{},
+	common.BytesToAddress([]byte{206}): &stakeToEEN{},
+	common.BytesToAddress([]byte{207}): &unstakeFromEEN{},
+	common.BytesToAddress([]byte{208}): &ed25519Verify{},
+	common.BytesToAddress([]byte{209}): &lightningStake{},
+	common.BytesToAddress([]byte{210}): &eenStake{},
+	common.BytesToAddress([]byte{211}): &stakeRewardDistribution{},
+}
+
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
 func RunPrecompiledContract(evm *EVM, p PrecompiledContract, input []byte, contract *Contract) (ret []byte, err error) {
 	blockHeight := evm.StateDB.GetBlockHeight()
@@ -366,6 +429,9 @@ var (
 
 	// errBadPairingInput is returned if the bn256 pairing input is invalid.
 	errBadPairingInput = errors.New("bad elliptic curve pairing size")
+
+	// errBadEd25519VerifyInput is returned if the ed25519 verification input is too short.
+	errBadEd25519VerifyInput = errors.New("bad ed25519 signature verification input size")
 )
 
 // bn256Pairing implements a pairing pre-compile for the bn256 curve
@@ -541,3 +607,103 @@ func (c *unstakeFromEEN) Run(evm *EVM, input []byte, callerAddr common.Address,
 	}
 	return common.Bytes{}, nil
 }
+
+// ed25519Verify verifies an ed25519 signature. The input is the 32 byte public key, followed by
+// the 64 byte signature and the message. The output is 32 bytes, with the last byte set to 1 if
+// the signature is valid, and 0 otherwise.
+type ed25519Verify struct {
+}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *ed25519Verify) RequiredGas(input []byte, blockHeight uint64) uint64 {
+	return uint64(len(input)+31)/32*params.Ed25519VerifyPerWordGas + params.Ed25519VerifyBaseGas
+}
+
+func (c *ed25519Verify) Run(evm *EVM, input []byte, callerAddr common.Address, contract *Contract) ([]byte, error) {
+	if len(input) < ed25519.PublicKeySize+ed25519.SignatureSize {
+		return nil, errBadEd25519VerifyInput
+	}
+	pubKey := ed25519.PublicKey(input[:ed25519.PublicKeySize])
+	sig := input[ed25519.PublicKeySize : ed25519.PublicKeySize+ed25519.SignatureSize]
+	msg := input[ed25519.PublicKeySize+ed25519.SignatureSize:]
+	if ed25519.Verify(pubKey, msg, sig) {
+		return true32Byte, nil
+	}
+	return false32Byte, nil
+}
+
+// lightningStake retrieves the total stake of a lightning node, and the stake of a source to it. The input
+// is the ABI encoding of (address lightning, address source), and the output is the ABI encoding of
+// (uint256 totalStake, uint256 amount, bool withdrawn, uint256 returnHeight).
+type lightningStake struct {
+}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *lightningStake) RequiredGas(input []byte, blockHeight uint64) uint64 {
+	return params.LightningStakeGas
+}
+
+func (c *lightningStake) Run(evm *EVM, input []byte, callerAddr common.Address, contract *Contract) ([]byte, error) {
+	lightningAddr := common.BytesToAddress(getData(input, 0, 32))
+	source := common.BytesToAddress(getData(input, 32, 32))
+	totalStake, stake := GetLightningStake(evm.StateDB, lightningAddr, source)
+	return encodeStakeInfo(totalStake, stake), nil
+}
+
+// eenStake retrieves the total stake of an elite edge node, and the stake of a source to it. The input
+// is the ABI encoding of (address een, address source), and the output is the ABI encoding of
+// (uint256 totalStake, uint256 amount, bool withdrawn, uint256 returnHeight).
+type eenStake struct {
+}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *eenStake) RequiredGas(input []byte, blockHeight uint64) uint64 {
+	return params.EENStakeGas
+}
+
+func (c *eenStake) Run(evm *EVM, input []byte, callerAddr common.Address, contract *Contract) ([]byte, error) {
+	eenAddr := common.BytesToAddress(getData(input, 0, 32))
+	source := common.BytesToAddress(getData(input, 32, 32))
+	totalStake, stake := GetEENStake(evm.StateDB, eenAddr, source)
+	return encodeStakeInfo(totalStake, stake), nil
+}
+
+// stakeRewardDistribution retrieves the reward distribution rule of a stake holder. The input is the ABI
+// encoding of (address holder), and the output is the ABI encoding of (address beneficiary, uint256 splitBasisPoint),
+// which is all zeros if the stake holder has not set any rule.
+type stakeRewardDistribution struct {
+}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *stakeRewardDistribution) RequiredGas(input []byte, blockHeight uint64) uint64 {
+	return params.StakeRewardDistributionGas
+}
+
+func (c *stakeRewardDistribution) Run(evm *EVM, input []byte, callerAddr common.Address, contract *Contract) ([]byte, error) {
+	holder := common.BytesToAddress(getData(input, 0, 32))
+	rd := GetStakeRewardDistribution(evm.StateDB, holder)
+	if rd == nil {
+		return make([]byte, 64), nil
+	}
+	ret := common.LeftPadBytes(rd.Beneficiary.Bytes(), 32)
+	ret = append(ret, common.LeftPadBytes(new(big.Int).SetUint64(uint64(rd.SplitBasisPoint)).Bytes(), 32)...)
+	return ret, nil
+}
+
+// encodeStakeInfo ABI-encodes the total stake of a stake holder, and the amount, the withdrawal status
+// and the return height of a stake to it. The return height is zero unless the stake is withdrawn.
+func encodeStakeInfo(totalStake *big.Int, stake *core.Stake) []byte {
+	ret := common.LeftPadBytes(totalStake.Bytes(), 32)
+	if stake == nil {
+		return append(ret, make([]byte, 96)...)
+	}
+	ret = append(ret, common.LeftPadBytes(stake.Amount.Bytes(), 32)...)
+	if stake.Withdrawn {
+		ret = append(ret, true32Byte...)
+		ret = append(ret, common.LeftPadBytes(new(big.Int).SetUint64(stake.ReturnHeight).Bytes(), 32)...)
+	} else {
+		ret = append(ret, false32Byte...)
+		ret = append(ret, make([]byte, 32)...)
+	}
+	return ret
+}
@@ -0,0 +1,481 @@
+package vm
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	bh "github.com/herumi/bls-eth-go-binary/bls"
+	kbls "github.com/kilic/bls12-381"
+
+	"github.com/scripttoken/script/common"
+	_ "github.com/scripttoken/script/crypto/bls" // initializes the BLS12-381 curve of the mcl library
+	"github.com/scripttoken/script/ledger/vm/params"
+)
+
+// The BLS12-381 pre-compiles follow the encoding of EIP-2537: a base field element is encoded
+// as 64 big-endian bytes whose top 16 bytes are zero, a G1 point as the 128 byte encoding of
+// its affine coordinates x and y, and a G2 point as the 256 byte encoding of x.c0, x.c1, y.c0
+// and y.c1. The point at infinity is encoded as all zeros. A scalar is encoded as 32 big-endian
+// bytes, and does not need to be less than the group order.
+//
+// The map-to-curve operations (MAP_FP_TO_G1 and MAP_FP2_TO_G2) use the simplified SWU mapping,
+// the isogeny and the cofactor clearing of the kilic library, since the mcl library does not
+// implement the mapping of RFC 9380 that EIP-2537 requires.
+
+const (
+	bls12381FieldElementLength = 64
+	bls12381G1PointLength      = 2 * bls12381FieldElementLength
+	bls12381G2PointLength      = 4 * bls12381FieldElementLength
+	bls12381ScalarLength       = 32
+)
+
+var (
+	// bls12381FieldModulus is the modulus p of the BLS12-381 base field
+	bls12381FieldModulus, _ = new(big.Int).SetString("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab", 16)
+
+	// bls12381GroupOrder is the order r of the BLS12-381 G1 and G2 subgroups
+	bls12381GroupOrder, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+)
+
+var (
+	errBLS12381InvalidInputLength          = errors.New("invalid input length")
+	errBLS12381InvalidFieldElementTopBytes = errors.New("invalid field element top bytes")
+	errBLS12381InvalidFieldElement         = errors.New("invalid field element, not less than the field modulus")
+	errBLS12381PointNotOnCurve             = errors.New("invalid point: not on curve")
+	errBLS12381G1PointSubgroup             = errors.New("g1 point is not on correct subgroup")
+	errBLS12381G2PointSubgroup             = errors.New("g2 point is not on correct subgroup")
+)
+
+// bls12381G1Add implements EIP-2537 G1Add precompile.
+type bls12381G1Add struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G1Add) RequiredGas(input []byte, blockHeight uint64) uint64 {
+	return params.Bls12381G1AddGas
+}
+
+func (c *bls12381G1Add) Run(evm *EVM, input []byte, callerAddr common.Address, contract *Contract) ([]byte, error) {
+	// Implements EIP-2537 G1Add precompile.
+	// > G1 addition call expects `256` bytes as an input that is interpreted as byte concatenation of two G1 points (`128` bytes each).
+	// > Output is an encoding of addition operation result - single G1 point (`128` bytes).
+	if len(input) != 2*bls12381G1PointLength {
+		return nil, errBLS12381InvalidInputLength
+	}
+	p0, err := decodeBLS12381G1Point(input[:bls12381G1PointLength])
+	if err != nil {
+		return nil, err
+	}
+	p1, err := decodeBLS12381G1Point(input[bls12381G1PointLength:])
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(bh.G1)
+	bh.G1Add(r, p0, p1)
+	return encodeBLS12381G1Point(r), nil
+}
+
+// bls12381G1MultiExp implements EIP-2537 G1MultiExp precompile.
+type bls12381G1MultiExp struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G1MultiExp) RequiredGas(input []byte, blockHeight uint64) uint64 {
+	k := len(input) / (bls12381G1PointLength + bls12381ScalarLength)
+	return bls12381MultiExpGas(k, params.Bls12381G1MulGas, params.Bls12381G1MultiExpDiscountTable)
+}
+
+func (c *bls12381G1MultiExp) Run(evm *EVM, input []byte, callerAddr common.Address, contract *Contract) ([]byte, error) {
+	// Implements EIP-2537 G1MultiExp precompile.
+	// > G1 multiplication call expects `160*k` bytes as an input that is interpreted as byte concatenation of `k` slices each of them being a byte concatenation of encoding of G1 point (`128` bytes) and encoding of a scalar value (`32` bytes).
+	// > Output is an encoding of multiexponentiation operation result - single G1 point (`128` bytes).
+	pairLength := bls12381G1PointLength + bls12381ScalarLength
+	k := len(input) / pairLength
+	if len(input) == 0 || len(input)%pairLength != 0 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	points := make([]bh.G1, 0, k)
+	scalars := make([]bh.Fr, 0, k)
+	for i := 0; i < k; i++ {
+		off := pairLength * i
+		p, err := decodeBLS12381G1SubgroupPoint(input[off : off+bls12381G1PointLength])
+		if err != nil {
+			return nil, err
+		}
+		e := decodeBLS12381Scalar(input[off+bls12381G1PointLength : off+pairLength])
+		if p.IsZero() || e.IsZero() {
+			continue
+		}
+		points = append(points, *p)
+		scalars = append(scalars, *e)
+	}
+
+	r := new(bh.G1)
+	if len(points) > 0 {
+		bh.G1MulVec(r, points, scalars)
+	}
+	return encodeBLS12381G1Point(r), nil
+}
+
+// bls12381G2Add implements EIP-2537 G2Add precompile.
+type bls12381G2Add struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G2Add) RequiredGas(input []byte, blockHeight uint64) uint64 {
+	return params.Bls12381G2AddGas
+}
+
+func (c *bls12381G2Add) Run(evm *EVM, input []byte, callerAddr common.Address, contract *Contract) ([]byte, error) {
+	// Implements EIP-2537 G2Add precompile.
+	// > G2 addition call expects `512` bytes as an input that is interpreted as byte concatenation of two G2 points (`256` bytes each).
+	// > Output is an encoding of addition operation result - single G2 point (`256` bytes).
+	if len(input) != 2*bls12381G2PointLength {
+		return nil, errBLS12381InvalidInputLength
+	}
+	p0, err := decodeBLS12381G2Point(input[:bls12381G2PointLength])
+	if err != nil {
+		return nil, err
+	}
+	p1, err := decodeBLS12381G2Point(input[bls12381G2PointLength:])
+	if err != nil {
+		return nil, err
+	}
+
+	r := new(bh.G2)
+	bh.G2Add(r, p0, p1)
+	return encodeBLS12381G2Point(r), nil
+}
+
+// bls12381G2MultiExp implements EIP-2537 G2MultiExp precompile.
+type bls12381G2MultiExp struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381G2MultiExp) RequiredGas(input []byte, blockHeight uint64) uint64 {
+	k := len(input) / (bls12381G2PointLength + bls12381ScalarLength)
+	return bls12381MultiExpGas(k, params.Bls12381G2MulGas, params.Bls12381G2MultiExpDiscountTable)
+}
+
+func (c *bls12381G2MultiExp) Run(evm *EVM, input []byte, callerAddr common.Address, contract *Contract) ([]byte, error) {
+	// Implements EIP-2537 G2MultiExp precompile.
+	// > G2 multiplication call expects `288*k` bytes as an input that is interpreted as byte concatenation of `k` slices each of them being a byte concatenation of encoding of G2 point (`256` bytes) and encoding of a scalar value (`32` bytes).
+	// > Output is an encoding of multiexponentiation operation result - single G2 point (`256` bytes).
+	pairLength := bls12381G2PointLength + bls12381ScalarLength
+	k := len(input) / pairLength
+	if len(input) == 0 || len(input)%pairLength != 0 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	points := make([]bh.G2, 0, k)
+	scalars := make([]bh.Fr, 0, k)
+	for i := 0; i < k; i++ {
+		off := pairLength * i
+		p, err := decodeBLS12381G2SubgroupPoint(input[off : off+bls12381G2PointLength])
+		if err != nil {
+			return nil, err
+		}
+		e := decodeBLS12381Scalar(input[off+bls12381G2PointLength : off+pairLength])
+		if p.IsZero() || e.IsZero() {
+			continue
+		}
+		points = append(points, *p)
+		scalars = append(scalars, *e)
+	}
+
+	r := new(bh.G2)
+	if len(points) > 0 {
+		bh.G2MulVec(r, points, scalars)
+	}
+	return encodeBLS12381G2Point(r), nil
+}
+
+// bls12381Pairing implements EIP-2537 Pairing precompile.
+type bls12381Pairing struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381Pairing) RequiredGas(input []byte, blockHeight uint64) uint64 {
+	k := len(input) / (bls12381G1PointLength + bls12381G2PointLength)
+	return params.Bls12381PairingBaseGas + uint64(k)*params.Bls12381PairingPerPairGas
+}
+
+func (c *bls12381Pairing) Run(evm *EVM, input []byte, callerAddr common.Address, contract *Contract) ([]byte, error) {
+	// Implements EIP-2537 Pairing precompile logic.
+	// > Pairing call expects `384*k` bytes as an inputs that is interpreted as byte concatenation of `k` slices. Each slice has the following structure:
+	// > - `128` bytes of G1 point encoding
+	// > - `256` bytes of G2 point encoding
+	// > Output is a `32` bytes where last single byte is `0x01` if pairing result is equal to multiplicative identity in a pairing target field and `0x00` otherwise
+	// > (which is equivalent of Big Endian encoding of Solidity values `uint256(1)` and `uin256(0)` respectively).
+	pairLength := bls12381G1PointLength + bls12381G2PointLength
+	k := len(input) / pairLength
+	if len(input) == 0 || len(input)%pairLength != 0 {
+		return nil, errBLS12381InvalidInputLength
+	}
+	g1s := make([]bh.G1, 0, k)
+	g2s := make([]bh.G2, 0, k)
+	for i := 0; i < k; i++ {
+		off := pairLength * i
+		p1, err := decodeBLS12381G1SubgroupPoint(input[off : off+bls12381G1PointLength])
+		if err != nil {
+			return nil, err
+		}
+		p2, err := decodeBLS12381G2SubgroupPoint(input[off+bls12381G1PointLength : off+pairLength])
+		if err != nil {
+			return nil, err
+		}
+		// The pairing with the point at infinity is the identity
+		if p1.IsZero() || p2.IsZero() {
+			continue
+		}
+		g1s = append(g1s, *p1)
+		g2s = append(g2s, *p2)
+	}
+	if len(g1s) == 0 {
+		return true32Byte, nil
+	}
+
+	e := new(bh.GT)
+	bh.MillerLoopVec(e, g1s, g2s)
+	bh.FinalExp(e, e)
+	if e.IsOne() {
+		return true32Byte, nil
+	}
+	return false32Byte, nil
+}
+
+// bls12381MapG1 implements EIP-2537 MapG1 precompile.
+type bls12381MapG1 struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381MapG1) RequiredGas(input []byte, blockHeight uint64) uint64 {
+	return params.Bls12381MapG1Gas
+}
+
+func (c *bls12381MapG1) Run(evm *EVM, input []byte, callerAddr common.Address, contract *Contract) ([]byte, error) {
+	// Implements EIP-2537 Map_To_G1 precompile.
+	// > Field-to-curve call expects an `64` bytes input that is interpreted as an element of the base field.
+	// > Output of this call is `128` bytes and is G1 point following respective encoding rules.
+	if len(input) != bls12381FieldElementLength {
+		return nil, errBLS12381InvalidInputLength
+	}
+	fe, err := decodeBLS12381FieldElementBytes(input)
+	if err != nil {
+		return nil, err
+	}
+
+	g := kbls.NewG1()
+	r, err := g.MapToCurve(fe)
+	if err != nil {
+		return nil, err
+	}
+	// The kilic library serializes the affine coordinates as x | y, 48 bytes each
+	return encodeBLS12381FieldElements(g.ToBytes(r), 0, 1), nil
+}
+
+// bls12381MapG2 implements EIP-2537 MapG2 precompile.
+type bls12381MapG2 struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bls12381MapG2) RequiredGas(input []byte, blockHeight uint64) uint64 {
+	return params.Bls12381MapG2Gas
+}
+
+func (c *bls12381MapG2) Run(evm *EVM, input []byte, callerAddr common.Address, contract *Contract) ([]byte, error) {
+	// Implements EIP-2537 Map_FP2_TO_G2 precompile logic.
+	// > Field-to-curve call expects an `128` bytes input that is interpreted as an element of the quadratic extension field.
+	// > Output of this call is `256` bytes and is G2 point following respective encoding rules.
+	if len(input) != 2*bls12381FieldElementLength {
+		return nil, errBLS12381InvalidInputLength
+	}
+	c0, err := decodeBLS12381FieldElementBytes(input[:bls12381FieldElementLength])
+	if err != nil {
+		return nil, err
+	}
+	c1, err := decodeBLS12381FieldElementBytes(input[bls12381FieldElementLength:])
+	if err != nil {
+		return nil, err
+	}
+
+	// The kilic library expects an element of the quadratic extension field as c1 | c0
+	g := kbls.NewG2()
+	r, err := g.MapToCurve(append(append([]byte{}, c1...), c0...))
+	if err != nil {
+		return nil, err
+	}
+	// and serializes the affine coordinates as x.c1 | x.c0 | y.c1 | y.c0
+	return encodeBLS12381FieldElements(g.ToBytes(r), 1, 0, 3, 2), nil
+}
+
+// bls12381MultiExpGas calculates the gas of a multi exponentiation of k points, discounted
+// according to the number of points
+func bls12381MultiExpGas(k int, mulGas uint64, discountTable [128]uint64) uint64 {
+	if k == 0 {
+		return 0
+	}
+	var discount uint64
+	if dLen := len(discountTable); k < dLen {
+		discount = discountTable[k-1]
+	} else {
+		discount = discountTable[dLen-1]
+	}
+	return (uint64(k) * mulGas * discount) / 1000
+}
+
+// decodeBLS12381FieldElementBytes decodes a base field element into its 48 byte big-endian representation
+func decodeBLS12381FieldElementBytes(in []byte) ([]byte, error) {
+	for _, b := range in[:16] {
+		if b != 0 {
+			return nil, errBLS12381InvalidFieldElementTopBytes
+		}
+	}
+	if new(big.Int).SetBytes(in[16:]).Cmp(bls12381FieldModulus) >= 0 {
+		return nil, errBLS12381InvalidFieldElement
+	}
+	return in[16:], nil
+}
+
+// decodeBLS12381FieldElement decodes a base field element into its hexadecimal representation
+func decodeBLS12381FieldElement(in []byte) (string, error) {
+	fe, err := decodeBLS12381FieldElementBytes(in)
+	if err != nil {
+		return "", err
+	}
+	return new(big.Int).SetBytes(fe).Text(16), nil
+}
+
+// encodeBLS12381FieldElements pads the 48 byte base field elements of in, taken in the given
+// order, to 64 bytes each
+func encodeBLS12381FieldElements(in []byte, order ...int) []byte {
+	out := make([]byte, len(order)*bls12381FieldElementLength)
+	for i, j := range order {
+		copy(out[i*bls12381FieldElementLength+16:(i+1)*bls12381FieldElementLength], in[j*48:(j+1)*48])
+	}
+	return out
+}
+
+// decodeBLS12381Coordinates decodes the base field elements of a point. It returns nil
+// if the point is the point at infinity.
+func decodeBLS12381Coordinates(in []byte) ([]string, error) {
+	isInfinity := true
+	for _, b := range in {
+		if b != 0 {
+			isInfinity = false
+			break
+		}
+	}
+	if isInfinity {
+		return nil, nil
+	}
+	coords := []string{}
+	for off := 0; off < len(in); off += bls12381FieldElementLength {
+		coord, err := decodeBLS12381FieldElement(in[off : off+bls12381FieldElementLength])
+		if err != nil {
+			return nil, err
+		}
+		coords = append(coords, coord)
+	}
+	return coords, nil
+}
+
+// encodeBLS12381Coordinates encodes the point string of the mcl library, i.e. "0" for the
+// point at infinity, or "1" followed by the hexadecimal affine coordinates
+func encodeBLS12381Coordinates(pointStr string, length int) []byte {
+	out := make([]byte, length)
+	fields := strings.Fields(pointStr)
+	if len(fields) == 0 || fields[0] == "0" {
+		return out
+	}
+	for i, field := range fields[1:] {
+		v, _ := new(big.Int).SetString(field, 16)
+		copy(out[i*bls12381FieldElementLength:], common.LeftPadBytes(v.Bytes(), bls12381FieldElementLength))
+	}
+	return out
+}
+
+// decodeBLS12381G1Point decodes a G1 point, which is checked to be on the curve
+func decodeBLS12381G1Point(in []byte) (*bh.G1, error) {
+	coords, err := decodeBLS12381Coordinates(in)
+	if err != nil {
+		return nil, err
+	}
+	p := new(bh.G1)
+	if coords == nil {
+		p.Clear()
+		return p, nil
+	}
+	if err := p.SetString("1 "+strings.Join(coords, " "), 16); err != nil {
+		return nil, errBLS12381PointNotOnCurve
+	}
+	return p, nil
+}
+
+// decodeBLS12381G1SubgroupPoint decodes a G1 point, which is checked to be in the subgroup of order r
+func decodeBLS12381G1SubgroupPoint(in []byte) (*bh.G1, error) {
+	p, err := decodeBLS12381G1Point(in)
+	if err != nil {
+		return nil, err
+	}
+	// Multiplies by r bit by bit, since the scalar multiplication of the mcl library
+	// reduces the scalar modulo r
+	acc := new(bh.G1)
+	for i := bls12381GroupOrder.BitLen() - 1; i >= 0; i-- {
+		bh.G1Dbl(acc, acc)
+		if bls12381GroupOrder.Bit(i) == 1 {
+			bh.G1Add(acc, acc, p)
+		}
+	}
+	if !acc.IsZero() {
+		return nil, errBLS12381G1PointSubgroup
+	}
+	return p, nil
+}
+
+func encodeBLS12381G1Point(p *bh.G1) []byte {
+	return encodeBLS12381Coordinates(p.GetString(16), bls12381G1PointLength)
+}
+
+// decodeBLS12381G2Point decodes a G2 point, which is checked to be on the curve
+func decodeBLS12381G2Point(in []byte) (*bh.G2, error) {
+	coords, err := decodeBLS12381Coordinates(in)
+	if err != nil {
+		return nil, err
+	}
+	p := new(bh.G2)
+	if coords == nil {
+		p.Clear()
+		return p, nil
+	}
+	if err := p.SetString("1 "+strings.Join(coords, " "), 16); err != nil {
+		return nil, errBLS12381PointNotOnCurve
+	}
+	return p, nil
+}
+
+// decodeBLS12381G2SubgroupPoint decodes a G2 point, which is checked to be in the subgroup of order r
+func decodeBLS12381G2SubgroupPoint(in []byte) (*bh.G2, error) {
+	p, err := decodeBLS12381G2Point(in)
+	if err != nil {
+		return nil, err
+	}
+	acc := new(bh.G2)
+	for i := bls12381GroupOrder.BitLen() - 1; i >= 0; i-- {
+		bh.G2Dbl(acc, acc)
+		if bls12381GroupOrder.Bit(i) == 1 {
+			bh.G2Add(acc, acc, p)
+		}
+	}
+	if !acc.IsZero() {
+		return nil, errBLS12381G2PointSubgroup
+	}
+	return p, nil
+}
+
+func encodeBLS12381G2Point(p *bh.G2) []byte {
+	return encodeBLS12381Coordinates(p.GetString(16), bls12381G2PointLength)
+}
+
+// decodeBLS12381Scalar decodes a scalar, reduced modulo the group order r
+func decodeBLS12381Scalar(in []byte) *bh.Fr {
+	v := new(big.Int).SetBytes(in)
+	v.Mod(v, bls12381GroupOrder)
+	e := new(bh.Fr)
+	e.SetString(v.Text(16), 16)
+	return e
+}
@@ -22,6 +22,8 @@ import (
 	"testing"
 
 	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/ledger/state"
+	"github.com/scripttoken/script/store/database/backend"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -336,13 +338,20 @@ var bn256PairingTests = []precompiledTest{
 	},
 }
 
+// newPrecompiledTestEVM returns an EVM backed by an empty in-memory state, since
+// RunPrecompiledContract reads the block height from the state
+func newPrecompiledTestEVM() *EVM {
+	storeView := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	return NewEVM(Context{}, storeView, nil, Config{})
+}
+
 func testPrecompiled(addr string, test precompiledTest, t *testing.T) {
 	p := PrecompiledContractsByzantium[common.HexToAddress(addr)]
 	in := common.Hex2Bytes(test.input)
 	contract := NewContract(AccountRef(common.HexToAddress("1337")),
-		nil, new(big.Int), new(big.Int), p.RequiredGas(in))
+		nil, new(big.Int), new(big.Int), p.RequiredGas(in, 0))
 	t.Run(fmt.Sprintf("%s-Gas=%d", test.name, contract.Gas), func(t *testing.T) {
-		if res, err := RunPrecompiledContract(nil, p, in, contract); err != nil {
+		if res, err := RunPrecompiledContract(newPrecompiledTestEVM(), p, in, contract); err != nil {
 			t.Error(err)
 		} else if common.Bytes2Hex(res) != test.expected {
 			t.Errorf("Expected %v, got %v", test.expected, common.Bytes2Hex(res))
@@ -356,7 +365,7 @@ func benchmarkPrecompiled(addr string, test precompiledTest, bench *testing.B) {
 	}
 	p := PrecompiledContractsByzantium[common.HexToAddress(addr)]
 	in := common.Hex2Bytes(test.input)
-	reqGas := p.RequiredGas(in)
+	reqGas := p.RequiredGas(in, 0)
 	contract := NewContract(AccountRef(common.HexToAddress("1337")),
 		nil, new(big.Int), new(big.Int), reqGas)
 
@@ -364,6 +373,7 @@ func benchmarkPrecompiled(addr string, test precompiledTest, bench *testing.B) {
 		res  []byte
 		err  error
 		data = make([]byte, len(in))
+		evm  = newPrecompiledTestEVM()
 	)
 
 	bench.Run(fmt.Sprintf("%s-Gas=%d", test.name, contract.Gas), func(bench *testing.B) {
@@ -371,7 +381,7 @@ func benchmarkPrecompiled(addr string, test precompiledTest, bench *testing.B) {
 		for i := 0; i < bench.N; i++ {
 			contract.Gas = reqGas
 			copy(data, in)
-			res, err = RunPrecompiledContract(nil, p, data, contract)
+			res, err = RunPrecompiledContract(evm, p, data, contract)
 		}
 		bench.StopTimer()
 		//Check if it is correct
@@ -481,3 +491,47 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+// Tests the ed25519 signature verification with the test vectors of RFC 8032.
+func TestPrecompiledEd25519Verify(t *testing.T) {
+	tests := []struct {
+		pubKey, sig, msg string
+		expected         []byte
+	}{
+		{ // RFC 8032 TEST 1
+			pubKey:   "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+			sig:      "e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b",
+			msg:      "",
+			expected: true32Byte,
+		},
+		{ // RFC 8032 TEST 2
+			pubKey:   "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+			sig:      "92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00",
+			msg:      "72",
+			expected: true32Byte,
+		},
+		{ // RFC 8032 TEST 2 with a different message
+			pubKey:   "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+			sig:      "92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00",
+			msg:      "73",
+			expected: false32Byte,
+		},
+	}
+	p := &ed25519Verify{}
+	for i, test := range tests {
+		in := common.Hex2Bytes(test.pubKey + test.sig + test.msg)
+		res, err := p.Run(nil, in, common.Address{}, nil)
+		if err != nil {
+			t.Errorf("test %v: %v", i, err)
+		} else if common.Bytes2Hex(res) != common.Bytes2Hex(test.expected) {
+			t.Errorf("test %v: expected %x, got %x", i, test.expected, res)
+		}
+	}
+
+	if _, err := p.Run(nil, make([]byte, 95), common.Address{}, nil); err != errBadEd25519VerifyInput {
+		t.Errorf("Expected %v, got %v", errBadEd25519VerifyInput, err)
+	}
+	if gas := p.RequiredGas(make([]byte, 97), 0); gas != 3000+4*12 {
+		t.Errorf("Expected gas %v, got %v", 3000+4*12, gas)
+	}
+}
@@ -0,0 +1,351 @@
+package vm
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/ledger/state"
+	"github.com/scripttoken/script/ledger/types"
+	"github.com/scripttoken/script/ledger/vm/params"
+	"github.com/scripttoken/script/store/database/backend"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	eipTestOrigin   = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+	eipTestContract = common.HexToAddress("0x000000000000000000000000000000000000cccc")
+)
+
+func newEIPTestChainConfig(berlin, london, shanghai, cancun bool) *params.ChainConfig {
+	forkBlock := func(enabled bool) *big.Int {
+		if enabled {
+			return big.NewInt(0)
+		}
+		return nil
+	}
+	return &params.ChainConfig{
+		ChainID:       big.NewInt(0),
+		BerlinBlock:   forkBlock(berlin),
+		LondonBlock:   forkBlock(london),
+		ShanghaiBlock: forkBlock(shanghai),
+		CancunBlock:   forkBlock(cancun),
+	}
+}
+
+// runEIPTestCode deploys the code at the test contract address, calls it from the test
+// origin, and returns the output and the gas consumed by the execution
+func runEIPTestCode(storeView *state.StoreView, chainConfig *params.ChainConfig, code string) ([]byte, uint64, error) {
+	bytecode, err := hex.DecodeString(code)
+	if err != nil {
+		panic(err)
+	}
+	storeView.SetCode(eipTestContract, bytecode)
+
+	context := Context{
+		CanTransfer: CanTransfer,
+		Transfer:    Transfer,
+		Origin:      eipTestOrigin,
+		GasPrice:    big.NewInt(1),
+		GasLimit:    10000000,
+		BlockNumber: big.NewInt(1),
+		Time:        big.NewInt(0),
+		Difficulty:  big.NewInt(0),
+		BaseFee:     big.NewInt(7),
+	}
+	evm := NewEVM(context, storeView, chainConfig, Config{})
+	evm.PrepareAccessList(eipTestOrigin, &eipTestContract, nil)
+
+	gas := uint64(1000000)
+	ret, leftOverGas, err := evm.Call(AccountRef(eipTestOrigin), eipTestContract, nil, gas, big.NewInt(0), big.NewInt(0))
+	return ret, gas - leftOverGas, err
+}
+
+// TestEIP2929Cases ports the test cases of EIP-2929, the expected gas excludes the intrinsic gas
+func TestEIP2929Cases(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		description string
+		code        string
+		expectedGas uint64
+	}{
+		{
+			"extcodehash, extcodesize and balance of precompiles, cold and warm addresses",
+			"60013f5060023b506003315060f13f5060f23b5060f3315060f23f5060f33b5060f1315032315030315000",
+			8653,
+		},
+		{
+			"extcodecopy of cold, warm and own address",
+			"60006000600060ff3c60006000600060ff3c600060006000303c00",
+			2835,
+		},
+		{
+			"sload and sstore of cold and warm slots",
+			"60015450601160015560116002556011600255600254600154",
+			44529,
+		},
+		{
+			"call of a precompile, a cold and a warm address",
+			"60008080808060046000f15060008080808060ff6000f15060008080808060ff6000fa50",
+			2869,
+		},
+	}
+
+	for _, tc := range testCases {
+		storeView := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+		_, gasUsed, err := runEIPTestCode(storeView, newEIPTestChainConfig(true, false, false, false), tc.code)
+		assert.Nil(err, tc.description)
+		assert.Equal(tc.expectedGas, gasUsed, tc.description)
+	}
+}
+
+func TestEIP3529SstoreRefund(t *testing.T) {
+	assert := assert.New(t)
+
+	// push 0x0, push 0x0, sstore: clears the storage slot 0
+	code := "6000600055"
+	slot := common.Hash{}
+	value := common.BigToHash(big.NewInt(1))
+
+	storeView := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	storeView.SetState(eipTestContract, slot, value)
+	_, gasUsed, err := runEIPTestCode(storeView, newEIPTestChainConfig(true, false, false, false), code)
+	assert.Nil(err)
+	assert.Equal(uint64(6+params.ColdSloadCostEIP2929+params.SstoreResetGasEIP2200-params.ColdSloadCostEIP2929), gasUsed)
+	assert.Equal(params.NetSstoreClearRefund, storeView.GetRefund())
+
+	storeView = state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	storeView.SetState(eipTestContract, slot, value)
+	_, _, err = runEIPTestCode(storeView, newEIPTestChainConfig(true, true, false, false), code)
+	assert.Nil(err)
+	assert.Equal(params.SstoreClearsScheduleRefundEIP3529, storeView.GetRefund())
+}
+
+func TestEIP3198BaseFee(t *testing.T) {
+	assert := assert.New(t)
+
+	// basefee, push 0x0, mstore, push 0x20, push 0x0, return
+	code := "4860005260206000f3"
+
+	storeView := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	ret, _, err := runEIPTestCode(storeView, newEIPTestChainConfig(true, true, false, false), code)
+	assert.Nil(err)
+	assert.Equal(big.NewInt(7), new(big.Int).SetBytes(ret))
+
+	storeView = state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	_, _, err = runEIPTestCode(storeView, newEIPTestChainConfig(true, false, false, false), code)
+	assert.NotNil(err) // BASEFEE is not available before London
+}
+
+func TestEIP3541RejectEFCode(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		initCode    string
+		london      bool
+		expectedErr error
+	}{
+		{"60ef60005360016000f3", true, ErrInvalidCode}, // deploys 0xef
+		{"60ef60005360026000f3", true, ErrInvalidCode}, // deploys 0xef00
+		{"60fe60005360016000f3", true, nil},            // deploys 0xfe
+		{"60ef60005360016000f3", false, nil},           // deploys 0xef before London
+		{"60006000f3", true, nil},                      // deploys empty code
+	}
+
+	for _, tc := range testCases {
+		storeView := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+		context := Context{
+			CanTransfer: CanTransfer,
+			Transfer:    Transfer,
+			Origin:      eipTestOrigin,
+			BlockNumber: big.NewInt(1),
+			Time:        big.NewInt(0),
+			Difficulty:  big.NewInt(0),
+		}
+		evm := NewEVM(context, storeView, newEIPTestChainConfig(true, tc.london, false, false), Config{})
+
+		initCode, _ := hex.DecodeString(tc.initCode)
+		_, _, _, err := evm.Create(AccountRef(eipTestOrigin), initCode, 100000, big.NewInt(0), big.NewInt(0))
+		assert.Equal(tc.expectedErr, err, tc.initCode)
+	}
+}
+
+func TestEIP3855Push0(t *testing.T) {
+	assert := assert.New(t)
+
+	// push 0x1, push0, sstore: sets the storage slot 0 to 1
+	code := "60015f55"
+
+	storeView := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	_, gasUsed, err := runEIPTestCode(storeView, newEIPTestChainConfig(true, true, true, false), code)
+	assert.Nil(err)
+	assert.Equal(uint64(3+2+params.ColdSloadCostEIP2929+params.SstoreSetGasEIP2200), gasUsed)
+	assert.Equal(common.BigToHash(big.NewInt(1)), storeView.GetState(eipTestContract, common.Hash{}))
+
+	storeView = state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	_, _, err = runEIPTestCode(storeView, newEIPTestChainConfig(true, true, false, false), code)
+	assert.NotNil(err) // PUSH0 is not available before Shanghai
+}
+
+func TestEIP3860InitCodeGas(t *testing.T) {
+	assert := assert.New(t)
+
+	// push 0x40, push 0x0, push 0x0, create: creates a contract with 64 zero bytes of init code
+	code := "604060006000f0"
+
+	storeView := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	_, gasShanghai, err := runEIPTestCode(storeView, newEIPTestChainConfig(true, true, true, false), code)
+	assert.Nil(err)
+
+	storeView = state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	_, gasLondon, err := runEIPTestCode(storeView, newEIPTestChainConfig(true, true, false, false), code)
+	assert.Nil(err)
+	assert.Equal(gasLondon+2*params.InitCodeWordGas, gasShanghai)
+}
+
+// TestEIP5656Mcopy ports the test vectors of EIP-5656
+func TestEIP5656Mcopy(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		dst, src, length string
+		pre              string
+		expected         string
+		expectedGas      uint64
+	}{
+		{
+			"00", "20", "20",
+			"0000000000000000000000000000000000000000000000000000000000000000000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			6,
+		},
+		{
+			"00", "00", "20",
+			"0101010101010101010101010101010101010101010101010101010101010101",
+			"0101010101010101010101010101010101010101010101010101010101010101",
+			6,
+		},
+		{
+			"00", "01", "08",
+			"0001020304050607080000000000000000000000000000000000000000000000",
+			"0102030405060708080000000000000000000000000000000000000000000000",
+			6,
+		},
+		{
+			"01", "00", "08",
+			"0001020304050607080000000000000000000000000000000000000000000000",
+			"0000010203040506070000000000000000000000000000000000000000000000",
+			6,
+		},
+	}
+
+	for _, tc := range testCases {
+		pre, _ := hex.DecodeString(tc.pre)
+		expected, _ := hex.DecodeString(tc.expected)
+
+		// Store the pre memory with the word sized mstore operations, which is not
+		// included in the gas of the MCOPY operation
+		code := ""
+		for offset := 0; offset < len(pre); offset += 32 {
+			word := make([]byte, 32)
+			copy(word, pre[offset:])
+			code += "7f" + hex.EncodeToString(word) + "61" + hex.EncodeToString([]byte{byte(offset >> 8), byte(offset)}) + "52"
+		}
+		prefixCode := code
+		code += "60" + tc.length + "60" + tc.src + "60" + tc.dst + "5e"
+		memorySize := (len(pre) + 31) / 32 * 32
+		returnCode := "61" + hex.EncodeToString([]byte{byte(memorySize >> 8), byte(memorySize)}) + "6000f3"
+
+		storeView := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+		ret, gasUsed, err := runEIPTestCode(storeView, newEIPTestChainConfig(true, true, true, true), code+returnCode)
+		assert.Nil(err)
+		assert.Equal(expected, ret[:len(expected)], tc.expected)
+
+		storeView = state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+		_, prefixGasUsed, err := runEIPTestCode(storeView, newEIPTestChainConfig(true, true, true, true), prefixCode+returnCode)
+		assert.Nil(err)
+		assert.Equal(tc.expectedGas+9, gasUsed-prefixGasUsed, tc.expected) // 9 for the three pushes
+	}
+}
+
+func TestEIP1153TransientStorage(t *testing.T) {
+	assert := assert.New(t)
+
+	// push 0x1, push 0xa, tstore, push 0xa, tload, push 0x0, mstore, push 0x20, push 0x0, return
+	code := "6001600a5d600a5c60005260206000f3"
+
+	storeView := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	ret, gasUsed, err := runEIPTestCode(storeView, newEIPTestChainConfig(true, true, true, true), code)
+	assert.Nil(err)
+	assert.Equal(big.NewInt(1), new(big.Int).SetBytes(ret))
+	assert.Equal(uint64(3*6+2*params.TransientStorageGas+3+3), gasUsed) // 6 pushes, tstore, tload, mstore and memory
+	assert.Equal(common.Hash{}, storeView.GetState(eipTestContract, common.BigToHash(big.NewInt(10))))
+
+	// The transient storage is reverted along with the state
+	ts := newTxState()
+	key := common.BigToHash(big.NewInt(10))
+	ts.SetTransientState(eipTestContract, key, common.BigToHash(big.NewInt(1)))
+	snapshot := ts.Snapshot()
+	ts.SetTransientState(eipTestContract, key, common.BigToHash(big.NewInt(2)))
+	assert.Equal(common.BigToHash(big.NewInt(2)), ts.GetTransientState(eipTestContract, key))
+	ts.RevertToSnapshot(snapshot)
+	assert.Equal(common.BigToHash(big.NewInt(1)), ts.GetTransientState(eipTestContract, key))
+
+	storeView = state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	_, _, err = runEIPTestCode(storeView, newEIPTestChainConfig(true, true, true, false), code)
+	assert.NotNil(err) // TSTORE is not available before Cancun
+}
+
+func TestEIP6780Selfdestruct(t *testing.T) {
+	assert := assert.New(t)
+
+	// push 0xbb, selfdestruct
+	code := "60bbff"
+	beneficiary := common.BytesToAddress([]byte{0xbb})
+	balance := big.NewInt(1000)
+
+	for _, cancun := range []bool{true, false} {
+		storeView := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+		storeView.AddBalance(eipTestContract, balance)
+		_, _, err := runEIPTestCode(storeView, newEIPTestChainConfig(true, true, true, cancun), code)
+		assert.Nil(err)
+		assert.Equal(0, balance.Cmp(storeView.GetBalance(beneficiary)))
+		assert.Equal(0, storeView.GetBalance(eipTestContract).Sign())
+		assert.Equal(!cancun, storeView.HasSuicided(eipTestContract))
+	}
+}
+
+func TestExecuteWithAccessList(t *testing.T) {
+	assert := assert.New(t)
+
+	storeView := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	// push 0x0, sload
+	code, _ := hex.DecodeString("600054")
+	storeView.SetCode(eipTestContract, code)
+
+	tx := &types.SmartContractTx{
+		From: types.TxInput{
+			Address: eipTestOrigin,
+			Coins:   types.NewCoins(0, 0),
+		},
+		To: types.TxOutput{
+			Address: eipTestContract,
+		},
+		GasLimit: 100000,
+		GasPrice: big.NewInt(1),
+	}
+	parentBlockInfo := NewBlockInfo(common.HeightEnableEVMBerlin-1, big.NewInt(0), "testnet")
+
+	_, _, gasUsed, err := ExecuteWithAccessList(parentBlockInfo, tx, nil, storeView)
+	assert.Nil(err)
+	assert.Equal(params.TxGas+3+params.ColdSloadCostEIP2929, gasUsed)
+
+	accessList := types.AccessList{
+		{Address: eipTestContract, StorageKeys: []common.Hash{{}}},
+	}
+	_, _, gasUsed, err = ExecuteWithAccessList(parentBlockInfo, tx, accessList, storeView)
+	assert.Nil(err)
+	assert.Equal(params.TxGas+params.TxAccessListAddressGas+params.TxAccessListStorageKeyGas+3+params.WarmStorageReadCostEIP2929, gasUsed)
+}
@@ -30,4 +30,6 @@ var (
 	ErrInvalidGasLimit          = errors.New("invalid gas limit")
 	ErrInsufficientScriptBlance  = errors.New("insufficient Script balance for transfer")
 	ErrInvalidStakeOperation    = errors.New("invalid stake operation")
+	ErrInvalidCode              = errors.New("invalid code: must not begin with 0xef")
+	ErrMaxInitCodeSizeExceeded  = errors.New("max initcode size exceeded")
 )
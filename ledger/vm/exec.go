@@ -5,6 +5,7 @@ import (
 	"math/big"
 
 	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/ledger/state"
 	"github.com/scripttoken/script/ledger/types"
 	"github.com/scripttoken/script/ledger/vm/params"
 )
@@ -26,6 +27,30 @@ func NewBlockInfo(height uint64, timestamp *big.Int, chainID string) *BlockInfo
 // Execute executes the given smart contract
 func Execute(parentBlockInfo *BlockInfo, tx *types.SmartContractTx, statedb StateDB) (evmRet common.Bytes,
 	contractAddr common.Address, gasUsed uint64, evmErr error) {
+	return ExecuteWithAccessList(parentBlockInfo, tx, nil, statedb)
+}
+
+// ExecuteWithAccessList executes the given smart contract with the addresses and storage
+// slots of the access list (EIP-2930) warmed up
+func ExecuteWithAccessList(parentBlockInfo *BlockInfo, tx *types.SmartContractTx, accessList types.AccessList,
+	statedb StateDB) (evmRet common.Bytes, contractAddr common.Address, gasUsed uint64, evmErr error) {
+	return executeWithChainConfig(newChainConfig(), parentBlockInfo, tx, accessList, statedb)
+}
+
+// newChainConfig returns the chain config with the EVM upgrades activated at their fork heights
+func newChainConfig() *params.ChainConfig {
+	//chainIDBigInt := types.MapChainID(parentBlockInfo.ChainID, context.BlockNumber.Uint64())
+	return &params.ChainConfig{
+		ChainID:       big.NewInt(0), //chainIDBigInt,
+		BerlinBlock:   new(big.Int).SetUint64(common.HeightEnableEVMBerlin),
+		LondonBlock:   new(big.Int).SetUint64(common.HeightEnableEVMLondon),
+		ShanghaiBlock: new(big.Int).SetUint64(common.HeightEnableEVMShanghai),
+		CancunBlock:   new(big.Int).SetUint64(common.HeightEnableEVMCancun),
+	}
+}
+
+func executeWithChainConfig(chainConfig *params.ChainConfig, parentBlockInfo *BlockInfo, tx *types.SmartContractTx,
+	accessList types.AccessList, statedb StateDB) (evmRet common.Bytes, contractAddr common.Address, gasUsed uint64, evmErr error) {
 	context := Context{
 		CanTransfer: CanTransfer,
 		Transfer:    Transfer,
@@ -36,9 +61,8 @@ func Execute(parentBlockInfo *BlockInfo, tx *types.SmartContractTx, statedb Stat
 		Time:        parentBlockInfo.Timestamp,
 		Difficulty:  new(big.Int).SetInt64(0),
 	}
-	//chainIDBigInt := types.MapChainID(parentBlockInfo.ChainID, context.BlockNumber.Uint64())
-	chainConfig := &params.ChainConfig{
-		ChainID: big.NewInt(0), //chainIDBigInt,
+	if view, ok := statedb.(*state.StoreView); ok {
+		context.BaseFee = view.GetBaseFee()
 	}
 	config := Config{}
 	evm := NewEVM(context, statedb, chainConfig, config)
@@ -70,14 +94,32 @@ func Execute(parentBlockInfo *BlockInfo, tx *types.SmartContractTx, statedb Stat
 		return common.Bytes{}, common.Address{}, 0, ErrInvalidGasLimit
 	}
 
-	intrinsicGas, err := CalculateIntrinsicGas(tx.Data, createContract)
+	rules := chainConfig.Rules(context.BlockNumber)
+	if rules.IsShanghai && createContract && len(tx.Data) > GetMaxInitCodeSize(blockHeight) {
+		return common.Bytes{}, common.Address{}, 0, ErrMaxInitCodeSizeExceeded
+	}
+
+	intrinsicGas, err := CalculateIntrinsicGas(tx.Data, createContract, rules.IsBerlin)
 	if err != nil {
 		return common.Bytes{}, common.Address{}, 0, err
 	}
+	if rules.IsBerlin {
+		intrinsicGas += CalculateAccessListGas(accessList)
+	}
+	if rules.IsShanghai && createContract {
+		intrinsicGas += toWordSize(uint64(len(tx.Data))) * params.InitCodeWordGas
+	}
 	if intrinsicGas > gasLimit {
 		return common.Bytes{}, common.Address{}, 0, ErrOutOfGas
 	}
 
+	if createContract {
+		evm.PrepareAccessList(fromAddr, nil, accessList)
+	} else {
+		evm.PrepareAccessList(fromAddr, &contractAddr, accessList)
+	}
+	refundBefore := statedb.GetRefund()
+
 	var leftOverGas uint64
 	remainingGas := gasLimit - intrinsicGas
 	if createContract {
@@ -94,11 +136,27 @@ func Execute(parentBlockInfo *BlockInfo, tx *types.SmartContractTx, statedb Stat
 		gasUsed = gasLimit - leftOverGas
 	}
 
+	// Refund the gas of the cleared storage slots, at most a fifth of the gas used (EIP-3529)
+	if refund := statedb.GetRefund(); rules.IsLondon && refund > refundBefore {
+		refund -= refundBefore
+		if refund > gasUsed/params.RefundQuotientEIP3529 {
+			refund = gasUsed / params.RefundQuotientEIP3529
+		}
+		gasUsed -= refund
+	}
+
 	return evmRet, contractAddr, gasUsed, evmErr
 }
 
+// CalculateAccessListGas computes the intrinsic gas of the access list of a transaction (EIP-2930)
+func CalculateAccessListGas(accessList types.AccessList) uint64 {
+	return uint64(len(accessList))*params.TxAccessListAddressGas +
+		uint64(accessList.StorageKeys())*params.TxAccessListStorageKeyGas
+}
+
 // CalculateIntrinsicGas computes the 'intrinsic gas' for a message with the given data.
-func CalculateIntrinsicGas(data []byte, createContract bool) (uint64, error) {
+// The non-zero bytes are repriced from 68 to 16 gas with the Berlin upgrade (EIP-2028).
+func CalculateIntrinsicGas(data []byte, createContract bool, isEIP2028 bool) (uint64, error) {
 	// Set the starting gas for the raw transaction
 	var gas uint64
 	if createContract {
@@ -115,11 +173,15 @@ func CalculateIntrinsicGas(data []byte, createContract bool) (uint64, error) {
 				nz++
 			}
 		}
+		nonZeroGas := params.TxDataNonZeroGas
+		if isEIP2028 {
+			nonZeroGas = params.TxDataNonZeroGasEIP2028
+		}
 		// Make sure we don't exceed uint64 for all data combinations
-		if (math.MaxUint64-gas)/params.TxDataNonZeroGas < nz {
+		if (math.MaxUint64-gas)/nonZeroGas < nz {
 			return 0, ErrOutOfGas
 		}
-		gas += nz * params.TxDataNonZeroGas
+		gas += nz * nonZeroGas
 
 		z := uint64(len(data)) - nz
 		if (math.MaxUint64-gas)/params.TxDataZeroGas < z {
@@ -22,6 +22,7 @@ func TestVMExecute(t *testing.T) {
 	assert := assert.New(t)
 
 	storeView := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	parentBlockInfo := NewBlockInfo(0, big.NewInt(0), "testnet")
 	privAccounts := prepareInitState(storeView, 2)
 	deployerAcc := privAccounts[0].Account
 	callerAcc := privAccounts[1].Account
@@ -63,7 +64,7 @@ func TestVMExecute(t *testing.T) {
 		GasPrice: big.NewInt(5000),
 		Data:     deployCode,
 	}
-	vmRet, contractAddr, gasUsed, vmErr := Execute(deploySCTx, storeView)
+	vmRet, contractAddr, gasUsed, vmErr := Execute(parentBlockInfo, deploySCTx, storeView)
 	assert.Nil(vmErr)
 	retrievedCode := storeView.GetCode(contractAddr)
 	assert.True(bytes.Equal(code, retrievedCode))
@@ -89,7 +90,7 @@ func TestVMExecute(t *testing.T) {
 		GasPrice: big.NewInt(5000),
 		Data:     nil,
 	}
-	vmRet, _, gasUsed, vmErr = Execute(callSCTX, storeView)
+	vmRet, _, gasUsed, vmErr = Execute(parentBlockInfo, callSCTX, storeView)
 	assert.Nil(vmErr)
 	assert.Equal(common.Bytes{0x3}, vmRet)
 
@@ -122,6 +123,7 @@ func TestVMExecutionInteractWithContract(t *testing.T) {
 	assert := assert.New(t)
 
 	storeView := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	parentBlockInfo := NewBlockInfo(0, big.NewInt(0), "testnet")
 	privAccounts := prepareInitState(storeView, 2)
 	deployerAcc := privAccounts[0].Account
 	callerAcc := privAccounts[1].Account
@@ -147,7 +149,7 @@ func TestVMExecutionInteractWithContract(t *testing.T) {
 		GasPrice: big.NewInt(50),
 		Data:     deploymentCode,
 	}
-	vmRet, contractAddr, gasUsed, vmErr := Execute(deploySCTx, storeView)
+	vmRet, contractAddr, gasUsed, vmErr := Execute(parentBlockInfo, deploySCTx, storeView)
 	assert.Nil(vmErr)
 	assert.True(bytes.Equal(code, vmRet))
 
@@ -171,7 +173,7 @@ func TestVMExecutionInteractWithContract(t *testing.T) {
 	setValueCallTx := callSCTXTmpl
 	setValueCallData, _ := hex.DecodeString("ed8b07060000000000000000000000000000000000000000000000000000000000004797") // "ed8b0706" is signature of the SetValue() interface, and 0x4797 is the hex of the value 18327
 	setValueCallTx.Data = setValueCallData
-	_, _, gasUsed, vmErr = Execute(setValueCallTx, storeView)
+	_, _, gasUsed, vmErr = Execute(parentBlockInfo, setValueCallTx, storeView)
 	assert.Nil(vmErr)
 	log.Infof("Call   Contract -- SetValue: %v, gasUsed: %v", value, gasUsed)
 
@@ -179,7 +181,7 @@ func TestVMExecutionInteractWithContract(t *testing.T) {
 	calculateSquareCallTx := callSCTXTmpl
 	calculateSquareCallData, _ := hex.DecodeString("b5a0241a") // signature of the CalculateSquare() interface
 	calculateSquareCallTx.Data = calculateSquareCallData
-	vmRet, _, gasUsed, vmErr = Execute(setValueCallTx, storeView)
+	vmRet, _, gasUsed, vmErr = Execute(parentBlockInfo, setValueCallTx, storeView)
 	calculatedSquare, success := new(big.Int).SetString(hex.EncodeToString(vmRet), 16)
 	assert.True(success)
 	assert.Equal(expectedSquare, calculatedSquare)
@@ -193,6 +195,7 @@ func TestVMExecutionDeployComplexContract(t *testing.T) {
 	assert := assert.New(t)
 
 	storeView := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	parentBlockInfo := NewBlockInfo(0, big.NewInt(0), "testnet")
 	privAccounts := prepareInitState(storeView, 2)
 	deployerAcc := privAccounts[0].Account
 	callerAcc := privAccounts[1].Account
@@ -218,7 +221,7 @@ func TestVMExecutionDeployComplexContract(t *testing.T) {
 		GasPrice: big.NewInt(50),
 		Data:     deploymentCode,
 	}
-	vmRet, contractAddr, gasUsed, vmErr := Execute(deploySCTx, storeView)
+	vmRet, contractAddr, gasUsed, vmErr := Execute(parentBlockInfo, deploySCTx, storeView)
 	assert.Nil(vmErr)
 	assert.True(bytes.Equal(code, vmRet))
 
@@ -240,7 +243,7 @@ func TestVMExecutionDeployComplexContract(t *testing.T) {
 	monthlyWithdrawLimitInWeiCallTx := callSCTXTmpl
 	monthlyWithdrawLimitInWeiCallData, _ := hex.DecodeString("03216695") // signature of the monthlyWithdrawLimitInWei() interface
 	monthlyWithdrawLimitInWeiCallTx.Data = monthlyWithdrawLimitInWeiCallData
-	vmRet, _, gasUsed, vmErr = Execute(monthlyWithdrawLimitInWeiCallTx, storeView)
+	vmRet, _, gasUsed, vmErr = Execute(parentBlockInfo, monthlyWithdrawLimitInWeiCallTx, storeView)
 	assert.Nil(vmErr)
 	monthlyWithdrawLimitInWei, success := new(big.Int).SetString(hex.EncodeToString(vmRet), 16)
 	assert.True(success)
@@ -252,7 +255,7 @@ func TestVMExecutionDeployComplexContract(t *testing.T) {
 	lockingPeriodInMonthsCallTx := callSCTXTmpl
 	lockingPeriodInMonthsCallData, _ := hex.DecodeString("32aeaddf") // signature of the lockingPeriodInMonths() interface
 	lockingPeriodInMonthsCallTx.Data = lockingPeriodInMonthsCallData
-	vmRet, _, gasUsed, vmErr = Execute(lockingPeriodInMonthsCallTx, storeView)
+	vmRet, _, gasUsed, vmErr = Execute(parentBlockInfo, lockingPeriodInMonthsCallTx, storeView)
 	assert.Nil(vmErr)
 	lockingPeriodInMonths, success := new(big.Int).SetString(hex.EncodeToString(vmRet), 16)
 	assert.True(success)
@@ -262,7 +265,7 @@ func TestVMExecutionDeployComplexContract(t *testing.T) {
 	tokenAddressCallTx := callSCTXTmpl
 	tokenAddressCallData, _ := hex.DecodeString("fc0c546a") // signature of the token() interface
 	tokenAddressCallTx.Data = tokenAddressCallData
-	vmRet, _, gasUsed, vmErr = Execute(tokenAddressCallTx, storeView)
+	vmRet, _, gasUsed, vmErr = Execute(parentBlockInfo, tokenAddressCallTx, storeView)
 	assert.Nil(vmErr)
 	expectedTokenAddrBytes, _ := hex.DecodeString("3883f5e181fccaF8410FA61e12b59BAd963fb645")
 	expectedTokenAddr := common.BytesToAddress(expectedTokenAddrBytes)
@@ -278,6 +281,7 @@ func TestVMExecutionDeployERC20TokenContract(t *testing.T) {
 	assert := assert.New(t)
 
 	storeView := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	parentBlockInfo := NewBlockInfo(0, big.NewInt(0), "testnet")
 	privAccounts := prepareInitState(storeView, 2)
 	deployerAcc := privAccounts[0].Account
 	callerAcc := privAccounts[1].Account
@@ -303,7 +307,7 @@ func TestVMExecutionDeployERC20TokenContract(t *testing.T) {
 		GasPrice: big.NewInt(50),
 		Data:     deploymentCode,
 	}
-	vmRet, contractAddr, gasUsed, vmErr := Execute(deploySCTx, storeView)
+	vmRet, contractAddr, gasUsed, vmErr := Execute(parentBlockInfo, deploySCTx, storeView)
 	assert.Nil(vmErr)
 	assert.True(bytes.Equal(code, vmRet))
 
@@ -325,19 +329,19 @@ func TestVMExecutionDeployERC20TokenContract(t *testing.T) {
 	nameCallTx := callSCTXTmpl
 	nameCallData, _ := hex.DecodeString("06fdde03") // signature of the name() interface
 	nameCallTx.Data = nameCallData
-	vmRet, _, gasUsed, vmErr = Execute(nameCallTx, storeView)
+	vmRet, _, gasUsed, vmErr = Execute(parentBlockInfo, nameCallTx, storeView)
 	assert.Nil(vmErr)
 	name := string(vmRet[64:75])
-	assert.Equal("Script Token", name)
+	assert.Equal("Theta Token", name) // the name compiled into testdata/erc20_token.json
 	log.Infof("Call   Contract -- name: %v", name)
 
 	symbolCallTx := callSCTXTmpl
 	symbolCallData, _ := hex.DecodeString("95d89b41") // signature of the symbol() interface
 	symbolCallTx.Data = symbolCallData
-	vmRet, _, gasUsed, vmErr = Execute(symbolCallTx, storeView)
+	vmRet, _, gasUsed, vmErr = Execute(parentBlockInfo, symbolCallTx, storeView)
 	assert.Nil(vmErr)
 	symbol := string(vmRet[64:69])
-	assert.Equal("SCRIPT", symbol)
+	assert.Equal("THETA", symbol)
 	log.Infof("Call   Contract -- symbol: %v", symbol)
 }
 
@@ -17,6 +17,8 @@
 package vm
 
 import (
+	"math/big"
+
 	"github.com/scripttoken/script/common"
 	"github.com/scripttoken/script/common/math"
 	"github.com/scripttoken/script/ledger/vm/params"
@@ -361,6 +363,63 @@ func gasCreate2(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack,
 	return gas, nil
 }
 
+// gasCreateEip3860 charges the init code word gas of CREATE on top of gasCreate (EIP-3860)
+func gasCreateEip3860(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	gas, err := gasCreate(gt, evm, contract, stack, mem, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	return addInitCodeWordGas(evm, gas, stack.Back(2))
+}
+
+// gasCreate2Eip3860 charges the init code word gas of CREATE2 on top of gasCreate2 (EIP-3860)
+func gasCreate2Eip3860(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	gas, err := gasCreate2(gt, evm, contract, stack, mem, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	return addInitCodeWordGas(evm, gas, stack.Back(2))
+}
+
+func addInitCodeWordGas(evm *EVM, gas uint64, size *big.Int) (uint64, error) {
+	initCodeSize, overflow := bigUint64(size)
+	if overflow || initCodeSize > uint64(GetMaxInitCodeSize(evm.StateDB.GetBlockHeight())) {
+		return 0, errGasUintOverflow
+	}
+	// Since size <= the max init code size, these multiplication cannot overflow
+	wordGas := params.InitCodeWordGas * toWordSize(initCodeSize)
+	if gas, overflow = math.SafeAdd(gas, wordGas); overflow {
+		return 0, errGasUintOverflow
+	}
+	return gas, nil
+}
+
+func gasMcopy(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	gas, err := memoryGasCost(mem, memorySize)
+	if err != nil {
+		return 0, err
+	}
+
+	var overflow bool
+	if gas, overflow = math.SafeAdd(gas, GasFastestStep); overflow {
+		return 0, errGasUintOverflow
+	}
+
+	words, overflow := bigUint64(stack.Back(2))
+	if overflow {
+		return 0, errGasUintOverflow
+	}
+
+	if words, overflow = math.SafeMul(toWordSize(words), params.MemoryCopyWordGas); overflow {
+		return 0, errGasUintOverflow
+	}
+
+	if gas, overflow = math.SafeAdd(gas, words); overflow {
+		return 0, errGasUintOverflow
+	}
+	return gas, nil
+}
+
 func gasBalance(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 	return gt.Balance, nil
 }
@@ -600,6 +600,18 @@ func opSelfBalance(pc *uint64, interpreter *EVMInterpreter, contract *Contract,
 	return nil, nil
 }
 
+// opBaseFee implements BASEFEE opcode
+func opBaseFee(pc *uint64, interpreter *EVMInterpreter, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	baseFee := interpreter.intPool.get()
+	if interpreter.evm.BaseFee != nil {
+		baseFee.Set(interpreter.evm.BaseFee)
+	} else {
+		baseFee.SetUint64(0)
+	}
+	stack.push(baseFee)
+	return nil, nil
+}
+
 func opPop(pc *uint64, interpreter *EVMInterpreter, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	interpreter.intPool.put(stack.pop())
 	return nil, nil
@@ -640,12 +652,48 @@ func opSload(pc *uint64, interpreter *EVMInterpreter, contract *Contract, memory
 func opSstore(pc *uint64, interpreter *EVMInterpreter, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	loc := common.BigToHash(stack.pop())
 	val := stack.pop()
+	if interpreter.evm.chainRules.IsBerlin {
+		original := interpreter.evm.StateDB.GetState(contract.Address(), loc)
+		interpreter.evm.txState.RecordOriginalState(contract.Address(), loc, original)
+	}
 	interpreter.evm.StateDB.SetState(contract.Address(), loc, common.BigToHash(val))
 
 	interpreter.intPool.put(val)
 	return nil, nil
 }
 
+// opTload implements TLOAD opcode
+func opTload(pc *uint64, interpreter *EVMInterpreter, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	loc := stack.peek()
+	val := interpreter.evm.txState.GetTransientState(contract.Address(), common.BigToHash(loc))
+	loc.SetBytes(val.Bytes())
+	return nil, nil
+}
+
+// opTstore implements TSTORE opcode
+func opTstore(pc *uint64, interpreter *EVMInterpreter, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	loc := common.BigToHash(stack.pop())
+	val := stack.pop()
+	interpreter.evm.txState.SetTransientState(contract.Address(), loc, common.BigToHash(val))
+
+	interpreter.intPool.put(val)
+	return nil, nil
+}
+
+// opMcopy implements MCOPY opcode
+func opMcopy(pc *uint64, interpreter *EVMInterpreter, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	var (
+		dst    = stack.pop()
+		src    = stack.pop()
+		length = stack.pop()
+	)
+	// These values are checked for validity during the gas cost calculation
+	memory.Copy(dst.Uint64(), src.Uint64(), length.Uint64())
+
+	interpreter.intPool.put(dst, src, length)
+	return nil, nil
+}
+
 func opJump(pc *uint64, interpreter *EVMInterpreter, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	pos := stack.pop()
 	if !contract.validJumpdest(pos) {
@@ -891,6 +939,19 @@ func opSuicide(pc *uint64, interpreter *EVMInterpreter, contract *Contract, memo
 	return nil, nil
 }
 
+// opSuicide6780 implements SELFDESTRUCT as changed by EIP-6780: the contract is only
+// deleted if it is created by the same transaction, otherwise only its balance is sent
+// to the beneficiary.
+func opSuicide6780(pc *uint64, interpreter *EVMInterpreter, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	if interpreter.evm.txState.IsCreated(contract.Address()) {
+		return opSuicide(pc, interpreter, contract, memory, stack)
+	}
+	balance := interpreter.evm.StateDB.GetBalance(contract.Address())
+	interpreter.evm.StateDB.SubBalance(contract.Address(), balance)
+	interpreter.evm.StateDB.AddBalance(common.BigToAddress(stack.pop()), balance)
+	return nil, nil
+}
+
 // following functions are used by the instruction jump  table
 
 // make log instruction function
@@ -915,6 +976,12 @@ func makeLog(size int) executionFunc {
 }
 
 // make push instruction function
+// opPush0 implements the PUSH0 opcode
+func opPush0(pc *uint64, interpreter *EVMInterpreter, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
+	stack.push(interpreter.intPool.getZero())
+	return nil, nil
+}
+
 func makePush(size uint64, pushByteSize int) executionFunc {
 	return func(pc *uint64, interpreter *EVMInterpreter, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 		codeLen := len(contract.Code)
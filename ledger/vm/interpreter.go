@@ -85,14 +85,30 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 	// the jump table was initialised. If it was not
 	// we'll set the default jump table.
 	if !cfg.JumpTable[STOP].valid {
-		cfg.JumpTable = constantinopleInstructionSet
+		switch {
+		case evm.chainRules.IsCancun:
+			cfg.JumpTable = cancunInstructionSet
+		case evm.chainRules.IsShanghai:
+			cfg.JumpTable = shanghaiInstructionSet
+		case evm.chainRules.IsLondon:
+			cfg.JumpTable = londonInstructionSet
+		case evm.chainRules.IsBerlin:
+			cfg.JumpTable = berlinInstructionSet
+		default:
+			cfg.JumpTable = constantinopleInstructionSet
+		}
+	}
+
+	// gasTable: evm.ChainConfig().GasTable(evm.BlockNumber),
+	gasTable := params.ScriptGasTable
+	if evm.chainRules.IsBerlin {
+		gasTable = params.ScriptGasTableBerlin
 	}
 
 	return &EVMInterpreter{
-		evm: evm,
-		cfg: cfg,
-		// gasTable: evm.ChainConfig().GasTable(evm.BlockNumber),
-		gasTable: params.ScriptGasTable,
+		evm:      evm,
+		cfg:      cfg,
+		gasTable: gasTable,
 	}
 }
 
@@ -55,8 +55,89 @@ var (
 	homesteadInstructionSet      = newHomesteadInstructionSet()
 	byzantiumInstructionSet      = newByzantiumInstructionSet()
 	constantinopleInstructionSet = newConstantinopleInstructionSet()
+	berlinInstructionSet         = newBerlinInstructionSet()
+	londonInstructionSet         = newLondonInstructionSet()
+	shanghaiInstructionSet       = newShanghaiInstructionSet()
+	cancunInstructionSet         = newCancunInstructionSet()
 )
 
+// newCancunInstructionSet returns the instructions of the Cancun upgrade: transient
+// storage (EIP-1153), MCOPY (EIP-5656) and SELFDESTRUCT only in the same transaction
+// (EIP-6780).
+func newCancunInstructionSet() [256]operation {
+	instructionSet := newShanghaiInstructionSet()
+	instructionSet[TLOAD] = operation{
+		execute:       opTload,
+		gasCost:       constGasFunc(params.TransientStorageGas),
+		validateStack: makeStackFunc(1, 1),
+		valid:         true,
+	}
+	instructionSet[TSTORE] = operation{
+		execute:       opTstore,
+		gasCost:       constGasFunc(params.TransientStorageGas),
+		validateStack: makeStackFunc(2, 0),
+		valid:         true,
+		writes:        true,
+	}
+	instructionSet[MCOPY] = operation{
+		execute:       opMcopy,
+		gasCost:       gasMcopy,
+		validateStack: makeStackFunc(3, 0),
+		memorySize:    memoryMcopy,
+		valid:         true,
+	}
+	instructionSet[SELFDESTRUCT].execute = opSuicide6780
+	return instructionSet
+}
+
+// newShanghaiInstructionSet returns the instructions of the Shanghai upgrade: PUSH0
+// (EIP-3855) and the init code metering of CREATE and CREATE2 (EIP-3860).
+func newShanghaiInstructionSet() [256]operation {
+	instructionSet := newLondonInstructionSet()
+	instructionSet[PUSH0] = operation{
+		execute:       opPush0,
+		gasCost:       constGasFunc(GasQuickStep),
+		validateStack: makeStackFunc(0, 1),
+		valid:         true,
+	}
+	instructionSet[CREATE].gasCost = gasCreateEip3860
+	instructionSet[CREATE2].gasCost = gasCreate2Eip3860
+	return instructionSet
+}
+
+// newLondonInstructionSet returns the instructions of the London upgrade: BASEFEE
+// (EIP-3198) and the reduced refunds (EIP-3529).
+func newLondonInstructionSet() [256]operation {
+	instructionSet := newBerlinInstructionSet()
+	instructionSet[BASEFEE] = operation{
+		execute:       opBaseFee,
+		gasCost:       constGasFunc(GasQuickStep),
+		validateStack: makeStackFunc(0, 1),
+		valid:         true,
+	}
+	instructionSet[SSTORE].gasCost = gasSStoreEIP3529
+	instructionSet[SELFDESTRUCT].gasCost = gasSelfdestructEIP3529
+	return instructionSet
+}
+
+// newBerlinInstructionSet returns the instructions of the Berlin upgrade, which charge
+// the cold and warm state accesses differently (EIP-2929).
+func newBerlinInstructionSet() [256]operation {
+	instructionSet := newConstantinopleInstructionSet()
+	instructionSet[SLOAD].gasCost = gasSLoadEIP2929
+	instructionSet[SSTORE].gasCost = gasSStoreEIP2929
+	instructionSet[BALANCE].gasCost = gasBalanceEIP2929
+	instructionSet[EXTCODESIZE].gasCost = gasExtCodeSizeEIP2929
+	instructionSet[EXTCODECOPY].gasCost = gasExtCodeCopyEIP2929
+	instructionSet[EXTCODEHASH].gasCost = gasExtCodeHashEIP2929
+	instructionSet[CALL].gasCost = gasCallEIP2929
+	instructionSet[CALLCODE].gasCost = gasCallCodeEIP2929
+	instructionSet[DELEGATECALL].gasCost = gasDelegateCallEIP2929
+	instructionSet[STATICCALL].gasCost = gasStaticCallEIP2929
+	instructionSet[SELFDESTRUCT].gasCost = gasSelfdestructEIP2929
+	return instructionSet
+}
+
 // NewConstantinopleInstructionSet returns the frontier, homestead
 // byzantium and contantinople instructions.
 func newConstantinopleInstructionSet() [256]operation {
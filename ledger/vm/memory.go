@@ -48,6 +48,15 @@ func (m *Memory) Set(offset, size uint64, value []byte) {
 	}
 }
 
+// Copy copies data from the src position slice into the dst position.
+// The source and destination may overlap.
+func (m *Memory) Copy(dst, src, size uint64) {
+	if size == 0 {
+		return
+	}
+	copy(m.store[dst:], m.store[src:src+size])
+}
+
 // Set32 sets the 32 bytes starting at offset to the value of val, left-padded with zeroes to
 // 32 bytes.
 func (m *Memory) Set32(offset uint64, val *big.Int) {
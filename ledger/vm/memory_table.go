@@ -95,3 +95,10 @@ func memoryLog(stack *Stack) *big.Int {
 	mSize, mStart := stack.Back(1), stack.Back(0)
 	return calcMemSize(mStart, mSize)
 }
+
+func memoryMcopy(stack *Stack) *big.Int {
+	x := calcMemSize(stack.Back(0), stack.Back(2))
+	y := calcMemSize(stack.Back(1), stack.Back(2))
+
+	return math.BigMax(x, y)
+}
@@ -50,4 +50,19 @@ var (
 
 		CreateBySuicide: 25000,
 	}
+
+	// ScriptGasTableBerlin contains the gas prices after the Berlin upgrade (EIP-2929). The
+	// state access prices are the warm ones, the cold access surcharge is charged separately.
+	ScriptGasTableBerlin = GasTable{
+		ExtcodeSize: WarmStorageReadCostEIP2929,
+		ExtcodeCopy: WarmStorageReadCostEIP2929,
+		ExtcodeHash: WarmStorageReadCostEIP2929,
+		Balance:     WarmStorageReadCostEIP2929,
+		SLoad:       WarmStorageReadCostEIP2929,
+		Calls:       WarmStorageReadCostEIP2929,
+		Suicide:     5000,
+		ExpByte:     50,
+
+		CreateBySuicide: 25000,
+	}
 )
@@ -72,6 +72,29 @@ const (
 	MaxCodeSize             = 24576 // Maximum bytecode to permit for a contract
 	MaxCodeSizeForMetachain = 49152 // Maximum bytecode to permit for a contract
 
+	// Ethereum Berlin fork
+	ColdAccountAccessCostEIP2929 uint64 = 2600 // COLD_ACCOUNT_ACCESS_COST
+	ColdSloadCostEIP2929         uint64 = 2100 // COLD_SLOAD_COST
+	WarmStorageReadCostEIP2929   uint64 = 100  // WARM_STORAGE_READ_COST
+	TxAccessListAddressGas       uint64 = 2400 // Per address specified in EIP 2930 access list
+	TxAccessListStorageKeyGas    uint64 = 1900 // Per storage key specified in EIP 2930 access list
+	TxDataNonZeroGasEIP2028      uint64 = 16   // Per byte of non zero data attached to a transaction after EIP 2028
+
+	SstoreSentryGasEIP2200 uint64 = 2300  // Minimum gas required to be present for an SSTORE call, not consumed
+	SstoreSetGasEIP2200    uint64 = 20000 // Once per SSTORE operation from clean zero to non-zero
+	SstoreResetGasEIP2200  uint64 = 5000  // Once per SSTORE operation from clean non-zero to something else
+
+	// Ethereum London fork
+	SstoreClearsScheduleRefundEIP3529 uint64 = SstoreResetGasEIP2200 - ColdSloadCostEIP2929 + TxAccessListStorageKeyGas // Refund for clearing a storage slot
+	RefundQuotientEIP3529             uint64 = 5                                                                        // Maximum refund quotient, i.e. at most gasUsed/5 is refunded
+
+	// Ethereum Shanghai fork
+	InitCodeWordGas uint64 = 2 // Once per word of the init code when creating a contract
+
+	// Ethereum Cancun fork
+	TransientStorageGas uint64 = 100 // Once per TLOAD or TSTORE operation
+	MemoryCopyWordGas   uint64 = 3   // Once per word of the MCOPY operation's data
+
 	// Precompiled contract gas prices
 
 	EcrecoverGas        uint64 = 3000 // Elliptic curve sender recovery gas price
@@ -103,8 +126,31 @@ const (
 	UnstakeFromLightningGas uint64 = 21000 // Unstake from Lightning
 	StakeToEENGas          uint64 = 21000 // Stake to EEN
 	UnstakeFromEENGas      uint64 = 21000 // Unstake from EEN
+
+	LightningStakeGas          uint64 = 200 // Retrieve the stake of a source to a lightning node
+	EENStakeGas                uint64 = 200 // Retrieve the stake of a source to an elite edge node
+	StakeRewardDistributionGas uint64 = 200 // Retrieve the reward distribution rule of a stake holder
+
+	Ed25519VerifyBaseGas    uint64 = 3000 // Base price for an ed25519 signature verification
+	Ed25519VerifyPerWordGas uint64 = 12   // Per-word price of the message for an ed25519 signature verification
+
+	// BLS12-381 curve operations (EIP-2537)
+	Bls12381G1AddGas          uint64 = 375   // Price for BLS12-381 elliptic curve G1 point addition
+	Bls12381G1MulGas          uint64 = 12000 // Price for BLS12-381 elliptic curve G1 point scalar multiplication
+	Bls12381G2AddGas          uint64 = 600   // Price for BLS12-381 elliptic curve G2 point addition
+	Bls12381G2MulGas          uint64 = 22500 // Price for BLS12-381 elliptic curve G2 point scalar multiplication
+	Bls12381PairingBaseGas    uint64 = 37700 // Base gas price for BLS12-381 elliptic curve pairing check
+	Bls12381PairingPerPairGas uint64 = 32600 // Per-point pair gas price for BLS12-381 elliptic curve pairing check
+	Bls12381MapG1Gas          uint64 = 5500  // Gas price for BLS12-381 mapping field element to G1 operation
+	Bls12381MapG2Gas          uint64 = 23800 // Gas price for BLS12-381 mapping field element to G2 operation
 )
 
+// Bls12381G1MultiExpDiscountTable is the gas discount table for BLS12-381 G1 multi exponentiation operation
+var Bls12381G1MultiExpDiscountTable = [128]uint64{1000, 949, 848, 797, 764, 750, 738, 728, 719, 712, 705, 698, 692, 687, 682, 677, 673, 669, 665, 661, 658, 654, 651, 648, 645, 642, 640, 637, 635, 632, 630, 627, 625, 623, 621, 619, 617, 615, 613, 611, 609, 608, 606, 604, 603, 601, 599, 598, 596, 595, 593, 592, 591, 589, 588, 586, 585, 584, 582, 581, 580, 579, 577, 576, 575, 574, 573, 572, 570, 569, 568, 567, 566, 565, 564, 563, 562, 561, 560, 559, 558, 557, 556, 555, 554, 553, 552, 551, 550, 549, 548, 547, 547, 546, 545, 544, 543, 542, 541, 540, 540, 539, 538, 537, 536, 536, 535, 534, 533, 532, 532, 531, 530, 529, 528, 528, 527, 526, 525, 525, 524, 523, 522, 522, 521, 520, 520, 519}
+
+// Bls12381G2MultiExpDiscountTable is the gas discount table for BLS12-381 G2 multi exponentiation operation
+var Bls12381G2MultiExpDiscountTable = [128]uint64{1000, 1000, 923, 884, 855, 832, 812, 796, 782, 770, 759, 749, 740, 732, 724, 717, 711, 704, 699, 693, 688, 683, 679, 674, 670, 666, 663, 659, 655, 652, 649, 646, 643, 640, 637, 634, 632, 629, 627, 624, 622, 620, 618, 615, 613, 611, 609, 607, 606, 604, 602, 600, 598, 597, 595, 593, 592, 590, 589, 587, 586, 584, 583, 582, 580, 579, 578, 576, 575, 574, 573, 571, 570, 569, 568, 567, 566, 565, 563, 562, 561, 560, 559, 558, 557, 556, 555, 554, 553, 552, 552, 551, 550, 549, 548, 547, 546, 545, 545, 544, 543, 542, 541, 541, 540, 539, 538, 537, 537, 536, 535, 535, 534, 533, 532, 532, 531, 530, 530, 529, 528, 528, 527, 526, 526, 525, 524, 524}
+
 var (
 	DifficultyBoundDivisor = big.NewInt(2048)   // The bound divisor of the difficulty, used in the update calculations.
 	GenesisDifficulty      = big.NewInt(131072) // Difficulty of the Genesis block.
@@ -0,0 +1,259 @@
+package vm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/common/hexutil"
+	"github.com/scripttoken/script/common/math"
+	"github.com/scripttoken/script/crypto"
+	"github.com/scripttoken/script/ledger/state"
+	"github.com/scripttoken/script/ledger/types"
+	"github.com/scripttoken/script/ledger/vm/params"
+	"github.com/scripttoken/script/rlp"
+	"github.com/scripttoken/script/store/database/backend"
+)
+
+// The state tests in testdata/GeneralStateTests follow the format of the Ethereum
+// GeneralStateTests. Each test runs a transaction against a pre state under the
+// rules of an upgrade and checks the gas used, the hash of the logs and the post
+// state. The state roots are not compared, since the state trie of Script is laid
+// out differently.
+//
+// The tests are written for Script, since the upstream cases mostly depend on the
+// features below, and their post states were filled with the state test runner of
+// go-ethereum v1.15.11. They cover the calldata gas (EIP-2028), the cold and warm
+// state accesses (EIP-2929), the refunds (EIP-3529), the init code limit and metering
+// (EIP-3860) and SELFDESTRUCT (EIP-6780). The upstream cases of Berlin to Cancun are
+// not imported for the following reasons:
+//
+//   - COINBASE, BLOCKHASH and DIFFICULTY/PREVRANDAO are not supported by the Script EVM
+//   - the blob transactions, BLOBHASH and BLOBBASEFEE (EIP-4844, EIP-7516), the beacon
+//     block root (EIP-4788) and the point evaluation precompile are not part of Script
+//   - the gas fees and the sender nonce are handled by the ledger executor, so the
+//     balances of the sender and the coinbase differ
+//   - the contracts start with nonce 0 (no EIP-161), so the addresses of the contracts
+//     they create differ
+//   - the state roots are not comparable
+
+// stateTestDir is the directory of the state tests
+const stateTestDir = "testdata/GeneralStateTests"
+
+// stateTestForks maps the upgrades of the state tests to the chain configs activating them
+var stateTestForks = map[string]*params.ChainConfig{
+	"Berlin": {
+		ChainID:     big.NewInt(0),
+		BerlinBlock: big.NewInt(0),
+	},
+	"London": {
+		ChainID:     big.NewInt(0),
+		BerlinBlock: big.NewInt(0),
+		LondonBlock: big.NewInt(0),
+	},
+	"Shanghai": {
+		ChainID:       big.NewInt(0),
+		BerlinBlock:   big.NewInt(0),
+		LondonBlock:   big.NewInt(0),
+		ShanghaiBlock: big.NewInt(0),
+	},
+	"Cancun": {
+		ChainID:       big.NewInt(0),
+		BerlinBlock:   big.NewInt(0),
+		LondonBlock:   big.NewInt(0),
+		ShanghaiBlock: big.NewInt(0),
+		CancunBlock:   big.NewInt(0),
+	},
+}
+
+type stateTest struct {
+	Env         stateTestEnv                     `json:"env"`
+	Pre         map[string]stateTestAccount      `json:"pre"`
+	Transaction stateTestTransaction             `json:"transaction"`
+	Post        map[string][]stateTestPostResult `json:"post"`
+}
+
+type stateTestEnv struct {
+	CurrentCoinbase  string              `json:"currentCoinbase"`
+	CurrentTimestamp math.HexOrDecimal64 `json:"currentTimestamp"`
+}
+
+type stateTestAccount struct {
+	Balance *math.HexOrDecimal256 `json:"balance"`
+	Code    hexutil.Bytes         `json:"code"`
+	Nonce   math.HexOrDecimal64   `json:"nonce"`
+	Storage map[string]string     `json:"storage"`
+}
+
+type stateTestTransaction struct {
+	Data        []hexutil.Bytes         `json:"data"`
+	GasLimit    []math.HexOrDecimal64   `json:"gasLimit"`
+	GasPrice    *math.HexOrDecimal256   `json:"gasPrice"`
+	Value       []*math.HexOrDecimal256 `json:"value"`
+	Sender      string                  `json:"sender"`
+	To          string                  `json:"to"`
+	AccessLists []*stateTestAccessList  `json:"accessLists"`
+}
+
+type stateTestAccessList []struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+type stateTestPostResult struct {
+	Indexes struct {
+		Data  int `json:"data"`
+		Gas   int `json:"gas"`
+		Value int `json:"value"`
+	} `json:"indexes"`
+	ExpectException string                      `json:"expectException"`
+	GasUsed         math.HexOrDecimal64         `json:"gasUsed"`
+	Logs            common.Hash                 `json:"logs"`
+	PostState       map[string]stateTestAccount `json:"postState"`
+}
+
+func TestGeneralStateTests(t *testing.T) {
+	err := filepath.Walk(stateTestDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".json") {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var tests map[string]*stateTest
+		if err := json.Unmarshal(content, &tests); err != nil {
+			return fmt.Errorf("%v: %v", path, err)
+		}
+		dir := filepath.Base(filepath.Dir(path))
+		for name, test := range tests {
+			forks := []string{}
+			for fork := range test.Post {
+				forks = append(forks, fork)
+			}
+			sort.Strings(forks)
+			for _, fork := range forks {
+				for i, result := range test.Post[fork] {
+					test, fork, result := test, fork, result
+					t.Run(fmt.Sprintf("%v/%v/%v/%d", dir, name, fork, i), func(t *testing.T) {
+						runStateTest(t, test, fork, result)
+					})
+				}
+			}
+		}
+		return nil
+	})
+	require.Nil(t, err)
+}
+
+func runStateTest(t *testing.T, test *stateTest, fork string, result stateTestPostResult) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	chainConfig, ok := stateTestForks[fork]
+	require.True(ok, "unsupported fork %v", fork)
+
+	// Run at the height where all the upgrades of the chain are activated, so that the chain
+	// config alone decides the rules of the fork
+	height := common.HeightEnableEVMCancun
+	storeView := state.NewStoreView(height, common.Hash{}, backend.NewMemDatabase())
+	for addrStr, acc := range test.Pre {
+		addr := common.HexToAddress(addrStr)
+		storeView.CreateAccount(addr)
+		storeView.AddBalance(addr, (*big.Int)(acc.Balance))
+		storeView.SetNonce(addr, uint64(acc.Nonce))
+		if len(acc.Code) > 0 {
+			storeView.SetCode(addr, acc.Code)
+		}
+		for key, val := range acc.Storage {
+			storeView.SetState(addr, common.HexToHash(key), common.HexToHash(val))
+		}
+	}
+
+	txJSON := test.Transaction
+	sender := common.HexToAddress(txJSON.Sender)
+	tx := &types.SmartContractTx{
+		From: types.TxInput{
+			Address: sender,
+			Coins: types.Coins{
+				SCPTWei: big.NewInt(0),
+				SPAYWei: (*big.Int)(txJSON.Value[result.Indexes.Value]),
+			},
+		},
+		GasLimit: uint64(txJSON.GasLimit[result.Indexes.Gas]),
+		GasPrice: (*big.Int)(txJSON.GasPrice),
+		Data:     common.Bytes(txJSON.Data[result.Indexes.Data]),
+	}
+	if txJSON.To != "" {
+		tx.To = types.TxOutput{Address: common.HexToAddress(txJSON.To)}
+	}
+	var accessList types.AccessList
+	if result.Indexes.Data < len(txJSON.AccessLists) && txJSON.AccessLists[result.Indexes.Data] != nil {
+		for _, tuple := range *txJSON.AccessLists[result.Indexes.Data] {
+			keys := []common.Hash{}
+			for _, key := range tuple.StorageKeys {
+				keys = append(keys, common.HexToHash(key))
+			}
+			accessList = append(accessList, types.AccessTuple{
+				Address:     common.HexToAddress(tuple.Address),
+				StorageKeys: keys,
+			})
+		}
+	}
+
+	parentBlockInfo := NewBlockInfo(height, new(big.Int).SetUint64(uint64(test.Env.CurrentTimestamp)), "testnet")
+	_, _, gasUsed, evmErr := executeWithChainConfig(chainConfig, parentBlockInfo, tx, accessList, storeView)
+	if result.ExpectException != "" {
+		assert.NotNil(evmErr, "expected %v", result.ExpectException)
+		return
+	}
+	assert.Equal(uint64(result.GasUsed), gasUsed, "gas used")
+
+	logs, err := rlp.EncodeToBytes(storeView.PopLogs())
+	require.Nil(err)
+	assert.Equal(result.Logs, crypto.Keccak256Hash(logs), "logs")
+
+	// The gas fees and the sender nonce are handled by the ledger executor instead of the EVM.
+	// The contracts created by the EVM start with nonce 0, since EIP-161 is not part of the
+	// Script EVM, so only the nonces of the existing accounts are compared.
+	coinbase := common.HexToAddress(test.Env.CurrentCoinbase)
+	for addrStr, expected := range result.PostState {
+		addr := common.HexToAddress(addrStr)
+		if addr == coinbase {
+			continue
+		}
+		_, existing := test.Pre[addrStr]
+		if addr != sender {
+			assert.Equal((*big.Int)(expected.Balance).String(), storeView.GetBalance(addr).String(), "balance of %v", addrStr)
+		}
+		if addr != sender && existing {
+			assert.Equal(uint64(expected.Nonce), storeView.GetNonce(addr), "nonce of %v", addrStr)
+		}
+		assert.Equal(common.Bytes2Hex(expected.Code), common.Bytes2Hex(storeView.GetCode(addr)), "code of %v", addrStr)
+		for key, val := range expected.Storage {
+			assert.Equal(common.HexToHash(val), storeView.GetState(addr, common.HexToHash(key)), "slot %v of %v", key, addrStr)
+		}
+		if pre, ok := test.Pre[addrStr]; ok {
+			for key := range pre.Storage {
+				if _, ok := expected.Storage[key]; !ok {
+					assert.Equal(common.Hash{}, storeView.GetState(addr, common.HexToHash(key)), "cleared slot %v of %v", key, addrStr)
+				}
+			}
+		}
+	}
+	// The accounts missing from the post state are destructed
+	for addrStr := range test.Pre {
+		if _, ok := result.PostState[addrStr]; !ok {
+			assert.True(storeView.HasSuicided(common.HexToAddress(addrStr)), "account %v is not destructed", addrStr)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package vm
+
+import (
+	"github.com/scripttoken/script/common"
+)
+
+// txState holds the state scoped to a single transaction: the accessed addresses and storage
+// slots (EIP-2929), the transient storage (EIP-1153), the contracts created by the
+// transaction (EIP-6780) and the original values of the written storage slots (EIP-2200).
+// It is not persisted. The changes are recorded in a journal so that they can be reverted
+// along with the StateDB snapshots, except for the original values which do not change
+// with the reverts.
+type txState struct {
+	addresses map[common.Address]struct{}
+	slots     map[common.Address]map[common.Hash]struct{}
+	transient map[common.Address]map[common.Hash]common.Hash
+	created   map[common.Address]struct{}
+	originals map[common.Address]map[common.Hash]common.Hash
+
+	journal []func()
+}
+
+func newTxState() *txState {
+	return &txState{
+		addresses: make(map[common.Address]struct{}),
+		slots:     make(map[common.Address]map[common.Hash]struct{}),
+		transient: make(map[common.Address]map[common.Hash]common.Hash),
+		created:   make(map[common.Address]struct{}),
+		originals: make(map[common.Address]map[common.Hash]common.Hash),
+	}
+}
+
+// AddressInAccessList returns true if the given address is in the access list
+func (ts *txState) AddressInAccessList(addr common.Address) bool {
+	_, ok := ts.addresses[addr]
+	return ok
+}
+
+// SlotInAccessList returns true if the given storage slot of the address is in the access list
+func (ts *txState) SlotInAccessList(addr common.Address, slot common.Hash) bool {
+	_, ok := ts.slots[addr][slot]
+	return ok
+}
+
+// AddAddressToAccessList adds the given address to the access list
+func (ts *txState) AddAddressToAccessList(addr common.Address) {
+	if ts.AddressInAccessList(addr) {
+		return
+	}
+	ts.addresses[addr] = struct{}{}
+	ts.journal = append(ts.journal, func() { delete(ts.addresses, addr) })
+}
+
+// AddSlotToAccessList adds the given storage slot of the address, and the address itself,
+// to the access list
+func (ts *txState) AddSlotToAccessList(addr common.Address, slot common.Hash) {
+	ts.AddAddressToAccessList(addr)
+	if ts.SlotInAccessList(addr, slot) {
+		return
+	}
+	if ts.slots[addr] == nil {
+		ts.slots[addr] = make(map[common.Hash]struct{})
+	}
+	ts.slots[addr][slot] = struct{}{}
+	ts.journal = append(ts.journal, func() { delete(ts.slots[addr], slot) })
+}
+
+// GetTransientState returns the transient storage value of the given key
+func (ts *txState) GetTransientState(addr common.Address, key common.Hash) common.Hash {
+	return ts.transient[addr][key]
+}
+
+// SetTransientState sets the transient storage value of the given key
+func (ts *txState) SetTransientState(addr common.Address, key, value common.Hash) {
+	prev := ts.GetTransientState(addr, key)
+	if prev == value {
+		return
+	}
+	ts.setTransientState(addr, key, value)
+	ts.journal = append(ts.journal, func() { ts.setTransientState(addr, key, prev) })
+}
+
+func (ts *txState) setTransientState(addr common.Address, key, value common.Hash) {
+	if ts.transient[addr] == nil {
+		ts.transient[addr] = make(map[common.Hash]common.Hash)
+	}
+	if value == (common.Hash{}) {
+		delete(ts.transient[addr], key)
+		return
+	}
+	ts.transient[addr][key] = value
+}
+
+// MarkCreated records that the contract at the given address is created by the transaction
+func (ts *txState) MarkCreated(addr common.Address) {
+	if ts.IsCreated(addr) {
+		return
+	}
+	ts.created[addr] = struct{}{}
+	ts.journal = append(ts.journal, func() { delete(ts.created, addr) })
+}
+
+// IsCreated returns true if the contract at the given address is created by the transaction
+func (ts *txState) IsCreated(addr common.Address) bool {
+	_, ok := ts.created[addr]
+	return ok
+}
+
+// RecordOriginalState records the value of the given storage slot before its first write
+// in the transaction
+func (ts *txState) RecordOriginalState(addr common.Address, slot, value common.Hash) {
+	if _, ok := ts.originals[addr][slot]; ok {
+		return
+	}
+	if ts.originals[addr] == nil {
+		ts.originals[addr] = make(map[common.Hash]common.Hash)
+	}
+	ts.originals[addr][slot] = value
+}
+
+// GetOriginalState returns the value of the given storage slot at the start of the
+// transaction, which is the current value of the StateDB if the slot is not written yet
+func (ts *txState) GetOriginalState(statedb StateDB, addr common.Address, slot common.Hash) common.Hash {
+	if value, ok := ts.originals[addr][slot]; ok {
+		return value
+	}
+	return statedb.GetState(addr, slot)
+}
+
+// Snapshot returns an identifier of the current state
+func (ts *txState) Snapshot() int {
+	return len(ts.journal)
+}
+
+// RevertToSnapshot undoes the changes made after the given snapshot
+func (ts *txState) RevertToSnapshot(snapshot int) {
+	for i := len(ts.journal) - 1; i >= snapshot; i-- {
+		ts.journal[i]()
+	}
+	ts.journal = ts.journal[:snapshot]
+}
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
 	"github.com/scripttoken/script/crypto"
 	"github.com/scripttoken/script/crypto/bls"
 	"github.com/scripttoken/script/ledger/state"
@@ -242,14 +243,59 @@ func UnstakeFromEEN(db StateDB, addr common.Address, eenAddr common.Address) boo
 	return true
 }
 
+// GetLightningStake returns the total stake of the lightning node, and the stake of the source to
+// it, or nil if the source has no stake to the lightning node.
+func GetLightningStake(db StateDB, lightningAddr common.Address, source common.Address) (*big.Int, *core.Stake) {
+	view := db.(*state.StoreView)
+	gcp := view.GetLightningCandidatePool()
+	lightning := gcp.GetWithHolderAddress(lightningAddr)
+	if lightning == nil {
+		return big.NewInt(0), nil
+	}
+	return lightning.TotalStake(), getSourceStake(lightning.StakeHolder, source)
+}
+
+// GetEENStake returns the total stake of the elite edge node, and the stake of the source to
+// it, or nil if the source has no stake to the elite edge node. A withdrawn stake stays in the
+// pool until it is returned at its return height.
+func GetEENStake(db StateDB, eenAddr common.Address, source common.Address) (*big.Int, *core.Stake) {
+	view := db.(*state.StoreView)
+	eenp := state.NewEliteEdgeNodePool(view, true)
+	een := eenp.Get(eenAddr)
+	if een == nil {
+		return big.NewInt(0), nil
+	}
+	return een.TotalStake(), getSourceStake(een.StakeHolder, source)
+}
+
+// GetStakeRewardDistribution returns the reward distribution rule of the stake holder, or nil if
+// the stake holder has not set any.
+func GetStakeRewardDistribution(db StateDB, holder common.Address) *core.RewardDistribution {
+	view := db.(*state.StoreView)
+	return state.NewStakeRewardDistributionRuleSet(view).Get(holder)
+}
+
+func getSourceStake(holder *core.StakeHolder, source common.Address) *core.Stake {
+	for _, stake := range holder.Stakes {
+		if stake.Source == source {
+			return stake
+		}
+	}
+	return nil
+}
+
 func getPrecompiledContracts(blockHeight uint64) map[common.Address]PrecompiledContract {
 	var precompiles map[common.Address]PrecompiledContract
 	if blockHeight < common.HeightSupportScriptTokenInSmartContract {
 		precompiles = PrecompiledContractsByzantium
 	} else if blockHeight < common.HeightSupportWrappedScript {
 		precompiles = PrecompiledContractsScriptSupport
-	} else {
+	} else if blockHeight < common.HeightEnableCryptoPrecompiles {
 		precompiles = PrecompiledContractsWrappedScriptSupport
+	} else if blockHeight < common.HeightEnableStakeQueryPrecompiles {
+		precompiles = PrecompiledContractsCryptoSupport
+	} else {
+		precompiles = PrecompiledContractsStakeQuerySupport
 	}
 	return precompiles
 }
@@ -300,6 +346,7 @@ type Context struct {
 	BlockNumber *big.Int       // Provides information for NUMBER
 	Time        *big.Int       // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
+	BaseFee     *big.Int       // Provides information for BASEFEE
 }
 
 // EVM is the Ethereum Virtual Machine base object and provides
@@ -337,6 +384,16 @@ type EVM struct {
 	// available gas is calculated in gasCall* according to the 63/64 rule and later
 	// applied in opCall*.
 	callGasTemp uint64
+	// txState holds the access list, the transient storage and the contracts
+	// created by the transaction
+	txState *txState
+}
+
+// evmSnapshot is a snapshot of both the StateDB and the transaction scoped state
+type evmSnapshot struct {
+	root    common.Hash
+	journal int
+	refund  uint64
 }
 
 // NewEVM returns a new EVM. The returned EVM is not thread safe and should
@@ -349,6 +406,10 @@ func NewEVM(ctx Context, statedb StateDB, chainConfig *params.ChainConfig, vmCon
 		chainConfig: chainConfig,
 		// chainRules:   chainConfig.Rules(ctx.BlockNumber),
 		interpreters: make([]Interpreter, 0, 1),
+		txState:      newTxState(),
+	}
+	if chainConfig != nil {
+		evm.chainRules = chainConfig.Rules(ctx.BlockNumber)
 	}
 
 	// vmConfig.EVMInterpreter will be used by EVM-C, it won't be checked here
@@ -370,6 +431,50 @@ func (evm *EVM) Interpreter() Interpreter {
 	return evm.interpreter
 }
 
+// snapshot takes a snapshot of the StateDB, the transaction scoped state and the refund counter
+func (evm *EVM) snapshot() evmSnapshot {
+	return evmSnapshot{
+		root:    evm.StateDB.Snapshot(),
+		journal: evm.txState.Snapshot(),
+		refund:  evm.StateDB.GetRefund(),
+	}
+}
+
+// revertToSnapshot reverts the StateDB, the transaction scoped state and the refund counter
+// to the given snapshot
+func (evm *EVM) revertToSnapshot(snapshot evmSnapshot) {
+	evm.StateDB.RevertToSnapshot(snapshot.root)
+	evm.txState.RevertToSnapshot(snapshot.journal)
+	if refund := evm.StateDB.GetRefund(); refund > snapshot.refund {
+		evm.StateDB.SubRefund(refund - snapshot.refund)
+	} else if refund < snapshot.refund {
+		evm.StateDB.AddRefund(snapshot.refund - refund)
+	}
+}
+
+// PrepareAccessList warms up the addresses and storage slots accessed by the transaction
+// from the start (EIP-2929): the sender, the destination, the precompiled contracts and the
+// optional access list of the transaction (EIP-2930). It is a no-op before Berlin.
+func (evm *EVM) PrepareAccessList(sender common.Address, dest *common.Address, accessList types.AccessList) {
+	if !evm.chainRules.IsBerlin {
+		return
+	}
+
+	evm.txState.AddAddressToAccessList(sender)
+	if dest != nil {
+		evm.txState.AddAddressToAccessList(*dest)
+	}
+	for addr := range getPrecompiledContracts(evm.StateDB.GetBlockHeight()) {
+		evm.txState.AddAddressToAccessList(addr)
+	}
+	for _, tuple := range accessList {
+		evm.txState.AddAddressToAccessList(tuple.Address)
+		for _, key := range tuple.StorageKeys {
+			evm.txState.AddSlotToAccessList(tuple.Address, key)
+		}
+	}
+}
+
 // Call executes the contract associated with the addr with the given input as
 // parameters. It also handles any necessary value transfer required and takes
 // the necessary steps to create accounts and reverses the state in case of an
@@ -397,7 +502,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 
 	var (
 		to       = AccountRef(addr)
-		snapshot = evm.StateDB.Snapshot()
+		snapshot = evm.snapshot()
 	)
 	if !evm.StateDB.Exist(addr) {
 
@@ -434,7 +539,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	// above we revert to the snapshot and consume any gas remaining. Additionally
 	// when we're in homestead this also counts for code storage gas errors.
 	if err != nil {
-		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.revertToSnapshot(snapshot)
 		if err != errExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
@@ -469,7 +574,7 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 	}
 
 	var (
-		snapshot = evm.StateDB.Snapshot()
+		snapshot = evm.snapshot()
 		to       = AccountRef(caller.Address())
 	)
 	// initialise a new contract and set the code that is to be used by the
@@ -480,7 +585,7 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 
 	ret, err = run(evm, contract, input, false)
 	if err != nil {
-		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.revertToSnapshot(snapshot)
 		if err != errExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
@@ -503,7 +608,7 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	}
 
 	var (
-		snapshot = evm.StateDB.Snapshot()
+		snapshot = evm.snapshot()
 		to       = AccountRef(caller.Address())
 	)
 
@@ -513,7 +618,7 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 
 	ret, err = run(evm, contract, input, false)
 	if err != nil {
-		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.revertToSnapshot(snapshot)
 		if err != errExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
@@ -536,7 +641,7 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 
 	var (
 		to       = AccountRef(addr)
-		snapshot = evm.StateDB.Snapshot()
+		snapshot = evm.snapshot()
 	)
 	// Initialise a new contract and set the code that is to be used by the
 	// EVM. The contract is a scoped environment for this execution context
@@ -549,7 +654,7 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	// when we're in Homestead this also counts for code storage gas errors.
 	ret, err = run(evm, contract, input, true)
 	if err != nil {
-		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.revertToSnapshot(snapshot)
 		if err != errExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
@@ -569,6 +674,19 @@ func (c *codeAndHash) Hash() common.Hash {
 	return c.hash
 }
 
+// getMaxCodeSize returns the maximum bytecode size permitted for a contract
+func getMaxCodeSize(blockHeight uint64) int {
+	if blockHeight >= common.HeightEnableMetachainSupport {
+		return params.MaxCodeSizeForMetachain
+	}
+	return params.MaxCodeSize
+}
+
+// GetMaxInitCodeSize returns the maximum size of the init code of a contract creation (EIP-3860)
+func GetMaxInitCodeSize(blockHeight uint64) int {
+	return 2 * getMaxCodeSize(blockHeight)
+}
+
 // create creates a new contract using code as deployment code.
 func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64, value *big.Int, scriptValue *big.Int, address common.Address) ([]byte, common.Address, uint64, error) {
 	// Depth check execution. Fail if we're trying to execute above the
@@ -592,14 +710,21 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	if evm.StateDB.GetNonce(address) != 0 || (contractHash != (common.Hash{}) && contractHash != types.EmptyCodeHash) {
 		return nil, common.Address{}, 0, ErrContractAddressCollision
 	}
+	// The address of the contract being created is warm (EIP-2929), even if the creation fails
+	if evm.chainRules.IsBerlin {
+		evm.txState.AddAddressToAccessList(address)
+	}
 	// Create a new account on the state
-	snapshot := evm.StateDB.Snapshot()
+	snapshot := evm.snapshot()
 
 	if !SupportScriptTransferInEVM(blockHeight) { // just for backward compatibility
 		evm.StateDB.CreateAccount(address)
 	} else { // should not wipe out the Script/SPAY balance sent to the contract address prior to contract creation
 		evm.StateDB.CreateAccountWithPreviousBalance(address)
 	}
+	if evm.chainRules.IsCancun {
+		evm.txState.MarkCreated(address)
+	}
 	Transfer(evm.StateDB, caller.Address(), address, value)
 
 	if SupportScriptTransferInEVM(blockHeight) {
@@ -624,12 +749,12 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	ret, err := run(evm, contract, nil, false)
 
 	// check whether the max code size has been exceeded
-	maxCodeSize := params.MaxCodeSize
-	if blockHeight >= common.HeightEnableMetachainSupport {
-		maxCodeSize = params.MaxCodeSizeForMetachain
-	}
+	maxCodeSizeExceeded := len(ret) > getMaxCodeSize(blockHeight)
 
-	maxCodeSizeExceeded := len(ret) > maxCodeSize
+	// Reject the code starting with the 0xEF byte (EIP-3541)
+	if err == nil && !maxCodeSizeExceeded && evm.chainRules.IsLondon && len(ret) >= 1 && ret[0] == 0xEF {
+		err = ErrInvalidCode
+	}
 	// if the contract creation ran successfully and no errors were returned
 	// calculate the gas required to store the code. If the code could not
 	// be stored due to not enough gas set an error and let it be handled
@@ -637,7 +762,11 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	if err == nil && !maxCodeSizeExceeded {
 		createDataGas := uint64(len(ret)) * params.CreateDataGas
 		if contract.UseGas(createDataGas) {
-			evm.StateDB.SetCode(address, ret)
+			// Storing the code would resurrect a contract destructed by its init code, which
+			// was the behavior before the Berlin upgrade
+			if !evm.chainRules.IsBerlin || !evm.StateDB.HasSuicided(address) {
+				evm.StateDB.SetCode(address, ret)
+			}
 		} else {
 			err = ErrCodeStoreOutOfGas
 		}
@@ -647,7 +776,7 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	// above we revert to the snapshot and consume any gas remaining. Additionally
 	// when we're in homestead this also counts for code storage gas errors.
 	if maxCodeSizeExceeded || err != nil {
-		evm.StateDB.RevertToSnapshot(snapshot)
+		evm.revertToSnapshot(snapshot)
 		if err != errExecutionReverted {
 			contract.UseGas(contract.Gas)
 		}
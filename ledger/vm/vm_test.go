@@ -10,6 +10,8 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/crypto/bls"
 	"github.com/scripttoken/script/ledger/state"
 	"github.com/scripttoken/script/ledger/types"
 	"github.com/scripttoken/script/store/database/backend"
@@ -90,7 +92,7 @@ func TestVMCreate(t *testing.T) {
 	store.SetAccount(addr, account)
 
 	evm := NewEVM(context, store, nil, Config{})
-	_, contractAddress, gas, err := evm.Create(AccountRef(addr), code, math.MaxUint64, big.NewInt(123))
+	_, contractAddress, gas, err := evm.Create(AccountRef(addr), code, math.MaxUint64, big.NewInt(123), big.NewInt(0))
 
 	assert.Nil(err)
 	assert.True(gas < math.MaxUint64)
@@ -147,14 +149,87 @@ func TestContractDeployment(t *testing.T) {
 	store.SetAccount(addr, account)
 
 	evm := NewEVM(context, store, nil, Config{})
-	_, contractAddress, _, err := evm.Create(AccountRef(addr), deployCode, math.MaxUint64, big.NewInt(123))
+	_, contractAddress, _, err := evm.Create(AccountRef(addr), deployCode, math.MaxUint64, big.NewInt(123), big.NewInt(0))
 
 	assert.Nil(err)
 	ccode := store.GetCode(contractAddress)
 	assert.True(bytes.Equal(code, ccode))
 
-	ret, leftOverGas, err := evm.Call(AccountRef(addr), contractAddress, nil, math.MaxUint64, big.NewInt(123))
+	ret, leftOverGas, err := evm.Call(AccountRef(addr), contractAddress, nil, math.MaxUint64, big.NewInt(123), big.NewInt(0))
 	assert.Nil(err)
 	assert.True(leftOverGas < math.MaxUint64)
 	assert.Equal([]byte{0x3}, ret)
 }
+
+func TestStakeQueryPrecompiles(t *testing.T) {
+	assert := assert.New(t)
+
+	store := state.NewStoreView(0, common.Hash{}, backend.NewMemDatabase())
+	evm := NewEVM(Context{}, store, nil, Config{})
+	holder := common.HexToAddress("0x1000")
+	source1 := common.HexToAddress("0x2001")
+	source2 := common.HexToAddress("0x2002")
+	beneficiary := common.HexToAddress("0x3000")
+	blsKey, err := bls.RandKey()
+	assert.Nil(err)
+
+	amount1 := new(big.Int).Set(core.MinLightningStakeDeposit)
+	amount2 := new(big.Int).Mul(core.MinLightningStakeDeposit, big.NewInt(2))
+	gcp := store.GetLightningCandidatePool()
+	assert.Nil(gcp.DepositStake(source1, holder, amount1, blsKey.PublicKey(), 1))
+	assert.Nil(gcp.DepositStake(source2, holder, amount2, blsKey.PublicKey(), 1))
+	assert.Nil(gcp.WithdrawStake(source2, holder, 10))
+	store.UpdateLightningCandidatePool(gcp)
+
+	eenp := state.NewEliteEdgeNodePool(store, false)
+	assert.Nil(eenp.DepositStake(source1, holder, core.MinEliteEdgeNodeStakeDeposit, blsKey.PublicKey(), 1))
+
+	rd, err := core.NewRewardDistribution(holder, beneficiary, 250)
+	assert.Nil(err)
+	state.NewStakeRewardDistributionRuleSet(store).Upsert(rd)
+
+	word := func(v uint64) []byte {
+		return common.LeftPadBytes(new(big.Int).SetUint64(v).Bytes(), 32)
+	}
+	bigWord := func(v *big.Int) []byte {
+		return common.LeftPadBytes(v.Bytes(), 32)
+	}
+	input := func(addrs ...common.Address) []byte {
+		ret := []byte{}
+		for _, addr := range addrs {
+			ret = append(ret, common.LeftPadBytes(addr.Bytes(), 32)...)
+		}
+		return ret
+	}
+
+	// The withdrawn stake does not count in the total stake
+	ret, err := (&lightningStake{}).Run(evm, input(holder, source1), common.Address{}, nil)
+	assert.Nil(err)
+	assert.Equal(bytes.Join([][]byte{bigWord(amount1), bigWord(amount1), word(0), word(0)}, nil), ret)
+
+	ret, err = (&lightningStake{}).Run(evm, input(holder, source2), common.Address{}, nil)
+	assert.Nil(err)
+	returnHeight := 10 + core.ReturnLockingPeriod
+	assert.Equal(bytes.Join([][]byte{bigWord(amount1), bigWord(amount2), word(1), word(returnHeight)}, nil), ret)
+
+	ret, err = (&lightningStake{}).Run(evm, input(source1, source1), common.Address{}, nil)
+	assert.Nil(err)
+	assert.Equal(make([]byte, 128), ret)
+
+	ret, err = (&eenStake{}).Run(evm, input(holder, source1), common.Address{}, nil)
+	assert.Nil(err)
+	minEENStake := core.MinEliteEdgeNodeStakeDeposit
+	assert.Equal(bytes.Join([][]byte{bigWord(minEENStake), bigWord(minEENStake), word(0), word(0)}, nil), ret)
+
+	ret, err = (&eenStake{}).Run(evm, input(holder, source2), common.Address{}, nil)
+	assert.Nil(err)
+	assert.Equal(bytes.Join([][]byte{bigWord(minEENStake), word(0), word(0), word(0)}, nil), ret)
+
+	ret, err = (&stakeRewardDistribution{}).Run(evm, input(holder), common.Address{}, nil)
+	assert.Nil(err)
+	assert.Equal(append(input(beneficiary), word(250)...), ret)
+
+	ret, err = (&stakeRewardDistribution{}).Run(evm, input(source1), common.Address{}, nil)
+	assert.Nil(err)
+	assert.Equal(make([]byte, 64), ret)
+}
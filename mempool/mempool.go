@@ -196,8 +196,8 @@ func (mp *Mempool) InsertTransaction(rawTx common.Bytes) error {
 	var txInfo *core.TxInfo
 	var checkTxRes result.Result
 
-	// Delay tx verification when in fast sync
-	if mp.consensus.HasSynced() {
+	// Delay tx verification when in fast sync. The mempool has no consensus engine in the tests
+	if mp.consensus == nil || mp.consensus.HasSynced() {
 		txInfo, checkTxRes = mp.ledger.ScreenTx(rawTx)
 		if !checkTxRes.IsOK() {
 			logger.Debugf("Transaction screening failed, tx: %v, error: %v", hex.EncodeToString(rawTx), checkTxRes.Message)
@@ -280,6 +280,15 @@ func (mp *Mempool) Reap(maxNumTxs int) []common.Bytes {
 
 // ReapUnsafe is the non-locking version of Reap.
 func (mp *Mempool) ReapUnsafe(maxNumTxs int) []common.Bytes {
+	return mp.ReapUnsafeWithGasLimit(maxNumTxs, 0, nil)
+}
+
+// ReapUnsafeWithGasLimit is the non-locking version of Reap, which also caps the total
+// gas limit of the reaped transactions. The transactions of an account are skipped from
+// the first one exceeding the remaining gas, and stay in the candidate pool. gasLimit == 0
+// means uncapped. The dynamic fee transactions are re-priced under the given base fee
+// before reaping, baseFee == nil keeps their prices.
+func (mp *Mempool) ReapUnsafeWithGasLimit(maxNumTxs int, gasLimit uint64, baseFee *big.Int) []common.Bytes {
 	if maxNumTxs == 0 {
 		return []common.Bytes{}
 	} else if maxNumTxs < 0 {
@@ -288,13 +297,28 @@ func (mp *Mempool) ReapUnsafe(maxNumTxs int) []common.Bytes {
 		maxNumTxs = math.MinInt(mp.Size(), maxNumTxs)
 	}
 
+	if baseFee != nil {
+		mp.updateEffectiveGasPrices(baseFee)
+	}
+
 	txs := make([]common.Bytes, 0, maxNumTxs)
-	for i := 0; i < maxNumTxs; i++ {
+	skippedGroups := []*mempoolTransactionGroup{}
+	remainingGas := gasLimit
+	for i := 0; i < maxNumTxs; {
 		if mp.candidateTxs.IsEmpty() {
 			break
 		}
 		txGroup := mp.candidateTxs.Pop().(*mempoolTransactionGroup)
+		if gasLimit > 0 {
+			txGasLimit := txGroup.txs.Peek().(*mempoolTransaction).txInfo.GasLimit
+			if txGasLimit > remainingGas {
+				skippedGroups = append(skippedGroups, txGroup)
+				continue
+			}
+			remainingGas -= txGasLimit
+		}
 		rawTx, txInfo := txGroup.PopTx()
+		i++
 
 		// Check for outdated txs
 		txHash := getTransactionHash(rawTx)
@@ -314,11 +338,27 @@ func (mp *Mempool) ReapUnsafe(maxNumTxs int) []common.Bytes {
 			hex.EncodeToString(rawTx), txInfo)
 	}
 
+	for _, txGroup := range skippedGroups {
+		mp.candidateTxs.Push(txGroup)
+	}
+
 	mp.size -= len(txs)
 
 	return txs
 }
 
+// updateEffectiveGasPrices re-prices the dynamic fee transactions under the given base fee,
+// and re-sorts the transaction groups by their new priorities
+func (mp *Mempool) updateEffectiveGasPrices(baseFee *big.Int) {
+	for _, txGroupEl := range *mp.candidateTxs.ElementList() {
+		txGroup := txGroupEl.(*mempoolTransactionGroup)
+		for _, txEl := range *txGroup.txs.ElementList() {
+			txEl.(*mempoolTransaction).txInfo.UpdateEffectiveGasPrice(baseFee)
+		}
+	}
+	mp.candidateTxs.Reorder()
+}
+
 // Update removes the committed transactions from the transaction candidate list
 // RUNTIME COMPLEXITY: O(k + n), where k is the number committed raw transactions,
 // and n is the number of transactions in the candidate pool.
@@ -423,6 +463,25 @@ func (mp *Mempool) GetCandidateTransactionHashes() []string {
 	return txHashes
 }
 
+// GetCandidateTransactions returns the raw transactions in the candidate pool
+func (mp *Mempool) GetCandidateTransactions() []common.Bytes {
+	mp.mutex.Lock()
+	defer mp.mutex.Unlock()
+
+	rawTxs := []common.Bytes{}
+	txgElemList := mp.candidateTxs.ElementList()
+	for _, txgElem := range *txgElemList {
+		txg := txgElem.(*mempoolTransactionGroup)
+		txElemList := txg.txs.ElementList()
+		for _, txElem := range *txElemList {
+			tx := txElem.(*mempoolTransaction)
+			rawTxs = append(rawTxs, tx.rawTransaction)
+		}
+	}
+
+	return rawTxs
+}
+
 // Flush removes all transactions from the Mempool and the transactionBookkeeper
 func (mp *Mempool) Flush() {
 	mp.mutex.Lock()
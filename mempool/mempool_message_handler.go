@@ -8,6 +8,7 @@ import (
 
 	"github.com/scripttoken/script/common"
 	dp "github.com/scripttoken/script/dispatcher"
+	"github.com/scripttoken/script/p2p/reputation"
 	"github.com/scripttoken/script/p2p/types"
 	"github.com/scripttoken/script/rlp"
 )
@@ -50,7 +51,9 @@ func Fuzz(data []byte) int {
 // ParseMessage implements the p2p.MessageHandler interface
 func (mmh *MempoolMessageHandler) ParseMessage(peerID string, channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (types.Message, error) {
 	var dataResponse dp.DataResponse
-	rlp.DecodeBytes(rawMessageBytes, &dataResponse)
+	if err := rlp.DecodeBytes(rawMessageBytes, &dataResponse); err != nil {
+		mmh.reportPeer(peerID, reputation.InfractionMalformedMessage, err.Error())
+	}
 
 	rawTx := dataResponse.Payload
 	message := types.Message{
@@ -66,6 +69,9 @@ func (mmh *MempoolMessageHandler) HandleMessage(message types.Message) error {
 	if message.ChannelID != common.ChannelIDTransaction {
 		return fmt.Errorf("Invalid channel for MempoolMessageHandler: %v", message.ChannelID)
 	}
+	if !mmh.allowMessage(message.PeerID, message.ChannelID) {
+		return nil
+	}
 	rawTx := message.Content.(common.Bytes)
 	logger.Debugf("Received gossiped transaction: %v", hex.EncodeToString(rawTx))
 
@@ -86,3 +92,17 @@ func (mmh *MempoolMessageHandler) HandleMessage(message types.Message) error {
 
 	return nil
 }
+
+func (mmh *MempoolMessageHandler) reportPeer(peerID string, infraction reputation.Infraction, reason string) {
+	if mmh.mempool.dispatcher != nil {
+		mmh.mempool.dispatcher.ReportPeer(peerID, infraction, reason)
+	}
+}
+
+// allowMessage drops the transactions of the peers flooding the mempool channel
+func (mmh *MempoolMessageHandler) allowMessage(peerID string, channelID common.ChannelIDEnum) bool {
+	if mmh.mempool.dispatcher != nil {
+		return mmh.mempool.dispatcher.AllowMessage(peerID, channelID)
+	}
+	return true
+}
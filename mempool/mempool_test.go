@@ -197,6 +197,95 @@ func TestMempoolReapOrder(t *testing.T) {
 	assert.Equal("tx3", string(reapedRawTxs[9][:]))  // gasPrice: 32, address: A3, seq: 2012
 }
 
+func TestMempoolReapWithGasLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+	ledger := newTestLedger().(*TestLedger)
+	ledger.gasLimitList = []uint64{
+		0,   // tx1
+		0,   // tx2
+		100, // tx3
+		40,  // tx4
+		10,  // tx5
+		5,   // tx6
+		30,  // tx7
+		20,  // tx8
+		60,  // tx9
+		50,  // tx10
+	}
+	mempool.SetLedger(ledger)
+
+	for i := 1; i <= 10; i++ {
+		assert.Nil(mempool.InsertTransaction(createTestRawTx("tx" + strconv.Itoa(i))))
+	}
+
+	reapedTxs := func(maxNumTxs int, gasLimit uint64) []string {
+		mempool.Lock()
+		defer mempool.Unlock()
+		txs := []string{}
+		for _, rawTx := range mempool.ReapUnsafeWithGasLimit(maxNumTxs, gasLimit, nil) {
+			txs = append(txs, string(rawTx))
+		}
+		return txs
+	}
+
+	// tx10 (A4), tx8 (B1) and tx4 (A1) exceed the remaining gas, the later transactions of B1 and A1
+	// are skipped with them even if they fit, while tx6 with a lower priority still fits
+	assert.Equal([]string{"tx2", "tx9", "tx7", "tx6"}, reapedTxs(-1, 100))
+	assert.Equal(6, mempool.Size())
+
+	// The skipped transactions stay in the candidate pool
+	assert.Equal([]string{"tx10", "tx8", "tx5"}, reapedTxs(-1, 100))
+	assert.Equal(3, mempool.Size())
+
+	// A transaction exceeding the block gas limit is never reaped
+	assert.Equal([]string{"tx4", "tx1"}, reapedTxs(-1, 99))
+	assert.Equal(1, mempool.Size())
+
+	// Uncapped
+	assert.Equal([]string{"tx3"}, reapedTxs(-1, 0))
+	assert.Equal(0, mempool.Size())
+}
+
+func TestMempoolReapRepricesDynamicFeeTxs(t *testing.T) {
+	assert := assert.New(t)
+
+	p2psimnet := p2psim.NewSimnetWithHandler(nil)
+	mempool, _ := newTestMempool("peer0", p2psimnet)
+	ledger := newTestLedger().(*TestLedger)
+	ledger.tipList = []uint64{
+		0,   // tx1
+		100, // tx2
+		0,   // tx3
+		0,   // tx4
+		0,   // tx5
+		0,   // tx6
+		0,   // tx7
+		0,   // tx8
+		10,  // tx9
+		0,   // tx10
+	}
+	mempool.SetLedger(ledger)
+
+	for i := 1; i <= 10; i++ {
+		assert.Nil(mempool.InsertTransaction(createTestRawTx("tx" + strconv.Itoa(i))))
+	}
+
+	mempool.Lock()
+	defer mempool.Unlock()
+	reapedTxs := []string{}
+	for _, rawTx := range mempool.ReapUnsafeWithGasLimit(-1, 0, big.NewInt(1000)) {
+		reapedTxs = append(reapedTxs, string(rawTx))
+	}
+
+	// Under the base fee of 1000, tx2 and tx9 only pay 1100 and 1010 per gas instead of their
+	// max fees 234234 and 9273, which moves them behind tx5 (gasPrice: 2392992)
+	assert.Equal([]string{"tx10", "tx7", "tx8", "tx5", "tx2", "tx9", "tx4", "tx1"}, reapedTxs[:8])
+	assert.ElementsMatch([]string{"tx6", "tx3"}, reapedTxs[8:]) // same gasPrice: 32
+}
+
 func TestMempoolUpdate(t *testing.T) {
 	assert := assert.New(t)
 
@@ -374,9 +463,11 @@ func TestMempoolTransactionGossip(t *testing.T) {
 	tx2 := createTestRawTx("tx2")
 	tx3 := createTestRawTx("tx3")
 
-	assert.Nil(mempool.InsertTransaction(tx1))
-	assert.Nil(mempool.InsertTransaction(tx2))
-	assert.Nil(mempool.InsertTransaction(tx3))
+	// The transactions submitted by the clients are broadcasted after the insertion, as in the RPC
+	for _, tx := range []common.Bytes{tx1, tx2, tx3} {
+		assert.Nil(mempool.InsertTransaction(tx))
+		mempool.BroadcastTx(tx)
+	}
 	assert.Equal(3, mempool.Size())
 	log.Infof(">>> Client submitted tx1, tx2, tx3")
 
@@ -397,8 +488,8 @@ func newTestMempool(peerID string, simnet *p2psim.Simnet) (*Mempool, context.Con
 	ctx := context.Background()
 
 	messenger := simnet.AddEndpoint(peerID)
-	dispatcher := dp.NewDispatcher(messenger, nil)
-	mempool := CreateMempool(dispatcher)
+	dispatcher := dp.NewDispatcher(messenger)
+	mempool := CreateMempool(dispatcher, nil)
 	mempool.SetLedger(newTestLedger())
 	txMsgHandler := CreateMempoolMessageHandler(mempool)
 	messenger.RegisterMessageHandler(txMsgHandler)
@@ -411,6 +502,8 @@ type TestLedger struct {
 	effectiveGasPriceList []uint64
 	addressList           []string
 	sequenceList          []uint64
+	gasLimitList          []uint64 // optional, the gas limits of the smart contract transactions
+	tipList               []uint64 // optional, a non-zero tip makes a dynamic fee transaction capped at its gas price
 }
 
 func newTestLedger() core.Ledger {
@@ -466,6 +559,13 @@ func (tl *TestLedger) ScreenTx(rawTx common.Bytes) (*core.TxInfo, result.Result)
 		Address:           common.HexToAddress(tl.addressList[tl.counter]),
 		Sequence:          tl.sequenceList[tl.counter],
 	}
+	if tl.gasLimitList != nil {
+		txInfo.GasLimit = tl.gasLimitList[tl.counter]
+	}
+	if tl.tipList != nil && tl.tipList[tl.counter] > 0 {
+		txInfo.MaxFeePerGas = txInfo.EffectiveGasPrice
+		txInfo.MaxPriorityFeePerGas = new(big.Int).SetUint64(tl.tipList[tl.counter])
+	}
 	tl.counter = (tl.counter + 1) % len(tl.effectiveGasPriceList)
 	return txInfo, result.OK
 }
@@ -482,7 +582,7 @@ func (tl *TestLedger) ApplyBlockTxs(block *core.Block) result.Result {
 	return result.OK
 }
 
-func (tl *TestLedger) ResetState(height uint64, rootHash common.Hash) result.Result {
+func (tl *TestLedger) ResetState(block *core.Block) result.Result {
 	return result.OK
 }
 
@@ -498,6 +598,10 @@ func (tl *TestLedger) GetLightningCandidatePool(blockHash common.Hash) (*core.Li
 	return nil, nil
 }
 
+func (tl *TestLedger) GetEliteEdgeNodePoolOfLastCheckpoint(blockHash common.Hash) (core.EliteEdgeNodePool, error) {
+	return nil, nil
+}
+
 func (tl *TestLedger) PruneState(endHeight uint64) error {
 	return nil
 }
@@ -0,0 +1,330 @@
+package netsync
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/crypto"
+	"github.com/scripttoken/script/dispatcher"
+	"github.com/scripttoken/script/p2p/reputation"
+	"github.com/scripttoken/script/rlp"
+)
+
+const (
+	compactBlockCacheLimit = 64
+	compactBlockTimeout    = RequestTimeout
+)
+
+//
+// CompactBlock is the payload of the DataResponse sent over the ChannelIDCompactBlock
+// channel. It carries the block header, and the short IDs of the block transactions so
+// that the receiver can rebuild the block from its mempool. The transactions the
+// receiver is unlikely to have, i.e. the coinbase transaction, are prefilled.
+//
+// It is also the response to a request for the transactions missing from the receiver's
+// mempool, in which case ShortIDs is empty and Txs contains the requested transactions.
+//
+type CompactBlock struct {
+	Header   *core.BlockHeader
+	ShortIDs []uint64
+	Txs      []PrefilledTx
+}
+
+// PrefilledTx is a transaction of a compact block and its index in the block
+type PrefilledTx struct {
+	Index uint64
+	Tx    common.Bytes
+}
+
+// TxSource provides the transactions compact blocks are rebuilt from, i.e. the mempool
+type TxSource interface {
+	GetCandidateTransactions() []common.Bytes
+}
+
+// shortTxID returns the short ID of a raw transaction, the first 8 bytes of its hash
+func shortTxID(rawTx common.Bytes) uint64 {
+	return binary.BigEndian.Uint64(crypto.Keccak256(rawTx)[:8])
+}
+
+// NewCompactBlock creates the compact block of the given block
+func NewCompactBlock(block *core.Block) *CompactBlock {
+	cb := &CompactBlock{
+		Header:   block.BlockHeader,
+		ShortIDs: make([]uint64, len(block.Txs)),
+	}
+	for i, rawTx := range block.Txs {
+		cb.ShortIDs[i] = shortTxID(rawTx)
+	}
+	if len(block.Txs) > 0 {
+		// The coinbase transaction is never in the mempool
+		cb.Txs = []PrefilledTx{{Index: 0, Tx: block.Txs[0]}}
+	}
+	return cb
+}
+
+//
+// compactBlockReconstruction tracks a compact block being rebuilt from the mempool, while
+// the missing transactions are requested from the peer which sent it.
+//
+type compactBlockReconstruction struct {
+	header    *core.BlockHeader
+	txs       []common.Bytes
+	missing   map[uint64]bool
+	peerID    string
+	createdAt time.Time
+}
+
+// newCompactBlockReconstruction fills the transactions of the compact block from the
+// prefilled ones and the given pool. Transactions with ambiguous short IDs are left
+// missing.
+func newCompactBlockReconstruction(cb *CompactBlock, pool []common.Bytes, peerID string) (*compactBlockReconstruction, error) {
+	if cb.Header == nil {
+		return nil, errors.New("compact block header is missing")
+	}
+
+	r := &compactBlockReconstruction{
+		header:    cb.Header,
+		txs:       make([]common.Bytes, len(cb.ShortIDs)),
+		missing:   make(map[uint64]bool),
+		peerID:    peerID,
+		createdAt: time.Now(),
+	}
+
+	wanted := make(map[uint64]bool, len(cb.ShortIDs))
+	for _, id := range cb.ShortIDs {
+		wanted[id] = true
+	}
+	found := make(map[uint64]common.Bytes)
+	for _, rawTx := range pool {
+		id := shortTxID(rawTx)
+		if !wanted[id] {
+			continue
+		}
+		if _, ok := found[id]; ok {
+			found[id] = nil // collision, request the transaction from the peer
+			continue
+		}
+		found[id] = rawTx
+	}
+
+	for i, id := range cb.ShortIDs {
+		if rawTx := found[id]; rawTx != nil {
+			r.txs[i] = rawTx
+		} else {
+			r.missing[uint64(i)] = true
+		}
+	}
+
+	for _, tx := range cb.Txs {
+		if tx.Index >= uint64(len(r.txs)) {
+			return nil, fmt.Errorf("prefilled transaction index out of range: %v", tx.Index)
+		}
+		r.txs[tx.Index] = tx.Tx
+		delete(r.missing, tx.Index)
+	}
+	return r, nil
+}
+
+// fill adds the missing transactions sent by the peer
+func (r *compactBlockReconstruction) fill(txs []PrefilledTx) error {
+	for _, tx := range txs {
+		if !r.missing[tx.Index] {
+			return fmt.Errorf("unexpected transaction index: %v", tx.Index)
+		}
+		r.txs[tx.Index] = tx.Tx
+		delete(r.missing, tx.Index)
+	}
+	if len(r.missing) > 0 {
+		return fmt.Errorf("%v transactions still missing", len(r.missing))
+	}
+	return nil
+}
+
+// missingEntries returns the DataRequest entries requesting the missing transactions
+func (r *compactBlockReconstruction) missingEntries() []string {
+	entries := []string{r.header.Hash().Hex()}
+	for i := range r.txs {
+		if r.missing[uint64(i)] {
+			entries = append(entries, strconv.FormatUint(uint64(i), 10))
+		}
+	}
+	return entries
+}
+
+// block returns the rebuilt block. It fails if the transactions do not match the
+// header, e.g. due to a short ID collision.
+func (r *compactBlockReconstruction) block() (*core.Block, error) {
+	if len(r.missing) > 0 {
+		return nil, fmt.Errorf("%v transactions still missing", len(r.missing))
+	}
+	if core.CalculateRootHash(r.txs) != r.header.TxHash {
+		return nil, errors.New("rebuilt transactions do not match the TxHash")
+	}
+	return &core.Block{BlockHeader: r.header, Txs: r.txs}, nil
+}
+
+// SetTxSource sets the source of the transactions compact blocks are rebuilt from
+func (sm *SyncManager) SetTxSource(txSource TxSource) {
+	sm.txSource = txSource
+}
+
+// sendCompactBlock handles a DataRequest on the ChannelIDCompactBlock channel. The first
+// entry is the block hash, the following ones, if any, the indexes of the requested
+// transactions.
+func (m *SyncManager) sendCompactBlock(peerID string, entries []string) {
+	if len(entries) == 0 {
+		return
+	}
+	hash := common.HexToHash(entries[0])
+	block, err := m.chain.FindBlock(hash)
+	if err != nil {
+		m.logger.WithFields(log.Fields{
+			"hashStr": entries[0],
+			"err":     err,
+			"peerID":  peerID,
+		}).Debug("Failed to find hash string locally")
+		return
+	}
+
+	var cb *CompactBlock
+	if len(entries) == 1 {
+		cb = NewCompactBlock(block.Block)
+	} else {
+		if len(entries)-1 > len(block.Txs) {
+			m.dispatcher.ReportPeer(peerID, reputation.InfractionMalformedMessage, "too many transactions requested")
+			return
+		}
+		cb = &CompactBlock{Header: block.BlockHeader}
+		for _, idxStr := range entries[1:] {
+			idx, err := strconv.ParseUint(idxStr, 10, 64)
+			if err != nil || idx >= uint64(len(block.Txs)) {
+				m.dispatcher.ReportPeer(peerID, reputation.InfractionMalformedMessage, "invalid transaction index "+idxStr)
+				return
+			}
+			cb.Txs = append(cb.Txs, PrefilledTx{Index: idx, Tx: block.Txs[idx]})
+		}
+	}
+
+	payload, err := rlp.EncodeToBytes(cb)
+	if err != nil {
+		m.logger.WithFields(log.Fields{
+			"block":  block,
+			"peerID": peerID,
+		}).Error("Failed to encode compact block")
+		return
+	}
+	data := dispatcher.DataResponse{
+		ChannelID: common.ChannelIDCompactBlock,
+		Payload:   payload,
+	}
+	m.logger.WithFields(log.Fields{
+		"hashStr": entries[0],
+		"numTxs":  len(cb.Txs),
+		"peerID":  peerID,
+	}).Debug("Sending compact block")
+	m.dispatcher.SendData([]string{peerID}, data)
+}
+
+// handleCompactBlock rebuilds the block from the mempool, and requests the missing
+// transactions from the peer. It falls back to downloading the full block if the block
+// cannot be rebuilt.
+func (m *SyncManager) handleCompactBlock(cb *CompactBlock, peerID string) {
+	if cb.Header == nil {
+		m.dispatcher.ReportPeer(peerID, reputation.InfractionMalformedMessage, "compact block header is missing")
+		return
+	}
+	hash := cb.Header.Hash()
+	m.purgeCompactBlocks()
+
+	var r *compactBlockReconstruction
+	if cached, ok := m.compactBlocks.Get(hash); ok && len(cb.ShortIDs) == 0 {
+		// Response to the request for the missing transactions
+		r = cached.(*compactBlockReconstruction)
+		m.compactBlocks.Remove(hash)
+		if err := r.fill(cb.Txs); err != nil {
+			m.logger.WithFields(log.Fields{
+				"hash":   hash.Hex(),
+				"err":    err,
+				"peerID": peerID,
+			}).Debug("Failed to fill compact block")
+			m.requestFullBlock(peerID, hash)
+			return
+		}
+	} else {
+		if eb, err := m.chain.FindBlock(hash); err == nil && !eb.Status.IsPending() {
+			return
+		}
+		var pool []common.Bytes
+		if m.txSource != nil {
+			pool = m.txSource.GetCandidateTransactions()
+		}
+		var err error
+		r, err = newCompactBlockReconstruction(cb, pool, peerID)
+		if err != nil {
+			m.dispatcher.ReportPeer(peerID, reputation.InfractionMalformedMessage, err.Error())
+			return
+		}
+		if len(r.missing) > 0 {
+			m.compactBlocks.Add(hash, r)
+			request := dispatcher.DataRequest{
+				ChannelID: common.ChannelIDCompactBlock,
+				Entries:   r.missingEntries(),
+			}
+			m.logger.WithFields(log.Fields{
+				"hash":    hash.Hex(),
+				"missing": len(r.missing),
+				"total":   len(r.txs),
+				"peerID":  peerID,
+			}).Debug("Requesting missing transactions of compact block")
+			m.dispatcher.GetData([]string{peerID}, request)
+			return
+		}
+	}
+
+	block, err := r.block()
+	if err != nil {
+		m.logger.WithFields(log.Fields{
+			"hash":   hash.Hex(),
+			"err":    err,
+			"peerID": peerID,
+		}).Debug("Failed to rebuild compact block")
+		m.requestFullBlock(peerID, hash)
+		return
+	}
+	m.logger.WithFields(log.Fields{
+		"block.Hash":   hash.Hex(),
+		"block.Height": block.Height,
+		"peer":         peerID,
+	}).Debug("Received compact block")
+	m.handleBlock(block, peerID, false)
+}
+
+// purgeCompactBlocks falls back to downloading the full blocks for the compact blocks
+// whose missing transactions did not arrive in time
+func (m *SyncManager) purgeCompactBlocks() {
+	for _, key := range m.compactBlocks.Keys() {
+		cached, ok := m.compactBlocks.Peek(key)
+		if !ok {
+			continue
+		}
+		r := cached.(*compactBlockReconstruction)
+		if time.Since(r.createdAt) > compactBlockTimeout {
+			m.compactBlocks.Remove(key)
+			m.requestFullBlock(r.peerID, key.(common.Hash))
+		}
+	}
+}
+
+func (m *SyncManager) requestFullBlock(peerID string, hash common.Hash) {
+	request := dispatcher.DataRequest{
+		ChannelID: common.ChannelIDBlock,
+		Entries:   []string{hash.Hex()},
+	}
+	m.dispatcher.GetData([]string{peerID}, request)
+}
@@ -0,0 +1,338 @@
+package netsync
+
+import (
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/scripttoken/script/blockchain"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/dispatcher"
+	"github.com/scripttoken/script/p2p/types"
+	"github.com/scripttoken/script/rlp"
+)
+
+func TestCompactBlockReconstruction(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	txs := testTxs("coinbase", "tx1", "tx2", "tx3")
+	block := newTestBlockWithTxs(core.CreateTestBlock("A0", ""), txs)
+	cb := NewCompactBlock(block)
+	assert.Equal(4, len(cb.ShortIDs))
+	assert.Equal([]PrefilledTx{{Index: 0, Tx: txs[0]}}, cb.Txs)
+
+	// All the transactions are in the pool
+	r, err := newCompactBlockReconstruction(cb, testTxs("tx3", "other", "tx1", "tx2"), "peer1")
+	assert.Nil(err)
+	assert.Equal(0, len(r.missing))
+	rebuilt, err := r.block()
+	assert.Nil(err)
+	assert.Equal(block.Hash(), rebuilt.Hash())
+	assert.Equal(block.Txs, rebuilt.Txs)
+
+	// tx2 is missing from the pool
+	r, err = newCompactBlockReconstruction(cb, testTxs("tx1", "tx3"), "peer1")
+	assert.Nil(err)
+	assert.Equal(map[uint64]bool{2: true}, r.missing)
+	assert.Equal([]string{block.Hash().Hex(), "2"}, r.missingEntries())
+	_, err = r.block()
+	assert.NotNil(err)
+
+	assert.NotNil(r.fill([]PrefilledTx{{Index: 1, Tx: txs[1]}})) // not requested
+	assert.Nil(r.fill([]PrefilledTx{{Index: 2, Tx: txs[2]}}))
+	rebuilt, err = r.block()
+	assert.Nil(err)
+	assert.Equal(block.Txs, rebuilt.Txs)
+
+	// Transactions not matching the TxHash
+	r, err = newCompactBlockReconstruction(cb, testTxs("tx1", "tx3"), "peer1")
+	assert.Nil(err)
+	assert.Nil(r.fill([]PrefilledTx{{Index: 2, Tx: common.Bytes("forged")}}))
+	_, err = r.block()
+	assert.NotNil(err)
+
+	// Malformed compact blocks
+	_, err = newCompactBlockReconstruction(&CompactBlock{ShortIDs: cb.ShortIDs}, nil, "peer1")
+	assert.NotNil(err)
+	malformed := &CompactBlock{Header: cb.Header, ShortIDs: cb.ShortIDs, Txs: []PrefilledTx{{Index: 4, Tx: txs[0]}}}
+	_, err = newCompactBlockReconstruction(malformed, nil, "peer1")
+	assert.NotNil(err)
+}
+
+func TestHandleCompactBlock(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	chain := blockchain.CreateTestChainByBlocks([]string{"A1", "A0"})
+	txs := testTxs("coinbase", "tx1", "tx2")
+	block := newTestBlockWithTxs(core.GetTestBlock("A1"), txs)
+
+	net := NewMockNetwork("peer1")
+	sm := newCompactTestSyncManager(chain, net, testTxs("tx1", "tx2"))
+	sm.handleCompactBlock(NewCompactBlock(block), "peer1")
+
+	// The block is rebuilt from the mempool without any request
+	assert.Nil(net.NextSent())
+	eb, err := chain.FindBlock(block.Hash())
+	assert.Nil(err)
+	assert.Equal(txs, eb.Txs)
+}
+
+func TestHandleCompactBlockMissingTxs(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	chain := blockchain.CreateTestChainByBlocks([]string{"A1", "A0"})
+	txs := testTxs("coinbase", "tx1", "tx2", "tx3")
+	block := newTestBlockWithTxs(core.GetTestBlock("A1"), txs)
+
+	net := NewMockNetwork("peer1")
+	sm := newCompactTestSyncManager(chain, net, testTxs("tx2"))
+	sm.handleCompactBlock(NewCompactBlock(block), "peer1")
+
+	// The missing transactions are requested from the peer
+	sent := net.NextSent()
+	assert.NotNil(sent)
+	assert.Equal("peer1", sent.PeerID)
+	assert.Equal(dispatcher.DataRequest{
+		ChannelID: common.ChannelIDCompactBlock,
+		Entries:   []string{block.Hash().Hex(), "1", "3"},
+	}, sent.Message.Content)
+	_, err := chain.FindBlock(block.Hash())
+	assert.NotNil(err)
+
+	// The peer serves the requested transactions
+	peerChain := blockchain.CreateTestChainByBlocks([]string{"A1", "A0"})
+	peerChain.AddBlock(block)
+	peerNet := NewMockNetwork("local")
+	peerSM := newCompactTestSyncManager(peerChain, peerNet, nil)
+	peerSM.sendCompactBlock("local", sent.Message.Content.(dispatcher.DataRequest).Entries)
+	response := peerNet.NextSent()
+	assert.NotNil(response)
+	cb := decodeCompactBlock(assert, response.Message.Content)
+	assert.Equal(0, len(cb.ShortIDs))
+	assert.Equal([]PrefilledTx{{Index: 1, Tx: txs[1]}, {Index: 3, Tx: txs[3]}}, cb.Txs)
+
+	sm.handleCompactBlock(cb, "peer1")
+	assert.Nil(net.NextSent())
+	eb, err := chain.FindBlock(block.Hash())
+	assert.Nil(err)
+	assert.Equal(txs, eb.Txs)
+}
+
+func TestHandleCompactBlockFallback(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	chain := blockchain.CreateTestChainByBlocks([]string{"A1", "A0"})
+	txs := testTxs("coinbase", "tx1", "tx2")
+	block := newTestBlockWithTxs(core.GetTestBlock("A1"), txs)
+	fullBlockRequest := dispatcher.DataRequest{
+		ChannelID: common.ChannelIDBlock,
+		Entries:   []string{block.Hash().Hex()},
+	}
+
+	// The transactions sent by the peer do not match the TxHash
+	net := NewMockNetwork("peer1")
+	sm := newCompactTestSyncManager(chain, net, testTxs("tx1"))
+	sm.handleCompactBlock(NewCompactBlock(block), "peer1")
+	assert.NotNil(net.NextSent())
+	sm.handleCompactBlock(&CompactBlock{Header: block.BlockHeader, Txs: []PrefilledTx{{Index: 2, Tx: common.Bytes("forged")}}}, "peer1")
+	sent := net.NextSent()
+	assert.NotNil(sent)
+	assert.Equal("peer1", sent.PeerID)
+	assert.Equal(fullBlockRequest, sent.Message.Content)
+
+	// The peer does not send all the requested transactions
+	sm.handleCompactBlock(NewCompactBlock(block), "peer1")
+	assert.NotNil(net.NextSent())
+	sm.handleCompactBlock(&CompactBlock{Header: block.BlockHeader, Txs: []PrefilledTx{}}, "peer1")
+	sent = net.NextSent()
+	assert.NotNil(sent)
+	assert.Equal(fullBlockRequest, sent.Message.Content)
+
+	// The peer does not answer in time
+	sm.handleCompactBlock(NewCompactBlock(block), "peer1")
+	assert.NotNil(net.NextSent())
+	cached, ok := sm.compactBlocks.Peek(block.Hash())
+	assert.True(ok)
+	cached.(*compactBlockReconstruction).createdAt = time.Now().Add(-compactBlockTimeout - time.Second)
+	sm.purgeCompactBlocks()
+	sent = net.NextSent()
+	assert.NotNil(sent)
+	assert.Equal(fullBlockRequest, sent.Message.Content)
+	assert.Equal(0, sm.compactBlocks.Len())
+
+	_, err := chain.FindBlock(block.Hash())
+	assert.NotNil(err)
+}
+
+func TestSendCompactBlock(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	chain := blockchain.CreateTestChainByBlocks([]string{"A1", "A0"})
+	txs := testTxs("coinbase", "tx1", "tx2")
+	block := newTestBlockWithTxs(core.GetTestBlock("A1"), txs)
+	chain.AddBlock(block)
+
+	net := NewMockNetwork("peer1")
+	sm := newCompactTestSyncManager(chain, net, nil)
+
+	sm.sendCompactBlock("peer1", []string{block.Hash().Hex()})
+	sent := net.NextSent()
+	assert.NotNil(sent)
+	assert.Equal("peer1", sent.PeerID)
+	cb, expected := decodeCompactBlock(assert, sent.Message.Content), NewCompactBlock(block)
+	assert.Equal(block.Hash(), cb.Header.Hash())
+	assert.Equal(expected.ShortIDs, cb.ShortIDs)
+	assert.Equal(expected.Txs, cb.Txs)
+
+	// Out of range and too many transactions are not served
+	sm.sendCompactBlock("peer1", []string{block.Hash().Hex(), strconv.Itoa(len(txs))})
+	sm.sendCompactBlock("peer1", []string{block.Hash().Hex(), "0", "1", "2", "0"})
+	// Unknown block
+	sm.sendCompactBlock("peer1", []string{core.CreateTestBlock("B1", "A0").Hash().Hex()})
+	assert.Nil(net.NextSent())
+}
+
+func TestShouldRequestCompactBlock(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	chain := blockchain.CreateTestChainByBlocks([]string{"A1", "A0"})
+	net := NewMockNetwork("capable", "incapable", "legacy")
+	net.Info["capable"] = &types.HandshakeInfo{Capabilities: []string{types.CapabilityCompactBlock}}
+	net.Info["incapable"] = &types.HandshakeInfo{}
+	sm := newCompactTestSyncManager(chain, net, nil)
+	rm := sm.requestMgr
+	rm.ifCompactBlock = true
+
+	gossiped := NewPendingBlock(core.CreateTestBlock("A2", "A1").Hash(), []string{"capable"}, true)
+	assert.True(rm.shouldRequestCompactBlock(gossiped, "capable"))
+	assert.False(rm.shouldRequestCompactBlock(gossiped, "incapable"))
+	assert.False(rm.shouldRequestCompactBlock(gossiped, "legacy"))
+
+	// The blocks downloaded by fast sync, and those far ahead of the tip, are unlikely
+	// to be in the mempool
+	fastsync := NewPendingBlock(core.GetTestBlock("A2").Hash(), []string{"capable"}, false)
+	assert.False(rm.shouldRequestCompactBlock(fastsync, "capable"))
+	ahead := NewPendingBlock(core.CreateTestBlock("A3", "A2").Hash(), []string{"capable"}, true)
+	ahead.header = core.GetTestBlock("A3").BlockHeader
+	assert.False(rm.shouldRequestCompactBlock(ahead, "capable"))
+
+	// The retries download the full block
+	gossiped.compactRequested = true
+	assert.False(rm.shouldRequestCompactBlock(gossiped, "capable"))
+
+	rm.ifCompactBlock = false
+	assert.False(rm.shouldRequestCompactBlock(NewPendingBlock(gossiped.hash, []string{"capable"}, true), "capable"))
+}
+
+func TestDownloadCompactBlock(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	chain := blockchain.CreateTestChainByBlocks([]string{"A1", "A0"})
+	hash := core.CreateTestBlock("A2", "A1").Hash()
+
+	for _, test := range []struct {
+		info      *types.HandshakeInfo
+		channelID common.ChannelIDEnum
+	}{
+		{&types.HandshakeInfo{Capabilities: []string{types.CapabilityCompactBlock}}, common.ChannelIDCompactBlock},
+		{&types.HandshakeInfo{}, common.ChannelIDBlock},
+		{nil, common.ChannelIDBlock}, // older version
+	} {
+		net := NewMockNetwork("peer1")
+		net.Info["peer1"] = test.info
+		sm := newCompactTestSyncManager(chain, net, nil)
+		rm := sm.requestMgr
+		rm.ifCompactBlock = true
+
+		// From the hash
+		rm.AddHash(hash, []string{"peer1"}, true)
+		rm.gossipQuota = 1
+		rm.downloadBlockFromHash()
+		sent := net.NextSent()
+		assert.NotNil(sent)
+		assert.Equal(dispatcher.DataRequest{ChannelID: test.channelID, Entries: []string{hash.String()}}, sent.Message.Content)
+	}
+
+	// From the header, the compact block is requested only once
+	net := NewMockNetwork("peer1")
+	net.Info["peer1"] = &types.HandshakeInfo{Capabilities: []string{types.CapabilityCompactBlock}}
+	sm := newCompactTestSyncManager(chain, net, nil)
+	rm := sm.requestMgr
+	rm.ifCompactBlock = true
+	rm.ifDownloadByHeader = true
+	rm.AddHeader(core.GetTestBlock("A2").BlockHeader, []string{"peer1"})
+
+	rm.fastsyncQuota = 1
+	rm.downloadBlockFromHeader()
+	sent := net.NextSent()
+	assert.NotNil(sent)
+	assert.Equal(dispatcher.DataRequest{ChannelID: common.ChannelIDCompactBlock, Entries: []string{hash.String()}}, sent.Message.Content)
+
+	// The retry after a timeout downloads the full block
+	pendingBlock := rm.pendingBlocksByHash[hash.String()].Value.(*PendingBlock)
+	pendingBlock.lastUpdate = time.Now().Add(-RequestTimeout - time.Second)
+	rm.fastsyncQuota = 1
+	rm.downloadBlockFromHeader()
+	sent = net.NextSent()
+	assert.NotNil(sent)
+	assert.Equal(dispatcher.DataRequest{ChannelID: common.ChannelIDBlock, Entries: []string{hash.String()}}, sent.Message.Content)
+}
+
+// --------------- Test Utilities --------------- //
+
+type mockTxSource struct {
+	txs []common.Bytes
+}
+
+func (ts *mockTxSource) GetCandidateTransactions() []common.Bytes {
+	return ts.txs
+}
+
+func testTxs(names ...string) []common.Bytes {
+	txs := []common.Bytes{}
+	for _, name := range names {
+		txs = append(txs, common.Bytes(name))
+	}
+	return txs
+}
+
+// newTestBlockWithTxs creates a signed child block of the given block
+func newTestBlockWithTxs(parent *core.Block, txs []common.Bytes) *core.Block {
+	block := core.NewBlock()
+	block.ChainID = parent.ChainID
+	block.Epoch = parent.Epoch + 1
+	block.Height = parent.Height + 1
+	block.Parent = parent.Hash()
+	block.HCC.BlockHash = parent.Hash()
+	block.Proposer = core.DefaultSigner.PublicKey().Address()
+	block.Timestamp = big.NewInt(time.Now().Unix())
+	block.AddTxs(txs)
+	block.Signature, _ = core.DefaultSigner.Sign(block.SignBytes())
+	return block
+}
+
+func newCompactTestSyncManager(chain *blockchain.Chain, net *MockNetwork, pool []common.Bytes) *SyncManager {
+	tip, _ := chain.FindBlock(core.GetTestBlock("A1").Hash())
+	sm := NewSyncManager(chain, NewMockConsensus(chain, tip), net, dispatcher.NewDispatcher(net), NewMockMessageConsumer(), nil)
+	sm.SetTxSource(&mockTxSource{txs: pool})
+	return sm
+}
+
+func decodeCompactBlock(assert *assert.Assertions, content interface{}) *CompactBlock {
+	data, ok := content.(dispatcher.DataResponse)
+	assert.True(ok)
+	assert.Equal(common.ChannelIDCompactBlock, data.ChannelID)
+	cb := &CompactBlock{}
+	assert.Nil(rlp.DecodeBytes(data.Payload, cb))
+	return cb
+}
@@ -0,0 +1,290 @@
+package netsync
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+)
+
+const (
+	// Weight of the latest sample in the moving averages
+	throughputSmoothing = 0.3
+	// Blocks per second assumed for the peers not measured yet, so that they get tried
+	defaultPeerThroughput = 1.0
+	// The throughput of a peer is halved when a request to it times out
+	timeoutThroughputPenalty = 0.5
+)
+
+//
+// PeerDownloadStats is the block download throughput of a peer
+//
+type PeerDownloadStats struct {
+	ID              string  `json:"id"`
+	InFlight        int     `json:"in_flight"`
+	Delivered       uint64  `json:"delivered"`
+	TimedOut        uint64  `json:"timed_out"`
+	BlocksPerSecond float64 `json:"blocks_per_second"`
+}
+
+//
+// SyncProgress is the progress of the block download
+//
+type SyncProgress struct {
+	CurrentHeight   uint64              `json:"current_height"`
+	TargetHeight    uint64              `json:"target_height"`
+	PendingBlocks   int                 `json:"pending_blocks"`
+	InFlight        int                 `json:"in_flight"`
+	BlocksPerSecond float64             `json:"blocks_per_second"`
+	ETA             string              `json:"eta"` // empty if unknown
+	Peers           []PeerDownloadStats `json:"peers"`
+}
+
+type blockRequest struct {
+	peerID    string
+	batchSize int
+	sentAt    time.Time
+}
+
+//
+// downloadScheduler spreads the block body requests across the peers according to their
+// measured throughput, and limits the requests in flight to each peer. It is goroutine safe.
+//
+type downloadScheduler struct {
+	mu *sync.Mutex
+
+	maxInFlightPerPeer int
+	peers              map[string]*PeerDownloadStats
+	requests           map[common.Hash]*blockRequest
+
+	targetHeight  uint64
+	lastHeight    uint64
+	lastHeightAt  time.Time
+	blocksPerSecs float64
+}
+
+func newDownloadScheduler(maxInFlightPerPeer int) *downloadScheduler {
+	if maxInFlightPerPeer < 1 {
+		maxInFlightPerPeer = 1
+	}
+	return &downloadScheduler{
+		mu:                 &sync.Mutex{},
+		maxInFlightPerPeer: maxInFlightPerPeer,
+		peers:              make(map[string]*PeerDownloadStats),
+		requests:           make(map[common.Hash]*blockRequest),
+	}
+}
+
+func (ds *downloadScheduler) peer(peerID string) *PeerDownloadStats {
+	ps, ok := ds.peers[peerID]
+	if !ok {
+		ps = &PeerDownloadStats{ID: peerID}
+		ds.peers[peerID] = ps
+	}
+	return ps
+}
+
+func (ds *downloadScheduler) throughput(ps *PeerDownloadStats) float64 {
+	if ps.Delivered == 0 && ps.TimedOut == 0 {
+		return defaultPeerThroughput
+	}
+	return ps.BlocksPerSecond
+}
+
+// selectPeer returns the candidate expected to deliver a block the soonest, i.e. with
+// the highest throughput per request in flight, or an empty string if all the candidates
+// are saturated. The blocks queued for a peer but not requested yet count as in flight.
+func (ds *downloadScheduler) selectPeer(candidates []string, queued map[string]int) string {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	selected := ""
+	best := -1.0
+	for _, peerID := range candidates {
+		ps := ds.peer(peerID)
+		inFlight := ps.InFlight + queued[peerID]
+		if inFlight >= ds.maxInFlightPerPeer {
+			continue
+		}
+		score := ds.throughput(ps) / float64(inFlight+1)
+		if score > best {
+			best = score
+			selected = peerID
+		}
+	}
+	return selected
+}
+
+// capacity returns the number of requests that can be in flight to the given peers
+func (ds *downloadScheduler) capacity(peerIDs []string) int {
+	return len(peerIDs) * ds.maxInFlightPerPeer
+}
+
+// onRequest records the blocks requested together from the peer
+func (ds *downloadScheduler) onRequest(peerID string, hashes []common.Hash) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	now := time.Now()
+	for _, hash := range hashes {
+		ds.release(hash)
+		ds.requests[hash] = &blockRequest{peerID: peerID, batchSize: len(hashes), sentAt: now}
+		ds.peer(peerID).InFlight++
+	}
+}
+
+// onBlock records the delivery of the block, and updates the throughput of the peer
+// it was requested from
+func (ds *downloadScheduler) onBlock(hash common.Hash) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	req := ds.release(hash)
+	if req == nil {
+		return
+	}
+	ps := ds.peer(req.peerID)
+	elapsed := time.Since(req.sentAt).Seconds()
+	if elapsed < 0.001 {
+		elapsed = 0.001
+	}
+	sample := float64(req.batchSize) / elapsed
+	if ps.Delivered == 0 && ps.TimedOut == 0 {
+		ps.BlocksPerSecond = sample
+	} else {
+		ps.BlocksPerSecond = (1-throughputSmoothing)*ps.BlocksPerSecond + throughputSmoothing*sample
+	}
+	ps.Delivered++
+}
+
+// onTimeout releases the request of the block, and penalizes the peer it was requested from
+func (ds *downloadScheduler) onTimeout(hash common.Hash) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	req := ds.release(hash)
+	if req == nil {
+		return
+	}
+	ps := ds.peer(req.peerID)
+	if ps.Delivered == 0 && ps.TimedOut == 0 {
+		ps.BlocksPerSecond = defaultPeerThroughput
+	}
+	ps.BlocksPerSecond *= timeoutThroughputPenalty
+	ps.TimedOut++
+}
+
+// release removes the request of the block, if any
+func (ds *downloadScheduler) release(hash common.Hash) *blockRequest {
+	req, ok := ds.requests[hash]
+	if !ok {
+		return nil
+	}
+	delete(ds.requests, hash)
+	if ps, ok := ds.peers[req.peerID]; ok && ps.InFlight > 0 {
+		ps.InFlight--
+	}
+	return req
+}
+
+// cancel removes the request of the block, if any
+func (ds *downloadScheduler) cancel(hash common.Hash) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	ds.release(hash)
+}
+
+// removePeers forgets the peers no longer connected, and their requests
+func (ds *downloadScheduler) removePeers(isConnected func(peerID string) bool) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for peerID := range ds.peers {
+		if !isConnected(peerID) {
+			delete(ds.peers, peerID)
+		}
+	}
+	for hash, req := range ds.requests {
+		if _, ok := ds.peers[req.peerID]; !ok {
+			delete(ds.requests, hash)
+		}
+	}
+}
+
+// updateTargetHeight records the height of a verified header
+func (ds *downloadScheduler) updateTargetHeight(height uint64) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if height > ds.targetHeight {
+		ds.targetHeight = height
+	}
+}
+
+// updateHeight samples the height of the local chain to measure the sync speed
+func (ds *downloadScheduler) updateHeight(height uint64) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	now := time.Now()
+	if !ds.lastHeightAt.IsZero() && height >= ds.lastHeight {
+		elapsed := now.Sub(ds.lastHeightAt).Seconds()
+		if elapsed > 0 {
+			sample := float64(height-ds.lastHeight) / elapsed
+			ds.blocksPerSecs = (1-throughputSmoothing)*ds.blocksPerSecs + throughputSmoothing*sample
+		}
+	}
+	ds.lastHeight = height
+	ds.lastHeightAt = now
+}
+
+// progress returns the download progress from the given height
+func (ds *downloadScheduler) progress(currentHeight uint64, pendingBlocks int) *SyncProgress {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	progress := &SyncProgress{
+		CurrentHeight:   currentHeight,
+		TargetHeight:    ds.targetHeight,
+		PendingBlocks:   pendingBlocks,
+		InFlight:        len(ds.requests),
+		BlocksPerSecond: ds.blocksPerSecs,
+		Peers:           []PeerDownloadStats{},
+	}
+	if progress.TargetHeight < currentHeight {
+		progress.TargetHeight = currentHeight
+	}
+	if remaining := progress.TargetHeight - currentHeight; remaining > 0 && ds.blocksPerSecs > 0 {
+		eta := time.Duration(float64(remaining) / ds.blocksPerSecs * float64(time.Second))
+		progress.ETA = eta.Round(time.Second).String()
+	} else if remaining == 0 {
+		progress.ETA = "0s"
+	}
+	for _, ps := range ds.peers {
+		progress.Peers = append(progress.Peers, *ps)
+	}
+	sort.Slice(progress.Peers, func(i, j int) bool {
+		return progress.Peers[i].BlocksPerSecond > progress.Peers[j].BlocksPerSecond
+	})
+	return progress
+}
+
+// verifyHeaderSkeleton checks that the headers linked to each other in the batch have
+// consecutive heights
+func verifyHeaderSkeleton(headers []*core.BlockHeader) error {
+	heights := make(map[common.Hash]uint64, len(headers))
+	for _, header := range headers {
+		heights[header.Hash()] = header.Height
+	}
+	for _, header := range headers {
+		if parentHeight, ok := heights[header.Parent]; ok && parentHeight+1 != header.Height {
+			return fmt.Errorf("header %v at height %v does not follow its parent at height %v",
+				header.Hash().Hex(), header.Height, parentHeight)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package netsync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/scripttoken/script/blockchain"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/dispatcher"
+)
+
+func TestDownloadSchedulerSelectPeer(t *testing.T) {
+	assert := assert.New(t)
+
+	ds := newDownloadScheduler(2)
+	assert.Equal(4, ds.capacity([]string{"peer1", "peer2"}))
+
+	// Peers not measured yet are tried in order
+	assert.Equal("peer1", ds.selectPeer([]string{"peer1", "peer2"}, nil))
+	assert.Equal("", ds.selectPeer([]string{}, nil))
+
+	// The fastest peer is preferred
+	ds.onRequest("peer1", testHashes(1))
+	ds.onRequest("peer2", testHashes(2))
+	ds.requests[testHash(1)].sentAt = time.Now().Add(-10 * time.Second)
+	ds.requests[testHash(2)].sentAt = time.Now().Add(-100 * time.Millisecond)
+	ds.onBlock(testHash(1))
+	ds.onBlock(testHash(2))
+	assert.InDelta(0.1, ds.peers["peer1"].BlocksPerSecond, 0.01)
+	assert.True(ds.peers["peer2"].BlocksPerSecond > 5)
+	assert.Equal("peer2", ds.selectPeer([]string{"peer1", "peer2"}, nil))
+
+	// The score is shared among the requests in flight
+	ds.peers["peer1"].BlocksPerSecond = 4
+	ds.peers["peer2"].BlocksPerSecond = 6
+	ds.onRequest("peer2", testHashes(3))
+	assert.Equal("peer1", ds.selectPeer([]string{"peer1", "peer2"}, nil))
+
+	// Delivering an unknown block changes nothing
+	ds.onBlock(testHash(100))
+	assert.Equal(uint64(1), ds.peers["peer2"].Delivered)
+	assert.Equal(1, ds.peers["peer2"].InFlight)
+}
+
+func TestDownloadSchedulerInFlightLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	ds := newDownloadScheduler(2)
+	ds.onRequest("peer1", testHashes(1, 2))
+	assert.Equal(2, ds.peers["peer1"].InFlight)
+	assert.Equal("", ds.selectPeer([]string{"peer1"}, nil))
+	assert.Equal("peer2", ds.selectPeer([]string{"peer1", "peer2"}, nil))
+
+	// Requesting a block again moves it to the new peer
+	ds.onRequest("peer2", testHashes(2))
+	assert.Equal(1, ds.peers["peer1"].InFlight)
+	assert.Equal(1, ds.peers["peer2"].InFlight)
+	assert.Equal("peer1", ds.selectPeer([]string{"peer1"}, nil))
+
+	ds.cancel(testHash(1))
+	ds.onBlock(testHash(2))
+	assert.Equal(0, ds.peers["peer1"].InFlight)
+	assert.Equal(0, ds.peers["peer2"].InFlight)
+	assert.Equal(0, len(ds.requests))
+
+	// The blocks queued for a peer count as in flight
+	assert.Equal("peer2", ds.selectPeer([]string{"peer1", "peer2"}, map[string]int{"peer1": 1}))
+	assert.Equal("", ds.selectPeer([]string{"peer1"}, map[string]int{"peer1": 2}))
+
+	// At least one request is allowed per peer
+	assert.Equal(1, newDownloadScheduler(0).maxInFlightPerPeer)
+}
+
+func TestDownloadSchedulerTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	ds := newDownloadScheduler(4)
+	ds.onRequest("peer1", testHashes(1))
+	ds.onTimeout(testHash(1))
+	assert.Equal(0, ds.peers["peer1"].InFlight)
+	assert.Equal(uint64(1), ds.peers["peer1"].TimedOut)
+	assert.Equal(defaultPeerThroughput*timeoutThroughputPenalty, ds.peers["peer1"].BlocksPerSecond)
+
+	// The block is reassigned to the peer which did not time out
+	assert.Equal("peer2", ds.selectPeer([]string{"peer1", "peer2"}, nil))
+	ds.onRequest("peer2", testHashes(1))
+	assert.Equal(1, ds.peers["peer2"].InFlight)
+
+	ds.onTimeout(testHash(1))
+	ds.onTimeout(testHash(1)) // already released
+	assert.Equal(0, ds.peers["peer2"].InFlight)
+	assert.Equal(uint64(1), ds.peers["peer2"].TimedOut)
+}
+
+func TestDownloadSchedulerRemovePeers(t *testing.T) {
+	assert := assert.New(t)
+
+	ds := newDownloadScheduler(4)
+	ds.onRequest("peer1", testHashes(1, 2))
+	ds.onRequest("peer2", testHashes(3))
+	ds.removePeers(func(peerID string) bool { return peerID == "peer2" })
+	assert.Equal(1, len(ds.peers))
+	assert.Equal(1, len(ds.requests))
+	assert.NotNil(ds.requests[testHash(3)])
+}
+
+func TestDownloadSchedulerProgress(t *testing.T) {
+	assert := assert.New(t)
+
+	ds := newDownloadScheduler(4)
+	progress := ds.progress(10, 0)
+	assert.Equal(uint64(10), progress.TargetHeight)
+	assert.Equal("0s", progress.ETA)
+
+	ds.updateTargetHeight(110)
+	ds.updateTargetHeight(50)
+	ds.updateHeight(10)
+	assert.Equal("", ds.progress(10, 5).ETA)
+
+	ds.lastHeightAt = ds.lastHeightAt.Add(-10 * time.Second)
+	ds.updateHeight(20)
+	ds.onRequest("peer1", testHashes(1))
+	progress = ds.progress(20, 5)
+	assert.Equal(uint64(110), progress.TargetHeight)
+	assert.Equal(1, progress.InFlight)
+	assert.Equal(5, progress.PendingBlocks)
+	assert.InDelta(0.3, progress.BlocksPerSecond, 0.01)
+	assert.NotEqual("", progress.ETA)
+	assert.Equal(1, len(progress.Peers))
+}
+
+func TestDownloadBlockFromHeaderParallel(t *testing.T) {
+	assert := assert.New(t)
+	core.ResetTestBlocks()
+
+	chain := blockchain.CreateTestChainByBlocks([]string{"A1", "A0"})
+	a2 := core.CreateTestBlock("A2", "A1")
+	a3 := core.CreateTestBlock("A3", "A2")
+
+	net := NewMockNetwork("peer1", "peer2")
+	sm := newCompactTestSyncManager(chain, net, nil)
+	rm := sm.requestMgr
+	rm.ifCompactBlock = false
+	rm.ifDownloadByHeader = true
+	rm.ifParallelDownload = true
+	rm.scheduler = newDownloadScheduler(1)
+	rm.AddHeader(a2.BlockHeader, []string{"peer1"})
+	rm.AddHeader(a3.BlockHeader, []string{"peer1", "peer2"})
+
+	// One block at most is in flight to each peer
+	rm.fastsyncQuota = 10
+	rm.downloadBlockFromHeader()
+	requested := map[string]string{}
+	for i := 0; i < 2; i++ {
+		sent := net.NextSent()
+		assert.NotNil(sent)
+		request := sent.Message.Content.(dispatcher.DataRequest)
+		assert.Equal(common.ChannelIDBlock, request.ChannelID)
+		assert.Equal(1, len(request.Entries))
+		requested[request.Entries[0]] = sent.PeerID
+	}
+	assert.Nil(net.NextSent())
+	assert.Equal(map[string]string{a2.Hash().Hex(): "peer1", a3.Hash().Hex(): "peer2"}, requested)
+
+	// The request which timed out is reassigned to the peer which delivered
+	rm.AddBlock(a2)
+	pendingBlock := rm.pendingBlocksByHash[a3.Hash().Hex()].Value.(*PendingBlock)
+	pendingBlock.lastUpdate = time.Now().Add(-RequestTimeout - time.Second)
+	rm.fastsyncQuota = 10
+	rm.downloadBlockFromHeader()
+	sent := net.NextSent()
+	assert.NotNil(sent)
+	assert.Equal("peer1", sent.PeerID)
+	assert.Equal(dispatcher.DataRequest{ChannelID: common.ChannelIDBlock, Entries: []string{a3.Hash().Hex()}}, sent.Message.Content)
+	assert.Equal(uint64(1), rm.scheduler.peers["peer2"].TimedOut)
+	assert.Equal(uint64(1), rm.scheduler.peers["peer1"].Delivered)
+}
+
+// --------------- Test Utilities --------------- //
+
+func testHash(i int) common.Hash {
+	return common.BytesToHash([]byte{byte(i)})
+}
+
+func testHashes(ids ...int) []common.Hash {
+	hashes := []common.Hash{}
+	for _, i := range ids {
+		hashes = append(hashes, testHash(i))
+	}
+	return hashes
+}
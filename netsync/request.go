@@ -17,6 +17,7 @@ import (
 	"github.com/scripttoken/script/common/util"
 	"github.com/scripttoken/script/core"
 	"github.com/scripttoken/script/dispatcher"
+	p2ptypes "github.com/scripttoken/script/p2p/types"
 	rp "github.com/scripttoken/script/report"
 
 	log "github.com/sirupsen/logrus"
@@ -53,6 +54,8 @@ type PendingBlock struct {
 	createdAt  time.Time
 	status     RequestState
 	fromGossip bool
+
+	compactRequested bool
 }
 
 func NewPendingBlock(x common.Hash, peerIds []string, fromGossip bool) *PendingBlock {
@@ -134,6 +137,9 @@ type RequestManager struct {
 	fastsyncQuota           uint
 	ifDownloadByHash        bool
 	ifDownloadByHeader      bool
+	ifCompactBlock          bool
+	ifParallelDownload      bool
+	scheduler               *downloadScheduler
 
 	dumpBlockCache *lru.Cache
 
@@ -172,6 +178,9 @@ func NewRequestManager(syncMgr *SyncManager, reporter *rp.Reporter) *RequestMana
 		pendingBlocksWithHeader: &HeaderHeap{},
 		ifDownloadByHash:        viper.GetBool(common.CfgSyncDownloadByHash),
 		ifDownloadByHeader:      viper.GetBool(common.CfgSyncDownloadByHeader),
+		ifCompactBlock:          viper.GetBool(common.CfgSyncCompactBlockEnabled),
+		ifParallelDownload:      viper.GetBool(common.CfgSyncParallelDownload),
+		scheduler:               newDownloadScheduler(viper.GetInt(common.CfgSyncMaxBlocksInFlightPerPeer)),
 
 		blockNotify:    make(chan *core.ExtendedBlock, 1),
 		dumpBlockCache: dumpBlockCache,
@@ -211,6 +220,8 @@ func (rm *RequestManager) recoveryModeLoop() {
 
 	for {
 		select {
+		case <-rm.ctx.Done():
+			return
 		case <-rm.recoveryModeTicker.C:
 			rm.attemptToRunRecoveryMode()
 		}
@@ -417,6 +428,14 @@ func (rm *RequestManager) tryToDownload() {
 	rm.gossipQuota = GossipRequestQuotaPerSecond
 	// rm.fastsyncQuota = FastsyncRequestQuota
 	rm.fastsyncQuota = viper.GetUint(common.CfgSyncFastsyncQuota)
+	if rm.ifParallelDownload {
+		// The requests in flight are limited per peer instead
+		rm.scheduler.removePeers(rm.dispatcher.PeerExists)
+		if capacity := uint(rm.scheduler.capacity(rm.dispatcher.Peers(true))); capacity > rm.fastsyncQuota {
+			rm.fastsyncQuota = capacity
+		}
+	}
+	rm.scheduler.updateHeight(rm.currentHeight())
 
 	hasUndownloadedBlocks := rm.pendingBlocks.Len() > 0 || len(rm.pendingBlocksByHash) > 0 || rm.pendingBlocksWithHeader.Len() > 0
 
@@ -605,6 +624,10 @@ func (rm *RequestManager) downloadBlockFromHash() {
 				ChannelID: common.ChannelIDBlock,
 				Entries:   []string{pendingBlock.hash.String()},
 			}
+			if rm.shouldRequestCompactBlock(pendingBlock, randomPeerID) {
+				request.ChannelID = common.ChannelIDCompactBlock
+				pendingBlock.compactRequested = true
+			}
 
 			// forcedBlockHash := viper.GetString(common.CfgSyncForcedDownloadBlockHash)
 			// if forcedBlockHash != "" {
@@ -693,8 +716,19 @@ func (rm *RequestManager) downloadBlockFromHeader() {
 		if pendingBlock.status == RequestToSendBodyReq ||
 			(pendingBlock.status == RequestWaitingBodyResp && pendingBlock.HasTimedOut()) {
 
+			if pendingBlock.status == RequestWaitingBodyResp {
+				rm.scheduler.onTimeout(pendingBlock.hash)
+			}
+
 			peersWithBlock := util.Shuffle(pendingBlock.peers)
 			var randomPeerID string
+			if rm.ifParallelDownload {
+				peersWithBlock = rm.selectPeer(peersWithBlock, peerMap)
+				if len(peersWithBlock) == 0 {
+					// All the peers with the block are busy
+					continue
+				}
+			}
 			for i := 0; i < len(peersWithBlock); i++ {
 				if rm.dispatcher.PeerExists(peersWithBlock[i]) { // the peer may have been purged
 					randomPeerID = peersWithBlock[i]
@@ -714,6 +748,15 @@ func (rm *RequestManager) downloadBlockFromHeader() {
 				continue
 			}
 
+			if rm.shouldRequestCompactBlock(pendingBlock, randomPeerID) {
+				rm.sendCompactBlockRequest(randomPeerID, pendingBlock.hash.String())
+				pendingBlock.compactRequested = true
+				pendingBlock.UpdateTimestamp()
+				pendingBlock.status = RequestWaitingBodyResp
+				rm.fastsyncQuota--
+				continue
+			}
+
 			if blockBuffer, ok = peerMap[randomPeerID]; !ok {
 				blockBuffer = []string{}
 			}
@@ -810,7 +853,7 @@ func (rm *RequestManager) getInventory(req dispatcher.InventoryRequest) {
 	}
 	if len(peersToRequest) < targetSize { // resample
 		allPeers := rm.syncMgr.dispatcher.Peers(true) // skip edge nodes
-		samples := util.Sample(allPeers, targetSize)
+		samples := rm.samplePeersAhead(allPeers, targetSize)
 		for _, sample := range samples {
 			duplicate := false
 			for _, pid := range peersToRequest {
@@ -840,11 +883,56 @@ func (rm *RequestManager) getInventory(req dispatcher.InventoryRequest) {
 	rm.syncMgr.dispatcher.GetInventory(peersToRequest, req)
 }
 
+// samplePeersAhead samples the given peers, preferring the ones which advertised in the
+// handshake a finalized height above ours, since they have the blocks we are missing
+func (rm *RequestManager) samplePeersAhead(peerIDs []string, sampleSize int) []string {
+	lfbHeight := rm.syncMgr.consensus.GetLastFinalizedBlock().Height
+	ahead := []string{}
+	others := []string{}
+	for _, pid := range peerIDs {
+		info := rm.syncMgr.dispatcher.PeerHandshakeInfo(pid)
+		if info != nil && info.FinalizedHeight > lfbHeight {
+			ahead = append(ahead, pid)
+		} else {
+			others = append(others, pid)
+		}
+	}
+
+	samples := util.Sample(ahead, sampleSize)
+	if len(samples) < sampleSize {
+		samples = append(samples, util.Sample(others, sampleSize-len(samples))...)
+	}
+	return samples
+}
+
+// selectPeer returns the peer with the highest throughput and free capacity among the
+// connected peers, or nil if they are all busy. peerMap holds the blocks queued for each
+// peer and not requested yet.
+func (rm *RequestManager) selectPeer(peerIDs []string, peerMap map[string][]string) []string {
+	connected := []string{}
+	queued := make(map[string]int)
+	for _, peerID := range peerIDs {
+		if rm.dispatcher.PeerExists(peerID) {
+			connected = append(connected, peerID)
+			queued[peerID] = len(peerMap[peerID])
+		}
+	}
+	if peerID := rm.scheduler.selectPeer(connected, queued); peerID != "" {
+		return []string{peerID}
+	}
+	return nil
+}
+
 func (rm *RequestManager) sendBlocksRequest(peerID string, entries []string) {
 	request := dispatcher.DataRequest{
 		ChannelID: common.ChannelIDBlock,
 		Entries:   entries,
 	}
+	hashes := []common.Hash{}
+	for _, entry := range entries {
+		hashes = append(hashes, common.HexToHash(entry))
+	}
+	rm.scheduler.onRequest(peerID, hashes)
 	rm.logger.WithFields(log.Fields{
 		"channelID":       request.ChannelID,
 		"request.Entries": request.Entries,
@@ -853,6 +941,34 @@ func (rm *RequestManager) sendBlocksRequest(peerID string, entries []string) {
 	rm.syncMgr.dispatcher.GetData([]string{peerID}, request)
 }
 
+func (rm *RequestManager) sendCompactBlockRequest(peerID string, hash string) {
+	request := dispatcher.DataRequest{
+		ChannelID: common.ChannelIDCompactBlock,
+		Entries:   []string{hash},
+	}
+	rm.scheduler.onRequest(peerID, []common.Hash{common.HexToHash(hash)})
+	rm.logger.WithFields(log.Fields{
+		"channelID":       request.ChannelID,
+		"request.Entries": request.Entries,
+		"peer":            peerID,
+	}).Debug("Sending compact block request from header")
+	rm.syncMgr.dispatcher.GetData([]string{peerID}, request)
+}
+
+// shouldRequestCompactBlock indicates whether to download the block as a compact block
+// from the peer. Only the new gossiped blocks are, since their transactions are likely in
+// the mempool already. A block is requested as a compact block only once, the retries
+// after a timeout download the full block.
+func (rm *RequestManager) shouldRequestCompactBlock(pendingBlock *PendingBlock, peerID string) bool {
+	if !rm.ifCompactBlock || !pendingBlock.fromGossip || pendingBlock.compactRequested {
+		return false
+	}
+	if pendingBlock.header != nil && pendingBlock.header.Height > rm.syncMgr.consensus.GetTip(true).Height+1 {
+		return false
+	}
+	return rm.dispatcher.PeerHandshakeInfo(peerID).HasCapability(p2ptypes.CapabilityCompactBlock)
+}
+
 func (rm *RequestManager) removeEl(el *list.Element) {
 	pendingBlock := el.Value.(*PendingBlock)
 	hash := pendingBlock.hash.Hex()
@@ -860,6 +976,35 @@ func (rm *RequestManager) removeEl(el *list.Element) {
 	delete(rm.pendingBlocksByHash, hash)
 
 	rm.pendingBlocks.Remove(el)
+	rm.scheduler.cancel(pendingBlock.hash)
+}
+
+// currentHeight returns the height of the blocks passed to the consensus engine
+func (rm *RequestManager) currentHeight() uint64 {
+	if tip, ok := rm.tip.Load().(*core.ExtendedBlock); ok && tip != nil {
+		return tip.Height
+	}
+	return rm.syncMgr.consensus.GetTip(true).Height
+}
+
+// UpdateTargetHeight records the height of a verified header
+func (rm *RequestManager) UpdateTargetHeight(height uint64) {
+	rm.scheduler.updateTargetHeight(height)
+}
+
+// Progress returns the progress of the block download
+func (rm *RequestManager) Progress() *SyncProgress {
+	for _, peerID := range rm.dispatcher.Peers(true) {
+		if info := rm.dispatcher.PeerHandshakeInfo(peerID); info != nil {
+			rm.scheduler.updateTargetHeight(info.FinalizedHeight)
+		}
+	}
+
+	rm.mu.RLock()
+	pendingBlocks := len(rm.pendingBlocksByHash)
+	rm.mu.RUnlock()
+
+	return rm.scheduler.progress(rm.currentHeight(), pendingBlocks)
 }
 
 func (rm *RequestManager) AddHash(x common.Hash, peerIDs []string, fromGossip bool) {
@@ -954,6 +1099,8 @@ func (rm *RequestManager) AddBlock(block *core.Block) {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
+	rm.scheduler.onBlock(block.Hash())
+
 	eb, err := rm.chain.AddBlock(block)
 	if err != nil {
 		log.Debugf("failed to add block, err=%v", err)
@@ -2,7 +2,6 @@ package netsync
 
 import (
 	"context"
-	"reflect"
 	"strings"
 	"sync"
 
@@ -15,8 +14,8 @@ import (
 	"github.com/scripttoken/script/core"
 	"github.com/scripttoken/script/dispatcher"
 	"github.com/scripttoken/script/p2p"
+	"github.com/scripttoken/script/p2p/reputation"
 	p2ptypes "github.com/scripttoken/script/p2p/types"
-	"github.com/scripttoken/script/p2pl"
 	rp "github.com/scripttoken/script/report"
 	"github.com/scripttoken/script/rlp"
 )
@@ -29,6 +28,13 @@ type MessageConsumer interface {
 	AddMessage(interface{})
 }
 
+// VoteObserver tracks the aggregated votes and vote summaries received from the peers
+type VoteObserver interface {
+	ObserveLightningVote(peerID string, vote *core.AggregatedVotes)
+	ObserveAggregatedEENVotes(peerID string, vote *core.AggregatedEENVotes)
+	HandleVoteSummary(peerID string, summary *core.VoteSummary)
+}
+
 type Headers struct {
 	HeaderArray []*core.BlockHeader
 }
@@ -59,10 +65,15 @@ type SyncManager struct {
 	logger *log.Entry
 
 	voteCache *lru.Cache // Cache for votes
+
+	txSource      TxSource
+	voteObserver  VoteObserver
+	compactBlocks *lru.Cache // Compact blocks waiting for the missing transactions
 }
 
-func NewSyncManager(chain *blockchain.Chain, cons core.ConsensusEngine, networkOld p2p.Network, network p2pl.Network, disp *dispatcher.Dispatcher, consumer MessageConsumer, reporter *rp.Reporter) *SyncManager {
+func NewSyncManager(chain *blockchain.Chain, cons core.ConsensusEngine, network p2p.Network, disp *dispatcher.Dispatcher, consumer MessageConsumer, reporter *rp.Reporter) *SyncManager {
 	voteCache, _ := lru.New(voteCacheLimit)
+	compactBlocks, _ := lru.New(compactBlockCacheLimit)
 	sm := &SyncManager{
 		chain:      chain,
 		consensus:  cons,
@@ -71,14 +82,12 @@ func NewSyncManager(chain *blockchain.Chain, cons core.ConsensusEngine, networkO
 		wg:         &sync.WaitGroup{},
 		incoming:   make(chan p2ptypes.Message, viper.GetInt(common.CfgSyncMessageQueueSize)),
 
-		voteCache: voteCache,
+		voteCache:     voteCache,
+		compactBlocks: compactBlocks,
 	}
 	sm.requestMgr = NewRequestManager(sm, reporter)
 
-	if !reflect.ValueOf(networkOld).IsNil() {
-		networkOld.RegisterMessageHandler(sm)
-	}
-	if !reflect.ValueOf(network).IsNil() {
+	if network != nil {
 		network.RegisterMessageHandler(sm)
 	}
 
@@ -140,6 +149,8 @@ func (sm *SyncManager) GetChannelIDs() []common.ChannelIDEnum {
 		common.ChannelIDLightning,
 		common.ChannelIDEliteEdgeNodeVote,
 		common.ChannelIDAggregatedEliteEdgeNodeVotes,
+		common.ChannelIDCompactBlock,
+		common.ChannelIDVoteSummary,
 	}
 }
 
@@ -201,6 +212,11 @@ func (sm *SyncManager) processMessage(message p2ptypes.Message) {
 	}
 }
 
+// SetVoteObserver sets the observer of the received aggregated votes
+func (sm *SyncManager) SetVoteObserver(voteObserver VoteObserver) {
+	sm.voteObserver = voteObserver
+}
+
 // PassdownMessage passes message through to the consumer.
 func (sm *SyncManager) PassdownMessage(msg interface{}) {
 	sm.consumer.AddMessage(msg)
@@ -468,6 +484,8 @@ func (m *SyncManager) handleDataRequest(peerID string, data *dispatcher.DataRequ
 			}).Debug("Sending requested block")
 			m.dispatcher.SendData([]string{peerID}, sendData)
 		}
+	case common.ChannelIDCompactBlock:
+		m.sendCompactBlock(peerID, data.Entries)
 	default:
 		m.logger.WithFields(log.Fields{
 			"channelID": data.ChannelID,
@@ -559,6 +577,7 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 					"error":     err,
 					"peerID":    peerID,
 				}).Warn("Failed to decode DataResponse payload")
+				m.dispatcher.ReportPeer(peerID, reputation.InfractionMalformedMessage, err.Error())
 				return
 			}
 			for _, block = range blocks.BlockArray {
@@ -583,6 +602,20 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 			m.handleBlock(block, peerID, false)
 			maxReceivedHeight = block.Height
 		}
+	case common.ChannelIDCompactBlock:
+		cb := &CompactBlock{}
+		err := rlp.DecodeBytes(data.Payload, cb)
+		if err != nil {
+			m.logger.WithFields(log.Fields{
+				"channelID": data.ChannelID,
+				"payload":   data.Payload,
+				"error":     err,
+				"peerID":    peerID,
+			}).Warn("Failed to decode DataResponse payload")
+			m.dispatcher.ReportPeer(peerID, reputation.InfractionMalformedMessage, err.Error())
+			return
+		}
+		m.handleCompactBlock(cb, peerID)
 	case common.ChannelIDVote:
 		vote := core.Vote{}
 		err := rlp.DecodeBytes(data.Payload, &vote)
@@ -593,6 +626,7 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 				"error":     err,
 				"peerID":    peerID,
 			}).Warn("Failed to decode DataResponse payload")
+			m.dispatcher.ReportPeer(peerID, reputation.InfractionMalformedMessage, err.Error())
 			return
 		}
 		m.logger.WithFields(log.Fields{
@@ -612,6 +646,7 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 				"error":     err,
 				"peerID":    peerID,
 			}).Warn("Failed to decode DataResponse payload")
+			m.dispatcher.ReportPeer(peerID, reputation.InfractionMalformedMessage, err.Error())
 			return
 		}
 		m.logger.WithFields(log.Fields{
@@ -629,6 +664,7 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 				"error":     err,
 				"peerID":    peerID,
 			}).Warn("Failed to decode DataResponse payload")
+			m.dispatcher.ReportPeer(peerID, reputation.InfractionMalformedMessage, err.Error())
 			return
 		}
 		m.logger.WithFields(log.Fields{
@@ -637,7 +673,7 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 			"vote.Multiplies": vote.Multiplies,
 			"peer":            peerID,
 		}).Debug("Received lightning vote")
-		m.handleLightningVote(vote)
+		m.handleLightningVote(vote, peerID)
 	case common.ChannelIDEliteEdgeNodeVote:
 		vote := &core.EENVote{}
 		err := rlp.DecodeBytes(data.Payload, vote)
@@ -648,6 +684,7 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 				"error":     err,
 				"peerID":    peerID,
 			}).Warn("Failed to decode DataResponse payload")
+			m.dispatcher.ReportPeer(peerID, reputation.InfractionMalformedMessage, err.Error())
 			return
 		}
 		// m.logger.WithFields(log.Fields{
@@ -665,6 +702,7 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 				"error":     err,
 				"peerID":    peerID,
 			}).Warn("Failed to decode DataResponse payload")
+			m.dispatcher.ReportPeer(peerID, reputation.InfractionMalformedMessage, err.Error())
 			return
 		}
 		m.logger.WithFields(log.Fields{
@@ -672,7 +710,23 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 			"vote.Multiplies": vote.Multiplies,
 			"peer":            peerID,
 		}).Debug("Received aggregated elite edge node vote")
-		m.handleAggregatedEliteEdgeNodeVotes(vote)
+		m.handleAggregatedEliteEdgeNodeVotes(vote, peerID)
+	case common.ChannelIDVoteSummary:
+		summary := &core.VoteSummary{}
+		err := rlp.DecodeBytes(data.Payload, summary)
+		if err != nil {
+			m.logger.WithFields(log.Fields{
+				"channelID": data.ChannelID,
+				"payload":   data.Payload,
+				"error":     err,
+				"peerID":    peerID,
+			}).Warn("Failed to decode DataResponse payload")
+			m.dispatcher.ReportPeer(peerID, reputation.InfractionMalformedMessage, err.Error())
+			return
+		}
+		if m.voteObserver != nil {
+			m.voteObserver.HandleVoteSummary(peerID, summary)
+		}
 	case common.ChannelIDHeader:
 		headers := &Headers{}
 		err := rlp.DecodeBytes(data.Payload, headers)
@@ -683,6 +737,15 @@ func (m *SyncManager) handleDataResponse(peerID string, data *dispatcher.DataRes
 				"error":     err,
 				"peerID":    peerID,
 			}).Debug("Failed to decode HeaderResponse payload")
+			m.dispatcher.ReportPeer(peerID, reputation.InfractionMalformedMessage, err.Error())
+			return
+		}
+		if err := verifyHeaderSkeleton(headers.HeaderArray); err != nil {
+			m.logger.WithFields(log.Fields{
+				"error":  err,
+				"peerID": peerID,
+			}).Debug("Received inconsistent headers")
+			m.dispatcher.ReportPeer(peerID, reputation.InfractionInvalidBlock, err.Error())
 			return
 		}
 		for _, header := range headers.HeaderArray {
@@ -729,6 +792,19 @@ func (sm *SyncManager) handleHeader(header *core.BlockHeader, peerID []string) {
 		}
 	}
 
+	if res := header.Validate(sm.chain.ChainID); res.IsError() {
+		sm.logger.WithFields(log.Fields{
+			"block hash":   header.Hash().String(),
+			"block height": header.Height,
+			"peer":         peerID,
+		}).Debug("received invalid header")
+		for _, pid := range peerID {
+			sm.dispatcher.ReportPeer(pid, reputation.InfractionInvalidBlock, res.Message)
+		}
+		return
+	}
+	sm.requestMgr.UpdateTargetHeight(header.Height)
+
 	lfbHeight := sm.consensus.GetLastFinalizedBlock().Height
 	tipHeight := sm.consensus.GetTip(true).Height
 	if header.Height > lfbHeight && header.Height <= tipHeight+dispatcher.MaxInventorySize+1 {
@@ -736,6 +812,11 @@ func (sm *SyncManager) handleHeader(header *core.BlockHeader, peerID []string) {
 	}
 }
 
+// Progress returns the progress of the block download
+func (sm *SyncManager) Progress() *SyncProgress {
+	return sm.requestMgr.Progress()
+}
+
 func (sm *SyncManager) handleBlock(block *core.Block, pid string, shouldGossip bool) {
 	if res := block.Validate(sm.chain.ChainID); res.IsError() {
 		sm.logger.WithFields(log.Fields{
@@ -743,6 +824,7 @@ func (sm *SyncManager) handleBlock(block *core.Block, pid string, shouldGossip b
 			"block height": block.Height,
 			"peer":         pid,
 		}).Debug("received invalid block")
+		sm.dispatcher.ReportPeer(pid, reputation.InfractionInvalidBlock, res.Message)
 		return
 	}
 
@@ -795,13 +877,14 @@ func (sm *SyncManager) handleBlock(block *core.Block, pid string, shouldGossip b
 }
 
 func (sm *SyncManager) handleVote(vote core.Vote, pid string) {
-	if vote.Validate().IsError() {
+	if res := vote.Validate(); res.IsError() {
 		sm.logger.WithFields(log.Fields{
 			"vote.Hash":  vote.Block.Hex(),
 			"vote.ID":    vote.ID.Hex(),
 			"vote.Epoch": vote.Epoch,
 			"peer":       pid,
 		}).Debug("Ignoring invalid vote")
+		sm.dispatcher.ReportPeer(pid, reputation.InfractionInvalidVote, res.Message)
 		return
 	}
 
@@ -865,7 +948,10 @@ func (sm *SyncManager) handleVote(vote core.Vote, pid string) {
 	}
 }
 
-func (sm *SyncManager) handleLightningVote(vote *core.AggregatedVotes) {
+func (sm *SyncManager) handleLightningVote(vote *core.AggregatedVotes, pid string) {
+	if sm.voteObserver != nil {
+		sm.voteObserver.ObserveLightningVote(pid, vote)
+	}
 	sm.PassdownMessage(vote)
 }
 
@@ -873,6 +959,9 @@ func (sm *SyncManager) handleEliteEdgeNodeVote(vote *core.EENVote) {
 	sm.PassdownMessage(vote)
 }
 
-func (sm *SyncManager) handleAggregatedEliteEdgeNodeVotes(vote *core.AggregatedEENVotes) {
+func (sm *SyncManager) handleAggregatedEliteEdgeNodeVotes(vote *core.AggregatedEENVotes, pid string) {
+	if sm.voteObserver != nil {
+		sm.voteObserver.ObserveAggregatedEENVotes(pid, vote)
+	}
 	sm.PassdownMessage(vote)
 }
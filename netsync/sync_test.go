@@ -19,12 +19,15 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/scripttoken/script/blockchain"
+	"github.com/scripttoken/script/p2p"
 	"github.com/scripttoken/script/p2p/simulation"
 	"github.com/scripttoken/script/p2p/types"
 )
 
 type MockMessageConsumer struct {
 	Received []interface{}
+
+	chain *blockchain.Chain // if set, the received blocks are marked valid as the consensus engine does
 }
 
 func NewMockMessageConsumer() *MockMessageConsumer {
@@ -35,6 +38,9 @@ func NewMockMessageConsumer() *MockMessageConsumer {
 
 func (m *MockMessageConsumer) AddMessage(msg interface{}) {
 	m.Received = append(m.Received, msg)
+	if block, ok := msg.(*core.Block); ok && m.chain != nil {
+		m.chain.MarkBlockValid(block.Hash())
+	}
 }
 
 type MockMsgHandler struct {
@@ -42,15 +48,19 @@ type MockMsgHandler struct {
 }
 
 func (m *MockMsgHandler) GetChannelIDs() []common.ChannelIDEnum {
-	return []common.ChannelIDEnum{}
+	return []common.ChannelIDEnum{
+		common.ChannelIDHeader,
+		common.ChannelIDBlock,
+	}
 }
 
 func (m *MockMsgHandler) ParseMessage(peerID string, channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (types.Message, error) {
-	return types.Message{}, nil
+	data, err := decodeMessage(rawMessageBytes)
+	return types.Message{PeerID: peerID, ChannelID: channelID, Content: data}, err
 }
 
 func (m *MockMsgHandler) EncodeMessage(message interface{}) (common.Bytes, error) {
-	return nil, nil
+	return encodeMessage(message)
 }
 
 func (m *MockMsgHandler) HandleMessage(message types.Message) error {
@@ -85,11 +95,12 @@ func TestSyncManager(t *testing.T) {
 	privKey, _, _ := crypto.GenerateKeyPair()
 	valMgr := consensus.NewFixedValidatorManager()
 	db := kvstore.NewKVStore(backend.NewMemDatabase())
-	dispatch := dispatcher.NewDispatcher(net1, nil)
+	dispatch := dispatcher.NewDispatcher(net1)
 	consensus := consensus.NewConsensusEngine(privKey, db, initChain, dispatch, valMgr)
 	mockMsgConsumer := NewMockMessageConsumer()
+	mockMsgConsumer.chain = initChain
 
-	sm := NewSyncManager(initChain, consensus, net1, nil, dispatch, mockMsgConsumer)
+	sm := NewSyncManager(initChain, consensus, net1, dispatch, mockMsgConsumer, nil)
 	sm.Start(context.Background())
 
 	// Send block A4 to node1
@@ -100,21 +111,11 @@ func TestSyncManager(t *testing.T) {
 			ChannelID: common.ChannelIDBlock,
 			Payload:   payload,
 		},
-	})
+	}, false)
 
-	// node1 should broadcast InventoryResponse
+	// node1 should request the missing blocks. The blocks received in a DataResponse
+	// are not gossiped further.
 	var res interface{}
-	res = <-mockMsgHandler.C
-	msg1, ok := res.(dispatcher.InventoryResponse)
-	assert.True(ok)
-	assert.Equal(common.ChannelIDBlock, msg1.ChannelID)
-	assert.Equal(core.GetTestBlock("A4").Hash().Hex(), msg1.Entries[0])
-
-	res = <-mockMsgHandler.C
-	msg11, ok := res.(dispatcher.DataResponse)
-	assert.True(ok)
-	assert.Equal(common.ChannelIDHeader, msg11.ChannelID)
-
 	res = <-mockMsgHandler.C
 	msg2, ok := res.(dispatcher.InventoryRequest)
 	assert.True(ok)
@@ -135,7 +136,7 @@ func TestSyncManager(t *testing.T) {
 			ChannelID: common.ChannelIDBlock,
 			Entries:   entries,
 		},
-	})
+	}, false)
 
 	// node2 replies with A3 first
 	payload, _ = rlp.EncodeToBytes(core.CreateTestBlock("A3", "A2"))
@@ -145,7 +146,7 @@ func TestSyncManager(t *testing.T) {
 			ChannelID: common.ChannelIDBlock,
 			Payload:   payload,
 		},
-	})
+	}, false)
 
 	time.Sleep(1 * time.Second)
 
@@ -157,9 +158,10 @@ func TestSyncManager(t *testing.T) {
 			ChannelID: common.ChannelIDBlock,
 			Payload:   payload,
 		},
-	})
+	}, false)
 
-	time.Sleep(1 * time.Second)
+	// The ready blocks are passed down one height per round once the parent is valid
+	time.Sleep(4 * time.Second)
 
 	sm.Stop()
 	sm.Wait()
@@ -172,6 +174,70 @@ func TestSyncManager(t *testing.T) {
 	}
 }
 
+type MockSentMessage struct {
+	PeerID  string // empty if broadcast
+	Message types.Message
+}
+
+// MockNetwork records the messages sent through it. Each peer advertises the handshake
+// info set in Info, the peers without one run an older version.
+type MockNetwork struct {
+	PeerIDs []string
+	Info    map[string]*types.HandshakeInfo
+	Sent    chan MockSentMessage
+}
+
+func NewMockNetwork(peerIDs ...string) *MockNetwork {
+	return &MockNetwork{
+		PeerIDs: peerIDs,
+		Info:    make(map[string]*types.HandshakeInfo),
+		Sent:    make(chan MockSentMessage, 128),
+	}
+}
+
+func (mn *MockNetwork) Start(ctx context.Context) error { return nil }
+func (mn *MockNetwork) Wait()                           {}
+func (mn *MockNetwork) Stop()                           {}
+func (mn *MockNetwork) Broadcast(message types.Message, skipEdgeNode bool) chan bool {
+	mn.Sent <- MockSentMessage{Message: message}
+	return make(chan bool, 1)
+}
+func (mn *MockNetwork) BroadcastToNeighbors(message types.Message, maxNumPeersToBroadcast int, skipEdgeNode bool) chan bool {
+	return mn.Broadcast(message, skipEdgeNode)
+}
+func (mn *MockNetwork) Send(peerID string, message types.Message) bool {
+	mn.Sent <- MockSentMessage{PeerID: peerID, Message: message}
+	return mn.PeerExists(peerID)
+}
+func (mn *MockNetwork) Peers(skipEdgeNode bool) []string    { return mn.PeerIDs }
+func (mn *MockNetwork) PeerURLs(skipEdgeNode bool) []string { return mn.PeerIDs }
+func (mn *MockNetwork) PeerExists(peerID string) bool {
+	for _, id := range mn.PeerIDs {
+		if id == peerID {
+			return true
+		}
+	}
+	return false
+}
+func (mn *MockNetwork) PeerHandshakeInfo(peerID string) *types.HandshakeInfo { return mn.Info[peerID] }
+func (mn *MockNetwork) NetworkStats(skipEdgeNode bool) *types.NetworkStats {
+	return &types.NetworkStats{}
+}
+func (mn *MockNetwork) RegisterMessageHandler(messageHandler p2p.MessageHandler) {}
+func (mn *MockNetwork) IsSeedPeer(peerID string) bool                          { return false }
+func (mn *MockNetwork) ID() string                                             { return "local" }
+
+// NextSent returns the next message sent, nil if none is sent within a second. The
+// dispatcher sends the messages to the peers asynchronously.
+func (mn *MockNetwork) NextSent() *MockSentMessage {
+	select {
+	case sent := <-mn.Sent:
+		return &sent
+	case <-time.After(1 * time.Second):
+		return nil
+	}
+}
+
 type MockConsensus struct {
 	chain *blockchain.Chain
 	lfb   *core.ExtendedBlock
@@ -202,7 +268,7 @@ func (c *MockConsensus) PrivateKey() *crypto.PrivateKey {
 }
 
 func (c *MockConsensus) GetTip(includePendingBlockingLeaf bool) *core.ExtendedBlock {
-	return nil
+	return c.lfb
 }
 
 func (c *MockConsensus) GetEpoch() uint64 {
@@ -222,6 +288,14 @@ func (c *MockConsensus) GetLastFinalizedBlock() *core.ExtendedBlock {
 	return c.lfb
 }
 
+func (c *MockConsensus) GetEpochVotes() (*core.VoteSet, error) {
+	return core.NewVoteSet(), nil
+}
+
+func (c *MockConsensus) GetValidatorSet(blockHash common.Hash) *core.ValidatorSet {
+	return nil
+}
+
 func TestCollectBlocks(t *testing.T) {
 	assert := assert.New(t)
 	core.ResetTestBlocks()
@@ -245,12 +319,12 @@ func TestCollectBlocks(t *testing.T) {
 	net2.RegisterMessageHandler(mockMsgHandler)
 	simnet.Start(context.Background())
 
-	dispatch := dispatcher.NewDispatcher(net1, nil)
+	dispatch := dispatcher.NewDispatcher(net1)
 	a3, _ := initChain.FindBlock(core.GetTestBlock("A3").Hash())
 	consensus := NewMockConsensus(initChain, a3)
 	mockMsgConsumer := NewMockMessageConsumer()
 
-	sm := NewSyncManager(initChain, consensus, net1, nil, dispatch, mockMsgConsumer)
+	sm := NewSyncManager(initChain, consensus, net1, dispatch, mockMsgConsumer, nil)
 
 	blocks := sm.collectBlocks(core.GetTestBlock("A1").Hash(), core.GetTestBlock("A5").Hash())
 	// Expected blocks: [A1, A2, A3, A4, D4, A5, A3]
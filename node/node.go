@@ -3,7 +3,6 @@ package node
 import (
 	"context"
 	"log"
-	"reflect"
 	"sync"
 
 	"github.com/spf13/viper"
@@ -17,7 +16,9 @@ import (
 	mp "github.com/scripttoken/script/mempool"
 	"github.com/scripttoken/script/netsync"
 	"github.com/scripttoken/script/p2p"
-	"github.com/scripttoken/script/p2pl"
+	"github.com/scripttoken/script/p2p/permission"
+	"github.com/scripttoken/script/p2p/reputation"
+	p2ptypes "github.com/scripttoken/script/p2p/types"
 	rp "github.com/scripttoken/script/report"
 	"github.com/scripttoken/script/rpc"
 	"github.com/scripttoken/script/snapshot"
@@ -25,6 +26,8 @@ import (
 	"github.com/scripttoken/script/store/database"
 	"github.com/scripttoken/script/store/kvstore"
 	"github.com/scripttoken/script/store/rollingdb"
+	"github.com/scripttoken/script/store/verifier"
+	"github.com/scripttoken/script/version"
 )
 
 type Node struct {
@@ -37,7 +40,11 @@ type Node struct {
 	Ledger           core.Ledger
 	Mempool          *mp.Mempool
 	RPC              *rpc.ScriptRPCServer
+	NodeFetcher      *verifier.NodeFetcher
 	reporter         *rp.Reporter
+	stateVerifier    *verifier.BackgroundVerifier
+	snapshotProducer *snapshot.Producer
+	snapshotServer   *snapshot.Server
 
 	// Life cycle
 	wg      *sync.WaitGroup
@@ -52,11 +59,13 @@ type Params struct {
 	EthChainID          int64
 	PrivateKey          *crypto.PrivateKey
 	Root                *core.Block
-	NetworkOld          p2p.Network
-	Network             p2pl.Network
+	Network             p2p.Network // usually a p2p.Registry composing the enabled transports
+	PeerReputation      *reputation.ReputationManager
+	PeerAllowlist       *permission.Allowlist
 	DB                  database.Database
 	RollingDB           *rollingdb.RollingDB
 	SnapshotPath        string
+	SnapshotDir         string
 	ChainImportDirPath  string
 	ChainCorrectionPath string
 }
@@ -67,25 +76,32 @@ func NewNode(params *Params) *Node {
 	params.RollingDB.SetChain(chain)
 
 	validatorManager := consensus.NewRotatingValidatorManager()
-	dispatcher := dp.NewDispatcher(params.NetworkOld, params.Network)
+	dispatcher := dp.NewDispatcher(params.Network)
+	if params.PeerReputation != nil {
+		dispatcher.SetReputationManager(params.PeerReputation)
+	}
+	if params.PeerAllowlist != nil {
+		dispatcher.SetAllowlist(params.PeerAllowlist)
+	}
 	consensus := consensus.NewConsensusEngine(params.PrivateKey, store, chain, dispatcher, validatorManager)
 	reporter := rp.NewReporter(dispatcher, consensus, chain)
 
 	// TODO: check if this is a lightning node
-	syncMgr := netsync.NewSyncManager(chain, consensus, params.NetworkOld, params.Network, dispatcher, consensus, reporter)
+	syncMgr := netsync.NewSyncManager(chain, consensus, params.Network, dispatcher, consensus, reporter)
 	mempool := mp.CreateMempool(dispatcher, consensus)
 	ledger := ld.NewLedger(params.ChainID, params.RollingDB, params.RollingDB, chain, consensus, validatorManager, mempool)
 
 	validatorManager.SetConsensusEngine(consensus)
 	consensus.SetLedger(ledger)
 	mempool.SetLedger(ledger)
+	syncMgr.SetTxSource(mempool)
+	syncMgr.SetVoteObserver(consensus.VoteGossip())
 	txMsgHandler := mp.CreateMempoolMessageHandler(mempool)
+	nodeFetcher := verifier.NewNodeFetcher(params.RollingDB, dispatcher)
 
-	if !reflect.ValueOf(params.Network).IsNil() {
+	if params.Network != nil {
 		params.Network.RegisterMessageHandler(txMsgHandler)
-	}
-	if !reflect.ValueOf(params.NetworkOld).IsNil() {
-		params.NetworkOld.RegisterMessageHandler(txMsgHandler)
+		params.Network.RegisterMessageHandler(nodeFetcher)
 	}
 
 	currentHeight := consensus.GetLastFinalizedBlock().Height
@@ -107,6 +123,8 @@ func NewNode(params *Params) *Node {
 		}
 	}
 
+	params.RollingDB.InitOldestStateHeight(chain.Root().Height)
+
 	node := &Node{
 		Store:            store,
 		Chain:            chain,
@@ -116,11 +134,33 @@ func NewNode(params *Params) *Node {
 		Dispatcher:       dispatcher,
 		Ledger:           ledger,
 		Mempool:          mempool,
+		NodeFetcher:      nodeFetcher,
 		reporter:         reporter,
 	}
 
+	if viper.GetBool(common.CfgStorageVerifyInBackground) {
+		// The ref counts are only maintained when the DB layers do not roll
+		var refDB database.Database
+		if !rollingdb.IsRollingEnabled() {
+			refDB = params.DB
+		}
+		var fetcher *verifier.NodeFetcher
+		if viper.GetBool(common.CfgStorageVerifyRepair) {
+			fetcher = nodeFetcher
+		}
+		node.stateVerifier = verifier.NewBackgroundVerifier(params.RollingDB, refDB, consensus, fetcher)
+	}
+
+	if viper.GetBool(common.CfgSnapshotAutoEnabled) {
+		node.snapshotProducer = snapshot.NewProducer(params.RollingDB, consensus, chain, params.PrivateKey, params.SnapshotDir)
+	}
+	if viper.GetBool(common.CfgSnapshotServeEnabled) {
+		node.snapshotServer = snapshot.NewServer(params.SnapshotDir)
+	}
+
 	if viper.GetBool(common.CfgRPCEnabled) {
 		node.RPC = rpc.NewScriptRPCServer(mempool, ledger, dispatcher, chain, consensus)
+		node.RPC.SetSyncManager(syncMgr)
 	}
 	return node
 }
@@ -136,6 +176,15 @@ func (n *Node) Start(ctx context.Context) {
 	n.Dispatcher.Start(n.ctx)
 	n.Mempool.Start(n.ctx)
 	n.reporter.Start(n.ctx)
+	if n.stateVerifier != nil {
+		n.stateVerifier.Start(n.ctx)
+	}
+	if n.snapshotProducer != nil {
+		n.snapshotProducer.Start(n.ctx)
+	}
+	if n.snapshotServer != nil {
+		n.snapshotServer.Start(n.ctx)
+	}
 
 	if viper.GetBool(common.CfgRPCEnabled) {
 		n.RPC.Start(n.ctx)
@@ -151,7 +200,47 @@ func (n *Node) Stop() {
 func (n *Node) Wait() {
 	n.Consensus.Wait()
 	n.SyncManager.Wait()
+	if n.stateVerifier != nil {
+		n.stateVerifier.Wait()
+	}
+	if n.snapshotProducer != nil {
+		n.snapshotProducer.Wait()
+	}
+	if n.snapshotServer != nil {
+		n.snapshotServer.Wait()
+	}
 	if n.RPC != nil {
 		n.RPC.Wait()
 	}
 }
+
+// HandshakeInfo returns the information and the capabilities of the node advertised
+// to the peers in the P2P handshake
+func (n *Node) HandshakeInfo() *p2ptypes.HandshakeInfo {
+	capabilities := []string{p2ptypes.CapabilityStateSync}
+	if common.IsArchiveMode() {
+		capabilities = append(capabilities, p2ptypes.CapabilityArchive)
+	}
+	if viper.GetBool(common.CfgSnapshotServeEnabled) {
+		capabilities = append(capabilities, p2ptypes.CapabilitySnapshot)
+	}
+	if viper.GetBool(common.CfgSyncCompactBlockEnabled) {
+		capabilities = append(capabilities, p2ptypes.CapabilityCompactBlock)
+	}
+	if viper.GetBool(common.CfgConsensusVoteGossipEnabled) {
+		capabilities = append(capabilities, p2ptypes.CapabilityVoteSummary)
+	}
+	if viper.GetBool(common.CfgP2PQUICEnabled) {
+		capabilities = append(capabilities, p2ptypes.CapabilityQUIC)
+	}
+
+	lfb := n.Consensus.GetLastFinalizedBlock()
+	return &p2ptypes.HandshakeInfo{
+		ProtocolVersion: p2ptypes.ProtocolVersion,
+		SoftwareVersion: version.Version,
+		GenesisHash:     common.HexToHash(viper.GetString(common.CfgGenesisHash)),
+		FinalizedHeight: lfb.Height,
+		FinalizedHash:   lfb.Hash(),
+		Capabilities:    capabilities,
+	}
+}
@@ -82,7 +82,8 @@ var errPlainMessageTooLarge = errors.New("message length >= 16MB")
 // DoEncHandshake runs the protocol handshake using authenticated
 // messages. the protocol handshake is the first authenticated message
 // and also verifies whether the encryption handshake 'worked' and the
-// remote side actually provided the right public key.
+// remote side actually provided the right public key. Over QUIC, it also
+// binds the QUIC session to the keys of the handshake.
 func (conn *Connection) DoEncHandshake(prv *ecdsa.PrivateKey, dial *ecdsa.PublicKey) (*crypto.PublicKey, error) {
 	var (
 		sec secrets
@@ -99,6 +100,11 @@ func (conn *Connection) DoEncHandshake(prv *ecdsa.PrivateKey, dial *ecdsa.Public
 	conn.wmu.Lock()
 	conn.rw = newRLPXFrameRW(conn.bufConn, sec)
 	conn.wmu.Unlock()
+	if conn.quic != nil {
+		if err := conn.bindQUICSession(); err != nil {
+			return nil, err
+		}
+	}
 	return crypto.ECDSAToPubKey(sec.Remote.ExportECDSA()), nil
 }
 
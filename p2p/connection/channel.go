@@ -86,6 +86,7 @@ func (ch *Channel) sendPacketTo(conn *Connection) (nonemptyPacket bool, numBytes
 	if err != nil {
 		return true, int(0), nil
 	}
+	conn.recordSent(ch.id, len(packet.Bytes))
 	numBytes = 0
 
 	// numBytes, err = writer.Write(packetBytes)
@@ -11,6 +11,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/quic-go/quic-go"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/scripttoken/script/common"
@@ -53,12 +54,26 @@ type Connection struct {
 	pingTimer  *timer.RepeatTimer   // send pings periodically
 
 	pendingPings uint32
+	pingSentAt   int64 // unix nano time of the last ping
+	latency      int64 // round trip time of the last ping/pong, in nanoseconds
+
+	// Diagnostics
+	createdAt time.Time
+	traffic   *p2ptypes.TrafficCounter
+	sendStats *flowrate.Monitor // measures the rates, unlike sendMonitor which throttles them
+	recvStats *flowrate.Monitor
 
 	config ConnectionConfig
 
 	rmu, wmu sync.Mutex
 	rw       *rlpxFrameRW
 
+	// QUIC transport, nil over TCP
+	quic         quic.Connection
+	streamPulses map[common.ChannelIDEnum]chan bool
+	recvStreams  map[common.ChannelIDEnum]bool
+	streamMu     sync.Mutex
+
 	// Life cycle
 	wg      *sync.WaitGroup
 	quit    chan struct{}
@@ -113,6 +128,9 @@ func CreateConnection(netconn net.Conn, config ConnectionConfig) *Connection {
 	channelNATMapping := createDefaultChannel(common.ChannelIDNATMapping)
 	channelEliteEdgeNodeVote := createDefaultChannel(common.ChannelIDEliteEdgeNodeVote)
 	channelEliteAggregatedEdgeNodeVotes := createDefaultChannel(common.ChannelIDAggregatedEliteEdgeNodeVotes)
+	channelStateNode := createDefaultChannel(common.ChannelIDStateNode)
+	channelCompactBlock := createDefaultChannel(common.ChannelIDCompactBlock)
+	channelVoteSummary := createDefaultChannel(common.ChannelIDVoteSummary)
 	channels := []*Channel{
 		&channelCheckpoint,
 		&channelHeader,
@@ -126,6 +144,9 @@ func CreateConnection(netconn net.Conn, config ConnectionConfig) *Connection {
 		&channelNATMapping,
 		&channelEliteEdgeNodeVote,
 		&channelEliteAggregatedEdgeNodeVotes,
+		&channelStateNode,
+		&channelCompactBlock,
+		&channelVoteSummary,
 	}
 
 	success, channelGroup := createChannelGroup(getDefaultChannelGroupConfig(), channels)
@@ -145,6 +166,10 @@ func CreateConnection(netconn net.Conn, config ConnectionConfig) *Connection {
 		quitPulse:    make(chan bool, 1),
 		flushTimer:   timer.NewThrottleTimer("flush", config.FlushThrottle),
 		pingTimer:    timer.NewRepeatTimer("ping", config.PingTimeout),
+		createdAt:    time.Now(),
+		traffic:      p2ptypes.NewTrafficCounter(),
+		sendStats:    flowrate.New(0, 0),
+		recvStats:    flowrate.New(0, 0),
 		config:       config,
 		wg:           &sync.WaitGroup{},
 
@@ -154,6 +179,15 @@ func CreateConnection(netconn net.Conn, config ConnectionConfig) *Connection {
 		io.Reader
 		io.Writer
 	}{Reader: conn.bufReader, Writer: conn.netconn}
+
+	if qc, ok := netconn.(*quicConn); ok {
+		conn.quic = qc.session
+		conn.streamPulses = make(map[common.ChannelIDEnum]chan bool)
+		conn.recvStreams = make(map[common.ChannelIDEnum]bool)
+		for _, channel := range channels {
+			conn.streamPulses[channel.getID()] = make(chan bool, 1)
+		}
+	}
 	return conn
 }
 
@@ -191,6 +225,9 @@ func (conn *Connection) Start(ctx context.Context) bool {
 
 	go conn.sendRoutine()
 	go conn.recvRoutine()
+	if conn.quic != nil {
+		conn.startStreams()
+	}
 	return true
 }
 
@@ -258,7 +295,7 @@ func (conn *Connection) EnqueueMessage(channelID common.ChannelIDEnum, message i
 	}
 	success := channel.enqueueMessage(msgBytes)
 	if success {
-		conn.scheduleSendPulse()
+		conn.scheduleChannelSendPulse(channelID)
 	}
 
 	return success
@@ -280,7 +317,7 @@ func (conn *Connection) AttemptToEnqueueMessage(channelID common.ChannelIDEnum,
 	}
 	success := channel.attemptToEnqueueMessage(msgBytes)
 	if success {
-		conn.scheduleSendPulse()
+		conn.scheduleChannelSendPulse(channelID)
 	}
 
 	return success
@@ -354,6 +391,7 @@ func (conn *Connection) sendPingSignal() error {
 	conn.sendMonitor.Update(int(1))
 	conn.flush()
 	atomic.AddUint32(&conn.pendingPings, 1)
+	atomic.StoreInt64(&conn.pingSentAt, time.Now().UnixNano())
 	return nil
 }
 
@@ -456,7 +494,9 @@ func (conn *Connection) handlePingPong(packet *Packet) (success bool) {
 	case p2ptypes.PingSignal:
 		conn.schedulePongPulse()
 	case p2ptypes.PongSignal:
-		// do nothing for now
+		if sentAt := atomic.LoadInt64(&conn.pingSentAt); sentAt > 0 {
+			atomic.StoreInt64(&conn.latency, time.Now().UnixNano()-sentAt)
+		}
 	default:
 		logger.Errorf("Invalid Ping/Pong signal")
 		return false
@@ -476,6 +516,7 @@ func (conn *Connection) handleReceivedPacket(packet *Packet) (success bool) {
 	if !success {
 		return false
 	}
+	conn.recordReceived(channelID, len(packet.Bytes))
 
 	if aggregatedBytes == nil {
 		return true
@@ -547,6 +588,58 @@ func (conn *Connection) sendPacket() (success bool, exhausted bool) {
 	return true, false
 }
 
+// --------------------- Diagnostics --------------------- //
+
+//
+// ConnectionStats provides the traffic statistics of the connection
+//
+type ConnectionStats struct {
+	CreatedAt time.Time
+	Transport string        // p2ptypes.TransportTCP or p2ptypes.TransportQUIC
+	Latency   time.Duration // round trip time of the last ping, 0 if not measured yet
+	SendRate  int64         // bytes per second
+	RecvRate  int64         // bytes per second
+	Channels  []p2ptypes.ChannelStats
+}
+
+// Stats returns the traffic statistics of the connection
+func (conn *Connection) Stats() ConnectionStats {
+	stats := ConnectionStats{
+		CreatedAt: conn.createdAt,
+		Transport: p2ptypes.TransportTCP,
+		Latency:   time.Duration(atomic.LoadInt64(&conn.latency)),
+		SendRate:  conn.sendStats.Status().CurRate,
+		RecvRate:  conn.recvStats.Status().CurRate,
+	}
+
+	if conn.quic != nil {
+		stats.Transport = p2ptypes.TransportQUIC
+	}
+
+	traffic := map[common.ChannelIDEnum]p2ptypes.ChannelStats{}
+	for _, ch := range conn.traffic.ChannelStats() {
+		traffic[ch.ChannelID] = ch
+	}
+	for _, channel := range *conn.channelGroup.getAllChannels() {
+		ch := traffic[channel.getID()]
+		ch.ChannelID = channel.getID()
+		ch.QueueSize = channel.sendBuf.getSize()
+		ch.QueueCapacity = channel.sendBuf.config.queueCapacity
+		stats.Channels = append(stats.Channels, ch)
+	}
+	return stats
+}
+
+func (conn *Connection) recordSent(channelID common.ChannelIDEnum, size int) {
+	conn.traffic.RecordSent(channelID, size)
+	conn.sendStats.Update(size)
+}
+
+func (conn *Connection) recordReceived(channelID common.ChannelIDEnum, size int) {
+	conn.traffic.RecordReceived(channelID, size)
+	conn.recvStats.Update(size)
+}
+
 // --------------------- Utils --------------------- //
 
 // GetNetconn returns the attached network connection
@@ -0,0 +1,394 @@
+package connection
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/rlp"
+)
+
+//
+// Over QUIC, the handshake and the ping/pong signals run on a bidirectional control
+// stream opened by the dialer, which is wrapped as the net.Conn of the Connection.
+// After the handshake, each channel sends its packets on a unidirectional stream of
+// its own, so the votes are not held back behind the block data queued before them.
+//
+
+const (
+	quicALPN = "script-p2p"
+
+	// quicBindingLabel is the TLS exporter label of the value exchanged in the
+	// encrypted frames to bind the QUIC session to the authenticated peers
+	quicBindingLabel = "EXPORTER-script-p2p-quic-binding"
+	quicBindingSize  = 32
+
+	quicHandshakeTimeout = 5 * time.Second
+	quicKeepAlivePeriod  = 15 * time.Second
+	quicMaxIdleTimeout   = 60 * time.Second
+	quicMaxStreams       = 64 // unidirectional streams, at most one per channel
+)
+
+var (
+	errQUICListenerClosed  = errors.New("QUIC listener closed")
+	errQUICSessionNotBound = errors.New("QUIC session is not bound to the authenticated peer")
+)
+
+//
+// quicConn is the control stream of a QUIC connection
+//
+type quicConn struct {
+	quic.Stream
+	session quic.Connection
+}
+
+var _ net.Conn = (*quicConn)(nil)
+
+// LocalAddr returns the local UDP address of the QUIC connection
+func (qc *quicConn) LocalAddr() net.Addr {
+	return qc.session.LocalAddr()
+}
+
+// RemoteAddr returns the remote UDP address of the QUIC connection
+func (qc *quicConn) RemoteAddr() net.Addr {
+	return qc.session.RemoteAddr()
+}
+
+// Close closes the whole QUIC connection, including the streams of the channels
+func (qc *quicConn) Close() error {
+	return qc.session.CloseWithError(0, "")
+}
+
+// IsQUIC returns whether the given network connection is the control stream of a
+// QUIC connection
+func IsQUIC(netconn net.Conn) bool {
+	_, ok := netconn.(*quicConn)
+	return ok
+}
+
+// DialQUIC dials a QUIC connection to the given UDP address and opens its
+// control stream
+func DialQUIC(addr string, timeout time.Duration) (net.Conn, error) {
+	tlsConfig, err := newQUICTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	session, err := quic.DialAddr(ctx, addr, tlsConfig, newQUICConfig())
+	if err != nil {
+		return nil, err
+	}
+	stream, err := session.OpenStreamSync(ctx)
+	if err != nil {
+		session.CloseWithError(0, "")
+		return nil, err
+	}
+	return &quicConn{Stream: stream, session: session}, nil
+}
+
+//
+// QUICListener accepts the QUIC connections of the peers, and hands out their
+// control streams
+//
+type QUICListener struct {
+	listener *quic.Listener
+	conns    chan net.Conn
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+var _ net.Listener = (*QUICListener)(nil)
+
+// ListenQUIC listens for QUIC connections on the given UDP address
+func ListenQUIC(addr string) (*QUICListener, error) {
+	tlsConfig, err := newQUICTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := quic.ListenAddr(addr, tlsConfig, newQUICConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ql := &QUICListener{
+		listener: listener,
+		conns:    make(chan net.Conn),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	go ql.acceptRoutine()
+	return ql, nil
+}
+
+// Accept waits for the next QUIC connection and returns its control stream
+func (ql *QUICListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-ql.conns:
+		return conn, nil
+	case <-ql.ctx.Done():
+		return nil, errQUICListenerClosed
+	}
+}
+
+// Close stops the listener. The accepted connections stay open
+func (ql *QUICListener) Close() error {
+	ql.cancel()
+	return ql.listener.Close()
+}
+
+// Addr returns the UDP address of the listener
+func (ql *QUICListener) Addr() net.Addr {
+	return ql.listener.Addr()
+}
+
+func (ql *QUICListener) acceptRoutine() {
+	for {
+		session, err := ql.listener.Accept(ql.ctx)
+		if err != nil {
+			return // closed
+		}
+		go ql.acceptControlStream(session)
+	}
+}
+
+// acceptControlStream waits for the dialer to open the control stream, which it
+// does by sending its NodeInfo, without holding up the other connections
+func (ql *QUICListener) acceptControlStream(session quic.Connection) {
+	ctx, cancel := context.WithTimeout(ql.ctx, quicHandshakeTimeout)
+	defer cancel()
+
+	stream, err := session.AcceptStream(ctx)
+	if err != nil {
+		logger.Debugf("No control stream from %v: %v", session.RemoteAddr(), err)
+		session.CloseWithError(0, "")
+		return
+	}
+	select {
+	case ql.conns <- &quicConn{Stream: stream, session: session}:
+	case <-ql.ctx.Done():
+		session.CloseWithError(0, "")
+	}
+}
+
+// newQUICTLSConfig creates the TLS configuration with an ephemeral certificate. The
+// certificates are not verified, since the peers are authenticated by the
+// encryption handshake, to which the session is bound in bindQUICSession()
+func newQUICTLSConfig() (*tls.Config, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * 365 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates:       []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		InsecureSkipVerify: true,
+		NextProtos:         []string{quicALPN},
+		MinVersion:         tls.VersionTLS13,
+	}, nil
+}
+
+func newQUICConfig() *quic.Config {
+	return &quic.Config{
+		HandshakeIdleTimeout:  quicHandshakeTimeout,
+		MaxIdleTimeout:        quicMaxIdleTimeout,
+		KeepAlivePeriod:       quicKeepAlivePeriod,
+		MaxIncomingStreams:    1, // the control stream
+		MaxIncomingUniStreams: quicMaxStreams,
+	}
+}
+
+// --------------------- QUIC streams --------------------- //
+
+// bindQUICSession exchanges the TLS exporter values of the QUIC session in the
+// encrypted frames. They only match if the TLS session ends at the peer which
+// completed the encryption handshake, and not at a relay in between
+func (conn *Connection) bindQUICSession() error {
+	tlsState := conn.quic.ConnectionState().TLS
+	local, err := tlsState.ExportKeyingMaterial(quicBindingLabel, nil, quicBindingSize)
+	if err != nil {
+		return err
+	}
+
+	var remote *Packet
+	var sendError, recvError error
+	common.Parallel(
+		func() {
+			sendError = conn.writePacket(&Packet{
+				ChannelID: common.ChannelIDPing,
+				Bytes:     local,
+				IsEOF:     byte(0x01),
+			})
+		},
+		func() {
+			remote, recvError = conn.readPacket()
+		},
+	)
+	if sendError != nil {
+		return sendError
+	}
+	if recvError != nil {
+		return recvError
+	}
+	if !hmac.Equal(local, remote.Bytes) {
+		return errQUICSessionNotBound
+	}
+	return nil
+}
+
+// startStreams starts a sender for each channel, and the routine accepting the
+// streams of the peer
+func (conn *Connection) startStreams() {
+	for _, channel := range *conn.channelGroup.getAllChannels() {
+		go conn.streamSendRoutine(channel, conn.streamPulses[channel.getID()])
+	}
+	go conn.acceptStreamsRoutine()
+}
+
+// streamSendRoutine sends the packets of the channel on its own stream. The stream
+// starts with the channel ID.
+func (conn *Connection) streamSendRoutine(channel *Channel, pulse chan bool) {
+	defer conn.recover()
+
+	stream, err := conn.quic.OpenUniStreamSync(conn.ctx)
+	if err != nil {
+		conn.stopForError(fmt.Errorf("Failed to open the stream of channel %v: %v", channel.getID(), err))
+		return
+	}
+	defer stream.Close()
+
+	writer := bufio.NewWriter(stream)
+	writer.WriteByte(byte(channel.getID())) // sent along with the first packets
+
+	for {
+		select {
+		case <-conn.ctx.Done():
+			return
+		case <-pulse:
+		}
+
+		for {
+			// Block until sendMonitor allows sending
+			conn.sendMonitor.Limit(maxPacketTotalSize, atomic.LoadInt64(&conn.config.SendRate), true)
+
+			packet := channel.sendBuf.emitPacket(channel.getID())
+			if packet.isEmpty() {
+				break
+			}
+			if err := rlp.Encode(writer, &packet); err != nil {
+				conn.stopForError(fmt.Errorf("Failed to send packet on channel %v: %v", channel.getID(), err))
+				return
+			}
+			conn.sendMonitor.Update(len(packet.Bytes))
+			conn.recordSent(channel.getID(), len(packet.Bytes))
+		}
+		if err := writer.Flush(); err != nil {
+			conn.stopForError(fmt.Errorf("Failed to flush channel %v: %v", channel.getID(), err))
+			return
+		}
+	}
+}
+
+func (conn *Connection) acceptStreamsRoutine() {
+	defer conn.recover()
+
+	for {
+		stream, err := conn.quic.AcceptUniStream(conn.ctx)
+		if err != nil {
+			return // the connection is closed
+		}
+		go conn.streamRecvRoutine(stream)
+	}
+}
+
+// streamRecvRoutine reads the packets of the channel the peer opened the stream for
+func (conn *Connection) streamRecvRoutine(stream quic.ReceiveStream) {
+	defer conn.recover()
+
+	reader := bufio.NewReader(stream)
+	id, err := reader.ReadByte()
+	if err != nil {
+		return
+	}
+	channelID := common.ChannelIDEnum(id)
+	if !conn.claimRecvStream(channelID) {
+		conn.stopForError(fmt.Errorf("Unexpected stream for channel %v", channelID))
+		return
+	}
+
+	for {
+		// Block until recvMonitor allows reading
+		conn.recvMonitor.Limit(maxPacketTotalSize, atomic.LoadInt64(&conn.config.RecvRate), true)
+
+		packet := &Packet{}
+		s := rlp.NewStream(reader, maxPayloadSize*1024)
+		if err := s.Decode(packet); err != nil {
+			conn.stopForError(fmt.Errorf("Failed to decode packet on channel %v: %v", channelID, err))
+			return
+		}
+		if packet.ChannelID != channelID {
+			conn.stopForError(fmt.Errorf("Packet of channel %v on the stream of channel %v", packet.ChannelID, channelID))
+			return
+		}
+		conn.recvMonitor.Update(len(packet.Bytes))
+		switch channelID {
+		case common.ChannelIDPing:
+			conn.handlePingPong(packet)
+		default:
+			conn.handleReceivedPacket(packet)
+		}
+	}
+}
+
+// claimRecvStream makes sure the peer opens at most one stream for each channel,
+// since the receive buffer of a channel reassembles the packets in order
+func (conn *Connection) claimRecvStream(channelID common.ChannelIDEnum) bool {
+	if conn.channelGroup.getChannel(channelID) == nil {
+		return false
+	}
+
+	conn.streamMu.Lock()
+	defer conn.streamMu.Unlock()
+
+	if conn.recvStreams[channelID] {
+		return false
+	}
+	conn.recvStreams[channelID] = true
+	return true
+}
+
+func (conn *Connection) scheduleChannelSendPulse(channelID common.ChannelIDEnum) {
+	pulse, ok := conn.streamPulses[channelID]
+	if !ok {
+		conn.scheduleSendPulse()
+		return
+	}
+	select {
+	case pulse <- true:
+	default:
+	}
+}
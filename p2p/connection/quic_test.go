@@ -0,0 +1,91 @@
+package connection
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/crypto"
+)
+
+func TestQUICSessionBinding(t *testing.T) {
+	assert := assert.New(t)
+	addr := "127.0.0.1:43261"
+	listener, err := ListenQUIC(addr)
+	assert.Nil(err)
+	defer listener.Close()
+
+	netconn, err := DialQUIC(addr, 5*time.Second)
+	assert.Nil(err)
+	defer netconn.Close()
+	assert.True(IsQUIC(netconn))
+
+	errA, errB := encHandshakeOverQUIC(assert, listener, netconn, netconn.(*quicConn).session)
+	assert.Nil(errA)
+	assert.Nil(errB)
+}
+
+func TestQUICSessionBindingMismatch(t *testing.T) {
+	assert := assert.New(t)
+	addr := "127.0.0.1:43262"
+	listener, err := ListenQUIC(addr)
+	assert.Nil(err)
+	defer listener.Close()
+
+	// The handshake runs through the control stream of another session, as it would
+	// through a relay terminating the TLS of the bound session on its own
+	bound, err := DialQUIC(addr, 5*time.Second)
+	assert.Nil(err)
+	defer bound.Close()
+	relayed, err := DialQUIC(addr, 5*time.Second)
+	assert.Nil(err)
+	defer relayed.Close()
+
+	errA, errB := encHandshakeOverQUIC(assert, listener, relayed, bound.(*quicConn).session)
+	assert.Equal(errQUICSessionNotBound, errA)
+	assert.Equal(errQUICSessionNotBound, errB)
+}
+
+// --------------- Test Utilities --------------- //
+
+func encHandshakeOverQUIC(assert *assert.Assertions, listener net.Listener, netconn net.Conn, session quic.Connection) (errA, errB error) {
+	privKeyA, pubKeyA, err := crypto.GenerateKeyPair()
+	assert.Nil(err)
+	privKeyB, pubKeyB, err := crypto.GenerateKeyPair()
+	assert.Nil(err)
+
+	connA := CreateConnection(netconn, GetDefaultConnectionConfig())
+	connA.quic = session
+
+	var accepted net.Conn
+	defer func() {
+		if accepted != nil {
+			accepted.Close()
+		}
+	}()
+	common.Parallel(
+		func() {
+			// The stream reaches the listener with its first bytes, as with the NodeInfo
+			// sent first in the peer handshake
+			if _, errA = netconn.Write([]byte{0x01}); errA != nil {
+				return
+			}
+			_, errA = connA.DoEncHandshake(crypto.PrivKeyToECDSA(privKeyA), crypto.PubKeyToECDSA(pubKeyB))
+		},
+		func() {
+			accepted, errB = listener.Accept()
+			if errB != nil {
+				return
+			}
+			connB := CreateConnection(accepted, GetDefaultConnectionConfig())
+			if _, errB = connB.GetBufReader().ReadByte(); errB != nil {
+				return
+			}
+			_, errB = connB.DoEncHandshake(crypto.PrivKeyToECDSA(privKeyB), crypto.PubKeyToECDSA(pubKeyA))
+		},
+	)
+	return errA, errB
+}
@@ -57,6 +57,13 @@ type Network interface {
 	// PeerExists indicates if the given peerID is a neighboring peer
 	PeerExists(peerID string) bool
 
+	// PeerHandshakeInfo returns the versioned handshake info of the given peer, nil if the
+	// peer is not connected or runs an older version
+	PeerHandshakeInfo(peerID string) *types.HandshakeInfo
+
+	// NetworkStats returns the diagnostic information of the peers and their traffic
+	NetworkStats(skipEdgeNode bool) *types.NetworkStats
+
 	// RegisterMessageHandler registers message handler
 	RegisterMessageHandler(messageHandler MessageHandler)
 
@@ -11,6 +11,7 @@ import (
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/spf13/viper"
 	"github.com/scripttoken/script/common"
+	cn "github.com/scripttoken/script/p2p/connection"
 	"github.com/scripttoken/script/p2p/netutil"
 	pr "github.com/scripttoken/script/p2p/peer"
 
@@ -27,6 +28,7 @@ type InboundPeerListener struct {
 	discMgr *PeerDiscoveryManager
 
 	netListener  net.Listener
+	quicListener net.Listener // nil if QUIC is disabled
 	internalAddr *netutil.NetAddress
 	externalAddr *netutil.NetAddress
 
@@ -62,6 +64,18 @@ func createInboundPeerListener(discMgr *PeerDiscoveryManager, protocol string, l
 	netListenerIP, netListenerPort := splitHostPort(netListener.Addr().String())
 	logger.Infof("Local network listener, ip: %v, port: %v", netListenerIP, netListenerPort)
 
+	var quicListener net.Listener
+	if viper.GetBool(common.CfgP2PQUICEnabled) {
+		quicAddr := net.JoinHostPort(localAddrIP, strconv.Itoa(netListenerPort))
+		ql, err := cn.ListenQUIC(quicAddr)
+		if err != nil {
+			netListener.Close()
+			return InboundPeerListener{}, fmt.Errorf("Failed to initiate QUIC listener: %v", err)
+		}
+		quicListener = ql
+		logger.Infof("Local QUIC listener: %v", ql.Addr())
+	}
+
 	internalNetAddr := getInternalNetAddress(localAddr)
 	externalNetAddr := getExternalNetAddress(localAddrIP, externalPort, netListenerPort, skipUPNP)
 
@@ -73,6 +87,7 @@ func createInboundPeerListener(discMgr *PeerDiscoveryManager, protocol string, l
 	inboundPeerListener := InboundPeerListener{
 		discMgr:      discMgr,
 		netListener:  netListener,
+		quicListener: quicListener,
 		internalAddr: internalNetAddr,
 		externalAddr: externalNetAddr,
 		config:       config,
@@ -101,12 +116,20 @@ func (ipl *InboundPeerListener) Start(ctx context.Context) error {
 	ipl.wg.Add(1)
 	go ipl.listenRoutine()
 
+	if ipl.quicListener != nil {
+		ipl.wg.Add(1)
+		go ipl.quicListenRoutine()
+	}
+
 	return nil
 }
 
 // Stop is called when the InboundPeerListener instance stops
 func (ipl *InboundPeerListener) Stop() {
 	ipl.netListener.Close()
+	if ipl.quicListener != nil {
+		ipl.quicListener.Close()
+	}
 	ipl.cancel()
 }
 
@@ -143,60 +166,82 @@ func (ipl *InboundPeerListener) listenRoutine() {
 			logger.Fatalf("net listener error: %v", err)
 		}
 
-		remoteAddr := netutil.NewNetAddress(netconn.RemoteAddr())
-		if seedPeerOnly {
-			isNotASeedPeer := !ipl.discMgr.seedPeerConnector.isASeedPeerIgnoringPort(remoteAddr)
-			if isNotASeedPeer {
-				logger.Debugf("%v is not a seed peer, ignore inbound connection request", remoteAddr.String())
-				netconn.Close()
-				continue
-			} else {
-				logger.Infof("Accept inbound connection from seed peer %v", remoteAddr.String())
-			}
-		} else {
-			skipEdgeNode := !viper.GetBool(common.CfgP2PIsBootstrapNode)
-			numPeers := int(ipl.discMgr.peerTable.GetTotalNumPeers(skipEdgeNode))
-			if numPeers >= maxNumPeers {
-				if viper.GetBool(common.CfgP2PConnectionFIFO) {
-					purgedPeer := ipl.discMgr.peerTable.PurgeOldestPeer()
-					if purgedPeer != nil {
-						purgedPeer.Stop()
-						logger.Infof("Purged old peer %v to make room for inbound connection request from %v", purgedPeer.ID(), remoteAddr.String())
-					}
-				} else {
-					logger.Debugf("Max peers limit %v reached, ignore inbound connection request from %v", maxNumPeers, remoteAddr.String())
-					netconn.Close()
-					continue
-				}
-			}
+		ipl.acceptInboundConnection(netconn, seedPeerOnly, maxNumPeers)
+	}
+}
 
-			backOffTimeVal, ok := ipl.coolOffPool.Get(remoteAddr.IP.String())
-			if ok {
-				backOffTime, _ := backOffTimeVal.(time.Time)
-				if backOffTime.After(time.Now()) {
-					logger.Debugf("Rejecting aggressive connection from %v", remoteAddr.String())
-					netconn.Close()
-					continue
-				}
-			}
+func (ipl *InboundPeerListener) quicListenRoutine() {
+	defer ipl.wg.Done()
 
-			ipl.coolOffPool.Add(remoteAddr.IP.String(), time.Now().Add(3*time.Second))
-		}
+	seedPeerOnly := viper.GetBool(common.CfgP2PSeedPeerOnly)
+	maxNumPeers := GetDefaultPeerDiscoveryManagerConfig().MaxNumPeers
+	logger.Infof("InboundPeerListener QUIC listen routine started, seedPeerOnly set to %v", seedPeerOnly)
 
-		go func(netconn net.Conn) {
-			peer, err := ipl.discMgr.connectWithInboundPeer(netconn, true)
-			if err != nil {
-				ipl.coolOffPool.Add(remoteAddr.IP.String(), time.Now().Add(1*time.Minute))
+	for {
+		netconn, err := ipl.quicListener.Accept()
+		if err != nil {
+			logger.Infof("QUIC listener stopped: %v", err)
+			return
+		}
 
-				logger.Debugf("Backing off from invalid handshake %v", remoteAddr.String())
+		ipl.acceptInboundConnection(netconn, seedPeerOnly, maxNumPeers)
+	}
+}
 
+func (ipl *InboundPeerListener) acceptInboundConnection(netconn net.Conn, seedPeerOnly bool, maxNumPeers int) {
+	remoteAddr := netutil.NewNetAddress(netconn.RemoteAddr())
+	if seedPeerOnly {
+		isNotASeedPeer := !ipl.discMgr.seedPeerConnector.isASeedPeerIgnoringPort(remoteAddr)
+		if isNotASeedPeer {
+			logger.Debugf("%v is not a seed peer, ignore inbound connection request", remoteAddr.String())
+			netconn.Close()
+			return
+		} else {
+			logger.Infof("Accept inbound connection from seed peer %v", remoteAddr.String())
+		}
+	} else {
+		skipEdgeNode := !viper.GetBool(common.CfgP2PIsBootstrapNode)
+		numPeers := int(ipl.discMgr.peerTable.GetTotalNumPeers(skipEdgeNode))
+		if numPeers >= maxNumPeers {
+			if viper.GetBool(common.CfgP2PConnectionFIFO) {
+				purgedPeer := ipl.discMgr.peerTable.PurgeOldestPeer()
+				if purgedPeer != nil {
+					purgedPeer.Stop()
+					logger.Infof("Purged old peer %v to make room for inbound connection request from %v", purgedPeer.ID(), remoteAddr.String())
+				}
+			} else {
+				logger.Debugf("Max peers limit %v reached, ignore inbound connection request from %v", maxNumPeers, remoteAddr.String())
 				netconn.Close()
+				return
 			}
-			if ipl.inboundCallback != nil {
-				ipl.inboundCallback(peer, err)
+		}
+
+		backOffTimeVal, ok := ipl.coolOffPool.Get(remoteAddr.IP.String())
+		if ok {
+			backOffTime, _ := backOffTimeVal.(time.Time)
+			if backOffTime.After(time.Now()) {
+				logger.Debugf("Rejecting aggressive connection from %v", remoteAddr.String())
+				netconn.Close()
+				return
 			}
-		}(netconn)
+		}
+
+		ipl.coolOffPool.Add(remoteAddr.IP.String(), time.Now().Add(3*time.Second))
 	}
+
+	go func(netconn net.Conn) {
+		peer, err := ipl.discMgr.connectWithInboundPeer(netconn, true)
+		if err != nil {
+			ipl.coolOffPool.Add(remoteAddr.IP.String(), time.Now().Add(1*time.Minute))
+
+			logger.Debugf("Backing off from invalid handshake %v", remoteAddr.String())
+
+			netconn.Close()
+		}
+		if ipl.inboundCallback != nil {
+			ipl.inboundCallback(peer, err)
+		}
+	}(netconn)
 }
 
 func (ipl *InboundPeerListener) purgeAllNonSeedPeers() {
@@ -29,6 +29,9 @@ type PeerDiscoveryManager struct {
 
 	seedPeerOnly bool
 
+	quicEnabled bool
+	quicSupport map[string]bool // whether the peer at the address can be dialed over QUIC
+
 	// Three mechanisms for peer discovery
 	seedPeerConnector   SeedPeerConnector           // pro-actively connect to seed peers
 	peerDiscMsgHandler  PeerDiscoveryMessageHandler // pro-actively connect to peer candidates obtained from connected peers
@@ -63,6 +66,8 @@ func CreatePeerDiscoveryManager(msgr *Messenger, nodeInfo *p2ptypes.NodeInfo, ad
 		seedPeers:    make(map[string]*pr.Peer),
 		mutex:        &sync.Mutex{},
 		seedPeerOnly: viper.GetBool(common.CfgP2PSeedPeerOnly),
+		quicEnabled:  viper.GetBool(common.CfgP2PQUICEnabled),
+		quicSupport:  make(map[string]bool),
 		wg:           &sync.WaitGroup{},
 	}
 
@@ -204,6 +209,10 @@ func (discMgr *PeerDiscoveryManager) HandlePeerWithErrors(peer *pr.Peer) {
 func (discMgr *PeerDiscoveryManager) connectToOutboundPeer(peerNetAddress *netutil.NetAddress, persistent bool) (*pr.Peer, error) {
 	logger.Debugf("Connecting to outbound peer: %v...", peerNetAddress)
 	peerConfig := pr.GetDefaultPeerConfig()
+	if discMgr.messenger != nil {
+		peerConfig.Authorize = discMgr.messenger.authorizePeer
+	}
+	peerConfig.DialQUIC = discMgr.shouldDialQUIC(peerNetAddress)
 	connConfig := cn.GetDefaultConnectionConfig()
 	peer, err := pr.CreateOutboundPeer(peerNetAddress, peerConfig, connConfig)
 	if err != nil {
@@ -212,12 +221,18 @@ func (discMgr *PeerDiscoveryManager) connectToOutboundPeer(peerNetAddress *netut
 	}
 	peer.SetPersistency(persistent)
 	err = discMgr.handshakeAndAddPeer(peer)
+	if err == nil {
+		discMgr.recordQUICSupport(peer, peerConfig.DialQUIC)
+	}
 	return peer, err
 }
 
 func (discMgr *PeerDiscoveryManager) connectWithInboundPeer(netconn net.Conn, persistent bool) (*pr.Peer, error) {
 	logger.Infof("Connecting with inbound peer: %v...", netconn.RemoteAddr())
 	peerConfig := pr.GetDefaultPeerConfig()
+	if discMgr.messenger != nil {
+		peerConfig.Authorize = discMgr.messenger.authorizePeer
+	}
 	connConfig := cn.GetDefaultConnectionConfig()
 	peer, err := pr.CreateInboundPeer(netconn, peerConfig, connConfig)
 	if err != nil {
@@ -226,17 +241,31 @@ func (discMgr *PeerDiscoveryManager) connectWithInboundPeer(netconn net.Conn, pe
 	}
 	peer.SetPersistency(persistent)
 	err = discMgr.handshakeAndAddPeer(peer)
+	if err == nil {
+		discMgr.recordQUICSupport(peer, false)
+	}
 	return peer, err
 }
 
 // handshakeAndAddPeer performs handshake with a peer. Upon successful handshake,
 // it save the peer to the peer table
 func (discMgr *PeerDiscoveryManager) handshakeAndAddPeer(peer *pr.Peer) error {
-	if err := peer.Handshake(discMgr.nodeInfo); err != nil {
+	var localInfo *p2ptypes.HandshakeInfo
+	if discMgr.messenger != nil {
+		localInfo = discMgr.messenger.localHandshakeInfo()
+	}
+	if err := peer.Handshake(discMgr.nodeInfo, localInfo); err != nil {
 		logger.Warnf("Failed to handshake with peer, error: %v", err)
 		return err
 	}
 
+	if discMgr.messenger != nil && discMgr.messenger.isBanned(peer.ID()) {
+		peer.Stop()
+		errMsg := "Rejected banned peer " + peer.ID()
+		logger.Infof(errMsg)
+		return errors.New(errMsg)
+	}
+
 	isSeed := discMgr.seedPeerConnector.isASeedPeer(peer.NetAddress())
 	peer.SetSeed(isSeed)
 	if isSeed {
@@ -281,3 +310,33 @@ func (discMgr *PeerDiscoveryManager) isSeedPeer(pid string) bool {
 	_, isSeed := discMgr.seedPeers[pid]
 	return isSeed
 }
+
+// shouldDialQUIC returns whether to dial the peer at the given address over QUIC
+// first. The addresses not seen yet are tried over QUIC as well
+func (discMgr *PeerDiscoveryManager) shouldDialQUIC(addr *netutil.NetAddress) bool {
+	if !discMgr.quicEnabled {
+		return false
+	}
+
+	discMgr.mutex.Lock()
+	defer discMgr.mutex.Unlock()
+
+	supported, known := discMgr.quicSupport[addr.String()]
+	return supported || !known
+}
+
+// recordQUICSupport remembers whether the peer can be dialed over QUIC, which is
+// the case if it advertises QUIC in the handshake, unless dialing it over QUIC
+// just failed
+func (discMgr *PeerDiscoveryManager) recordQUICSupport(peer *pr.Peer, dialedQUIC bool) {
+	if !discMgr.quicEnabled || peer.NetAddress() == nil {
+		return
+	}
+	overQUIC := cn.IsQUIC(peer.GetConnection().GetNetconn())
+	advertised := peer.HandshakeInfo().HasCapability(p2ptypes.CapabilityQUIC)
+
+	discMgr.mutex.Lock()
+	defer discMgr.mutex.Unlock()
+
+	discMgr.quicSupport[peer.NetAddress().String()] = overQUIC || (advertised && !dialedQUIC)
+}
@@ -2,6 +2,7 @@ package messenger
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"sync"
 
@@ -16,6 +17,8 @@ import (
 	"github.com/scripttoken/script/crypto"
 	"github.com/scripttoken/script/p2p"
 	pr "github.com/scripttoken/script/p2p/peer"
+	"github.com/scripttoken/script/p2p/permission"
+	"github.com/scripttoken/script/p2p/reputation"
 	p2ptypes "github.com/scripttoken/script/p2p/types"
 )
 
@@ -31,8 +34,12 @@ type Messenger struct {
 	natMgr        *NATManager
 	msgHandlerMap map[common.ChannelIDEnum](p2p.MessageHandler)
 
-	peerTable pr.PeerTable
-	nodeInfo  p2ptypes.NodeInfo // information of our blockchain node
+	peerTable  pr.PeerTable
+	nodeInfo   p2ptypes.NodeInfo // information of our blockchain node
+	reputation *reputation.ReputationManager
+	allowlist  *permission.Allowlist
+
+	handshakeInfoProvider p2ptypes.HandshakeInfoProvider
 
 	config MessengerConfig
 
@@ -119,6 +126,75 @@ func (msgr *Messenger) SetNATManager(natMgr *NATManager) {
 	msgr.natMgr = natMgr
 }
 
+// SetReputationManager sets the ReputationManager for the Messenger, which is
+// then notified of the malformed messages and can disconnect the misbehaving peers
+func (msgr *Messenger) SetReputationManager(rm *reputation.ReputationManager) {
+	msgr.reputation = rm
+	rm.AddDisconnectHandler(msgr.disconnectPeer)
+}
+
+// SetAllowlist sets the Allowlist for the Messenger. In the permissioned mode, the nodes
+// not listed are rejected during the handshake, and disconnected when the list is reloaded.
+func (msgr *Messenger) SetAllowlist(al *permission.Allowlist) {
+	msgr.allowlist = al
+	al.AddReloadHandler(msgr.disconnectUnlistedPeers)
+}
+
+// SetHandshakeInfoProvider sets the provider of the HandshakeInfo sent to the peers
+func (msgr *Messenger) SetHandshakeInfoProvider(provider p2ptypes.HandshakeInfoProvider) {
+	msgr.handshakeInfoProvider = provider
+}
+
+// localHandshakeInfo returns the HandshakeInfo of the local node, nil if not available
+func (msgr *Messenger) localHandshakeInfo() *p2ptypes.HandshakeInfo {
+	if msgr.handshakeInfoProvider == nil {
+		return nil
+	}
+	return msgr.handshakeInfoProvider()
+}
+
+// isBanned indicates whether the given peer is banned
+func (msgr *Messenger) isBanned(peerID string) bool {
+	return msgr.reputation != nil && msgr.reputation.IsBanned(peerID)
+}
+
+// authorizePeer rejects the nodes not in the allowlist
+func (msgr *Messenger) authorizePeer(pubKey *crypto.PublicKey) error {
+	if msgr.allowlist == nil || msgr.allowlist.IsAllowedKey(pubKey) {
+		return nil
+	}
+	return fmt.Errorf("Rejected peer %v, not in the allowlist", pubKey.Address().Hex())
+}
+
+// disconnectUnlistedPeers disconnects the neighbors no longer in the allowlist
+func (msgr *Messenger) disconnectUnlistedPeers() {
+	for _, peer := range *msgr.peerTable.GetAllPeers(false) {
+		if !msgr.allowlist.IsAllowedAddress(common.HexToAddress(peer.ID())) {
+			msgr.disconnectPeer(peer.ID())
+		}
+	}
+}
+
+// NetworkStats returns the diagnostic information of the peers and their traffic
+func (msgr *Messenger) NetworkStats(skipEdgeNode bool) *p2ptypes.NetworkStats {
+	stats := &p2ptypes.NetworkStats{}
+	for _, peer := range *msgr.peerTable.GetAllPeers(skipEdgeNode) {
+		stats.AddPeer(peer.Stats())
+	}
+	return stats
+}
+
+// disconnectPeer disconnects the given peer if it is a neighbor
+func (msgr *Messenger) disconnectPeer(peerID string) {
+	peer := msgr.peerTable.GetPeer(peerID)
+	if peer == nil {
+		return
+	}
+	msgr.peerTable.DeletePeer(peerID)
+	peer.Stop()
+	logger.Infof("Disconnected peer %v", peerID)
+}
+
 // Start is called when the Messenger starts
 func (msgr *Messenger) Start(ctx context.Context) error {
 	c, cancel := context.WithCancel(ctx)
@@ -271,6 +347,16 @@ func (msgr *Messenger) RegisterMessageHandler(msgHandler p2p.MessageHandler) {
 	}
 }
 
+// PeerHandshakeInfo returns the versioned handshake info of the given peer, nil if the
+// peer is not connected or runs an older version
+func (msgr *Messenger) PeerHandshakeInfo(peerID string) *p2ptypes.HandshakeInfo {
+	peer := msgr.peerTable.GetPeer(peerID)
+	if peer == nil {
+		return nil
+	}
+	return peer.HandshakeInfo()
+}
+
 func (msgr *Messenger) IsSeedPeer(peerID string) bool {
 	peer := msgr.peerTable.GetPeer(peerID)
 	if peer == nil {
@@ -294,6 +380,9 @@ func (msgr *Messenger) AttachMessageHandlersToPeer(peer *pr.Peer) {
 			logger.Errorf("Failed to setup message parser for channelID %v", channelID)
 		}
 		message, err := msgHandler.ParseMessage(peerID, channelID, rawMessageBytes)
+		if err != nil && msgr.reputation != nil {
+			msgr.reputation.Report(peerID, reputation.InfractionMalformedMessage, err.Error())
+		}
 		return message, err
 	}
 	peer.GetConnection().SetMessageParser(messageParser)
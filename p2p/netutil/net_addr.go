@@ -21,31 +21,36 @@ type NetAddress struct {
 	str  string
 }
 
-// NewNetAddress returns a new NetAddress using the provided TCP
-// address. When testing, other net.Addr (except TCP) will result in
-// using 0.0.0.0:0. When normal run, other net.Addr (except TCP) will
-// panic.
+// NewNetAddress returns a new NetAddress using the provided TCP or UDP
+// address. When testing, other net.Addr (except TCP and UDP) will result
+// in using 0.0.0.0:0. When normal run, other net.Addr (except TCP and
+// UDP) will panic.
 func NewNetAddress(addr net.Addr) *NetAddress {
 	netAddr := NewNetAddressWithEnforcedPort(addr, -1)
 	return netAddr
 }
 
 func NewNetAddressWithEnforcedPort(addr net.Addr, enforcedPort int) *NetAddress {
-	tcpAddr, ok := addr.(*net.TCPAddr)
-	if !ok {
+	var ip net.IP
+	var addrPort int
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip, addrPort = a.IP, a.Port
+	case *net.UDPAddr: // QUIC, served on the port with the same number
+		ip, addrPort = a.IP, a.Port
+	default:
 		if flag.Lookup("test.v") == nil { // normal run
-			panic(fmt.Sprintf("Only TCPAddrs are supported. Got: %v", addr))
+			panic(fmt.Sprintf("Only TCPAddrs and UDPAddrs are supported. Got: %v", addr))
 		} else { // in testing
 			return NewNetAddressIPPort(net.IP("0.0.0.0"), 0)
 		}
 	}
-	ip := tcpAddr.IP
 
 	var port uint16
 	if enforcedPort >= 0 {
 		port = uint16(enforcedPort)
 	} else {
-		port = uint16(tcpAddr.Port)
+		port = uint16(addrPort)
 	}
 	return NewNetAddressIPPort(ip, port)
 }
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,6 +26,10 @@ var logger *log.Entry = log.WithFields(log.Fields{"prefix": "p2p"})
 
 const maxExtraHandshakeInfo = 4096
 
+// handshakeInfoPrefix marks the versioned HandshakeInfo among the extra handshake
+// strings, which the peers running an older version skip until "EOH"
+const handshakeInfoPrefix = "HSI:"
+
 //
 // Peer models a peer node in a network
 //
@@ -36,8 +41,9 @@ type Peer struct {
 	isSeed       bool
 	netAddress   *nu.NetAddress
 
-	nodeInfo p2ptypes.NodeInfo // information of the blockchain node of the peer
-	nodeType cmn.NodeType
+	nodeInfo      p2ptypes.NodeInfo // information of the blockchain node of the peer
+	nodeType      cmn.NodeType
+	handshakeInfo *p2ptypes.HandshakeInfo // nil if the peer runs an older version
 	config   PeerConfig
 
 	// Life cycle
@@ -54,6 +60,15 @@ type Peer struct {
 type PeerConfig struct {
 	HandshakeTimeout time.Duration
 	DialTimeout      time.Duration
+
+	// Authorize, if set, is called with the public key claimed by the remote node
+	// during the handshake, and the peer is rejected if it returns an error. The key
+	// is authenticated by the key exchange later in the handshake.
+	Authorize func(pubKey *crypto.PublicKey) error
+
+	// DialQUIC, if set, makes the outbound peers dial over QUIC first, and fall back
+	// to TCP if the QUIC dial fails
+	DialQUIC bool
 }
 
 // CreateOutboundPeer creates an instance of an outbound peer
@@ -113,9 +128,10 @@ func (peer *Peer) Stop() {
 	peer.connection.Stop()
 }
 
-// Handshake handles the initial signaling between two peers
+// Handshake handles the initial signaling between two peers. The localInfo is
+// sent along if not nil, and the peer is rejected if it is on another chain.
 // NOTE: need to call peer.Handshake() before peer.Start()
-func (peer *Peer) Handshake(sourceNodeInfo *p2ptypes.NodeInfo) error {
+func (peer *Peer) Handshake(sourceNodeInfo *p2ptypes.NodeInfo, localInfo *p2ptypes.HandshakeInfo) error {
 	remoteAddr := peer.connection.GetNetconn().RemoteAddr()
 	logger.Infof("Handshaking with %v...", remoteAddr)
 
@@ -148,6 +164,13 @@ func (peer *Peer) Handshake(sourceNodeInfo *p2ptypes.NodeInfo) error {
 		logger.Warnf("Error during handshake/recv: %v", err)
 		return err
 	}
+	if peer.config.Authorize != nil {
+		if err := peer.config.Authorize(targetNodePubKey); err != nil {
+			logger.Warnf("Error during handshake: %v", err)
+			netconn.Close()
+			return err
+		}
+	}
 	targetPeerNodeInfo.PubKey = targetNodePubKey
 	peer.nodeInfo = targetPeerNodeInfo
 
@@ -155,6 +178,7 @@ func (peer *Peer) Handshake(sourceNodeInfo *p2ptypes.NodeInfo) error {
 	localChainID := viper.GetString(cmn.CfgGenesisChainID)
 	selfNodeType := viper.GetInt(cmn.CfgNodeType)
 	var peerType int
+	var remoteInfo *p2ptypes.HandshakeInfo
 	cmn.Parallel(
 		func() {
 			sendError = rlp.Encode(peer.connection.GetBufNetconn(), localChainID)
@@ -165,6 +189,17 @@ func (peer *Peer) Handshake(sourceNodeInfo *p2ptypes.NodeInfo) error {
 			if sendError != nil {
 				return
 			}
+			if localInfo != nil {
+				var raw []byte
+				raw, sendError = rlp.EncodeToBytes(localInfo)
+				if sendError != nil {
+					return
+				}
+				sendError = rlp.Encode(peer.connection.GetBufNetconn(), handshakeInfoPrefix+string(raw))
+				if sendError != nil {
+					return
+				}
+			}
 			sendError = rlp.Encode(peer.connection.GetBufNetconn(), "EOH")
 		},
 		func() {
@@ -205,6 +240,14 @@ func (peer *Peer) Handshake(sourceNodeInfo *p2ptypes.NodeInfo) error {
 				if msg == "EOH" {
 					return
 				}
+				if strings.HasPrefix(msg, handshakeInfoPrefix) {
+					info := &p2ptypes.HandshakeInfo{}
+					if err := rlp.DecodeBytes([]byte(msg[len(handshakeInfoPrefix):]), info); err != nil {
+						logger.Warnf("Cannot parse the peer handshake info: %v", err)
+						continue
+					}
+					remoteInfo = info
+				}
 			}
 		},
 	)
@@ -218,6 +261,16 @@ func (peer *Peer) Handshake(sourceNodeInfo *p2ptypes.NodeInfo) error {
 	}
 
 	peer.nodeType = common.NodeType(peerType)
+	peer.handshakeInfo = remoteInfo
+	if remoteInfo != nil {
+		logger.Infof("Peer protocol version: %v, software version: %v, finalized height: %v, capabilities: %v",
+			remoteInfo.ProtocolVersion, remoteInfo.SoftwareVersion, remoteInfo.FinalizedHeight, remoteInfo.Capabilities)
+	}
+	if !localInfo.IsCompatible(remoteInfo) {
+		err := fmt.Errorf("Genesis mismatch: peer genesis: %v, local genesis: %v", remoteInfo.GenesisHash.Hex(), localInfo.GenesisHash.Hex())
+		logger.Warnf("Error during handshake: %v", err)
+		return err
+	}
 
 	remotePub, err := peer.connection.DoEncHandshake(
 		crypto.PrivKeyToECDSA(sourceNodeInfo.PrivKey), crypto.PubKeyToECDSA(targetNodePubKey))
@@ -263,6 +316,25 @@ func (peer *Peer) CanSend(channelID cmn.ChannelIDEnum) bool {
 	return canSend
 }
 
+// Stats returns the diagnostic information of the peer
+func (peer *Peer) Stats() p2ptypes.PeerStats {
+	connStats := peer.connection.Stats()
+	address := ""
+	if peer.netAddress != nil {
+		address = peer.netAddress.String()
+	}
+	stats := p2ptypes.NewPeerStats(peer.ID(), address, p2ptypes.StackP2P, connStats.CreatedAt)
+	stats.Transport = connStats.Transport
+	stats.IsOutbound = peer.isOutbound
+	stats.NodeType = peer.nodeType
+	stats.IsSeed = peer.isSeed
+	stats.LatencyMs = connStats.Latency.Milliseconds()
+	stats.SendRate = connStats.SendRate
+	stats.RecvRate = connStats.RecvRate
+	stats.SetChannels(connStats.Channels)
+	return stats
+}
+
 // GetConnection returns the connection object attached to the peer
 func (peer *Peer) GetConnection() *cn.Connection {
 	return peer.connection
@@ -288,6 +360,11 @@ func (peer *Peer) IsOutbound() bool {
 	return peer.isOutbound
 }
 
+// HandshakeInfo returns the versioned handshake info of the peer, nil if the peer runs an older version
+func (peer *Peer) HandshakeInfo() *p2ptypes.HandshakeInfo {
+	return peer.handshakeInfo
+}
+
 // NodeType returns the node type of the peer
 func (peer *Peer) NodeType() cmn.NodeType {
 	return peer.nodeType
@@ -327,6 +404,13 @@ func (peer *Peer) ID() string {
 }
 
 func dial(addr *nu.NetAddress, config PeerConfig) (net.Conn, error) {
+	if config.DialQUIC {
+		netconn, err := cn.DialQUIC(addr.String(), config.DialTimeout)
+		if err == nil {
+			return netconn, nil
+		}
+		logger.Debugf("Failed to dial %v over QUIC, falling back to TCP: %v", addr, err)
+	}
 	netconn, err := addr.DialTimeout(config.DialTimeout)
 	if err != nil {
 		return nil, err
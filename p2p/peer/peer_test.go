@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -42,7 +43,7 @@ func TestPeerHandshakeAndCommunication(t *testing.T) {
 		outboundPeer := newOutboundPeer("127.0.0.1:" + strconv.Itoa(port))
 		randPeerPrivKey, _, _ := crypto.GenerateKeyPair()
 		peerANodeInfo := p2ptypes.CreateLocalNodeInfo(randPeerPrivKey, uint16(port))
-		err := outboundPeer.Handshake(&peerANodeInfo) // send out PeerA's node info
+		err := outboundPeer.Handshake(&peerANodeInfo, nil) // send out PeerA's node info
 		assert.Nil(err)
 		assert.True(outboundPeer.IsOutbound())
 
@@ -77,7 +78,7 @@ func TestPeerHandshakeAndCommunication(t *testing.T) {
 	inboundPeer := newInboundPeer(netconn)
 	peerBPrivKey, _, _ := crypto.GenerateKeyPair()
 	peerBNodeInfo := p2ptypes.CreateLocalNodeInfo(peerBPrivKey, uint16(port))
-	err = inboundPeer.Handshake(&peerBNodeInfo) // send out PeerB's node info
+	err = inboundPeer.Handshake(&peerBNodeInfo, nil) // send out PeerB's node info
 	assert.Nil(err)
 	assert.False(inboundPeer.IsOutbound())
 
@@ -148,14 +149,130 @@ func TestPeerHandshakeAndCommunication(t *testing.T) {
 	}
 }
 
+func TestPeerHandshakeAndCommunicationOverQUIC(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	port := 38858
+	listener, err := cn.ListenQUIC("127.0.0.1:" + strconv.Itoa(port))
+	assert.Nil(err)
+	defer listener.Close()
+
+	// A large block on its own stream should not hold back the votes
+	blockMsg := strings.Repeat("block data ", 20000)
+	voteMsgs := []string{"vote 1", "vote 2", "vote 3"}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		peerConfig := GetDefaultPeerConfig()
+		peerConfig.DialQUIC = true
+		outboundPeer := newOutboundPeerWithConfig("127.0.0.1:"+strconv.Itoa(port), peerConfig)
+		assert.True(cn.IsQUIC(outboundPeer.GetConnection().GetNetconn()))
+
+		randPeerPrivKey, _, _ := crypto.GenerateKeyPair()
+		peerANodeInfo := p2ptypes.CreateLocalNodeInfo(randPeerPrivKey, uint16(port))
+		err := outboundPeer.Handshake(&peerANodeInfo, nil)
+		assert.Nil(err)
+
+		outboundPeer.Start(ctx)
+		defer outboundPeer.Stop()
+
+		assert.True(outboundPeer.Send(common.ChannelIDBlock, blockMsg))
+		for _, voteMsg := range voteMsgs {
+			assert.True(outboundPeer.Send(common.ChannelIDVote, voteMsg))
+		}
+		assert.Equal(p2ptypes.TransportQUIC, outboundPeer.Stats().Transport)
+		<-done
+	}()
+
+	netconn, err := listener.Accept()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to accept the QUIC connection: %v", err))
+	}
+
+	inboundPeer := newInboundPeer(netconn)
+	peerBPrivKey, _, _ := crypto.GenerateKeyPair()
+	peerBNodeInfo := p2ptypes.CreateLocalNodeInfo(peerBPrivKey, uint16(port))
+	err = inboundPeer.Handshake(&peerBNodeInfo, nil)
+	assert.Nil(err)
+
+	received := make(chan p2ptypes.Message, len(voteMsgs)+1)
+	inboundPeer.GetConnection().SetMessageParser(func(channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (p2ptypes.Message, error) {
+		var msg string
+		err := rlp.DecodeBytes(rawMessageBytes, &msg)
+		return p2ptypes.Message{ChannelID: channelID, Content: msg}, err
+	})
+	inboundPeer.GetConnection().SetReceiveHandler(func(message p2ptypes.Message) error {
+		received <- message
+		return nil
+	})
+
+	inboundPeer.Start(ctx)
+	defer inboundPeer.Stop()
+
+	votes := []string{}
+	blocks := []string{}
+	for i := 0; i < len(voteMsgs)+1; i++ {
+		select {
+		case message := <-received:
+			switch message.ChannelID {
+			case common.ChannelIDVote:
+				votes = append(votes, message.Content.(string))
+			case common.ChannelIDBlock:
+				blocks = append(blocks, message.Content.(string))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the messages")
+		}
+	}
+	assert.Equal(voteMsgs, votes) // in order within the channel
+	assert.Equal([]string{blockMsg}, blocks)
+	assert.Equal(p2ptypes.TransportQUIC, inboundPeer.Stats().Transport)
+}
+
+func TestPeerDialQUICFallsBackToTCP(t *testing.T) {
+	assert := assert.New(t)
+
+	port := 38859
+	go func() {
+		peerConfig := GetDefaultPeerConfig()
+		peerConfig.DialQUIC = true // no QUIC listener on the port
+		peerConfig.DialTimeout = 2 * time.Second
+		outboundPeer := newOutboundPeerWithConfig("127.0.0.1:"+strconv.Itoa(port), peerConfig)
+		defer outboundPeer.Stop()
+		assert.False(cn.IsQUIC(outboundPeer.GetConnection().GetNetconn()))
+
+		randPeerPrivKey, _, _ := crypto.GenerateKeyPair()
+		peerANodeInfo := p2ptypes.CreateLocalNodeInfo(randPeerPrivKey, uint16(port))
+		assert.Nil(outboundPeer.Handshake(&peerANodeInfo, nil))
+	}()
+
+	listener := p2ptypes.GetTestListener(port)
+	netconn, err := listener.Accept()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to listen to the netconn: %v", err))
+	}
+	defer netconn.Close()
+
+	inboundPeer := newInboundPeer(netconn)
+	peerBPrivKey, _, _ := crypto.GenerateKeyPair()
+	peerBNodeInfo := p2ptypes.CreateLocalNodeInfo(peerBPrivKey, uint16(port))
+	assert.Nil(inboundPeer.Handshake(&peerBNodeInfo, nil))
+	assert.Equal(p2ptypes.TransportTCP, inboundPeer.Stats().Transport)
+}
+
 // --------------- Test Utilities --------------- //
 
 func newOutboundPeer(ipAddr string) *Peer {
+	return newOutboundPeerWithConfig(ipAddr, GetDefaultPeerConfig())
+}
+
+func newOutboundPeerWithConfig(ipAddr string, peerConfig PeerConfig) *Peer {
 	netaddr, err := nu.NewNetAddressString(ipAddr)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to create net address: %v", err))
 	}
-	peerConfig := GetDefaultPeerConfig()
 	connConfig := cn.GetDefaultConnectionConfig()
 	outboundPeer, err := CreateOutboundPeer(netaddr, peerConfig, connConfig)
 	if err != nil {
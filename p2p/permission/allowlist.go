@@ -0,0 +1,179 @@
+package permission
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/crypto"
+)
+
+var logger *log.Entry = log.WithFields(log.Fields{"prefix": "permission"})
+
+//
+// AllowedPeer is an entry of the allowlist
+//
+type AllowedPeer struct {
+	PublicKey string `json:"public_key"`     // hex encoded public key of the node
+	Name      string `json:"name,omitempty"` // optional, for the operators
+	Address   string `json:"address"`        // node address derived from the public key, i.e. its peer ID
+}
+
+// Source loads the allowed peers, e.g. from a file or an on-chain registry
+type Source func() ([]AllowedPeer, error)
+
+// ReloadHandler is called after the allowlist is reloaded, so that the P2P stacks can
+// disconnect the peers no longer allowed
+type ReloadHandler func()
+
+//
+// Allowlist restricts the P2P connections to a set of nodes identified by their public
+// keys in the permissioned mode. Both P2P stacks authenticate the public key of the
+// remote node during the connection setup, and reject the nodes not listed. When the
+// permissioned mode is disabled, every node is allowed.
+//
+type Allowlist struct {
+	mu *sync.RWMutex
+
+	enabled  bool
+	source   Source
+	peers    map[common.Address]AllowedPeer
+	pubKeys  map[common.Address]*crypto.PublicKey
+	handlers []ReloadHandler
+}
+
+// NewAllowlist creates an Allowlist loaded from the given JSON file, which contains
+// an array of AllowedPeer. Call Reload to load it.
+func NewAllowlist(filePath string) *Allowlist {
+	return NewAllowlistWithSource(FileSource(filePath))
+}
+
+// NewAllowlistWithSource creates an Allowlist loaded from the given source
+func NewAllowlistWithSource(source Source) *Allowlist {
+	return &Allowlist{
+		mu:      &sync.RWMutex{},
+		enabled: viper.GetBool(common.CfgP2PPermissionedEnabled),
+		source:  source,
+		peers:   make(map[common.Address]AllowedPeer),
+		pubKeys: make(map[common.Address]*crypto.PublicKey),
+	}
+}
+
+// FileSource reads the allowed peers from a JSON file
+func FileSource(filePath string) Source {
+	return func() ([]AllowedPeer, error) {
+		raw, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		peers := []AllowedPeer{}
+		if err := json.Unmarshal(raw, &peers); err != nil {
+			return nil, fmt.Errorf("Failed to parse the allowlist %v: %v", filePath, err)
+		}
+		return peers, nil
+	}
+}
+
+// Enabled indicates whether the permissioned mode is enabled
+func (al *Allowlist) Enabled() bool {
+	return al.enabled
+}
+
+// AddReloadHandler registers a handler called after each reload
+func (al *Allowlist) AddReloadHandler(handler ReloadHandler) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	al.handlers = append(al.handlers, handler)
+}
+
+// Reload loads the allowlist from its source, and returns the number of allowed peers.
+// The current list is kept if the new one cannot be loaded.
+func (al *Allowlist) Reload() (int, error) {
+	entries, err := al.source()
+	if err != nil {
+		return 0, err
+	}
+
+	peers := make(map[common.Address]AllowedPeer)
+	pubKeys := make(map[common.Address]*crypto.PublicKey)
+	for _, entry := range entries {
+		pubKey, err := crypto.PublicKeyFromBytes(common.FromHex(strings.TrimSpace(entry.PublicKey)))
+		if err != nil {
+			return 0, fmt.Errorf("Invalid public key in the allowlist: %v, %v", entry.PublicKey, err)
+		}
+		address := pubKey.Address()
+		entry.Address = address.Hex()
+		peers[address] = entry
+		pubKeys[address] = pubKey
+	}
+
+	al.mu.Lock()
+	al.peers = peers
+	al.pubKeys = pubKeys
+	handlers := al.handlers
+	al.mu.Unlock()
+
+	logger.Infof("Loaded %v peers in the allowlist", len(peers))
+	for _, handler := range handlers {
+		handler()
+	}
+	return len(peers), nil
+}
+
+// IsAllowedKey indicates whether the node with the given public key can connect
+func (al *Allowlist) IsAllowedKey(pubKey *crypto.PublicKey) bool {
+	if !al.enabled {
+		return true
+	}
+	if pubKey == nil {
+		return false
+	}
+	return al.IsAllowedAddress(pubKey.Address())
+}
+
+// IsAllowedAddress indicates whether the node with the given address can connect
+func (al *Allowlist) IsAllowedAddress(address common.Address) bool {
+	if !al.enabled {
+		return true
+	}
+	al.mu.RLock()
+	defer al.mu.RUnlock()
+
+	_, ok := al.peers[address]
+	return ok
+}
+
+// PublicKeys returns the public keys of the allowed peers
+func (al *Allowlist) PublicKeys() []*crypto.PublicKey {
+	al.mu.RLock()
+	defer al.mu.RUnlock()
+
+	pubKeys := []*crypto.PublicKey{}
+	for _, pubKey := range al.pubKeys {
+		pubKeys = append(pubKeys, pubKey)
+	}
+	return pubKeys
+}
+
+// Peers returns the allowed peers, ordered by address
+func (al *Allowlist) Peers() []AllowedPeer {
+	al.mu.RLock()
+	defer al.mu.RUnlock()
+
+	peers := []AllowedPeer{}
+	for _, peer := range al.peers {
+		peers = append(peers, peer)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Address < peers[j].Address
+	})
+	return peers
+}
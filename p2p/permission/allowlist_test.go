@@ -0,0 +1,87 @@
+package permission
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/crypto"
+)
+
+func newTestAllowlist(filePath string) *Allowlist {
+	al := NewAllowlist(filePath)
+	al.enabled = true
+	return al
+}
+
+func writeAllowlist(t *testing.T, filePath string, keys ...*crypto.PublicKey) {
+	peers := []AllowedPeer{}
+	for _, key := range keys {
+		peers = append(peers, AllowedPeer{PublicKey: common.Bytes2Hex(key.ToBytes())})
+	}
+	raw, err := json.Marshal(peers)
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(filePath, raw, 0600))
+}
+
+func TestAllowlistReload(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "allowlist")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	filePath := path.Join(dir, "allowlist.json")
+
+	_, key1, _ := crypto.GenerateKeyPair()
+	_, key2, _ := crypto.GenerateKeyPair()
+	writeAllowlist(t, filePath, key1)
+
+	al := newTestAllowlist(filePath)
+	numReloads := 0
+	al.AddReloadHandler(func() {
+		numReloads++
+	})
+
+	// Nothing is allowed before the list is loaded
+	assert.False(al.IsAllowedKey(key1))
+
+	numPeers, err := al.Reload()
+	assert.Nil(err)
+	assert.Equal(1, numPeers)
+	assert.Equal(1, numReloads)
+	assert.True(al.IsAllowedKey(key1))
+	assert.True(al.IsAllowedAddress(key1.Address()))
+	assert.False(al.IsAllowedKey(key2))
+	assert.False(al.IsAllowedKey(nil))
+	assert.Equal(key1.Address().Hex(), al.Peers()[0].Address)
+
+	writeAllowlist(t, filePath, key2)
+	numPeers, err = al.Reload()
+	assert.Nil(err)
+	assert.Equal(1, numPeers)
+	assert.Equal(2, numReloads)
+	assert.False(al.IsAllowedKey(key1))
+	assert.True(al.IsAllowedKey(key2))
+
+	// The current list is kept if the new one is invalid
+	assert.Nil(ioutil.WriteFile(filePath, []byte(`[{"public_key": "0x1234"}]`), 0600))
+	_, err = al.Reload()
+	assert.NotNil(err)
+	assert.Equal(2, numReloads)
+	assert.True(al.IsAllowedKey(key2))
+	assert.Equal(1, len(al.PublicKeys()))
+}
+
+func TestAllowlistDisabled(t *testing.T) {
+	assert := assert.New(t)
+
+	_, key, _ := crypto.GenerateKeyPair()
+	al := NewAllowlist("")
+	assert.False(al.Enabled())
+	assert.True(al.IsAllowedKey(key))
+	assert.True(al.IsAllowedKey(nil))
+}
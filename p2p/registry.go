@@ -0,0 +1,289 @@
+package p2p
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/p2p/types"
+)
+
+type namedNetwork struct {
+	name    string
+	network Network
+}
+
+//
+// Registry composes multiple transports into a single Network. By default a message is
+// sent over all the transports, Route restricts a channel to a subset of them. The peer
+// queries are answered by the primary transport, i.e. the first one registered.
+//
+type Registry struct {
+	mu         *sync.RWMutex
+	transports []namedNetwork
+	routes     map[common.ChannelIDEnum][]string
+	handlers   []MessageHandler
+}
+
+var _ Network = (*Registry)(nil)
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{
+		mu:     &sync.RWMutex{},
+		routes: make(map[common.ChannelIDEnum][]string),
+	}
+}
+
+// Register adds a transport under the given name, e.g. types.StackP2P. A nil transport is ignored, so that the
+// disabled stacks can be passed as is. The message handlers registered so far are
+// registered with the transport as well.
+func (r *Registry) Register(name string, network Network) {
+	if network == nil || reflect.ValueOf(network).IsNil() {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, t := range r.transports {
+		if t.name == name {
+			r.transports[i].network = network
+			r.registerHandlers(network)
+			return
+		}
+	}
+	r.transports = append(r.transports, namedNetwork{name: name, network: network})
+	r.registerHandlers(network)
+}
+
+func (r *Registry) registerHandlers(network Network) {
+	for _, handler := range r.handlers {
+		network.RegisterMessageHandler(handler)
+	}
+}
+
+// Route restricts the messages of the given channel to the named transports. Without
+// names, the channel is sent over all the transports again.
+func (r *Registry) Route(channelID common.ChannelIDEnum, names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(names) == 0 {
+		delete(r.routes, channelID)
+		return
+	}
+	r.routes[channelID] = names
+}
+
+// Transport returns the transport registered under the given name, nil if none
+func (r *Registry) Transport(name string) Network {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, t := range r.transports {
+		if t.name == name {
+			return t.network
+		}
+	}
+	return nil
+}
+
+// Transports returns the names of the registered transports, the primary one first
+func (r *Registry) Transports() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, len(r.transports))
+	for i, t := range r.transports {
+		names[i] = t.name
+	}
+	return names
+}
+
+func (r *Registry) all() []Network {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	networks := make([]Network, len(r.transports))
+	for i, t := range r.transports {
+		networks[i] = t.network
+	}
+	return networks
+}
+
+func (r *Registry) primary() Network {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.transports) == 0 {
+		return nil
+	}
+	return r.transports[0].network
+}
+
+// routed returns the transports the messages of the given channel are sent over
+func (r *Registry) routed(channelID common.ChannelIDEnum) []Network {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names, ok := r.routes[channelID]
+	if !ok {
+		networks := make([]Network, len(r.transports))
+		for i, t := range r.transports {
+			networks[i] = t.network
+		}
+		return networks
+	}
+	networks := []Network{}
+	for _, t := range r.transports {
+		for _, name := range names {
+			if t.name == name {
+				networks = append(networks, t.network)
+				break
+			}
+		}
+	}
+	return networks
+}
+
+// Start implements the Network interface, it starts all the transports.
+func (r *Registry) Start(ctx context.Context) error {
+	for _, network := range r.all() {
+		if err := network.Start(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Wait implements the Network interface.
+func (r *Registry) Wait() {
+	for _, network := range r.all() {
+		network.Wait()
+	}
+}
+
+// Stop implements the Network interface.
+func (r *Registry) Stop() {
+	for _, network := range r.all() {
+		network.Stop()
+	}
+}
+
+// Broadcast implements the Network interface. It returns the result channel of the first
+// transport the message is sent over.
+func (r *Registry) Broadcast(message types.Message, skipEdgeNode bool) chan bool {
+	var successes chan bool
+	for _, network := range r.routed(message.ChannelID) {
+		ch := network.Broadcast(message, skipEdgeNode)
+		if successes == nil {
+			successes = ch
+		}
+	}
+	if successes == nil {
+		successes = make(chan bool)
+	}
+	return successes
+}
+
+// BroadcastToNeighbors implements the Network interface. It returns the result channel of
+// the first transport the message is sent over.
+func (r *Registry) BroadcastToNeighbors(message types.Message, maxNumPeersToBroadcast int, skipEdgeNode bool) chan bool {
+	var successes chan bool
+	for _, network := range r.routed(message.ChannelID) {
+		ch := network.BroadcastToNeighbors(message, maxNumPeersToBroadcast, skipEdgeNode)
+		if successes == nil {
+			successes = ch
+		}
+	}
+	if successes == nil {
+		successes = make(chan bool)
+	}
+	return successes
+}
+
+// Send implements the Network interface. It succeeds if any of the transports delivered
+// the message.
+func (r *Registry) Send(peerID string, message types.Message) bool {
+	success := false
+	for _, network := range r.routed(message.ChannelID) {
+		if network.Send(peerID, message) {
+			success = true
+		}
+	}
+	return success
+}
+
+// Peers implements the Network interface.
+func (r *Registry) Peers(skipEdgeNode bool) []string {
+	if network := r.primary(); network != nil {
+		return network.Peers(skipEdgeNode)
+	}
+	return []string{}
+}
+
+// PeerURLs implements the Network interface.
+func (r *Registry) PeerURLs(skipEdgeNode bool) []string {
+	if network := r.primary(); network != nil {
+		return network.PeerURLs(skipEdgeNode)
+	}
+	return []string{}
+}
+
+// PeerExists implements the Network interface.
+func (r *Registry) PeerExists(peerID string) bool {
+	if network := r.primary(); network != nil {
+		return network.PeerExists(peerID)
+	}
+	return false
+}
+
+// PeerHandshakeInfo implements the Network interface. It returns the handshake info of the
+// first transport the peer completed a versioned handshake over.
+func (r *Registry) PeerHandshakeInfo(peerID string) *types.HandshakeInfo {
+	for _, network := range r.all() {
+		if info := network.PeerHandshakeInfo(peerID); info != nil {
+			return info
+		}
+	}
+	return nil
+}
+
+// NetworkStats implements the Network interface, merging the stats of all the transports.
+func (r *Registry) NetworkStats(skipEdgeNode bool) *types.NetworkStats {
+	stats := &types.NetworkStats{}
+	for _, network := range r.all() {
+		stats.Merge(network.NetworkStats(skipEdgeNode))
+	}
+	return stats
+}
+
+// RegisterMessageHandler implements the Network interface. The handler is registered with
+// all the transports, including the ones registered later.
+func (r *Registry) RegisterMessageHandler(messageHandler MessageHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers = append(r.handlers, messageHandler)
+	for _, t := range r.transports {
+		t.network.RegisterMessageHandler(messageHandler)
+	}
+}
+
+// IsSeedPeer implements the Network interface.
+func (r *Registry) IsSeedPeer(peerID string) bool {
+	if network := r.primary(); network != nil {
+		return network.IsSeedPeer(peerID)
+	}
+	return false
+}
+
+// ID implements the Network interface.
+func (r *Registry) ID() string {
+	if network := r.primary(); network != nil {
+		return network.ID()
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package p2p
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/p2p/types"
+)
+
+type mockNetwork struct {
+	id       string
+	peers    []string
+	handlers []MessageHandler
+	sent     []types.Message
+	info     *types.HandshakeInfo
+}
+
+func (mn *mockNetwork) Start(ctx context.Context) error { return nil }
+func (mn *mockNetwork) Wait()                           {}
+func (mn *mockNetwork) Stop()                           {}
+func (mn *mockNetwork) Broadcast(message types.Message, skipEdgeNode bool) chan bool {
+	mn.sent = append(mn.sent, message)
+	return make(chan bool)
+}
+func (mn *mockNetwork) BroadcastToNeighbors(message types.Message, maxNumPeersToBroadcast int, skipEdgeNode bool) chan bool {
+	return mn.Broadcast(message, skipEdgeNode)
+}
+func (mn *mockNetwork) Send(peerID string, message types.Message) bool {
+	mn.sent = append(mn.sent, message)
+	return mn.PeerExists(peerID)
+}
+func (mn *mockNetwork) Peers(skipEdgeNode bool) []string    { return mn.peers }
+func (mn *mockNetwork) PeerURLs(skipEdgeNode bool) []string { return mn.peers }
+func (mn *mockNetwork) PeerExists(peerID string) bool {
+	for _, id := range mn.peers {
+		if id == peerID {
+			return true
+		}
+	}
+	return false
+}
+func (mn *mockNetwork) PeerHandshakeInfo(peerID string) *types.HandshakeInfo { return mn.info }
+func (mn *mockNetwork) NetworkStats(skipEdgeNode bool) *types.NetworkStats {
+	stats := &types.NetworkStats{}
+	for _, id := range mn.peers {
+		stats.AddPeer(types.PeerStats{ID: id})
+	}
+	return stats
+}
+func (mn *mockNetwork) RegisterMessageHandler(messageHandler MessageHandler) {
+	mn.handlers = append(mn.handlers, messageHandler)
+}
+func (mn *mockNetwork) IsSeedPeer(peerID string) bool { return false }
+func (mn *mockNetwork) ID() string                    { return mn.id }
+
+func TestRegistryRouting(t *testing.T) {
+	assert := assert.New(t)
+
+	net1 := &mockNetwork{id: "a", peers: []string{"peer1"}}
+	net2 := &mockNetwork{id: "b", peers: []string{"peer2"}, info: &types.HandshakeInfo{}}
+	var disabled *mockNetwork
+
+	registry := NewRegistry()
+	registry.RegisterMessageHandler(nil)
+	registry.Register(types.StackP2P, net1)
+	registry.Register(types.StackP2PL, disabled)
+	registry.Register(types.StackLocal, net2)
+
+	assert.Equal([]string{types.StackP2P, types.StackLocal}, registry.Transports())
+	assert.Nil(registry.Transport(types.StackP2PL))
+	assert.Equal(1, len(net1.handlers))
+	assert.Equal(1, len(net2.handlers))
+
+	// The primary transport answers the peer queries
+	assert.Equal("a", registry.ID())
+	assert.Equal([]string{"peer1"}, registry.Peers(false))
+	assert.NotNil(registry.PeerHandshakeInfo("peer2"))
+	assert.Equal(2, len(registry.NetworkStats(false).Peers))
+
+	// The messages are sent over all the transports unless routed
+	assert.True(registry.Send("peer2", types.Message{ChannelID: common.ChannelIDBlock}))
+	assert.Equal(1, len(net1.sent))
+	assert.Equal(1, len(net2.sent))
+
+	registry.Route(common.ChannelIDBlock, types.StackP2P)
+	assert.False(registry.Send("peer2", types.Message{ChannelID: common.ChannelIDBlock}))
+	registry.Broadcast(types.Message{ChannelID: common.ChannelIDBlock}, false)
+	assert.Equal(3, len(net1.sent))
+	assert.Equal(1, len(net2.sent))
+
+	registry.Route(common.ChannelIDBlock)
+	registry.Broadcast(types.Message{ChannelID: common.ChannelIDBlock}, false)
+	assert.Equal(4, len(net1.sent))
+	assert.Equal(2, len(net2.sent))
+}
@@ -0,0 +1,382 @@
+package reputation
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/scripttoken/script/common"
+)
+
+var logger *log.Entry = log.WithFields(log.Fields{"prefix": "reputation"})
+
+//
+// Infraction is a kind of peer misbehavior reported by the message handlers
+//
+type Infraction byte
+
+const (
+	// InfractionMalformedMessage: the message cannot be parsed or decoded
+	InfractionMalformedMessage Infraction = iota
+	// InfractionInvalidBlock: the block or proposal fails the validation
+	InfractionInvalidBlock
+	// InfractionInvalidVote: the vote signature or content is invalid
+	InfractionInvalidVote
+	// InfractionFlooding: the peer sends messages faster than the rate limit
+	InfractionFlooding
+)
+
+// penalties are the misbehavior scores added for each kind of infraction
+var penalties = map[Infraction]float64{
+	InfractionMalformedMessage: 25,
+	InfractionInvalidBlock:     100,
+	InfractionInvalidVote:      20,
+	InfractionFlooding:         10,
+}
+
+func (infraction Infraction) String() string {
+	switch infraction {
+	case InfractionMalformedMessage:
+		return "malformed message"
+	case InfractionInvalidBlock:
+		return "invalid block"
+	case InfractionInvalidVote:
+		return "invalid vote"
+	case InfractionFlooding:
+		return "flooding"
+	default:
+		return "unknown"
+	}
+}
+
+// Penalty returns the misbehavior score added for the infraction
+func (infraction Infraction) Penalty() float64 {
+	return penalties[infraction]
+}
+
+//
+// BanInfo describes a banned peer
+//
+type BanInfo struct {
+	PeerID string    `json:"peer_id"`
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+}
+
+// DisconnectHandler disconnects the given peer
+type DisconnectHandler func(peerID string)
+
+type peerScore struct {
+	score       float64
+	updated     time.Time
+	windowStart time.Time
+	numTxs      uint64
+}
+
+//
+// ReputationManager keeps track of the misbehavior of the peers across both P2P
+// stacks. Each reported infraction adds a weighted penalty to the peer's score,
+// which decays exponentially over time. A peer is disconnected once its score
+// reaches the disconnect threshold, and banned for a period of time once it
+// reaches the ban threshold. The bans are persisted, so they survive restarts.
+//
+type ReputationManager struct {
+	mu *sync.Mutex
+
+	enabled             bool
+	disconnectThreshold float64
+	banThreshold        float64
+	banDuration         time.Duration
+	halfLife            time.Duration
+	txRateLimit         uint64
+
+	scores             map[string]*peerScore
+	bans               map[string]*BanInfo
+	banFilePath        string
+	disconnectHandlers []DisconnectHandler
+
+	now func() time.Time
+}
+
+// NewReputationManager creates an instance of the ReputationManager. The bans
+// are persisted in the given file, nothing is persisted if the path is empty.
+func NewReputationManager(banFilePath string) *ReputationManager {
+	rm := &ReputationManager{
+		mu:                  &sync.Mutex{},
+		enabled:             viper.GetBool(common.CfgP2PReputationEnabled),
+		disconnectThreshold: viper.GetFloat64(common.CfgP2PReputationDisconnectThreshold),
+		banThreshold:        viper.GetFloat64(common.CfgP2PReputationBanThreshold),
+		banDuration:         time.Duration(viper.GetInt64(common.CfgP2PReputationBanDuration)) * time.Second,
+		halfLife:            time.Duration(viper.GetInt64(common.CfgP2PReputationHalfLife)) * time.Second,
+		txRateLimit:         viper.GetUint64(common.CfgP2PReputationTxRateLimit),
+		scores:              make(map[string]*peerScore),
+		bans:                make(map[string]*BanInfo),
+		banFilePath:         banFilePath,
+		now:                 time.Now,
+	}
+	if err := rm.loadBans(); err != nil {
+		logger.Warnf("Failed to load the peer bans from %v: %v", banFilePath, err)
+	}
+	return rm
+}
+
+// AddDisconnectHandler registers a handler called to disconnect misbehaving peers. Each
+// P2P stack registers its own handler, which should ignore the peers it doesn't know.
+func (rm *ReputationManager) AddDisconnectHandler(handler DisconnectHandler) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.disconnectHandlers = append(rm.disconnectHandlers, handler)
+}
+
+// Report records an infraction of the given peer, and disconnects or bans the
+// peer if its score reaches the thresholds
+func (rm *ReputationManager) Report(peerID string, infraction Infraction, reason string) {
+	if !rm.enabled || peerID == "" {
+		return
+	}
+
+	rm.mu.Lock()
+	now := rm.now()
+	ps := rm.getScore(peerID, now)
+	ps.score += infraction.Penalty()
+	score := ps.score
+
+	logger.WithFields(log.Fields{
+		"peer":       peerID,
+		"infraction": infraction.String(),
+		"reason":     reason,
+		"score":      score,
+	}).Info("Peer misbehaved")
+
+	disconnect := false
+	if score >= rm.banThreshold {
+		rm.ban(peerID, rm.banDuration, infraction.String()+": "+reason, now)
+		disconnect = true
+	} else if score >= rm.disconnectThreshold {
+		disconnect = true
+	}
+	handlers := rm.disconnectHandlers
+	rm.mu.Unlock()
+
+	if disconnect {
+		logger.Infof("Disconnecting misbehaving peer %v, score: %v", peerID, score)
+		rm.disconnect(handlers, peerID)
+	}
+}
+
+// AllowMessage applies the rate limit of the given channel to the messages received
+// from the peer. It returns false if the message should be dropped, in which case
+// the peer is penalized for flooding once per second.
+func (rm *ReputationManager) AllowMessage(peerID string, channelID common.ChannelIDEnum) bool {
+	if !rm.enabled || peerID == "" || channelID != common.ChannelIDTransaction || rm.txRateLimit == 0 {
+		return true
+	}
+
+	rm.mu.Lock()
+	now := rm.now()
+	ps := rm.getScore(peerID, now)
+	if now.Sub(ps.windowStart) >= time.Second {
+		ps.windowStart = now
+		ps.numTxs = 0
+	}
+	ps.numTxs++
+	numTxs := ps.numTxs
+	rm.mu.Unlock()
+
+	if numTxs <= rm.txRateLimit {
+		return true
+	}
+	if numTxs == rm.txRateLimit+1 {
+		rm.Report(peerID, InfractionFlooding, "transaction rate limit exceeded")
+	}
+	return false
+}
+
+// Score returns the current misbehavior score of the given peer
+func (rm *ReputationManager) Score(peerID string) float64 {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	ps, ok := rm.scores[peerID]
+	if !ok {
+		return 0
+	}
+	rm.decay(ps, rm.now())
+	return ps.score
+}
+
+// IsBanned indicates whether the given peer is currently banned
+func (rm *ReputationManager) IsBanned(peerID string) bool {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	ban, ok := rm.bans[peerID]
+	if !ok {
+		return false
+	}
+	if !rm.now().Before(ban.Until) {
+		delete(rm.bans, peerID)
+		rm.saveBans()
+		return false
+	}
+	return true
+}
+
+// Ban bans the given peer for the given duration, and disconnects it
+func (rm *ReputationManager) Ban(peerID string, duration time.Duration, reason string) {
+	rm.mu.Lock()
+	rm.ban(peerID, duration, reason, rm.now())
+	handlers := rm.disconnectHandlers
+	rm.mu.Unlock()
+
+	rm.disconnect(handlers, peerID)
+}
+
+// Unban lifts the ban of the given peer, and resets its score. It returns
+// false if the peer is not banned.
+func (rm *ReputationManager) Unban(peerID string) bool {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	delete(rm.scores, peerID)
+	if _, ok := rm.bans[peerID]; !ok {
+		return false
+	}
+	delete(rm.bans, peerID)
+	rm.saveBans()
+	logger.Infof("Unbanned peer %v", peerID)
+	return true
+}
+
+// ClearBans lifts all the bans, and returns the number of peers unbanned
+func (rm *ReputationManager) ClearBans() int {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	count := len(rm.bans)
+	for peerID := range rm.bans {
+		delete(rm.scores, peerID)
+	}
+	rm.bans = make(map[string]*BanInfo)
+	rm.saveBans()
+	logger.Infof("Cleared %v peer bans", count)
+	return count
+}
+
+// Bans returns the active bans, ordered by expiry
+func (rm *ReputationManager) Bans() []BanInfo {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	now := rm.now()
+	bans := []BanInfo{}
+	for peerID, ban := range rm.bans {
+		if !now.Before(ban.Until) {
+			delete(rm.bans, peerID)
+			continue
+		}
+		bans = append(bans, *ban)
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].Until.Before(bans[j].Until)
+	})
+	return bans
+}
+
+func (rm *ReputationManager) getScore(peerID string, now time.Time) *peerScore {
+	ps, ok := rm.scores[peerID]
+	if !ok {
+		ps = &peerScore{updated: now}
+		rm.scores[peerID] = ps
+		return ps
+	}
+	rm.decay(ps, now)
+	return ps
+}
+
+// decay halves the score every halfLife
+func (rm *ReputationManager) decay(ps *peerScore, now time.Time) {
+	elapsed := now.Sub(ps.updated)
+	if elapsed <= 0 {
+		return
+	}
+	if rm.halfLife > 0 {
+		ps.score *= math.Pow(0.5, float64(elapsed)/float64(rm.halfLife))
+	}
+	ps.updated = now
+}
+
+func (rm *ReputationManager) ban(peerID string, duration time.Duration, reason string, now time.Time) {
+	rm.bans[peerID] = &BanInfo{
+		PeerID: peerID,
+		Reason: reason,
+		Since:  now,
+		Until:  now.Add(duration),
+	}
+	delete(rm.scores, peerID)
+	rm.saveBans()
+	logger.Warnf("Banned peer %v until %v, reason: %v", peerID, now.Add(duration), reason)
+}
+
+func (rm *ReputationManager) disconnect(handlers []DisconnectHandler, peerID string) {
+	for _, handler := range handlers {
+		go handler(peerID)
+	}
+}
+
+func (rm *ReputationManager) loadBans() error {
+	if rm.banFilePath == "" {
+		return nil
+	}
+	raw, err := ioutil.ReadFile(rm.banFilePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	bans := []BanInfo{}
+	if err := json.Unmarshal(raw, &bans); err != nil {
+		return err
+	}
+	now := rm.now()
+	for i := range bans {
+		if now.Before(bans[i].Until) {
+			rm.bans[bans[i].PeerID] = &bans[i]
+		}
+	}
+	return nil
+}
+
+// saveBans persists the bans, the caller must hold the lock
+func (rm *ReputationManager) saveBans() {
+	if rm.banFilePath == "" {
+		return
+	}
+	bans := []BanInfo{}
+	for _, ban := range rm.bans {
+		bans = append(bans, *ban)
+	}
+	raw, err := json.MarshalIndent(bans, "", "  ")
+	if err != nil {
+		logger.Errorf("Failed to encode the peer bans: %v", err)
+		return
+	}
+	tmpPath := rm.banFilePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, raw, 0644); err != nil {
+		logger.Errorf("Failed to save the peer bans: %v", err)
+		return
+	}
+	if err := os.Rename(tmpPath, rm.banFilePath); err != nil {
+		logger.Errorf("Failed to save the peer bans: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package reputation
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/scripttoken/script/common"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newTestReputationManager(banFilePath string, clock *testClock) *ReputationManager {
+	rm := NewReputationManager(banFilePath)
+	rm.enabled = true
+	rm.now = clock.Now
+	return rm
+}
+
+func TestReputationDisconnectAndBan(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	rm := newTestReputationManager("", clock)
+
+	wg := &sync.WaitGroup{}
+	disconnected := []string{}
+	mu := &sync.Mutex{}
+	rm.AddDisconnectHandler(func(peerID string) {
+		mu.Lock()
+		defer mu.Unlock()
+		disconnected = append(disconnected, peerID)
+		wg.Done()
+	})
+
+	rm.Report("peer1", InfractionInvalidVote, "bad signature")
+	assert.Equal(InfractionInvalidVote.Penalty(), rm.Score("peer1"))
+	assert.False(rm.IsBanned("peer1"))
+
+	// Reaching the disconnect threshold
+	wg.Add(1)
+	rm.Report("peer1", InfractionMalformedMessage, "rlp")
+	rm.Report("peer1", InfractionInvalidVote, "bad signature")
+	wg.Wait()
+	assert.Equal([]string{"peer1"}, disconnected)
+	assert.False(rm.IsBanned("peer1"))
+
+	// The score decays by half every half life
+	clock.now = clock.now.Add(rm.halfLife)
+	assert.InDelta(32.5, rm.Score("peer1"), 0.001)
+
+	// Reaching the ban threshold
+	wg.Add(1)
+	rm.Report("peer2", InfractionInvalidBlock, "invalid signature")
+	wg.Wait()
+	assert.True(rm.IsBanned("peer2"))
+	assert.Equal(1, len(rm.Bans()))
+
+	// The ban expires
+	clock.now = clock.now.Add(rm.banDuration)
+	assert.False(rm.IsBanned("peer2"))
+	assert.Equal(0, len(rm.Bans()))
+}
+
+func TestReputationBansPersisted(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "reputation")
+	assert.Nil(err)
+	defer os.RemoveAll(tmpdir)
+	banFilePath := path.Join(tmpdir, "peer_bans.json")
+
+	clock := &testClock{now: time.Now()}
+	rm := newTestReputationManager(banFilePath, clock)
+	rm.Ban("peer1", time.Hour, "test")
+	rm.Ban("peer2", 2*time.Hour, "test")
+	rm.Ban("peer3", 3*time.Hour, "test")
+	assert.True(rm.Unban("peer3"))
+	assert.False(rm.Unban("peer3"))
+
+	rm = newTestReputationManager(banFilePath, clock)
+	bans := rm.Bans()
+	assert.Equal(2, len(bans))
+	assert.Equal("peer1", bans[0].PeerID)
+	assert.Equal("peer2", bans[1].PeerID)
+
+	assert.Equal(2, rm.ClearBans())
+	rm = newTestReputationManager(banFilePath, clock)
+	assert.Equal(0, len(rm.Bans()))
+}
+
+func TestReputationTxRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	rm := newTestReputationManager("", clock)
+	rm.txRateLimit = 10
+
+	for i := 0; i < 10; i++ {
+		assert.True(rm.AllowMessage("peer1", common.ChannelIDTransaction))
+	}
+	assert.False(rm.AllowMessage("peer1", common.ChannelIDTransaction))
+	assert.False(rm.AllowMessage("peer1", common.ChannelIDTransaction))
+	assert.True(rm.AllowMessage("peer1", common.ChannelIDBlock))
+	assert.Equal(InfractionFlooding.Penalty(), rm.Score("peer1"))
+
+	clock.now = clock.now.Add(time.Second)
+	assert.True(rm.AllowMessage("peer1", common.ChannelIDTransaction))
+}
@@ -2,22 +2,33 @@ package simulation
 
 import (
 	"context"
+	"math/rand"
 	"sync"
 	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/common/clock"
 	"github.com/scripttoken/script/p2p"
 	p2ptypes "github.com/scripttoken/script/p2p/types"
 )
 
+var logger *log.Entry = log.WithFields(log.Fields{"prefix": "simnet"})
+
 // Envelope wraps a message with network information for delivery.
 type Envelope struct {
-	From    string
-	To      string
-	Content interface{}
+	From      string
+	To        string
+	ChannelID common.ChannelIDEnum
+	Content   interface{}
 }
 
+// Interceptor inspects a message about to be delivered to a single endpoint, and returns
+// the envelopes to deliver instead. Returning the envelope unchanged lets it through, an
+// empty list drops it. Interceptors are used to simulate the byzantine behaviors.
+type Interceptor func(envelope Envelope) []Envelope
+
 // Simnet represents an instance of simulated network.
 type Simnet struct {
 	Endpoints  []*SimnetEndpoint
@@ -25,6 +36,15 @@ type Simnet struct {
 	messages   chan Envelope
 	MsgLogs    []Envelope
 
+	// Fault injection, protected by mu
+	clock        clock.Clock
+	rand         *rand.Rand
+	minLatency   time.Duration
+	maxLatency   time.Duration
+	dropRate     float64
+	partitions   map[string]int
+	interceptors []Interceptor
+
 	// Life cycle.
 	wg      *sync.WaitGroup
 	mu      *sync.Mutex
@@ -36,21 +56,21 @@ type Simnet struct {
 // NewSimnet creates a new instance of Simnet.
 func NewSimnet() *Simnet {
 	return &Simnet{
-		messages: make(chan Envelope, viper.GetInt(common.CfgP2PMessageQueueSize)),
-		MsgLogs:  []Envelope{},
-		wg:       &sync.WaitGroup{},
-		mu:       &sync.Mutex{},
+		messages:   make(chan Envelope, viper.GetInt(common.CfgP2PMessageQueueSize)),
+		MsgLogs:    []Envelope{},
+		clock:      clock.NewRealClock(),
+		rand:       rand.New(rand.NewSource(0)),
+		partitions: make(map[string]int),
+		wg:         &sync.WaitGroup{},
+		mu:         &sync.Mutex{},
 	}
 }
 
 // NewSimnetWithHandler creates a new instance of Simnet with given MessageHandler as the default handler.
 func NewSimnetWithHandler(msgHandler p2p.MessageHandler) *Simnet {
-	return &Simnet{
-		msgHandler: msgHandler,
-		messages:   make(chan Envelope, viper.GetInt(common.CfgP2PMessageQueueSize)),
-		wg:         &sync.WaitGroup{},
-		mu:         &sync.Mutex{},
-	}
+	sn := NewSimnet()
+	sn.msgHandler = msgHandler
+	return sn
 }
 
 // AddEndpoint adds an endpoint with given ID to the Simnet instance.
@@ -60,11 +80,84 @@ func (sn *Simnet) AddEndpoint(id string) *SimnetEndpoint {
 		network:  sn,
 		incoming: make(chan Envelope, viper.GetInt(common.CfgP2PMessageQueueSize)),
 		outgoing: make(chan Envelope, viper.GetInt(common.CfgP2PMessageQueueSize)),
+		wg:       &sync.WaitGroup{},
+		mu:       &sync.Mutex{},
 	}
 	sn.Endpoints = append(sn.Endpoints, endpoint)
 	return endpoint
 }
 
+// SetClock sets the clock used to delay the messages. It should be called before Start.
+func (sn *Simnet) SetClock(clock clock.Clock) {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	sn.clock = clock
+}
+
+// SetSeed seeds the random source used for the latencies and the drops, so that the
+// same seed reproduces the same fault decisions.
+func (sn *Simnet) SetSeed(seed int64) {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	sn.rand = rand.New(rand.NewSource(seed))
+}
+
+// SetLatency delays each message by a random duration between min and max.
+func (sn *Simnet) SetLatency(min, max time.Duration) {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	if max < min {
+		max = min
+	}
+	sn.minLatency = min
+	sn.maxLatency = max
+}
+
+// SetDropRate drops the given fraction of the messages between different endpoints.
+func (sn *Simnet) SetDropRate(rate float64) {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	sn.dropRate = rate
+}
+
+// Partition splits the network into the given groups of endpoint IDs. The messages
+// between different groups are dropped. The endpoints not listed form their own group.
+func (sn *Simnet) Partition(groups ...[]string) {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	sn.partitions = make(map[string]int)
+	for i, group := range groups {
+		for _, id := range group {
+			sn.partitions[id] = i + 1
+		}
+	}
+}
+
+// Heal removes all the partitions.
+func (sn *Simnet) Heal() {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	sn.partitions = make(map[string]int)
+}
+
+// AddInterceptor registers an interceptor applied to every delivery.
+func (sn *Simnet) AddInterceptor(interceptor Interceptor) {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	sn.interceptors = append(sn.interceptors, interceptor)
+}
+
+// IsConnected indicates whether messages can flow between the two endpoints.
+func (sn *Simnet) IsConnected(from, to string) bool {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	return sn.isConnected(from, to)
+}
+
+func (sn *Simnet) isConnected(from, to string) bool {
+	return from == to || sn.partitions[from] == sn.partitions[to]
+}
+
 // Start is the main entry point for Simnet. It starts all endpoints and start a goroutine to handle message dlivery.
 func (sn *Simnet) Start(ctx context.Context) {
 	c, cancel := context.WithCancel(ctx)
@@ -72,9 +165,10 @@ func (sn *Simnet) Start(ctx context.Context) {
 	sn.cancel = cancel
 
 	for _, endpoint := range sn.Endpoints {
-		endpoint.Start(ctx)
+		endpoint.Start(c)
 	}
 
+	sn.wg.Add(1)
 	go sn.mainLoop()
 }
 
@@ -86,10 +180,12 @@ func (sn *Simnet) Stop() {
 // Wait blocks until all goroutines have stopped.
 func (sn *Simnet) Wait() {
 	sn.wg.Wait()
+	for _, endpoint := range sn.Endpoints {
+		endpoint.Wait()
+	}
 }
 
 func (sn *Simnet) mainLoop() {
-	sn.wg.Add(1)
 	defer sn.wg.Done()
 
 	for {
@@ -100,34 +196,104 @@ func (sn *Simnet) mainLoop() {
 			sn.mu.Unlock()
 			return
 		case envelope := <-sn.messages:
-			time.Sleep(1 * time.Microsecond)
 			for _, endpoint := range sn.Endpoints {
 				if (envelope.To == "" && envelope.From != endpoint.ID()) || envelope.To == endpoint.ID() {
-					go func(endpoint *SimnetEndpoint, envelope Envelope) {
-						// Simulate network delay except for messages to self.
-						if envelope.From != endpoint.ID() {
-							// time.Sleep(100 * time.Millisecond)
-						}
-						endpoint.incoming <- envelope
-
-					}(endpoint, envelope)
+					delivery := envelope
+					delivery.To = endpoint.ID()
+					sn.deliver(delivery)
 				}
 			}
 		}
 	}
 }
 
+// deliver applies the interceptors and the faults to a message for a single endpoint.
+func (sn *Simnet) deliver(envelope Envelope) {
+	sn.mu.Lock()
+	envelopes := []Envelope{envelope}
+	for _, interceptor := range sn.interceptors {
+		intercepted := []Envelope{}
+		for _, e := range envelopes {
+			intercepted = append(intercepted, interceptor(e)...)
+		}
+		envelopes = intercepted
+	}
+
+	type scheduled struct {
+		envelope Envelope
+		delay    time.Duration
+	}
+	deliveries := []scheduled{}
+	for _, e := range envelopes {
+		endpoint := sn.endpoint(e.To)
+		if endpoint == nil {
+			continue
+		}
+		if e.From == e.To {
+			deliveries = append(deliveries, scheduled{e, 0})
+			continue
+		}
+		if !sn.isConnected(e.From, e.To) {
+			continue
+		}
+		if sn.dropRate > 0 && sn.rand.Float64() < sn.dropRate {
+			continue
+		}
+		delay := sn.minLatency
+		if sn.maxLatency > sn.minLatency {
+			delay += time.Duration(sn.rand.Int63n(int64(sn.maxLatency - sn.minLatency)))
+		}
+		deliveries = append(deliveries, scheduled{e, delay})
+	}
+	clk := sn.clock
+	sn.mu.Unlock()
+
+	for _, d := range deliveries {
+		endpoint := sn.endpoint(d.envelope.To)
+		if d.delay == 0 {
+			go endpoint.receive(d.envelope)
+			continue
+		}
+		timer := clk.NewTimer(d.delay)
+		go func(endpoint *SimnetEndpoint, envelope Envelope) {
+			select {
+			case <-timer.C():
+				endpoint.receive(envelope)
+			case <-sn.ctx.Done():
+				timer.Stop()
+			}
+		}(endpoint, d.envelope)
+	}
+}
+
+func (sn *Simnet) endpoint(id string) *SimnetEndpoint {
+	for _, endpoint := range sn.Endpoints {
+		if endpoint.ID() == id {
+			return endpoint
+		}
+	}
+	return nil
+}
+
 // AddMessage send a message through the network.
 func (sn *Simnet) AddMessage(msg Envelope) {
 	sn.mu.Lock()
-	defer sn.mu.Unlock()
-
 	if sn.stopped {
+		sn.mu.Unlock()
 		return
 	}
-
 	sn.MsgLogs = append(sn.MsgLogs, msg)
-	sn.messages <- msg
+	sn.mu.Unlock()
+
+	// The lock is released before queueing, the main loop takes it to deliver
+	if sn.ctx == nil {
+		sn.messages <- msg
+		return
+	}
+	select {
+	case sn.messages <- msg:
+	case <-sn.ctx.Done():
+	}
 }
 
 // SimnetEndpoint is the implementation of Network interface for Simnet.
@@ -137,19 +303,36 @@ type SimnetEndpoint struct {
 	handlers []p2p.MessageHandler
 	incoming chan Envelope
 	outgoing chan Envelope
+
+	wg      *sync.WaitGroup
+	mu      *sync.Mutex
+	started bool
 }
 
 var _ p2p.Network = &SimnetEndpoint{}
 
-// Start implements the Network interface. It starts goroutines to receive/send message from network.
+// Start implements the Network interface. It starts goroutines to receive/send message from network. The
+// endpoint is started by both the Simnet and the dispatcher of the node, only the first call has an effect.
 func (se *SimnetEndpoint) Start(ctx context.Context) error {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	if se.started {
+		return nil
+	}
+	se.started = true
+
+	se.wg.Add(2)
 	go func() {
+		defer se.wg.Done()
 		for {
 			select {
+			case <-ctx.Done():
+				return
 			case envelope := <-se.incoming:
 				message := p2ptypes.Message{
-					PeerID:  envelope.From,
-					Content: envelope.Content,
+					PeerID:    envelope.From,
+					ChannelID: envelope.ChannelID,
+					Content:   envelope.Content,
 				}
 				se.HandleMessage(message)
 			}
@@ -157,8 +340,11 @@ func (se *SimnetEndpoint) Start(ctx context.Context) error {
 	}()
 
 	go func() {
+		defer se.wg.Done()
 		for {
 			select {
+			case <-ctx.Done():
+				return
 			case envelope := <-se.outgoing:
 				se.network.messages <- envelope
 			}
@@ -174,13 +360,18 @@ func (se *SimnetEndpoint) Stop() {
 
 // Wait blocks until all goroutines have stopped.
 func (se *SimnetEndpoint) Wait() {
+	se.wg.Wait()
+}
+
+func (se *SimnetEndpoint) receive(envelope Envelope) {
+	se.incoming <- envelope
 }
 
 // Broadcast implements the Network interface.
 func (se *SimnetEndpoint) Broadcast(message p2ptypes.Message, skipEdgeNode bool) (successes chan bool) {
 	successes = make(chan bool, 10)
 	go func() {
-		se.network.AddMessage(Envelope{From: se.ID(), Content: message.Content})
+		se.network.AddMessage(Envelope{From: se.ID(), ChannelID: message.ChannelID, Content: message.Content})
 		successes <- true
 	}()
 	return successes
@@ -190,7 +381,7 @@ func (se *SimnetEndpoint) Broadcast(message p2ptypes.Message, skipEdgeNode bool)
 func (se *SimnetEndpoint) BroadcastToNeighbors(message p2ptypes.Message, maxNumPeersToBroadcast int, skipEdgeNode bool) (successes chan bool) {
 	successes = make(chan bool, 10)
 	go func() {
-		se.network.AddMessage(Envelope{From: se.ID(), Content: message.Content})
+		se.network.AddMessage(Envelope{From: se.ID(), ChannelID: message.ChannelID, Content: message.Content})
 		successes <- true
 	}()
 	return successes
@@ -199,14 +390,20 @@ func (se *SimnetEndpoint) BroadcastToNeighbors(message p2ptypes.Message, maxNumP
 // Send implements the Network interface.
 func (se *SimnetEndpoint) Send(id string, message p2ptypes.Message) bool {
 	go func() {
-		se.network.AddMessage(Envelope{From: se.ID(), To: id, Content: message.Content})
+		se.network.AddMessage(Envelope{From: se.ID(), To: id, ChannelID: message.ChannelID, Content: message.Content})
 	}()
 	return true
 }
 
-// Peers returns the IDs of all peers
+// Peers returns the IDs of all peers, i.e. the other endpoints not partitioned away
 func (se *SimnetEndpoint) Peers(skipEdgeNode bool) []string {
-	return []string{}
+	peers := []string{}
+	for _, endpoint := range se.network.Endpoints {
+		if endpoint.ID() != se.ID() && se.network.IsConnected(se.ID(), endpoint.ID()) {
+			peers = append(peers, endpoint.ID())
+		}
+	}
+	return peers
 }
 
 // PeerURLs returns the URLs of all peers
@@ -216,6 +413,11 @@ func (se *SimnetEndpoint) PeerURLs(skipEdgeNode bool) []string {
 
 // PeerExists indicates if the given peerID is a neighboring peer
 func (se *SimnetEndpoint) PeerExists(peerID string) bool {
+	for _, id := range se.Peers(false) {
+		if id == peerID {
+			return true
+		}
+	}
 	return false
 }
 
@@ -224,6 +426,21 @@ func (se *SimnetEndpoint) RegisterMessageHandler(handler p2p.MessageHandler) {
 	se.handlers = append(se.handlers, handler)
 }
 
+// PeerHandshakeInfo implements the Network interface.
+func (se *SimnetEndpoint) PeerHandshakeInfo(peerID string) *p2ptypes.HandshakeInfo {
+	return nil
+}
+
+// NetworkStats implements the Network interface. The simulated network does not measure
+// the traffic, only the peers are listed.
+func (se *SimnetEndpoint) NetworkStats(skipEdgeNode bool) *p2ptypes.NetworkStats {
+	stats := &p2ptypes.NetworkStats{}
+	for _, peerID := range se.Peers(skipEdgeNode) {
+		stats.AddPeer(p2ptypes.PeerStats{ID: peerID, Stack: p2ptypes.StackP2P, NodeType: common.NodeTypeBlockchainNode})
+	}
+	return stats
+}
+
 func (se *SimnetEndpoint) IsSeedPeer(peerID string) bool {
 	return false
 }
@@ -233,13 +450,47 @@ func (se *SimnetEndpoint) ID() string {
 	return se.id
 }
 
-// HandleMessage implements the MessageHandler interface.
+// HandleMessage implements the MessageHandler interface. Like the messengers, the message is
+// only passed to the handlers of its channel, after a round trip through the wire format of
+// the handler. Messages without channel go to all handlers as is.
 func (se *SimnetEndpoint) HandleMessage(message p2ptypes.Message) error {
 	for _, handler := range se.handlers {
-		handler.HandleMessage(message)
+		if message.ChannelID == common.ChannelIDInvalid {
+			handler.HandleMessage(message)
+			continue
+		}
+		if !handlesChannel(handler, message.ChannelID) {
+			continue
+		}
+		parsed, err := transcode(handler, message)
+		if err != nil {
+			logger.Warnf("Failed to transcode message from %v on channel %v: %v", message.PeerID, message.ChannelID, err)
+			continue
+		}
+		handler.HandleMessage(parsed)
 	}
 	if se.network.msgHandler != nil {
 		se.network.msgHandler.HandleMessage(message)
 	}
 	return nil
 }
+
+func handlesChannel(handler p2p.MessageHandler, channelID common.ChannelIDEnum) bool {
+	for _, id := range handler.GetChannelIDs() {
+		if id == channelID {
+			return true
+		}
+	}
+	return false
+}
+
+// transcode encodes the message content and parses it back with the given handler, as
+// the messengers do on both ends of a connection. Contents the handler cannot encode
+// are passed as is.
+func transcode(handler p2p.MessageHandler, message p2ptypes.Message) (p2ptypes.Message, error) {
+	raw, err := handler.EncodeMessage(message.Content)
+	if err != nil {
+		return message, nil
+	}
+	return handler.ParseMessage(message.PeerID, message.ChannelID, raw)
+}
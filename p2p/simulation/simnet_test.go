@@ -12,6 +12,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/common/clock"
 	p2ptypes "github.com/scripttoken/script/p2p/types"
 	"github.com/scripttoken/script/rlp"
 )
@@ -65,7 +66,7 @@ func TestSimnetBroadcast(t *testing.T) {
 	simnet.AddEndpoint("e3")
 	simnet.Start(context.Background())
 
-	e2.Broadcast(createBlockMessage("hello!"))
+	e2.Broadcast(createBlockMessage("hello!"), false)
 	time.Sleep(1 * time.Second)
 	msgHandler.lock.Lock()
 	sort.Strings(msgHandler.ReceivedMessages)
@@ -74,7 +75,7 @@ func TestSimnetBroadcast(t *testing.T) {
 	assert.EqualValues([]string{"e2 -> hello!", "e2 -> hello!"}, msgHandler.ReceivedMessages)
 
 	msgHandler.ReceivedMessages = make([]string, 0)
-	e1.Broadcast(createBlockMessage("world!"))
+	e1.Broadcast(createBlockMessage("world!"), false)
 	time.Sleep(1 * time.Second)
 	msgHandler.lock.Lock()
 	sort.Strings(msgHandler.ReceivedMessages)
@@ -106,3 +107,59 @@ func TestSimnetSend(t *testing.T) {
 	msgHandler.lock.Unlock()
 	assert.EqualValues([]string{"e1 -> world!"}, msgHandler.ReceivedMessages)
 }
+
+func TestSimnetPartition(t *testing.T) {
+	assert := assert.New(t)
+	msgHandler := &SimMessageHandler{lock: &sync.Mutex{}}
+	simnet := NewSimnetWithHandler(msgHandler)
+	e1 := simnet.AddEndpoint("e1")
+	simnet.AddEndpoint("e2")
+	simnet.AddEndpoint("e3")
+	simnet.Start(context.Background())
+
+	simnet.Partition([]string{"e1", "e2"}, []string{"e3"})
+	assert.EqualValues([]string{"e2"}, e1.Peers(false))
+
+	e1.Broadcast(createBlockMessage("hello!"), false)
+	time.Sleep(1 * time.Second)
+	msgHandler.lock.Lock()
+	assert.EqualValues([]string{"e1 -> hello!"}, msgHandler.ReceivedMessages)
+	msgHandler.ReceivedMessages = make([]string, 0)
+	msgHandler.lock.Unlock()
+
+	simnet.Heal()
+	assert.EqualValues([]string{"e2", "e3"}, e1.Peers(false))
+	e1.Send("e3", createBlockMessage("world!"))
+	time.Sleep(1 * time.Second)
+	msgHandler.lock.Lock()
+	assert.EqualValues([]string{"e1 -> world!"}, msgHandler.ReceivedMessages)
+	msgHandler.lock.Unlock()
+}
+
+func TestSimnetInterceptorAndLatency(t *testing.T) {
+	assert := assert.New(t)
+	msgHandler := &SimMessageHandler{lock: &sync.Mutex{}}
+	simnet := NewSimnetWithHandler(msgHandler)
+	e1 := simnet.AddEndpoint("e1")
+	simnet.AddEndpoint("e2")
+	clock := clock.NewSimulatedClock(time.Unix(1600000000, 0))
+	simnet.SetClock(clock)
+	simnet.SetLatency(time.Second, time.Second)
+	simnet.AddInterceptor(func(envelope Envelope) []Envelope {
+		envelope.Content = "tampered"
+		return []Envelope{envelope}
+	})
+	simnet.Start(context.Background())
+
+	e1.Send("e2", createBlockMessage("hello!"))
+	time.Sleep(500 * time.Millisecond)
+	msgHandler.lock.Lock()
+	assert.Equal(0, len(msgHandler.ReceivedMessages))
+	msgHandler.lock.Unlock()
+
+	clock.Advance(time.Second)
+	time.Sleep(500 * time.Millisecond)
+	msgHandler.lock.Lock()
+	assert.EqualValues([]string{"e1 -> tampered"}, msgHandler.ReceivedMessages)
+	msgHandler.lock.Unlock()
+}
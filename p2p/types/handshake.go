@@ -0,0 +1,68 @@
+package types
+
+import (
+	"github.com/scripttoken/script/common"
+)
+
+// ProtocolVersion is the version of the P2P protocol spoken by this node. It should
+// be bumped whenever the wire format of an existing channel changes.
+const ProtocolVersion uint64 = 1
+
+// Capabilities advertised in the handshake. New channels should be guarded by a
+// capability, and only used with the peers advertising it.
+const (
+	// CapabilityStateSync: serves the state trie nodes over ChannelIDStateNode
+	CapabilityStateSync = "state-sync"
+	// CapabilityArchive: retains every historical state
+	CapabilityArchive = "archive"
+	// CapabilitySnapshot: serves the snapshots over HTTP
+	CapabilitySnapshot = "snapshot"
+	// CapabilityCompactBlock: relays the blocks as compact blocks over ChannelIDCompactBlock
+	CapabilityCompactBlock = "compact-block"
+	// CapabilityVoteSummary: exchanges the aggregated vote summaries over ChannelIDVoteSummary
+	CapabilityVoteSummary = "vote-summary"
+	// CapabilityQUIC: accepts QUIC connections of the native P2P stack on the UDP port
+	// with the same number as its TCP port
+	CapabilityQUIC = "quic"
+)
+
+//
+// HandshakeInfo is exchanged with the peers during the handshake, on top of the
+// NodeInfo. The peers running an older version don't send it.
+//
+type HandshakeInfo struct {
+	ProtocolVersion uint64
+	SoftwareVersion string
+	GenesisHash     common.Hash
+	FinalizedHeight uint64
+	FinalizedHash   common.Hash
+	Capabilities    []string
+}
+
+// HandshakeInfoProvider returns the current HandshakeInfo of the local node
+type HandshakeInfoProvider func() *HandshakeInfo
+
+// HasCapability indicates whether the given capability is advertised
+func (info *HandshakeInfo) HasCapability(capability string) bool {
+	if info == nil {
+		return false
+	}
+	for _, c := range info.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCompatible checks that the peer is on the same chain, i.e. has the same
+// genesis block. An unknown genesis hash on either side is considered compatible.
+func (info *HandshakeInfo) IsCompatible(remote *HandshakeInfo) bool {
+	if info == nil || remote == nil {
+		return true
+	}
+	if info.GenesisHash.IsEmpty() || remote.GenesisHash.IsEmpty() {
+		return true
+	}
+	return info.GenesisHash == remote.GenesisHash
+}
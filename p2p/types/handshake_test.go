@@ -0,0 +1,46 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/rlp"
+)
+
+func TestHandshakeInfoRLPEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	info := &HandshakeInfo{
+		ProtocolVersion: ProtocolVersion,
+		SoftwareVersion: "1.0.0",
+		GenesisHash:     common.HexToHash("0x01"),
+		FinalizedHeight: 100,
+		FinalizedHash:   common.HexToHash("0x02"),
+		Capabilities:    []string{CapabilityStateSync, CapabilityArchive},
+	}
+	raw, err := rlp.EncodeToBytes(info)
+	assert.Nil(err)
+
+	decoded := &HandshakeInfo{}
+	assert.Nil(rlp.DecodeBytes(raw, decoded))
+	assert.Equal(info, decoded)
+	assert.True(decoded.HasCapability(CapabilityArchive))
+	assert.False(decoded.HasCapability(CapabilitySnapshot))
+}
+
+func TestHandshakeInfoCompatibility(t *testing.T) {
+	assert := assert.New(t)
+
+	local := &HandshakeInfo{GenesisHash: common.HexToHash("0x01")}
+	assert.True(local.IsCompatible(&HandshakeInfo{GenesisHash: common.HexToHash("0x01")}))
+	assert.False(local.IsCompatible(&HandshakeInfo{GenesisHash: common.HexToHash("0x02")}))
+
+	// Legacy peers and unknown genesis
+	assert.True(local.IsCompatible(nil))
+	assert.True(local.IsCompatible(&HandshakeInfo{}))
+
+	var legacy *HandshakeInfo
+	assert.True(legacy.IsCompatible(local))
+	assert.False(legacy.HasCapability(CapabilityStateSync))
+}
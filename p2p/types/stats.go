@@ -0,0 +1,205 @@
+package types
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/scripttoken/script/common"
+)
+
+//
+// ChannelStats is the traffic of a channel
+//
+type ChannelStats struct {
+	ChannelID     common.ChannelIDEnum `json:"channel_id"`
+	BytesSent     uint64               `json:"bytes_sent"`
+	BytesReceived uint64               `json:"bytes_received"`
+	QueueSize     int                  `json:"queue_size"`     // messages waiting in the send queue
+	QueueCapacity int                  `json:"queue_capacity"` // capacity of the send queue, 0 if unknown
+}
+
+//
+// PeerStats is the diagnostic information of a connected peer
+//
+type PeerStats struct {
+	ID            string          `json:"id"`
+	Address       string          `json:"address"`
+	Stack         string          `json:"stack"`               // P2P stack the peer is connected through
+	Transport     string          `json:"transport,omitempty"` // transport of the native P2P stack, tcp or quic
+	IsOutbound    bool            `json:"is_outbound"`
+	NodeType      common.NodeType `json:"node_type"`
+	IsSeed        bool            `json:"is_seed"`
+	ConnectedAt   time.Time       `json:"connected_at"`
+	ConnectionAge string          `json:"connection_age"`
+	LatencyMs     int64           `json:"latency_ms"` // round trip time of the last ping, 0 if not measured yet
+	SendRate      int64           `json:"send_rate"`  // bytes per second
+	RecvRate      int64           `json:"recv_rate"`  // bytes per second
+	BytesSent     uint64          `json:"bytes_sent"`
+	BytesReceived uint64          `json:"bytes_received"`
+	Channels      []ChannelStats  `json:"channels"`
+}
+
+//
+// NetworkStats is the diagnostic information of a P2P stack, or of all the P2P stacks
+//
+type NetworkStats struct {
+	Peers []PeerStats `json:"peers"`
+
+	// Aggregated over the peers, plus the gossip traffic not attributed to a peer
+	Channels      []ChannelStats `json:"channels"`
+	BytesSent     uint64         `json:"bytes_sent"`
+	BytesReceived uint64         `json:"bytes_received"`
+	SendRate      int64          `json:"send_rate"`
+	RecvRate      int64          `json:"recv_rate"`
+}
+
+// P2P stacks, also the names of the transports registered in the p2p.Registry
+const (
+	StackP2P   = "p2p"
+	StackP2PL  = "p2pl"
+	StackLocal = "local" // in-process transport, e.g. the simulated network
+)
+
+// Transports of the native P2P stack
+const (
+	TransportTCP  = "tcp"
+	TransportQUIC = "quic"
+)
+
+// NewPeerStats creates the PeerStats of a peer connected at the given time
+func NewPeerStats(id string, address string, stack string, connectedAt time.Time) PeerStats {
+	return PeerStats{
+		ID:            id,
+		Address:       address,
+		Stack:         stack,
+		ConnectedAt:   connectedAt,
+		ConnectionAge: time.Since(connectedAt).Round(time.Second).String(),
+	}
+}
+
+// SetChannels sets the channel stats of the peer, and the total bytes
+func (ps *PeerStats) SetChannels(channels []ChannelStats) {
+	ps.Channels = channels
+	ps.BytesSent = 0
+	ps.BytesReceived = 0
+	for _, ch := range channels {
+		ps.BytesSent += ch.BytesSent
+		ps.BytesReceived += ch.BytesReceived
+	}
+}
+
+// AddPeer adds the peer to the network stats, and aggregates its traffic
+func (ns *NetworkStats) AddPeer(ps PeerStats) {
+	ns.Peers = append(ns.Peers, ps)
+	ns.SendRate += ps.SendRate
+	ns.RecvRate += ps.RecvRate
+	ns.AddChannels(ps.Channels)
+}
+
+// AddChannels aggregates the given channel traffic
+func (ns *NetworkStats) AddChannels(channels []ChannelStats) {
+	for _, ch := range channels {
+		ns.BytesSent += ch.BytesSent
+		ns.BytesReceived += ch.BytesReceived
+
+		found := false
+		for i := range ns.Channels {
+			if ns.Channels[i].ChannelID == ch.ChannelID {
+				ns.Channels[i].BytesSent += ch.BytesSent
+				ns.Channels[i].BytesReceived += ch.BytesReceived
+				ns.Channels[i].QueueSize += ch.QueueSize
+				ns.Channels[i].QueueCapacity += ch.QueueCapacity
+				found = true
+				break
+			}
+		}
+		if !found {
+			ns.Channels = append(ns.Channels, ch)
+		}
+	}
+	sort.Slice(ns.Channels, func(i, j int) bool {
+		return ns.Channels[i].ChannelID < ns.Channels[j].ChannelID
+	})
+}
+
+// Merge aggregates the stats of another P2P stack
+func (ns *NetworkStats) Merge(other *NetworkStats) {
+	if other == nil {
+		return
+	}
+	ns.Peers = append(ns.Peers, other.Peers...)
+	ns.SendRate += other.SendRate
+	ns.RecvRate += other.RecvRate
+	ns.AddChannels(other.Channels)
+}
+
+//
+// TrafficCounter counts the bytes sent and received on each channel. It is goroutine safe.
+//
+type TrafficCounter struct {
+	mu       *sync.Mutex
+	sent     map[common.ChannelIDEnum]uint64
+	received map[common.ChannelIDEnum]uint64
+}
+
+// NewTrafficCounter creates an instance of TrafficCounter
+func NewTrafficCounter() *TrafficCounter {
+	return &TrafficCounter{
+		mu:       &sync.Mutex{},
+		sent:     make(map[common.ChannelIDEnum]uint64),
+		received: make(map[common.ChannelIDEnum]uint64),
+	}
+}
+
+// RecordSent records the bytes sent on the channel
+func (tc *TrafficCounter) RecordSent(channelID common.ChannelIDEnum, size int) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.sent[channelID] += uint64(size)
+}
+
+// RecordReceived records the bytes received on the channel
+func (tc *TrafficCounter) RecordReceived(channelID common.ChannelIDEnum, size int) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.received[channelID] += uint64(size)
+}
+
+// Received returns the bytes received on the channel
+func (tc *TrafficCounter) Received(channelID common.ChannelIDEnum) uint64 {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return tc.received[channelID]
+}
+
+// ChannelStats returns the traffic of the channels with any traffic, ordered by channel ID
+func (tc *TrafficCounter) ChannelStats() []ChannelStats {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	channels := map[common.ChannelIDEnum]*ChannelStats{}
+	get := func(channelID common.ChannelIDEnum) *ChannelStats {
+		ch, ok := channels[channelID]
+		if !ok {
+			ch = &ChannelStats{ChannelID: channelID}
+			channels[channelID] = ch
+		}
+		return ch
+	}
+	for channelID, size := range tc.sent {
+		get(channelID).BytesSent = size
+	}
+	for channelID, size := range tc.received {
+		get(channelID).BytesReceived = size
+	}
+
+	stats := []ChannelStats{}
+	for _, ch := range channels {
+		stats = append(stats, *ch)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].ChannelID < stats[j].ChannelID
+	})
+	return stats
+}
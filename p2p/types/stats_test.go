@@ -0,0 +1,63 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/scripttoken/script/common"
+)
+
+func TestTrafficCounter(t *testing.T) {
+	assert := assert.New(t)
+
+	tc := NewTrafficCounter()
+	tc.RecordSent(common.ChannelIDVote, 10)
+	tc.RecordSent(common.ChannelIDVote, 5)
+	tc.RecordReceived(common.ChannelIDBlock, 100)
+	tc.RecordReceived(common.ChannelIDVote, 1)
+
+	stats := tc.ChannelStats()
+	assert.Equal(2, len(stats))
+	assert.Equal(common.ChannelIDBlock, stats[0].ChannelID)
+	assert.Equal(uint64(100), stats[0].BytesReceived)
+	assert.Equal(common.ChannelIDVote, stats[1].ChannelID)
+	assert.Equal(uint64(15), stats[1].BytesSent)
+	assert.Equal(uint64(1), stats[1].BytesReceived)
+}
+
+func TestNetworkStatsAggregation(t *testing.T) {
+	assert := assert.New(t)
+
+	peer1 := NewPeerStats("peer1", "127.0.0.1:1000", StackP2P, time.Now())
+	peer1.SendRate = 10
+	peer1.SetChannels([]ChannelStats{
+		{ChannelID: common.ChannelIDVote, BytesSent: 10, BytesReceived: 20, QueueSize: 1, QueueCapacity: 1},
+	})
+	assert.Equal(uint64(10), peer1.BytesSent)
+	assert.Equal(uint64(20), peer1.BytesReceived)
+
+	peer2 := NewPeerStats("peer2", "127.0.0.1:2000", StackP2PL, time.Now())
+	peer2.RecvRate = 5
+	peer2.SetChannels([]ChannelStats{
+		{ChannelID: common.ChannelIDBlock, BytesReceived: 100},
+		{ChannelID: common.ChannelIDVote, BytesSent: 1},
+	})
+
+	stats := &NetworkStats{}
+	stats.AddPeer(peer1)
+	other := &NetworkStats{}
+	other.AddPeer(peer2)
+	stats.Merge(other)
+	stats.Merge(nil)
+
+	assert.Equal(2, len(stats.Peers))
+	assert.Equal(int64(10), stats.SendRate)
+	assert.Equal(int64(5), stats.RecvRate)
+	assert.Equal(uint64(11), stats.BytesSent)
+	assert.Equal(uint64(120), stats.BytesReceived)
+	assert.Equal(2, len(stats.Channels))
+	assert.Equal(common.ChannelIDBlock, stats.Channels[0].ChannelID)
+	assert.Equal(uint64(11), stats.Channels[1].BytesSent)
+	assert.Equal(1, stats.Channels[1].QueueSize)
+}
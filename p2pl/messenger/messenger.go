@@ -3,6 +3,7 @@ package messenger
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/rand"
 	"strconv"
@@ -16,6 +17,8 @@ import (
 	"github.com/scripttoken/script/common"
 	"github.com/scripttoken/script/common/util"
 	"github.com/scripttoken/script/crypto"
+	"github.com/scripttoken/script/p2p/permission"
+	"github.com/scripttoken/script/p2p/reputation"
 	p2ptypes "github.com/scripttoken/script/p2p/types"
 	p2pcmn "github.com/scripttoken/script/p2pl/common"
 
@@ -23,6 +26,7 @@ import (
 
 	"github.com/scripttoken/script/p2pl"
 	"github.com/scripttoken/script/p2pl/transport"
+	"github.com/scripttoken/script/rlp"
 
 	"github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p-core/host"
@@ -38,6 +42,7 @@ import (
 	dhtopts "github.com/libp2p/go-libp2p-kad-dht/opts"
 	ps "github.com/libp2p/go-libp2p-pubsub"
 	rhost "github.com/libp2p/go-libp2p/p2p/host/routed"
+	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
 
 	// "github.com/libp2p/go-libp2p/p2p/discovery"
 
@@ -59,6 +64,11 @@ const (
 	connectInterval                   = 1000 // 1 sec
 	lowConnectivityCheckInterval      = 60
 	highConnectivityCheckInterval     = 10
+	handshakeProtocol                 = "handshake"
+	handshakeTimeout                  = 10 * time.Second
+	maxHandshakeInfoSize              = 4096
+	latencyCheckInterval              = 30 * time.Second
+	pingTimeout                       = 10 * time.Second
 )
 
 type Messenger struct {
@@ -72,6 +82,13 @@ type Messenger struct {
 	seedPeerOnly  bool
 
 	peerTable    *peer.PeerTable
+	reputation   *reputation.ReputationManager
+
+	allowlist     *permission.Allowlist
+	allowedIDs    map[pr.ID]bool // libp2p IDs of the nodes in the allowlist
+	allowlistLock sync.RWMutex
+
+	handshakeInfoProvider p2ptypes.HandshakeInfoProvider
 	newPeers     chan pr.ID
 	peerDead     chan pr.ID
 	newPeerError chan pr.ID
@@ -85,6 +102,7 @@ type Messenger struct {
 	statsEnabled bool
 	statsLock    sync.Mutex
 	statsCounter map[common.ChannelIDEnum]uint64
+	gossip       *p2ptypes.TrafficCounter // pubsub traffic, not attributed to a peer
 
 	// Life cycle
 	wg      *sync.WaitGroup
@@ -157,6 +175,7 @@ func CreateMessenger(pubKey *crypto.PublicKey, seedPeerMultiAddresses []string,
 		protocolPrefix:      protocolPrefix,
 		config:              msgrConfig,
 		statsCounter:        make(map[common.ChannelIDEnum]uint64),
+		gossip:              p2ptypes.NewTrafficCounter(),
 		wg:                  &sync.WaitGroup{},
 		ctx:                 ctx,
 	}
@@ -166,7 +185,7 @@ func CreateMessenger(pubKey *crypto.PublicKey, seedPeerMultiAddresses []string,
 		messenger.msgNormalBufferPool <- make([]byte, p2pcmn.MaxNormalMessageSize)
 	}
 
-	hostId, _, err := cr.GenerateEd25519Key(strings.NewReader(common.Bytes2Hex(pubKey.ToBytes())))
+	hostId, err := hostKey(pubKey)
 	if err != nil {
 		return messenger, err
 	}
@@ -260,11 +279,194 @@ func CreateMessenger(pubKey *crypto.PublicKey, seedPeerMultiAddresses []string,
 	messenger.pubsub = pubsub
 
 	host.Network().Notify((*PeerNotif)(messenger))
+	messenger.registerHandshakeHandler()
 
 	logger.Infof("Created node %v, %v, seedPeerOnly: %v", host.ID(), host.Addrs(), seedPeerOnly)
 	return messenger, nil
 }
 
+// SetReputationManager sets the ReputationManager for the Messenger, which is
+// then notified of the malformed messages and can disconnect the misbehaving peers
+func (msgr *Messenger) SetReputationManager(rm *reputation.ReputationManager) {
+	msgr.reputation = rm
+	rm.AddDisconnectHandler(msgr.disconnectPeer)
+}
+
+// SetAllowlist sets the Allowlist for the Messenger. In the permissioned mode, the
+// connections from the nodes not listed are closed before the peers are added, and
+// the peers no longer listed are disconnected when the list is reloaded.
+func (msgr *Messenger) SetAllowlist(al *permission.Allowlist) {
+	msgr.allowlist = al
+	msgr.updateAllowedIDs()
+	al.AddReloadHandler(func() {
+		msgr.updateAllowedIDs()
+		msgr.disconnectUnlistedPeers()
+	})
+}
+
+// hostKey derives the libp2p identity of a node from its public key
+func hostKey(pubKey *crypto.PublicKey) (cr.PrivKey, error) {
+	privKey, _, err := cr.GenerateEd25519Key(strings.NewReader(common.Bytes2Hex(pubKey.ToBytes())))
+	return privKey, err
+}
+
+// updateAllowedIDs computes the libp2p IDs of the nodes in the allowlist
+func (msgr *Messenger) updateAllowedIDs() {
+	allowedIDs := make(map[pr.ID]bool)
+	for _, pubKey := range msgr.allowlist.PublicKeys() {
+		privKey, err := hostKey(pubKey)
+		if err != nil {
+			logger.Warnf("Failed to derive the libp2p ID of %v: %v", pubKey.Address().Hex(), err)
+			continue
+		}
+		pid, err := pr.IDFromPublicKey(privKey.GetPublic())
+		if err != nil {
+			logger.Warnf("Failed to derive the libp2p ID of %v: %v", pubKey.Address().Hex(), err)
+			continue
+		}
+		allowedIDs[pid] = true
+	}
+
+	msgr.allowlistLock.Lock()
+	msgr.allowedIDs = allowedIDs
+	msgr.allowlistLock.Unlock()
+}
+
+// isAllowed indicates whether the given peer is in the allowlist, always true when the
+// permissioned mode is disabled
+func (msgr *Messenger) isAllowed(pid pr.ID) bool {
+	if msgr.allowlist == nil || !msgr.allowlist.Enabled() {
+		return true
+	}
+	msgr.allowlistLock.RLock()
+	defer msgr.allowlistLock.RUnlock()
+	return msgr.allowedIDs[pid]
+}
+
+// disconnectUnlistedPeers disconnects the neighbors no longer in the allowlist
+func (msgr *Messenger) disconnectUnlistedPeers() {
+	for _, peer := range *msgr.peerTable.GetAllPeers(false) {
+		if !msgr.isAllowed(peer.ID()) {
+			msgr.disconnectPeer(peer.ID().Pretty())
+		}
+	}
+}
+
+// SetHandshakeInfoProvider sets the provider of the HandshakeInfo sent to the peers
+func (msgr *Messenger) SetHandshakeInfoProvider(provider p2ptypes.HandshakeInfoProvider) {
+	msgr.handshakeInfoProvider = provider
+}
+
+// localHandshakeInfo returns the HandshakeInfo of the local node, nil if not available
+func (msgr *Messenger) localHandshakeInfo() *p2ptypes.HandshakeInfo {
+	if msgr.handshakeInfoProvider == nil {
+		return nil
+	}
+	return msgr.handshakeInfoProvider()
+}
+
+// registerHandshakeHandler serves the local HandshakeInfo to the peers. The peers
+// running an older version don't support the protocol.
+func (msgr *Messenger) registerHandshakeHandler() {
+	msgr.host.SetStreamHandler(protocol.ID(msgr.protocolPrefix+handshakeProtocol), func(strm network.Stream) {
+		defer strm.Close()
+
+		if !msgr.isAllowed(strm.Conn().RemotePeer()) {
+			strm.Reset()
+			return
+		}
+
+		localInfo := msgr.localHandshakeInfo()
+		if localInfo == nil {
+			strm.Reset()
+			return
+		}
+		raw, err := rlp.EncodeToBytes(localInfo)
+		if err != nil {
+			logger.Errorf("Failed to encode handshake info: %v", err)
+			strm.Reset()
+			return
+		}
+		strm.SetWriteDeadline(time.Now().Add(handshakeTimeout))
+		if _, err := strm.Write(raw); err != nil {
+			logger.Debugf("Failed to send handshake info to %v: %v", strm.Conn().RemotePeer(), err)
+		}
+	})
+}
+
+// requestHandshakeInfo retrieves the HandshakeInfo of the given peer, and disconnects
+// the peer if it is on another chain
+func (msgr *Messenger) requestHandshakeInfo(remotePeer *peer.Peer) {
+	strm, err := msgr.host.NewStream(msgr.ctx, remotePeer.ID(), protocol.ID(msgr.protocolPrefix+handshakeProtocol))
+	if err != nil {
+		logger.Debugf("Peer %v does not support the handshake protocol: %v", remotePeer.ID(), err)
+		return
+	}
+	defer strm.Close()
+
+	strm.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	raw, err := ioutil.ReadAll(io.LimitReader(strm, maxHandshakeInfoSize))
+	if err != nil || len(raw) == 0 {
+		logger.Debugf("Failed to read handshake info from %v: %v", remotePeer.ID(), err)
+		return
+	}
+	remoteInfo := &p2ptypes.HandshakeInfo{}
+	if err := rlp.DecodeBytes(raw, remoteInfo); err != nil {
+		logger.Warnf("Cannot parse the handshake info of peer %v: %v", remotePeer.ID(), err)
+		return
+	}
+	remotePeer.SetHandshakeInfo(remoteInfo)
+	logger.Infof("Peer %v protocol version: %v, software version: %v, finalized height: %v, capabilities: %v", remotePeer.ID(),
+		remoteInfo.ProtocolVersion, remoteInfo.SoftwareVersion, remoteInfo.FinalizedHeight, remoteInfo.Capabilities)
+
+	localInfo := msgr.localHandshakeInfo()
+	if !localInfo.IsCompatible(remoteInfo) {
+		logger.Warnf("Genesis mismatch: peer %v genesis: %v, local genesis: %v", remotePeer.ID(), remoteInfo.GenesisHash.Hex(), localInfo.GenesisHash.Hex())
+		select {
+		case msgr.newPeerError <- remotePeer.ID():
+		case <-msgr.ctx.Done():
+		}
+	}
+}
+
+// PeerHandshakeInfo returns the versioned handshake info of the given peer, nil if the
+// peer is not connected or runs an older version
+func (msgr *Messenger) PeerHandshakeInfo(peerID string) *p2ptypes.HandshakeInfo {
+	prID, err := pr.IDB58Decode(peerID)
+	if err != nil {
+		return nil
+	}
+	remotePeer := msgr.peerTable.GetPeer(prID)
+	if remotePeer == nil {
+		return nil
+	}
+	return remotePeer.HandshakeInfo()
+}
+
+// isBanned indicates whether the given peer is banned
+func (msgr *Messenger) isBanned(pid pr.ID) bool {
+	return msgr.reputation != nil && msgr.reputation.IsBanned(pid.Pretty())
+}
+
+// reportMalformedMessage notifies the ReputationManager of a message that failed to parse
+func (msgr *Messenger) reportMalformedMessage(peerID string, err error) {
+	if msgr.reputation != nil {
+		msgr.reputation.Report(peerID, reputation.InfractionMalformedMessage, err.Error())
+	}
+}
+
+// disconnectPeer disconnects the given peer if it is a neighbor
+func (msgr *Messenger) disconnectPeer(peerID string) {
+	pid, err := pr.IDB58Decode(peerID)
+	if err != nil || !msgr.peerTable.PeerExists(pid) {
+		return
+	}
+	select {
+	case msgr.newPeerError <- pid:
+	case <-msgr.ctx.Done():
+	}
+}
+
 func (msgr *Messenger) IsSeedPeer(pid string) bool {
 	_, isSeed := msgr.seedPeers[pr.ID(pid)]
 	return isSeed
@@ -288,6 +490,18 @@ func (msgr *Messenger) processLoop(ctx context.Context) {
 				continue
 			}
 
+			if msgr.isBanned(pid) {
+				logger.Infof("Rejected banned peer %v", pid)
+				msgr.host.Network().ClosePeer(pid)
+				continue
+			}
+
+			if !msgr.isAllowed(pid) {
+				logger.Infof("Rejected peer %v, not in the allowlist", pid)
+				msgr.host.Network().ClosePeer(pid)
+				continue
+			}
+
 			if msgr.seedPeerOnly {
 				if !msgr.IsSeedPeer(string(pid)) {
 					msgr.host.Network().ClosePeer(pid)
@@ -311,6 +525,7 @@ func (msgr *Messenger) processLoop(ctx context.Context) {
 			msgr.attachHandlersToPeer(peer)
 			peer.Start(msgr.ctx)
 			peer.OpenStreams()
+			go msgr.requestHandshakeInfo(peer)
 			logger.Infof("Peer connected, id: %v, addrs: %v", pr.ID, pr.Addrs)
 		case pid := <-msgr.newPeerError:
 			peer := msgr.peerTable.GetPeer(pid)
@@ -352,6 +567,7 @@ func (msgr *Messenger) maintainConnectivityRoutine(ctx context.Context) {
 		seedsConnectivityCheckPulse = time.NewTicker(lowConnectivityCheckInterval * time.Second)
 	}
 	sufficientConnectionsCheckPulse = time.NewTicker(lowConnectivityCheckInterval * time.Second)
+	latencyCheckPulse := time.NewTicker(latencyCheckInterval)
 
 	for {
 		select {
@@ -359,6 +575,8 @@ func (msgr *Messenger) maintainConnectivityRoutine(ctx context.Context) {
 			msgr.maintainSeedsConnectivity(ctx)
 		case <-sufficientConnectionsCheckPulse.C:
 			msgr.maintainSufficientConnections(ctx)
+		case <-latencyCheckPulse.C:
+			msgr.measureLatencies(ctx)
 		}
 	}
 }
@@ -567,6 +785,7 @@ func (msgr *Messenger) Publish(message p2ptypes.Message) error {
 		log.Errorf("Failed to publish to gossipsub topic: %v", err)
 		return err
 	}
+	msgr.gossip.RecordSent(message.ChannelID, len(bytes))
 
 	return nil
 }
@@ -676,7 +895,15 @@ func (msgr *Messenger) PeerExists(peerID string) bool {
 	return msgr.peerTable.PeerExists(prID)
 }
 
-func (msgr *Messenger) recordReceivedBytes(cid common.ChannelIDEnum, size int) {
+// recordReceivedBytes records the bytes of a message received from the given peer, or
+// through the gossip if the peer is nil
+func (msgr *Messenger) recordReceivedBytes(remotePeer *peer.Peer, cid common.ChannelIDEnum, size int) {
+	if remotePeer != nil {
+		remotePeer.RecordReceived(cid, size)
+	} else {
+		msgr.gossip.RecordReceived(cid, size)
+	}
+
 	if !msgr.statsEnabled {
 		return
 	}
@@ -692,12 +919,40 @@ func (msgr *Messenger) recordReceivedBytes(cid common.ChannelIDEnum, size int) {
 	}
 }
 
+// NetworkStats returns the diagnostic information of the peers and their traffic. The
+// gossip traffic is only included in the totals.
+func (msgr *Messenger) NetworkStats(skipEdgeNode bool) *p2ptypes.NetworkStats {
+	stats := &p2ptypes.NetworkStats{}
+	for _, remotePeer := range *msgr.peerTable.GetAllPeers(skipEdgeNode) {
+		peerStats := remotePeer.Stats()
+		peerStats.IsSeed = msgr.IsSeedPeer(string(remotePeer.ID()))
+		peerStats.LatencyMs = msgr.host.Peerstore().LatencyEWMA(remotePeer.ID()).Milliseconds()
+		stats.AddPeer(peerStats)
+	}
+	stats.AddChannels(msgr.gossip.ChannelStats())
+	return stats
+}
+
+// measureLatencies pings the peers, the round trip times are recorded in the peerstore
+func (msgr *Messenger) measureLatencies(ctx context.Context) {
+	for _, pid := range *msgr.peerTable.GetAllPeerIDs() {
+		go func(pid pr.ID) {
+			c, cancel := context.WithTimeout(ctx, pingTimeout)
+			defer cancel()
+			res := <-ping.Ping(c, msgr.host, pid)
+			if res.Error != nil {
+				logger.Debugf("Failed to ping peer %v: %v", pid, res.Error)
+			}
+		}(pid)
+	}
+}
+
 func (msgr *Messenger) printStats() {
 	msgr.statsLock.Lock()
 	defer msgr.statsLock.Unlock()
 
 	ret := "Received bytes:"
-	for k := byte(0); k <= byte(common.ChannelIDAggregatedEliteEdgeNodeVotes); k++ {
+	for k := byte(0); k <= byte(common.ChannelIDVoteSummary); k++ {
 		v, ok := msgr.statsCounter[common.ChannelIDEnum(k)]
 		if !ok {
 			continue
@@ -749,10 +1004,11 @@ func (msgr *Messenger) RegisterMessageHandler(msgHandler p2pl.MessageHandler) {
 				message, err := msgHandler.ParseMessage(msg.GetFrom().String(), channelID, msg.Data)
 				if err != nil {
 					logger.Errorf("Failed to parse message, %v", err)
-					return
+					msgr.reportMalformedMessage(msg.GetFrom().String(), err)
+					continue
 				}
 
-				msgr.recordReceivedBytes(channelID, len(msg.Data))
+				msgr.recordReceivedBytes(nil, channelID, len(msg.Data))
 
 				msgHandler.HandleMessage(message)
 			}
@@ -772,6 +1028,12 @@ func (msgr *Messenger) registerStreamHandler(channelID common.ChannelIDEnum) {
 			}
 		}
 
+		if msgr.isBanned(peerID) || !msgr.isAllowed(peerID) {
+			strm.Reset()
+			msgr.host.Network().ClosePeer(peerID)
+			return
+		}
+
 		if strings.Compare(msgr.host.ID().String(), peerID.String()) > 0 {
 			logger.Warnf("Received stream from an outbound peer")
 			return
@@ -786,6 +1048,7 @@ func (msgr *Messenger) registerStreamHandler(channelID common.ChannelIDEnum) {
 			msgr.peerTable.AddPeer(remotePeer)
 			msgr.attachHandlersToPeer(remotePeer)
 			remotePeer.Start(msgr.ctx)
+			go msgr.requestHandshakeInfo(remotePeer)
 
 			logger.Infof("Peer connected (via stream), id: %v, addrs: %v", remotePeer.ID, remotePeer.Addrs)
 		}
@@ -797,7 +1060,7 @@ func (msgr *Messenger) registerStreamHandler(channelID common.ChannelIDEnum) {
 			}
 			stream := transport.NewBufferedStream(strm, errorHandler)
 			stream.Start(msgr.ctx)
-			go msgr.readPeerMessageRoutine(stream, peerID.String(), channelID)
+			go msgr.readPeerMessageRoutine(stream, remotePeer, channelID)
 			remotePeer.AcceptStream(channelID, stream)
 
 		} else {
@@ -810,19 +1073,22 @@ func (msgr *Messenger) registerStreamHandler(channelID common.ChannelIDEnum) {
 			message, err := msgHandler.ParseMessage(peerID.String(), channelID, rawPeerMsg)
 			if err != nil {
 				logger.Errorf("Failed to parse message, %v. len(): %v, channel: %v, peer: %v, msg: %v", err, len(rawPeerMsg), channelID, peerID, rawPeerMsg)
+				msgr.reportMalformedMessage(peerID.String(), err)
 				return
 			}
 
-			msgr.recordReceivedBytes(channelID, len(rawPeerMsg))
+			msgr.recordReceivedBytes(remotePeer, channelID, len(rawPeerMsg))
 
 			msgHandler.HandleMessage(message)
 		}
 	})
 }
 
-func (msgr *Messenger) readPeerMessageRoutine(stream *transport.BufferedStream, peerID string, channelID common.ChannelIDEnum) {
+func (msgr *Messenger) readPeerMessageRoutine(stream *transport.BufferedStream, remotePeer *peer.Peer, channelID common.ChannelIDEnum) {
 	defer stream.Stop()
 
+	peerID := remotePeer.ID().String()
+
 	for {
 		if msgr.ctx != nil {
 			select {
@@ -869,10 +1135,11 @@ func (msgr *Messenger) readPeerMessageRoutine(stream *transport.BufferedStream,
 		bufferPool <- msgBuffer
 		if err != nil {
 			logger.Errorf("Failed to parse message, %v. msgSize: %v, len(): %v, channel: %v, peer: %v, msg: %v", err, msgSize, len(rawPeerMsg), channelID, peerID, rawPeerMsg)
+			msgr.reportMalformedMessage(peerID, err)
 			return
 		}
 
-		msgr.recordReceivedBytes(channelID, len(rawPeerMsg))
+		msgr.recordReceivedBytes(remotePeer, channelID, len(rawPeerMsg))
 
 		msgHandler.HandleMessage(message)
 	}
@@ -887,8 +1154,11 @@ func (msgr *Messenger) attachHandlersToPeer(peer *peer.Peer) {
 			logger.Errorf("Failed to setup message parser for channelID %v", channelID)
 		}
 		message, err := msgHandler.ParseMessage(peerID.String(), channelID, rawMessageBytes)
+		if err != nil {
+			msgr.reportMalformedMessage(peerID.String(), err)
+		}
 
-		msgr.recordReceivedBytes(channelID, len(rawMessageBytes))
+		msgr.recordReceivedBytes(peer, channelID, len(rawMessageBytes))
 
 		return message, err
 	}
@@ -927,7 +1197,7 @@ func (msgr *Messenger) attachHandlersToPeer(peer *peer.Peer) {
 		}
 		stream := transport.NewBufferedStream(strm, errorHandler)
 		stream.Start(msgr.ctx)
-		go msgr.readPeerMessageRoutine(stream, peer.ID().String(), channelID)
+		go msgr.readPeerMessageRoutine(stream, peer, channelID)
 		return stream, nil
 	}
 	peer.SetStreamCreator(streamCreator)
@@ -22,6 +22,11 @@ func (p *PeerNotif) ClosedStream(n network.Network, s network.Stream) {
 }
 
 func (p *PeerNotif) Connected(n network.Network, c network.Conn) {
+	if !(*Messenger)(p).isAllowed(c.RemotePeer()) {
+		// Permissioned mode, reject the connection before the peer is added
+		c.Close()
+		return
+	}
 	go func() {
 		select {
 		case p.newPeers <- c.RemotePeer():
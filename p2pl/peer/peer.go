@@ -10,6 +10,7 @@ import (
 	cmn "github.com/scripttoken/script/common"
 	p2ptypes "github.com/scripttoken/script/p2p/types"
 	"github.com/scripttoken/script/p2pl/transport"
+	"github.com/scripttoken/script/p2pl/transport/buffer/flowrate"
 
 	"github.com/libp2p/go-libp2p-core/network"
 	"github.com/scripttoken/script/rlp"
@@ -32,6 +33,9 @@ var Channels = []cmn.ChannelIDEnum{
 	cmn.ChannelIDLightning,
 	cmn.ChannelIDEliteEdgeNodeVote,
 	cmn.ChannelIDAggregatedEliteEdgeNodeVotes,
+	cmn.ChannelIDStateNode,
+	cmn.ChannelIDCompactBlock,
+	cmn.ChannelIDVoteSummary,
 }
 
 //
@@ -45,6 +49,14 @@ type Peer struct {
 
 	openStreamsTimer *time.Timer
 
+	handshakeInfo *p2ptypes.HandshakeInfo // nil if the peer runs an older version
+
+	// Diagnostics
+	connectedAt time.Time
+	traffic     *p2ptypes.TrafficCounter
+	sendStats   *flowrate.Monitor
+	recvStats   *flowrate.Monitor
+
 	onStream    StreamCreator
 	onRawStream RawStreamCreator
 	onParse     MessageParser
@@ -68,6 +80,11 @@ func CreatePeer(addrInfo pr.AddrInfo, isOutbound bool) *Peer {
 		mutex:      &sync.Mutex{},
 		onEncode:   defaultMessageEncoder,
 		wg:         &sync.WaitGroup{},
+
+		connectedAt: time.Now(),
+		traffic:     p2ptypes.NewTrafficCounter(),
+		sendStats:   flowrate.New(0, 0),
+		recvStats:   flowrate.New(0, 0),
 	}
 
 	return peer
@@ -197,6 +214,8 @@ func (peer *Peer) Send(channelID cmn.ChannelIDEnum, message interface{}) bool {
 		return false
 	}
 
+	peer.traffic.RecordSent(channelID, n)
+	peer.sendStats.Update(n)
 	return true
 }
 
@@ -215,6 +234,61 @@ func (peer *Peer) AddrInfo() pr.AddrInfo {
 	return peer.addrInfo
 }
 
+// SetHandshakeInfo sets the versioned handshake info received from the peer
+func (peer *Peer) SetHandshakeInfo(info *p2ptypes.HandshakeInfo) {
+	peer.mutex.Lock()
+	defer peer.mutex.Unlock()
+	peer.handshakeInfo = info
+}
+
+// HandshakeInfo returns the versioned handshake info of the peer, nil if the peer runs an older version
+func (peer *Peer) HandshakeInfo() *p2ptypes.HandshakeInfo {
+	peer.mutex.Lock()
+	defer peer.mutex.Unlock()
+	return peer.handshakeInfo
+}
+
+// RecordReceived records the bytes of a message received from the peer
+func (peer *Peer) RecordReceived(channelID cmn.ChannelIDEnum, size int) {
+	peer.traffic.RecordReceived(channelID, size)
+	peer.recvStats.Update(size)
+}
+
+// Stats returns the diagnostic information of the peer. The messenger fills in the
+// information it keeps, e.g. the latency.
+func (peer *Peer) Stats() p2ptypes.PeerStats {
+	address := ""
+	if len(peer.addrInfo.Addrs) > 0 {
+		address = peer.addrInfo.Addrs[0].String()
+	}
+	stats := p2ptypes.NewPeerStats(peer.ID().Pretty(), address, p2ptypes.StackP2PL, peer.connectedAt)
+	stats.IsOutbound = peer.isOutbound
+	stats.NodeType = cmn.NodeTypeBlockchainNode
+	stats.SendRate = peer.sendStats.Status().CurRate
+	stats.RecvRate = peer.recvStats.Status().CurRate
+
+	traffic := map[cmn.ChannelIDEnum]p2ptypes.ChannelStats{}
+	for _, ch := range peer.traffic.ChannelStats() {
+		traffic[ch.ChannelID] = ch
+	}
+
+	peer.mutex.Lock()
+	channels := []p2ptypes.ChannelStats{}
+	for _, channelID := range Channels {
+		ch := traffic[channelID]
+		ch.ChannelID = channelID
+		if stream, ok := peer.streamMap[channelID]; ok && stream != nil {
+			ch.QueueSize = stream.QueueSize()
+			ch.QueueCapacity = stream.QueueCapacity()
+		}
+		channels = append(channels, ch)
+	}
+	peer.mutex.Unlock()
+
+	stats.SetChannels(channels)
+	return stats
+}
+
 // StreamCreator creates a buffered stream with this peer
 type StreamCreator func(channelID cmn.ChannelIDEnum) (*transport.BufferedStream, error)
 
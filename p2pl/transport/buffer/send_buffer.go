@@ -123,6 +123,11 @@ func (sb *SendBuffer) CanInsert() bool {
 	return sb.GetSize() < sb.config.queueCapacity
 }
 
+// Capacity returns the capacity of the queue
+func (sb *SendBuffer) Capacity() int {
+	return sb.config.queueCapacity
+}
+
 // Write insert the bytes to queue, and times out after
 // the configured timeout. It is goroutine safe
 func (sb *SendBuffer) Write(bytes []byte) bool {
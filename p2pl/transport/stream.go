@@ -71,6 +71,16 @@ func (s *BufferedStream) Stop() {
 	s.cancel()
 }
 
+// QueueSize returns the number of messages waiting to be sent
+func (s *BufferedStream) QueueSize() int {
+	return s.sendBuf.GetSize()
+}
+
+// QueueCapacity returns the capacity of the send queue
+func (s *BufferedStream) QueueCapacity() int {
+	return s.sendBuf.Capacity()
+}
+
 // TODO: Read implements the io.Reader
 func (s *BufferedStream) Read(bufferPool chan []byte) ([]byte, int, error) {
 	var err error
@@ -14,6 +14,12 @@ type BackupSnapshotArgs struct {
 	Config  string `json:"config"`
 	Height  uint64 `json:"height"`
 	Version uint64 `json:"version"`
+
+	// Options of the chunked snapshot (version 5)
+	Compression string `json:"compression"`
+	ChunkSize   uint64 `json:"chunk_size"`
+	Base        string `json:"base"`
+	Resume      bool   `json:"resume"`
 }
 
 type BackupSnapshotResult struct {
@@ -23,7 +29,11 @@ type BackupSnapshotResult struct {
 func (t *ScriptRPCService) BackupSnapshot(args *BackupSnapshotArgs, result *BackupSnapshotResult) error {
 	// Default to older verison
 	if args.Version == 0 {
-		args.Version = 2
+		if args.Compression != "" || args.ChunkSize != 0 || args.Base != "" || args.Resume {
+			args.Version = 5
+		} else {
+			args.Version = 2
+		}
 	}
 
 	db := t.ledger.State().DB()
@@ -43,6 +53,15 @@ func (t *ScriptRPCService) BackupSnapshot(args *BackupSnapshotArgs, result *Back
 		snapshotFile, err := snapshot.ExportSnapshotV3(db, consensus, chain, snapshotDir, args.Height)
 		result.SnapshotFile = snapshotFile
 		return err
+	} else if args.Version == 5 {
+		snapshotFile, err := snapshot.ExportSnapshotV5(db, consensus, chain, snapshotDir, args.Height, &snapshot.ExportOptions{
+			Compression: args.Compression,
+			ChunkSize:   args.ChunkSize,
+			Base:        args.Base,
+			Resume:      args.Resume,
+		})
+		result.SnapshotFile = snapshotFile
+		return err
 	}
 
 	snapshotFile, err := snapshot.ExportSnapshotV4(db, consensus, chain, snapshotDir, args.Height)
@@ -82,6 +101,39 @@ func (t *ScriptRPCService) BackupChain(args *BackupChainArgs, result *BackupChai
 	return err
 }
 
+// ------------------------------- BackupChainArchive -----------------------------------
+
+type BackupChainArchiveArgs struct {
+	Start            uint64 `json:"start"`
+	End              uint64 `json:"end"`
+	Config           string `json:"config"`
+	Compression      string `json:"compression"`
+	BlocksPerSegment uint64 `json:"blocks_per_segment"`
+}
+
+type BackupChainArchiveResult struct {
+	ActualStartHeight uint64 `json:"actual_start_height"`
+	ActualEndHeight   uint64 `json:"actual_end_height"`
+	ArchiveDir        string `json:"archive_dir"`
+}
+
+func (t *ScriptRPCService) BackupChainArchive(args *BackupChainArchiveArgs, result *BackupChainArchiveResult) error {
+	backupDir := path.Join(args.Config, "backup", "archive")
+	if _, err := os.Stat(backupDir); os.IsNotExist(err) {
+		os.MkdirAll(backupDir, os.ModePerm)
+	}
+
+	actualStartHeight, actualEndHeight, archiveDir, err := snapshot.ExportChainArchive(t.chain, args.Start, args.End, backupDir, &snapshot.ArchiveOptions{
+		Compression:      args.Compression,
+		BlocksPerSegment: args.BlocksPerSegment,
+	})
+	result.ActualStartHeight = actualStartHeight
+	result.ActualEndHeight = actualEndHeight
+	result.ArchiveDir = archiveDir
+
+	return err
+}
+
 // ------------------------------- BackupChainCorrection -----------------------------------
 
 type BackupChainCorrectionArgs struct {
@@ -47,8 +47,13 @@ func (t *ScriptRPCService) CallSmartContract(args *CallSmartContractArgs, result
 	if err != nil {
 		return fmt.Errorf("Failed to parse SmartContractTx, error: %v", err)
 	}
-	sctx, ok := tx.(*types.SmartContractTx)
-	if !ok {
+	var sctx *types.SmartContractTx
+	switch ctx := tx.(type) {
+	case *types.SmartContractTx:
+		sctx = ctx
+	case *types.SmartContractTxV2:
+		sctx = ctx.SmartContractTx(ctx.EffectiveGasPrice(ledgerState.GetBaseFee()))
+	default:
 		return fmt.Errorf("Failed to parse SmartContractTx: %v", args.SctxBytes)
 	}
 
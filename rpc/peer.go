@@ -0,0 +1,104 @@
+package rpc
+
+import (
+	"errors"
+
+	"github.com/scripttoken/script/p2p/permission"
+	"github.com/scripttoken/script/p2p/reputation"
+	p2ptypes "github.com/scripttoken/script/p2p/types"
+)
+
+// ------------------------------- GetNetworkInfo -----------------------------------
+
+type GetNetworkInfoArgs struct {
+	SkipEdgeNode bool `json:"skip_edge_node"`
+}
+
+type GetNetworkInfoResult struct {
+	NumPeers int `json:"num_peers"`
+	*p2ptypes.NetworkStats
+}
+
+func (t *ScriptRPCService) GetNetworkInfo(args *GetNetworkInfoArgs, result *GetNetworkInfoResult) (err error) {
+	result.NetworkStats = t.dispatcher.NetworkStats(args.SkipEdgeNode)
+	result.NumPeers = len(result.NetworkStats.Peers)
+	return nil
+}
+
+// ------------------------------- GetPeerBans -----------------------------------
+
+type GetPeerBansArgs struct{}
+
+type GetPeerBansResult struct {
+	Bans []reputation.BanInfo `json:"bans"`
+}
+
+func (t *ScriptRPCService) GetPeerBans(args *GetPeerBansArgs, result *GetPeerBansResult) (err error) {
+	rm := t.dispatcher.ReputationManager()
+	if rm == nil {
+		return errors.New("Peer reputation is not enabled")
+	}
+
+	result.Bans = rm.Bans()
+	return nil
+}
+
+// ------------------------------- ClearPeerBans -----------------------------------
+
+type ClearPeerBansArgs struct {
+	PeerID string `json:"peer_id"` // clears all the bans if empty
+}
+
+type ClearPeerBansResult struct {
+	NumCleared int `json:"num_cleared"`
+}
+
+func (t *ScriptRPCService) ClearPeerBans(args *ClearPeerBansArgs, result *ClearPeerBansResult) (err error) {
+	rm := t.dispatcher.ReputationManager()
+	if rm == nil {
+		return errors.New("Peer reputation is not enabled")
+	}
+
+	if args.PeerID == "" {
+		result.NumCleared = rm.ClearBans()
+	} else if rm.Unban(args.PeerID) {
+		result.NumCleared = 1
+	}
+	return nil
+}
+
+// ------------------------------- GetPeerAllowlist -----------------------------------
+
+type GetPeerAllowlistArgs struct{}
+
+type GetPeerAllowlistResult struct {
+	Peers []permission.AllowedPeer `json:"peers"`
+}
+
+func (t *ScriptRPCService) GetPeerAllowlist(args *GetPeerAllowlistArgs, result *GetPeerAllowlistResult) (err error) {
+	al := t.dispatcher.Allowlist()
+	if al == nil {
+		return errors.New("Permissioned mode is not enabled")
+	}
+
+	result.Peers = al.Peers()
+	return nil
+}
+
+// ------------------------------- ReloadPeerAllowlist -----------------------------------
+
+type ReloadPeerAllowlistArgs struct{}
+
+type ReloadPeerAllowlistResult struct {
+	NumPeers int `json:"num_peers"`
+}
+
+func (t *ScriptRPCService) ReloadPeerAllowlist(args *ReloadPeerAllowlistArgs, result *ReloadPeerAllowlistResult) (err error) {
+	al := t.dispatcher.Allowlist()
+	if al == nil {
+		return errors.New("Permissioned mode is not enabled")
+	}
+
+	result.NumPeers, err = al.Reload()
+	return err
+}
@@ -16,11 +16,13 @@ import (
 	"github.com/scripttoken/script/crypto/bls"
 
 	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/consensus"
 	"github.com/scripttoken/script/core"
 	"github.com/scripttoken/script/crypto"
 	"github.com/scripttoken/script/ledger/state"
 	"github.com/scripttoken/script/ledger/types"
 	"github.com/scripttoken/script/mempool"
+	"github.com/scripttoken/script/netsync"
 	"github.com/scripttoken/script/version"
 )
 
@@ -83,6 +85,9 @@ func (t *ScriptRPCService) GetAccount(args *GetAccountArgs, result *GetAccountRe
 
 		result.Account = account
 	} else {
+		if err := t.checkStateAvailable(height); err != nil {
+			return err
+		}
 		blocks := t.chain.FindBlocksByHeight(height)
 		if len(blocks) == 0 {
 			result.Account = nil
@@ -100,7 +105,7 @@ func (t *ScriptRPCService) GetAccount(args *GetAccountArgs, result *GetAccountRe
 				stateRoot := b.StateHash
 				ledgerState := state.NewStoreView(height, stateRoot, db)
 				if ledgerState == nil { // might have been pruned
-					return fmt.Errorf("the account details for height %v is not available, it might have been pruned", height)
+					return t.stateNotAvailableError(height)
 				}
 				account := ledgerState.GetAccount(address)
 				if account == nil {
@@ -144,6 +149,32 @@ func (t *ScriptRPCService) GetSplitRule(args *GetSplitRuleArgs, result *GetSplit
 	return nil
 }
 
+// ------------------------------- GetAsset -----------------------------------
+
+type GetAssetArgs struct {
+	AssetID string `json:"asset_id"`
+}
+
+type GetAssetResult struct {
+	*types.Asset
+}
+
+func (t *ScriptRPCService) GetAsset(args *GetAssetArgs, result *GetAssetResult) (err error) {
+	if args.AssetID == "" {
+		return errors.New("Asset ID must be specified")
+	}
+	assetID := common.HexToAddress(args.AssetID)
+	ledgerState, err := t.ledger.GetFinalizedSnapshot()
+	if err != nil {
+		return err
+	}
+	result.Asset = ledgerState.GetAsset(assetID)
+	if result.Asset == nil {
+		return fmt.Errorf("Asset %v is not found", assetID.Hex())
+	}
+	return nil
+}
+
 // ------------------------------ GetTransaction -----------------------------------
 
 type GetTransactionArgs struct {
@@ -208,7 +239,7 @@ func (t *ScriptRPCService) GetTransaction(args *GetTransactionArgs, result *GetT
 
 	// args.Hash maybe an ETH tx hash, need to lookup the receipt using the hash of the corresponding native Smart contract Tx
 	canonicalTxHash := hash
-	if result.Type == TxTypeSmartContract {
+	if result.Type == TxTypeSmartContract || result.Type == TxTypeSmartContractV2 {
 		canonicalTxHash = crypto.Keccak256Hash(raw)
 	}
 	result.TxHash = canonicalTxHash
@@ -365,6 +396,9 @@ type GetBlockResultInner struct {
 
 	Hash common.Hash   `json:"hash"`
 	Txs  []interface{} `json:"transactions"` // for backward conpatibility, see function ScriptRPCService.gatherTxs()
+
+	GasUsed  common.JSONUint64 `json:"gas_used"`
+	GasLimit common.JSONUint64 `json:"gas_limit"` // 0 if the gas of the block is not limited
 }
 
 type TxType byte
@@ -382,6 +416,8 @@ const (
 	TxTypeWithdrawStake
 	TxTypeDepositStakeTxV2
 	TxTypeStakeRewardDistributionTx
+	TxTypeSmartContractV2
+	TxTypeCreateAsset
 )
 
 func (t *ScriptRPCService) GetBlock(args *GetBlockArgs, result *GetBlockResult) (err error) {
@@ -410,7 +446,7 @@ func (t *ScriptRPCService) GetBlock(args *GetBlockArgs, result *GetBlockResult)
 
 	result.Hash = block.Hash()
 
-	t.gatherTxs(block, &result.Txs, args.IncludeEthTxHashes)
+	t.gatherTxs(block, result.GetBlockResultInner, args.IncludeEthTxHashes)
 
 	return
 }
@@ -481,7 +517,7 @@ func (t *ScriptRPCService) GetBlockByHeight(args *GetBlockByHeightArgs, result *
 
 	result.Hash = block.Hash()
 
-	t.gatherTxs(block, &result.Txs, args.IncludeEthTxHashes)
+	t.gatherTxs(block, result.GetBlockResultInner, args.IncludeEthTxHashes)
 
 	return
 }
@@ -590,7 +626,7 @@ func (t *ScriptRPCService) GetBlocksByRange(args *GetBlocksByRangeArgs, result *
 
 		blkInner.Hash = block.Hash()
 
-		t.gatherTxs(block, &blkInner.Txs, args.IncludeEthTxHashes)
+		t.gatherTxs(block, blkInner, args.IncludeEthTxHashes)
 
 		*result = append([]*GetBlockResultInner{blkInner}, *result...)
 
@@ -611,23 +647,26 @@ func (t *ScriptRPCService) GetBlocksByRange(args *GetBlocksByRangeArgs, result *
 type GetStatusArgs struct{}
 
 type GetStatusResult struct {
-	Address                    string            `json:"address"`
-	ChainID                    string            `json:"chain_id"`
-	EthChainID                 int64             `json:"eth_chain_id"`
-	PeerID                     string            `json:"peer_id"`
-	LatestFinalizedBlockHash   common.Hash       `json:"latest_finalized_block_hash"`
-	LatestFinalizedBlockHeight common.JSONUint64 `json:"latest_finalized_block_height"`
-	LatestFinalizedBlockTime   *common.JSONBig   `json:"latest_finalized_block_time"`
-	LatestFinalizedBlockEpoch  common.JSONUint64 `json:"latest_finalized_block_epoch"`
-	CurrentEpoch               common.JSONUint64 `json:"current_epoch"`
-	CurrentHeight              common.JSONUint64 `json:"current_height"`
-	CurrentHash                common.Hash       `json:"current_hash"`
-	CurrentTime                *common.JSONBig   `json:"current_time"`
-	Syncing                    bool              `json:"syncing"`
-	TipHash                    common.Hash       `json:"tip_hash"`
-	GenesisBlockHash           common.Hash       `json:"genesis_block_hash"`
-	SnapshotBlockHeight        common.JSONUint64 `json:"snapshot_block_height"`
-	SnapshotBlockHash          common.Hash       `json:"snapshot_block_hash"`
+	Address                    string                `json:"address"`
+	ChainID                    string                `json:"chain_id"`
+	EthChainID                 int64                 `json:"eth_chain_id"`
+	PeerID                     string                `json:"peer_id"`
+	LatestFinalizedBlockHash   common.Hash           `json:"latest_finalized_block_hash"`
+	LatestFinalizedBlockHeight common.JSONUint64     `json:"latest_finalized_block_height"`
+	LatestFinalizedBlockTime   *common.JSONBig       `json:"latest_finalized_block_time"`
+	LatestFinalizedBlockEpoch  common.JSONUint64     `json:"latest_finalized_block_epoch"`
+	CurrentEpoch               common.JSONUint64     `json:"current_epoch"`
+	CurrentHeight              common.JSONUint64     `json:"current_height"`
+	CurrentHash                common.Hash           `json:"current_hash"`
+	CurrentTime                *common.JSONBig       `json:"current_time"`
+	Syncing                    bool                  `json:"syncing"`
+	TipHash                    common.Hash           `json:"tip_hash"`
+	GenesisBlockHash           common.Hash           `json:"genesis_block_hash"`
+	SnapshotBlockHeight        common.JSONUint64     `json:"snapshot_block_height"`
+	SnapshotBlockHash          common.Hash           `json:"snapshot_block_hash"`
+	StorageMode                string                `json:"storage_mode"`
+	OldestStateHeight          common.JSONUint64     `json:"oldest_state_height"`
+	SyncProgress               *netsync.SyncProgress `json:"sync_progress,omitempty"`
 }
 
 func (t *ScriptRPCService) GetStatus(args *GetStatusArgs, result *GetStatusResult) (err error) {
@@ -682,6 +721,11 @@ func (t *ScriptRPCService) GetStatus(args *GetStatusArgs, result *GetStatusResul
 	result.GenesisBlockHash = genesisHash
 	result.SnapshotBlockHeight = common.JSONUint64(t.chain.Root().Block.BlockHeader.Height)
 	result.SnapshotBlockHash = t.chain.Root().Block.BlockHeader.Hash()
+	result.StorageMode = viper.GetString(common.CfgStorageMode)
+	result.OldestStateHeight = common.JSONUint64(t.ledger.GetOldestStateHeight())
+	if t.syncMgr != nil {
+		result.SyncProgress = t.syncMgr.Progress()
+	}
 
 	return
 }
@@ -753,6 +797,9 @@ func (t *ScriptRPCService) GetVcpByHeight(args *GetVcpByHeightArgs, result *GetV
 
 	db := deliveredView.GetDB()
 	height := uint64(args.Height)
+	if err := t.checkStateAvailable(height); err != nil {
+		return err
+	}
 
 	blockHashVcpPairs := []BlockHashVcpPair{}
 	blocks := t.chain.FindBlocksByHeight(height)
@@ -761,7 +808,7 @@ func (t *ScriptRPCService) GetVcpByHeight(args *GetVcpByHeightArgs, result *GetV
 		stateRoot := b.StateHash
 		blockStoreView := state.NewStoreView(height, stateRoot, db)
 		if blockStoreView == nil { // might have been pruned
-			return fmt.Errorf("the VCP for height %v does not exists, it might have been pruned", height)
+			return t.stateNotAvailableError(height)
 		}
 		vcp := blockStoreView.GetValidatorCandidatePool()
 		hl := blockStoreView.GetStakeTransactionHeightList()
@@ -800,6 +847,9 @@ func (t *ScriptRPCService) GetGcpByHeight(args *GetGcpByHeightArgs, result *GetG
 
 	db := deliveredView.GetDB()
 	height := uint64(args.Height)
+	if err := t.checkStateAvailable(height); err != nil {
+		return err
+	}
 
 	blockHashGcpPairs := []BlockHashGcpPair{}
 	blocks := t.chain.FindBlocksByHeight(height)
@@ -808,7 +858,7 @@ func (t *ScriptRPCService) GetGcpByHeight(args *GetGcpByHeightArgs, result *GetG
 		stateRoot := b.StateHash
 		blockStoreView := state.NewStoreView(height, stateRoot, db)
 		if blockStoreView == nil { // might have been pruned
-			return fmt.Errorf("the GCP for height %v does not exists, it might have been pruned", height)
+			return t.stateNotAvailableError(height)
 		}
 		gcp := blockStoreView.GetLightningCandidatePool()
 		blockHashGcpPairs = append(blockHashGcpPairs, BlockHashGcpPair{
@@ -855,6 +905,19 @@ func (t *ScriptRPCService) GetLightningInfo(args *GetLightningInfoArgs, result *
 	return nil
 }
 
+// ------------------------------ GetVoteGossipStats -----------------------------------
+
+type GetVoteGossipStatsArgs struct{}
+
+type GetVoteGossipStatsResult struct {
+	consensus.VoteGossipStats
+}
+
+func (t *ScriptRPCService) GetVoteGossipStats(args *GetVoteGossipStatsArgs, result *GetVoteGossipStatsResult) (err error) {
+	result.VoteGossipStats = t.consensus.VoteGossip().Stats()
+	return nil
+}
+
 // ------------------------------ GetEenp -----------------------------------
 
 type GetEenpByHeightArgs struct {
@@ -878,6 +941,9 @@ func (t *ScriptRPCService) GetEenpByHeight(args *GetEenpByHeightArgs, result *Ge
 
 	db := deliveredView.GetDB()
 	height := uint64(args.Height)
+	if err := t.checkStateAvailable(height); err != nil {
+		return err
+	}
 
 	blockHashEenpPairs := []BlockHashEenpPair{}
 	b := t.chain.FindBestBlockByHeight(height)
@@ -886,7 +952,7 @@ func (t *ScriptRPCService) GetEenpByHeight(args *GetEenpByHeightArgs, result *Ge
 		stateRoot := b.StateHash
 		blockStoreView := state.NewStoreView(height, stateRoot, db)
 		if blockStoreView == nil { // might have been pruned
-			return fmt.Errorf("the EENP for height %v does not exists, it might have been pruned", height)
+			return t.stateNotAvailableError(height)
 		}
 		eenp := state.NewEliteEdgeNodePool(blockStoreView, true)
 		eens := eenp.GetAll(false)
@@ -922,6 +988,9 @@ func (t *ScriptRPCService) GetEenpStakeByHeight(args *GetEenpStakeByHeightArgs,
 
 	db := deliveredView.GetDB()
 	height := uint64(args.Height)
+	if err := t.checkStateAvailable(height); err != nil {
+		return err
+	}
 
 	var stake *core.Stake
 	b := t.chain.FindBestBlockByHeight(height)
@@ -932,7 +1001,7 @@ func (t *ScriptRPCService) GetEenpStakeByHeight(args *GetEenpStakeByHeightArgs,
 	stateRoot := b.StateHash
 	blockStoreView := state.NewStoreView(height, stateRoot, db)
 	if blockStoreView == nil { // might have been pruned
-		return fmt.Errorf("the EENP for height %v does not exists, it might have been pruned", height)
+		return t.stateNotAvailableError(height)
 	}
 	eenp := state.NewEliteEdgeNodePool(blockStoreView, true)
 	stake, err = eenp.GetStake(args.Source, args.Holder, args.WithdrawnOnly)
@@ -971,6 +1040,9 @@ func (t *ScriptRPCService) GetStakeRewardDistributionByHeight(
 	db := deliveredView.GetDB()
 	height := uint64(args.Height)
 	addressStr := args.Address
+	if err := t.checkStateAvailable(height); err != nil {
+		return err
+	}
 
 	blockHashSrdrsPairs := []BlockHashStakeRewardDistributionRuleSetPair{}
 	blocks := t.chain.FindBlocksByHeight(height)
@@ -979,7 +1051,7 @@ func (t *ScriptRPCService) GetStakeRewardDistributionByHeight(
 		stateRoot := b.StateHash
 		blockStoreView := state.NewStoreView(height, stateRoot, db)
 		if blockStoreView == nil { // might have been pruned
-			return fmt.Errorf("the EENP for height %v does not exists, it might have been pruned", height)
+			return t.stateNotAvailableError(height)
 		}
 		srdrs := state.NewStakeRewardDistributionRuleSet(blockStoreView)
 
@@ -1100,6 +1172,9 @@ func (t *ScriptRPCService) GetCode(args *GetCodeArgs, result *GetCodeResult) (er
 		codeBytes := ledgerState.GetCode(address)
 		result.Code = hex.EncodeToString(codeBytes)
 	} else {
+		if err := t.checkStateAvailable(height); err != nil {
+			return err
+		}
 		blocks := t.chain.FindBlocksByHeight(height)
 		if len(blocks) == 0 {
 			result.Code = ""
@@ -1118,7 +1193,7 @@ func (t *ScriptRPCService) GetCode(args *GetCodeArgs, result *GetCodeResult) (er
 				stateRoot := b.StateHash
 				ledgerState := state.NewStoreView(height, stateRoot, db)
 				if ledgerState == nil { // might have been pruned
-					return fmt.Errorf("the account details for height %v is not available, it might have been pruned", height)
+					return t.stateNotAvailableError(height)
 				}
 				codeBytes := ledgerState.GetCode(address)
 				result.Code = hex.EncodeToString(codeBytes)
@@ -1160,6 +1235,9 @@ func (t *ScriptRPCService) GetStorageAt(args *GetStorageAtArgs, result *GetStora
 		value := ledgerState.GetState(address, key)
 		result.Value = hex.EncodeToString(value.Bytes())
 	} else {
+		if err := t.checkStateAvailable(height); err != nil {
+			return err
+		}
 		blocks := t.chain.FindBlocksByHeight(height)
 		if len(blocks) == 0 {
 			result.Value = ""
@@ -1177,7 +1255,7 @@ func (t *ScriptRPCService) GetStorageAt(args *GetStorageAtArgs, result *GetStora
 				stateRoot := b.StateHash
 				ledgerState := state.NewStoreView(height, stateRoot, db)
 				if ledgerState == nil { // might have been pruned
-					return fmt.Errorf("the account details for height %v is not available, it might have been pruned", height)
+					return t.stateNotAvailableError(height)
 				}
 				value := ledgerState.GetState(address, key)
 				result.Value = hex.EncodeToString(value.Bytes())
@@ -1189,9 +1267,183 @@ func (t *ScriptRPCService) GetStorageAt(args *GetStorageAtArgs, result *GetStora
 	return nil
 }
 
+// ------------------------------- GetLogs -----------------------------------
+
+type GetLogsArgs struct {
+	FromHeight common.JSONUint64 `json:"from_height"`
+	ToHeight   common.JSONUint64 `json:"to_height"`
+	Addresses  []string          `json:"addresses"`
+	Topics     [][]string        `json:"topics"`    // topics[i] lists the alternatives for the topic at position i
+	Page       common.JSONUint64 `json:"page"`      // pages are numbered from 0
+	PageSize   common.JSONUint64 `json:"page_size"` // 100 if not specified
+}
+
+type GetLogsResult struct {
+	Logs []*LogResult `json:"logs"`
+}
+
+type LogResult struct {
+	BlockHash   common.Hash       `json:"block_hash"`
+	BlockHeight common.JSONUint64 `json:"block_height"`
+	TxHash      common.Hash       `json:"tx_hash"`
+	TxIndex     common.JSONUint64 `json:"tx_index"`
+	LogIndex    common.JSONUint64 `json:"log_index"`
+	Log         *types.Log        `json:"log"`
+}
+
+// GetLogs returns a page of the logs of the finalized blocks in the height range matching the addresses
+// and topics, from the oldest one.
+func (t *ScriptRPCService) GetLogs(args *GetLogsArgs, result *GetLogsResult) (err error) {
+	if !viper.GetBool(common.CfgStorageIndexLogs) {
+		return errors.New("the log index is not enabled")
+	}
+	if args.FromHeight > args.ToHeight {
+		return errors.New("from height must not be greater than to height")
+	}
+	maxBlockRange := common.JSONUint64(100000)
+	if args.ToHeight-args.FromHeight > maxBlockRange {
+		return fmt.Errorf("can't query the logs of more than %v blocks at a time", maxBlockRange)
+	}
+	pageSize := args.PageSize
+	if pageSize == 0 {
+		pageSize = 100
+	}
+	maxPageSize := common.JSONUint64(1000)
+	if pageSize > maxPageSize {
+		return fmt.Errorf("page size can't be greater than %v", maxPageSize)
+	}
+
+	filter := &blockchain.LogFilter{
+		FromHeight: uint64(args.FromHeight),
+		ToHeight:   uint64(args.ToHeight),
+	}
+	for _, addr := range args.Addresses {
+		filter.Addresses = append(filter.Addresses, common.HexToAddress(addr))
+	}
+	for _, topics := range args.Topics {
+		hashes := []common.Hash{}
+		for _, topic := range topics {
+			hashes = append(hashes, common.HexToHash(topic))
+		}
+		filter.Topics = append(filter.Topics, hashes)
+	}
+
+	logs, err := t.chain.FindLogs(filter, uint64(args.Page), uint64(pageSize))
+	if err != nil {
+		return err
+	}
+	result.Logs = []*LogResult{}
+	for _, log := range logs {
+		result.Logs = append(result.Logs, &LogResult{
+			BlockHash:   log.BlockHash,
+			BlockHeight: common.JSONUint64(log.BlockHeight),
+			TxHash:      log.TxHash,
+			TxIndex:     common.JSONUint64(log.TxIndex),
+			LogIndex:    common.JSONUint64(log.LogIndex),
+			Log:         log.Log,
+		})
+	}
+	return nil
+}
+
+// ------------------------------- GetAccountTransactions -----------------------------------
+
+type GetAccountTransactionsArgs struct {
+	Address    string            `json:"address"`
+	FromHeight common.JSONUint64 `json:"from_height"`
+	ToHeight   common.JSONUint64 `json:"to_height"`
+	Page       common.JSONUint64 `json:"page"`      // pages are numbered from 0
+	PageSize   common.JSONUint64 `json:"page_size"` // 100 if not specified
+}
+
+type GetAccountTransactionsResult struct {
+	Txs []*AccountTxResult `json:"txs"`
+}
+
+type AccountTxResult struct {
+	BlockHash   common.Hash       `json:"block_hash"`
+	BlockHeight common.JSONUint64 `json:"block_height"`
+	TxIndex     common.JSONUint64 `json:"tx_index"`
+	TxHash      common.Hash       `json:"hash"`
+	Type        byte              `json:"type"`
+	Tx          types.Tx          `json:"transaction"`
+}
+
+// GetAccountTransactions returns the finalized transactions touching the account in the height range,
+// from the most recent one.
+func (t *ScriptRPCService) GetAccountTransactions(args *GetAccountTransactionsArgs, result *GetAccountTransactionsResult) (err error) {
+	if !viper.GetBool(common.CfgStorageIndexAccountTxs) {
+		return errors.New("the account transaction index is not enabled")
+	}
+	if args.Address == "" {
+		return errors.New("Address must be specified")
+	}
+	if args.FromHeight > args.ToHeight {
+		return errors.New("from height must not be greater than to height")
+	}
+	maxBlockRange := common.JSONUint64(100000)
+	if args.ToHeight-args.FromHeight > maxBlockRange {
+		return fmt.Errorf("can't query the transactions of more than %v blocks at a time", maxBlockRange)
+	}
+	pageSize := args.PageSize
+	if pageSize == 0 {
+		pageSize = 100
+	}
+	maxPageSize := common.JSONUint64(1000)
+	if pageSize > maxPageSize {
+		return fmt.Errorf("page size can't be greater than %v", maxPageSize)
+	}
+
+	address := common.HexToAddress(args.Address)
+	entries, err := t.chain.FindAccountTxs(address, uint64(args.FromHeight), uint64(args.ToHeight), uint64(args.Page), uint64(pageSize))
+	if err != nil {
+		return err
+	}
+	result.Txs = []*AccountTxResult{}
+	for _, entry := range entries {
+		block, err := t.chain.FindBlock(entry.BlockHash)
+		if err != nil {
+			return fmt.Errorf("failed to load block %v: %v", entry.BlockHash.Hex(), err)
+		}
+		if entry.Index >= uint64(len(block.Txs)) {
+			return fmt.Errorf("transaction %v not found in block %v", entry.Index, entry.BlockHash.Hex())
+		}
+		raw := block.Txs[entry.Index]
+		tx, err := types.TxFromBytes(raw)
+		if err != nil {
+			return err
+		}
+		result.Txs = append(result.Txs, &AccountTxResult{
+			BlockHash:   entry.BlockHash,
+			BlockHeight: common.JSONUint64(entry.BlockHeight),
+			TxIndex:     common.JSONUint64(entry.Index),
+			TxHash:      crypto.Keccak256Hash(raw),
+			Type:        getTxType(tx),
+			Tx:          tx,
+		})
+	}
+	return nil
+}
+
 // ------------------------------ Utils ------------------------------
 
-func (t *ScriptRPCService) gatherTxs(block *core.ExtendedBlock, txs *[]interface{}, includeEthTxHashes bool) error {
+// checkStateAvailable returns an error if the state at the given height is no longer retained by the node
+func (t *ScriptRPCService) checkStateAvailable(height uint64) error {
+	if !t.ledger.IsStateAvailable(height) {
+		return t.stateNotAvailableError(height)
+	}
+	return nil
+}
+
+func (t *ScriptRPCService) stateNotAvailableError(height uint64) error {
+	return fmt.Errorf("state not available at height %v, the oldest available state height is %v",
+		height, t.ledger.GetOldestStateHeight())
+}
+
+func (t *ScriptRPCService) gatherTxs(block *core.ExtendedBlock, result *GetBlockResultInner, includeEthTxHashes bool) error {
+	txs := &result.Txs
+	result.GasLimit = common.JSONUint64(types.GetBlockGasLimit(block.Height))
+
 	// Parse and fulfill Txs.
 	//var tx types.Tx
 	for _, txBytes := range block.Txs {
@@ -1204,6 +1456,8 @@ func (t *ScriptRPCService) gatherTxs(block *core.ExtendedBlock, txs *[]interface
 		receipt, found := t.chain.FindTxReceiptByHash(blockHash, hash)
 		if !found {
 			receipt = nil
+		} else {
+			result.GasUsed += common.JSONUint64(receipt.GasUsed)
 		}
 		balanceChanges, found := t.chain.FindTxBalanceChangesByHash(blockHash, hash)
 		if !found {
@@ -1266,6 +1520,10 @@ func getTxType(tx types.Tx) byte {
 		t = TxTypeDepositStakeTxV2
 	case *types.StakeRewardDistributionTx:
 		t = TxTypeStakeRewardDistributionTx
+	case *types.SmartContractTxV2:
+		t = TxTypeSmartContractV2
+	case *types.CreateAssetTx:
+		t = TxTypeCreateAsset
 	}
 
 	return t
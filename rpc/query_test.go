@@ -0,0 +1,59 @@
+package rpc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/scripttoken/script/blockchain"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/ledger/types"
+	"github.com/scripttoken/script/store/database/backend"
+	"github.com/scripttoken/script/store/kvstore"
+)
+
+func TestGetBlockGas(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	chainID := "test_chain_id"
+	root := &core.Block{BlockHeader: &core.BlockHeader{ChainID: chainID, Height: common.HeightEnableBlockGasLimit - 1}}
+	chain := blockchain.NewChain(chainID, kvstore.NewKVStore(backend.NewMemDatabase()), root)
+	service := &ScriptRPCService{chain: chain}
+
+	block := core.NewBlock()
+	block.ChainID = chainID
+	block.Height = common.HeightEnableBlockGasLimit
+	block.Parent = root.Hash()
+	acc := types.MakeAcc("rpc_test")
+	gasUsed := []uint64{21000, 50000}
+	for idx, gas := range gasUsed {
+		scTx := &types.SmartContractTx{
+			From:     types.TxInput{Address: acc.Address, Sequence: uint64(idx + 1)},
+			GasLimit: 100000,
+			GasPrice: big.NewInt(1e8),
+		}
+		scTx.From.Signature = acc.Sign(scTx.SignBytes(chainID))
+		raw, err := types.TxToBytes(scTx)
+		require.Nil(err)
+		block.Txs = append(block.Txs, raw)
+		chain.AddTxReceipt(block, scTx, nil, nil, nil, common.Address{}, gas, nil)
+	}
+	_, err := chain.AddBlock(block)
+	require.Nil(err)
+
+	// The gas used sums up the receipts of the block transactions
+	res := &GetBlockResult{}
+	require.Nil(service.GetBlock(&GetBlockArgs{Hash: block.Hash()}, res))
+	assert.Equal(2, len(res.Txs))
+	assert.Equal(common.JSONUint64(71000), res.GasUsed)
+	assert.Equal(common.JSONUint64(types.BlockGasLimit), res.GasLimit)
+
+	// The gas of the blocks before the block gas limit is enabled is not limited
+	res = &GetBlockResult{}
+	require.Nil(service.GetBlock(&GetBlockArgs{Hash: root.Hash()}, res))
+	assert.Equal(common.JSONUint64(0), res.GasUsed)
+	assert.Equal(common.JSONUint64(0), res.GasLimit)
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/scripttoken/script/common/timer"
 	"github.com/scripttoken/script/common/util"
 	"github.com/scripttoken/script/consensus"
+	"github.com/scripttoken/script/netsync"
 	"github.com/scripttoken/script/dispatcher"
 	"github.com/scripttoken/script/ledger"
 	"github.com/scripttoken/script/mempool"
@@ -39,6 +40,7 @@ type ScriptRPCService struct {
 	dispatcher *dispatcher.Dispatcher
 	chain      *blockchain.Chain
 	consensus  *consensus.ConsensusEngine
+	syncMgr    *netsync.SyncManager
 
 	pendingHeavyGetBlocksCounter           uint64
 	pendingHeavyGetBlocksCounterLock       *sync.Mutex
@@ -103,6 +105,11 @@ func NewScriptRPCServer(mempool *mempool.Mempool, ledger *ledger.Ledger, dispatc
 	return t
 }
 
+// SetSyncManager sets the sync manager reporting the block download progress
+func (t *ScriptRPCServer) SetSyncManager(syncMgr *netsync.SyncManager) {
+	t.syncMgr = syncMgr
+}
+
 // Start creates the main goroutine.
 func (t *ScriptRPCServer) Start(ctx context.Context) {
 	c, cancel := context.WithCancel(ctx)
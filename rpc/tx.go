@@ -271,8 +271,7 @@ func translateEthTx(ethTxStr string) (string, error) {
 		return "", err
 	}
 
-	logger.Debugf("Recovered from address: %v, signature: %v",
-		scriptSmartContractTx.From.Address.Hex(), scriptSmartContractTx.From.Signature.ToBytes().String())
+	logger.Debugf("Translated ETH transaction: %v", scriptSmartContractTx)
 
 	raw, err := types.TxToBytes(scriptSmartContractTx)
 	if err != nil {
@@ -0,0 +1,193 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+)
+
+// ArchiveIndexFileName is the name of the index file in a block archive directory
+const ArchiveIndexFileName = "index.json"
+
+// ArchiveMagic identifies a block archive
+const ArchiveMagic = "ScriptBlockArchive"
+
+// ArchiveVersion is the current version of the block archive format
+const ArchiveVersion = 1
+
+// DefaultBlocksPerSegment is the default number of blocks in an archive segment
+const DefaultBlocksPerSegment = 10000
+
+// ArchiveSegment describes a segment file of a block archive. A segment stores
+// the finalized blocks of a height range in ascending order, each along with the
+// votes for it.
+type ArchiveSegment struct {
+	Name        string      `json:"name"`
+	StartHeight uint64      `json:"start_height"`
+	EndHeight   uint64      `json:"end_height"`
+	FirstBlock  common.Hash `json:"first_block"`
+	LastBlock   common.Hash `json:"last_block"`
+	NumBlocks   uint64      `json:"num_blocks"`
+	Hash        string      `json:"hash"` // sha256 of the segment file
+	Size        uint64      `json:"size"` // size of the segment file
+}
+
+// ArchiveIndex describes a block archive, i.e. a directory of segment files
+// covering a contiguous range of finalized blocks
+type ArchiveIndex struct {
+	Magic       string           `json:"magic"`
+	Version     uint             `json:"version"`
+	ChainID     string           `json:"chain_id"`
+	StartHeight uint64           `json:"start_height"`
+	EndHeight   uint64           `json:"end_height"`
+	Compression string           `json:"compression"`
+	Segments    []ArchiveSegment `json:"segments"`
+	Complete    bool             `json:"complete"`
+}
+
+// LoadArchiveIndex loads the index of the block archive in the given directory
+func LoadArchiveIndex(archiveDir string) (*ArchiveIndex, error) {
+	raw, err := ioutil.ReadFile(path.Join(archiveDir, ArchiveIndexFileName))
+	if err != nil {
+		return nil, err
+	}
+	index := &ArchiveIndex{}
+	if err := json.Unmarshal(raw, index); err != nil {
+		return nil, fmt.Errorf("Failed to parse archive index, %v", err)
+	}
+	if index.Magic != ArchiveMagic {
+		return nil, fmt.Errorf("Invalid archive magic: %v", index.Magic)
+	}
+	if index.Version == 0 || index.Version > ArchiveVersion {
+		return nil, fmt.Errorf("Unsupported archive version: %v", index.Version)
+	}
+	return index, nil
+}
+
+// saveArchiveIndex atomically replaces the index in the given directory
+func saveArchiveIndex(archiveDir string, index *ArchiveIndex) error {
+	raw, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := path.Join(archiveDir, ArchiveIndexFileName+".tmp")
+	if err := ioutil.WriteFile(tmpPath, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path.Join(archiveDir, ArchiveIndexFileName))
+}
+
+func segmentFileName(index int, compression string) string {
+	name := fmt.Sprintf("segment-%06d.dat", index)
+	switch compression {
+	case CompressionGzip:
+		name += ".gz"
+	case CompressionSnappy:
+		name += ".sz"
+	}
+	return name
+}
+
+// readArchiveSegment checks the hash of a segment file and calls handler for each
+// of its blocks, in ascending height order
+func readArchiveSegment(archiveDir string, index *ArchiveIndex, segment *ArchiveSegment, handler func(*core.BackupBlock) error) error {
+	segmentPath := path.Join(archiveDir, segment.Name)
+	hash, err := hashFile(segmentPath)
+	if err != nil {
+		return fmt.Errorf("Failed to read archive segment %v, %v", segment.Name, err)
+	}
+	if hash != segment.Hash {
+		return fmt.Errorf("Archive segment %v is corrupted, expected hash %v, got %v", segment.Name, segment.Hash, hash)
+	}
+
+	file, err := os.Open(segmentPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader, err := newDecompressor(file, index.Compression)
+	if err != nil {
+		return fmt.Errorf("Failed to decompress archive segment %v, %v", segment.Name, err)
+	}
+	defer reader.Close()
+
+	var count uint64
+	var prevBlock *core.ExtendedBlock
+	for {
+		backupBlock := &core.BackupBlock{}
+		_, err := core.ReadRecord(reader, backupBlock)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to read block from archive segment %v, %v", segment.Name, err)
+		}
+		block := backupBlock.Block
+		if block == nil || block.Block == nil {
+			return fmt.Errorf("Empty block in archive segment %v", segment.Name)
+		}
+		if block.ChainID != index.ChainID {
+			return fmt.Errorf("ChainID mismatch: block.ChainID(%s) != %s", block.ChainID, index.ChainID)
+		}
+		if prevBlock == nil {
+			if block.Height != segment.StartHeight || block.Hash() != segment.FirstBlock {
+				return fmt.Errorf("Archive segment %v starts with block %v at height %v", segment.Name, block.Hash().Hex(), block.Height)
+			}
+		} else if block.Height != prevBlock.Height+1 || block.Parent != prevBlock.Hash() {
+			return fmt.Errorf("Block %v at height %v is not a child of block %v", block.Hash().Hex(), block.Height, prevBlock.Hash().Hex())
+		}
+		if err := handler(backupBlock); err != nil {
+			return err
+		}
+		prevBlock = block
+		count++
+	}
+
+	if count != segment.NumBlocks || prevBlock == nil || prevBlock.Hash() != segment.LastBlock {
+		return fmt.Errorf("Archive segment %v is truncated, %v out of %v blocks", segment.Name, count, segment.NumBlocks)
+	}
+	return nil
+}
+
+// VerifyChainArchive checks the integrity of a block archive: the segment hashes,
+// and the parent links of all the blocks
+func VerifyChainArchive(archiveDir string) (*ArchiveIndex, error) {
+	index, err := LoadArchiveIndex(archiveDir)
+	if err != nil {
+		return nil, err
+	}
+	if !index.Complete {
+		return nil, fmt.Errorf("Archive %v is incomplete", archiveDir)
+	}
+	for i := range index.Segments {
+		segment := &index.Segments[i]
+		if i > 0 {
+			prev := &index.Segments[i-1]
+			if segment.StartHeight != prev.EndHeight+1 {
+				return nil, fmt.Errorf("Archive segment %v does not follow segment %v", segment.Name, prev.Name)
+			}
+		}
+		var parent common.Hash
+		if i > 0 {
+			parent = index.Segments[i-1].LastBlock
+		}
+		first := true
+		err := readArchiveSegment(archiveDir, index, segment, func(backupBlock *core.BackupBlock) error {
+			if first && i > 0 && backupBlock.Block.Parent != parent {
+				return fmt.Errorf("Archive segment %v is not chained to segment %v", segment.Name, index.Segments[i-1].Name)
+			}
+			first = false
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
@@ -0,0 +1,166 @@
+package snapshot
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/scripttoken/script/blockchain"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+)
+
+// ArchiveOptions configures the export of a block archive
+type ArchiveOptions struct {
+	Compression      string // one of CompressionNone, CompressionGzip, CompressionSnappy and CompressionZstd
+	BlocksPerSegment uint64 // DefaultBlocksPerSegment if 0
+}
+
+// ExportChainArchive exports the finalized blocks between startHeight and endHeight into
+// a block archive directory under backupDir. As with ExportChainBackup, the range starts
+// later if the blocks below are not available.
+func ExportChainArchive(chain *blockchain.Chain, startHeight, endHeight uint64, backupDir string, options *ArchiveOptions) (actualStartHeight, actualEndHeight uint64, archiveDir string, err error) {
+	if startHeight > endHeight {
+		return 0, 0, "", errors.New("start height must be <= end height")
+	}
+	if options == nil {
+		options = &ArchiveOptions{}
+	}
+	if options.Compression == "" {
+		options.Compression = CompressionNone
+	}
+	if err := checkCompression(options.Compression); err != nil {
+		return 0, 0, "", err
+	}
+	if options.BlocksPerSegment == 0 {
+		options.BlocksPerSegment = DefaultBlocksPerSegment
+	}
+
+	var finalizedBlock *core.ExtendedBlock
+	for i := endHeight; i >= startHeight && finalizedBlock == nil; i-- {
+		for _, block := range chain.FindBlocksByHeight(i) {
+			if block.Status.IsFinalized() {
+				finalizedBlock = block
+				break
+			}
+		}
+		if i == 0 {
+			break
+		}
+	}
+	if finalizedBlock == nil {
+		return 0, 0, "", fmt.Errorf("There's no finalized block between height %v and %v", startHeight, endHeight)
+	}
+
+	// Walk back the finalized chain, the blocks are then written in ascending order
+	hashes := []common.Hash{finalizedBlock.Hash()}
+	for block := finalizedBlock; block.Height > startHeight; {
+		parent, err := chain.FindBlock(block.Parent)
+		if err != nil {
+			break
+		}
+		hashes = append(hashes, parent.Hash())
+		block = parent
+	}
+	actualEndHeight = finalizedBlock.Height
+	actualStartHeight = actualEndHeight + 1 - uint64(len(hashes))
+
+	archiveDir = "script_archive-" + strconv.FormatUint(actualStartHeight, 10) + "-" + strconv.FormatUint(actualEndHeight, 10)
+	archivePath := path.Join(backupDir, archiveDir)
+	if err := os.MkdirAll(archivePath, os.ModePerm); err != nil {
+		return 0, 0, "", err
+	}
+
+	index := &ArchiveIndex{
+		Magic:       ArchiveMagic,
+		Version:     ArchiveVersion,
+		ChainID:     chain.ChainID,
+		StartHeight: actualStartHeight,
+		EndHeight:   actualEndHeight,
+		Compression: options.Compression,
+		Segments:    []ArchiveSegment{},
+	}
+	if err := saveArchiveIndex(archivePath, index); err != nil {
+		return 0, 0, "", err
+	}
+
+	for end := len(hashes); end > 0; end -= int(options.BlocksPerSegment) {
+		start := end - int(options.BlocksPerSegment)
+		if start < 0 {
+			start = 0
+		}
+		segment, err := writeArchiveSegment(chain, archivePath, len(index.Segments), options.Compression, hashes[start:end])
+		if err != nil {
+			return 0, 0, "", err
+		}
+		index.Segments = append(index.Segments, *segment)
+		if err := saveArchiveIndex(archivePath, index); err != nil {
+			return 0, 0, "", err
+		}
+	}
+
+	index.Complete = true
+	if err := saveArchiveIndex(archivePath, index); err != nil {
+		return 0, 0, "", err
+	}
+	return actualStartHeight, actualEndHeight, archiveDir, nil
+}
+
+// writeArchiveSegment writes the blocks of the given hashes, listed in descending height
+// order, into a segment file in ascending height order
+func writeArchiveSegment(chain *blockchain.Chain, archivePath string, segmentIndex int, compression string, hashes []common.Hash) (*ArchiveSegment, error) {
+	name := segmentFileName(segmentIndex, compression)
+	file, err := os.Create(path.Join(archivePath, name))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	compressor, err := newCompressor(io.MultiWriter(file, hasher), compression)
+	if err != nil {
+		return nil, err
+	}
+	writer := bufio.NewWriter(compressor)
+
+	segment := &ArchiveSegment{Name: name}
+	for i := len(hashes) - 1; i >= 0; i-- {
+		block, err := chain.FindBlock(hashes[i])
+		if err != nil {
+			return nil, fmt.Errorf("Failed to find block %v, %v", hashes[i].Hex(), err)
+		}
+		voteSet := chain.FindVotesByHash(block.Hash())
+		if err := writeBlock(writer, &core.BackupBlock{Block: block, Votes: voteSet}); err != nil {
+			return nil, err
+		}
+		if segment.NumBlocks == 0 {
+			segment.StartHeight = block.Height
+			segment.FirstBlock = block.Hash()
+		}
+		segment.EndHeight = block.Height
+		segment.LastBlock = block.Hash()
+		segment.NumBlocks++
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+	if err := compressor.Close(); err != nil {
+		return nil, err
+	}
+	if err := file.Sync(); err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	segment.Hash = hex.EncodeToString(hasher.Sum(nil))
+	segment.Size = uint64(info.Size())
+	return segment, nil
+}
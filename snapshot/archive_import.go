@@ -0,0 +1,195 @@
+package snapshot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/scripttoken/script/blockchain"
+	"github.com/scripttoken/script/consensus"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/ledger"
+)
+
+//
+// ChainImporter replays the blocks of block archives on top of the local chain. In
+// the full validation mode each block is validated as the consensus engine would,
+// i.e. the proposer, the signatures, the commit certificate and the votes are checked
+// and the transactions are executed with all their checks. In the trusted mode only
+// the chaining and the resulting state root are checked.
+//
+type ChainImporter struct {
+	chain   *blockchain.Chain
+	ledger  *ledger.Ledger
+	valMgr  core.ValidatorManager
+	state   *consensus.State
+	trusted bool
+
+	lastBlock *core.ExtendedBlock
+	imported  uint64
+	skipped   uint64
+}
+
+// NewChainImporter creates an instance of the ChainImporter
+func NewChainImporter(chain *blockchain.Chain, ledger *ledger.Ledger, valMgr core.ValidatorManager, state *consensus.State, trusted bool) *ChainImporter {
+	ledger.SetSkipSanityCheck(trusted)
+	return &ChainImporter{
+		chain:   chain,
+		ledger:  ledger,
+		valMgr:  valMgr,
+		state:   state,
+		trusted: trusted,
+	}
+}
+
+// ImportArchive verifies and imports the blocks of the given block archive. The blocks
+// already finalized locally are skipped, so an interrupted import can be resumed.
+func (ci *ChainImporter) ImportArchive(archiveDir string) error {
+	index, err := LoadArchiveIndex(archiveDir)
+	if err != nil {
+		return err
+	}
+	if !index.Complete {
+		return fmt.Errorf("Archive %v is incomplete", archiveDir)
+	}
+	if index.ChainID != ci.chain.ChainID {
+		return fmt.Errorf("ChainID mismatch: archive.ChainID(%s) != %s", index.ChainID, ci.chain.ChainID)
+	}
+	logger.Infof("Importing archive %v, height %v to %v, %v segments", archiveDir, index.StartHeight, index.EndHeight, len(index.Segments))
+
+	for i := range index.Segments {
+		segment := &index.Segments[i]
+		start := time.Now()
+		imported := ci.imported
+		err := readArchiveSegment(archiveDir, index, segment, ci.importBlock)
+		if err != nil {
+			return err
+		}
+		if err := ci.finalize(); err != nil {
+			return err
+		}
+		logger.Infof("Imported archive segment %v, height %v to %v, %v blocks in %v",
+			segment.Name, segment.StartHeight, segment.EndHeight, ci.imported-imported, time.Since(start))
+	}
+	return nil
+}
+
+// Summary returns the number of imported and skipped blocks, and the last imported block
+func (ci *ChainImporter) Summary() (imported, skipped uint64, lastBlock *core.ExtendedBlock) {
+	return ci.imported, ci.skipped, ci.lastBlock
+}
+
+func (ci *ChainImporter) importBlock(backupBlock *core.BackupBlock) error {
+	block := backupBlock.Block.Block
+	hash := block.Hash()
+
+	existing, err := ci.chain.FindBlock(hash)
+	if err == nil && (existing.Status.IsFinalized() || existing.Status.IsTrusted()) {
+		ci.skipped++
+		return nil
+	}
+
+	parent, err := ci.chain.FindBlock(block.Parent)
+	if err != nil {
+		return fmt.Errorf("Parent %v of block %v at height %v not found, import the preceding blocks first",
+			block.Parent.Hex(), hash.Hex(), block.Height)
+	}
+	if !parent.Status.IsValid() {
+		return fmt.Errorf("Parent %v of block %v is invalid", block.Parent.Hex(), hash.Hex())
+	}
+	if parent.Height+1 != block.Height {
+		return fmt.Errorf("Block %v height %v does not follow parent height %v", hash.Hex(), block.Height, parent.Height)
+	}
+	if existing == nil {
+		// Added as pending, as the sync manager does before the consensus engine processes it
+		if _, err := ci.chain.AddBlock(block); err != nil {
+			return err
+		}
+	}
+	if !ci.trusted {
+		if err := ci.validateBlock(backupBlock, parent); err != nil {
+			ci.chain.MarkBlockInvalid(hash)
+			return fmt.Errorf("Block %v at height %v is invalid, %v", hash.Hex(), block.Height, err)
+		}
+	}
+
+	if res := ci.ledger.ResetState(parent.Block); res.IsError() {
+		return fmt.Errorf("Failed to reset state to block %v, %v", parent.Hash().Hex(), res.String())
+	}
+	res := ci.ledger.ApplyBlockTxs(block)
+	if res.IsError() {
+		ci.chain.MarkBlockInvalid(hash)
+		return fmt.Errorf("Failed to apply block %v at height %v, %v", hash.Hex(), block.Height, res.String())
+	}
+
+	if hasValidatorUpdate, ok := res.Info["hasValidatorUpdate"]; ok && hasValidatorUpdate.(bool) {
+		ci.chain.MarkBlockHasValidatorUpdate(hash)
+	}
+	ci.lastBlock = ci.chain.MarkBlockValid(hash)
+	if backupBlock.Votes != nil {
+		for _, vote := range backupBlock.Votes.Votes() {
+			ci.chain.AddVoteToIndex(vote)
+		}
+	}
+	ci.imported++
+	return nil
+}
+
+// validateBlock checks the block as ConsensusEngine.validateBlock does, and the votes
+// for the block if present
+func (ci *ChainImporter) validateBlock(backupBlock *core.BackupBlock, parent *core.ExtendedBlock) error {
+	block := backupBlock.Block.Block
+	if res := block.Validate(ci.chain.ChainID); res.IsError() {
+		return fmt.Errorf("%v", res.String())
+	}
+	if parent.Epoch >= block.Epoch {
+		return fmt.Errorf("epoch %v is not greater than parent epoch %v", block.Epoch, parent.Epoch)
+	}
+	if !ci.chain.IsDescendant(block.HCC.BlockHash, block.Hash()) {
+		return fmt.Errorf("HCC %v is not an ancestor", block.HCC.BlockHash.Hex())
+	}
+	hccBlock, err := ci.chain.FindBlock(block.HCC.BlockHash)
+	if err != nil {
+		return fmt.Errorf("HCC block %v not found", block.HCC.BlockHash.Hex())
+	}
+	if !hccBlock.Status.IsFinalized() && !hccBlock.Status.IsTrusted() {
+		if !block.HCC.IsValid(ci.valMgr.GetValidatorSet(block.HCC.BlockHash)) {
+			return fmt.Errorf("invalid HCC %v", block.HCC.String())
+		}
+	}
+	proposer := ci.valMgr.GetNextProposer(block.Parent, block.Epoch)
+	if proposer.ID() != block.Proposer {
+		return fmt.Errorf("invalid proposer %v, expected %v", block.Proposer.Hex(), proposer.ID().Hex())
+	}
+	if backupBlock.Votes != nil && len(backupBlock.Votes.Votes()) > 0 {
+		if err := validateVotes(ci.valMgr.GetValidatorSet(block.Hash()), block.BlockHeader, backupBlock.Votes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finalize marks the last imported block and its ancestors as finalized
+func (ci *ChainImporter) finalize() error {
+	if ci.lastBlock == nil || ci.lastBlock.Status.IsFinalized() {
+		return nil
+	}
+	if err := ci.chain.FinalizePreviousBlocks(ci.lastBlock.Hash()); err != nil {
+		return err
+	}
+	if res := ci.ledger.FinalizeState(ci.lastBlock.Height, ci.lastBlock.StateHash); res.IsError() {
+		return fmt.Errorf("Failed to finalize state of block %v, %v", ci.lastBlock.Hash().Hex(), res.String())
+	}
+	lastBlock, err := ci.chain.FindBlock(ci.lastBlock.Hash())
+	if err != nil {
+		return err
+	}
+	ci.lastBlock = lastBlock
+	if ci.state != nil {
+		ci.state.SetLastFinalizedBlock(lastBlock)
+		ci.state.SetHighestCCBlock(lastBlock)
+		if ci.state.GetEpoch() < lastBlock.Epoch {
+			ci.state.SetEpoch(lastBlock.Epoch)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/scripttoken/script/blockchain"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/store/database/backend"
+	"github.com/scripttoken/script/store/kvstore"
+)
+
+func createTestArchiveChain(numBlocks int) *blockchain.Chain {
+	root := core.CreateTestBlock("archive0", "")
+	chain := blockchain.NewChain("testchain", kvstore.NewKVStore(backend.NewMemDatabase()), root)
+	var last *core.ExtendedBlock
+	for i := 1; i <= numBlocks; i++ {
+		block := core.CreateTestBlock("archive"+strconv.Itoa(i), "archive"+strconv.Itoa(i-1))
+		chain.AddBlock(block)
+		last = chain.MarkBlockValid(block.Hash())
+	}
+	chain.FinalizePreviousBlocks(last.Hash())
+	return chain
+}
+
+func TestChainArchiveRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "archive")
+	assert.Nil(err)
+	defer os.RemoveAll(tmpdir)
+
+	chain := createTestArchiveChain(24)
+	for _, compression := range []string{CompressionNone, CompressionGzip, CompressionSnappy, CompressionZstd} {
+		backupDir := path.Join(tmpdir, compression)
+		start, end, archiveDir, err := ExportChainArchive(chain, 0, 30, backupDir, &ArchiveOptions{Compression: compression, BlocksPerSegment: 10})
+		assert.Nil(err)
+		assert.Equal(uint64(0), start)
+		assert.Equal(uint64(24), end)
+
+		index, err := VerifyChainArchive(path.Join(backupDir, archiveDir))
+		assert.Nil(err)
+		assert.True(index.Complete)
+		assert.Equal(3, len(index.Segments))
+		assert.Equal(uint64(10), index.Segments[1].StartHeight)
+		assert.Equal(uint64(19), index.Segments[1].EndHeight)
+		assert.Equal(uint64(5), index.Segments[2].NumBlocks)
+		assert.Equal(chain.Root().Hash(), index.Segments[0].FirstBlock)
+
+		var heights []uint64
+		for i := range index.Segments {
+			err := readArchiveSegment(path.Join(backupDir, archiveDir), index, &index.Segments[i], func(block *core.BackupBlock) error {
+				heights = append(heights, block.Block.Height)
+				return nil
+			})
+			assert.Nil(err)
+		}
+		assert.Equal(25, len(heights))
+		assert.Equal(uint64(24), heights[24])
+	}
+}
+
+func TestChainArchiveCorruption(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "archive")
+	assert.Nil(err)
+	defer os.RemoveAll(tmpdir)
+
+	chain := createTestArchiveChain(24)
+	_, _, archiveDir, err := ExportChainArchive(chain, 0, 24, tmpdir, &ArchiveOptions{BlocksPerSegment: 10})
+	assert.Nil(err)
+	archivePath := path.Join(tmpdir, archiveDir)
+
+	// Corrupted segment file
+	segmentPath := path.Join(archivePath, segmentFileName(1, CompressionNone))
+	raw, err := ioutil.ReadFile(segmentPath)
+	assert.Nil(err)
+	raw[len(raw)/2] ^= 0xff
+	assert.Nil(ioutil.WriteFile(segmentPath, raw, 0644))
+	_, err = VerifyChainArchive(archivePath)
+	assert.NotNil(err)
+	raw[len(raw)/2] ^= 0xff
+	assert.Nil(ioutil.WriteFile(segmentPath, raw, 0644))
+	_, err = VerifyChainArchive(archivePath)
+	assert.Nil(err)
+
+	// Segments not chained to each other
+	index, err := LoadArchiveIndex(archivePath)
+	assert.Nil(err)
+	index.Segments = append(index.Segments[:1], index.Segments[2:]...)
+	assert.Nil(saveArchiveIndex(archivePath, index))
+	_, err = VerifyChainArchive(archivePath)
+	assert.NotNil(err)
+
+	// Incomplete archive
+	index.Complete = false
+	assert.Nil(saveArchiveIndex(archivePath, index))
+	_, err = VerifyChainArchive(archivePath)
+	assert.NotNil(err)
+}
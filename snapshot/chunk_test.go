@@ -0,0 +1,219 @@
+package snapshot
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/ledger/state"
+	"github.com/scripttoken/script/ledger/types"
+	"github.com/scripttoken/script/store/database"
+	"github.com/scripttoken/script/store/database/backend"
+	"github.com/scripttoken/script/store/verifier"
+)
+
+func createTestState(db database.Database, height uint64, root common.Hash, numAccounts int) common.Hash {
+	sv := state.NewStoreView(height, root, db)
+	for i := 0; i < numAccounts; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i + 1)))
+		sv.AddBalance(addr, big.NewInt(int64(height*1000)+int64(i)))
+		if i%10 == 0 {
+			for j := 0; j < 20; j++ {
+				sv.SetState(addr, common.BigToHash(big.NewInt(int64(j+1))), common.BigToHash(big.NewInt(int64(height)*100+int64(j))))
+			}
+		}
+	}
+	return sv.Save()
+}
+
+func exportTestState(t *testing.T, dir string, db database.Database, height uint64, stateHash common.Hash,
+	base *SnapshotBase, options *ExportOptions) *SnapshotManifest {
+	manifest := &SnapshotManifest{
+		Magic:       core.SnapshotHeaderMagic,
+		Version:     5,
+		Height:      height,
+		StateHash:   stateHash,
+		Compression: options.Compression,
+		Base:        base,
+	}
+	cw, err := newChunkWriter(dir, manifest, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cw.abort()
+
+	var baseSV *state.StoreView
+	baseHash := common.Hash{}
+	if base != nil {
+		baseHash = base.StateHash
+		baseSV = state.NewStoreView(base.Height, base.StateHash, db)
+	}
+	if err := cw.writeTrie(stateHash, db, baseHash); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.writeStorageTries(state.NewStoreView(height, stateHash, db), baseSV, db); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.close(); err != nil {
+		t.Fatal(err)
+	}
+	return manifest
+}
+
+func verifyTestState(assert *assert.Assertions, db database.Database, stateHash common.Hash, numAccounts int) {
+	report, err := verifier.NewStateVerifier(db, nil).Verify(context.Background(), stateHash)
+	assert.Nil(err)
+	assert.True(report.IsHealthy())
+	assert.Equal(uint64(numAccounts), report.NumAccounts)
+}
+
+func TestChunkedSnapshotRoundTrip(t *testing.T) {
+	for _, compression := range []string{CompressionNone, CompressionGzip, CompressionSnappy, CompressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			assert := assert.New(t)
+
+			tmpdir, err := ioutil.TempDir("", "snapshot")
+			assert.Nil(err)
+			defer os.RemoveAll(tmpdir)
+
+			db := backend.NewMemDatabase()
+			stateHash := createTestState(db, 1, common.Hash{}, 100)
+
+			dir := path.Join(tmpdir, "full")
+			manifest := exportTestState(t, dir, db, 1, stateHash, nil, &ExportOptions{Compression: compression, ChunkSize: 4096})
+			assert.True(len(manifest.Chunks) > 1)
+
+			loaded, err := LoadManifest(dir)
+			assert.Nil(err)
+			assert.True(loaded.Complete)
+			assert.Equal(manifest.Chunks, loaded.Chunks)
+
+			importDB := backend.NewMemDatabase()
+			assert.Nil(loadSnapshotState(dir, loaded, importDB, "Loading"))
+			verifyTestState(assert, importDB, stateHash, 100)
+		})
+	}
+}
+
+func TestChunkedSnapshotIncremental(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "snapshot")
+	assert.Nil(err)
+	defer os.RemoveAll(tmpdir)
+
+	db := backend.NewMemDatabase()
+	baseHash := createTestState(db, 1, common.Hash{}, 100)
+	stateHash := createTestState(db, 2, baseHash, 30)
+
+	options := &ExportOptions{Compression: CompressionGzip, ChunkSize: 4096}
+	baseManifest := exportTestState(t, path.Join(tmpdir, "base"), db, 1, baseHash, nil, options)
+	base := &SnapshotBase{Path: "base", Height: 1, StateHash: baseHash}
+	incManifest := exportTestState(t, path.Join(tmpdir, "inc"), db, 2, stateHash, base, options)
+
+	var numBase, numInc uint64
+	for _, chunk := range baseManifest.Chunks {
+		numBase += chunk.NumRecords
+	}
+	for _, chunk := range incManifest.Chunks {
+		numInc += chunk.NumRecords
+	}
+	assert.True(numInc < numBase)
+
+	// The base snapshot is loaded along with the incremental snapshot
+	importDB := backend.NewMemDatabase()
+	assert.Nil(loadSnapshotState(path.Join(tmpdir, "inc"), incManifest, importDB, "Loading"))
+	verifyTestState(assert, importDB, stateHash, 100)
+	verifyTestState(assert, importDB, baseHash, 100)
+
+	// Without the base snapshot, the incremental snapshot alone is incomplete
+	os.RemoveAll(path.Join(tmpdir, "base"))
+	assert.NotNil(loadSnapshotState(path.Join(tmpdir, "inc"), incManifest, backend.NewMemDatabase(), "Loading"))
+}
+
+func TestChunkedSnapshotResume(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "snapshot")
+	assert.Nil(err)
+	defer os.RemoveAll(tmpdir)
+
+	db := backend.NewMemDatabase()
+	stateHash := createTestState(db, 1, common.Hash{}, 100)
+
+	dir := path.Join(tmpdir, "snapshot")
+	options := &ExportOptions{Compression: CompressionSnappy, ChunkSize: 4096}
+	manifest := exportTestState(t, dir, db, 1, stateHash, nil, options)
+	numChunks := len(manifest.Chunks)
+	assert.True(numChunks > 3)
+
+	_, err = os.Stat(dir + stagingSuffix)
+	assert.True(os.IsNotExist(err)) // the staging directory is renamed
+	_, err = newChunkWriter(dir, &SnapshotManifest{StateHash: stateHash, Compression: options.Compression}, &ExportOptions{Resume: true})
+	assert.NotNil(err) // the snapshot has already been exported
+
+	// Simulate an export interrupted while writing the third chunk
+	stagingDir := dir + stagingSuffix
+	assert.Nil(os.Rename(dir, stagingDir))
+	manifest.Complete = false
+	manifest.Chunks = manifest.Chunks[:3]
+	assert.Nil(ioutil.WriteFile(path.Join(stagingDir, manifest.Chunks[2].Name), []byte("partial"), 0644))
+	assert.Nil(saveManifest(stagingDir, manifest))
+
+	_, err = newChunkWriter(dir, &SnapshotManifest{StateHash: stateHash, Compression: options.Compression}, options)
+	assert.NotNil(err) // the staging directory exists
+
+	resumed := exportTestState(t, dir, db, 1, stateHash, nil, &ExportOptions{Compression: CompressionSnappy, ChunkSize: 4096, Resume: true})
+	assert.True(resumed.Complete)
+	assert.Equal(numChunks, len(resumed.Chunks))
+	_, err = os.Stat(stagingDir)
+	assert.True(os.IsNotExist(err))
+
+	// Simulate an export interrupted before renaming the staging directory
+	assert.Nil(os.Rename(dir, stagingDir))
+	renamed := exportTestState(t, dir, db, 1, stateHash, nil, &ExportOptions{Compression: CompressionSnappy, ChunkSize: 4096, Resume: true})
+	assert.Equal(resumed.Chunks, renamed.Chunks)
+
+	importDB := backend.NewMemDatabase()
+	assert.Nil(loadSnapshotState(dir, resumed, importDB, "Loading"))
+	verifyTestState(assert, importDB, stateHash, 100)
+
+	// A corrupted chunk is rejected on import
+	assert.Nil(ioutil.WriteFile(path.Join(dir, resumed.Chunks[1].Name), []byte("corrupted"), 0644))
+	assert.NotNil(loadSnapshotState(dir, resumed, backend.NewMemDatabase(), "Loading"))
+}
+
+func TestChunkedSnapshotWithAsset(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "snapshot")
+	assert.Nil(err)
+	defer os.RemoveAll(tmpdir)
+
+	db := backend.NewMemDatabase()
+	sv := state.NewStoreView(1, createTestState(db, 1, common.Hash{}, 20), db)
+	asset := &types.Asset{
+		ID:       common.HexToAddress("0x2e833968e5bb786ae419c4d13189fb081cc43bab"),
+		Issuer:   common.BigToAddress(big.NewInt(1)),
+		Name:     "Test Token",
+		Symbol:   "TT",
+		Decimals: 18,
+		Supply:   big.NewInt(1000000),
+	}
+	sv.SetAsset(asset)
+	stateHash := sv.Save()
+
+	dir := path.Join(tmpdir, "snapshot")
+	manifest := exportTestState(t, dir, db, 1, stateHash, nil, &ExportOptions{Compression: CompressionGzip, ChunkSize: 4096})
+
+	importDB := backend.NewMemDatabase()
+	assert.Nil(loadSnapshotState(dir, manifest, importDB, "Loading"))
+	verifyTestState(assert, importDB, stateHash, 20)
+	assert.Equal(asset, state.NewStoreView(1, stateHash, importDB).GetAsset(asset.ID))
+}
@@ -0,0 +1,399 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/scripttoken/script/blockchain"
+	"github.com/scripttoken/script/common"
+	cns "github.com/scripttoken/script/consensus"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/ledger/state"
+	"github.com/scripttoken/script/ledger/types"
+	"github.com/scripttoken/script/rlp"
+	"github.com/scripttoken/script/store/database"
+	"github.com/scripttoken/script/store/trie"
+)
+
+// ExportOptions configures the export of a chunked snapshot
+type ExportOptions struct {
+	Compression string // one of CompressionNone, CompressionGzip, CompressionSnappy and CompressionZstd
+	ChunkSize   uint64 // uncompressed size of a chunk, DefaultChunkSize if 0
+	Base        string // path of the base snapshot of an incremental snapshot, empty for a full snapshot
+	Resume      bool   // resume an interrupted export of the same snapshot
+}
+
+// stagingSuffix is appended to the directory of a snapshot while it is being exported
+const stagingSuffix = ".tmp"
+
+// ExportSnapshotV5 exports a chunked snapshot with a manifest into a directory under snapshotDir.
+// If options.Base is set, only the trie nodes added since the base snapshot are exported.
+// The snapshot is written into a staging directory, which is renamed once the export completes.
+func ExportSnapshotV5(db database.Database, consensus *cns.ConsensusEngine, chain *blockchain.Chain, snapshotDir string, height uint64, options *ExportOptions) (string, error) {
+	if options == nil {
+		options = &ExportOptions{}
+	}
+	if options.Compression == "" {
+		options.Compression = CompressionNone
+	}
+	if err := checkCompression(options.Compression); err != nil {
+		return "", err
+	}
+	if options.ChunkSize == 0 {
+		options.ChunkSize = DefaultChunkSize
+	}
+
+	blocks, err := getSnapshotBlocks(consensus, chain, db, height)
+	if err != nil {
+		return "", err
+	}
+	lastFinalizedBlock := blocks.lastFinalizedBlock
+
+	checkpointRaw, err := rlp.EncodeToBytes(*blocks.lastCheckpoint)
+	if err != nil {
+		return "", err
+	}
+	metadataRaw, err := rlp.EncodeToBytes(*blocks.metadata)
+	if err != nil {
+		return "", err
+	}
+	manifest := &SnapshotManifest{
+		Magic:       core.SnapshotHeaderMagic,
+		Version:     5,
+		Height:      lastFinalizedBlock.Height,
+		BlockHash:   lastFinalizedBlock.Hash(),
+		StateHash:   lastFinalizedBlock.StateHash,
+		Compression: options.Compression,
+		Checkpoint:  checkpointRaw,
+		Metadata:    metadataRaw,
+	}
+
+	dirname := "script_snapshot-" + strconv.FormatUint(manifest.Height, 10) + "-" + manifest.StateHash.String()
+	if options.Base != "" {
+		if !filepath.IsAbs(options.Base) && !IsChunkedSnapshot(options.Base) {
+			options.Base = path.Join(snapshotDir, options.Base) // name of a snapshot in snapshotDir
+		}
+		baseManifest, err := LoadManifest(options.Base)
+		if err != nil {
+			return "", fmt.Errorf("Failed to load base snapshot %v, %v", options.Base, err)
+		}
+		if !baseManifest.Complete {
+			return "", fmt.Errorf("Base snapshot %v is incomplete", options.Base)
+		}
+		if baseManifest.Height >= manifest.Height {
+			return "", fmt.Errorf("Base snapshot height %v is not below the snapshot height %v", baseManifest.Height, manifest.Height)
+		}
+		basePath, err := filepath.Abs(options.Base)
+		if err != nil {
+			return "", err
+		}
+		absSnapshotDir, err := filepath.Abs(snapshotDir)
+		if err != nil {
+			return "", err
+		}
+		if rel, err := filepath.Rel(absSnapshotDir, basePath); err == nil {
+			basePath = rel
+		}
+		if has, _ := db.Has(baseManifest.StateHash.Bytes()); !has {
+			return "", fmt.Errorf("The state of base snapshot %v is no longer available", options.Base)
+		}
+		manifest.Base = &SnapshotBase{
+			Path:      basePath,
+			Height:    baseManifest.Height,
+			StateHash: baseManifest.StateHash,
+		}
+		dirname += "-inc-" + strconv.FormatUint(baseManifest.Height, 10)
+	}
+
+	exportDir := path.Join(snapshotDir, dirname)
+	cw, err := newChunkWriter(exportDir, manifest, options)
+	if err != nil {
+		return "", err
+	}
+	defer cw.abort()
+
+	base := common.Hash{}
+	if manifest.Base != nil {
+		base = manifest.Base.StateHash
+	}
+
+	// Last checkpoint storeview
+	lastCheckpointBlock := blocks.lastCheckpointBlock
+	if lastFinalizedBlock.Height != common.LastCheckPointHeight(lastFinalizedBlock.Height) {
+		if err := cw.writeTrie(lastCheckpointBlock.StateHash, db, base); err != nil {
+			return "", err
+		}
+	}
+
+	// Parent block storeview
+	parentBlock := blocks.parentBlock
+	if err := cw.writeTrie(parentBlock.StateHash, db, base); err != nil {
+		return "", err
+	}
+
+	// Last finalized block storeview, with the account storage
+	if err := cw.writeTrie(lastFinalizedBlock.StateHash, db, parentBlock.StateHash); err != nil {
+		return "", err
+	}
+	var baseSV *state.StoreView
+	if !base.IsEmpty() {
+		baseSV = state.NewStoreView(manifest.Base.Height, base, db)
+	}
+	sv := state.NewStoreView(lastFinalizedBlock.Height, lastFinalizedBlock.StateHash, db)
+	if err := cw.writeStorageTries(sv, baseSV, db); err != nil {
+		return "", err
+	}
+
+	if err := cw.close(); err != nil {
+		return "", err
+	}
+	return dirname, nil
+}
+
+//
+// chunkWriter writes the snapshot records into chunk files in a staging directory,
+// and keeps the manifest up to date after each completed chunk so an interrupted
+// export can be resumed
+//
+type chunkWriter struct {
+	dir       string // staging directory
+	exportDir string // directory of the completed snapshot
+	manifest  *SnapshotManifest
+	chunkSize uint64
+	skip      uint64 // number of records already exported before resuming
+
+	file       *os.File
+	hasher     hash.Hash
+	compressor interface{ Close() error }
+	writer     *bufio.Writer
+	size       uint64
+	numRecords uint64
+}
+
+func newChunkWriter(exportDir string, manifest *SnapshotManifest, options *ExportOptions) (*chunkWriter, error) {
+	dir := exportDir + stagingSuffix
+	cw := &chunkWriter{
+		dir:       dir,
+		exportDir: exportDir,
+		manifest:  manifest,
+		chunkSize: options.ChunkSize,
+	}
+
+	if _, err := os.Stat(exportDir); err == nil {
+		return nil, fmt.Errorf("Snapshot %v has already been exported", exportDir)
+	}
+	if options.Resume {
+		// The intact chunks of an interrupted export are kept, even if it was only
+		// interrupted before renaming the staging directory
+		if existing, err := LoadManifest(dir); err == nil {
+			if existing.StateHash == manifest.StateHash && existing.Compression == manifest.Compression &&
+				(existing.Base == nil) == (manifest.Base == nil) {
+				manifest.Chunks = validChunks(dir, existing.Chunks)
+				for _, chunk := range manifest.Chunks {
+					cw.skip += chunk.NumRecords
+				}
+				logger.Infof("Resuming snapshot export after %v chunks, %v records", len(manifest.Chunks), cw.skip)
+			}
+		}
+	} else if _, err := os.Stat(dir); err == nil {
+		return nil, fmt.Errorf("Snapshot directory %v already exists", dir)
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := saveManifest(dir, manifest); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// validChunks returns the longest prefix of the chunks whose files are intact
+func validChunks(dir string, chunks []SnapshotChunk) []SnapshotChunk {
+	for i, chunk := range chunks {
+		if hash, err := hashFile(path.Join(dir, chunk.Name)); err != nil || hash != chunk.Hash {
+			logger.Warnf("Snapshot chunk %v is corrupted, re-exporting from there", chunk.Name)
+			return chunks[:i]
+		}
+	}
+	return chunks
+}
+
+func (cw *chunkWriter) writeRecord(k, v common.Bytes) error {
+	if cw.skip > 0 {
+		cw.skip--
+		return nil
+	}
+	if cw.file == nil {
+		if err := cw.openChunk(); err != nil {
+			return err
+		}
+	}
+	if err := core.WriteRecord(cw.writer, k, v); err != nil {
+		return err
+	}
+	cw.numRecords++
+	cw.size += uint64(len(k) + len(v))
+	if cw.size >= cw.chunkSize {
+		return cw.finishChunk()
+	}
+	return nil
+}
+
+func (cw *chunkWriter) writeTrie(root common.Hash, db database.Database, base common.Hash) error {
+	tr, err := trie.New(root, trie.NewDatabase(db))
+	if err != nil {
+		return err
+	}
+	var it trie.NodeIterator
+	if !base.IsEmpty() {
+		baseTr, err := trie.New(base, trie.NewDatabase(db))
+		if err != nil {
+			return err
+		}
+		it, _ = trie.NewDifferenceIterator(baseTr.NodeIterator(nil), tr.NodeIterator(nil))
+	} else {
+		it = tr.NodeIterator(nil)
+	}
+	for it.Next(true) {
+		if it.Hash() == (common.Hash{}) {
+			continue
+		}
+		hash := it.Hash()
+		val, err := db.Get(hash.Bytes())
+		if err != nil {
+			return fmt.Errorf("Failed to get trie node %v, %v", hash.Hex(), err)
+		}
+		if err := cw.writeRecord(hash.Bytes(), val); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+// writeStorageTries writes the storage tries of the accounts in sv, diffed against
+// the storage tries of the same accounts in baseSV if it is not nil
+func (cw *chunkWriter) writeStorageTries(sv *state.StoreView, baseSV *state.StoreView, db database.Database) error {
+	var err error
+	sv.GetStore().Traverse(nil, func(k, v common.Bytes) bool {
+		if !bytes.HasPrefix(k, []byte("ls/a")) {
+			return true
+		}
+		account := &types.Account{}
+		err = types.FromBytes([]byte(v), account)
+		if err != nil {
+			err = fmt.Errorf("Failed to parse account for %v, %v", []byte(v), err)
+			return false
+		}
+		if account.Root == (common.Hash{}) {
+			return true
+		}
+		storageBase := common.Hash{}
+		if baseSV != nil {
+			if raw := baseSV.Get(k); raw != nil {
+				baseAccount := &types.Account{}
+				if types.FromBytes([]byte(raw), baseAccount) == nil {
+					storageBase = baseAccount.Root
+				}
+			}
+		}
+		if storageBase == account.Root {
+			return true // unchanged since the base snapshot
+		}
+		err = cw.writeTrie(account.Root, db, storageBase)
+		return err == nil
+	})
+	return err
+}
+
+func (cw *chunkWriter) openChunk() error {
+	name := chunkFileName(len(cw.manifest.Chunks), cw.manifest.Compression)
+	file, err := os.Create(path.Join(cw.dir, name))
+	if err != nil {
+		return err
+	}
+	cw.hasher = sha256.New()
+	compressor, err := newCompressor(&hashingWriter{file: file, hasher: cw.hasher}, cw.manifest.Compression)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	cw.file = file
+	cw.compressor = compressor
+	cw.writer = bufio.NewWriter(compressor)
+	cw.size = 0
+	cw.numRecords = 0
+	return nil
+}
+
+func (cw *chunkWriter) finishChunk() error {
+	if err := cw.writer.Flush(); err != nil {
+		return err
+	}
+	if err := cw.compressor.Close(); err != nil {
+		return err
+	}
+	if err := cw.file.Sync(); err != nil {
+		return err
+	}
+	info, err := cw.file.Stat()
+	if err != nil {
+		return err
+	}
+	name := path.Base(cw.file.Name())
+	if err := cw.file.Close(); err != nil {
+		return err
+	}
+	cw.file = nil
+
+	cw.manifest.Chunks = append(cw.manifest.Chunks, SnapshotChunk{
+		Name:       name,
+		Hash:       hex.EncodeToString(cw.hasher.Sum(nil)),
+		Size:       uint64(info.Size()),
+		NumRecords: cw.numRecords,
+	})
+	return saveManifest(cw.dir, cw.manifest)
+}
+
+func (cw *chunkWriter) close() error {
+	if cw.skip > 0 {
+		return fmt.Errorf("Snapshot content changed since the interrupted export, %v records missing", cw.skip)
+	}
+	if cw.file != nil {
+		if err := cw.finishChunk(); err != nil {
+			return err
+		}
+	}
+	cw.manifest.Complete = true
+	if err := saveManifest(cw.dir, cw.manifest); err != nil {
+		return err
+	}
+	return os.Rename(cw.dir, cw.exportDir)
+}
+
+// abort closes the pending chunk without recording it in the manifest
+func (cw *chunkWriter) abort() {
+	if cw.file != nil {
+		cw.file.Close()
+		cw.file = nil
+	}
+}
+
+type hashingWriter struct {
+	file   *os.File
+	hasher hash.Hash
+}
+
+func (hw *hashingWriter) Write(p []byte) (int, error) {
+	n, err := hw.file.Write(p)
+	hw.hasher.Write(p[:n])
+	return n, err
+}
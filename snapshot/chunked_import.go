@@ -0,0 +1,129 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/ledger/state"
+	"github.com/scripttoken/script/store/database"
+	"github.com/scripttoken/script/store/kvstore"
+)
+
+// snapshotImportProgressKeyPrefix is the DB key prefix of the number of chunks
+// of a chunked snapshot already loaded, used to resume an interrupted import
+const snapshotImportProgressKeyPrefix = "/snapshot_import_progress/"
+
+// loadChunkedSnapshot loads a chunked snapshot (version 5). The base snapshots of an
+// incremental snapshot are loaded first if their state is not yet in the DB.
+func loadChunkedSnapshot(snapshotDir string, db database.Database, logStr string) (*core.BlockHeader, *core.SnapshotMetadata, error) {
+	manifest, err := LoadManifest(snapshotDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to load snapshot manifest, %v", err)
+	}
+	if !manifest.Complete {
+		return nil, nil, fmt.Errorf("Snapshot %v is incomplete", snapshotDir)
+	}
+	logger.Infof("Reading chunked snapshot, version: %v, height: %v, chunks: %v, compression: %v",
+		manifest.Version, manifest.Height, len(manifest.Chunks), manifest.Compression)
+
+	if err := loadSnapshotState(snapshotDir, manifest, db, logStr); err != nil {
+		return nil, nil, err
+	}
+
+	kvstore := kvstore.NewKVStore(db)
+
+	lastCheckpoint, err := manifest.DecodeCheckpoint()
+	if err != nil {
+		return nil, nil, err
+	}
+	saveLastCheckpointBlocks(kvstore, lastCheckpoint)
+
+	metadata, err := manifest.DecodeMetadata()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lfb := metadata.TailTrio.Second
+	sv := state.NewStoreView(lfb.Header.Height, lfb.Header.StateHash, db)
+	secondBlockHeader, err := finalizeSnapshot(sv, metadata, lastCheckpoint, manifest.Version, db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return secondBlockHeader, metadata, nil
+}
+
+// loadSnapshotState loads the trie nodes of a chunked snapshot, and of its bases
+func loadSnapshotState(snapshotDir string, manifest *SnapshotManifest, db database.Database, logStr string) error {
+	if manifest.IsIncremental() {
+		if has, _ := db.Has(manifest.Base.StateHash.Bytes()); !has {
+			basePath := manifest.Base.Path
+			if !filepath.IsAbs(basePath) {
+				basePath = path.Join(filepath.Dir(filepath.Clean(snapshotDir)), basePath)
+			}
+			baseManifest, err := LoadManifest(basePath)
+			if err != nil {
+				return fmt.Errorf("Failed to load base snapshot %v, %v", basePath, err)
+			}
+			if baseManifest.StateHash != manifest.Base.StateHash || baseManifest.Height != manifest.Base.Height {
+				return fmt.Errorf("Base snapshot %v does not match, expected height %v, state hash %v",
+					basePath, manifest.Base.Height, manifest.Base.StateHash.Hex())
+			}
+			if !baseManifest.Complete {
+				return fmt.Errorf("Base snapshot %v is incomplete", basePath)
+			}
+			logger.Infof("Loading base snapshot %v", basePath)
+			if err := loadSnapshotState(basePath, baseManifest, db, logStr); err != nil {
+				return err
+			}
+		}
+	}
+
+	kvstore := kvstore.NewKVStore(db)
+	progressKey := []byte(snapshotImportProgressKeyPrefix + manifest.Hash().Hex())
+	var loaded uint64
+	if kvstore.Get(progressKey, &loaded) == nil && loaded > 0 {
+		logger.Infof("Resuming snapshot import after %v chunks", loaded)
+	}
+
+	numChunks := uint64(len(manifest.Chunks))
+	for i := loaded; i < numChunks; i++ {
+		chunk := manifest.Chunks[i]
+		if err := loadChunk(snapshotDir, chunk, manifest.Compression, db); err != nil {
+			return err
+		}
+		if err := kvstore.Put(progressKey, i+1); err != nil {
+			return err
+		}
+		logger.Infof("%s, chunk %v/%v done.", logStr, i+1, numChunks)
+	}
+	return nil
+}
+
+// loadChunk verifies the hash of a chunk file and writes its records into the DB
+func loadChunk(snapshotDir string, chunk SnapshotChunk, compression string, db database.Database) error {
+	chunkPath := path.Join(snapshotDir, chunk.Name)
+	hash, err := hashFile(chunkPath)
+	if err != nil {
+		return fmt.Errorf("Failed to read snapshot chunk %v, %v", chunk.Name, err)
+	}
+	if hash != chunk.Hash {
+		return fmt.Errorf("Snapshot chunk %v is corrupted, expected hash %v, got %v", chunk.Name, chunk.Hash, hash)
+	}
+
+	file, err := os.Open(chunkPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := newDecompressor(file, compression)
+	if err != nil {
+		return fmt.Errorf("Failed to decompress snapshot chunk %v, %v", chunk.Name, err)
+	}
+	defer reader.Close()
+	return loadStateV3(reader, db, 0, "Loading "+chunk.Name)
+}
@@ -0,0 +1,120 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/crypto"
+)
+
+// IndexFileName is the name of the signed snapshot index in a snapshot directory
+const IndexFileName = "snapshots.json"
+
+// SnapshotIndexEntry describes a snapshot listed in the snapshot index
+type SnapshotIndexEntry struct {
+	Name         string      `json:"name"`
+	Height       uint64      `json:"height"`
+	BlockHash    common.Hash `json:"block_hash"`
+	StateHash    common.Hash `json:"state_hash"`
+	Base         string      `json:"base,omitempty"`
+	ManifestHash string      `json:"manifest_hash"` // sha256 of the manifest file
+}
+
+// SnapshotIndex lists the snapshots available in a snapshot directory, signed by
+// the node which produced them
+type SnapshotIndex struct {
+	ChainID   string               `json:"chain_id"`
+	Timestamp int64                `json:"timestamp"`
+	Snapshots []SnapshotIndexEntry `json:"snapshots"`
+	Signer    common.Address       `json:"signer"`
+	Signature *crypto.Signature    `json:"signature"`
+}
+
+// SignBytes returns the bytes to be signed
+func (idx *SnapshotIndex) SignBytes() common.Bytes {
+	sig := idx.Signature
+	idx.Signature = nil
+	raw, _ := json.Marshal(idx)
+	idx.Signature = sig
+	return raw
+}
+
+// Verify checks the signature of the index
+func (idx *SnapshotIndex) Verify() error {
+	if idx.Signature == nil || !idx.Signature.Verify(idx.SignBytes(), idx.Signer) {
+		return fmt.Errorf("Invalid signature of the snapshot index, signer: %v", idx.Signer.Hex())
+	}
+	return nil
+}
+
+// NewSnapshotIndex creates a signed index of the complete chunked snapshots in the given directory
+func NewSnapshotIndex(dir string, chainID string, privateKey *crypto.PrivateKey) (*SnapshotIndex, error) {
+	idx := &SnapshotIndex{
+		ChainID:   chainID,
+		Timestamp: time.Now().Unix(),
+		Snapshots: []SnapshotIndexEntry{},
+		Signer:    privateKey.PublicKey().Address(),
+	}
+	for _, s := range ListSnapshots(dir) {
+		manifestHash, err := hashFile(path.Join(dir, s.Name, ManifestFileName))
+		if err != nil {
+			return nil, err
+		}
+		entry := SnapshotIndexEntry{
+			Name:         s.Name,
+			Height:       s.Height,
+			BlockHash:    s.Manifest.BlockHash,
+			StateHash:    s.Manifest.StateHash,
+			ManifestHash: manifestHash,
+		}
+		if s.Manifest.IsIncremental() {
+			entry.Base = s.Manifest.Base.Path
+		}
+		idx.Snapshots = append(idx.Snapshots, entry)
+	}
+
+	sig, err := privateKey.Sign(idx.SignBytes())
+	if err != nil {
+		return nil, err
+	}
+	idx.Signature = sig
+	return idx, nil
+}
+
+// PublishIndex writes the signed index of the snapshots in the given directory
+func PublishIndex(dir string, chainID string, privateKey *crypto.PrivateKey) error {
+	idx, err := NewSnapshotIndex(dir, chainID, privateKey)
+	if err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := path.Join(dir, IndexFileName+".tmp")
+	if err := ioutil.WriteFile(tmpPath, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path.Join(dir, IndexFileName))
+}
+
+// LoadIndex loads and verifies the signed snapshot index in the given directory
+func LoadIndex(dir string) (*SnapshotIndex, error) {
+	raw, err := ioutil.ReadFile(path.Join(dir, IndexFileName))
+	if err != nil {
+		return nil, err
+	}
+	idx := &SnapshotIndex{}
+	if err := json.Unmarshal(raw, idx); err != nil {
+		return nil, fmt.Errorf("Failed to parse snapshot index, %v", err)
+	}
+	if err := idx.Verify(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
@@ -0,0 +1,215 @@
+package snapshot
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/common/hexutil"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/rlp"
+)
+
+// ManifestFileName is the name of the manifest file in a chunked snapshot directory
+const ManifestFileName = "manifest.json"
+
+// DefaultChunkSize is the default uncompressed size of a snapshot chunk
+const DefaultChunkSize = 64 * 1024 * 1024
+
+// Supported chunk compressions
+const (
+	CompressionNone   = "none"
+	CompressionGzip   = "gzip"
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+)
+
+// SnapshotChunk describes a chunk file of a chunked snapshot
+type SnapshotChunk struct {
+	Name       string `json:"name"`
+	Hash       string `json:"hash"` // sha256 of the chunk file
+	Size       uint64 `json:"size"` // size of the chunk file
+	NumRecords uint64 `json:"num_records"`
+}
+
+// SnapshotBase refers to the snapshot an incremental snapshot is built upon
+type SnapshotBase struct {
+	Path      string      `json:"path"` // relative to the parent directory of the snapshot, unless absolute
+	Height    uint64      `json:"height"`
+	StateHash common.Hash `json:"state_hash"`
+}
+
+// SnapshotManifest describes a chunked snapshot (version 5). The state trie nodes
+// are stored in the chunk files as a stream of snapshot records.
+type SnapshotManifest struct {
+	Magic       string          `json:"magic"`
+	Version     uint            `json:"version"`
+	Height      uint64          `json:"height"`
+	BlockHash   common.Hash     `json:"block_hash"`
+	StateHash   common.Hash     `json:"state_hash"`
+	Compression string          `json:"compression"`
+	Base        *SnapshotBase   `json:"base,omitempty"`
+	Checkpoint  hexutil.Bytes   `json:"last_checkpoint"` // RLP encoded core.LastCheckpoint
+	Metadata    hexutil.Bytes   `json:"metadata"`        // RLP encoded core.SnapshotMetadata, i.e. the block trios
+	Chunks      []SnapshotChunk `json:"chunks"`
+	Complete    bool            `json:"complete"`
+}
+
+// IsIncremental returns true if the snapshot only contains the trie nodes added since its base
+func (m *SnapshotManifest) IsIncremental() bool {
+	return m.Base != nil
+}
+
+// Hash returns the hash of the manifest, which identifies the snapshot
+func (m *SnapshotManifest) Hash() common.Hash {
+	raw, _ := json.Marshal(m)
+	return common.BytesToHash(sha256Bytes(raw))
+}
+
+// DecodeCheckpoint decodes the last checkpoint of the snapshot
+func (m *SnapshotManifest) DecodeCheckpoint() (*core.LastCheckpoint, error) {
+	lastCheckpoint := &core.LastCheckpoint{}
+	if err := rlp.DecodeBytes(m.Checkpoint, lastCheckpoint); err != nil {
+		return nil, fmt.Errorf("Failed to decode snapshot last checkpoint, %v", err)
+	}
+	return lastCheckpoint, nil
+}
+
+// DecodeMetadata decodes the block trios of the snapshot
+func (m *SnapshotManifest) DecodeMetadata() (*core.SnapshotMetadata, error) {
+	metadata := &core.SnapshotMetadata{}
+	if err := rlp.DecodeBytes(m.Metadata, metadata); err != nil {
+		return nil, fmt.Errorf("Failed to decode snapshot metadata, %v", err)
+	}
+	return metadata, nil
+}
+
+// IsChunkedSnapshot returns true if the given path is a chunked snapshot directory
+func IsChunkedSnapshot(snapshotPath string) bool {
+	info, err := os.Stat(snapshotPath)
+	return err == nil && info.IsDir()
+}
+
+// LoadManifest loads the manifest of the chunked snapshot in the given directory
+func LoadManifest(snapshotDir string) (*SnapshotManifest, error) {
+	raw, err := ioutil.ReadFile(path.Join(snapshotDir, ManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+	manifest := &SnapshotManifest{}
+	if err := json.Unmarshal(raw, manifest); err != nil {
+		return nil, fmt.Errorf("Failed to parse snapshot manifest, %v", err)
+	}
+	if manifest.Magic != core.SnapshotHeaderMagic {
+		return nil, fmt.Errorf("Invalid snapshot manifest magic: %v", manifest.Magic)
+	}
+	if manifest.Version < 5 {
+		return nil, fmt.Errorf("Unsupported chunked snapshot version: %v", manifest.Version)
+	}
+	return manifest, nil
+}
+
+// saveManifest atomically replaces the manifest in the given directory
+func saveManifest(snapshotDir string, manifest *SnapshotManifest) error {
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := path.Join(snapshotDir, ManifestFileName+".tmp")
+	if err := ioutil.WriteFile(tmpPath, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path.Join(snapshotDir, ManifestFileName))
+}
+
+func chunkFileName(index int, compression string) string {
+	name := fmt.Sprintf("chunk-%06d.dat", index)
+	switch compression {
+	case CompressionGzip:
+		name += ".gz"
+	case CompressionSnappy:
+		name += ".sz"
+	case CompressionZstd:
+		name += ".zst"
+	}
+	return name
+}
+
+func checkCompression(compression string) error {
+	switch compression {
+	case CompressionNone, CompressionGzip, CompressionSnappy, CompressionZstd:
+		return nil
+	}
+	return fmt.Errorf("Unsupported snapshot compression: %v", compression)
+}
+
+// newCompressor wraps the given writer with the compression of the snapshot
+func newCompressor(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionNone:
+		return nopWriteCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionSnappy:
+		return snappy.NewBufferedWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	}
+	return nil, checkCompression(compression)
+}
+
+// newDecompressor wraps the given reader with the decompression of the snapshot. The returned
+// reader must be closed, which does not close the given reader.
+func newDecompressor(r io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case CompressionNone:
+		return ioutil.NopCloser(bufio.NewReader(r)), nil
+	case CompressionGzip:
+		return gzip.NewReader(bufio.NewReader(r))
+	case CompressionSnappy:
+		return ioutil.NopCloser(snappy.NewReader(r)), nil
+	case CompressionZstd:
+		decoder, err := zstd.NewReader(bufio.NewReader(r))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}
+	return nil, checkCompression(compression)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// hashFile returns the hex encoded sha256 of the given file
+func hashFile(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func sha256Bytes(raw []byte) []byte {
+	sum := sha256.Sum256(raw)
+	return sum[:]
+}
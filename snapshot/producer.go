@@ -0,0 +1,204 @@
+package snapshot
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/scripttoken/script/blockchain"
+	"github.com/scripttoken/script/common"
+	cns "github.com/scripttoken/script/consensus"
+	"github.com/scripttoken/script/crypto"
+	"github.com/scripttoken/script/store/database"
+)
+
+const producerPollInterval = 30 * time.Second
+
+//
+// Producer periodically exports a chunked snapshot of the last finalized block
+// every few checkpoints, removes the oldest snapshots and publishes a signed
+// index of the remaining ones
+//
+type Producer struct {
+	db         database.Database
+	consensus  *cns.ConsensusEngine
+	chain      *blockchain.Chain
+	privateKey *crypto.PrivateKey
+
+	dir         string
+	interval    uint64 // in blocks, a multiple of the checkpoint interval
+	retain      int
+	compression string
+	lastHeight  uint64 // height of the last produced snapshot
+
+	// Life cycle
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewProducer creates an instance of the Producer, which exports the snapshots into dir
+func NewProducer(db database.Database, consensus *cns.ConsensusEngine, chain *blockchain.Chain, privateKey *crypto.PrivateKey, dir string) *Producer {
+	numCheckpoints := viper.GetUint64(common.CfgSnapshotAutoInterval)
+	if numCheckpoints == 0 {
+		numCheckpoints = 1
+	}
+	retain := viper.GetInt(common.CfgSnapshotAutoRetain)
+	if retain < 1 {
+		retain = 1
+	}
+
+	return &Producer{
+		db:          db,
+		consensus:   consensus,
+		chain:       chain,
+		privateKey:  privateKey,
+		dir:         dir,
+		interval:    numCheckpoints * uint64(common.CheckpointInterval),
+		retain:      retain,
+		compression: viper.GetString(common.CfgSnapshotAutoCompression),
+		wg:          &sync.WaitGroup{},
+	}
+}
+
+// Start starts the snapshot production
+func (p *Producer) Start(ctx context.Context) {
+	c, cancel := context.WithCancel(ctx)
+	p.ctx = c
+	p.cancel = cancel
+
+	if err := os.MkdirAll(p.dir, os.ModePerm); err != nil {
+		logger.Errorf("Failed to create snapshot directory %v: %v", p.dir, err)
+	}
+	snapshots := ListSnapshots(p.dir)
+	if len(snapshots) > 0 {
+		p.lastHeight = snapshots[len(snapshots)-1].Height
+	}
+
+	p.wg.Add(1)
+	go p.mainLoop()
+}
+
+// Stop stops the snapshot production
+func (p *Producer) Stop() {
+	p.cancel()
+}
+
+// Wait suspends the caller goroutine
+func (p *Producer) Wait() {
+	p.wg.Wait()
+}
+
+func (p *Producer) mainLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(producerPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			block := p.consensus.GetLastFinalizedBlock()
+			if block == nil || !p.isDue(block.Height) {
+				continue
+			}
+			p.produce(block.Height)
+		}
+	}
+}
+
+// isDue returns true if a multiple of the snapshot interval has been finalized
+// since the last produced snapshot
+func (p *Producer) isDue(height uint64) bool {
+	return height/p.interval > p.lastHeight/p.interval
+}
+
+func (p *Producer) produce(height uint64) {
+	logger.Infof("Producing snapshot at height %v", height)
+	start := time.Now()
+
+	// The export only reads the finalized states from the DB, so neither the
+	// consensus engine nor the ledger is blocked meanwhile
+	name, err := ExportSnapshotV5(p.db, p.consensus, p.chain, p.dir, 0, &ExportOptions{
+		Compression: p.compression,
+		Resume:      true,
+	})
+	if err != nil {
+		logger.Errorf("Failed to produce snapshot at height %v: %v", height, err)
+	}
+	// Do not retry before the next interval, the failure is likely to persist
+	p.lastHeight = height
+	if err != nil {
+		return
+	}
+	logger.Infof("Produced snapshot %v in %v", name, time.Since(start))
+
+	p.prune()
+	if err := PublishIndex(p.dir, p.chain.ChainID, p.privateKey); err != nil {
+		logger.Errorf("Failed to publish the snapshot index: %v", err)
+	}
+}
+
+// prune removes the oldest snapshots beyond the retention limit, except the ones
+// still needed as the base of a retained incremental snapshot
+func (p *Producer) prune() {
+	snapshots := ListSnapshots(p.dir)
+	bases := make(map[common.Hash]bool)
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		s := snapshots[i]
+		if i >= len(snapshots)-p.retain || bases[s.Manifest.StateHash] {
+			if s.Manifest.IsIncremental() {
+				bases[s.Manifest.Base.StateHash] = true
+			}
+			continue
+		}
+		logger.Infof("Removing snapshot %v", s.Name)
+		if err := os.RemoveAll(path.Join(p.dir, s.Name)); err != nil {
+			logger.Errorf("Failed to remove snapshot %v: %v", s.Name, err)
+		}
+	}
+}
+
+// SnapshotInfo describes a complete chunked snapshot in a snapshot directory
+type SnapshotInfo struct {
+	Name     string
+	Height   uint64
+	Manifest *SnapshotManifest
+}
+
+// ListSnapshots returns the complete chunked snapshots in the given directory,
+// sorted by height
+func ListSnapshots(dir string) []SnapshotInfo {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	snapshots := []SnapshotInfo{}
+	for _, file := range files {
+		if !file.IsDir() || !strings.HasPrefix(file.Name(), "script_snapshot-") {
+			continue
+		}
+		manifest, err := LoadManifest(path.Join(dir, file.Name()))
+		if err != nil || !manifest.Complete {
+			continue
+		}
+		snapshots = append(snapshots, SnapshotInfo{
+			Name:     file.Name(),
+			Height:   manifest.Height,
+			Manifest: manifest,
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Height < snapshots[j].Height
+	})
+	return snapshots
+}
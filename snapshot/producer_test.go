@@ -0,0 +1,109 @@
+package snapshot
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/crypto"
+	"github.com/scripttoken/script/store/database/backend"
+)
+
+func TestProducerPruneAndIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "snapshot")
+	assert.Nil(err)
+	defer os.RemoveAll(tmpdir)
+
+	db := backend.NewMemDatabase()
+	options := &ExportOptions{Compression: CompressionGzip}
+	stateHash := common.Hash{}
+	for height := uint64(1); height <= 4; height++ {
+		stateHash = createTestState(db, height, stateHash, 10)
+		name := "script_snapshot-" + strconv.FormatUint(height, 10) + "-" + stateHash.Hex()
+		exportTestState(t, path.Join(tmpdir, name), db, height, stateHash, nil, options)
+	}
+	assert.Equal(4, len(ListSnapshots(tmpdir)))
+
+	p := &Producer{dir: tmpdir, retain: 2, interval: 100}
+	p.prune()
+	snapshots := ListSnapshots(tmpdir)
+	assert.Equal(2, len(snapshots))
+	assert.Equal(uint64(3), snapshots[0].Height)
+	assert.Equal(uint64(4), snapshots[1].Height)
+
+	assert.False(p.isDue(99))
+	assert.True(p.isDue(100))
+
+	privKey, _, err := crypto.GenerateKeyPair()
+	assert.Nil(err)
+	assert.Nil(PublishIndex(tmpdir, "testchain", privKey))
+
+	idx, err := LoadIndex(tmpdir)
+	assert.Nil(err)
+	assert.Equal("testchain", idx.ChainID)
+	assert.Equal(privKey.PublicKey().Address(), idx.Signer)
+	assert.Equal(2, len(idx.Snapshots))
+	assert.Equal(snapshots[1].Name, idx.Snapshots[1].Name)
+
+	idx.Snapshots[1].Height = 5
+	assert.NotNil(idx.Verify())
+}
+
+func TestServerIsReadOnly(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "snapshot")
+	assert.Nil(err)
+	defer os.RemoveAll(tmpdir)
+	assert.Nil(ioutil.WriteFile(path.Join(tmpdir, IndexFileName), []byte("{}"), 0644))
+	assert.Nil(ioutil.WriteFile(path.Join(tmpdir, IndexFileName+".tmp"), []byte("{}"), 0644))
+	for _, dir := range []string{"script_snapshot-1", "script_snapshot-2" + stagingSuffix} {
+		assert.Nil(os.Mkdir(path.Join(tmpdir, dir), os.ModePerm))
+		assert.Nil(ioutil.WriteFile(path.Join(tmpdir, dir, ManifestFileName), []byte("{}"), 0644))
+	}
+
+	ts := httptest.NewServer(NewServer(tmpdir).server.Handler)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/" + IndexFileName)
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	resp, err = http.Get(ts.URL + "/" + IndexFileName + ".tmp")
+	assert.Nil(err)
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+
+	resp, err = http.Get(ts.URL + "/script_snapshot-1/" + ManifestFileName)
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	// The snapshots being exported are hidden
+	resp, err = http.Get(ts.URL + "/script_snapshot-2" + stagingSuffix + "/" + ManifestFileName)
+	assert.Nil(err)
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+
+	// The directories are not listed
+	for _, dir := range []string{"/", "/script_snapshot-1/", "/script_snapshot-1"} {
+		resp, err = http.Get(ts.URL + dir)
+		assert.Nil(err)
+		assert.Equal(http.StatusNotFound, resp.StatusCode, dir)
+		resp.Body.Close()
+	}
+
+	resp, err = http.Post(ts.URL+"/"+IndexFileName, "application/json", nil)
+	assert.Nil(err)
+	assert.Equal(http.StatusMethodNotAllowed, resp.StatusCode)
+	resp.Body.Close()
+}
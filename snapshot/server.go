@@ -0,0 +1,120 @@
+package snapshot
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/scripttoken/script/common"
+)
+
+//
+// Server serves the snapshot directory over a read-only HTTP endpoint, so that
+// other nodes can bootstrap from the produced snapshots
+//
+type Server struct {
+	dir    string
+	server *http.Server
+
+	// Life cycle
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewServer creates an instance of the Server for the given snapshot directory
+func NewServer(dir string) *Server {
+	return &Server{
+		dir: dir,
+		server: &http.Server{
+			Handler: readOnlyHandler(http.FileServer(publishedFileSystem{fs: http.Dir(dir)})),
+		},
+		wg: &sync.WaitGroup{},
+	}
+}
+
+// readOnlyHandler only allows the GET and HEAD requests
+func readOnlyHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// publishedFileSystem only opens the published files. The directories are not listed,
+// and the temporary files and the staging directories of the snapshots being exported
+// are hidden.
+type publishedFileSystem struct {
+	fs http.FileSystem
+}
+
+func (pfs publishedFileSystem) Open(name string) (http.File, error) {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasSuffix(part, ".tmp") {
+			return nil, os.ErrNotExist
+		}
+	}
+	file, err := pfs.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		file.Close()
+		return nil, os.ErrNotExist
+	}
+	return file, nil
+}
+
+// Start starts the HTTP server
+func (s *Server) Start(ctx context.Context) {
+	c, cancel := context.WithCancel(ctx)
+	s.ctx = c
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go s.mainLoop()
+}
+
+// Stop stops the HTTP server
+func (s *Server) Stop() {
+	s.cancel()
+}
+
+// Wait suspends the caller goroutine
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Server) mainLoop() {
+	defer s.wg.Done()
+
+	go s.serve()
+
+	<-s.ctx.Done()
+	s.server.Shutdown(context.Background())
+}
+
+func (s *Server) serve() {
+	address := viper.GetString(common.CfgSnapshotServeAddress)
+	port := viper.GetString(common.CfgSnapshotServePort)
+	l, err := net.Listen("tcp", address+":"+port)
+	if err != nil {
+		logger.WithFields(log.Fields{"error": err}).Error("Failed to create snapshot server listener")
+		return
+	}
+	logger.WithFields(log.Fields{"address": address, "port": port, "dir": s.dir}).Info("Snapshot server started")
+
+	if err := s.server.Serve(l); err != nil && err != http.ErrServerClosed {
+		logger.Errorf("Snapshot server stopped: %v", err)
+	}
+}
@@ -455,27 +455,11 @@ func ExportSnapshotV3(db database.Database, consensus *cns.ConsensusEngine, chai
 }
 
 func ExportSnapshotV4(db database.Database, consensus *cns.ConsensusEngine, chain *blockchain.Chain, snapshotDir string, height uint64) (string, error) {
-	var lastFinalizedBlock *core.ExtendedBlock
-	if height != 0 {
-		blocks := chain.FindBlocksByHeight(height)
-		for _, block := range blocks {
-			if block.Status.IsDirectlyFinalized() {
-				lastFinalizedBlock = block
-				break
-			}
-		}
-		if lastFinalizedBlock == nil {
-			return "", fmt.Errorf("Can't find finalized block at height %v", height)
-		}
-	} else {
-		stub := consensus.GetSummary()
-		var err error
-		lastFinalizedBlock, err = chain.FindBlock(stub.LastFinalizedBlock)
-		if err != nil {
-			logger.Errorf("Failed to get block %v, %v", stub.LastFinalizedBlock, err)
-			return "", err
-		}
+	blocks, err := getSnapshotBlocks(consensus, chain, db, height)
+	if err != nil {
+		return "", err
 	}
+	lastFinalizedBlock := blocks.lastFinalizedBlock
 	sv := state.NewStoreView(lastFinalizedBlock.Height, lastFinalizedBlock.BlockHeader.StateHash, db)
 
 	currentTime := time.Now().UTC()
@@ -501,10 +485,77 @@ func ExportSnapshotV4(db database.Database, consensus *cns.ConsensusEngine, chai
 
 	// ------------ Export the Last Checkpoint Section ------------- //
 
+	err = core.WriteLastCheckpoint(writer, blocks.lastCheckpoint)
+	if err != nil {
+		return "", err
+	}
+
+	// -------------- Export the Metadata Section -------------- //
+
+	err = core.WriteMetadata(writer, blocks.metadata)
+	if err != nil {
+		return "", err
+	}
+
+	// -------------- Export the StoreView Section -------------- //
+	// Last checkpoint storeview
+	lastCheckpointBlock := blocks.lastCheckpointBlock
+	if lastFinalizedBlock.Height != common.LastCheckPointHeight(lastFinalizedBlock.Height) {
+		lastCheckpointSV := state.NewStoreView(lastCheckpointBlock.Height, lastCheckpointBlock.StateHash, db)
+		writeStoreViewV3(lastCheckpointSV, false, writer, db, common.Hash{})
+	}
+
+	// Parent block storeview
+	parentBlock := blocks.parentBlock
+	parentSV := state.NewStoreView(parentBlock.Height, parentBlock.StateHash, db)
+	writeStoreViewV3(parentSV, false, writer, db, common.Hash{})
+
+	writeStoreViewV3(sv, true, writer, db, parentSV.Hash())
+
+	return filename, nil
+}
+
+// snapshotBlocks are the blocks needed to export a snapshot of version 4 and above
+type snapshotBlocks struct {
+	lastFinalizedBlock  *core.ExtendedBlock
+	lastCheckpointBlock *core.ExtendedBlock
+	parentBlock         *core.ExtendedBlock
+	lastCheckpoint      *core.LastCheckpoint
+	metadata            *core.SnapshotMetadata
+}
+
+// getSnapshotBlocks collects the last checkpoint and the tail block trio of the
+// finalized block at the given height, or of the last finalized block if height is 0
+func getSnapshotBlocks(consensus *cns.ConsensusEngine, chain *blockchain.Chain, db database.Database, height uint64) (*snapshotBlocks, error) {
+	var lastFinalizedBlock *core.ExtendedBlock
+	if height != 0 {
+		blocks := chain.FindBlocksByHeight(height)
+		for _, block := range blocks {
+			if block.Status.IsDirectlyFinalized() {
+				lastFinalizedBlock = block
+				break
+			}
+		}
+		if lastFinalizedBlock == nil {
+			return nil, fmt.Errorf("Can't find finalized block at height %v", height)
+		}
+	} else {
+		stub := consensus.GetSummary()
+		var err error
+		lastFinalizedBlock, err = chain.FindBlock(stub.LastFinalizedBlock)
+		if err != nil {
+			logger.Errorf("Failed to get block %v, %v", stub.LastFinalizedBlock, err)
+			return nil, err
+		}
+	}
+
+	// ------------------- The Last Checkpoint ------------------- //
+
 	lastFinalizedBlockHeight := lastFinalizedBlock.Height
 	lastCheckpointHeight := common.LastCheckPointHeight(lastFinalizedBlockHeight)
 	lastCheckpoint := &core.LastCheckpoint{}
 
+	var err error
 	currHeight := lastFinalizedBlockHeight
 	currBlock := lastFinalizedBlock
 	for currHeight > lastCheckpointHeight {
@@ -512,7 +563,7 @@ func ExportSnapshotV4(db database.Database, consensus *cns.ConsensusEngine, chai
 		currBlock, err = chain.FindBlock(parentHash)
 		if err != nil {
 			logger.Errorf("Failed to get intermediate block %v, %v", parentHash.Hex(), err)
-			return "", err
+			return nil, err
 		}
 		lastCheckpoint.IntermediateHeaders = append(lastCheckpoint.IntermediateHeaders, currBlock.Block.BlockHeader)
 		currHeight = currBlock.Height
@@ -522,37 +573,32 @@ func ExportSnapshotV4(db database.Database, consensus *cns.ConsensusEngine, chai
 
 	lastCheckpoint.CheckpointHeader = lastCheckpointBlock.BlockHeader
 
-	err = core.WriteLastCheckpoint(writer, lastCheckpoint)
-	if err != nil {
-		return "", err
-	}
-
-	// -------------- Export the Metadata Section -------------- //
+	// ----------------------- The Metadata ---------------------- //
 
 	metadata := &core.SnapshotMetadata{}
 
 	parentBlock, err := chain.FindBlock(lastFinalizedBlock.Parent)
 	if err != nil {
-		return "", fmt.Errorf("Failed to find last finalized block's parent, %v", err)
+		return nil, fmt.Errorf("Failed to find last finalized block's parent, %v", err)
 	}
 	childBlock, err := getAtLeastCommittedChild(lastFinalizedBlock, chain)
 	if err != nil {
-		return "", fmt.Errorf("Failed to find last finalized block's committed child, %v", err)
+		return nil, fmt.Errorf("Failed to find last finalized block's committed child, %v", err)
 	}
 
 	if lastFinalizedBlock.HCC.BlockHash != parentBlock.Hash() {
-		return "", fmt.Errorf("Parent block hash mismatch: %v vs %v", lastFinalizedBlock.HCC.BlockHash, parentBlock.Hash())
+		return nil, fmt.Errorf("Parent block hash mismatch: %v vs %v", lastFinalizedBlock.HCC.BlockHash, parentBlock.Hash())
 	}
 
 	if childBlock.HCC.BlockHash != lastFinalizedBlock.Hash() {
-		return "", fmt.Errorf("Finalized block hash mismatch: %v vs %v", childBlock.HCC.BlockHash, lastFinalizedBlock.Hash())
+		return nil, fmt.Errorf("Finalized block hash mismatch: %v vs %v", childBlock.HCC.BlockHash, lastFinalizedBlock.Hash())
 	}
 
 	childVoteSet := chain.FindVotesByHash(childBlock.Hash())
 
 	vcpProof, err := proveVCP(parentBlock, db)
 	if err != nil {
-		return "", fmt.Errorf("Failed to get VCP Proof")
+		return nil, fmt.Errorf("Failed to get VCP Proof")
 	}
 	metadata.TailTrio = core.SnapshotBlockTrio{
 		First:  core.SnapshotFirstBlock{Header: parentBlock.BlockHeader, Proof: *vcpProof},
@@ -560,25 +606,13 @@ func ExportSnapshotV4(db database.Database, consensus *cns.ConsensusEngine, chai
 		Third:  core.SnapshotThirdBlock{Header: childBlock.BlockHeader, VoteSet: childVoteSet},
 	}
 
-	err = core.WriteMetadata(writer, metadata)
-	if err != nil {
-		return "", err
-	}
-
-	// -------------- Export the StoreView Section -------------- //
-	// Last checkpoint storeview
-	if lastFinalizedBlock.Height != lastCheckpointHeight {
-		lastCheckpointSV := state.NewStoreView(lastCheckpointBlock.Height, lastCheckpointBlock.StateHash, db)
-		writeStoreViewV3(lastCheckpointSV, false, writer, db, common.Hash{})
-	}
-
-	// Parent block storeview
-	parentSV := state.NewStoreView(parentBlock.Height, parentBlock.StateHash, db)
-	writeStoreViewV3(parentSV, false, writer, db, common.Hash{})
-
-	writeStoreViewV3(sv, true, writer, db, parentSV.Hash())
-
-	return filename, nil
+	return &snapshotBlocks{
+		lastFinalizedBlock:  lastFinalizedBlock,
+		lastCheckpointBlock: lastCheckpointBlock,
+		parentBlock:         parentBlock,
+		lastCheckpoint:      lastCheckpoint,
+		metadata:            metadata,
+	}, nil
 }
 
 func proveVCP(block *core.ExtendedBlock, db database.Database) (*core.VCPProof, error) {
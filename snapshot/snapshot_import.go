@@ -143,6 +143,18 @@ func ValidateSnapshot(snapshotFilePath, chainImportDirPath, chainCorrectionPath
 func LoadSnapshotCheckpointHeader(snapshotFilePath string) *core.BlockHeader {
 	var err error
 
+	if IsChunkedSnapshot(snapshotFilePath) {
+		manifest, err := LoadManifest(snapshotFilePath)
+		if err != nil {
+			return nil
+		}
+		metadata, err := manifest.DecodeMetadata()
+		if err != nil {
+			return nil
+		}
+		return metadata.TailTrio.Second.Header
+	}
+
 	snapshotFile, err := os.Open(snapshotFilePath)
 	if err != nil {
 		return nil
@@ -173,6 +185,10 @@ func LoadSnapshotCheckpointHeader(snapshotFilePath string) *core.BlockHeader {
 func loadSnapshot(snapshotFilePath string, db database.Database, logStr string) (*core.BlockHeader, *core.SnapshotMetadata, error) {
 	var err error
 
+	if IsChunkedSnapshot(snapshotFilePath) {
+		return loadChunkedSnapshot(snapshotFilePath, db, logStr)
+	}
+
 	snapshotFile, err := os.Open(snapshotFilePath)
 	if err != nil {
 		return nil, nil, err
@@ -201,38 +217,7 @@ func loadSnapshot(snapshotFilePath string, db database.Database, logStr string)
 			return nil, nil, fmt.Errorf("Failed to load snapshot last checkpoint, %v", err)
 		}
 
-		ckb := core.Block{
-			BlockHeader: lastCheckpoint.CheckpointHeader,
-		}
-		eckb := core.ExtendedBlock{
-			Block:  &ckb,
-			Status: core.BlockStatusTrusted, // HCC links between all three blocks
-		}
-		ckbHash := ckb.BlockHeader.Hash()
-
-		existingCkbExt := core.ExtendedBlock{}
-		if kvstore.Get(ckbHash[:], &existingCkbExt) != nil {
-			logger.Infof("Saving the last checkpoint block: %v", ckbHash.Hex())
-			err = kvstore.Put(ckbHash[:], &eckb)
-			if err != nil {
-				logger.Panicf("Failed to save the last checkpoint: %v, err: %v", ckbHash.Hex(), err)
-			}
-		}
-
-		for _, intermediateHeader := range lastCheckpoint.IntermediateHeaders {
-			ibHash := intermediateHeader.Hash()
-			eib := core.ExtendedBlock{
-				Block: &core.Block{BlockHeader: intermediateHeader},
-			}
-			existingEib := core.ExtendedBlock{}
-			if kvstore.Get(ibHash[:], &existingEib) != nil {
-				logger.Debugf("Saving intermediate blocks: %v", ibHash.Hex())
-				err = kvstore.Put(ibHash[:], &eib)
-				if err != nil {
-					logger.Panicf("Failed to save ntermediate block: %v, err: %v", ibHash.Hex(), err)
-				}
-			}
-		}
+		saveLastCheckpointBlocks(kvstore, &lastCheckpoint)
 	}
 
 	metadata := core.SnapshotMetadata{}
@@ -263,15 +248,65 @@ func loadSnapshot(snapshotFilePath string, db database.Database, logStr string)
 		}
 	}
 
+	secondBlockHeader, err := finalizeSnapshot(sv, &metadata, &lastCheckpoint, snapshotVersion, db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return secondBlockHeader, &metadata, nil
+}
+
+// saveLastCheckpointBlocks saves the last checkpoint and the intermediate blocks of a snapshot
+func saveLastCheckpointBlocks(kvstore store.Store, lastCheckpoint *core.LastCheckpoint) {
+	var err error
+	ckb := core.Block{
+		BlockHeader: lastCheckpoint.CheckpointHeader,
+	}
+	eckb := core.ExtendedBlock{
+		Block:  &ckb,
+		Status: core.BlockStatusTrusted, // HCC links between all three blocks
+	}
+	ckbHash := ckb.BlockHeader.Hash()
+
+	existingCkbExt := core.ExtendedBlock{}
+	if kvstore.Get(ckbHash[:], &existingCkbExt) != nil {
+		logger.Infof("Saving the last checkpoint block: %v", ckbHash.Hex())
+		err = kvstore.Put(ckbHash[:], &eckb)
+		if err != nil {
+			logger.Panicf("Failed to save the last checkpoint: %v, err: %v", ckbHash.Hex(), err)
+		}
+	}
+
+	for _, intermediateHeader := range lastCheckpoint.IntermediateHeaders {
+		ibHash := intermediateHeader.Hash()
+		eib := core.ExtendedBlock{
+			Block: &core.Block{BlockHeader: intermediateHeader},
+		}
+		existingEib := core.ExtendedBlock{}
+		if kvstore.Get(ibHash[:], &existingEib) != nil {
+			logger.Debugf("Saving intermediate blocks: %v", ibHash.Hex())
+			err = kvstore.Put(ibHash[:], &eib)
+			if err != nil {
+				logger.Panicf("Failed to save ntermediate block: %v, err: %v", ibHash.Hex(), err)
+			}
+		}
+	}
+}
+
+// finalizeSnapshot checks the loaded snapshot state against the block trios, and saves the proofs and the tail blocks
+func finalizeSnapshot(sv *state.StoreView, metadata *core.SnapshotMetadata, lastCheckpoint *core.LastCheckpoint, snapshotVersion uint, db database.Database) (*core.BlockHeader, error) {
+	var err error
+	kvstore := kvstore.NewKVStore(db)
+
 	// ----------------------------- Validity Checks -------------------------- //
 
 	if snapshotVersion >= 4 {
-		if err = checkSnapshotV4(sv, &metadata, db); err != nil {
-			return nil, nil, fmt.Errorf("Snapshot state validation failed: %v", err)
+		if err = checkSnapshotV4(sv, metadata, db); err != nil {
+			return nil, fmt.Errorf("Snapshot state validation failed: %v", err)
 		}
 	} else {
-		if err = checkSnapshot(sv, &metadata, db); err != nil {
-			return nil, nil, fmt.Errorf("Snapshot state validation failed: %v", err)
+		if err = checkSnapshot(sv, metadata, db); err != nil {
+			return nil, fmt.Errorf("Snapshot state validation failed: %v", err)
 		}
 	}
 
@@ -285,17 +320,17 @@ func loadSnapshot(snapshotFilePath string, db database.Database, logStr string)
 		}
 	}
 
-	secondBlockHeader := saveTailBlocks(&metadata, sv, kvstore)
+	secondBlockHeader := saveTailBlocks(metadata, sv, kvstore)
 
 	// ----------------------------- More Validity Checks -------------------------- //
 
 	if snapshotVersion >= 2 {
-		if err = checkLastCheckpoint(sv, secondBlockHeader, &lastCheckpoint, db); err != nil {
-			return nil, nil, fmt.Errorf("Snapshot last checkpoint validation failed: %v", err)
+		if err = checkLastCheckpoint(sv, secondBlockHeader, lastCheckpoint, db); err != nil {
+			return nil, fmt.Errorf("Snapshot last checkpoint validation failed: %v", err)
 		}
 	}
 
-	return secondBlockHeader, &metadata, nil
+	return secondBlockHeader, nil
 }
 
 func LoadChainCorrection(chainImportDirPath string, snapshotBlockHeader *core.BlockHeader, metadata *core.SnapshotMetadata, chain *blockchain.Chain, db database.Database, ledger *ledger.Ledger) (headBlock, tailBlock *core.ExtendedBlock, err error) {
@@ -681,7 +716,7 @@ func loadStateV2(file *os.File, db database.Database, fileSize uint64, logStr st
 	return sv, hash, nil
 }
 
-func loadStateV3(file *os.File, db database.Database, fileSize uint64, logStr string) error {
+func loadStateV3(file io.Reader, db database.Database, fileSize uint64, logStr string) error {
 	var progress, curSize uint64
 	batch := db.NewBatch()
 	record := core.SnapshotTrieRecord{}
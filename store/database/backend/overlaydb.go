@@ -0,0 +1,48 @@
+package backend
+
+import (
+	"github.com/scripttoken/script/store/database"
+)
+
+// OverlayDatabase keeps its writes in memory on top of a base database, which it only reads.
+// The writes are discarded with the OverlayDatabase and never reach the base database, e.g.
+// the trie nodes committed by a speculative transaction execution.
+type OverlayDatabase struct {
+	*MemDatabase
+	base database.Database
+}
+
+// NewOverlayDatabase returns an OverlayDatabase on top of the given base database
+func NewOverlayDatabase(base database.Database) *OverlayDatabase {
+	return &OverlayDatabase{
+		MemDatabase: NewMemDatabase(),
+		base:        base,
+	}
+}
+
+// Get returns the value written to the overlay, or else the value of the base database
+func (db *OverlayDatabase) Get(key []byte) ([]byte, error) {
+	if value, err := db.MemDatabase.Get(key); err == nil {
+		return value, nil
+	}
+	return db.base.Get(key)
+}
+
+// Has returns whether the key is written to the overlay or exists in the base database
+func (db *OverlayDatabase) Has(key []byte) (bool, error) {
+	if ok, _ := db.MemDatabase.Has(key); ok {
+		return true, nil
+	}
+	return db.base.Has(key)
+}
+
+// CountReference returns the reference count of the key in the overlay, or else the one of
+// the base database
+func (db *OverlayDatabase) CountReference(key []byte) (int, error) {
+	if ref, err := db.MemDatabase.CountReference(key); err == nil {
+		return ref, nil
+	}
+	return db.base.CountReference(key)
+}
+
+var _ database.Database = (*OverlayDatabase)(nil)
@@ -0,0 +1,39 @@
+package backend
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOverlayDB_PutGet(t *testing.T) {
+	overlayDB := NewOverlayDatabase(NewMemDatabase())
+	testPutGet(overlayDB, overlayDB.NewBatch(), t)
+}
+
+func TestOverlayDB_BaseUntouched(t *testing.T) {
+	baseDB := NewMemDatabase()
+	baseDB.Put([]byte("base"), []byte("value"))
+	baseDB.Reference([]byte("base"))
+
+	overlayDB := NewOverlayDatabase(baseDB)
+	if value, err := overlayDB.Get([]byte("base")); err != nil || !bytes.Equal(value, []byte("value")) {
+		t.Fatalf("get base key returned wrong result, got %q expected %q", string(value), "value")
+	}
+	overlayDB.Put([]byte("base"), []byte("overlay"))
+	overlayDB.Put([]byte("overlay"), []byte("value"))
+	overlayDB.Reference([]byte("overlay"))
+	overlayDB.Close()
+
+	if value, err := overlayDB.Get([]byte("base")); err != nil || !bytes.Equal(value, []byte("overlay")) {
+		t.Fatalf("get overlay key returned wrong result, got %q expected %q", string(value), "overlay")
+	}
+	if value, err := baseDB.Get([]byte("base")); err != nil || !bytes.Equal(value, []byte("value")) {
+		t.Fatalf("base key was overwritten, got %q expected %q", string(value), "value")
+	}
+	if ok, _ := baseDB.Has([]byte("overlay")); ok {
+		t.Fatalf("overlay key was written to the base database")
+	}
+	if ref, err := overlayDB.CountReference([]byte("base")); err != nil || ref != 1 {
+		t.Fatalf("wrong reference count of the base key, got %v expected 1", ref)
+	}
+}
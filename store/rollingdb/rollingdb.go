@@ -13,11 +13,14 @@ import (
 	"github.com/scripttoken/script/blockchain"
 	"github.com/scripttoken/script/common"
 	"github.com/scripttoken/script/common/util"
+	"github.com/scripttoken/script/rlp"
 	"github.com/scripttoken/script/store/database"
 )
 
 var logger = util.GetLoggerForModule("rollingdb")
 
+var oldestStateHeightKey = []byte("/oldeststateheight")
+
 type RollingDB struct {
 	mu sync.RWMutex
 
@@ -65,7 +68,7 @@ func (rdb *RollingDB) SetChain(chain *blockchain.Chain) {
 func (rdb *RollingDB) loadLayers(rollingPath string) (*DBLayer, []*DBLayer) {
 	files, err := ioutil.ReadDir(rollingPath)
 	if err != nil {
-		logger.Panicf("Failed to load layers", err)
+		logger.Panicf("Failed to load layers: %v", err)
 	}
 	names := []int{}
 	for _, file := range files {
@@ -80,7 +83,7 @@ func (rdb *RollingDB) loadLayers(rollingPath string) (*DBLayer, []*DBLayer) {
 	}
 
 	if len(names) == 0 {
-		if !viper.GetBool(common.CfgStorageRollingEnabled) {
+		if !IsRollingEnabled() {
 			return rdb.rootLayer, nil
 		}
 		return NewDBLayer(rollingPath, 1), nil
@@ -97,7 +100,7 @@ func (rdb *RollingDB) loadLayers(rollingPath string) (*DBLayer, []*DBLayer) {
 }
 
 func (rdb *RollingDB) Tag(height uint64, stateRoot common.Hash) {
-	if !viper.GetBool(common.CfgStorageRollingEnabled) {
+	if !IsRollingEnabled() {
 		return
 	}
 
@@ -126,7 +129,7 @@ func (rdb *RollingDB) addLayer() {
 }
 
 func (rdb *RollingDB) compact(height uint64) {
-	if !viper.GetBool(common.CfgStorageStatePruningEnabled) {
+	if !viper.GetBool(common.CfgStorageStatePruningEnabled) || common.IsArchiveMode() {
 		return
 	}
 
@@ -201,6 +204,7 @@ func (rdb *RollingDB) compact(height uint64) {
 							}
 						}
 						rdb.layers = remainingLayers
+						rdb.setOldestStateHeight(sourceLayer.tag.Height)
 						break
 					}
 				}
@@ -214,6 +218,58 @@ func (rdb *RollingDB) compact(height uint64) {
 
 }
 
+// InitOldestStateHeight records the given height as the oldest available state height,
+// unless a height has already been recorded.
+func (rdb *RollingDB) InitOldestStateHeight(height uint64) {
+	if _, ok := GetOldestStateHeight(rdb); ok {
+		return
+	}
+
+	rdb.mu.RLock()
+	if len(rdb.layers) > 0 && rdb.layers[0].name > 1 && rdb.layers[0].tag.Height > height {
+		// Layers were compacted before the height was recorded. The tag height of the oldest
+		// remaining layer is a conservative estimate of the oldest available state.
+		height = rdb.layers[0].tag.Height
+	}
+	rdb.mu.RUnlock()
+
+	rdb.setOldestStateHeight(height)
+}
+
+func (rdb *RollingDB) setOldestStateHeight(height uint64) {
+	raw, err := rlp.EncodeToBytes(height)
+	if err != nil {
+		logger.Panicf("Failed to encode oldest state height: %v", err)
+	}
+	// Always write to the root layer, since the rolling layers might be destroyed by compaction
+	err = rdb.root.Put(oldestStateHeightKey, raw)
+	if err != nil {
+		logger.Errorf("Failed to save oldest state height %v: %v", height, err)
+		return
+	}
+	logger.Infof("Oldest available state height: %v", height)
+}
+
+// GetOldestStateHeight returns the height of the oldest state retained in the given DB
+func GetOldestStateHeight(db database.Database) (uint64, bool) {
+	raw, err := db.Get(oldestStateHeightKey)
+	if err != nil {
+		return 0, false
+	}
+	var height uint64
+	err = rlp.DecodeBytes(raw, &height)
+	if err != nil {
+		logger.Warnf("Failed to decode oldest state height: %v", err)
+		return 0, false
+	}
+	return height, true
+}
+
+// IsRollingEnabled returns false in the archive mode, where every historical state is retained
+func IsRollingEnabled() bool {
+	return viper.GetBool(common.CfgStorageRollingEnabled) && !common.IsArchiveMode()
+}
+
 func isRollingHeight(height uint64) bool {
 	return int(height)%viper.GetInt(common.CfgStorageRollingInterval) == 50
 }
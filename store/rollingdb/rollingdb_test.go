@@ -0,0 +1,104 @@
+package rollingdb
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/ledger/state"
+	"github.com/scripttoken/script/ledger/types"
+	"github.com/scripttoken/script/store/database/backend"
+)
+
+func newTestRollingDB(t *testing.T) (*RollingDB, func()) {
+	dir, err := ioutil.TempDir("", "rollingdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(path.Join(dir, "db"), 0700)
+	rdb := NewRollingDB(dir, backend.NewMemDatabase())
+	return rdb, func() {
+		rdb.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestArchiveModeDisablesRolling(t *testing.T) {
+	assert := assert.New(t)
+
+	viper.Set(common.CfgStorageMode, common.StorageModeArchive)
+	defer viper.Set(common.CfgStorageMode, common.StorageModeDefault)
+
+	rdb, cleanup := newTestRollingDB(t)
+	defer cleanup()
+
+	assert.Equal(rdb.rootLayer, rdb.activeLayer)
+
+	interval := uint64(viper.GetInt(common.CfgStorageRollingInterval))
+	rdb.Tag(interval+50, common.Hash{0x1})
+	rdb.Tag(interval+70, common.Hash{0x2})
+	assert.Equal(0, len(rdb.layers))
+	assert.Equal(rdb.rootLayer, rdb.activeLayer)
+}
+
+func TestOldestStateHeight(t *testing.T) {
+	assert := assert.New(t)
+
+	rdb, cleanup := newTestRollingDB(t)
+	defer cleanup()
+
+	_, ok := GetOldestStateHeight(rdb)
+	assert.False(ok)
+
+	rdb.InitOldestStateHeight(100)
+	height, ok := GetOldestStateHeight(rdb)
+	assert.True(ok)
+	assert.Equal(uint64(100), height)
+
+	// Once recorded, the height is not overridden by initialization
+	rdb.InitOldestStateHeight(10)
+	height, _ = GetOldestStateHeight(rdb)
+	assert.Equal(uint64(100), height)
+
+	// The height is kept in the root layer
+	height, ok = GetOldestStateHeight(rdb.root)
+	assert.True(ok)
+	assert.Equal(uint64(100), height)
+
+	rdb.setOldestStateHeight(14450)
+	height, _ = GetOldestStateHeight(rdb)
+	assert.Equal(uint64(14450), height)
+}
+
+func TestCopyStateWithAsset(t *testing.T) {
+	assert := assert.New(t)
+
+	source := backend.NewMemDatabase()
+	sv := state.NewStoreView(1, common.Hash{}, source)
+	addr := common.BigToAddress(big.NewInt(1))
+	sv.AddBalance(addr, big.NewInt(1000))
+	sv.SetState(addr, common.BigToHash(big.NewInt(1)), common.BigToHash(big.NewInt(2)))
+	asset := &types.Asset{
+		ID:       common.HexToAddress("0x2e833968e5bb786ae419c4d13189fb081cc43bab"),
+		Issuer:   addr,
+		Name:     "Test Token",
+		Symbol:   "TT",
+		Decimals: 18,
+		Supply:   big.NewInt(1000000),
+	}
+	sv.SetAsset(asset)
+	root := sv.Save()
+
+	target := backend.NewMemDatabase()
+	copyState(source, target.NewBatch(), root)
+
+	copied := state.NewStoreView(1, root, target)
+	assert.Equal(asset, copied.GetAsset(asset.ID))
+	assert.Equal(common.BigToHash(big.NewInt(2)), copied.GetState(addr, common.BigToHash(big.NewInt(1))))
+	assert.Equal(int64(1000), copied.GetBalance(addr).Int64())
+}
@@ -76,6 +76,18 @@ func (store *TreeStore) Copy() (*TreeStore, error) {
 	return copiedStore, nil
 }
 
+// CopyWithDB returns a copy of the TreeStore with the given underlying database, e.g. an
+// in-memory overlay of the database of the TreeStore. The nodes of the copied trie still go
+// to the in-memory trie DB of the TreeStore, which is only flushed by Commit.
+func (store *TreeStore) CopyWithDB(db database.Database) (*TreeStore, error) {
+	copiedStore, err := store.Copy()
+	if err != nil {
+		return nil, err
+	}
+	copiedStore.db = db
+	return copiedStore, nil
+}
+
 // Get retrieves value of given key.
 func (store *TreeStore) Get(key common.Bytes) common.Bytes {
 	return store.Trie.Get(key)
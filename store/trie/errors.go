@@ -34,3 +34,15 @@ type MissingNodeError struct {
 func (err *MissingNodeError) Error() string {
 	return fmt.Sprintf("missing trie node %x (path %x)", err.NodeHash, err.Path)
 }
+
+// CorruptedNodeError is reported when a trie node is present in the database
+// but its content does not match its hash or cannot be decoded.
+type CorruptedNodeError struct {
+	NodeHash common.Hash // hash of the corrupted node
+	Path     []byte      // hex-encoded path to the corrupted node
+	Err      error       // underlying reason
+}
+
+func (err *CorruptedNodeError) Error() string {
+	return fmt.Sprintf("corrupted trie node %x (path %x): %v", err.NodeHash, err.Path, err.Err)
+}
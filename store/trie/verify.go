@@ -0,0 +1,113 @@
+package trie
+
+import (
+	"fmt"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/crypto"
+)
+
+// VerifyCallbacks are the hooks invoked while verifying a trie. Any of them can
+// be nil. Returning an error from a callback aborts the verification, and the
+// error is passed through to the caller of VerifyTrie.
+type VerifyCallbacks struct {
+	// OnNode is called for every node that has been loaded and hash-checked.
+	OnNode func(hash common.Hash, blob []byte) error
+
+	// OnLeaf is called for every key/value pair stored in the trie.
+	OnLeaf func(key, value []byte) error
+
+	// OnMissing is called when a referenced node is not in the database. The
+	// subtrie below the missing node is skipped.
+	OnMissing func(err *MissingNodeError) error
+
+	// OnCorrupted is called when a node fails the hash check or cannot be
+	// decoded. The subtrie below the corrupted node is skipped.
+	OnCorrupted func(err *CorruptedNodeError) error
+}
+
+// VerifyTrie walks the trie with the given root and checks that every node
+// is present in the database and that its content matches its hash.
+func VerifyTrie(root common.Hash, db DatabaseReader, callbacks *VerifyCallbacks) error {
+	if root == (common.Hash{}) || root == emptyRoot {
+		return nil
+	}
+	if callbacks == nil {
+		callbacks = &VerifyCallbacks{}
+	}
+	v := &trieVerifier{db: db, callbacks: callbacks}
+	return v.verifyHash(root.Bytes(), nil)
+}
+
+type trieVerifier struct {
+	db        DatabaseReader
+	callbacks *VerifyCallbacks
+}
+
+func (v *trieVerifier) verifyHash(hash hashNode, path []byte) error {
+	nodeHash := common.BytesToHash(hash)
+	blob, err := v.db.Get(hash)
+	if err != nil || len(blob) == 0 {
+		return v.missing(nodeHash, path)
+	}
+	if computed := crypto.Keccak256Hash(blob); computed != nodeHash {
+		return v.corrupted(nodeHash, path, fmt.Errorf("hash mismatch, computed %v", computed.Hex()))
+	}
+	n, err := decodeNode(hash, blob, 0)
+	if err != nil {
+		return v.corrupted(nodeHash, path, err)
+	}
+	if v.callbacks.OnNode != nil {
+		if err := v.callbacks.OnNode(nodeHash, blob); err != nil {
+			return err
+		}
+	}
+	return v.verifyNode(nodeHash, n, path)
+}
+
+// verifyNode checks the given node, parent is the hash of the closest hashed
+// ancestor which is reported if an embedded node turns out to be malformed.
+func (v *trieVerifier) verifyNode(parent common.Hash, n node, path []byte) error {
+	switch n := n.(type) {
+	case *shortNode:
+		return v.verifyNode(parent, n.Val, append(append([]byte{}, path...), n.Key...))
+	case *fullNode:
+		for i, child := range &n.Children {
+			if child == nil {
+				continue
+			}
+			if err := v.verifyNode(parent, child, append(append([]byte{}, path...), byte(i))); err != nil {
+				return err
+			}
+		}
+	case hashNode:
+		return v.verifyHash(n, path)
+	case valueNode:
+		if v.callbacks.OnLeaf == nil {
+			return nil
+		}
+		key := path
+		if hasTerm(key) {
+			key = key[:len(key)-1]
+		}
+		if len(key)&1 != 0 {
+			return v.corrupted(parent, path, fmt.Errorf("value at odd length path"))
+		}
+		return v.callbacks.OnLeaf(hexToKeybytes(key), n)
+	}
+	return nil
+}
+
+func (v *trieVerifier) missing(hash common.Hash, path []byte) error {
+	if v.callbacks.OnMissing == nil {
+		return nil
+	}
+	return v.callbacks.OnMissing(&MissingNodeError{NodeHash: hash, Path: path})
+}
+
+func (v *trieVerifier) corrupted(hash common.Hash, path []byte, err error) error {
+	if v.callbacks.OnCorrupted == nil {
+		return nil
+	}
+	return v.callbacks.OnCorrupted(&CorruptedNodeError{NodeHash: hash, Path: path, Err: err})
+}
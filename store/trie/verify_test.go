@@ -0,0 +1,126 @@
+package trie
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/scripttoken/script/common"
+	dbbackend "github.com/scripttoken/script/store/database/backend"
+)
+
+func makeTestDiskTrie(t *testing.T) (*dbbackend.MemDatabase, common.Hash, map[string][]byte) {
+	diskdb := dbbackend.NewMemDatabase()
+	triedb := NewDatabase(diskdb)
+	trie, _ := New(common.Hash{}, triedb)
+
+	content := make(map[string][]byte)
+	for i := byte(0); i < 200; i++ {
+		key, val := common.LeftPadBytes([]byte{1, i}, 32), []byte{i}
+		content[string(key)] = val
+		trie.Update(key, val)
+
+		key, val = common.LeftPadBytes([]byte{i}, 2), bytes.Repeat([]byte{i}, 40)
+		content[string(key)] = val
+		trie.Update(key, val)
+	}
+	root, err := trie.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie database: %v", err)
+	}
+	return diskdb, root, content
+}
+
+func TestVerifyTrie(t *testing.T) {
+	diskdb, root, content := makeTestDiskTrie(t)
+
+	numNodes := 0
+	found := make(map[string][]byte)
+	callbacks := &VerifyCallbacks{
+		OnNode: func(hash common.Hash, blob []byte) error {
+			numNodes++
+			return nil
+		},
+		OnLeaf: func(key, value []byte) error {
+			found[string(key)] = value
+			return nil
+		},
+		OnMissing: func(err *MissingNodeError) error {
+			t.Errorf("unexpected missing node: %v", err)
+			return nil
+		},
+		OnCorrupted: func(err *CorruptedNodeError) error {
+			t.Errorf("unexpected corrupted node: %v", err)
+			return nil
+		},
+	}
+	if err := VerifyTrie(root, diskdb, callbacks); err != nil {
+		t.Fatalf("failed to verify trie: %v", err)
+	}
+	if numNodes == 0 || numNodes > diskdb.Len() {
+		t.Errorf("unexpected number of nodes: %v, db size: %v", numNodes, diskdb.Len())
+	}
+	if len(found) != len(content) {
+		t.Errorf("leaf count mismatch: have %v, want %v", len(found), len(content))
+	}
+	for k, v := range content {
+		if !bytes.Equal(found[k], v) {
+			t.Errorf("leaf mismatch for %x: have %x, want %x", k, found[k], v)
+		}
+	}
+}
+
+func TestVerifyTrieMissingAndCorruptedNodes(t *testing.T) {
+	diskdb, root, _ := makeTestDiskTrie(t)
+
+	var hashes []common.Hash
+	VerifyTrie(root, diskdb, &VerifyCallbacks{
+		OnNode: func(hash common.Hash, blob []byte) error {
+			if hash != root {
+				hashes = append(hashes, hash)
+			}
+			return nil
+		},
+	})
+	if len(hashes) < 2 {
+		t.Fatalf("test trie too small: %v nodes", len(hashes))
+	}
+	missingHash := hashes[len(hashes)-1]
+	corruptedHash := hashes[len(hashes)-2]
+	diskdb.Delete(missingHash[:])
+	diskdb.Put(corruptedHash[:], []byte{0xc0})
+
+	var missing []*MissingNodeError
+	var corrupted []*CorruptedNodeError
+	err := VerifyTrie(root, diskdb, &VerifyCallbacks{
+		OnMissing: func(err *MissingNodeError) error {
+			missing = append(missing, err)
+			return nil
+		},
+		OnCorrupted: func(err *CorruptedNodeError) error {
+			corrupted = append(corrupted, err)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to verify trie: %v", err)
+	}
+	if len(missing) != 1 || missing[0].NodeHash != missingHash {
+		t.Errorf("unexpected missing nodes: %v", missing)
+	}
+	if len(corrupted) != 1 || corrupted[0].NodeHash != corruptedHash {
+		t.Errorf("unexpected corrupted nodes: %v", corrupted)
+	}
+
+	// Errors returned by the callbacks abort the verification
+	abortErr := VerifyTrie(root, diskdb, &VerifyCallbacks{
+		OnMissing: func(err *MissingNodeError) error {
+			return err
+		},
+	})
+	if _, ok := abortErr.(*MissingNodeError); !ok {
+		t.Errorf("wrong error: %v", abortErr)
+	}
+}
@@ -0,0 +1,127 @@
+package verifier
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/core"
+	"github.com/scripttoken/script/store/database"
+)
+
+const (
+	// maxRepairRounds bounds the verify/fetch iterations, each round restores
+	// at least one more level of the missing subtries
+	maxRepairRounds = 64
+
+	fetchTimeout = 30 * time.Second
+)
+
+//
+// BackgroundVerifier periodically verifies the state of the last finalized block
+// at a low priority, and optionally re-fetches the missing nodes from the peers
+//
+type BackgroundVerifier struct {
+	verifier  *StateVerifier
+	fetcher   *NodeFetcher
+	consensus core.ConsensusEngine
+	interval  time.Duration
+
+	// Life cycle
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewBackgroundVerifier creates an instance of the BackgroundVerifier. The missing
+// nodes are not repaired if fetcher is nil.
+func NewBackgroundVerifier(db database.Database, refDB database.Database, consensus core.ConsensusEngine, fetcher *NodeFetcher) *BackgroundVerifier {
+	verifier := NewStateVerifier(db, refDB)
+	verifier.SetThrottle(viper.GetInt(common.CfgStorageVerifyNodesPerSecond))
+
+	return &BackgroundVerifier{
+		verifier:  verifier,
+		fetcher:   fetcher,
+		consensus: consensus,
+		interval:  time.Duration(viper.GetInt(common.CfgStorageVerifyInterval)) * time.Second,
+		wg:        &sync.WaitGroup{},
+	}
+}
+
+// Start starts the background verification
+func (bv *BackgroundVerifier) Start(ctx context.Context) {
+	c, cancel := context.WithCancel(ctx)
+	bv.ctx = c
+	bv.cancel = cancel
+
+	bv.wg.Add(1)
+	go bv.mainLoop()
+}
+
+// Stop stops the background verification
+func (bv *BackgroundVerifier) Stop() {
+	bv.cancel()
+}
+
+// Wait suspends the caller goroutine
+func (bv *BackgroundVerifier) Wait() {
+	bv.wg.Wait()
+}
+
+func (bv *BackgroundVerifier) mainLoop() {
+	defer bv.wg.Done()
+
+	ticker := time.NewTicker(bv.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-bv.ctx.Done():
+			return
+		case <-ticker.C:
+			block := bv.consensus.GetLastFinalizedBlock()
+			if block == nil {
+				continue
+			}
+			logger.Infof("Verifying the state of block %v, height: %v, state hash: %v",
+				block.Hash().Hex(), block.Height, block.StateHash.Hex())
+
+			report, err := VerifyAndRepair(bv.ctx, bv.verifier, bv.fetcher, block.StateHash)
+			if err != nil {
+				if bv.ctx.Err() == nil {
+					logger.Errorf("Failed to verify state %v: %v", block.StateHash.Hex(), err)
+				}
+				continue
+			}
+			if report.IsHealthy() {
+				logger.Infof("State verified: %v", report)
+			} else {
+				logger.Errorf("State verification failed: %v", report)
+			}
+		}
+	}
+}
+
+// VerifyAndRepair verifies the given state, and re-fetches the missing nodes from
+// the peers until the state is complete or no more progress can be made. The
+// report of the last verification is returned.
+func VerifyAndRepair(ctx context.Context, verifier *StateVerifier, fetcher *NodeFetcher, stateHash common.Hash) (*Report, error) {
+	for round := 0; ; round++ {
+		report, err := verifier.Verify(ctx, stateHash)
+		if err != nil {
+			return nil, err
+		}
+		if len(report.MissingNodes) == 0 || fetcher == nil || round >= maxRepairRounds {
+			return report, nil
+		}
+
+		fetched := fetcher.Fetch(ctx, report.MissingNodes, fetchTimeout)
+		logger.Infof("Restored %v out of %v missing nodes of state %v", fetched, len(report.MissingNodes), stateHash.Hex())
+		if fetched == 0 {
+			return report, nil
+		}
+	}
+}
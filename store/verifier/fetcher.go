@@ -0,0 +1,232 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/crypto"
+	dp "github.com/scripttoken/script/dispatcher"
+	"github.com/scripttoken/script/p2p/types"
+	"github.com/scripttoken/script/rlp"
+	"github.com/scripttoken/script/store/database"
+)
+
+// StateNodes is the payload of the DataResponse sent over the ChannelIDStateNode channel
+type StateNodes struct {
+	Nodes []common.Bytes
+}
+
+//
+// NodeFetcher serves the state trie nodes requested by the peers, and re-fetches
+// the locally missing nodes from the peers. It handles the messages received over
+// the ChannelIDStateNode channel
+//
+type NodeFetcher struct {
+	db         database.Database
+	dispatcher *dp.Dispatcher
+
+	mu      *sync.Mutex
+	pending map[common.Hash]chan struct{}
+}
+
+// NewNodeFetcher creates an instance of the NodeFetcher
+func NewNodeFetcher(db database.Database, dispatcher *dp.Dispatcher) *NodeFetcher {
+	return &NodeFetcher{
+		db:         db,
+		dispatcher: dispatcher,
+		mu:         &sync.Mutex{},
+		pending:    make(map[common.Hash]chan struct{}),
+	}
+}
+
+// Fetch requests the given nodes from the peers, and waits until all of them are
+// received or the timeout is reached. It returns the number of nodes restored.
+func (nf *NodeFetcher) Fetch(ctx context.Context, hashes []common.Hash, timeout time.Duration) int {
+	requested := []common.Hash{}
+	waits := []chan struct{}{}
+	nf.mu.Lock()
+	for _, hash := range hashes {
+		if _, ok := nf.pending[hash]; ok {
+			continue // already being fetched
+		}
+		done := make(chan struct{})
+		nf.pending[hash] = done
+		requested = append(requested, hash)
+		waits = append(waits, done)
+	}
+	nf.mu.Unlock()
+
+	defer func() {
+		nf.mu.Lock()
+		for i, hash := range requested {
+			if nf.pending[hash] == waits[i] {
+				delete(nf.pending, hash)
+			}
+		}
+		nf.mu.Unlock()
+	}()
+
+	// Only ask the peers serving the state nodes, all the neighbors if none advertised it
+	peerIDs := nf.dispatcher.PeersWithCapability(types.CapabilityStateSync, true)
+	for i := 0; i < len(requested); i += dp.MaxInventorySize {
+		end := i + dp.MaxInventorySize
+		if end > len(requested) {
+			end = len(requested)
+		}
+		entries := []string{}
+		for _, hash := range requested[i:end] {
+			entries = append(entries, hash.Hex())
+		}
+		nf.dispatcher.GetData(peerIDs, dp.DataRequest{
+			ChannelID: common.ChannelIDStateNode,
+			Entries:   entries,
+		})
+	}
+
+	fetched := 0
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for _, done := range waits {
+		select {
+		case <-done:
+			fetched++
+		case <-timer.C:
+			return fetched
+		case <-ctx.Done():
+			return fetched
+		}
+	}
+	return fetched
+}
+
+// GetChannelIDs implements the p2p.MessageHandler interface
+func (nf *NodeFetcher) GetChannelIDs() []common.ChannelIDEnum {
+	return []common.ChannelIDEnum{
+		common.ChannelIDStateNode,
+	}
+}
+
+// EncodeMessage implements the p2p.MessageHandler interface
+func (nf *NodeFetcher) EncodeMessage(message interface{}) (common.Bytes, error) {
+	var buf bytes.Buffer
+	var msgID common.MessageIDEnum
+	switch message.(type) {
+	case dp.DataRequest:
+		msgID = common.MessageIDDataRequest
+	case dp.DataResponse:
+		msgID = common.MessageIDDataResponse
+	default:
+		return nil, errors.New("Unsupported message type")
+	}
+	if err := rlp.Encode(&buf, msgID); err != nil {
+		return nil, err
+	}
+	if err := rlp.Encode(&buf, message); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseMessage implements the p2p.MessageHandler interface
+func (nf *NodeFetcher) ParseMessage(peerID string, channelID common.ChannelIDEnum, rawMessageBytes common.Bytes) (types.Message, error) {
+	message := types.Message{
+		PeerID:    peerID,
+		ChannelID: channelID,
+	}
+	if len(rawMessageBytes) <= 1 {
+		return message, fmt.Errorf("Invalid message size")
+	}
+	var msgID common.MessageIDEnum
+	if err := rlp.DecodeBytes(rawMessageBytes[:1], &msgID); err != nil {
+		return message, err
+	}
+	switch msgID {
+	case common.MessageIDDataRequest:
+		data := dp.DataRequest{}
+		err := rlp.DecodeBytes(rawMessageBytes[1:], &data)
+		message.Content = data
+		return message, err
+	case common.MessageIDDataResponse:
+		data := dp.DataResponse{}
+		err := rlp.DecodeBytes(rawMessageBytes[1:], &data)
+		message.Content = data
+		return message, err
+	default:
+		return message, fmt.Errorf("Unknown message ID: %v", msgID)
+	}
+}
+
+// HandleMessage implements the p2p.MessageHandler interface
+func (nf *NodeFetcher) HandleMessage(message types.Message) error {
+	if message.ChannelID != common.ChannelIDStateNode {
+		return fmt.Errorf("Invalid channel for NodeFetcher: %v", message.ChannelID)
+	}
+	switch content := message.Content.(type) {
+	case dp.DataRequest:
+		return nf.handleDataRequest(message.PeerID, &content)
+	case dp.DataResponse:
+		return nf.handleDataResponse(message.PeerID, &content)
+	default:
+		return fmt.Errorf("Unknown message type: %v", message)
+	}
+}
+
+func (nf *NodeFetcher) handleDataRequest(peerID string, data *dp.DataRequest) error {
+	if len(data.Entries) > dp.MaxInventorySize {
+		return fmt.Errorf("Too many state nodes requested: %v", len(data.Entries))
+	}
+	nodes := &StateNodes{}
+	for _, hashStr := range data.Entries {
+		hash := common.HexToHash(hashStr)
+		blob, err := nf.db.Get(hash.Bytes())
+		// Only serve the trie nodes, i.e. entries keyed by the hash of their content
+		if err != nil || crypto.Keccak256Hash(blob) != hash {
+			continue
+		}
+		nodes.Nodes = append(nodes.Nodes, blob)
+	}
+	if len(nodes.Nodes) == 0 {
+		return nil
+	}
+	payload, err := rlp.EncodeToBytes(nodes)
+	if err != nil {
+		return err
+	}
+	logger.Debugf("Sending %v state nodes to peer %v", len(nodes.Nodes), peerID)
+	nf.dispatcher.SendData([]string{peerID}, dp.DataResponse{
+		ChannelID: common.ChannelIDStateNode,
+		Payload:   payload,
+	})
+	return nil
+}
+
+func (nf *NodeFetcher) handleDataResponse(peerID string, data *dp.DataResponse) error {
+	nodes := &StateNodes{}
+	if err := rlp.DecodeBytes(data.Payload, nodes); err != nil {
+		return err
+	}
+
+	nf.mu.Lock()
+	defer nf.mu.Unlock()
+
+	for _, blob := range nodes.Nodes {
+		hash := crypto.Keccak256Hash(blob)
+		done, ok := nf.pending[hash]
+		if !ok {
+			continue // not requested, or already received from another peer
+		}
+		if err := nf.db.Put(hash.Bytes(), blob); err != nil {
+			logger.Errorf("Failed to save state node %v: %v", hash.Hex(), err)
+			continue
+		}
+		logger.Debugf("Restored state node %v from peer %v", hash.Hex(), peerID)
+		delete(nf.pending, hash)
+		close(done)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/scripttoken/script/common"
+	"github.com/scripttoken/script/ledger/types"
+	"github.com/scripttoken/script/store/database"
+	"github.com/scripttoken/script/store/trie"
+)
+
+var logger *log.Entry = log.WithFields(log.Fields{"prefix": "verifier"})
+
+// accountKeyPrefix is the state key prefix of the accounts, see state.AccountKey()
+var accountKeyPrefix = []byte("ls/a/")
+
+// throttleBatchSize is the number of nodes checked between two throttling pauses
+const throttleBatchSize = 256
+
+// Report summarizes the result of a state DB verification
+type Report struct {
+	StateHash         common.Hash
+	NumNodes          uint64
+	NumAccounts       uint64
+	NumStorageTries   uint64
+	MissingNodes      []common.Hash
+	CorruptedNodes    []common.Hash
+	UnreferencedNodes []common.Hash
+	Elapsed           time.Duration
+}
+
+// IsHealthy returns true if all the state trie nodes are present and intact. Nodes
+// with a zero ref count do not break the state, and are only reported as warnings.
+func (r *Report) IsHealthy() bool {
+	return len(r.MissingNodes) == 0 && len(r.CorruptedNodes) == 0
+}
+
+func (r *Report) String() string {
+	return fmt.Sprintf("Report{StateHash: %v, NumNodes: %v, NumAccounts: %v, NumStorageTries: %v, Missing: %v, Corrupted: %v, Unreferenced: %v, Elapsed: %v}",
+		r.StateHash.Hex(), r.NumNodes, r.NumAccounts, r.NumStorageTries,
+		len(r.MissingNodes), len(r.CorruptedNodes), len(r.UnreferencedNodes), r.Elapsed)
+}
+
+// StateVerifier walks the account trie and all the account storage tries of a state,
+// and checks that every node is present and matches its hash.
+type StateVerifier struct {
+	db    database.Database // DB holding the state trie nodes
+	refDB database.Database // DB holding the ref counts, nil to skip the ref count check
+
+	nodesPerSecond int
+	batchStart     time.Time
+}
+
+// NewStateVerifier creates a new StateVerifier instance. The ref counts are checked
+// against refDB if it is not nil.
+func NewStateVerifier(db database.Database, refDB database.Database) *StateVerifier {
+	return &StateVerifier{
+		db:    db,
+		refDB: refDB,
+	}
+}
+
+// SetThrottle limits the number of nodes checked per second, 0 means unlimited.
+func (sv *StateVerifier) SetThrottle(nodesPerSecond int) {
+	sv.nodesPerSecond = nodesPerSecond
+}
+
+// Verify checks the state with the given state hash
+func (sv *StateVerifier) Verify(ctx context.Context, stateHash common.Hash) (*Report, error) {
+	start := time.Now()
+	sv.batchStart = start
+
+	report := &Report{StateHash: stateHash}
+	verifiedStorageRoots := make(map[common.Hash]bool)
+	var storageRoots []common.Hash
+
+	callbacks := &trie.VerifyCallbacks{
+		OnNode: func(hash common.Hash, blob []byte) error {
+			return sv.onNode(ctx, report, hash)
+		},
+		OnMissing: func(err *trie.MissingNodeError) error {
+			report.MissingNodes = append(report.MissingNodes, err.NodeHash)
+			logger.Warnf("State %v: %v", stateHash.Hex(), err)
+			return nil
+		},
+		OnCorrupted: func(err *trie.CorruptedNodeError) error {
+			report.CorruptedNodes = append(report.CorruptedNodes, err.NodeHash)
+			logger.Warnf("State %v: %v", stateHash.Hex(), err)
+			return nil
+		},
+	}
+
+	accountCallbacks := *callbacks
+	accountCallbacks.OnLeaf = func(key, value []byte) error {
+		if !bytes.HasPrefix(key, accountKeyPrefix) {
+			return nil
+		}
+		report.NumAccounts++
+		account := &types.Account{}
+		if err := types.FromBytes(value, account); err != nil {
+			return fmt.Errorf("failed to decode account %v: %v", common.Bytes(key), err)
+		}
+		if account.Root != (common.Hash{}) && !verifiedStorageRoots[account.Root] {
+			verifiedStorageRoots[account.Root] = true
+			storageRoots = append(storageRoots, account.Root)
+		}
+		return nil
+	}
+
+	if err := trie.VerifyTrie(stateHash, sv.db, &accountCallbacks); err != nil {
+		return nil, err
+	}
+	for _, root := range storageRoots {
+		report.NumStorageTries++
+		if err := trie.VerifyTrie(root, sv.db, callbacks); err != nil {
+			return nil, err
+		}
+	}
+
+	report.Elapsed = time.Since(start)
+	return report, nil
+}
+
+func (sv *StateVerifier) onNode(ctx context.Context, report *Report, hash common.Hash) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	report.NumNodes++
+	if sv.refDB != nil {
+		// Only the nodes committed to the ref counting DB are expected to be referenced
+		if has, _ := sv.refDB.Has(hash.Bytes()); has {
+			if count, err := sv.refDB.CountReference(hash.Bytes()); err != nil || count <= 0 {
+				report.UnreferencedNodes = append(report.UnreferencedNodes, hash)
+			}
+		}
+	}
+
+	sv.throttle(report.NumNodes)
+	return nil
+}
+
+func (sv *StateVerifier) throttle(numNodes uint64) {
+	if sv.nodesPerSecond <= 0 || numNodes%throttleBatchSize != 0 {
+		return
+	}
+	target := time.Duration(throttleBatchSize) * time.Second / time.Duration(sv.nodesPerSecond)
+	if elapsed := time.Since(sv.batchStart); elapsed < target {
+		time.Sleep(target - elapsed)
+	}
+	sv.batchStart = time.Now()
+}
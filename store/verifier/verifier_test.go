@@ -0,0 +1,118 @@
+package verifier
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/scripttoken/script/common"
+	dp "github.com/scripttoken/script/dispatcher"
+	"github.com/scripttoken/script/ledger/state"
+	"github.com/scripttoken/script/rlp"
+	"github.com/scripttoken/script/store/database/backend"
+)
+
+func createTestState(db *backend.MemDatabase) (common.Hash, common.Hash) {
+	sv := state.NewStoreView(1, common.Hash{}, db)
+	var storageRoot common.Hash
+	for i := 0; i < 50; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i + 1)))
+		sv.AddBalance(addr, big.NewInt(int64(1000+i)))
+		if i%10 == 0 {
+			for j := 0; j < 20; j++ {
+				sv.SetState(addr, common.BigToHash(big.NewInt(int64(j+1))), common.BigToHash(big.NewInt(int64(i*100+j+1))))
+			}
+			storageRoot = sv.GetAccount(addr).Root
+		}
+	}
+	return sv.Save(), storageRoot
+}
+
+func TestStateVerifier(t *testing.T) {
+	assert := assert.New(t)
+
+	db := backend.NewMemDatabase()
+	stateHash, storageRoot := createTestState(db)
+
+	report, err := NewStateVerifier(db, nil).Verify(context.Background(), stateHash)
+	assert.Nil(err)
+	assert.True(report.IsHealthy())
+	assert.Equal(stateHash, report.StateHash)
+	assert.Equal(uint64(50), report.NumAccounts)
+	assert.Equal(uint64(5), report.NumStorageTries)
+	assert.True(report.NumNodes > 0)
+
+	// Remove the root of a storage trie
+	db.Delete(storageRoot.Bytes())
+	report, err = NewStateVerifier(db, nil).Verify(context.Background(), stateHash)
+	assert.Nil(err)
+	assert.False(report.IsHealthy())
+	assert.Equal([]common.Hash{storageRoot}, report.MissingNodes)
+	assert.Equal(0, len(report.CorruptedNodes))
+
+	// Corrupt the root of the account trie
+	db.Put(stateHash.Bytes(), []byte{0x1})
+	report, err = NewStateVerifier(db, nil).Verify(context.Background(), stateHash)
+	assert.Nil(err)
+	assert.Equal([]common.Hash{stateHash}, report.CorruptedNodes)
+	assert.Equal(uint64(0), report.NumAccounts)
+}
+
+func TestStateVerifierCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	db := backend.NewMemDatabase()
+	stateHash, _ := createTestState(db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewStateVerifier(db, nil).Verify(ctx, stateHash)
+	assert.Equal(context.Canceled, err)
+}
+
+func TestNodeFetcherRestoresRequestedNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	source := backend.NewMemDatabase()
+	stateHash, storageRoot := createTestState(source)
+	blob, err := source.Get(storageRoot.Bytes())
+	assert.Nil(err)
+	unrequested, err := source.Get(stateHash.Bytes())
+	assert.Nil(err)
+
+	db := backend.NewMemDatabase()
+	nf := NewNodeFetcher(db, nil)
+	done := make(chan struct{})
+	nf.pending[storageRoot] = done
+
+	payload, err := rlp.EncodeToBytes(&StateNodes{Nodes: []common.Bytes{blob, unrequested}})
+	assert.Nil(err)
+	msg, err := nf.ParseMessage("peer1", common.ChannelIDStateNode, mustEncode(t, nf, dp.DataResponse{
+		ChannelID: common.ChannelIDStateNode,
+		Payload:   payload,
+	}))
+	assert.Nil(err)
+	assert.Nil(nf.HandleMessage(msg))
+
+	select {
+	case <-done:
+	default:
+		t.Fatal("requested node not restored")
+	}
+	restored, err := db.Get(storageRoot.Bytes())
+	assert.Nil(err)
+	assert.Equal(blob, restored)
+
+	has, _ := db.Has(stateHash.Bytes())
+	assert.False(has)
+	assert.Equal(0, len(nf.pending))
+}
+
+func mustEncode(t *testing.T, nf *NodeFetcher, message interface{}) common.Bytes {
+	raw, err := nf.EncodeMessage(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}